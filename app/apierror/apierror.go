@@ -0,0 +1,86 @@
+// Package apierror writes API error responses. The body is {"error": message} unless the
+// client accepts application/problem+json, in which case it is an RFC 7807 problem.
+package apierror
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem documents
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem document. Type is about:blank because errors are
+// identified by their status; Title is the status text and Detail the error message.
+type Problem struct {
+	Type     string `json:"type" example:"about:blank"`
+	Title    string `json:"title" example:"Not Found"`
+	Detail   string `json:"detail,omitempty" example:"Snippet not found"`
+	Instance string `json:"instance,omitempty" example:"/api/v1/snippets/42"`
+	Status   int    `json:"status" example:"404"`
+}
+
+// NewProblem builds the problem document for an error on the request to path
+func NewProblem(status int, message, path string) Problem {
+	return Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   message,
+		Instance: path,
+	}
+}
+
+// Respond writes an error response in the format the client negotiated
+func Respond(c *gin.Context, status int, message string) {
+	c.Header("Vary", "Accept")
+	if c.Request != nil && WantsProblem(c.GetHeader("Accept")) {
+		c.Header("Content-Type", ProblemContentType)
+		c.JSON(status, NewProblem(status, message, c.Request.URL.Path))
+		return
+	}
+	c.JSON(status, gin.H{"error": message})
+}
+
+// RespondWithDetails writes an error response like Respond, with details added as
+// members of the {"error": message} body or as extension members of the problem
+// document, for clients to act on without parsing the message
+func RespondWithDetails(c *gin.Context, status int, message string, details map[string]interface{}) {
+	c.Header("Vary", "Accept")
+	body := make(gin.H, len(details)+5)
+	for key, value := range details {
+		body[key] = value
+	}
+	if c.Request != nil && WantsProblem(c.GetHeader("Accept")) {
+		problem := NewProblem(status, message, c.Request.URL.Path)
+		body["type"], body["title"], body["status"] = problem.Type, problem.Title, problem.Status
+		body["detail"], body["instance"] = problem.Detail, problem.Instance
+		c.Header("Content-Type", ProblemContentType)
+		c.JSON(status, body)
+		return
+	}
+	body["error"] = message
+	c.JSON(status, body)
+}
+
+// WantsProblem reports whether an Accept header asks for problem documents. Clients opt
+// in by listing application/problem+json; anything else keeps the plain JSON body.
+func WantsProblem(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || mediaType != ProblemContentType {
+			continue
+		}
+		q, ok := params["q"]
+		if !ok {
+			return true
+		}
+		weight, err := strconv.ParseFloat(q, 64)
+		return err == nil && weight > 0
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWantsProblem(t *testing.T) {
+	tests := map[string]bool{
+		"":                         false,
+		"*/*":                      false,
+		"application/json":         false,
+		"application/problem+json": true,
+		"application/json, application/problem+json": true,
+		"application/problem+json;q=0.5":             true,
+		"application/problem+json; q=0":              false,
+		"text/html, application/problem+json;q=bad":  false,
+	}
+	for accept, want := range tests {
+		if got := WantsProblem(accept); got != want {
+			t.Errorf("WantsProblem(%q) = %v, want %v", accept, got, want)
+		}
+	}
+}
+
+func TestRespond(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	respond := func(accept string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/snippets/42", nil)
+		c.Request.Header.Set("Accept", accept)
+		Respond(c, http.StatusNotFound, "Snippet not found")
+		return w
+	}
+
+	w := respond("application/json")
+	if ct := w.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	var plain map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &plain); err != nil || plain["error"] != "Snippet not found" {
+		t.Errorf("body = %s, want the plain error", w.Body.String())
+	}
+
+	w = respond(ProblemContentType)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != ProblemContentType {
+		t.Errorf("Content-Type = %q, want %s", ct, ProblemContentType)
+	}
+	var p Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("decode problem: %v", err)
+	}
+	want := Problem{Type: "about:blank", Title: "Not Found", Status: 404, Detail: "Snippet not found", Instance: "/api/v1/snippets/42"}
+	if p != want {
+		t.Errorf("problem = %+v, want %+v", p, want)
+	}
+	if w.Header().Get("Vary") != "Accept" {
+		t.Error("missing Vary: Accept")
+	}
+}
+
+func TestRespondWithDetails(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	respond := func(accept string) map[string]interface{} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/snippets", nil)
+		c.Request.Header.Set("Accept", accept)
+		RespondWithDetails(c, http.StatusRequestEntityTooLarge, "Too large", map[string]interface{}{"maxContentBytes": 20480})
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("status = %d, want 413", w.Code)
+		}
+		var body map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decode body: %v", err)
+		}
+		return body
+	}
+
+	if body := respond("application/json"); body["error"] != "Too large" || body["maxContentBytes"] != float64(20480) {
+		t.Errorf("plain body = %v, want the error with its details", body)
+	}
+	body := respond(ProblemContentType)
+	if body["detail"] != "Too large" || body["status"] != float64(413) || body["maxContentBytes"] != float64(20480) || body["error"] != nil {
+		t.Errorf("problem = %v, want the problem with its details as extension members", body)
+	}
+}
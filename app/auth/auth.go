@@ -150,10 +150,12 @@ var jwtSecret = []byte(getEnvOrDefault("JWT_SECRET", "your-secret-key-change-in-
 // Claims represents the JWT claims
 type Claims struct {
 	jwt.RegisteredClaims
-	UserID   string   `json:"user_id"`
-	Username string   `json:"username"`
-	Email    string   `json:"email"`
-	Roles    []string `json:"roles"`
+	UserID    string   `json:"user_id"`
+	OrgID     string   `json:"org_id,omitempty"`
+	SessionID string   `json:"sid,omitempty"` // login session the token was issued for, if any
+	Username  string   `json:"username"`
+	Email     string   `json:"email"`
+	Roles     []string `json:"roles"`
 }
 
 // GenerateToken generates a new JWT token for a user (DEPRECATED - use GenerateAccessToken)
@@ -169,13 +171,21 @@ func GenerateAccessToken(user *models.User) (string, error) {
 
 // GenerateAccessTokenWithRoles generates a JWT access token with user roles included.
 func GenerateAccessTokenWithRoles(user *models.User, roles []string) (string, error) {
+	return GenerateSessionAccessToken(user, roles, "")
+}
+
+// GenerateSessionAccessToken generates a JWT access token with user roles for the login
+// session sessionID, so logging the session out also invalidates the token
+func GenerateSessionAccessToken(user *models.User, roles []string, sessionID string) (string, error) {
 	expirationTime := time.Now().Add(models.AccessTokenDuration) // 15 minutes
 
 	claims := &Claims{
-		UserID:   user.ID,
-		Username: user.Username,
-		Email:    user.Email,
-		Roles:    roles,
+		UserID:    user.ID,
+		OrgID:     user.OrgID,
+		SessionID: sessionID,
+		Username:  user.Username,
+		Email:     user.Email,
+		Roles:     roles,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -191,6 +201,15 @@ func GenerateAccessTokenWithRoles(user *models.User, roles []string) (string, er
 	return tokenString, nil
 }
 
+// Org returns the organization of the token's user. Tokens issued before organizations
+// were introduced carry none and belong to the default organization.
+func (c *Claims) Org() string {
+	if c.OrgID == "" {
+		return models.DefaultOrgID
+	}
+	return c.OrgID
+}
+
 // ValidateToken validates a JWT token and returns the claims
 func ValidateToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
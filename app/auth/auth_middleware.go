@@ -2,66 +2,179 @@
 package auth
 
 import (
-	"context"
+	"database/sql"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/apierror"
+	"github.com/jheysaaz/snippy-backend/app/middleware"
 	"github.com/jheysaaz/snippy-backend/app/models"
 )
 
+// ErrWrongOrganization is returned for a token used in another organization than its user's
+var ErrWrongOrganization = errors.New("token belongs to another organization")
+
 // Middleware validates JWT tokens and sets user context
 func Middleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+		token, ok := bearerToken(c)
+		if !ok {
+			return
+		}
+		authenticateAccessToken(c, token)
+	}
+}
+
+// ScopedMiddleware accepts an access token, which has full access, or a browser-extension
+// token or API key granting scope. Routes not wrapped in it reject extension tokens and
+// API keys. Requests made with an API key carry it under "api_key" for rate limiting.
+// Like access tokens, extension tokens and API keys only work in their user's organization.
+// They're looked up in store.
+func ScopedMiddleware(store *models.Store, scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := bearerToken(c)
+		if !ok {
+			return
+		}
+
+		var userID string
+		var scopes []string
+		switch {
+		case models.IsExtensionToken(token):
+			ext, err := store.ValidateExtensionToken(c.Request.Context(), token, middleware.OrgID(c))
+			if err != nil {
+				rejectScopedToken(c, "extension token", err)
+				return
+			}
+			userID, scopes = ext.UserID, ext.Scopes
+		case models.IsAPIKey(token):
+			key, err := store.ValidateAPIKey(c.Request.Context(), token, middleware.OrgID(c))
+			if err != nil {
+				rejectScopedToken(c, "API key", err)
+				return
+			}
+			userID, scopes = key.UserID, key.Scopes
+			c.Set("api_key", key)
+		default:
+			authenticateAccessToken(c, token)
+			return
+		}
+
+		if !containsScope(scopes, scope) {
+			apierror.Respond(c, http.StatusForbidden, "Token does not allow this action")
 			c.Abort()
 			return
 		}
 
-		// Extract token from "Bearer <token>"
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
+		c.Set("user_id", userID)
+		c.Set("token_scopes", scopes)
+		c.Next()
+	}
+}
+
+// rejectScopedToken responds 401 to an extension token or API key that failed validation
+func rejectScopedToken(c *gin.Context, kind string, err error) {
+	if !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Failed to validate %s: %v", kind, err)
+	}
+	apierror.Respond(c, http.StatusUnauthorized, "Invalid or expired token")
+	c.Abort()
+}
+
+// bearerToken extracts the token from "Authorization: Bearer <token>", responding 401
+// if it's missing or malformed
+func bearerToken(c *gin.Context) (string, bool) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		apierror.Respond(c, http.StatusUnauthorized, "Authorization header required")
+		c.Abort()
+		return "", false
+	}
+
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		apierror.Respond(c, http.StatusUnauthorized, "Invalid authorization header format")
+		c.Abort()
+		return "", false
+	}
+	return parts[1], true
+}
+
+// authenticateAccessToken validates a JWT access token, sets the user context and
+// continues the chain, or responds 401. Tokens of another organization are rejected.
+func authenticateAccessToken(c *gin.Context, token string) {
+	claims, err := validateOrgToken(c, token)
+	if err != nil {
+		apierror.Respond(c, http.StatusUnauthorized, "Invalid or expired token")
+		c.Abort()
+		return
+	}
+
+	// Add user info to context
+	c.Set("user_id", claims.UserID)
+	c.Set("username", claims.Username)
+	c.Set("email", claims.Email)
+	c.Set("roles", claims.Roles) // Store roles in context for authorization checks
+
+	// Track activity of the session the token was issued for
+	if claims.SessionID != "" {
+		c.Set("session_id", claims.SessionID)
+		RecordSessionActivity(claims.SessionID)
+	}
+
+	c.Next()
+}
+
+// RequireActiveSession rejects access tokens whose login session has been logged out or
+// has expired, so logging out a session takes effect before its access tokens expire.
+// Tokens issued without a session are rejected too. Sessions are looked up in store. It
+// must run after Middleware.
+func RequireActiveSession(store *models.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID := c.GetString("session_id")
+		if sessionID == "" {
+			apierror.Respond(c, http.StatusUnauthorized, "Session required, please login again")
 			c.Abort()
 			return
 		}
 
-		token := parts[1]
-		claims, err := ValidateToken(token)
+		active, err := store.IsSessionActive(c.Request.Context(), sessionID, c.GetString("user_id"))
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			log.Printf("Failed to check session %s: %v", sessionID, err)
+			apierror.Respond(c, http.StatusInternalServerError, "Failed to check session")
 			c.Abort()
 			return
 		}
-
-		// Add user info to context
-		c.Set("user_id", claims.UserID)
-		c.Set("username", claims.Username)
-		c.Set("email", claims.Email)
-		c.Set("roles", claims.Roles) // Store roles in context for authorization checks
-
-		// Track session activity if session ID is provided
-		sessionID := c.GetHeader("X-Session-ID")
-		if sessionID != "" {
-			// Update session activity in background to avoid blocking
-			// Use background context since request context may be cancelled
-			go func(sid string) {
-				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-				defer cancel()
-				if err := models.UpdateSessionActivity(ctx, sid); err != nil {
-					log.Printf("Failed to update session activity for %s: %v", sid, err)
-				}
-			}(sessionID)
+		if !active {
+			apierror.Respond(c, http.StatusUnauthorized, "Session has ended, please login again")
+			c.Abort()
+			return
 		}
-
 		c.Next()
 	}
 }
 
+// validateOrgToken validates a JWT access token issued to a user of the request's organization
+func validateOrgToken(c *gin.Context, token string) (*Claims, error) {
+	return ValidateOrgToken(token, middleware.OrgID(c))
+}
+
+// ValidateOrgToken validates a JWT access token issued to a user of organization orgID,
+// returning ErrWrongOrganization for another organization's token
+func ValidateOrgToken(token, orgID string) (*Claims, error) {
+	claims, err := ValidateToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Org() != orgID {
+		return nil, ErrWrongOrganization
+	}
+	return claims, nil
+}
+
 // OptionalAuthMiddleware validates token if present, but doesn't require it
 func OptionalAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -70,7 +183,7 @@ func OptionalAuthMiddleware() gin.HandlerFunc {
 			parts := strings.Split(authHeader, " ")
 			if len(parts) == 2 && parts[0] == "Bearer" {
 				token := parts[1]
-				claims, err := ValidateToken(token)
+				claims, err := validateOrgToken(c, token)
 				if err == nil {
 					c.Set("user_id", claims.UserID)
 					c.Set("username", claims.Username)
@@ -91,3 +204,25 @@ func GetUserIDFromContext(c *gin.Context) (string, bool) {
 	userIDStr, ok := userID.(string)
 	return userIDStr, ok
 }
+
+// HasTokenScope reports whether the request's token grants scope. Access tokens carry no
+// scopes and grant everything; extension tokens and API keys only grant the scopes they
+// were issued with.
+func HasTokenScope(c *gin.Context, scope string) bool {
+	value, exists := c.Get("token_scopes")
+	if !exists {
+		return true
+	}
+	scopes, _ := value.([]string)
+	return containsScope(scopes, scope)
+}
+
+// containsScope reports whether scopes includes scope
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -175,3 +175,157 @@ func findSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+func TestScopedMiddlewareAccessToken(t *testing.T) {
+	os.Setenv("JWT_SECRET", "test-scoped-secret")
+	defer os.Unsetenv("JWT_SECRET")
+	jwtSecret = []byte(getEnvOrDefault("JWT_SECRET", "your-secret-key-change-in-production"))
+
+	testUser := &models.User{ID: "123e4567-e89b-12d3-a456-426614174000", Username: "testuser"}
+	validToken, err := GenerateAccessToken(testUser)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	serve := func(middleware gin.HandlerFunc, authHeader string) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.GET("/snippets", middleware, func(c *gin.Context) {
+			userID, _ := GetUserIDFromContext(c)
+			c.JSON(http.StatusOK, gin.H{"user_id": userID})
+		})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequestWithContext(context.Background(), "GET", "/snippets", nil)
+		if authHeader != "" {
+			req.Header.Set("Authorization", authHeader)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Access tokens have full access, so they pass any scope
+	if w := serve(ScopedMiddleware(nil, models.ScopeSnippetsRead), "Bearer "+validToken); w.Code != http.StatusOK || !contains(w.Body.String(), testUser.ID) {
+		t.Errorf("access token: status %d, body %s", w.Code, w.Body.String())
+	}
+	if w := serve(ScopedMiddleware(nil, models.ScopeSnippetsRead), ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("missing header: status %d, want 401", w.Code)
+	}
+
+	// Routes behind the full middleware never accept extension tokens or API keys
+	if w := serve(Middleware(), "Bearer "+models.ExtensionTokenPrefix+"abc"); w.Code != http.StatusUnauthorized {
+		t.Errorf("extension token on full route: status %d, want 401", w.Code)
+	}
+	if w := serve(Middleware(), "Bearer "+models.APIKeyPrefix+"abc"); w.Code != http.StatusUnauthorized {
+		t.Errorf("API key on full route: status %d, want 401", w.Code)
+	}
+}
+
+func TestMiddlewareOrganization(t *testing.T) {
+	os.Setenv("JWT_SECRET", "test-org-secret")
+	defer os.Unsetenv("JWT_SECRET")
+	jwtSecret = []byte(getEnvOrDefault("JWT_SECRET", "your-secret-key-change-in-production"))
+
+	const acmeOrgID = "7d1f2c3b-4a5e-4f60-8b7a-9c8d7e6f5a4b"
+	defaultToken, err := GenerateAccessToken(&models.User{ID: "123e4567-e89b-12d3-a456-426614174000"})
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	acmeToken, err := GenerateAccessToken(&models.User{ID: "223e4567-e89b-12d3-a456-426614174000", OrgID: acmeOrgID})
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		requestOrg string
+		wantStatus int
+	}{
+		{"token without org in default org", defaultToken, "", http.StatusOK},
+		{"token without org in other org", defaultToken, acmeOrgID, http.StatusUnauthorized},
+		{"token in its org", acmeToken, acmeOrgID, http.StatusOK},
+		{"token in default org", acmeToken, "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/protected", func(c *gin.Context) {
+				if tt.requestOrg != "" {
+					c.Set("org_id", tt.requestOrg)
+				}
+			}, Middleware(), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequestWithContext(context.Background(), "GET", "/protected", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestHasTokenScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	if !HasTokenScope(c, models.ScopeUsageWrite) {
+		t.Error("access tokens should grant every scope")
+	}
+
+	c.Set("token_scopes", []string{models.ScopeSnippetsRead})
+	if !HasTokenScope(c, models.ScopeSnippetsRead) {
+		t.Error("extension token should grant its own scope")
+	}
+	if HasTokenScope(c, models.ScopeUsageWrite) {
+		t.Error("extension token should not grant scopes it wasn't issued with")
+	}
+}
+
+func TestMiddlewareSessionID(t *testing.T) {
+	os.Setenv("JWT_SECRET", "test-session-secret")
+	defer os.Unsetenv("JWT_SECRET")
+	jwtSecret = []byte(getEnvOrDefault("JWT_SECRET", "your-secret-key-change-in-production"))
+
+	const sessionID = "5a0c1e2d-3b4f-4a6e-9d8c-7b6a5f4e3d2c"
+	testUser := &models.User{ID: "123e4567-e89b-12d3-a456-426614174000", Username: "testuser"}
+	sessionToken, err := GenerateSessionAccessToken(testUser, nil, sessionID)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	plainToken, err := GenerateAccessToken(testUser)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	serve := func(token string, handlers ...gin.HandlerFunc) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.GET("/protected", append([]gin.HandlerFunc{Middleware()}, handlers...)...)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequestWithContext(context.Background(), "GET", "/protected", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// The session comes from the token, not from a client-supplied header
+	w := serve(sessionToken, func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("session_id"))
+	})
+	if w.Code != http.StatusOK || w.Body.String() != sessionID {
+		t.Errorf("session token: status %d, session %q, want %q", w.Code, w.Body.String(), sessionID)
+	}
+	if queued := <-activity.sessions; queued != sessionID {
+		t.Errorf("activity queued for %q, want %q", queued, sessionID)
+	}
+	activity.done(sessionID)
+
+	// Sensitive routes can't be checked against a session the token doesn't carry
+	if w := serve(plainToken, RequireActiveSession(nil)); w.Code != http.StatusUnauthorized {
+		t.Errorf("token without session on sensitive route: status %d, want 401", w.Code)
+	}
+}
@@ -192,3 +192,36 @@ func TestGenerateRandomToken(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateSessionAccessToken(t *testing.T) {
+	os.Setenv("JWT_SECRET", "test-secret-key-for-testing")
+	defer os.Unsetenv("JWT_SECRET")
+	jwtSecret = []byte(getEnvOrDefault("JWT_SECRET", "your-secret-key-change-in-production"))
+
+	testUser := &models.User{ID: "123e4567-e89b-12d3-a456-426614174000", Username: "testuser"}
+	token, err := GenerateSessionAccessToken(testUser, []string{"premium"}, "session-1")
+	if err != nil {
+		t.Fatalf("GenerateSessionAccessToken() error = %v", err)
+	}
+
+	claims, err := ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	if claims.SessionID != "session-1" {
+		t.Errorf("SessionID = %q, want %q", claims.SessionID, "session-1")
+	}
+
+	// Tokens without a session omit the claim entirely
+	token, err = GenerateAccessToken(testUser)
+	if err != nil {
+		t.Fatalf("GenerateAccessToken() error = %v", err)
+	}
+	claims, err = ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	if claims.SessionID != "" {
+		t.Errorf("plain token SessionID = %q, want none", claims.SessionID)
+	}
+}
@@ -0,0 +1,94 @@
+// Package auth provides queued session activity updates.
+package auth
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+const (
+	// activityWorkers is how many session activity updates run at once
+	activityWorkers = 4
+
+	// activityQueueSize is how many sessions can wait for an update. Beyond it updates
+	// are dropped; a session's next request records its activity again.
+	activityQueueSize = 1024
+
+	// activityUpdateTimeout bounds each session activity update
+	activityUpdateTimeout = 5 * time.Second
+)
+
+// activityQueue holds sessions whose activity is waiting to be recorded. queued tracks
+// them so a session making many requests is only queued once.
+type activityQueue struct {
+	queued   map[string]struct{}
+	sessions chan string
+	mu       sync.Mutex
+}
+
+var activity = &activityQueue{
+	queued:   make(map[string]struct{}),
+	sessions: make(chan string, activityQueueSize),
+}
+
+// enqueue queues sessionID for an activity update without blocking, reporting whether it
+// was queued (or already waiting)
+func (q *activityQueue) enqueue(sessionID string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.queued[sessionID]; ok {
+		return true
+	}
+	select {
+	case q.sessions <- sessionID:
+		q.queued[sessionID] = struct{}{}
+		return true
+	default:
+		return false
+	}
+}
+
+// done marks sessionID as no longer waiting
+func (q *activityQueue) done(sessionID string) {
+	q.mu.Lock()
+	delete(q.queued, sessionID)
+	q.mu.Unlock()
+}
+
+// RecordSessionActivity queues an update of the session's last activity, so requests
+// and gRPC calls don't wait on it
+func RecordSessionActivity(sessionID string) {
+	if !activity.enqueue(sessionID) {
+		log.Printf("Session activity queue full, dropping update for %s", sessionID)
+	}
+}
+
+// RunActivityWorkers records queued session activity in store until ctx is cancelled.
+// Updates still queued at shutdown are dropped.
+func RunActivityWorkers(ctx context.Context, store *models.Store) {
+	var wg sync.WaitGroup
+	for range activityWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case sessionID := <-activity.sessions:
+					activity.done(sessionID)
+					updateCtx, cancel := context.WithTimeout(ctx, activityUpdateTimeout)
+					if err := store.UpdateSessionActivity(updateCtx, sessionID); err != nil {
+						log.Printf("Failed to update session activity for %s: %v", sessionID, err)
+					}
+					cancel()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
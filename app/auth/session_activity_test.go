@@ -0,0 +1,23 @@
+package auth
+
+import "testing"
+
+func TestActivityQueue(t *testing.T) {
+	q := &activityQueue{queued: make(map[string]struct{}), sessions: make(chan string, 2)}
+
+	if !q.enqueue("s1") || !q.enqueue("s1") || len(q.sessions) != 1 {
+		t.Fatalf("a waiting session should only be queued once (queue length %d)", len(q.sessions))
+	}
+	if !q.enqueue("s2") {
+		t.Fatal("s2 should fit in the queue")
+	}
+	if q.enqueue("s3") {
+		t.Error("enqueue should drop updates when the queue is full")
+	}
+
+	// Once taken by a worker, a session can be queued again
+	q.done(<-q.sessions)
+	if !q.enqueue("s1") || len(q.sessions) != 2 {
+		t.Errorf("s1 should be queued again after its update started (queue length %d)", len(q.sessions))
+	}
+}
@@ -0,0 +1,116 @@
+// Package autotls lets the server terminate TLS itself with certificates from Let's
+// Encrypt (ACME), so small deployments don't need a reverse proxy in front of it.
+package autotls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/jheysaaz/snippy-backend/app/httpserver"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+const (
+	// DefaultCacheDir is where issued certificates and the ACME account key are kept
+	DefaultCacheDir = "certs"
+
+	// DefaultHTTPSPort serves the API over TLS
+	DefaultHTTPSPort = httpserver.DefaultHTTPSPort
+
+	// DefaultHTTPPort serves ACME HTTP-01 challenges and redirects everything else to HTTPS
+	DefaultHTTPPort = "80"
+)
+
+// Config configures built-in TLS. It's disabled when Domains is empty.
+type Config struct {
+	// Domains are the host names certificates are requested for; others are refused
+	Domains  []string
+	CacheDir string
+	// Email is given to Let's Encrypt for expiry and problem notices (optional)
+	Email     string
+	HTTPSPort string
+	// HTTPPort is the redirect server's port; "off" disables it, leaving TLS-ALPN-01
+	// as the only challenge type
+	HTTPPort string
+	// RedirectExclude are path prefixes the redirect server serves over plain HTTP
+	RedirectExclude []string
+}
+
+// LoadConfig reads TLS_* settings from the environment
+func LoadConfig() Config {
+	cfg := Config{
+		CacheDir:  os.Getenv("TLS_CACHE_DIR"),
+		Email:     os.Getenv("TLS_EMAIL"),
+		HTTPSPort: os.Getenv("TLS_PORT"),
+		HTTPPort:  os.Getenv("TLS_HTTP_PORT"),
+
+		RedirectExclude: httpserver.RedirectExcludeFromEnv(),
+	}
+	for _, domain := range strings.Split(os.Getenv("TLS_DOMAINS"), ",") {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			cfg.Domains = append(cfg.Domains, domain)
+		}
+	}
+	if cfg.CacheDir == "" {
+		cfg.CacheDir = DefaultCacheDir
+	}
+	if cfg.HTTPSPort == "" {
+		cfg.HTTPSPort = DefaultHTTPSPort
+	}
+	if cfg.HTTPPort == "" {
+		cfg.HTTPPort = DefaultHTTPPort
+	}
+	return cfg
+}
+
+// Enabled reports whether the server should terminate TLS itself
+func (cfg Config) Enabled() bool {
+	return len(cfg.Domains) > 0
+}
+
+// newManager returns the ACME certificate manager for cfg
+func newManager(cfg Config) *autocert.Manager {
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(cfg.CacheDir),
+		HostPolicy: autocert.HostWhitelist(cfg.Domains...),
+		Email:      cfg.Email,
+	}
+}
+
+// Serve serves handler over TLS on httpsLis, obtaining and renewing certificates on
+// demand, and runs the redirect server, answering ACME HTTP-01 challenges and serving
+// excluded paths with handler, on httpLis unless it's nil (TLS_HTTP_PORT=off),
+// until ctx is done. It returns when the HTTPS server stops; the redirect server failing
+// only disables HTTP-01 challenges and redirects.
+func Serve(ctx context.Context, cfg Config, handler http.Handler, httpsLis, httpLis net.Listener) error {
+	if !cfg.Enabled() {
+		return errors.New("autotls: no domains configured")
+	}
+	if err := os.MkdirAll(cfg.CacheDir, 0o700); err != nil {
+		return fmt.Errorf("autotls: create certificate cache: %w", err)
+	}
+	manager := newManager(cfg)
+
+	if httpLis != nil {
+		redirect := httpserver.RedirectConfig{HTTPSPort: cfg.HTTPSPort, Exclude: cfg.RedirectExclude}
+		redirectServer := httpserver.New(httpLis.Addr().String(), manager.HTTPHandler(httpserver.Redirect(redirect, handler)), false)
+		go func() {
+			log.Printf("HTTP redirect server running on %s", httpLis.Addr())
+			if err := httpserver.Serve(ctx, redirectServer, httpLis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("HTTP redirect server stopped: %v", err)
+			}
+		}()
+	}
+
+	server := httpserver.New(httpsLis.Addr().String(), handler, false)
+	server.TLSConfig = manager.TLSConfig()
+	log.Printf("Server running with TLS on %s for %s", httpsLis.Addr(), strings.Join(cfg.Domains, ", "))
+	return httpserver.Serve(ctx, server, httpsLis)
+}
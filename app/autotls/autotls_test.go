@@ -0,0 +1,44 @@
+package autotls
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("TLS_DOMAINS", " Snippy.example.com, ,api.snippy.example.com")
+	t.Setenv("TLS_CACHE_DIR", "")
+	t.Setenv("TLS_PORT", "")
+	t.Setenv("TLS_HTTP_PORT", "off")
+	t.Setenv("REDIRECT_EXCLUDE", "/.well-known/, /health")
+
+	cfg := LoadConfig()
+	if want := []string{"snippy.example.com", "api.snippy.example.com"}; !reflect.DeepEqual(cfg.Domains, want) {
+		t.Errorf("Domains = %v, want %v", cfg.Domains, want)
+	}
+	if cfg.CacheDir != DefaultCacheDir || cfg.HTTPSPort != DefaultHTTPSPort || cfg.HTTPPort != "off" {
+		t.Errorf("CacheDir, HTTPSPort, HTTPPort = %q, %q, %q", cfg.CacheDir, cfg.HTTPSPort, cfg.HTTPPort)
+	}
+	if want := []string{"/.well-known/", "/health"}; !reflect.DeepEqual(cfg.RedirectExclude, want) {
+		t.Errorf("RedirectExclude = %v, want %v", cfg.RedirectExclude, want)
+	}
+	if !cfg.Enabled() {
+		t.Error("Enabled() = false with domains set")
+	}
+
+	t.Setenv("TLS_DOMAINS", "")
+	if LoadConfig().Enabled() {
+		t.Error("Enabled() = true without domains")
+	}
+}
+
+func TestHostPolicy(t *testing.T) {
+	manager := newManager(Config{Domains: []string{"snippy.example.com"}, CacheDir: t.TempDir()})
+	if err := manager.HostPolicy(context.Background(), "snippy.example.com"); err != nil {
+		t.Errorf("configured domain refused: %v", err)
+	}
+	if err := manager.HostPolicy(context.Background(), "evil.example.com"); err == nil {
+		t.Error("other domain accepted")
+	}
+}
@@ -0,0 +1,242 @@
+// Package avatar validates uploaded profile pictures and renders the sized variants served to clients.
+package avatar
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	// Register the GIF decoder for image.Decode
+	_ "image/gif"
+
+	"golang.org/x/image/draw"
+)
+
+// Upload limits
+const (
+	MaxUploadBytes = 2 << 20 // 2 MiB
+	MaxDimension   = 4096
+	MinDimension   = 32
+)
+
+// Sizes are the square variants generated for every avatar, in pixels
+var Sizes = []int{64, 256}
+
+// DefaultSize is the variant linked from a user's avatarUrl
+const DefaultSize = 256
+
+// jpegQuality is used when re-encoding JPEG uploads
+const jpegQuality = 90
+
+// allowedTypes maps sniffed content types to whether they are accepted
+var allowedTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+}
+
+// Validation errors returned by Process
+var (
+	ErrTooLarge        = errors.New("avatar exceeds the 2 MiB upload limit")
+	ErrUnsupportedType = errors.New("avatar must be a PNG, JPEG or GIF image")
+	ErrDimensions      = fmt.Errorf("avatar width and height must be between %dpx and %dpx", MinDimension, MaxDimension)
+	ErrCorrupt         = errors.New("avatar image could not be decoded")
+)
+
+// Variant is a single re-encoded avatar size
+type Variant struct {
+	ContentType string
+	Data        []byte
+	Size        int
+}
+
+// Process validates an uploaded image and renders one square variant per entry in Sizes.
+// The image is center-cropped, EXIF orientation is applied, and the output is re-encoded
+// from raw pixels so EXIF and other metadata from the upload are never stored.
+func Process(data []byte) ([]Variant, error) {
+	if len(data) > MaxUploadBytes {
+		return nil, ErrTooLarge
+	}
+
+	// Sniff the content rather than trusting the client-supplied header or file name
+	contentType := http.DetectContentType(data)
+	if !allowedTypes[contentType] {
+		return nil, ErrUnsupportedType
+	}
+
+	// Check dimensions from the header before decoding to avoid decompression bombs
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	if cfg.Width < MinDimension || cfg.Height < MinDimension || cfg.Width > MaxDimension || cfg.Height > MaxDimension {
+		return nil, ErrDimensions
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+
+	orientation := 1
+	outputType := "image/png"
+	if contentType == "image/jpeg" {
+		orientation = jpegOrientation(data)
+		outputType = "image/jpeg"
+	}
+
+	crop := centerSquare(src.Bounds())
+	variants := make([]Variant, 0, len(Sizes))
+	for _, size := range Sizes {
+		// Cropping to a centered square commutes with rotation, so orient the small image
+		canvas := image.NewRGBA(image.Rect(0, 0, size, size))
+		draw.CatmullRom.Scale(canvas, canvas.Bounds(), src, crop, draw.Src, nil)
+		dst := orient(canvas, orientation)
+
+		var buf bytes.Buffer
+		if outputType == "image/jpeg" {
+			err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: jpegQuality})
+		} else {
+			err = png.Encode(&buf, dst)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("encode %dpx avatar: %w", size, err)
+		}
+
+		variants = append(variants, Variant{Size: size, ContentType: outputType, Data: buf.Bytes()})
+	}
+
+	return variants, nil
+}
+
+// IsValidSize reports whether size is one of the generated variants
+func IsValidSize(size int) bool {
+	for _, s := range Sizes {
+		if s == size {
+			return true
+		}
+	}
+	return false
+}
+
+// centerSquare returns the largest square centered in r
+func centerSquare(r image.Rectangle) image.Rectangle {
+	side := r.Dx()
+	if r.Dy() < side {
+		side = r.Dy()
+	}
+	x0 := r.Min.X + (r.Dx()-side)/2
+	y0 := r.Min.Y + (r.Dy()-side)/2
+	return image.Rect(x0, y0, x0+side, y0+side)
+}
+
+// orient applies an EXIF orientation (1-8) so the image displays upright
+func orient(src image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90 clockwise
+				dx, dy = h-1-y, x
+			case 7: // transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90 counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, color.RGBAModel.Convert(src.At(b.Min.X+x, b.Min.Y+y)))
+		}
+	}
+	return dst
+}
+
+// jpegOrientation reads the EXIF orientation tag from a JPEG, defaulting to 1 (upright)
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		// Start of scan or end of image: no more metadata segments
+		if marker == 0xDA || marker == 0xD9 {
+			return 1
+		}
+		segLen := int(data[i+2])<<8 | int(data[i+3])
+		if segLen < 2 || i+2+segLen > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+segLen]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + segLen
+	}
+	return 1
+}
+
+// exifOrientation finds the orientation tag (0x0112) in the first IFD of a TIFF block
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd : ifd+2]))
+	for n := 0; n < entries; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			value := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if value >= 1 && value <= 8 {
+				return value
+			}
+			return 1
+		}
+	}
+	return 1
+}
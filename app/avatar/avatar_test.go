@@ -0,0 +1,167 @@
+package avatar
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// exifSegment builds a big-endian APP1 EXIF segment carrying only an orientation tag
+func exifSegment(orientation byte) []byte {
+	tiff := []byte{
+		'M', 'M', 0x00, 0x2A, 0x00, 0x00, 0x00, 0x08, // header, IFD at offset 8
+		0x00, 0x01, // one entry
+		0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01, 0x00, orientation, 0x00, 0x00, // orientation SHORT
+		0x00, 0x00, 0x00, 0x00, // no next IFD
+	}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segLen := len(payload) + 2
+	return append([]byte{0xFF, 0xE1, byte(segLen >> 8), byte(segLen)}, payload...)
+}
+
+func encodeJPEGWithEXIF(t *testing.T, w, h int, orientation byte) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("encode jpeg: %v", err)
+	}
+	raw := buf.Bytes()
+	// Insert the EXIF segment right after SOI
+	out := append([]byte{}, raw[:2]...)
+	out = append(out, exifSegment(orientation)...)
+	return append(out, raw[2:]...)
+}
+
+func TestProcessRejectsInvalidUploads(t *testing.T) {
+	tests := []struct {
+		want error
+		name string
+		data []byte
+	}{
+		{name: "Too large", data: make([]byte, MaxUploadBytes+1), want: ErrTooLarge},
+		{name: "Not an image", data: []byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"), want: ErrUnsupportedType},
+		{name: "Too small", data: encodePNG(t, 16, 16), want: ErrDimensions},
+		{name: "Truncated", data: encodePNG(t, 64, 64)[:40], want: ErrCorrupt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Process(tt.data)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Process() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessGeneratesSquareVariants(t *testing.T) {
+	variants, err := Process(encodePNG(t, 300, 200))
+	if err != nil {
+		t.Fatalf("Process() returned error: %v", err)
+	}
+	if len(variants) != len(Sizes) {
+		t.Fatalf("expected %d variants, got %d", len(Sizes), len(variants))
+	}
+
+	for i, v := range variants {
+		if v.Size != Sizes[i] {
+			t.Errorf("variant %d size = %d, want %d", i, v.Size, Sizes[i])
+		}
+		if v.ContentType != "image/png" {
+			t.Errorf("variant %d content type = %s, want image/png", i, v.ContentType)
+		}
+		cfg, err := png.DecodeConfig(bytes.NewReader(v.Data))
+		if err != nil {
+			t.Fatalf("variant %d is not a valid PNG: %v", i, err)
+		}
+		if cfg.Width != v.Size || cfg.Height != v.Size {
+			t.Errorf("variant %d is %dx%d, want %dx%d", i, cfg.Width, cfg.Height, v.Size, v.Size)
+		}
+	}
+}
+
+func TestProcessStripsEXIF(t *testing.T) {
+	data := encodeJPEGWithEXIF(t, 120, 80, 6)
+	if !bytes.Contains(data, []byte("Exif")) {
+		t.Fatal("test image is missing its EXIF segment")
+	}
+
+	variants, err := Process(data)
+	if err != nil {
+		t.Fatalf("Process() returned error: %v", err)
+	}
+	for _, v := range variants {
+		if v.ContentType != "image/jpeg" {
+			t.Errorf("%dpx variant content type = %s, want image/jpeg", v.Size, v.ContentType)
+		}
+		if bytes.Contains(v.Data, []byte("Exif")) {
+			t.Errorf("%dpx variant still contains EXIF data", v.Size)
+		}
+	}
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	for _, orientation := range []byte{1, 3, 6, 8} {
+		data := encodeJPEGWithEXIF(t, 40, 40, orientation)
+		if got := jpegOrientation(data); got != int(orientation) {
+			t.Errorf("jpegOrientation() = %d, want %d", got, orientation)
+		}
+	}
+
+	if got := jpegOrientation([]byte("not a jpeg")); got != 1 {
+		t.Errorf("jpegOrientation() on invalid data = %d, want 1", got)
+	}
+}
+
+func TestOrient(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+
+	// 2x1 image: red on the left, blue on the right
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	src.Set(0, 0, red)
+	src.Set(1, 0, blue)
+
+	// Rotating 90 clockwise puts red on top
+	rotated := orient(src, 6)
+	if b := rotated.Bounds(); b.Dx() != 1 || b.Dy() != 2 {
+		t.Fatalf("rotated bounds = %v, want 1x2", b)
+	}
+	if rotated.At(0, 0) != red || rotated.At(0, 1) != blue {
+		t.Errorf("unexpected pixels after 90 degree rotation")
+	}
+
+	// Orientation 1 leaves the image untouched
+	if orient(src, 1) != image.Image(src) {
+		t.Errorf("orientation 1 should return the source image")
+	}
+}
+
+func TestIsValidSize(t *testing.T) {
+	if !IsValidSize(64) || !IsValidSize(256) {
+		t.Error("expected 64 and 256 to be valid sizes")
+	}
+	if IsValidSize(128) {
+		t.Error("expected 128 to be rejected")
+	}
+}
@@ -0,0 +1,249 @@
+// Package backup periodically exports the database, encrypts the archive and uploads
+// it to S3-compatible object storage (S3 or GCS), keeping the newest N backups.
+package backup
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jheysaaz/snippy-backend/app/database"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+const (
+	// DefaultInterval is how often backups run
+	DefaultInterval = 24 * time.Hour
+
+	// DefaultRetention is how many backups are kept
+	DefaultRetention = 7
+
+	// runTimeout bounds each backup, from the dump to pruning old backups
+	runTimeout = 2 * time.Hour
+
+	// objectSuffix ends every backup object key
+	objectSuffix = ".zip.enc"
+)
+
+// Config configures backups. Backups are disabled when Bucket is empty.
+type Config struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	EncryptionKey   string
+	Interval        time.Duration
+	Retention       int
+	Insecure        bool
+}
+
+// LoadConfig reads BACKUP_* settings from the environment
+func LoadConfig() Config {
+	cfg := Config{
+		Endpoint:        os.Getenv("BACKUP_ENDPOINT"),
+		Region:          os.Getenv("BACKUP_REGION"),
+		Bucket:          os.Getenv("BACKUP_BUCKET"),
+		Prefix:          os.Getenv("BACKUP_PREFIX"),
+		AccessKeyID:     os.Getenv("BACKUP_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("BACKUP_SECRET_ACCESS_KEY"),
+		EncryptionKey:   os.Getenv("BACKUP_ENCRYPTION_KEY"),
+		Interval:        DefaultInterval,
+		Retention:       DefaultRetention,
+		Insecure:        os.Getenv("BACKUP_INSECURE") == "true",
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "s3.amazonaws.com"
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = "backups/"
+	}
+
+	if raw := os.Getenv("BACKUP_INTERVAL"); raw != "" {
+		interval, err := time.ParseDuration(raw)
+		if err != nil || interval < time.Hour {
+			log.Printf("Ignoring invalid BACKUP_INTERVAL %q (minimum 1h), using %v", raw, DefaultInterval)
+		} else {
+			cfg.Interval = interval
+		}
+	}
+	if raw := os.Getenv("BACKUP_RETENTION"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			log.Printf("Ignoring invalid BACKUP_RETENTION %q, keeping %d backups", raw, DefaultRetention)
+		} else {
+			cfg.Retention = n
+		}
+	}
+	return cfg
+}
+
+// Enabled reports whether backups are configured
+func (cfg Config) Enabled() bool {
+	return cfg.Bucket != ""
+}
+
+// ObjectKey names a backup taken at t
+func ObjectKey(prefix string, t time.Time) string {
+	return prefix + "snippy-" + t.UTC().Format("20060102T150405Z") + objectSuffix
+}
+
+// Job runs scheduled backups
+type Job struct {
+	store   *models.Store
+	storage Storage
+	cfg     Config
+	key     []byte
+}
+
+// NewJob creates a backup job uploading to storage and recording its runs in store
+func NewJob(cfg Config, store *models.Store, storage Storage) (*Job, error) {
+	key, err := ParseKey(cfg.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Job{store: store, storage: storage, cfg: cfg, key: key}, nil
+}
+
+// NewJobFromEnv configures a job from the environment. It returns nil when BACKUP_BUCKET is unset.
+func NewJobFromEnv(store *models.Store) (*Job, error) {
+	cfg := LoadConfig()
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+	storage, err := NewObjectStorage(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewJob(cfg, store, storage)
+}
+
+// Run backs up every interval until ctx is cancelled
+func (j *Job) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			runCtx, cancel := context.WithTimeout(ctx, runTimeout)
+			run, err := j.RunOnce(runCtx)
+			cancel()
+			switch {
+			case errors.Is(err, database.ErrDumpInProgress):
+				log.Println("Skipping backup: another instance is backing up")
+			case err != nil:
+				log.Printf("Backup failed: %v", err)
+			default:
+				log.Printf("Backup %s uploaded (%d tables, %d rows, %d bytes, %d old backups pruned)",
+					*run.ObjectKey, run.Tables, run.Rows, run.SizeBytes, run.Pruned)
+			}
+		}
+	}
+}
+
+// RunOnce takes one backup, prunes old ones and records the run. A run skipped because
+// another instance holds the dump lock is not recorded.
+func (j *Job) RunOnce(ctx context.Context) (*models.BackupRun, error) {
+	run := &models.BackupRun{StartedAt: time.Now()}
+	key := ObjectKey(j.cfg.Prefix, run.StartedAt)
+
+	stats, size, err := j.upload(ctx, key)
+	if errors.Is(err, database.ErrDumpInProgress) {
+		return nil, err
+	}
+	run.Tables, run.Rows, run.SizeBytes = stats.Tables, stats.Rows, size
+
+	if err == nil {
+		run.ObjectKey = &key
+		// Only prune after a successful upload so a failing run never removes good backups
+		run.Pruned, err = prune(ctx, j.storage, j.cfg.Prefix, j.cfg.Retention)
+		if err != nil {
+			err = fmt.Errorf("prune old backups: %w", err)
+		}
+	}
+
+	run.FinishedAt = time.Now()
+	run.Status = models.BackupSucceeded
+	if err != nil {
+		run.Status = models.BackupFailed
+		msg := err.Error()
+		run.Error = &msg
+	}
+	if recordErr := j.store.RecordBackupRun(ctx, run); recordErr != nil {
+		log.Printf("Failed to record backup run: %v", recordErr)
+	}
+	return run, err
+}
+
+// upload streams an encrypted zip of the database dump to key
+func (j *Job) upload(ctx context.Context, key string) (database.DumpStats, int64, error) {
+	pr, pw := io.Pipe()
+	counter := &countingWriter{w: pw}
+
+	type result struct {
+		stats database.DumpStats
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		stats, err := writeArchive(ctx, counter, j.key)
+		_ = pw.CloseWithError(err)
+		done <- result{stats, err}
+	}()
+
+	uploadErr := j.storage.Upload(ctx, key, pr)
+	// Unblock the writer if the upload stopped reading early
+	_ = pr.CloseWithError(errors.New("upload finished"))
+	res := <-done
+
+	if res.err != nil {
+		return res.stats, counter.n, res.err
+	}
+	if uploadErr != nil {
+		return res.stats, counter.n, fmt.Errorf("upload %s: %w", key, uploadErr)
+	}
+	return res.stats, counter.n, nil
+}
+
+// writeArchive writes the encrypted zip archive (one tables/<name>.ndjson entry per table) to w
+func writeArchive(ctx context.Context, w io.Writer, key []byte) (database.DumpStats, error) {
+	enc, err := NewEncryptWriter(w, key)
+	if err != nil {
+		return database.DumpStats{}, err
+	}
+	zw := zip.NewWriter(enc)
+
+	stats, err := database.Dump(ctx, func(table string) (io.Writer, error) {
+		return zw.Create("tables/" + strings.ReplaceAll(table, "/", "_") + ".ndjson")
+	})
+	if err != nil {
+		return stats, err
+	}
+	if err := zw.Close(); err != nil {
+		return stats, err
+	}
+	return stats, enc.Close()
+}
+
+// countingWriter counts bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,212 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func encrypt(t *testing.T, key, plain []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewEncryptWriter(&buf, key)
+	if err != nil {
+		t.Fatalf("NewEncryptWriter: %v", err)
+	}
+	if _, err := w.Write(plain); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	key := testKey(t)
+	sizes := map[string]int{
+		"empty":          0,
+		"small":          100,
+		"exact chunk":    chunkSize,
+		"several chunks": 3*chunkSize + 17,
+	}
+	for name, size := range sizes {
+		t.Run(name, func(t *testing.T) {
+			plain := make([]byte, size)
+			_, _ = rand.Read(plain)
+
+			sealed := encrypt(t, key, plain)
+			if size > 0 && bytes.Contains(sealed, plain) {
+				t.Fatal("ciphertext contains the plaintext")
+			}
+
+			var out bytes.Buffer
+			if err := Decrypt(&out, bytes.NewReader(sealed), key); err != nil {
+				t.Fatalf("Decrypt: %v", err)
+			}
+			if !bytes.Equal(out.Bytes(), plain) {
+				t.Fatalf("round trip mismatch: got %d bytes, want %d", out.Len(), size)
+			}
+		})
+	}
+}
+
+func TestDecryptRejectsTampering(t *testing.T) {
+	key := testKey(t)
+	plain := bytes.Repeat([]byte("snippet"), chunkSize/3)
+	sealed := encrypt(t, key, plain)
+
+	cases := map[string]struct {
+		data []byte
+		key  []byte
+	}{
+		"wrong key": {sealed, testKey(t)},
+		// Dropping the final chunk must not decrypt to a silently shorter backup
+		"truncated":   {sealed[:len(sealed)-chunkSize/2], key},
+		"flipped bit": {flipByte(sealed, len(sealed)/2), key},
+		"bad magic":   {append([]byte("NOTABACK"), sealed[len(magic):]...), key},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := Decrypt(&bytes.Buffer{}, bytes.NewReader(tc.data), tc.key)
+			if !errors.Is(err, ErrCorrupt) {
+				t.Fatalf("expected ErrCorrupt, got %v", err)
+			}
+		})
+	}
+}
+
+func flipByte(data []byte, i int) []byte {
+	out := append([]byte(nil), data...)
+	out[i] ^= 0xff
+	return out
+}
+
+func TestParseKey(t *testing.T) {
+	valid := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	if _, err := ParseKey(valid); err != nil {
+		t.Errorf("valid key rejected: %v", err)
+	}
+	for _, bad := range []string{"", "not base64!", base64.StdEncoding.EncodeToString(make([]byte, 16))} {
+		if _, err := ParseKey(bad); err == nil {
+			t.Errorf("ParseKey(%q) should fail", bad)
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("BACKUP_BUCKET", "snippy-backups")
+	t.Setenv("BACKUP_ENDPOINT", "")
+	t.Setenv("BACKUP_PREFIX", "")
+	t.Setenv("BACKUP_INTERVAL", "6h")
+	t.Setenv("BACKUP_RETENTION", "14")
+
+	cfg := LoadConfig()
+	if !cfg.Enabled() {
+		t.Fatal("expected backups to be enabled")
+	}
+	if cfg.Endpoint != "s3.amazonaws.com" || cfg.Prefix != "backups/" {
+		t.Errorf("unexpected defaults: endpoint %q prefix %q", cfg.Endpoint, cfg.Prefix)
+	}
+	if cfg.Interval != 6*time.Hour || cfg.Retention != 14 {
+		t.Errorf("expected 6h and 14, got %v and %d", cfg.Interval, cfg.Retention)
+	}
+
+	t.Setenv("BACKUP_INTERVAL", "1m")
+	t.Setenv("BACKUP_RETENTION", "0")
+	cfg = LoadConfig()
+	if cfg.Interval != DefaultInterval || cfg.Retention != DefaultRetention {
+		t.Errorf("invalid values should fall back to defaults, got %v and %d", cfg.Interval, cfg.Retention)
+	}
+
+	t.Setenv("BACKUP_BUCKET", "")
+	if LoadConfig().Enabled() {
+		t.Error("expected backups to be disabled without a bucket")
+	}
+}
+
+func TestObjectKeySortsByTime(t *testing.T) {
+	earlier := ObjectKey("backups/", time.Date(2026, 1, 9, 23, 0, 0, 0, time.UTC))
+	later := ObjectKey("backups/", time.Date(2026, 1, 10, 1, 0, 0, 0, time.FixedZone("CET", 3600)))
+	if earlier != "backups/snippy-20260109T230000Z.zip.enc" {
+		t.Errorf("unexpected key %q", earlier)
+	}
+	if later <= earlier {
+		t.Errorf("expected %q to sort after %q", later, earlier)
+	}
+}
+
+type memStorage struct {
+	objects map[string][]byte
+}
+
+func (m *memStorage) Upload(_ context.Context, key string, r io.Reader) error {
+	var buf bytes.Buffer
+	_, err := buf.ReadFrom(r)
+	m.objects[key] = buf.Bytes()
+	return err
+}
+
+func (m *memStorage) List(_ context.Context, prefix string) ([]string, error) {
+	keys := make([]string, 0, len(m.objects))
+	for key := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (m *memStorage) Delete(_ context.Context, key string) error {
+	delete(m.objects, key)
+	return nil
+}
+
+func TestPruneKeepsNewest(t *testing.T) {
+	storage := &memStorage{objects: map[string][]byte{
+		"backups/snippy-20260101T000000Z.zip.enc": nil,
+		"backups/snippy-20260102T000000Z.zip.enc": nil,
+		"backups/snippy-20260103T000000Z.zip.enc": nil,
+		"backups/snippy-20260104T000000Z.zip.enc": nil,
+		"backups/README.txt":                      nil,
+		"other/snippy-20250101T000000Z.zip.enc":   nil,
+	}}
+
+	deleted, err := prune(context.Background(), storage, "backups/", 2)
+	if err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("expected 2 deleted, got %d", deleted)
+	}
+	for _, key := range []string{
+		"backups/snippy-20260103T000000Z.zip.enc",
+		"backups/snippy-20260104T000000Z.zip.enc",
+		"backups/README.txt",
+		"other/snippy-20250101T000000Z.zip.enc",
+	} {
+		if _, ok := storage.objects[key]; !ok {
+			t.Errorf("%s should have been kept", key)
+		}
+	}
+	if len(storage.objects) != 4 {
+		t.Errorf("expected 4 objects left, got %d", len(storage.objects))
+	}
+}
@@ -0,0 +1,161 @@
+package backup
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Backups are encrypted in chunks with AES-256-GCM so they can be streamed. The file
+// starts with magic and a random 4-byte nonce prefix; each chunk is a 1-byte final flag,
+// a 4-byte ciphertext length and the ciphertext. The nonce is the prefix followed by the
+// chunk counter, and the chunk header is authenticated, so reordered, dropped or
+// truncated chunks fail to decrypt.
+const (
+	magic      = "SNPYBAK1"
+	chunkSize  = 64 << 10
+	prefixSize = 4
+	headerSize = 5
+)
+
+// ErrCorrupt is returned when a backup fails to decrypt
+var ErrCorrupt = errors.New("backup: corrupt or wrong key")
+
+// ParseKey decodes a base64-encoded 32-byte key (openssl rand -base64 32)
+func ParseKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("backup: decode encryption key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("backup: encryption key must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(prefix []byte, counter uint64) []byte {
+	nonce := make([]byte, prefixSize+8)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint64(nonce[prefixSize:], counter)
+	return nonce
+}
+
+// encryptWriter encrypts everything written to it; Close writes the final chunk
+type encryptWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	prefix  []byte
+	buf     []byte
+	counter uint64
+}
+
+// NewEncryptWriter returns a writer encrypting to w with key. Close must be called to
+// finish the backup; it does not close w.
+func NewEncryptWriter(w io.Writer, key []byte) (io.WriteCloser, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, prefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(w, magic); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(prefix); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{w: w, aead: aead, prefix: prefix, buf: make([]byte, 0, chunkSize)}, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := copy(e.buf[len(e.buf):chunkSize], p)
+		e.buf = e.buf[:len(e.buf)+n]
+		p = p[n:]
+		written += n
+		if len(e.buf) == chunkSize {
+			if err := e.flush(false); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+func (e *encryptWriter) Close() error {
+	return e.flush(true)
+}
+
+func (e *encryptWriter) flush(final bool) error {
+	header := make([]byte, headerSize)
+	if final {
+		header[0] = 1
+	}
+	binary.BigEndian.PutUint32(header[1:], uint32(len(e.buf)+e.aead.Overhead()))
+
+	sealed := e.aead.Seal(nil, chunkNonce(e.prefix, e.counter), e.buf, header)
+	e.counter++
+	e.buf = e.buf[:0]
+
+	if _, err := e.w.Write(header); err != nil {
+		return err
+	}
+	_, err := e.w.Write(sealed)
+	return err
+}
+
+// Decrypt decrypts a backup from r to w
+func Decrypt(w io.Writer, r io.Reader, key []byte) error {
+	aead, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+
+	start := make([]byte, len(magic)+prefixSize)
+	if _, err := io.ReadFull(r, start); err != nil || string(start[:len(magic)]) != magic {
+		return fmt.Errorf("%w: missing header", ErrCorrupt)
+	}
+	prefix := start[len(magic):]
+
+	header := make([]byte, headerSize)
+	for counter := uint64(0); ; counter++ {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return fmt.Errorf("%w: truncated", ErrCorrupt)
+		}
+		size := binary.BigEndian.Uint32(header[1:])
+		if size < uint32(aead.Overhead()) || size > chunkSize+uint32(aead.Overhead()) {
+			return fmt.Errorf("%w: invalid chunk size", ErrCorrupt)
+		}
+
+		sealed := make([]byte, size)
+		if _, err := io.ReadFull(r, sealed); err != nil {
+			return fmt.Errorf("%w: truncated", ErrCorrupt)
+		}
+		plain, err := aead.Open(nil, chunkNonce(prefix, counter), sealed, header)
+		if err != nil {
+			return ErrCorrupt
+		}
+		if _, err := w.Write(plain); err != nil {
+			return err
+		}
+		if header[0] == 1 {
+			return nil
+		}
+	}
+}
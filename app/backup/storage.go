@@ -0,0 +1,95 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// uploadPartSize is the multipart chunk size for streamed uploads of unknown length
+const uploadPartSize = 16 << 20
+
+// Storage stores backup objects
+type Storage interface {
+	Upload(ctx context.Context, key string, r io.Reader) error
+	// List returns object keys under prefix in ascending order
+	List(ctx context.Context, prefix string) ([]string, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// objectStorage talks to S3 or any S3-compatible service, including GCS through its
+// XML API with HMAC keys
+type objectStorage struct {
+	client *minio.Client
+	bucket string
+}
+
+// NewObjectStorage connects to the bucket described by cfg
+func NewObjectStorage(cfg Config) (Storage, error) {
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
+		Secure: !cfg.Insecure,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("backup: create storage client: %w", err)
+	}
+	return &objectStorage{client: client, bucket: cfg.Bucket}, nil
+}
+
+func (s *objectStorage) Upload(ctx context.Context, key string, r io.Reader) error {
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, -1, minio.PutObjectOptions{
+		ContentType: "application/octet-stream",
+		PartSize:    uploadPartSize,
+	})
+	return err
+}
+
+func (s *objectStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	keys := make([]string, 0)
+	for obj := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return nil, obj.Err
+		}
+		keys = append(keys, obj.Key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (s *objectStorage) Delete(ctx context.Context, key string) error {
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
+
+// prune deletes all but the newest keep backups under prefix. Backup keys embed their
+// UTC timestamp, so name order is age order.
+func prune(ctx context.Context, storage Storage, prefix string, keep int) (int, error) {
+	keys, err := storage.List(ctx, prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	backups := keys[:0]
+	for _, key := range keys {
+		if strings.HasSuffix(key, objectSuffix) {
+			backups = append(backups, key)
+		}
+	}
+	if len(backups) <= keep {
+		return 0, nil
+	}
+
+	deleted := 0
+	for _, key := range backups[:len(backups)-keep] {
+		if err := storage.Delete(ctx, key); err != nil {
+			return deleted, fmt.Errorf("delete %s: %w", key, err)
+		}
+		deleted++
+	}
+	return deleted, nil
+}
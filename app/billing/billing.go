@@ -0,0 +1,148 @@
+// Package billing sells premium through Stripe Checkout and keeps the premium role in
+// sync with each user's subscription status, as reported by Stripe webhooks.
+package billing
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+// Config configures Stripe billing. Billing is disabled unless the secret key and price are set.
+type Config struct {
+	SecretKey     string
+	WebhookSecret string
+	PriceID       string
+	SuccessURL    string
+	CancelURL     string
+}
+
+// LoadConfig reads STRIPE_* and BILLING_* settings from the environment. Checkout return
+// URLs default to PUBLIC_BASE_URL.
+func LoadConfig() Config {
+	cfg := Config{
+		SecretKey:     os.Getenv("STRIPE_SECRET_KEY"),
+		WebhookSecret: os.Getenv("STRIPE_WEBHOOK_SECRET"),
+		PriceID:       os.Getenv("STRIPE_PRICE_ID"),
+		SuccessURL:    os.Getenv("BILLING_SUCCESS_URL"),
+		CancelURL:     os.Getenv("BILLING_CANCEL_URL"),
+	}
+	base := strings.TrimRight(os.Getenv("PUBLIC_BASE_URL"), "/")
+	if cfg.SuccessURL == "" {
+		cfg.SuccessURL = base + "/billing/success"
+	}
+	if cfg.CancelURL == "" {
+		cfg.CancelURL = base + "/billing/cancel"
+	}
+	return cfg
+}
+
+// Enabled reports whether checkout is configured
+func (cfg Config) Enabled() bool {
+	return cfg.SecretKey != "" && cfg.PriceID != ""
+}
+
+// stripeSubscription is the subset of a Stripe subscription object used to track premium
+type stripeSubscription struct {
+	ID                string            `json:"id"`
+	Customer          string            `json:"customer"`
+	Status            string            `json:"status"`
+	Metadata          map[string]string `json:"metadata"`
+	CurrentPeriodEnd  int64             `json:"current_period_end"`
+	CancelAtPeriodEnd bool              `json:"cancel_at_period_end"`
+	Items             struct {
+		Data []struct {
+			CurrentPeriodEnd int64 `json:"current_period_end"`
+			Price            struct {
+				ID string `json:"id"`
+			} `json:"price"`
+		} `json:"data"`
+	} `json:"items"`
+}
+
+// stripeCheckoutSession is the subset of a completed checkout session used to link customers
+type stripeCheckoutSession struct {
+	ClientReferenceID string `json:"client_reference_id"`
+	Customer          string `json:"customer"`
+}
+
+// HandleEvent applies a verified webhook event to store. Unhandled event types are
+// ignored. Events for unknown users are logged and dropped so Stripe stops retrying them.
+func HandleEvent(ctx context.Context, store *models.Store, event *Event) error {
+	switch event.Type {
+	case "checkout.session.completed":
+		var session stripeCheckoutSession
+		if err := json.Unmarshal(event.Data.Object, &session); err != nil {
+			return fmt.Errorf("decode checkout session: %w", err)
+		}
+		if session.ClientReferenceID == "" || session.Customer == "" {
+			return nil
+		}
+		return store.LinkStripeCustomer(ctx, session.ClientReferenceID, session.Customer)
+
+	case "customer.subscription.created", "customer.subscription.updated", "customer.subscription.deleted":
+		var sub stripeSubscription
+		if err := json.Unmarshal(event.Data.Object, &sub); err != nil {
+			return fmt.Errorf("decode subscription: %w", err)
+		}
+		update, err := subscriptionUpdate(ctx, store, &sub, time.Unix(event.Created, 0))
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Ignoring Stripe event %s: no user for customer %s", event.ID, sub.Customer)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		changed, err := store.ApplySubscriptionUpdate(ctx, update)
+		if err != nil {
+			return err
+		}
+		if changed {
+			log.Printf("Subscription %s is %s; premium for user %s updated", sub.ID, sub.Status, update.UserID)
+		}
+	}
+	return nil
+}
+
+// subscriptionUpdate converts a Stripe subscription, finding its user from the metadata set
+// at checkout or, for subscriptions created elsewhere, from the linked customer
+func subscriptionUpdate(ctx context.Context, store *models.Store, sub *stripeSubscription, eventAt time.Time) (models.SubscriptionUpdate, error) {
+	update := models.SubscriptionUpdate{
+		EventAt:           eventAt,
+		UserID:            sub.Metadata["user_id"],
+		CustomerID:        sub.Customer,
+		SubscriptionID:    sub.ID,
+		Status:            sub.Status,
+		CancelAtPeriodEnd: sub.CancelAtPeriodEnd,
+	}
+
+	periodEnd := sub.CurrentPeriodEnd
+	if len(sub.Items.Data) > 0 {
+		update.PriceID = sub.Items.Data[0].Price.ID
+		// Newer API versions report the billing period per item
+		if periodEnd == 0 {
+			periodEnd = sub.Items.Data[0].CurrentPeriodEnd
+		}
+	}
+	if periodEnd > 0 {
+		t := time.Unix(periodEnd, 0)
+		update.CurrentPeriodEnd = &t
+	}
+
+	if update.UserID == "" {
+		userID, err := store.GetUserIDByStripeCustomer(ctx, sub.Customer)
+		if err != nil {
+			return update, err
+		}
+		update.UserID = userID
+	}
+	return update, nil
+}
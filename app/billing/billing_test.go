@@ -0,0 +1,139 @@
+package billing
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func signedHeader(payload []byte, secret string, at time.Time) string {
+	ts := fmt.Sprint(at.Unix())
+	return fmt.Sprintf("t=%s,v1=%s", ts, hex.EncodeToString(sign(payload, ts, secret)))
+}
+
+func TestParseEvent(t *testing.T) {
+	payload := []byte(`{"id":"evt_1","type":"customer.subscription.updated","created":1700000000,"data":{"object":{"id":"sub_1"}}}`)
+	now := time.Unix(1700000100, 0)
+	valid := signedHeader(payload, "whsec_test", now)
+
+	event, err := ParseEvent(payload, valid, "whsec_test", now)
+	if err != nil {
+		t.Fatalf("ParseEvent: %v", err)
+	}
+	if event.ID != "evt_1" || event.Type != "customer.subscription.updated" || string(event.Data.Object) != `{"id":"sub_1"}` {
+		t.Errorf("unexpected event %+v", event)
+	}
+
+	// A rolled secret sends one v1 signature per active secret
+	rolled := valid + ",v1=" + hex.EncodeToString(sign(payload, fmt.Sprint(now.Unix()), "whsec_old"))
+	if _, err := ParseEvent(payload, rolled, "whsec_old", now); err != nil {
+		t.Errorf("expected any matching v1 signature to be accepted: %v", err)
+	}
+
+	rejected := map[string]struct {
+		payload []byte
+		header  string
+		now     time.Time
+	}{
+		"wrong secret":     {payload, signedHeader(payload, "whsec_other", now), now},
+		"modified payload": {append([]byte(" "), payload...), valid, now},
+		"replayed":         {payload, valid, now.Add(10 * time.Minute)},
+		"missing header":   {payload, "", now},
+		"no v1 signature":  {payload, fmt.Sprintf("t=%d,v0=abc", now.Unix()), now},
+	}
+	for name, tc := range rejected {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseEvent(tc.payload, tc.header, "whsec_test", tc.now); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("expected ErrInvalidSignature, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSubscriptionUpdateFromMetadata(t *testing.T) {
+	var sub stripeSubscription
+	err := json.Unmarshal([]byte(`{
+		"id": "sub_1",
+		"customer": "cus_1",
+		"status": "active",
+		"metadata": {"user_id": "0b3c9a1e-6a5f-4c1b-9d2e-3f4a5b6c7d8e"},
+		"cancel_at_period_end": true,
+		"items": {"data": [{"current_period_end": 1700003600, "price": {"id": "price_premium"}}]}
+	}`), &sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eventAt := time.Unix(1700000000, 0)
+	update, err := subscriptionUpdate(context.Background(), nil, &sub, eventAt)
+	if err != nil {
+		t.Fatalf("subscriptionUpdate: %v", err)
+	}
+	if update.UserID != "0b3c9a1e-6a5f-4c1b-9d2e-3f4a5b6c7d8e" || update.CustomerID != "cus_1" ||
+		update.SubscriptionID != "sub_1" || update.Status != "active" || update.PriceID != "price_premium" ||
+		!update.CancelAtPeriodEnd || !update.EventAt.Equal(eventAt) {
+		t.Errorf("unexpected update %+v", update)
+	}
+	if update.CurrentPeriodEnd == nil || update.CurrentPeriodEnd.Unix() != 1700003600 {
+		t.Errorf("expected the item period end, got %v", update.CurrentPeriodEnd)
+	}
+}
+
+func TestHandleEventIgnoresOtherTypes(t *testing.T) {
+	event := &Event{ID: "evt_1", Type: "invoice.paid"}
+	if err := HandleEvent(context.Background(), nil, event); err != nil {
+		t.Errorf("expected unhandled events to be ignored, got %v", err)
+	}
+}
+
+func TestCreateCheckoutSession(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, _, _ := r.BasicAuth(); user != "sk_test" {
+			t.Errorf("expected the secret key as basic auth user, got %q", user)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		if r.URL.Path != "/v1/checkout/sessions" || r.Form.Get("mode") != "subscription" ||
+			r.Form.Get("line_items[0][price]") != "price_premium" || r.Form.Get("customer") != "cus_1" ||
+			r.Form.Get("customer_email") != "" || r.Form.Get("subscription_data[metadata][user_id]") != "user-1" {
+			t.Errorf("unexpected request %s %v", r.URL.Path, r.Form)
+		}
+		_, _ = w.Write([]byte(`{"id":"cs_1","url":"https://checkout.stripe.com/c/pay/cs_1"}`))
+	}))
+	defer server.Close()
+
+	stripe := NewStripe("sk_test")
+	stripe.endpoint = server.URL
+	url, err := stripe.CreateCheckoutSession(context.Background(), CheckoutParams{
+		UserID: "user-1", Email: "alice@example.com", CustomerID: "cus_1", PriceID: "price_premium",
+		SuccessURL: "https://app.example.com/ok", CancelURL: "https://app.example.com/cancel",
+	})
+	if err != nil {
+		t.Fatalf("CreateCheckoutSession: %v", err)
+	}
+	if url != "https://checkout.stripe.com/c/pay/cs_1" {
+		t.Errorf("unexpected url %q", url)
+	}
+}
+
+func TestCreateCheckoutSessionError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":{"message":"No such price: 'price_missing'"}}`))
+	}))
+	defer server.Close()
+
+	stripe := NewStripe("sk_test")
+	stripe.endpoint = server.URL
+	_, err := stripe.CreateCheckoutSession(context.Background(), CheckoutParams{PriceID: "price_missing"})
+	if err == nil || err.Error() != "stripe: status 400: No such price: 'price_missing'" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package billing
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	stripeAPI = "https://api.stripe.com"
+
+	// signatureTolerance is how old a signed webhook may be before it is rejected as a replay
+	signatureTolerance = 5 * time.Minute
+)
+
+var httpClient = &http.Client{Timeout: 15 * time.Second}
+
+// ErrInvalidSignature is returned for webhooks not signed with the endpoint secret
+var ErrInvalidSignature = errors.New("billing: invalid webhook signature")
+
+// Stripe calls the Stripe REST API
+type Stripe struct {
+	secretKey string
+	endpoint  string
+}
+
+// NewStripe creates a Stripe API client
+func NewStripe(secretKey string) *Stripe {
+	return &Stripe{secretKey: secretKey, endpoint: stripeAPI}
+}
+
+// CheckoutParams describes a subscription checkout
+type CheckoutParams struct {
+	UserID     string
+	Email      string
+	CustomerID string // reused when the user has checked out before
+	PriceID    string
+	SuccessURL string
+	CancelURL  string
+}
+
+// CreateCheckoutSession starts a hosted subscription checkout and returns its URL. The user
+// ID is attached to the session and the subscription so webhooks can be matched to the user.
+func (s *Stripe) CreateCheckoutSession(ctx context.Context, p CheckoutParams) (string, error) {
+	form := url.Values{
+		"mode":                                 {"subscription"},
+		"line_items[0][price]":                 {p.PriceID},
+		"line_items[0][quantity]":              {"1"},
+		"success_url":                          {p.SuccessURL},
+		"cancel_url":                           {p.CancelURL},
+		"client_reference_id":                  {p.UserID},
+		"subscription_data[metadata][user_id]": {p.UserID},
+	}
+	if p.CustomerID != "" {
+		form.Set("customer", p.CustomerID)
+	} else if p.Email != "" {
+		form.Set("customer_email", p.Email)
+	}
+
+	var session struct {
+		URL string `json:"url"`
+	}
+	if err := s.post(ctx, "/v1/checkout/sessions", form, &session); err != nil {
+		return "", err
+	}
+	return session.URL, nil
+}
+
+// post sends a form-encoded API request and decodes the JSON response into out
+func (s *Stripe) post(ctx context.Context, path string, form url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(s.secretKey, "")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Error.Message != "" {
+			return fmt.Errorf("stripe: status %d: %s", resp.StatusCode, apiErr.Error.Message)
+		}
+		return fmt.Errorf("stripe: status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Event is a Stripe webhook event
+type Event struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Created int64  `json:"created"`
+	Data    struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// ParseEvent verifies the Stripe-Signature header of a webhook payload against the
+// endpoint secret and decodes the event
+func ParseEvent(payload []byte, header, secret string, now time.Time) (*Event, error) {
+	if err := verifySignature(payload, header, secret, now); err != nil {
+		return nil, err
+	}
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("billing: decode event: %w", err)
+	}
+	return &event, nil
+}
+
+// verifySignature checks a "t=<unix>,v1=<hex>" header, where v1 is the HMAC-SHA256 of
+// "<t>.<payload>". Any matching v1 is accepted so secrets can be rolled.
+func verifySignature(payload []byte, header, secret string, now time.Time) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > signatureTolerance || age < -signatureTolerance {
+		return ErrInvalidSignature
+	}
+
+	expected := sign(payload, timestamp, secret)
+	for _, sig := range signatures {
+		decoded, err := hex.DecodeString(sig)
+		if err == nil && hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// sign computes the v1 webhook signature
+func sign(payload []byte, timestamp, secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,140 @@
+// Package broadcast delivers admin broadcast emails at a throttled rate, recording
+// delivery status per recipient.
+package broadcast
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jheysaaz/snippy-backend/app/mailer"
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// DefaultRatePerMinute is how many broadcast emails are sent per minute
+	DefaultRatePerMinute = 60
+
+	// pollInterval is how often the job looks for queued recipients
+	pollInterval = 30 * time.Second
+
+	// batchSize is how many recipients are claimed at a time
+	batchSize = 50
+)
+
+// RatePerMinute reads BROADCAST_RATE_PER_MINUTE, falling back to DefaultRatePerMinute
+func RatePerMinute() int {
+	raw := os.Getenv("BROADCAST_RATE_PER_MINUTE")
+	if raw == "" {
+		return DefaultRatePerMinute
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		log.Printf("Ignoring invalid BROADCAST_RATE_PER_MINUTE %q, using %d", raw, DefaultRatePerMinute)
+		return DefaultRatePerMinute
+	}
+	return n
+}
+
+// Render builds the broadcast email for one recipient
+func Render(d models.BroadcastDelivery) (mailer.Message, error) {
+	msg, err := mailer.Render("broadcast", map[string]interface{}{
+		"Username": d.Username,
+		"Subject":  d.Subject,
+		"Message":  d.Message,
+	})
+	if err != nil {
+		return mailer.Message{}, err
+	}
+	msg.To = d.Email
+	return msg, nil
+}
+
+// Job sends queued broadcast emails without exceeding its rate
+type Job struct {
+	store   *models.Store
+	sender  mailer.Mailer
+	limiter *rate.Limiter
+}
+
+// NewJob creates a job delivering the recipients queued in store through sender, at most
+// perMinute emails a minute
+func NewJob(store *models.Store, sender mailer.Mailer, perMinute int) *Job {
+	return &Job{
+		store:   store,
+		sender:  sender,
+		limiter: rate.NewLimiter(rate.Limit(float64(perMinute)/60), 1),
+	}
+}
+
+// Run delivers queued recipients until ctx is cancelled
+func (j *Job) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Keep draining while full batches come back
+			for {
+				sent, claimed, err := j.RunOnce(ctx)
+				if err != nil {
+					log.Printf("Broadcast delivery run failed: %v", err)
+					break
+				}
+				if sent > 0 {
+					log.Printf("Sent %d broadcast emails", sent)
+				}
+				if claimed < batchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// RunOnce claims a batch of due recipients and sends to each, waiting on the rate
+// limiter between sends. It returns how many were sent and how many were claimed.
+func (j *Job) RunOnce(ctx context.Context) (int, int, error) {
+	deliveries, err := j.store.ClaimBroadcastDeliveries(ctx, batchSize)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	sent := 0
+	for _, d := range deliveries {
+		if err := j.limiter.Wait(ctx); err != nil {
+			// Unsent claims are reclaimed once they go stale
+			return sent, len(deliveries), err
+		}
+
+		sendErr := j.deliver(ctx, d)
+		if sendErr != nil {
+			log.Printf("Failed to send broadcast %d to user %s (attempt %d): %v", d.BroadcastID, d.UserID, d.Attempts, sendErr)
+			if err := j.store.MarkBroadcastFailed(ctx, d, sendErr); err != nil {
+				log.Printf("Failed to record broadcast failure for user %s: %v", d.UserID, err)
+			}
+			continue
+		}
+
+		sent++
+		if err := j.store.MarkBroadcastDelivered(ctx, d); err != nil {
+			log.Printf("Failed to record broadcast delivery for user %s: %v", d.UserID, err)
+		}
+	}
+	return sent, len(deliveries), nil
+}
+
+// deliver renders and sends one broadcast email
+func (j *Job) deliver(ctx context.Context, d models.BroadcastDelivery) error {
+	msg, err := Render(d)
+	if err != nil {
+		return err
+	}
+	return j.sender.Send(ctx, msg)
+}
@@ -0,0 +1,55 @@
+package broadcast
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+func TestRender(t *testing.T) {
+	msg, err := Render(models.BroadcastDelivery{
+		Email:    "alice@example.com",
+		Username: "alice",
+		Subject:  "Scheduled maintenance",
+		Message:  "Snippy will be read-only on <Saturday>.",
+	})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	if msg.To != "alice@example.com" {
+		t.Errorf("Expected recipient alice@example.com, got %s", msg.To)
+	}
+	if msg.Subject != "Scheduled maintenance" {
+		t.Errorf("Expected admin subject, got %q", msg.Subject)
+	}
+	if !strings.Contains(msg.Text, "Hi alice") || !strings.Contains(msg.Text, "read-only on <Saturday>") {
+		t.Errorf("Unexpected text body:\n%s", msg.Text)
+	}
+	if !strings.Contains(msg.HTML, "&lt;Saturday&gt;") {
+		t.Errorf("Expected message to be escaped in HTML, got:\n%s", msg.HTML)
+	}
+}
+
+func TestRatePerMinute(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected int
+	}{
+		{name: "default", value: "", expected: DefaultRatePerMinute},
+		{name: "override", value: "120", expected: 120},
+		{name: "invalid", value: "fast", expected: DefaultRatePerMinute},
+		{name: "zero", value: "0", expected: DefaultRatePerMinute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("BROADCAST_RATE_PER_MINUTE", tt.value)
+			if got := RatePerMinute(); got != tt.expected {
+				t.Errorf("Expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
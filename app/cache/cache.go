@@ -0,0 +1,168 @@
+// Package cache keeps short-lived copies of hot point lookups (users, roles, snippets)
+// so authenticated requests don't repeat the same queries. Redis is used when REDIS_URL
+// is set; otherwise every lookup misses and callers read the database.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultPrefix starts every key, so the cache can share a Redis database
+const DefaultPrefix = "snippy:"
+
+// Store is a key/value store with expiry. Get reports a missing key as a miss, not an error.
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+	Close() error
+}
+
+// store is the process-wide cache, set once at startup
+var store Store = nopStore{}
+
+// SetStore installs s as the cache. A nil store disables caching.
+func SetStore(s Store) {
+	if s == nil {
+		s = nopStore{}
+	}
+	store = s
+}
+
+// Close closes the installed store
+func Close() error {
+	return store.Close()
+}
+
+// GetJSON decodes the cached value for key into out and reports whether it was found.
+// Cache errors are logged and treated as a miss so callers fall back to the database.
+func GetJSON(ctx context.Context, key string, out interface{}) bool {
+	data, ok, err := store.Get(ctx, key)
+	if err != nil {
+		log.Printf("Cache get %s failed: %v", key, err)
+		return false
+	}
+	if !ok {
+		return false
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		log.Printf("Cache entry %s is invalid: %v", key, err)
+		return false
+	}
+	return true
+}
+
+// SetJSON caches v under key for ttl. Failures are logged; the next lookup just misses.
+func SetJSON(ctx context.Context, key string, v interface{}, ttl time.Duration) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Cache set %s failed: %v", key, err)
+		return
+	}
+	if err := store.Set(ctx, key, data, ttl); err != nil {
+		log.Printf("Cache set %s failed: %v", key, err)
+	}
+}
+
+// Delete drops keys after the data behind them changed. A failed delete leaves the stale
+// entry until its TTL expires, which is why every entry has a short one.
+func Delete(ctx context.Context, keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	if err := store.Delete(ctx, keys...); err != nil {
+		log.Printf("Cache delete %s failed: %v", strings.Join(keys, ", "), err)
+	}
+}
+
+// nopStore never holds anything
+type nopStore struct{}
+
+func (nopStore) Get(context.Context, string) ([]byte, bool, error)        { return nil, false, nil }
+func (nopStore) Set(context.Context, string, []byte, time.Duration) error { return nil }
+func (nopStore) Delete(context.Context, ...string) error                  { return nil }
+func (nopStore) Close() error                                             { return nil }
+
+// Redis stores entries in Redis
+type Redis struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedis connects to the Redis server at rawURL (redis://[:password@]host:port/db)
+// and checks it responds
+func NewRedis(ctx context.Context, rawURL, prefix string) (*Redis, error) {
+	opts, err := redis.ParseURL(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("cache: invalid REDIS_URL: %w", err)
+	}
+	// A slow cache must not slow requests down more than the query it replaces
+	opts.DialTimeout = time.Second
+	opts.ReadTimeout = 200 * time.Millisecond
+	opts.WriteTimeout = 200 * time.Millisecond
+
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("cache: connect to redis: %w", err)
+	}
+	return &Redis{client: client, prefix: prefix}, nil
+}
+
+// NewRedisFromEnv connects to REDIS_URL, or returns nil when it isn't set
+func NewRedisFromEnv(ctx context.Context) (*Redis, error) {
+	rawURL := strings.TrimSpace(os.Getenv("REDIS_URL"))
+	if rawURL == "" {
+		return nil, nil
+	}
+	prefix := os.Getenv("REDIS_KEY_PREFIX")
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+	return NewRedis(ctx, rawURL, prefix)
+}
+
+// Get returns the value stored under key
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	data, err := r.client.Get(ctx, r.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// Set stores value under key for ttl
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, r.prefix+key, value, ttl).Err()
+}
+
+// Delete removes keys
+func (r *Redis) Delete(ctx context.Context, keys ...string) error {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = r.prefix + key
+	}
+	return r.client.Del(ctx, prefixed...).Err()
+}
+
+// Ping checks the Redis server responds
+func (r *Redis) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+// Close closes the connection pool
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type failingStore struct{ nopStore }
+
+func (failingStore) Get(context.Context, string) ([]byte, bool, error) {
+	return nil, false, errors.New("connection refused")
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	defer SetStore(nil)
+	SetStore(NewMemory())
+	ctx := context.Background()
+
+	type user struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	SetJSON(ctx, "user:1", user{ID: "1", Name: "ada"}, time.Minute)
+
+	var got user
+	if !GetJSON(ctx, "user:1", &got) || got.Name != "ada" {
+		t.Fatalf("GetJSON = %+v, want cached user", got)
+	}
+
+	Delete(ctx, "user:1")
+	if GetJSON(ctx, "user:1", &got) {
+		t.Error("GetJSON hit after Delete")
+	}
+}
+
+func TestGetJSONMisses(t *testing.T) {
+	defer SetStore(nil)
+	ctx := context.Background()
+	var out []string
+
+	SetStore(nil)
+	SetJSON(ctx, "roles:1", []string{"admin"}, time.Minute)
+	if GetJSON(ctx, "roles:1", &out) {
+		t.Error("disabled cache returned a hit")
+	}
+
+	SetStore(failingStore{})
+	if GetJSON(ctx, "roles:1", &out) {
+		t.Error("store error returned a hit")
+	}
+
+	mem := NewMemory()
+	SetStore(mem)
+	_ = mem.Set(ctx, "roles:1", []byte("not json"), time.Minute)
+	if GetJSON(ctx, "roles:1", &out) {
+		t.Error("invalid entry returned a hit")
+	}
+}
+
+func TestMemoryExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	mem := NewMemory()
+	mem.now = func() time.Time { return now }
+
+	_ = mem.Set(ctx, "k", []byte("v"), time.Second)
+	if _, ok, _ := mem.Get(ctx, "k"); !ok {
+		t.Fatal("entry missing before expiry")
+	}
+	now = now.Add(time.Second)
+	if _, ok, _ := mem.Get(ctx, "k"); ok {
+		t.Error("entry returned after expiry")
+	}
+}
+
+func TestNewRedisFromEnv(t *testing.T) {
+	t.Setenv("REDIS_URL", "")
+	r, err := NewRedisFromEnv(context.Background())
+	if err != nil || r != nil {
+		t.Fatalf("NewRedisFromEnv() = %v, %v; want nil, nil when unset", r, err)
+	}
+
+	t.Setenv("REDIS_URL", "http://localhost:6379")
+	if _, err := NewRedisFromEnv(context.Background()); err == nil {
+		t.Error("expected an error for a non-redis URL")
+	}
+}
@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory is an in-process store, for tests. It isn't shared between instances, so
+// invalidations on one instance don't reach another.
+type Memory struct {
+	entries map[string]memoryEntry
+	now     func() time.Time
+	mu      sync.Mutex
+}
+
+type memoryEntry struct {
+	expires time.Time
+	value   []byte
+}
+
+// NewMemory creates an empty in-process store
+func NewMemory() *Memory {
+	return &Memory{entries: make(map[string]memoryEntry), now: time.Now}
+}
+
+// Get returns the value stored under key unless it expired
+func (m *Memory) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if !m.now().Before(entry.expires) {
+		delete(m.entries, key)
+		return nil, false, nil
+	}
+	return entry.value, true, nil
+}
+
+// Set stores value under key for ttl
+func (m *Memory) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = memoryEntry{expires: m.now().Add(ttl), value: append([]byte(nil), value...)}
+	return nil
+}
+
+// Delete removes keys
+func (m *Memory) Delete(_ context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		delete(m.entries, key)
+	}
+	return nil
+}
+
+// Close is a no-op
+func (m *Memory) Close() error { return nil }
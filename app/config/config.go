@@ -0,0 +1,166 @@
+// Package config reloads selected settings without restarting the server. Settings are
+// read from the environment; when CONFIG_FILE names a file of KEY=VALUE lines (the
+// .env format), its values override the environment at startup and are read again on
+// every reload, so editing the file and sending SIGHUP applies them.
+package config
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Result describes a reload
+type Result struct {
+	ReloadedAt time.Time `json:"reloadedAt"`
+	// Failed maps settings that kept their previous values to the reason
+	Failed map[string]string `json:"failed,omitempty"`
+	// File is the configuration file that was read, if any
+	File    string   `json:"file,omitempty"`
+	Applied []string `json:"applied"`
+}
+
+type reloader struct {
+	apply func() error
+	name  string
+}
+
+var (
+	reloaders []reloader
+	// reloadMu serializes reloads, from SIGHUP and the admin endpoint alike
+	reloadMu sync.Mutex
+)
+
+// Register adds a setting that is re-read from the environment on every reload. apply
+// should leave the current value in place and return an error if the new one is invalid.
+func Register(name string, apply func() error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	reloaders = append(reloaders, reloader{name: name, apply: apply})
+}
+
+// LoadFile sets the variables in CONFIG_FILE in the process environment. It does
+// nothing when CONFIG_FILE isn't set.
+func LoadFile() error {
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		return nil
+	}
+	vars, err := readFile(path)
+	if err != nil {
+		return err
+	}
+	for key, value := range vars {
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("config: set %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// Reload reads CONFIG_FILE again and applies every registered setting. If the file
+// can't be read nothing changes; a setting with an invalid value keeps its previous one
+// and is reported in Result.Failed.
+func Reload() (*Result, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	if err := LoadFile(); err != nil {
+		return nil, err
+	}
+
+	result := &Result{ReloadedAt: time.Now(), File: os.Getenv("CONFIG_FILE"), Applied: make([]string, 0, len(reloaders))}
+	for _, r := range reloaders {
+		if err := r.apply(); err != nil {
+			if result.Failed == nil {
+				result.Failed = make(map[string]string)
+			}
+			result.Failed[r.name] = err.Error()
+			log.Printf("Config reload: keeping previous %s: %v", r.name, err)
+			continue
+		}
+		result.Applied = append(result.Applied, r.name)
+	}
+	log.Printf("Config reloaded: %s", strings.Join(result.Applied, ", "))
+	return result, nil
+}
+
+// ReloadOnSIGHUP reloads the configuration whenever the process receives SIGHUP,
+// until ctx is done
+func ReloadOnSIGHUP(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			if _, err := Reload(); err != nil {
+				log.Printf("Config reload failed, keeping the current settings: %v", err)
+			}
+		}
+	}
+}
+
+// readFile parses KEY=VALUE lines, skipping blank lines and # comments. Values may be
+// wrapped in single or double quotes, and lines may start with "export ".
+func readFile(path string) (map[string]string, error) {
+	file, err := os.Open(path) // #nosec G304 -- path supplied by the operator
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			log.Printf("error closing config file: %v", closeErr)
+		}
+	}()
+
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("config: %s:%d: expected KEY=VALUE", path, lineNo)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		vars[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("config: %s: %w", path, err)
+	}
+	return vars, nil
+}
+
+// PositiveInt returns the positive integer in environment variable name, or def when
+// it's unset. Returns an error for any other value.
+func PositiveInt(name string, def int) (int, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		return 0, errors.New(name + " must be a positive integer")
+	}
+	return n, nil
+}
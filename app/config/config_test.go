@@ -0,0 +1,82 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snippy.env")
+	content := "# Rate limits\nRATE_LIMIT_RPS=50\n\nexport LOG_LEVEL=warn\nCORS_ALLOWED_ORIGINS=\"https://a.example,https://b.example\"\nEMPTY=\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	vars, err := readFile(path)
+	if err != nil {
+		t.Fatalf("readFile: %v", err)
+	}
+	want := map[string]string{
+		"RATE_LIMIT_RPS":       "50",
+		"LOG_LEVEL":            "warn",
+		"CORS_ALLOWED_ORIGINS": "https://a.example,https://b.example",
+		"EMPTY":                "",
+	}
+	if len(vars) != len(want) {
+		t.Errorf("vars = %v, want %v", vars, want)
+	}
+	for key, value := range want {
+		if vars[key] != value {
+			t.Errorf("%s = %q, want %q", key, vars[key], value)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte("NOT A SETTING\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readFile(path); err == nil {
+		t.Error("readFile accepted a line without =")
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snippy.env")
+	if err := os.WriteFile(path, []byte("RELOAD_TEST_VALUE=2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("RELOAD_TEST_VALUE", "1")
+
+	saved := reloaders
+	defer func() { reloaders = saved }()
+	reloaders = nil
+
+	var applied int
+	Register("value", func() error {
+		n, err := PositiveInt("RELOAD_TEST_VALUE", 1)
+		if err != nil {
+			return err
+		}
+		applied = n
+		return nil
+	})
+	Register("broken", func() error { return errors.New("invalid") })
+
+	result, err := Reload()
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if applied != 2 {
+		t.Errorf("applied value = %d, want 2 from the file", applied)
+	}
+	if len(result.Applied) != 1 || result.Applied[0] != "value" || result.Failed["broken"] == "" {
+		t.Errorf("result = %+v", result)
+	}
+
+	t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.env"))
+	if _, err := Reload(); err == nil {
+		t.Error("Reload succeeded with a missing file")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jheysaaz/snippy-backend/app/snippetquery"
 	"github.com/lib/pq"
 )
 
@@ -103,7 +104,7 @@ func TestInitDatabase(t *testing.T) {
 	}
 
 	// Verify indexes exist (NEW schema)
-	indexes := []string{"idx_snippets_created_at", "idx_snippets_shortcut", "idx_snippets_tags", "idx_snippets_search"}
+	indexes := []string{"idx_snippets_created_at", "idx_snippets_shortcut", "idx_snippets_tags", SearchIndexName(snippetquery.Language())}
 	for _, idx := range indexes {
 		var idxExists bool
 		err = testDB.QueryRow(`
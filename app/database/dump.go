@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/lib/pq"
+)
+
+// dumpLockID is the advisory lock held while a dump runs, so only one instance backs up at a time
+const dumpLockID = 7_104_220_017
+
+// ErrDumpInProgress is returned by Dump when another connection holds the dump lock
+var ErrDumpInProgress = errors.New("database dump already in progress")
+
+// DumpStats counts what a dump wrote
+type DumpStats struct {
+	Tables int   `json:"tables"`
+	Rows   int64 `json:"rows"`
+}
+
+// Dump writes every table in the public schema as newline-delimited JSON, one row per
+// line, to the writer open returns for the table. All tables are read from a single
+// read-only snapshot.
+func Dump(ctx context.Context, open func(table string) (io.Writer, error)) (DumpStats, error) {
+	var stats DumpStats
+
+	tx, err := DB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return stats, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("error rolling back dump transaction: %v", rbErr)
+		}
+	}()
+
+	var locked bool
+	if err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, dumpLockID).Scan(&locked); err != nil {
+		return stats, err
+	}
+	if !locked {
+		return stats, ErrDumpInProgress
+	}
+
+	tables, err := dumpTables(ctx, tx)
+	if err != nil {
+		return stats, err
+	}
+
+	for _, table := range tables {
+		w, err := open(table)
+		if err != nil {
+			return stats, err
+		}
+		n, err := dumpTable(ctx, tx, table, w)
+		stats.Rows += n
+		if err != nil {
+			return stats, fmt.Errorf("dump %s: %w", table, err)
+		}
+		stats.Tables++
+	}
+	return stats, nil
+}
+
+// dumpTables lists the public schema's tables in name order
+func dumpTables(ctx context.Context, tx *sql.Tx) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT tablename FROM pg_tables WHERE schemaname = 'public' ORDER BY tablename`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Printf("error closing table list rows: %v", closeErr)
+		}
+	}()
+
+	tables := make([]string, 0, 32)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+// dumpTable writes one JSON object per row of table to w
+func dumpTable(ctx context.Context, tx *sql.Tx, table string, w io.Writer) (int64, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT row_to_json(t)::text FROM `+pq.QuoteIdentifier(table)+` t`)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Printf("error closing %s dump rows: %v", table, closeErr)
+		}
+	}()
+
+	var n int64
+	var line []byte
+	for rows.Next() {
+		if err := rows.Scan(&line); err != nil {
+			return n, err
+		}
+		if _, err := w.Write(append(line, '\n')); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, rows.Err()
+}
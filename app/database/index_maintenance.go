@@ -0,0 +1,120 @@
+// Package database contains index maintenance utilities.
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// HotTables are the tables whose planner statistics matter most for search
+// and sync performance. They are re-analyzed on every maintenance run.
+var HotTables = []string{"snippets", "snippet_history", "sessions", "refresh_tokens", "users"}
+
+// bloatDeadTupleRatio is the share of dead tuples in a table above which its
+// indexes are reported as likely bloated.
+const bloatDeadTupleRatio = 0.2
+
+// IndexStat describes usage and health information for a single index
+type IndexStat struct {
+	Table          string  `json:"table"`
+	Index          string  `json:"index"`
+	Scans          int64   `json:"scans"`
+	SizeBytes      int64   `json:"sizeBytes"`
+	DeadTupleRatio float64 `json:"deadTupleRatio"`
+	Unique         bool    `json:"unique"`
+	Unused         bool    `json:"unused"`
+	Bloated        bool    `json:"bloated"`
+}
+
+// MaintenanceRun records the outcome of the last index maintenance run
+type MaintenanceRun struct {
+	RanAt          time.Time `json:"ranAt"`
+	Error          string    `json:"error,omitempty"`
+	AnalyzedTables []string  `json:"analyzedTables"`
+	Duration       string    `json:"duration"`
+}
+
+var (
+	lastMaintenance   *MaintenanceRun
+	lastMaintenanceMu sync.RWMutex
+)
+
+// LastMaintenanceRun returns the most recent maintenance run, or nil if none has run yet.
+func LastMaintenanceRun() *MaintenanceRun {
+	lastMaintenanceMu.RLock()
+	defer lastMaintenanceMu.RUnlock()
+	return lastMaintenance
+}
+
+// RunIndexMaintenance runs ANALYZE on the hot tables and records the result.
+// Failures on one table don't stop the others from being analyzed.
+func RunIndexMaintenance(ctx context.Context) error {
+	start := time.Now()
+	run := &MaintenanceRun{RanAt: start, AnalyzedTables: make([]string, 0, len(HotTables))}
+
+	var firstErr error
+	for _, table := range HotTables {
+		if _, err := DB.ExecContext(ctx, "ANALYZE "+pq.QuoteIdentifier(table)); err != nil {
+			log.Printf("Error analyzing table %s: %v", table, err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("analyze %s: %w", table, err)
+			}
+			continue
+		}
+		run.AnalyzedTables = append(run.AnalyzedTables, table)
+	}
+
+	run.Duration = time.Since(start).String()
+	if firstErr != nil {
+		run.Error = firstErr.Error()
+	}
+
+	lastMaintenanceMu.Lock()
+	lastMaintenance = run
+	lastMaintenanceMu.Unlock()
+
+	log.Printf("Index maintenance analyzed %d tables in %s", len(run.AnalyzedTables), run.Duration)
+	return firstErr
+}
+
+// GetIndexStats reports scan counts, sizes and bloat indicators for all user indexes.
+// Primary keys and unique indexes are never reported as unused since they enforce constraints.
+func GetIndexStats(ctx context.Context) ([]IndexStat, error) {
+	query := `
+		SELECT s.relname, s.indexrelname, s.idx_scan, pg_relation_size(s.indexrelid),
+		       (i.indisunique OR i.indisprimary),
+		       COALESCE(t.n_dead_tup::float8 / NULLIF(t.n_live_tup + t.n_dead_tup, 0), 0)
+		FROM pg_stat_user_indexes s
+		JOIN pg_index i ON i.indexrelid = s.indexrelid
+		JOIN pg_stat_user_tables t ON t.relid = s.relid
+		ORDER BY pg_relation_size(s.indexrelid) DESC
+	`
+
+	rows, err := DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Printf("error closing index stats rows: %v", closeErr)
+		}
+	}()
+
+	stats := make([]IndexStat, 0)
+	for rows.Next() {
+		var st IndexStat
+		if err := rows.Scan(&st.Table, &st.Index, &st.Scans, &st.SizeBytes, &st.Unique, &st.DeadTupleRatio); err != nil {
+			return nil, err
+		}
+		st.Unused = st.Scans == 0 && !st.Unique
+		st.Bloated = st.DeadTupleRatio >= bloatDeadTupleRatio
+		stats = append(stats, st)
+	}
+
+	return stats, rows.Err()
+}
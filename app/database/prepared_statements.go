@@ -12,6 +12,8 @@ import (
 type PreparedStatements struct {
 	// Ownership check - used in update, delete, history, restore endpoints
 	SnippetOwnership *sql.Stmt
+	// db is the connection the statements were prepared on
+	db *sql.DB
 }
 
 var (
@@ -46,7 +48,7 @@ func initPreparedStatements() (*PreparedStatements, error) {
 	}
 
 	ctx := context.Background()
-	stmts := &PreparedStatements{}
+	stmts := &PreparedStatements{db: DB}
 
 	var err error
 
@@ -62,16 +64,17 @@ func initPreparedStatements() (*PreparedStatements, error) {
 	return stmts, nil
 }
 
-// CheckSnippetOwnership checks if a snippet exists and returns its owner.
-// Uses prepared statement for better performance on repeated calls.
-func CheckSnippetOwnership(ctx context.Context, snippetID int64) (ownerID sql.NullString, err error) {
+// CheckSnippetOwnership checks if a snippet exists in db and returns its owner.
+// Uses prepared statement for better performance on repeated calls when they were
+// prepared on db.
+func CheckSnippetOwnership(ctx context.Context, db *sql.DB, snippetID int64) (ownerID sql.NullString, err error) {
 	stmts := GetPreparedStatements()
-	if stmts != nil && stmts.SnippetOwnership != nil {
+	if stmts != nil && stmts.db == db && stmts.SnippetOwnership != nil {
 		// Use prepared statement
 		err = stmts.SnippetOwnership.QueryRowContext(ctx, snippetID).Scan(&ownerID)
 	} else {
 		// Fallback to non-prepared query
-		err = DB.QueryRowContext(ctx, `SELECT user_id FROM snippets WHERE id = $1`, snippetID).Scan(&ownerID)
+		err = db.QueryRowContext(ctx, `SELECT user_id FROM snippets WHERE id = $1`, snippetID).Scan(&ownerID)
 	}
 	return
 }
@@ -0,0 +1,296 @@
+// Package database provides slow query plan logging for debugging index usage.
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// defaultSlowQueryThreshold is used when DB_DEBUG is enabled without DB_SLOW_QUERY_THRESHOLD
+const defaultSlowQueryThreshold = 100 * time.Millisecond
+
+// planLogCooldown limits how often the plan for the same query text is logged
+const planLogCooldown = time.Minute
+
+// explainTimeout bounds the EXPLAIN round-trip so debugging never piles up work
+const explainTimeout = 5 * time.Second
+
+// slowQueryThreshold returns the latency above which query plans are logged.
+// Plan logging is enabled with DB_DEBUG=true; zero means disabled.
+func slowQueryThreshold() time.Duration {
+	if os.Getenv("DB_DEBUG") != "true" {
+		return 0
+	}
+
+	raw := os.Getenv("DB_SLOW_QUERY_THRESHOLD")
+	if raw == "" {
+		return defaultSlowQueryThreshold
+	}
+
+	threshold, err := time.ParseDuration(raw)
+	if err != nil || threshold <= 0 {
+		log.Printf("Ignoring invalid DB_SLOW_QUERY_THRESHOLD %q, using %s", raw, defaultSlowQueryThreshold)
+		return defaultSlowQueryThreshold
+	}
+	return threshold
+}
+
+// explainable reports whether a statement can be safely passed to EXPLAIN.
+// EXPLAIN without ANALYZE never executes the statement, including writes.
+func explainable(query string) bool {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return false
+	}
+	switch strings.ToUpper(fields[0]) {
+	case "SELECT", "WITH", "UPDATE", "DELETE", "INSERT":
+		return true
+	default:
+		return false
+	}
+}
+
+// planLogger runs EXPLAIN for slow statements on a separate, unwrapped pool
+type planLogger struct {
+	lastPruned time.Time
+	db         *sql.DB
+	lastSeen   map[string]time.Time
+	threshold  time.Duration
+	mu         sync.Mutex
+}
+
+// observe logs the plan for a statement when it exceeded the threshold
+func (pl *planLogger) observe(query string, args []driver.NamedValue, elapsed time.Duration) {
+	if elapsed < pl.threshold || !explainable(query) || !pl.shouldLog(query) {
+		return
+	}
+
+	values := make([]interface{}, len(args))
+	for i, value := range namedValuesToValues(args) {
+		values[i] = value
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), explainTimeout)
+		defer cancel()
+
+		rows, err := pl.db.QueryContext(ctx, "EXPLAIN (ANALYZE off) "+query, values...)
+		if err != nil {
+			log.Printf("Slow query (%s), EXPLAIN failed: %v", elapsed, err)
+			return
+		}
+		defer func() {
+			if closeErr := rows.Close(); closeErr != nil {
+				log.Printf("error closing explain rows: %v", closeErr)
+			}
+		}()
+
+		var plan strings.Builder
+		for rows.Next() {
+			var line string
+			if scanErr := rows.Scan(&line); scanErr != nil {
+				log.Printf("Slow query (%s), failed to read plan: %v", elapsed, scanErr)
+				return
+			}
+			plan.WriteString("\n  ")
+			plan.WriteString(line)
+		}
+		if rowsErr := rows.Err(); rowsErr != nil {
+			log.Printf("Slow query (%s), failed to read plan: %v", elapsed, rowsErr)
+			return
+		}
+
+		log.Printf("Slow query (%s): %s\nPlan:%s", elapsed, strings.Join(strings.Fields(query), " "), plan.String())
+	}()
+}
+
+// shouldLog applies a per-query cooldown so a hot slow query doesn't flood the logs
+func (pl *planLogger) shouldLog(query string) bool {
+	sum := sha256.Sum256([]byte(query))
+	key := hex.EncodeToString(sum[:])
+
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	now := time.Now()
+	if last, ok := pl.lastSeen[key]; ok && now.Sub(last) < planLogCooldown {
+		return false
+	}
+	// Forget queries whose cooldown is over, at most once per cooldown, so distinct ad-hoc
+	// statements don't grow the map for the life of the process
+	if now.Sub(pl.lastPruned) >= planLogCooldown {
+		for seenKey, seen := range pl.lastSeen {
+			if now.Sub(seen) >= planLogCooldown {
+				delete(pl.lastSeen, seenKey)
+			}
+		}
+		pl.lastPruned = now
+	}
+	pl.lastSeen[key] = now
+	return true
+}
+
+// explainConnector wraps a driver connector so every connection reports query latency
+type explainConnector struct {
+	base   driver.Connector
+	logger *planLogger
+}
+
+func (ec *explainConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := ec.base.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &explainConn{Conn: conn, logger: ec.logger}, nil
+}
+
+func (ec *explainConnector) Driver() driver.Driver {
+	return ec.base.Driver()
+}
+
+// explainConn times statements and forwards every optional driver interface
+type explainConn struct {
+	driver.Conn
+	logger *planLogger
+}
+
+func (c *explainConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	if err == nil {
+		c.logger.observe(query, args, time.Since(start))
+	}
+	return rows, err
+}
+
+func (c *explainConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	result, err := execer.ExecContext(ctx, query, args)
+	if err == nil {
+		c.logger.observe(query, args, time.Since(start))
+	}
+	return result, err
+}
+
+func (c *explainConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var (
+		stmt driver.Stmt
+		err  error
+	)
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &explainStmt{Stmt: stmt, query: query, logger: c.logger}, nil
+}
+
+func (c *explainConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin() //nolint:staticcheck // fallback for drivers without BeginTx
+}
+
+func (c *explainConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *explainConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *explainConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// explainStmt times prepared statement executions
+type explainStmt struct {
+	driver.Stmt
+	logger *planLogger
+	query  string
+}
+
+func (s *explainStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := s.Stmt.(driver.StmtQueryContext)
+	if !ok {
+		return s.Stmt.Query(namedValuesToValues(args)) //nolint:staticcheck // fallback for drivers without context support
+	}
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, args)
+	if err == nil {
+		s.logger.observe(s.query, args, time.Since(start))
+	}
+	return rows, err
+}
+
+func (s *explainStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := s.Stmt.(driver.StmtExecContext)
+	if !ok {
+		return s.Stmt.Exec(namedValuesToValues(args)) //nolint:staticcheck // fallback for drivers without context support
+	}
+	start := time.Now()
+	result, err := execer.ExecContext(ctx, args)
+	if err == nil {
+		s.logger.observe(s.query, args, time.Since(start))
+	}
+	return result, err
+}
+
+// namedValuesToValues strips names and ordinals from driver arguments
+func namedValuesToValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+// openWithPlanLogging opens the main pool through the plan-logging connector.
+// EXPLAIN statements run on a second, unwrapped pool to avoid recursion.
+func openWithPlanLogging(dbURL string, threshold time.Duration) (*sql.DB, error) {
+	connector, err := pq.NewConnector(dbURL)
+	if err != nil {
+		return nil, err
+	}
+
+	explainDB, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		return nil, err
+	}
+	explainDB.SetMaxOpenConns(2)
+
+	logger := &planLogger{db: explainDB, threshold: threshold, lastSeen: make(map[string]time.Time)}
+	log.Printf("Query plan logging enabled for statements slower than %s", threshold)
+	return sql.OpenDB(&explainConnector{base: connector, logger: logger}), nil
+}
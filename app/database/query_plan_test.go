@@ -0,0 +1,93 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestExplainable(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected bool
+	}{
+		{"SELECT id FROM snippets", true},
+		{"\n\t\tselect id from snippets", true},
+		{"WITH recent AS (SELECT 1) SELECT * FROM recent", true},
+		{"UPDATE snippets SET label = $1", true},
+		{"DELETE FROM snippets WHERE id = $1", true},
+		{"INSERT INTO snippets (label) VALUES ($1)", true},
+		{"ANALYZE snippets", false},
+		{"EXPLAIN SELECT 1", false},
+		{"-- schema\nCREATE TABLE x (id INT)", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := explainable(tt.query); got != tt.expected {
+			t.Errorf("explainable(%q) = %v, want %v", tt.query, got, tt.expected)
+		}
+	}
+}
+
+func TestSlowQueryThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		debug     string
+		threshold string
+		expected  time.Duration
+	}{
+		{"disabled by default", "", "", 0},
+		{"disabled ignores threshold", "", "50ms", 0},
+		{"enabled uses default", "true", "", defaultSlowQueryThreshold},
+		{"enabled with custom threshold", "true", "250ms", 250 * time.Millisecond},
+		{"invalid threshold falls back", "true", "soon", defaultSlowQueryThreshold},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv("DB_DEBUG", tt.debug)
+			os.Setenv("DB_SLOW_QUERY_THRESHOLD", tt.threshold)
+			defer os.Unsetenv("DB_DEBUG")
+			defer os.Unsetenv("DB_SLOW_QUERY_THRESHOLD")
+
+			if got := slowQueryThreshold(); got != tt.expected {
+				t.Errorf("slowQueryThreshold() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPlanLoggerCooldown(t *testing.T) {
+	pl := &planLogger{lastSeen: make(map[string]time.Time)}
+
+	if !pl.shouldLog("SELECT 1") {
+		t.Error("first occurrence should be logged")
+	}
+	if pl.shouldLog("SELECT 1") {
+		t.Error("repeat within cooldown should not be logged")
+	}
+	if !pl.shouldLog("SELECT 2") {
+		t.Error("different query should be logged")
+	}
+}
+
+func TestPlanLoggerPrunesExpiredEntries(t *testing.T) {
+	pl := &planLogger{lastSeen: make(map[string]time.Time)}
+	for i := 0; i < 5; i++ {
+		pl.shouldLog(fmt.Sprintf("SELECT %d", i))
+	}
+
+	// Once their cooldown is over, earlier queries are forgotten on the next insert
+	for key := range pl.lastSeen {
+		pl.lastSeen[key] = time.Now().Add(-2 * planLogCooldown)
+	}
+	pl.lastPruned = time.Now().Add(-2 * planLogCooldown)
+	if !pl.shouldLog("SELECT 'new'") {
+		t.Error("new query should be logged")
+	}
+	if len(pl.lastSeen) != 1 {
+		t.Errorf("expected only the new query to be tracked, got %d entries", len(pl.lastSeen))
+	}
+}
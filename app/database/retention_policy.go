@@ -4,35 +4,198 @@ package database
 import (
 	"context"
 	"log"
+	"os"
+	"strconv"
+	"sync"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 // RetentionPolicy defines how long to keep different types of data
 type RetentionPolicy struct {
 	SnippetVersionDays     int // Keep snippet versions for this many days
+	MaxSnippetVersions     int // Keep at most this many versions of each snippet, 0 for no limit
 	SoftDeletedSnippetDays int // Keep soft-deleted snippets for this many days
 	SoftDeletedUserDays    int // Keep soft-deleted users for this many days
 	IdleSessionDays        int // Auto-logout sessions idle for this many days
+
+	// BeforeUserPurge, when set, runs for each expired soft-deleted user before the user
+	// is permanently deleted. Returning an error keeps the user until the next run.
+	BeforeUserPurge func(ctx context.Context, userID string) error
 }
 
 // DefaultRetentionPolicy returns the default retention policy
 func DefaultRetentionPolicy() *RetentionPolicy {
 	return &RetentionPolicy{
 		SnippetVersionDays:     60, // 60 days for snippet versions
+		MaxSnippetVersions:     50, // the latest 50 versions of each snippet
 		SoftDeletedSnippetDays: 90, // 90 days for soft-deleted snippets
 		SoftDeletedUserDays:    30, // 30 days for soft-deleted users
 		IdleSessionDays:        7,  // 7 days of inactivity before auto-logout
 	}
 }
 
-// CleanupOldData removes data based on retention policy
-func CleanupOldData(policy *RetentionPolicy) error {
+// LoadRetentionPolicy returns the default retention policy with overrides
+// applied from the environment (SESSION_IDLE_DAYS, SNIPPET_VERSION_RETENTION_DAYS,
+// SNIPPET_MAX_VERSIONS, DELETED_SNIPPET_RETENTION_DAYS, DELETED_USER_RETENTION_DAYS).
+func LoadRetentionPolicy() *RetentionPolicy {
+	policy := DefaultRetentionPolicy()
+
+	envDays("SESSION_IDLE_DAYS", &policy.IdleSessionDays)
+	envDays("SNIPPET_VERSION_RETENTION_DAYS", &policy.SnippetVersionDays)
+	envCount("SNIPPET_MAX_VERSIONS", &policy.MaxSnippetVersions)
+	envDays("DELETED_SNIPPET_RETENTION_DAYS", &policy.SoftDeletedSnippetDays)
+	envDays("DELETED_USER_RETENTION_DAYS", &policy.SoftDeletedUserDays)
+
+	return policy
+}
+
+// envDays sets *days from environment variable name if it holds a positive number of days
+func envDays(name string, days *int) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		log.Printf("Ignoring invalid %s %q, using %d days", name, raw, *days)
+		return
+	}
+	*days = n
+}
+
+// envCount sets *count from environment variable name if it holds a count, 0 included
+func envCount(name string, count *int) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		log.Printf("Ignoring invalid %s %q, using %d", name, raw, *count)
+		return
+	}
+	*count = n
+}
+
+// CleanupRun records the outcome of the last data retention cleanup
+type CleanupRun struct {
+	RanAt                    time.Time `json:"ranAt"`
+	Error                    string    `json:"error,omitempty"`
+	Duration                 string    `json:"duration"`
+	IdleSessionsLoggedOut    int64     `json:"idleSessionsLoggedOut"`
+	SnippetVersionsDeleted   int64     `json:"snippetVersionsDeleted"`
+	SnippetVersionsCompacted int64     `json:"snippetVersionsCompacted"`
+	SnippetsPurged           int64     `json:"snippetsPurged"`
+	UsersPurged              int64     `json:"usersPurged"`
+	UsersExported            int64     `json:"usersExported"`
+	UsersPurgeDeferred       int64     `json:"usersPurgeDeferred"`
+}
+
+var (
+	lastCleanup   *CleanupRun
+	lastCleanupMu sync.RWMutex
+)
+
+// LastCleanupRun returns the most recent cleanup run, or nil if none has run yet.
+func LastCleanupRun() *CleanupRun {
+	lastCleanupMu.RLock()
+	defer lastCleanupMu.RUnlock()
+	return lastCleanup
+}
+
+// LogoutIdleSessions marks sessions inactive when their last activity is older
+// than idleDays and records each forced logout in the audit log and as a
+// session.ended event. The interval is bound as a parameter via make_interval.
+func LogoutIdleSessions(ctx context.Context, idleDays int) (int64, error) {
+	var loggedOut int64
+	err := DB.QueryRowContext(ctx, `
+		WITH logged_out AS (
+			UPDATE sessions
+			SET active = false, logged_out_at = NOW()
+			WHERE active = true AND last_activity < NOW() - make_interval(days => $1)
+			RETURNING id, user_id
+		), audited AS (
+			INSERT INTO audit_log (action, target_type, target_id, target_user_id, details)
+			SELECT 'session.idle_logged_out', 'session', id::text, user_id, jsonb_build_object('idleDays', $1::int)
+			FROM logged_out
+		), evt AS (
+			INSERT INTO outbox_events (event_type, aggregate_type, aggregate_id, payload)
+			SELECT 'session.ended', 'session', id::text, jsonb_build_object('sessionId', id, 'userId', user_id, 'reason', 'idle')
+			FROM logged_out
+		)
+		SELECT COUNT(*) FROM logged_out
+	`, idleDays).Scan(&loggedOut)
+	return loggedOut, err
+}
+
+// keptHistorySnippets selects the snippets whose versions retention keeps: those with
+// keep_history set whose owner still holds the premium or admin role
+const keptHistorySnippets = `
+	SELECT s.id FROM snippets s
+	JOIN user_roles ur ON ur.user_id = s.user_id
+	JOIN roles r ON r.id = ur.role_id
+	WHERE s.keep_history AND r.name IN ('premium', 'admin')
+`
+
+// CompactSnippetVersions deletes all but the latest maxVersions versions of each snippet
+// not kept forever and notifies each affected owner. It returns how many were deleted.
+func CompactSnippetVersions(ctx context.Context, maxVersions int) (int64, error) {
+	var compacted int64
+	err := DB.QueryRowContext(ctx, `
+		WITH ranked AS (
+			SELECT id, ROW_NUMBER() OVER (PARTITION BY snippet_id ORDER BY version_number DESC) AS rank
+			FROM snippet_history
+			WHERE snippet_id NOT IN (`+keptHistorySnippets+`)
+		), deleted AS (
+			DELETE FROM snippet_history h
+			USING ranked
+			WHERE h.id = ranked.id AND ranked.rank > $1
+			RETURNING h.snippet_id
+		), per_user AS (
+			SELECT s.user_id, COUNT(*) AS versions, COUNT(DISTINCT s.id) AS snippets
+			FROM deleted d
+			JOIN snippets s ON s.id = d.snippet_id
+			WHERE s.user_id IS NOT NULL AND s.is_deleted = false
+			GROUP BY s.user_id
+		), notified AS (
+			INSERT INTO notifications (user_id, type, title, body, data)
+			SELECT user_id, 'history_trimmed', 'Version history trimmed',
+			       versions || ' snippet versions beyond the latest ' || $1::int || ' were removed from ' || snippets || ' snippets.',
+			       jsonb_build_object('versionsDeleted', versions, 'snippets', snippets, 'maxVersions', $1::int)
+			FROM per_user
+		)
+		SELECT COUNT(*) FROM deleted
+	`, maxVersions).Scan(&compacted)
+	return compacted, err
+}
+
+// CleanupOldData removes data based on retention policy and records the run.
+// A run stopped by ctx leaves the remaining steps for the next run.
+func CleanupOldData(ctx context.Context, policy *RetentionPolicy) error {
 	if policy == nil {
 		policy = DefaultRetentionPolicy()
 	}
 
-	ctx := context.Background()
+	start := time.Now()
+	run := &CleanupRun{RanAt: start}
+	err := cleanupOldData(ctx, policy, run)
+
+	run.Duration = time.Since(start).String()
+	if err != nil {
+		run.Error = err.Error()
+	}
+
+	lastCleanupMu.Lock()
+	lastCleanup = run
+	lastCleanupMu.Unlock()
+
+	return err
+}
 
+// cleanupOldData performs the cleanup steps, filling in run counters as it goes
+func cleanupOldData(ctx context.Context, policy *RetentionPolicy, run *CleanupRun) error {
 	// Calculate cutoff dates
 	versionCutoff := time.Now().AddDate(0, 0, -policy.SnippetVersionDays)
 	snippetCutoff := time.Now().AddDate(0, 0, -policy.SoftDeletedSnippetDays)
@@ -40,21 +203,12 @@ func CleanupOldData(policy *RetentionPolicy) error {
 
 	// 0. Auto-logout idle sessions
 	log.Printf("Logging out sessions idle for more than %d days", policy.IdleSessionDays)
-	result, err := DB.ExecContext(ctx, `
-		UPDATE sessions 
-		SET active = false, logged_out_at = NOW()
-		WHERE active = true AND last_activity < NOW() - INTERVAL '%d days'
-	`, policy.IdleSessionDays)
-	if err != nil {
+	if loggedOut, err := LogoutIdleSessions(ctx, policy.IdleSessionDays); err != nil {
 		log.Printf("Error logging out idle sessions: %v", err)
 		// Don't return error, continue with other cleanup
 	} else {
-		rowsAffected, errRows := result.RowsAffected()
-		if errRows != nil {
-			log.Printf("Error getting rows affected: %v", errRows)
-		} else {
-			log.Printf("Logged out %d idle sessions", rowsAffected)
-		}
+		run.IdleSessionsLoggedOut = loggedOut
+		log.Printf("Logged out %d idle sessions", loggedOut)
 	}
 
 	// 0.1 Cleanup expired and old revoked refresh tokens
@@ -66,28 +220,118 @@ func CleanupOldData(policy *RetentionPolicy) error {
 		log.Printf("Error cleaning up expired/revoked refresh tokens: %v", cleanupErr)
 	}
 
-	// 1. Delete old snippet versions (older than 60 days)
+	// 0.2 Cleanup delivered outbox events (kept for a week for debugging)
+	if _, cleanupErr := DB.ExecContext(ctx, `
+		DELETE FROM outbox_events
+		WHERE delivered_at IS NOT NULL AND delivered_at < NOW() - INTERVAL '7 days'
+	`); cleanupErr != nil {
+		log.Printf("Error cleaning up delivered outbox events: %v", cleanupErr)
+	}
+
+	// 0.3 Cleanup login history older than 90 days
+	if _, cleanupErr := DB.ExecContext(ctx, `
+		DELETE FROM login_events
+		WHERE created_at < NOW() - INTERVAL '90 days'
+	`); cleanupErr != nil {
+		log.Printf("Error cleaning up old login events: %v", cleanupErr)
+	}
+
+	// 0.4 Cleanup username history past the reuse grace period
+	if _, cleanupErr := DB.ExecContext(ctx, `
+		DELETE FROM username_history
+		WHERE changed_at < NOW() - INTERVAL '30 days'
+	`); cleanupErr != nil {
+		log.Printf("Error cleaning up old username history: %v", cleanupErr)
+	}
+
+	// 0.5 Cleanup snippet usage older than 90 days (the weekly digest only reads the last week)
+	if _, cleanupErr := DB.ExecContext(ctx, `
+		DELETE FROM snippet_usage
+		WHERE used_at < NOW() - INTERVAL '90 days'
+	`); cleanupErr != nil {
+		log.Printf("Error cleaning up old snippet usage: %v", cleanupErr)
+	}
+
+	// 0.6 Cleanup push device tokens not re-registered in 90 days (apps refresh them on launch)
+	if _, cleanupErr := DB.ExecContext(ctx, `
+		DELETE FROM device_tokens
+		WHERE last_seen_at < NOW() - INTERVAL '90 days'
+	`); cleanupErr != nil {
+		log.Printf("Error cleaning up stale device tokens: %v", cleanupErr)
+	}
+
+	// 0.7 Cleanup read notifications older than 90 days
+	if _, cleanupErr := DB.ExecContext(ctx, `
+		DELETE FROM notifications
+		WHERE read_at IS NOT NULL AND created_at < NOW() - INTERVAL '90 days'
+	`); cleanupErr != nil {
+		log.Printf("Error cleaning up old notifications: %v", cleanupErr)
+	}
+
+	// 0.8 Cleanup expired pre-purge account exports
+	if _, cleanupErr := DB.ExecContext(ctx, `
+		DELETE FROM account_exports
+		WHERE expires_at < NOW()
+	`); cleanupErr != nil {
+		log.Printf("Error cleaning up expired account exports: %v", cleanupErr)
+	}
+
+	// 0.9 Cleanup finished webhook deliveries older than 30 days
+	if _, cleanupErr := DB.ExecContext(ctx, `
+		DELETE FROM webhook_deliveries
+		WHERE status IN ('succeeded', 'failed') AND created_at < NOW() - INTERVAL '30 days'
+	`); cleanupErr != nil {
+		log.Printf("Error cleaning up old webhook deliveries: %v", cleanupErr)
+	}
+
+	// 1. Delete old snippet versions (older than 60 days), except those kept forever, and
+	// notify each affected owner
 	log.Printf("Deleting snippet versions older than %v", versionCutoff)
-	result, err = DB.ExecContext(ctx, `
-		DELETE FROM snippet_history
-		WHERE changed_at < $1
-	`, versionCutoff)
+	var versionsDeleted int64
+	err := DB.QueryRowContext(ctx, `
+		WITH deleted AS (
+			DELETE FROM snippet_history
+			WHERE changed_at < $1 AND snippet_id NOT IN (`+keptHistorySnippets+`)
+			RETURNING snippet_id
+		), per_user AS (
+			SELECT s.user_id, COUNT(*) AS versions, COUNT(DISTINCT s.id) AS snippets
+			FROM deleted d
+			JOIN snippets s ON s.id = d.snippet_id
+			WHERE s.user_id IS NOT NULL AND s.is_deleted = false
+			GROUP BY s.user_id
+		), notified AS (
+			INSERT INTO notifications (user_id, type, title, body, data)
+			SELECT user_id, 'history_trimmed', 'Version history trimmed',
+			       versions || ' snippet versions older than ' || $2::int || ' days were removed from ' || snippets || ' snippets.',
+			       jsonb_build_object('versionsDeleted', versions, 'snippets', snippets, 'retentionDays', $2::int)
+			FROM per_user
+		)
+		SELECT COUNT(*) FROM deleted
+	`, versionCutoff, policy.SnippetVersionDays).Scan(&versionsDeleted)
 	if err != nil {
 		log.Printf("Error deleting old snippet versions: %v", err)
 		return err
 	}
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		log.Printf("Error getting rows affected: %v", err)
-	} else {
-		log.Printf("Deleted %d old snippet versions", rowsAffected)
+	run.SnippetVersionsDeleted = versionsDeleted
+	log.Printf("Deleted %d old snippet versions", versionsDeleted)
+
+	// 1.1 Compact each snippet's history down to its latest versions
+	if policy.MaxSnippetVersions > 0 {
+		log.Printf("Compacting snippet history to the latest %d versions", policy.MaxSnippetVersions)
+		compacted, err := CompactSnippetVersions(ctx, policy.MaxSnippetVersions)
+		if err != nil {
+			log.Printf("Error compacting snippet versions: %v", err)
+			return err
+		}
+		run.SnippetVersionsCompacted = compacted
+		log.Printf("Compacted away %d snippet versions", compacted)
 	}
 
 	// 2. Permanently delete soft-deleted snippets and their history (older than 90 days)
 	log.Printf("Deleting soft-deleted snippets older than %v", snippetCutoff)
 
 	// First delete their history
-	result, err = DB.ExecContext(ctx, `
+	result, err := DB.ExecContext(ctx, `
 		DELETE FROM snippet_history
 		WHERE snippet_id IN (
 			SELECT id FROM snippets WHERE is_deleted = true AND deleted_at < $1
@@ -116,20 +360,28 @@ func CleanupOldData(policy *RetentionPolicy) error {
 	if err != nil {
 		log.Printf("Error getting rows affected: %v", err)
 	} else {
+		run.SnippetsPurged = snippetsDeleted
 		log.Printf("Deleted %d old soft-deleted snippets and %d associated history entries", snippetsDeleted, historyDeleted)
 	}
 
 	// 3. Permanently delete soft-deleted users and all their associated data (older than configured days)
 	log.Printf("Deleting soft-deleted users older than %v", userCutoff)
 
+	// Run the pre-purge hook (final account export) first; users it fails for are kept
+	deferred, err := runBeforeUserPurge(ctx, policy, userCutoff, run)
+	if err != nil {
+		log.Printf("Error preparing expired users for purge: %v", err)
+		return err
+	}
+
 	// Use batch delete with CASCADE for efficiency instead of per-user loop
 	// Foreign keys with ON DELETE CASCADE handle snippet_history, snippets, sessions, refresh_tokens automatically
 
 	// First, cleanup sessions and tokens for users being deleted (sessions -> refresh_tokens cascade)
 	result, err = DB.ExecContext(ctx, `
 		DELETE FROM sessions
-		WHERE user_id IN (SELECT id FROM users WHERE is_deleted = true AND deleted_at < $1)
-	`, userCutoff)
+		WHERE user_id IN (SELECT id FROM users WHERE is_deleted = true AND deleted_at < $1 AND NOT (id = ANY($2)))
+	`, userCutoff, pq.Array(deferred))
 	if err != nil {
 		log.Printf("Error batch deleting sessions for expired users: %v", err)
 		// Continue with user deletion anyway
@@ -142,8 +394,8 @@ func CleanupOldData(policy *RetentionPolicy) error {
 	// Delete user roles
 	result, err = DB.ExecContext(ctx, `
 		DELETE FROM user_roles
-		WHERE user_id IN (SELECT id FROM users WHERE is_deleted = true AND deleted_at < $1)
-	`, userCutoff)
+		WHERE user_id IN (SELECT id FROM users WHERE is_deleted = true AND deleted_at < $1 AND NOT (id = ANY($2)))
+	`, userCutoff, pq.Array(deferred))
 	if err != nil {
 		log.Printf("Error batch deleting user_roles for expired users: %v", err)
 	} else {
@@ -152,18 +404,69 @@ func CleanupOldData(policy *RetentionPolicy) error {
 		}
 	}
 
-	// Now delete users (CASCADE will handle snippets -> snippet_history)
-	result, err = DB.ExecContext(ctx, `
-		DELETE FROM users WHERE is_deleted = true AND deleted_at < $1
-	`, userCutoff)
+	// Now delete users (CASCADE will handle snippets -> snippet_history), auditing each purge
+	var usersDeleted int64
+	err = DB.QueryRowContext(ctx, `
+		WITH purged AS (
+			DELETE FROM users WHERE is_deleted = true AND deleted_at < $1 AND NOT (id = ANY($2))
+			RETURNING id, username, deleted_at
+		), audited AS (
+			INSERT INTO audit_log (action, target_type, target_id, target_user_id, details)
+			SELECT 'account.purged', 'user', id::text, id,
+			       jsonb_build_object('username', username, 'deletedAt', deleted_at, 'retentionDays', $3::int)
+			FROM purged
+		)
+		SELECT COUNT(*) FROM purged
+	`, userCutoff, pq.Array(deferred), policy.SoftDeletedUserDays).Scan(&usersDeleted)
 	if err != nil {
 		log.Printf("Error batch deleting expired users: %v", err)
 		return err
 	}
-	if usersDeleted, rowErr := result.RowsAffected(); rowErr == nil {
-		log.Printf("Batch deleted %d expired soft-deleted users (with cascaded data)", usersDeleted)
-	}
+	run.UsersPurged = usersDeleted
+	log.Printf("Batch deleted %d expired soft-deleted users (with cascaded data)", usersDeleted)
 
 	log.Println("Data cleanup completed successfully")
 	return nil
 }
+
+// runBeforeUserPurge calls policy.BeforeUserPurge for each user about to be purged and
+// returns the IDs of users whose hook failed, which must not be deleted this run.
+func runBeforeUserPurge(ctx context.Context, policy *RetentionPolicy, userCutoff time.Time, run *CleanupRun) ([]string, error) {
+	deferred := make([]string, 0)
+	if policy.BeforeUserPurge == nil {
+		return deferred, nil
+	}
+
+	rows, err := DB.QueryContext(ctx, `
+		SELECT id FROM users WHERE is_deleted = true AND deleted_at < $1
+	`, userCutoff)
+	if err != nil {
+		return nil, err
+	}
+	userIDs := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, id := range userIDs {
+		if hookErr := policy.BeforeUserPurge(ctx, id); hookErr != nil {
+			log.Printf("Keeping user %s until next cleanup, pre-purge hook failed: %v", id, hookErr)
+			deferred = append(deferred, id)
+			continue
+		}
+		run.UsersExported++
+	}
+	run.UsersPurgeDeferred = int64(len(deferred))
+	return deferred, nil
+}
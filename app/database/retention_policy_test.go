@@ -0,0 +1,75 @@
+package database
+
+import (
+	"os"
+	"testing"
+)
+
+func TestLoadRetentionPolicy(t *testing.T) {
+	defaults := DefaultRetentionPolicy()
+
+	tests := []struct {
+		name     string
+		envValue string
+		expected int
+	}{
+		{"unset uses default", "", defaults.IdleSessionDays},
+		{"valid override", "14", 14},
+		{"non-numeric ignored", "two-weeks", defaults.IdleSessionDays},
+		{"zero ignored", "0", defaults.IdleSessionDays},
+		{"negative ignored", "-3", defaults.IdleSessionDays},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.envValue == "" {
+				os.Unsetenv("SESSION_IDLE_DAYS")
+			} else {
+				os.Setenv("SESSION_IDLE_DAYS", tt.envValue)
+			}
+			defer os.Unsetenv("SESSION_IDLE_DAYS")
+
+			policy := LoadRetentionPolicy()
+			if policy.IdleSessionDays != tt.expected {
+				t.Errorf("IdleSessionDays = %d, want %d", policy.IdleSessionDays, tt.expected)
+			}
+			if policy.SnippetVersionDays != defaults.SnippetVersionDays {
+				t.Errorf("SnippetVersionDays = %d, want %d", policy.SnippetVersionDays, defaults.SnippetVersionDays)
+			}
+		})
+	}
+}
+
+func TestLoadRetentionPolicyWindows(t *testing.T) {
+	t.Setenv("SNIPPET_VERSION_RETENTION_DAYS", "30")
+	t.Setenv("DELETED_SNIPPET_RETENTION_DAYS", "180")
+	t.Setenv("DELETED_USER_RETENTION_DAYS", "soon")
+
+	policy := LoadRetentionPolicy()
+	if policy.SnippetVersionDays != 30 || policy.SoftDeletedSnippetDays != 180 {
+		t.Errorf("SnippetVersionDays, SoftDeletedSnippetDays = %d, %d; want 30, 180", policy.SnippetVersionDays, policy.SoftDeletedSnippetDays)
+	}
+	if want := DefaultRetentionPolicy().SoftDeletedUserDays; policy.SoftDeletedUserDays != want {
+		t.Errorf("SoftDeletedUserDays = %d, want default %d", policy.SoftDeletedUserDays, want)
+	}
+}
+
+func TestLoadRetentionPolicyMaxVersions(t *testing.T) {
+	tests := []struct {
+		envValue string
+		expected int
+	}{
+		{"", DefaultRetentionPolicy().MaxSnippetVersions},
+		{"10", 10},
+		{"0", 0},
+		{"-1", DefaultRetentionPolicy().MaxSnippetVersions},
+		{"many", DefaultRetentionPolicy().MaxSnippetVersions},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("SNIPPET_MAX_VERSIONS", tt.envValue)
+		if got := LoadRetentionPolicy().MaxSnippetVersions; got != tt.expected {
+			t.Errorf("SNIPPET_MAX_VERSIONS=%q: MaxSnippetVersions = %d, want %d", tt.envValue, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Package database provides the text search setup for the configured search language.
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jheysaaz/snippy-backend/app/snippetquery"
+)
+
+// SearchIndexName returns the name of the label and description search index for language
+func SearchIndexName(language string) string {
+	return "idx_snippets_fts_" + language
+}
+
+// labelIndexName returns the name of the label-only search index language had before
+// descriptions were searched
+func labelIndexName(language string) string {
+	return "idx_snippets_search_" + language
+}
+
+// EnsureSearchLanguage creates the unaccenting text search configuration of language
+// and the label and description index searches in it use, if they don't exist yet, and
+// drops the label-only index it replaces. Indexes of languages used before are kept, so
+// switching back doesn't rebuild them; drop them by hand.
+// language must be one snippetquery.ValidLanguage accepts; it is written into the SQL.
+func EnsureSearchLanguage(ctx context.Context, db *sql.DB, language string) error {
+	if !snippetquery.ValidLanguage(language) {
+		return fmt.Errorf("unsupported search language %q", language)
+	}
+	config := snippetquery.SearchConfig(language)
+
+	_, err := db.ExecContext(ctx, `
+	DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = '`+config+`') THEN
+			CREATE TEXT SEARCH CONFIGURATION `+config+` (COPY = pg_catalog.`+language+`);
+			ALTER TEXT SEARCH CONFIGURATION `+config+`
+				ALTER MAPPING FOR hword, hword_part, word WITH unaccent, `+snippetquery.Dictionary(language)+`;
+		END IF;
+	END
+	$$;
+
+	CREATE INDEX IF NOT EXISTS `+SearchIndexName(language)+` ON snippets USING GIN(
+		`+snippetquery.SearchVector(config)+`
+	);
+
+	DROP INDEX IF EXISTS `+labelIndexName(language)+`;
+	`)
+	return err
+}
@@ -0,0 +1,159 @@
+// Package digest emails users a weekly summary of their snippet activity.
+package digest
+
+import (
+	"context"
+	"log"
+	"time"
+
+	// Embed the time zone database so user time zones resolve in minimal containers
+	_ "time/tzdata"
+
+	"github.com/jheysaaz/snippy-backend/app/mailer"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+const (
+	// SendWeekday and SendHour are when, in the user's time zone, digests go out
+	SendWeekday = time.Monday
+	SendHour    = 9
+
+	// Period is the span of activity a digest covers
+	Period = 7 * 24 * time.Hour
+
+	// checkInterval is how often the job looks for users whose digest is due
+	checkInterval = time.Hour
+
+	// runTimeout bounds each check, including the emails it sends
+	runTimeout = 30 * time.Minute
+
+	// minResendGap guards against sending twice in the same week
+	minResendGap = 6 * 24 * time.Hour
+)
+
+// Location resolves a user's time zone, falling back to UTC for unknown names
+func Location(timezone string) *time.Location {
+	if timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// IsDue reports whether a user's digest should be sent at now: it is past SendHour on
+// SendWeekday in their time zone and no digest went out in the last six days.
+func IsDue(now time.Time, loc *time.Location, lastSent *time.Time) bool {
+	local := now.In(loc)
+	if local.Weekday() != SendWeekday || local.Hour() < SendHour {
+		return false
+	}
+	return lastSent == nil || now.Sub(*lastSent) >= minResendGap
+}
+
+type templateDevice struct {
+	Name       string
+	LastSynced string
+}
+
+// Render builds the digest email for a user, formatting dates in their time zone
+func Render(recipient models.DigestRecipient, d *models.ActivityDigest, loc *time.Location) (mailer.Message, error) {
+	devices := make([]templateDevice, 0, len(d.Devices))
+	for _, dev := range d.Devices {
+		devices = append(devices, templateDevice{
+			Name:       dev.DeviceInfo,
+			LastSynced: dev.LastSyncedAt.In(loc).Format("Mon Jan 2 15:04"),
+		})
+	}
+
+	msg, err := mailer.Render("digest", map[string]interface{}{
+		"Username": recipient.Username,
+		"Start":    d.PeriodStart.In(loc).Format("Jan 2"),
+		"End":      d.PeriodEnd.In(loc).Format("Jan 2, 2006"),
+		"Digest":   d,
+		"Devices":  devices,
+	})
+	if err != nil {
+		return mailer.Message{}, err
+	}
+	msg.To = recipient.Email
+	return msg, nil
+}
+
+// Job periodically sends weekly digests to users whose digest is due
+type Job struct {
+	store  *models.Store
+	sender mailer.Mailer
+	now    func() time.Time
+}
+
+// NewJob creates a digest job for the users in store, delivering through sender
+func NewJob(store *models.Store, sender mailer.Mailer) *Job {
+	return &Job{store: store, sender: sender, now: time.Now}
+}
+
+// Run checks for due digests every hour until ctx is cancelled
+func (j *Job) Run(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			runCtx, cancel := context.WithTimeout(ctx, runTimeout)
+			sent, err := j.RunOnce(runCtx)
+			cancel()
+			if err != nil {
+				log.Printf("Weekly digest run failed: %v", err)
+			} else if sent > 0 {
+				log.Printf("Sent %d weekly digests", sent)
+			}
+		}
+	}
+}
+
+// RunOnce sends every digest that is currently due and returns how many were sent.
+// Users with no activity in the period are marked as processed without an email.
+func (j *Job) RunOnce(ctx context.Context) (int, error) {
+	recipients, err := j.store.GetDigestRecipients(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	now := j.now()
+	sent := 0
+	for _, r := range recipients {
+		loc := Location(r.Timezone)
+		if !IsDue(now, loc, r.LastSentAt) {
+			continue
+		}
+
+		d, err := j.store.GetActivityDigest(ctx, r.UserID, now.Add(-Period), now)
+		if err != nil {
+			log.Printf("Failed to build digest for user %s: %v", r.UserID, err)
+			continue
+		}
+
+		if !d.IsEmpty() {
+			msg, err := Render(r, d, loc)
+			if err != nil {
+				log.Printf("Failed to render digest for user %s: %v", r.UserID, err)
+				continue
+			}
+			if err := j.sender.Send(ctx, msg); err != nil {
+				log.Printf("Failed to send digest to user %s: %v", r.UserID, err)
+				continue
+			}
+			sent++
+		}
+
+		if err := j.store.MarkDigestSent(ctx, r.UserID, now); err != nil {
+			log.Printf("Failed to mark digest sent for user %s: %v", r.UserID, err)
+		}
+	}
+	return sent, nil
+}
@@ -0,0 +1,92 @@
+package digest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+func TestLocation(t *testing.T) {
+	if loc := Location("America/New_York"); loc.String() != "America/New_York" {
+		t.Errorf("Expected America/New_York, got %s", loc)
+	}
+	if loc := Location("Not/A_Zone"); loc != time.UTC {
+		t.Errorf("Expected UTC fallback for unknown zone, got %s", loc)
+	}
+	if loc := Location(""); loc != time.UTC {
+		t.Errorf("Expected UTC for empty zone, got %s", loc)
+	}
+}
+
+func TestIsDue(t *testing.T) {
+	tokyo := Location("Asia/Tokyo")
+	// Monday 2024-01-08 09:30 in Tokyo is Monday 00:30 UTC
+	mondayMorningTokyo := time.Date(2024, 1, 8, 0, 30, 0, 0, time.UTC)
+	lastWeek := mondayMorningTokyo.Add(-7 * 24 * time.Hour)
+	yesterday := mondayMorningTokyo.Add(-24 * time.Hour)
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		loc      *time.Location
+		lastSent *time.Time
+		expected bool
+	}{
+		{name: "due in user time zone", now: mondayMorningTokyo, loc: tokyo, expected: true},
+		{name: "not yet due in UTC", now: mondayMorningTokyo, loc: time.UTC, expected: false},
+		{name: "sent last week", now: mondayMorningTokyo, loc: tokyo, lastSent: &lastWeek, expected: true},
+		{name: "already sent this week", now: mondayMorningTokyo, loc: tokyo, lastSent: &yesterday, expected: false},
+		{name: "wrong weekday", now: mondayMorningTokyo.Add(24 * time.Hour), loc: tokyo, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsDue(tt.now, tt.loc, tt.lastSent); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	end := time.Date(2024, 1, 8, 9, 0, 0, 0, time.UTC)
+	d := &models.ActivityDigest{
+		PeriodStart:   end.Add(-Period),
+		PeriodEnd:     end,
+		SnippetsAdded: 3,
+		TopSnippets:   []models.DigestSnippet{{Label: "Greeting", Shortcut: "/hi", Uses: 12}},
+		Devices:       []models.DigestDevice{{DeviceInfo: "Chrome on macOS", LastSyncedAt: end.Add(-time.Hour)}},
+	}
+	recipient := models.DigestRecipient{Username: "alice", Email: "alice@example.com"}
+
+	msg, err := Render(recipient, d, time.UTC)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	if msg.To != "alice@example.com" {
+		t.Errorf("Expected recipient alice@example.com, got %s", msg.To)
+	}
+	if msg.HTML == "" {
+		t.Error("Expected an HTML alternative")
+	}
+	if !strings.Contains(msg.Subject, "3 new snippets") {
+		t.Errorf("Expected subject to mention snippet count, got %q", msg.Subject)
+	}
+	for _, want := range []string{"Hi alice", "Jan 1 to Jan 8, 2024", "Greeting (/hi): 12 uses", "Chrome on macOS, last synced Mon Jan 8 08:00"} {
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("Expected body to contain %q, got:\n%s", want, msg.Text)
+		}
+	}
+}
+
+func TestActivityDigestIsEmpty(t *testing.T) {
+	if !(&models.ActivityDigest{}).IsEmpty() {
+		t.Error("Expected digest without activity to be empty")
+	}
+	if (&models.ActivityDigest{SnippetsAdded: 1}).IsEmpty() {
+		t.Error("Expected digest with added snippets not to be empty")
+	}
+}
@@ -0,0 +1,302 @@
+// Package discord implements the /snippy Discord slash command: paste a snippet by
+// shortcut (with autocomplete), search snippets, and link the Discord user to Snippy.
+package discord
+
+import (
+	"context"
+	"crypto/ed25519"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"github.com/jheysaaz/snippy-backend/app/search"
+)
+
+// ErrInvalidSignature is returned for interactions not signed with the application's key
+var ErrInvalidSignature = errors.New("discord: invalid interaction signature")
+
+// Interaction types
+const (
+	InteractionPing         = 1
+	InteractionCommand      = 2
+	InteractionAutocomplete = 4
+)
+
+// Interaction response types
+const (
+	ResponsePong                 = 1
+	ResponseChannelMessage       = 4
+	ResponseAutocompleteFinished = 8
+)
+
+// flagEphemeral makes a message visible to the caller only
+const flagEphemeral = 1 << 6
+
+// Discord limits
+const (
+	maxChoices       = 25
+	maxChoiceName    = 100
+	maxChoiceValue   = 100
+	maxMessageLength = 2000
+	searchResults    = 10
+)
+
+// Config holds the Discord application settings
+type Config struct {
+	PublicKey ed25519.PublicKey
+}
+
+// LoadConfig reads DISCORD_PUBLIC_KEY (hex) from the environment
+func LoadConfig() Config {
+	raw := os.Getenv("DISCORD_PUBLIC_KEY")
+	if raw == "" {
+		return Config{}
+	}
+	key, err := hex.DecodeString(raw)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		log.Printf("Ignoring invalid DISCORD_PUBLIC_KEY")
+		return Config{}
+	}
+	return Config{PublicKey: key}
+}
+
+// Enabled reports whether interactions are configured
+func (cfg Config) Enabled() bool {
+	return len(cfg.PublicKey) == ed25519.PublicKeySize
+}
+
+// Option is a slash command option. Subcommands carry their own options.
+type Option struct {
+	Value   json.RawMessage `json:"value,omitempty"`
+	Name    string          `json:"name"`
+	Options []Option        `json:"options,omitempty"`
+	Focused bool            `json:"focused,omitempty"`
+}
+
+// user is the subset of a Discord user used to identify the caller
+type user struct {
+	ID string `json:"id"`
+}
+
+// Interaction is an incoming interaction. In servers the caller is Member.User; in DMs it's User.
+type Interaction struct {
+	Member *struct {
+		User user `json:"user"`
+	} `json:"member,omitempty"`
+	User *user `json:"user,omitempty"`
+	Data struct {
+		Name    string   `json:"name"`
+		Options []Option `json:"options"`
+	} `json:"data"`
+	Type int `json:"type"`
+}
+
+// UserID returns the Discord user who triggered the interaction
+func (i *Interaction) UserID() string {
+	if i.Member != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+	return ""
+}
+
+// Choice is an autocomplete suggestion
+type Choice struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ResponseData is the message or autocomplete choices of a response
+type ResponseData struct {
+	AllowedMentions *allowedMentions `json:"allowed_mentions,omitempty"`
+	Content         string           `json:"content,omitempty"`
+	Choices         []Choice         `json:"choices,omitempty"`
+	Flags           int              `json:"flags,omitempty"`
+}
+
+// allowedMentions controls which mentions in a message notify anyone
+type allowedMentions struct {
+	Parse []string `json:"parse"`
+}
+
+// Response is an interaction response
+type Response struct {
+	Data *ResponseData `json:"data,omitempty"`
+	Type int           `json:"type"`
+}
+
+// ParseInteraction verifies the X-Signature-Ed25519 of an interaction payload, which
+// signs the timestamp followed by the body, and decodes it
+func ParseInteraction(payload []byte, timestamp, signature string, key ed25519.PublicKey) (*Interaction, error) {
+	sig, err := hex.DecodeString(signature)
+	if err != nil || len(sig) != ed25519.SignatureSize || timestamp == "" {
+		return nil, ErrInvalidSignature
+	}
+	if !ed25519.Verify(key, append([]byte(timestamp), payload...), sig) {
+		return nil, ErrInvalidSignature
+	}
+	var interaction Interaction
+	if err := json.Unmarshal(payload, &interaction); err != nil {
+		return nil, fmt.Errorf("discord: decode interaction: %w", err)
+	}
+	return &interaction, nil
+}
+
+// message is a channel message response; pasted snippets never ping anyone
+func message(content string, ephemeral bool) Response {
+	data := &ResponseData{Content: truncate(content, maxMessageLength), AllowedMentions: &allowedMentions{Parse: []string{}}}
+	if ephemeral {
+		data.Flags = flagEphemeral
+	}
+	return Response{Type: ResponseChannelMessage, Data: data}
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// stringOption returns the string value of the named option
+func stringOption(options []Option, name string) string {
+	for _, o := range options {
+		if o.Name == name {
+			var value string
+			if err := json.Unmarshal(o.Value, &value); err == nil {
+				return strings.TrimSpace(value)
+			}
+		}
+	}
+	return ""
+}
+
+// HandleInteraction answers an interaction from the accounts and snippets in store.
+// Failures are reported to the caller as ephemeral messages.
+func HandleInteraction(ctx context.Context, store *models.Store, i *Interaction) Response {
+	switch i.Type {
+	case InteractionPing:
+		return Response{Type: ResponsePong}
+	case InteractionCommand, InteractionAutocomplete:
+	default:
+		return message("Unsupported interaction.", true)
+	}
+
+	if len(i.Data.Options) == 0 {
+		return message("Usage: `/snippy paste`, `/snippy search`, `/snippy link` or `/snippy unlink`.", true)
+	}
+	sub := i.Data.Options[0]
+	discordUserID := i.UserID()
+
+	switch sub.Name {
+	case "link":
+		code := stringOption(sub.Options, "code")
+		err := store.LinkIntegrationUser(ctx, models.IntegrationDiscord, "", discordUserID, code)
+		if errors.Is(err, sql.ErrNoRows) {
+			return message("That link code is invalid or has expired. Create a new one in Snippy.", true)
+		}
+		if err != nil {
+			log.Printf("Failed to link Discord user %s: %v", discordUserID, err)
+			return message("Something went wrong linking your account. Please try again.", true)
+		}
+		return message("Your Snippy account is linked. Try `/snippy paste`.", true)
+
+	case "unlink":
+		err := store.UnlinkIntegrationUser(ctx, models.IntegrationDiscord, "", discordUserID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return message("Your Discord account isn't linked to Snippy.", true)
+		}
+		if err != nil {
+			log.Printf("Failed to unlink Discord user %s: %v", discordUserID, err)
+			return message("Something went wrong unlinking your account. Please try again.", true)
+		}
+		return message("Your Snippy account is unlinked.", true)
+	}
+
+	userID, err := store.GetIntegrationLinkedUser(ctx, models.IntegrationDiscord, "", discordUserID)
+	if i.Type == InteractionAutocomplete {
+		// Unlinked users simply get no suggestions
+		if err != nil {
+			return Response{Type: ResponseAutocompleteFinished, Data: &ResponseData{Choices: []Choice{}}}
+		}
+		return autocomplete(ctx, store, userID, stringOption(sub.Options, "shortcut"))
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return message("Link your Snippy account first: create a link code in Snippy, then run `/snippy link`.", true)
+	}
+	if err != nil {
+		log.Printf("Failed to look up Discord user %s: %v", discordUserID, err)
+		return message("Something went wrong. Please try again.", true)
+	}
+
+	switch sub.Name {
+	case "paste":
+		shortcut := stringOption(sub.Options, "shortcut")
+		expansion, err := store.ExpandShortcut(ctx, userID, shortcut, true)
+		if errors.Is(err, sql.ErrNoRows) {
+			return message(fmt.Sprintf("You have no snippet with the shortcut `%s`.", shortcut), true)
+		}
+		if err != nil {
+			log.Printf("Failed to expand shortcut for Discord user %s: %v", discordUserID, err)
+			return message("Something went wrong. Please try again.", true)
+		}
+		return message(expansion.Content, false)
+
+	case "search":
+		return searchSnippets(ctx, store, userID, stringOption(sub.Options, "query"))
+	}
+	return message("Unknown command.", true)
+}
+
+// autocomplete suggests the user's shortcuts starting with prefix
+func autocomplete(ctx context.Context, store *models.Store, userID, prefix string) Response {
+	choices := make([]Choice, 0, maxChoices)
+	expansions, err := store.SearchShortcuts(ctx, userID, prefix, maxChoices)
+	if err != nil {
+		log.Printf("Failed to autocomplete shortcuts for user %s: %v", userID, err)
+	}
+	for _, e := range expansions {
+		// Values longer than Discord allows can't be offered
+		if len(e.Shortcut) > maxChoiceValue {
+			continue
+		}
+		name := e.Shortcut
+		if e.Label != "" {
+			name += " — " + e.Label
+		}
+		choices = append(choices, Choice{Name: truncate(name, maxChoiceName), Value: e.Shortcut})
+	}
+	return Response{Type: ResponseAutocompleteFinished, Data: &ResponseData{Choices: choices}}
+}
+
+// searchSnippets lists the user's snippets matching query, for the caller only
+func searchSnippets(ctx context.Context, store *models.Store, userID, query string) Response {
+	if query == "" {
+		return message("Usage: `/snippy search query:<text>`", true)
+	}
+	result, _, err := search.Run(ctx, store, models.SnippetSearch{UserID: userID, Text: query, Limit: searchResults})
+	if err != nil {
+		log.Printf("Failed to search snippets for user %s: %v", userID, err)
+		return message("Something went wrong searching. Please try again.", true)
+	}
+	if len(result.Snippets) == 0 {
+		return message(fmt.Sprintf("No snippets match “%s”.", query), true)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Snippets matching “%s” (paste one with `/snippy paste`):\n", query)
+	for _, s := range result.Snippets {
+		fmt.Fprintf(&b, "• `%s` %s\n", s.Shortcut, s.Label)
+	}
+	return message(b.String(), true)
+}
@@ -0,0 +1,79 @@
+package discord
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestParseInteraction(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := []byte(`{"type":2,"member":{"user":{"id":"80351110224678912"}},"data":{"name":"snippy","options":[{"name":"paste","options":[{"name":"shortcut","value":"sig"}]}]}}`)
+	timestamp := "1700000000"
+	sig := hex.EncodeToString(ed25519.Sign(private, append([]byte(timestamp), payload...)))
+
+	interaction, err := ParseInteraction(payload, timestamp, sig, public)
+	if err != nil {
+		t.Fatalf("ParseInteraction: %v", err)
+	}
+	if interaction.Type != InteractionCommand || interaction.UserID() != "80351110224678912" {
+		t.Errorf("unexpected interaction %+v", interaction)
+	}
+	if sub := interaction.Data.Options[0]; sub.Name != "paste" || stringOption(sub.Options, "shortcut") != "sig" {
+		t.Errorf("unexpected options %+v", interaction.Data.Options)
+	}
+
+	otherPublic, _, _ := ed25519.GenerateKey(rand.Reader)
+	rejected := map[string]struct {
+		payload   []byte
+		timestamp string
+		signature string
+		key       ed25519.PublicKey
+	}{
+		"wrong key":         {payload, timestamp, sig, otherPublic},
+		"modified payload":  {append([]byte(" "), payload...), timestamp, sig, public},
+		"other timestamp":   {payload, "1700000001", sig, public},
+		"missing timestamp": {payload, "", sig, public},
+		"malformed":         {payload, timestamp, "zz", public},
+	}
+	for name, tc := range rejected {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseInteraction(tc.payload, tc.timestamp, tc.signature, tc.key); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("expected ErrInvalidSignature, got %v", err)
+			}
+		})
+	}
+}
+
+func TestHandleInteractionWithoutLookup(t *testing.T) {
+	ctx := context.Background()
+
+	if resp := HandleInteraction(ctx, nil, &Interaction{Type: InteractionPing}); resp.Type != ResponsePong || resp.Data != nil {
+		t.Errorf("ping = %+v, want a pong", resp)
+	}
+
+	resp := HandleInteraction(ctx, nil, &Interaction{Type: InteractionCommand})
+	if resp.Type != ResponseChannelMessage || resp.Data.Flags != flagEphemeral {
+		t.Errorf("command without subcommand = %+v, want ephemeral usage", resp)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	long := make([]rune, maxMessageLength+10)
+	for i := range long {
+		long[i] = 'é'
+	}
+	resp := message(string(long), false)
+	if got := len([]rune(resp.Data.Content)); got != maxMessageLength {
+		t.Errorf("message length = %d runes, want %d", got, maxMessageLength)
+	}
+	if resp.Data.Flags != 0 || resp.Data.AllowedMentions == nil || len(resp.Data.AllowedMentions.Parse) != 0 {
+		t.Errorf("pasted message %+v should be public and mention nobody", resp.Data)
+	}
+}
@@ -0,0 +1,137 @@
+// Package events publishes snippet, user and session domain events from the outbox to
+// an external broker (NATS or Kafka) so other systems can consume Snippy activity.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+// Supported brokers
+const (
+	BrokerNATS  = "nats"
+	BrokerKafka = "kafka"
+)
+
+// DefaultPrefix starts every subject and topic name
+const DefaultPrefix = "snippy"
+
+// Config selects and addresses the broker
+type Config struct {
+	Broker string
+	// URL is the NATS server URL
+	URL string
+	// Brokers are the Kafka bootstrap addresses
+	Brokers []string
+	Prefix  string
+}
+
+// LoadConfig reads EVENTS_BROKER, EVENTS_NATS_URL, EVENTS_KAFKA_BROKERS (comma-separated)
+// and EVENTS_PREFIX
+func LoadConfig() Config {
+	cfg := Config{
+		Broker: strings.ToLower(strings.TrimSpace(os.Getenv("EVENTS_BROKER"))),
+		URL:    os.Getenv("EVENTS_NATS_URL"),
+		Prefix: os.Getenv("EVENTS_PREFIX"),
+	}
+	for _, addr := range strings.Split(os.Getenv("EVENTS_KAFKA_BROKERS"), ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			cfg.Brokers = append(cfg.Brokers, addr)
+		}
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = DefaultPrefix
+	}
+	return cfg
+}
+
+// Message is an event ready to publish
+type Message struct {
+	// Subject is the NATS subject, e.g. snippy.snippet.created
+	Subject string
+	// Topic is the Kafka topic, one per aggregate type, e.g. snippy.snippet
+	Topic string
+	// Key keeps an aggregate's events in order on a Kafka partition
+	Key  string
+	Type string
+	// Body is the JSON-encoded outbox event
+	Body []byte
+}
+
+// NewMessage builds the message for evt
+func NewMessage(prefix string, evt models.OutboxEvent) (Message, error) {
+	body, err := json.Marshal(evt)
+	if err != nil {
+		return Message{}, fmt.Errorf("marshal %s event: %w", evt.EventType, err)
+	}
+	return Message{
+		Subject: prefix + "." + evt.EventType,
+		Topic:   prefix + "." + evt.AggregateType,
+		Key:     evt.AggregateType + ":" + evt.AggregateID,
+		Type:    evt.EventType,
+		Body:    body,
+	}, nil
+}
+
+// Publisher sends messages to a broker
+type Publisher interface {
+	Publish(ctx context.Context, msg Message) error
+	Close() error
+}
+
+// Sink is an outbox sink publishing every event to a broker. A publish error fails the
+// event so the outbox retries it; consumers should expect at-least-once delivery and
+// deduplicate by event ID.
+type Sink struct {
+	publisher Publisher
+	prefix    string
+}
+
+// NewSink creates a sink publishing through publisher
+func NewSink(publisher Publisher, prefix string) *Sink {
+	return &Sink{publisher: publisher, prefix: prefix}
+}
+
+// NewSinkFromEnv connects to the configured broker. It returns nil when EVENTS_BROKER is unset.
+func NewSinkFromEnv() (*Sink, error) {
+	cfg := LoadConfig()
+
+	var publisher Publisher
+	var err error
+	switch cfg.Broker {
+	case "":
+		return nil, nil
+	case BrokerNATS:
+		publisher, err = NewNATSPublisher(cfg.URL)
+	case BrokerKafka:
+		publisher, err = NewKafkaPublisher(cfg.Brokers)
+	default:
+		return nil, fmt.Errorf("events: unknown EVENTS_BROKER %q (want nats or kafka)", cfg.Broker)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewSink(publisher, cfg.Prefix), nil
+}
+
+// Name returns the sink name
+func (s *Sink) Name() string { return "events" }
+
+// Deliver publishes evt
+func (s *Sink) Deliver(ctx context.Context, evt models.OutboxEvent) error {
+	msg, err := NewMessage(s.prefix, evt)
+	if err != nil {
+		return err
+	}
+	return s.publisher.Publish(ctx, msg)
+}
+
+// Close closes the broker connection
+func (s *Sink) Close() error {
+	return s.publisher.Close()
+}
@@ -0,0 +1,94 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+type fakePublisher struct {
+	err      error
+	messages []Message
+}
+
+func (p *fakePublisher) Publish(_ context.Context, msg Message) error {
+	p.messages = append(p.messages, msg)
+	return p.err
+}
+
+func (p *fakePublisher) Close() error { return nil }
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("EVENTS_BROKER", " Kafka ")
+	t.Setenv("EVENTS_KAFKA_BROKERS", "kafka-1:9092, kafka-2:9092,")
+	t.Setenv("EVENTS_PREFIX", "")
+
+	cfg := LoadConfig()
+	if cfg.Broker != BrokerKafka {
+		t.Errorf("Broker = %q, want kafka", cfg.Broker)
+	}
+	if len(cfg.Brokers) != 2 || cfg.Brokers[1] != "kafka-2:9092" {
+		t.Errorf("Brokers = %v", cfg.Brokers)
+	}
+	if cfg.Prefix != DefaultPrefix {
+		t.Errorf("Prefix = %q, want %q", cfg.Prefix, DefaultPrefix)
+	}
+}
+
+func TestNewSinkFromEnv(t *testing.T) {
+	t.Setenv("EVENTS_BROKER", "")
+	if sink, err := NewSinkFromEnv(); sink != nil || err != nil {
+		t.Errorf("expected no sink without EVENTS_BROKER, got %v, %v", sink, err)
+	}
+
+	t.Setenv("EVENTS_BROKER", "rabbitmq")
+	if _, err := NewSinkFromEnv(); err == nil {
+		t.Error("expected an error for an unknown broker")
+	}
+
+	t.Setenv("EVENTS_BROKER", BrokerKafka)
+	t.Setenv("EVENTS_KAFKA_BROKERS", "")
+	if _, err := NewSinkFromEnv(); err == nil {
+		t.Error("expected an error for kafka without brokers")
+	}
+}
+
+func TestSinkDeliver(t *testing.T) {
+	publisher := &fakePublisher{}
+	sink := NewSink(publisher, "snippy")
+
+	evt := models.OutboxEvent{
+		ID:            42,
+		EventType:     models.EventSessionEnded,
+		AggregateType: models.AggregateSession,
+		AggregateID:   "session-1",
+		Payload:       []byte(`{"sessionId":"session-1","reason":"logout"}`),
+	}
+	if err := sink.Deliver(context.Background(), evt); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+	if len(publisher.messages) != 1 {
+		t.Fatalf("published %d messages, want 1", len(publisher.messages))
+	}
+
+	msg := publisher.messages[0]
+	if msg.Subject != "snippy.session.ended" || msg.Topic != "snippy.session" || msg.Key != "session:session-1" {
+		t.Errorf("unexpected routing %+v", msg)
+	}
+	var decoded models.OutboxEvent
+	if err := json.Unmarshal(msg.Body, &decoded); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if decoded.ID != 42 || decoded.EventType != models.EventSessionEnded {
+		t.Errorf("unexpected body %s", msg.Body)
+	}
+
+	// Publish failures fail the delivery so the outbox retries
+	publisher.err = errors.New("broker down")
+	if err := sink.Deliver(context.Background(), evt); err == nil {
+		t.Error("expected the publish error to be returned")
+	}
+}
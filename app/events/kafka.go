@@ -0,0 +1,49 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaPublisher publishes events to one Kafka topic per aggregate type
+type KafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaPublisher creates a publisher for the given bootstrap brokers. Topics are
+// created on first use when the cluster allows it.
+func NewKafkaPublisher(brokers []string) (*KafkaPublisher, error) {
+	if len(brokers) == 0 {
+		return nil, errors.New("events: EVENTS_KAFKA_BROKERS is required for the kafka broker")
+	}
+	return &KafkaPublisher{writer: &kafka.Writer{
+		Addr:                   kafka.TCP(brokers...),
+		Balancer:               &kafka.Hash{},
+		RequiredAcks:           kafka.RequireAll,
+		AllowAutoTopicCreation: true,
+		BatchTimeout:           10 * time.Millisecond,
+	}}, nil
+}
+
+// Publish writes msg keyed by aggregate so each aggregate's events stay ordered
+func (p *KafkaPublisher) Publish(ctx context.Context, msg Message) error {
+	err := p.writer.WriteMessages(ctx, kafka.Message{
+		Topic:   msg.Topic,
+		Key:     []byte(msg.Key),
+		Value:   msg.Body,
+		Headers: []kafka.Header{{Key: "Snippy-Event", Value: []byte(msg.Type)}},
+	})
+	if err != nil {
+		return fmt.Errorf("events: publish to %s: %w", msg.Topic, err)
+	}
+	return nil
+}
+
+// Close flushes pending writes and closes the writer
+func (p *KafkaPublisher) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,46 @@
+package events
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSPublisher publishes events to NATS subjects
+type NATSPublisher struct {
+	conn *nats.Conn
+}
+
+// NewNATSPublisher connects to the NATS server at url (nats.DefaultURL when empty)
+func NewNATSPublisher(url string) (*NATSPublisher, error) {
+	if url == "" {
+		url = nats.DefaultURL
+	}
+	conn, err := nats.Connect(url, nats.Name("snippy-api"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("events: connect to NATS: %w", err)
+	}
+	return &NATSPublisher{conn: conn}, nil
+}
+
+// Publish sends msg and waits for the server to acknowledge it, so a lost connection
+// fails the delivery instead of dropping the event
+func (p *NATSPublisher) Publish(ctx context.Context, msg Message) error {
+	natsMsg := nats.NewMsg(msg.Subject)
+	natsMsg.Header.Set("Snippy-Event", msg.Type)
+	natsMsg.Data = msg.Body
+
+	if err := p.conn.PublishMsg(natsMsg); err != nil {
+		return fmt.Errorf("events: publish %s: %w", msg.Subject, err)
+	}
+	if err := p.conn.FlushWithContext(ctx); err != nil {
+		return fmt.Errorf("events: flush %s: %w", msg.Subject, err)
+	}
+	return nil
+}
+
+// Close drains pending messages and closes the connection
+func (p *NATSPublisher) Close() error {
+	return p.conn.Drain()
+}
@@ -0,0 +1,158 @@
+// Package export provides a streaming JSON archive of a user's snippets and tags.
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+// ArchiveFileName is the name of the JSON document, and of the file inside the zip
+const ArchiveFileName = "snippy-export.json"
+
+// ArchiveTag is a tag of an archive with the number of exported snippets carrying it
+type ArchiveTag struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Writer writes a streamed snippet export, one snippet at a time: ArchiveWriter,
+// VSCodeWriter, EspansoWriter or TextExpanderWriter
+type Writer interface {
+	Encode(snippet *models.ExportedSnippet) error
+	// Snippets returns how many snippets have been written
+	Snippets() int
+	// Close finishes the export
+	Close() error
+}
+
+// ArchiveWriter writes an account archive, a JSON document of the form
+// {"exportedAt": ..., "snippets": [...], "tags": [...]}, optionally zipped. Snippets are
+// written as they are encoded and flushed every few, like NDJSONWriter's lines; only the
+// tag counts are kept until Close writes them. Nothing is written before the first Encode
+// or Close, so a caller can still report a failure before it.
+type ArchiveWriter struct {
+	exportedAt time.Time
+	dst        io.Writer
+	out        io.Writer
+	flusher    http.Flusher
+	zw         *zip.Writer
+	tags       map[string]int
+	snippets   int
+	zipped     bool
+	opened     bool
+}
+
+// NewArchiveWriter creates an ArchiveWriter writing to w, zipped if zipped is set
+func NewArchiveWriter(w io.Writer, zipped bool, exportedAt time.Time) *ArchiveWriter {
+	flusher, _ := w.(http.Flusher)
+	return &ArchiveWriter{dst: w, zipped: zipped, exportedAt: exportedAt, flusher: flusher, tags: make(map[string]int)}
+}
+
+// open writes the zip entry's header and the start of the document
+func (w *ArchiveWriter) open() error {
+	w.opened = true
+	w.out = w.dst
+	if w.zipped {
+		w.zw = zip.NewWriter(w.dst)
+		fw, err := w.zw.CreateHeader(&zip.FileHeader{Name: ArchiveFileName, Method: zip.Deflate, Modified: w.exportedAt})
+		if err != nil {
+			return err
+		}
+		w.out = fw
+	}
+	exportedAt, err := json.Marshal(w.exportedAt)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w.out, `{"exportedAt":`+string(exportedAt)+`,"snippets":[`)
+	return err
+}
+
+// Encode writes snippet into the snippets array
+func (w *ArchiveWriter) Encode(snippet *models.ExportedSnippet) error {
+	if !w.opened {
+		if err := w.open(); err != nil {
+			return err
+		}
+	}
+	if w.snippets > 0 {
+		if _, err := io.WriteString(w.out, ","); err != nil {
+			return err
+		}
+	}
+	data, err := json.Marshal(snippet)
+	if err != nil {
+		return err
+	}
+	if _, err := w.out.Write(data); err != nil {
+		return err
+	}
+	for _, tag := range snippet.Tags {
+		w.tags[tag]++
+	}
+	w.snippets++
+	if w.snippets%ndjsonFlushEvery == 0 {
+		return w.flush()
+	}
+	return nil
+}
+
+// Snippets returns how many snippets have been written
+func (w *ArchiveWriter) Snippets() int {
+	return w.snippets
+}
+
+// Close ends the snippets array, writes the tags from the most used and finishes the zip
+func (w *ArchiveWriter) Close() error {
+	if !w.opened {
+		if err := w.open(); err != nil {
+			return err
+		}
+	}
+	tags := make([]ArchiveTag, 0, len(w.tags))
+	for name, count := range w.tags {
+		tags = append(tags, ArchiveTag{Name: name, Count: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Name < tags[j].Name
+	})
+	data, err := json.Marshal(tags)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w.out, `],"tags":`+string(data)+"}\n"); err != nil {
+		return err
+	}
+	if w.zw != nil {
+		if err := w.zw.Close(); err != nil {
+			return err
+		}
+	}
+	if w.flusher != nil {
+		w.flusher.Flush()
+	}
+	return nil
+}
+
+// flush sends what was written so far to the client, if the writer supports it
+func (w *ArchiveWriter) flush() error {
+	if w.flusher == nil {
+		return nil
+	}
+	if w.zw != nil {
+		if err := w.zw.Flush(); err != nil {
+			return err
+		}
+	}
+	w.flusher.Flush()
+	return nil
+}
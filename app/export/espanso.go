@@ -0,0 +1,79 @@
+// Package export provides streaming Espanso match files of a user's snippets.
+package export
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/goccy/go-yaml"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+// EspansoFileName is the name of the Espanso match file
+const EspansoFileName = "snippy-espanso.yml"
+
+// EspansoMatch is one Espanso trigger and its replacement
+type EspansoMatch struct {
+	Trigger string `yaml:"trigger"`
+	Replace string `yaml:"replace"`
+	Label   string `yaml:"label,omitempty"`
+}
+
+// NewEspansoMatch converts a snippet: the shortcut is the trigger, the content the
+// replacement and the label the label
+func NewEspansoMatch(s *models.Snippet) EspansoMatch {
+	return EspansoMatch{Trigger: s.Shortcut, Replace: s.Content, Label: s.Label}
+}
+
+// EspansoWriter writes snippets as an Espanso match file, the package.yml of an Espanso
+// package, in the shape GET /snippets/espanso serves. Like ArchiveWriter it writes each
+// snippet as it's encoded and nothing before the first Encode or Close.
+type EspansoWriter struct {
+	w        io.Writer
+	flusher  http.Flusher
+	snippets int
+}
+
+// NewEspansoWriter creates an EspansoWriter writing to w
+func NewEspansoWriter(w io.Writer) *EspansoWriter {
+	flusher, _ := w.(http.Flusher)
+	return &EspansoWriter{w: w, flusher: flusher}
+}
+
+// Encode writes snippet as a match
+func (w *EspansoWriter) Encode(snippet *models.ExportedSnippet) error {
+	// Each match is a one-item sequence, which Close leaves as the top-level matches list
+	item, err := yaml.Marshal([]EspansoMatch{NewEspansoMatch(&snippet.Snippet)})
+	if err != nil {
+		return err
+	}
+	if w.snippets == 0 {
+		item = append([]byte("matches:\n"), item...)
+	}
+	if _, err := w.w.Write(item); err != nil {
+		return err
+	}
+	w.snippets++
+	if w.flusher != nil && w.snippets%ndjsonFlushEvery == 0 {
+		w.flusher.Flush()
+	}
+	return nil
+}
+
+// Snippets returns how many snippets have been written
+func (w *EspansoWriter) Snippets() int {
+	return w.snippets
+}
+
+// Close ends the file
+func (w *EspansoWriter) Close() error {
+	if w.snippets == 0 {
+		if _, err := io.WriteString(w.w, "matches: []\n"); err != nil {
+			return err
+		}
+	}
+	if w.flusher != nil {
+		w.flusher.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+// Package export builds a final archive of a soft-deleted account before the
+// retention job purges it, and optionally emails the user a download link.
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jheysaaz/snippy-backend/app/mailer"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+const (
+	defaultTTLDays = 30
+	defaultBaseURL = "http://localhost:8080"
+)
+
+// Config controls pre-purge exports
+type Config struct {
+	// BaseURL is the public API origin used in download links
+	BaseURL string
+	// TTL is how long the download link stays valid
+	TTL time.Duration
+	// Email sends the user a download link when their export is ready
+	Email bool
+}
+
+// LoadConfig reads export settings from the environment (PUBLIC_BASE_URL,
+// PURGE_EXPORT_TTL_DAYS, PURGE_EXPORT_EMAIL).
+func LoadConfig() Config {
+	cfg := Config{
+		BaseURL: strings.TrimRight(os.Getenv("PUBLIC_BASE_URL"), "/"),
+		TTL:     defaultTTLDays * 24 * time.Hour,
+		Email:   true,
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = defaultBaseURL
+	}
+	if raw := os.Getenv("PURGE_EXPORT_TTL_DAYS"); raw != "" {
+		days, err := strconv.Atoi(raw)
+		if err != nil || days <= 0 {
+			log.Printf("Ignoring invalid PURGE_EXPORT_TTL_DAYS %q, using %d days", raw, defaultTTLDays)
+		} else {
+			cfg.TTL = time.Duration(days) * 24 * time.Hour
+		}
+	}
+	if raw := os.Getenv("PURGE_EXPORT_EMAIL"); raw != "" {
+		enabled, err := strconv.ParseBool(raw)
+		if err != nil {
+			log.Printf("Ignoring invalid PURGE_EXPORT_EMAIL %q, emailing download links", raw)
+		} else {
+			cfg.Email = enabled
+		}
+	}
+	return cfg
+}
+
+// DownloadURL returns the public download link for an export token
+func DownloadURL(baseURL, token string) string {
+	return baseURL + "/api/v1/exports/" + url.PathEscape(token)
+}
+
+// WriteArchive writes a zip with account.json (profile) and snippets.json
+// (snippets with version history).
+func WriteArchive(w io.Writer, data *models.AccountExportData) error {
+	zw := zip.NewWriter(w)
+
+	files := []struct {
+		value interface{}
+		name  string
+	}{
+		{name: "account.json", value: map[string]interface{}{"exportedAt": data.ExportedAt, "user": data.User}},
+		{name: "snippets.json", value: data.Snippets},
+	}
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: data.ExportedAt})
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.value); err != nil {
+			return fmt.Errorf("export: write %s: %w", f.name, err)
+		}
+	}
+
+	return zw.Close()
+}
+
+// Exporter archives accounts before they are purged
+type Exporter struct {
+	store  *models.Store
+	sender mailer.Mailer
+	cfg    Config
+}
+
+// NewExporter creates an exporter that saves exports in store and emails links through sender
+func NewExporter(cfg Config, store *models.Store, sender mailer.Mailer) *Exporter {
+	return &Exporter{cfg: cfg, store: store, sender: sender}
+}
+
+// BeforePurge stores a final export of the user's account and emails the download
+// link. It is installed as the retention policy's BeforeUserPurge hook; an error
+// keeps the user until the next cleanup run. A failed email does not.
+func (e *Exporter) BeforePurge(ctx context.Context, userID string) error {
+	data, err := e.store.GetAccountExportData(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("export: load account %s: %w", userID, err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteArchive(&buf, data); err != nil {
+		return err
+	}
+
+	token, expiresAt, err := e.store.SaveAccountExport(ctx, data.User, buf.Bytes(), e.cfg.TTL)
+	if err != nil {
+		return fmt.Errorf("export: store archive for %s: %w", userID, err)
+	}
+
+	if !e.cfg.Email || e.sender == nil || data.User.Email == "" {
+		return nil
+	}
+	msg, err := mailer.Render("account_export", map[string]interface{}{
+		"Username":  data.User.Username,
+		"URL":       DownloadURL(e.cfg.BaseURL, token),
+		"ExpiresAt": expiresAt.UTC().Format("Jan 2, 2006"),
+		"Snippets":  len(data.Snippets),
+	})
+	if err != nil {
+		log.Printf("Failed to render export email for user %s: %v", userID, err)
+		return nil
+	}
+	msg.To = data.User.Email
+	if err := e.sender.Send(ctx, msg); err != nil {
+		log.Printf("Failed to email export link to user %s: %v", userID, err)
+	}
+	return nil
+}
@@ -0,0 +1,359 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/jheysaaz/snippy-backend/app/mailer"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+func TestWriteArchive(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	data := &models.AccountExportData{
+		ExportedAt: now,
+		User:       &models.User{ID: "user-1", Username: "alice", Email: "alice@example.com"},
+		Snippets: []models.ExportedSnippet{{
+			Snippet: models.Snippet{ID: 7, Label: "Greeting", Shortcut: "hi", Content: "Hello!", Tags: []string{}},
+			History: []models.SnippetHistory{{SnippetID: 7, VersionNumber: 1, Content: "Hi", ChangeType: "create"}},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteArchive(&buf, data); err != nil {
+		t.Fatalf("WriteArchive failed: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("archive is not a valid zip: %v", err)
+	}
+	if len(zr.File) != 2 || zr.File[0].Name != "account.json" || zr.File[1].Name != "snippets.json" {
+		t.Fatalf("unexpected archive contents: %v", zr.File)
+	}
+
+	f, err := zr.File[1].Open()
+	if err != nil {
+		t.Fatalf("open snippets.json: %v", err)
+	}
+	defer f.Close()
+	var snippets []models.ExportedSnippet
+	if err := json.NewDecoder(f).Decode(&snippets); err != nil {
+		t.Fatalf("decode snippets.json: %v", err)
+	}
+	if len(snippets) != 1 || snippets[0].Shortcut != "hi" || len(snippets[0].History) != 1 {
+		t.Errorf("unexpected snippets: %+v", snippets)
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := NewNDJSONWriter(rec)
+	for i := range ndjsonFlushEvery + 1 {
+		snippet := &models.ExportedSnippet{Snippet: models.Snippet{ID: int64(i + 1), Shortcut: "s"}, History: []models.SnippetHistory{}}
+		if err := w.Encode(snippet); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	if !rec.Flushed {
+		t.Error("expected the writer to flush after a batch of lines")
+	}
+	if w.Lines() != ndjsonFlushEvery+1 {
+		t.Errorf("expected %d lines, got %d", ndjsonFlushEvery+1, w.Lines())
+	}
+
+	lines := strings.Split(strings.TrimSuffix(rec.Body.String(), "\n"), "\n")
+	if len(lines) != ndjsonFlushEvery+1 {
+		t.Fatalf("expected %d lines in the body, got %d", ndjsonFlushEvery+1, len(lines))
+	}
+	var first models.ExportedSnippet
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("first line is not JSON: %v", err)
+	}
+	if first.ID != 1 || first.Shortcut != "s" {
+		t.Errorf("unexpected first snippet: %+v", first)
+	}
+}
+
+func TestArchiveWriter(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	snippets := []*models.ExportedSnippet{
+		{Snippet: models.Snippet{ID: 1, Shortcut: "a", Tags: []string{"go", "sql"}}, History: []models.SnippetHistory{}},
+		{Snippet: models.Snippet{ID: 2, Shortcut: "b", Tags: []string{"go"}}, History: []models.SnippetHistory{}},
+	}
+
+	type archive struct {
+		ExportedAt time.Time                `json:"exportedAt"`
+		Snippets   []models.ExportedSnippet `json:"snippets"`
+		Tags       []ArchiveTag             `json:"tags"`
+	}
+	check := func(t *testing.T, data []byte, wantSnippets int) {
+		t.Helper()
+		var got archive
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("archive is not JSON: %v (%s)", err, data)
+		}
+		if !got.ExportedAt.Equal(now) || len(got.Snippets) != wantSnippets {
+			t.Errorf("unexpected archive: %+v", got)
+		}
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		w := NewArchiveWriter(rec, false, now)
+		for _, s := range snippets {
+			if err := w.Encode(s); err != nil {
+				t.Fatalf("Encode failed: %v", err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		check(t, rec.Body.Bytes(), 2)
+
+		var got archive
+		_ = json.Unmarshal(rec.Body.Bytes(), &got)
+		want := []ArchiveTag{{Name: "go", Count: 2}, {Name: "sql", Count: 1}}
+		if len(got.Tags) != 2 || got.Tags[0] != want[0] || got.Tags[1] != want[1] {
+			t.Errorf("tags = %+v, want %+v", got.Tags, want)
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := NewArchiveWriter(&buf, false, now)
+		if buf.Len() != 0 {
+			t.Fatal("expected nothing written before the first snippet")
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		check(t, buf.Bytes(), 0)
+	})
+
+	t.Run("Zip", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := NewArchiveWriter(&buf, true, now)
+		for _, s := range snippets {
+			if err := w.Encode(s); err != nil {
+				t.Fatalf("Encode failed: %v", err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatalf("archive is not a valid zip: %v", err)
+		}
+		if len(zr.File) != 1 || zr.File[0].Name != ArchiveFileName {
+			t.Fatalf("unexpected zip contents: %v", zr.File)
+		}
+		f, err := zr.File[0].Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", ArchiveFileName, err)
+		}
+		defer f.Close()
+		var data bytes.Buffer
+		if _, err := data.ReadFrom(f); err != nil {
+			t.Fatalf("read %s: %v", ArchiveFileName, err)
+		}
+		check(t, data.Bytes(), 2)
+	})
+}
+
+func TestNewVSCodeSnippet(t *testing.T) {
+	language, description := "bash", "Greets the *current* user"
+	got := NewVSCodeSnippet(&models.Snippet{
+		Shortcut:    "greet",
+		Description: &description,
+		Content:     "echo \"Hi {{name}}, $HOME\"\r\necho {{name}} {{count:number}} {{date}} {{ClipBoard}}",
+		Language:    &language,
+	})
+	want := VSCodeSnippet{
+		Prefix:      "greet",
+		Scope:       "shellscript",
+		Description: description,
+		Body: []string{
+			`echo "Hi ${1:name}, \$HOME"`,
+			`echo ${1:name} ${2:count} ${CURRENT_YEAR}-${CURRENT_MONTH}-${CURRENT_DATE} ${CLIPBOARD}`,
+		},
+	}
+	if got.Prefix != want.Prefix || got.Scope != want.Scope || got.Description != want.Description || strings.Join(got.Body, "\n") != strings.Join(want.Body, "\n") {
+		t.Errorf("NewVSCodeSnippet = %+v, want %+v", got, want)
+	}
+
+	if plain := NewVSCodeSnippet(&models.Snippet{Shortcut: "x", Content: `a\b`}); plain.Scope != "" || plain.Body[0] != `a\\b` {
+		t.Errorf("plain snippet = %+v, want no scope and an escaped backslash", plain)
+	}
+}
+
+func TestVSCodeWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := NewVSCodeWriter(rec)
+	for _, s := range []models.Snippet{
+		{ID: 1, Label: "Greeting", Shortcut: "hi", Content: "Hello!"},
+		{ID: 2, Label: "Greeting", Shortcut: "hey", Content: "Hey!"},
+		{ID: 3, Label: "Greeting (hi)", Shortcut: "hi2", Content: "Hi!"},
+		{ID: 4, Label: "Greeting", Shortcut: "hi", Content: "Hi again"},
+	} {
+		if err := w.Encode(&models.ExportedSnippet{Snippet: s}); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	var file map[string]VSCodeSnippet
+	if err := json.Unmarshal(rec.Body.Bytes(), &file); err != nil {
+		t.Fatalf("file is not JSON: %v (%s)", err, rec.Body.String())
+	}
+	for name, prefix := range map[string]string{
+		"Greeting": "hi", "Greeting (hey)": "hey", "Greeting (hi)": "hi2", "Greeting (hi) 2": "hi",
+	} {
+		if file[name].Prefix != prefix {
+			t.Errorf("%q has prefix %q, want %q (file %v)", name, file[name].Prefix, prefix, file)
+		}
+	}
+
+	var empty bytes.Buffer
+	if err := NewVSCodeWriter(&empty).Close(); err != nil || empty.String() != "{}\n" {
+		t.Errorf("empty file = %q, %v", empty.String(), err)
+	}
+}
+
+func TestEspansoWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := NewEspansoWriter(rec)
+	snippets := []models.Snippet{
+		{ID: 1, Label: "Greeting", Shortcut: ":hi", Content: "Hello,\nworld: {{name}}"},
+		{ID: 2, Shortcut: ":sig", Content: "- Jane"},
+	}
+	for _, s := range snippets {
+		if err := w.Encode(&models.ExportedSnippet{Snippet: s}); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	var file struct {
+		Matches []EspansoMatch `yaml:"matches"`
+	}
+	if err := yaml.Unmarshal(rec.Body.Bytes(), &file); err != nil {
+		t.Fatalf("file is not YAML: %v (%s)", err, rec.Body.String())
+	}
+	if len(file.Matches) != len(snippets) {
+		t.Fatalf("got %d matches, want %d (%s)", len(file.Matches), len(snippets), rec.Body.String())
+	}
+	for i, s := range snippets {
+		if file.Matches[i] != NewEspansoMatch(&s) {
+			t.Errorf("match %d = %+v, want %+v", i, file.Matches[i], NewEspansoMatch(&s))
+		}
+	}
+
+	var empty bytes.Buffer
+	if err := NewEspansoWriter(&empty).Close(); err != nil || empty.String() != "matches: []\n" {
+		t.Errorf("empty file = %q, %v", empty.String(), err)
+	}
+}
+
+func TestTextExpanderContent(t *testing.T) {
+	tests := map[string]string{
+		"plain":                           "plain",
+		"100% sure":                       "100%% sure",
+		"Hi {{name}}, {{name}}!":          "Hi %filltext:name=name%, %filltext:name=name%!",
+		"{{date}} {{time}} {{ClipBoard}}": "%Y-%m-%d %H:%M %clipboard",
+		"{{datetime}} {{count:number}}":   "%Y-%m-%dT%H:%M:%S %filltext:name=count%",
+	}
+	for content, want := range tests {
+		if got := TextExpanderContent(content); got != want {
+			t.Errorf("TextExpanderContent(%q) = %q, want %q", content, got, want)
+		}
+	}
+}
+
+func TestTextExpanderWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := NewTextExpanderWriter(rec)
+	for _, s := range []models.Snippet{
+		{ID: 1, Label: "Greeting", Shortcut: ";hi", Content: "Hello, \"{{name}}\"\nBye"},
+		{ID: 2, Label: "Discount", Shortcut: ";off", Content: "10% off"},
+	} {
+		if err := w.Encode(&models.ExportedSnippet{Snippet: s}); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if w.Snippets() != 2 {
+		t.Errorf("Snippets() = %d, want 2", w.Snippets())
+	}
+
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("file is not CSV: %v", err)
+	}
+	want := [][]string{
+		{";hi", "Hello, \"%filltext:name=name%\"\nBye", "Greeting"},
+		{";off", "10%% off", "Discount"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d: %q", len(rows), len(want), rows)
+	}
+	for i := range want {
+		if strings.Join(rows[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("row %d = %q, want %q", i, rows[i], want[i])
+		}
+	}
+}
+
+func TestDownloadURL(t *testing.T) {
+	got := DownloadURL("https://api.example.com", "abc_-=")
+	if got != "https://api.example.com/api/v1/exports/abc_-=" {
+		t.Errorf("unexpected download URL %q", got)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("PUBLIC_BASE_URL", "https://api.example.com/")
+	t.Setenv("PURGE_EXPORT_TTL_DAYS", "7")
+	t.Setenv("PURGE_EXPORT_EMAIL", "false")
+
+	cfg := LoadConfig()
+	if cfg.BaseURL != "https://api.example.com" {
+		t.Errorf("BaseURL = %q, want trailing slash trimmed", cfg.BaseURL)
+	}
+	if cfg.TTL != 7*24*time.Hour {
+		t.Errorf("TTL = %v, want 7 days", cfg.TTL)
+	}
+	if cfg.Email {
+		t.Error("Email should be disabled")
+	}
+}
+
+func TestExportEmailTemplate(t *testing.T) {
+	msg, err := mailer.Render("account_export", map[string]interface{}{
+		"Username":  "alice",
+		"URL":       "https://api.example.com/api/v1/exports/tok",
+		"ExpiresAt": "Mar 31, 2024",
+		"Snippets":  3,
+	})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if msg.Subject == "" || !strings.Contains(msg.Text, "/api/v1/exports/tok") || !strings.Contains(msg.HTML, "Mar 31, 2024") {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+}
@@ -0,0 +1,53 @@
+// Package export provides a streaming NDJSON encoding of a user's snippets.
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+// ndjsonFlushEvery is how many lines are buffered before being flushed to the client
+const ndjsonFlushEvery = 50
+
+// NDJSONWriter writes exported snippets as newline-delimited JSON, one snippet per line.
+// When the underlying writer is an http.Flusher it flushes every few lines, so a large
+// export reaches the client as it is read instead of piling up in a buffer. Writes block
+// while the client is slow to read, which holds the database cursors back with them.
+type NDJSONWriter struct {
+	enc     *json.Encoder
+	flusher http.Flusher
+	lines   int
+}
+
+// NewNDJSONWriter creates an NDJSONWriter writing to w
+func NewNDJSONWriter(w io.Writer) *NDJSONWriter {
+	flusher, _ := w.(http.Flusher)
+	return &NDJSONWriter{enc: json.NewEncoder(w), flusher: flusher}
+}
+
+// Encode writes snippet as one line
+func (w *NDJSONWriter) Encode(snippet *models.ExportedSnippet) error {
+	if err := w.enc.Encode(snippet); err != nil {
+		return err
+	}
+	w.lines++
+	if w.flusher != nil && w.lines%ndjsonFlushEvery == 0 {
+		w.flusher.Flush()
+	}
+	return nil
+}
+
+// Lines returns how many lines have been written
+func (w *NDJSONWriter) Lines() int {
+	return w.lines
+}
+
+// Flush flushes buffered lines to the client, if the writer supports it
+func (w *NDJSONWriter) Flush() {
+	if w.flusher != nil {
+		w.flusher.Flush()
+	}
+}
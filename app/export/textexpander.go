@@ -0,0 +1,87 @@
+// Package export provides streaming TextExpander CSV files of a user's snippets.
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"github.com/jheysaaz/snippy-backend/app/placeholder"
+)
+
+// TextExpanderFileName is the name of the TextExpander CSV file
+const TextExpanderFileName = "snippy-textexpander.csv"
+
+// textExpanderMacros are the TextExpander macros the built-in placeholders become
+var textExpanderMacros = map[string]string{
+	placeholder.TypeDate:     "%Y-%m-%d",
+	placeholder.TypeTime:     "%H:%M",
+	placeholder.TypeDateTime: "%Y-%m-%dT%H:%M:%S",
+	"clipboard":              "%clipboard",
+}
+
+// TextExpanderContent converts a snippet's content to TextExpander's syntax: placeholders
+// become fill-in fields named after them, the same name sharing one, and the built-in
+// ones date, time and clipboard macros. Other % are doubled so TextExpander types them as
+// written.
+func TextExpanderContent(content string) string {
+	return placeholder.ReplaceFunc(strings.ReplaceAll(content, "%", "%%"), func(p placeholder.Placeholder) string {
+		if placeholder.IsBuiltin(p.Name) {
+			return textExpanderMacros[strings.ToLower(p.Name)]
+		}
+		return "%filltext:name=" + p.Name + "%"
+	})
+}
+
+// TextExpanderWriter writes snippets as CSV for TextExpander's import, one snippet per
+// row: abbreviation (the shortcut), content and label, without a header row. Like
+// ArchiveWriter it writes each snippet as it's encoded and nothing before the first
+// Encode or Close.
+type TextExpanderWriter struct {
+	csv      *csv.Writer
+	flusher  http.Flusher
+	snippets int
+}
+
+// NewTextExpanderWriter creates a TextExpanderWriter writing to w
+func NewTextExpanderWriter(w io.Writer) *TextExpanderWriter {
+	flusher, _ := w.(http.Flusher)
+	return &TextExpanderWriter{csv: csv.NewWriter(w), flusher: flusher}
+}
+
+// Encode writes snippet as a row
+func (w *TextExpanderWriter) Encode(snippet *models.ExportedSnippet) error {
+	s := &snippet.Snippet
+	if err := w.csv.Write([]string{s.Shortcut, TextExpanderContent(s.Content), s.Label}); err != nil {
+		return err
+	}
+	w.snippets++
+	if w.snippets%ndjsonFlushEvery == 0 {
+		return w.flush()
+	}
+	return nil
+}
+
+// Snippets returns how many snippets have been written
+func (w *TextExpanderWriter) Snippets() int {
+	return w.snippets
+}
+
+// Close writes the buffered rows
+func (w *TextExpanderWriter) Close() error {
+	return w.flush()
+}
+
+// flush sends the buffered rows to the client
+func (w *TextExpanderWriter) flush() error {
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		return err
+	}
+	if w.flusher != nil {
+		w.flusher.Flush()
+	}
+	return nil
+}
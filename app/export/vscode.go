@@ -0,0 +1,157 @@
+// Package export provides a streaming VS Code snippets file of a user's snippets.
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"github.com/jheysaaz/snippy-backend/app/placeholder"
+)
+
+// VSCodeFileName is the name of the VS Code snippets file
+const VSCodeFileName = "snippy.code-snippets"
+
+// vsCodeScopes maps snippet languages to VS Code language identifiers where they differ
+var vsCodeScopes = map[string]string{
+	"bash":  "shellscript",
+	"c#":    "csharp",
+	"c++":   "cpp",
+	"sh":    "shellscript",
+	"shell": "shellscript",
+	"zsh":   "shellscript",
+}
+
+// vsCodeVariables are the VS Code variables the built-in placeholders become
+var vsCodeVariables = map[string]string{
+	placeholder.TypeDate:     "${CURRENT_YEAR}-${CURRENT_MONTH}-${CURRENT_DATE}",
+	placeholder.TypeTime:     "${CURRENT_HOUR}:${CURRENT_MINUTE}",
+	placeholder.TypeDateTime: "${CURRENT_YEAR}-${CURRENT_MONTH}-${CURRENT_DATE}T${CURRENT_HOUR}:${CURRENT_MINUTE}:${CURRENT_SECOND}",
+	"clipboard":              "${CLIPBOARD}",
+}
+
+// vsCodeEscaper escapes the characters VS Code snippet bodies give a meaning to
+var vsCodeEscaper = strings.NewReplacer(`\`, `\\`, `$`, `\$`)
+
+// VSCodeSnippet is one entry of a .code-snippets file
+type VSCodeSnippet struct {
+	Prefix      string   `json:"prefix"`
+	Scope       string   `json:"scope,omitempty"`
+	Body        []string `json:"body"`
+	Description string   `json:"description,omitempty"`
+}
+
+// NewVSCodeSnippet converts a snippet: the shortcut is the prefix, the content the body,
+// one line per element, the language the scope and the description the description shown
+// in VS Code's suggestions. Placeholders become tab stops named
+// after them, the same name sharing one, and the built-in ones VS Code variables; other
+// $ and \ are escaped so VS Code inserts them as written.
+func NewVSCodeSnippet(s *models.Snippet) VSCodeSnippet {
+	stops := make(map[string]int)
+	body := placeholder.ReplaceFunc(vsCodeEscaper.Replace(s.Content), func(p placeholder.Placeholder) string {
+		if placeholder.IsBuiltin(p.Name) {
+			return vsCodeVariables[strings.ToLower(p.Name)]
+		}
+		stop, ok := stops[p.Name]
+		if !ok {
+			stop = len(stops) + 1
+			stops[p.Name] = stop
+		}
+		return "${" + strconv.Itoa(stop) + ":" + p.Name + "}"
+	})
+
+	snippet := VSCodeSnippet{
+		Prefix: s.Shortcut,
+		Body:   strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n"),
+	}
+	if s.Description != nil {
+		snippet.Description = *s.Description
+	}
+	if s.Language != nil && *s.Language != "" {
+		snippet.Scope = *s.Language
+		if scope, ok := vsCodeScopes[*s.Language]; ok {
+			snippet.Scope = scope
+		}
+	}
+	return snippet
+}
+
+// VSCodeWriter writes snippets as a VS Code .code-snippets file, a JSON object keyed by
+// snippet name. Snippets are named after their label, with their shortcut added when
+// another snippet took the label. Like ArchiveWriter it writes each snippet as it's
+// encoded and nothing before the first Encode or Close.
+type VSCodeWriter struct {
+	w        io.Writer
+	flusher  http.Flusher
+	names    map[string]bool
+	snippets int
+	opened   bool
+}
+
+// NewVSCodeWriter creates a VSCodeWriter writing to w
+func NewVSCodeWriter(w io.Writer) *VSCodeWriter {
+	flusher, _ := w.(http.Flusher)
+	return &VSCodeWriter{w: w, flusher: flusher, names: make(map[string]bool)}
+}
+
+// name returns a name for a snippet no earlier snippet has
+func (w *VSCodeWriter) name(s *models.Snippet) string {
+	name := s.Label
+	if w.names[name] {
+		name = s.Label + " (" + s.Shortcut + ")"
+	}
+	for n := 2; w.names[name]; n++ {
+		name = s.Label + " (" + s.Shortcut + ") " + strconv.Itoa(n)
+	}
+	w.names[name] = true
+	return name
+}
+
+// Encode writes snippet as an entry of the file
+func (w *VSCodeWriter) Encode(snippet *models.ExportedSnippet) error {
+	separator := ",\n"
+	if !w.opened {
+		w.opened = true
+		separator = "{\n"
+	}
+	name, err := json.Marshal(w.name(&snippet.Snippet))
+	if err != nil {
+		return err
+	}
+	entry, err := json.MarshalIndent(NewVSCodeSnippet(&snippet.Snippet), "  ", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w.w, separator+"  "+string(name)+": "+string(entry)); err != nil {
+		return err
+	}
+	w.snippets++
+	if w.flusher != nil && w.snippets%ndjsonFlushEvery == 0 {
+		w.flusher.Flush()
+	}
+	return nil
+}
+
+// Snippets returns how many snippets have been written
+func (w *VSCodeWriter) Snippets() int {
+	return w.snippets
+}
+
+// Close ends the file
+func (w *VSCodeWriter) Close() error {
+	end := "\n}\n"
+	if !w.opened {
+		w.opened = true
+		end = "{}\n"
+	}
+	if _, err := io.WriteString(w.w, end); err != nil {
+		return err
+	}
+	if w.flusher != nil {
+		w.flusher.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,382 @@
+// Package gitsync mirrors users' snippets to Git repositories, one Markdown file per
+// snippet with its metadata in YAML front matter.
+package gitsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/goccy/go-yaml"
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"github.com/jheysaaz/snippy-backend/app/snippetquery"
+)
+
+const (
+	// Dir is the repository directory snippet files are written to; files elsewhere
+	// are left alone
+	Dir = "snippets"
+
+	// pollInterval is how often the job looks for due mirrors
+	pollInterval = 15 * time.Second
+
+	// batchSize is how many mirrors are claimed at a time
+	batchSize = 5
+
+	// syncTimeout bounds fetching, committing and pushing one mirror
+	syncTimeout = 2 * time.Minute
+
+	// maxSlugLength caps the label part of file names
+	maxSlugLength = 50
+
+	// maxErrorLength caps the error kept on the mirror
+	maxErrorLength = 500
+
+	// defaultUsername is sent with the token when the mirror has no username; hosts
+	// such as GitHub accept any name with a personal access token
+	defaultUsername = "git"
+)
+
+// author signs mirror commits
+var author = object.Signature{Name: "Snippy", Email: "noreply@snippy.app"}
+
+// FileName returns the path of a snippet's file: its ID followed by a slug of its label,
+// so files stay unique and sorted by age while being readable
+func FileName(s models.Snippet) string {
+	name := strconv.FormatInt(s.ID, 10)
+	if slug := slugify(s.Label); slug != "" {
+		name += "-" + slug
+	}
+	return path.Join(Dir, name+".md")
+}
+
+// slugify lowercases label and joins its letters and digits with dashes
+func slugify(label string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(label) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+			if b.Len() >= maxSlugLength {
+				break
+			}
+			continue
+		}
+		dash = true
+	}
+	return b.String()
+}
+
+// Render returns a snippet's file: YAML front matter followed by the content
+func Render(s models.Snippet) ([]byte, error) {
+	tags := s.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	// A MapSlice keeps the keys in reading order
+	meta, err := yaml.Marshal(yaml.MapSlice{
+		{Key: "id", Value: s.ID},
+		{Key: "label", Value: s.Label},
+		{Key: "shortcut", Value: s.Shortcut},
+		{Key: "tags", Value: tags},
+		{Key: "visibility", Value: s.Visibility},
+		{Key: "createdAt", Value: s.CreatedAt.UTC()},
+		{Key: "updatedAt", Value: s.UpdatedAt.UTC()},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("---\n")
+	buf.Write(meta)
+	buf.WriteString("---\n\n")
+	buf.WriteString(s.Content)
+	if !strings.HasSuffix(s.Content, "\n") {
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// ValidBranch reports whether name can be used as a branch name
+func ValidBranch(name string) bool {
+	return plumbing.NewBranchReferenceName(name).Validate() == nil
+}
+
+// Sync makes Dir on the mirror's branch hold exactly the given snippets, committing and
+// pushing any difference. It creates the branch if the repository doesn't have it yet.
+// Returns the commit the branch is left at and whether a new commit was pushed.
+func Sync(ctx context.Context, mirror models.GitMirror, snippets []models.Snippet) (string, bool, error) {
+	var auth transport.AuthMethod
+	if mirror.Token != "" {
+		username := mirror.Username
+		if username == "" {
+			username = defaultUsername
+		}
+		auth = &http.BasicAuth{Username: username, Password: mirror.Token}
+	}
+
+	repo, err := git.Init(memory.NewStorage(), memfs.New())
+	if err != nil {
+		return "", false, err
+	}
+	if _, err := repo.CreateRemote(&config.RemoteConfig{Name: git.DefaultRemoteName, URLs: []string{mirror.RepoURL}}); err != nil {
+		return "", false, err
+	}
+
+	branch := plumbing.NewBranchReferenceName(mirror.Branch)
+	remoteBranch := plumbing.NewRemoteReferenceName(git.DefaultRemoteName, mirror.Branch)
+	if err := repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, branch)); err != nil {
+		return "", false, err
+	}
+
+	err = repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: git.DefaultRemoteName,
+		RefSpecs:   []config.RefSpec{config.RefSpec("+" + branch.String() + ":" + remoteBranch.String())},
+		Auth:       auth,
+	})
+	worktree, wtErr := repo.Worktree()
+	if wtErr != nil {
+		return "", false, wtErr
+	}
+	switch {
+	case err == nil:
+		ref, err := repo.Reference(remoteBranch, true)
+		if err != nil {
+			return "", false, err
+		}
+		if err := repo.Storer.SetReference(plumbing.NewHashReference(branch, ref.Hash())); err != nil {
+			return "", false, err
+		}
+		if err := worktree.Checkout(&git.CheckoutOptions{Branch: branch}); err != nil {
+			return "", false, fmt.Errorf("check out %s: %w", mirror.Branch, err)
+		}
+	case errors.Is(err, transport.ErrEmptyRemoteRepository), errors.Is(err, git.NoMatchingRefSpecError{}):
+		// The branch is created by the first commit
+	default:
+		return "", false, fmt.Errorf("fetch %s: %w", mirror.Branch, err)
+	}
+
+	if err := writeSnippets(worktree, snippets); err != nil {
+		return "", false, err
+	}
+	if err := worktree.AddWithOptions(&git.AddOptions{All: true}); err != nil {
+		return "", false, err
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return "", false, err
+	}
+	if status.IsClean() {
+		head, err := repo.Head()
+		if err != nil {
+			// Nothing to commit to a new branch: no snippets yet
+			if errors.Is(err, plumbing.ErrReferenceNotFound) {
+				return "", false, nil
+			}
+			return "", false, err
+		}
+		return head.Hash().String(), false, nil
+	}
+
+	signature := author
+	signature.When = time.Now()
+	commit, err := worktree.Commit(commitMessage(status), &git.CommitOptions{Author: &signature})
+	if err != nil {
+		return "", false, err
+	}
+
+	err = repo.PushContext(ctx, &git.PushOptions{
+		RemoteName: git.DefaultRemoteName,
+		RefSpecs:   []config.RefSpec{config.RefSpec(branch.String() + ":" + branch.String())},
+		Auth:       auth,
+	})
+	if err != nil {
+		return "", false, fmt.Errorf("push %s: %w", mirror.Branch, err)
+	}
+	return commit.String(), true, nil
+}
+
+// writeSnippets replaces the files in Dir with one per snippet
+func writeSnippets(worktree *git.Worktree, snippets []models.Snippet) error {
+	fs := worktree.Filesystem
+	if err := util.RemoveAll(fs, Dir); err != nil {
+		return err
+	}
+	if err := fs.MkdirAll(Dir, 0o755); err != nil {
+		return err
+	}
+	for _, s := range snippets {
+		content, err := Render(s)
+		if err != nil {
+			return fmt.Errorf("render snippet %d: %w", s.ID, err)
+		}
+		if err := util.WriteFile(fs, FileName(s), content, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// commitMessage summarizes the staged changes
+func commitMessage(status git.Status) string {
+	var added, changed, removed int
+	for _, file := range status {
+		switch file.Staging {
+		case git.Added:
+			added++
+		case git.Deleted:
+			removed++
+		case git.Unmodified, git.Untracked:
+		default:
+			changed++
+		}
+	}
+
+	var parts []string
+	for _, p := range []struct {
+		verb  string
+		count int
+	}{{"add", added}, {"update", changed}, {"remove", removed}} {
+		if p.count == 0 {
+			continue
+		}
+		noun := "snippets"
+		if p.count == 1 {
+			noun = "snippet"
+		}
+		parts = append(parts, fmt.Sprintf("%s %d %s", p.verb, p.count, noun))
+	}
+	if len(parts) == 0 {
+		return "Sync snippets"
+	}
+	msg := strings.Join(parts, ", ")
+	return strings.ToUpper(msg[:1]) + msg[1:]
+}
+
+// Sink is an outbox sink that schedules a sync of the owner's on_change mirror after
+// each snippet change. Pushing happens in Job so a slow Git host doesn't hold up other
+// sinks.
+type Sink struct {
+	store *models.Store
+}
+
+// NewSink creates a sink scheduling syncs in store
+func NewSink(store *models.Store) *Sink {
+	return &Sink{store: store}
+}
+
+// Name returns the sink name
+func (s *Sink) Name() string { return "git-mirror" }
+
+// Deliver schedules a mirror sync for snippet events
+func (s *Sink) Deliver(ctx context.Context, evt models.OutboxEvent) error {
+	if evt.AggregateType != models.AggregateSnippet {
+		return nil
+	}
+	var payload struct {
+		UserID *string `json:"userId"`
+	}
+	if err := json.Unmarshal(evt.Payload, &payload); err != nil {
+		return fmt.Errorf("decode %s payload: %w", evt.EventType, err)
+	}
+	if payload.UserID == nil {
+		return nil
+	}
+	return s.store.ScheduleGitMirrorSync(ctx, *payload.UserID)
+}
+
+// Job syncs due Git mirrors
+type Job struct {
+	store *models.Store
+}
+
+// NewJob creates a job syncing the mirrors in store
+func NewJob(store *models.Store) *Job {
+	return &Job{store: store}
+}
+
+// Run syncs due mirrors until ctx is cancelled
+func (j *Job) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Keep draining while full batches come back
+			for {
+				claimed, err := j.RunOnce(ctx)
+				if err != nil {
+					log.Printf("Git mirror sync run failed: %v", err)
+					break
+				}
+				if claimed < batchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// RunOnce syncs one batch of due mirrors and returns how many were claimed
+func (j *Job) RunOnce(ctx context.Context) (int, error) {
+	mirrors, err := j.store.ClaimGitMirrors(ctx, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, mirror := range mirrors {
+		commit, err := j.syncMirror(ctx, mirror)
+		if err != nil {
+			msg := err.Error()
+			if len(msg) > maxErrorLength {
+				msg = msg[:maxErrorLength]
+			}
+			if markErr := j.store.MarkGitMirrorFailed(ctx, mirror.UserID, errors.New(msg)); markErr != nil {
+				log.Printf("Failed to record git mirror failure for user %s: %v", mirror.UserID, markErr)
+			}
+			continue
+		}
+		if err := j.store.MarkGitMirrorSynced(ctx, mirror.UserID, commit); err != nil {
+			log.Printf("Failed to record git mirror sync for user %s: %v", mirror.UserID, err)
+		}
+	}
+	return len(mirrors), nil
+}
+
+// syncMirror pushes a user's current snippets to their mirror
+func (j *Job) syncMirror(ctx context.Context, mirror models.GitMirror) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, syncTimeout)
+	defer cancel()
+
+	snippets, err := j.store.ListUserSnippets(ctx, mirror.UserID, snippetquery.Filter{})
+	if err != nil {
+		return "", fmt.Errorf("load snippets: %w", err)
+	}
+	commit, _, err := Sync(ctx, mirror, snippets)
+	return commit, err
+}
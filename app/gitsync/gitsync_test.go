@@ -0,0 +1,181 @@
+package gitsync
+
+import (
+	"context"
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+func testSnippet(id int64, label, content string) models.Snippet {
+	created := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	return models.Snippet{
+		ID:         id,
+		Label:      label,
+		Shortcut:   "/s" + label[:1],
+		Content:    content,
+		Tags:       []string{"work"},
+		Visibility: models.VisibilityPrivate,
+		CreatedAt:  created,
+		UpdatedAt:  created.Add(time.Hour),
+	}
+}
+
+func TestFileName(t *testing.T) {
+	tests := []struct {
+		label string
+		want  string
+	}{
+		{"Email signature", "snippets/7-email-signature.md"},
+		{"  Ünïcode & -- symbols!  ", "snippets/7-n-code-symbols.md"},
+		{"???", "snippets/7.md"},
+		{strings.Repeat("a", 80), "snippets/7-" + strings.Repeat("a", maxSlugLength) + ".md"},
+	}
+	for _, tt := range tests {
+		if got := FileName(models.Snippet{ID: 7, Label: tt.label}); got != tt.want {
+			t.Errorf("FileName(%q) = %q, want %q", tt.label, got, tt.want)
+		}
+	}
+}
+
+func TestValidBranch(t *testing.T) {
+	for _, name := range []string{"main", "backup/snippets", "v1.2"} {
+		if !ValidBranch(name) {
+			t.Errorf("ValidBranch(%q) = false, want true", name)
+		}
+	}
+	for _, name := range []string{"a..b", "has space", "ends.lock", "-x/../y", "a~1"} {
+		if ValidBranch(name) {
+			t.Errorf("ValidBranch(%q) = true, want false", name)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	got, err := Render(testSnippet(42, "Greeting: hello", "Hi there"))
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	want := "---\n" +
+		"id: 42\n" +
+		"label: \"Greeting: hello\"\n" +
+		"shortcut: /sG\n" +
+		"tags:\n- work\n" +
+		"visibility: private\n" +
+		"createdAt: 2026-03-01T09:30:00Z\n" +
+		"updatedAt: 2026-03-01T10:30:00Z\n" +
+		"---\n\nHi there\n"
+	if string(got) != want {
+		t.Errorf("Render =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCommitMessage(t *testing.T) {
+	status := git.Status{
+		"snippets/1-a.md": {Staging: git.Added},
+		"snippets/2-b.md": {Staging: git.Added},
+		"snippets/3-c.md": {Staging: git.Modified},
+		"snippets/4-d.md": {Staging: git.Deleted},
+	}
+	if got, want := commitMessage(status), "Add 2 snippets, update 1 snippet, remove 1 snippet"; got != want {
+		t.Errorf("commitMessage = %q, want %q", got, want)
+	}
+}
+
+func TestSinkIgnoresOtherAggregates(t *testing.T) {
+	err := NewSink(nil).Deliver(context.Background(), models.OutboxEvent{
+		AggregateType: models.AggregateUser,
+		EventType:     models.EventUserDeleted,
+		Payload:       json.RawMessage(`{}`),
+	})
+	if err != nil {
+		t.Errorf("Deliver(user event) = %v, want nil", err)
+	}
+}
+
+func TestSync(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	remote := t.TempDir()
+	if _, err := git.PlainInit(remote, true); err != nil {
+		t.Fatalf("init remote: %v", err)
+	}
+	mirror := models.GitMirror{RepoURL: remote, Branch: "main"}
+	ctx := context.Background()
+
+	first, pushed, err := Sync(ctx, mirror, []models.Snippet{
+		testSnippet(1, "Alpha", "one"),
+		testSnippet(2, "Beta", "two"),
+	})
+	if err != nil {
+		t.Fatalf("first Sync: %v", err)
+	}
+	if !pushed || first == "" {
+		t.Fatalf("first Sync = %q, %v; want a pushed commit", first, pushed)
+	}
+
+	// An unchanged set of snippets doesn't commit
+	again, pushed, err := Sync(ctx, mirror, []models.Snippet{
+		testSnippet(1, "Alpha", "one"),
+		testSnippet(2, "Beta", "two"),
+	})
+	if err != nil {
+		t.Fatalf("unchanged Sync: %v", err)
+	}
+	if pushed || again != first {
+		t.Errorf("unchanged Sync = %q, %v; want %q without a push", again, pushed, first)
+	}
+
+	// Renaming, editing and deleting replace the files
+	second, pushed, err := Sync(ctx, mirror, []models.Snippet{
+		testSnippet(1, "Alpha renamed", "one, edited"),
+	})
+	if err != nil {
+		t.Fatalf("second Sync: %v", err)
+	}
+	if !pushed || second == first {
+		t.Fatalf("second Sync = %q, %v; want a new pushed commit", second, pushed)
+	}
+
+	repo, err := git.PlainOpen(remote)
+	if err != nil {
+		t.Fatalf("open remote: %v", err)
+	}
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName("main"), true)
+	if err != nil {
+		t.Fatalf("main branch: %v", err)
+	}
+	if ref.Hash().String() != second {
+		t.Errorf("main = %s, want %s", ref.Hash(), second)
+	}
+	commit, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if commit.NumParents() != 1 || commit.ParentHashes[0].String() != first {
+		t.Errorf("second commit parents = %v, want [%s]", commit.ParentHashes, first)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		t.Fatalf("tree: %v", err)
+	}
+	var files []string
+	if err := tree.Files().ForEach(func(f *object.File) error {
+		files = append(files, f.Name)
+		return nil
+	}); err != nil {
+		t.Fatalf("files: %v", err)
+	}
+	if len(files) != 1 || files[0] != "snippets/1-alpha-renamed.md" {
+		t.Errorf("files = %v, want [snippets/1-alpha-renamed.md]", files)
+	}
+}
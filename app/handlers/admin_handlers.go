@@ -0,0 +1,474 @@
+// Package handlers provides admin-only operational endpoints.
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/backup"
+	"github.com/jheysaaz/snippy-backend/app/config"
+	"github.com/jheysaaz/snippy-backend/app/database"
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"github.com/jheysaaz/snippy-backend/app/search"
+	"github.com/lib/pq"
+)
+
+// getIndexStats reports index usage and bloat indicators along with the last maintenance run
+// @Summary Get index statistics
+// @Description Report unused and likely bloated indexes plus the last ANALYZE run (admin only)
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/stats/indexes [get]
+func (s *Server) getIndexStats(c *gin.Context) {
+	stats, err := database.GetIndexStats(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch index statistics")
+		return
+	}
+
+	unused := make([]database.IndexStat, 0)
+	bloated := make([]database.IndexStat, 0)
+	for _, st := range stats {
+		if st.Unused {
+			unused = append(unused, st)
+		}
+		if st.Bloated {
+			bloated = append(bloated, st)
+		}
+	}
+
+	respondSuccess(c, http.StatusOK, gin.H{
+		"lastMaintenance": database.LastMaintenanceRun(),
+		"indexes":         stats,
+		"unused":          unused,
+		"bloated":         bloated,
+	})
+}
+
+// getAdminStats reports aggregate counts, per-day series, storage usage and cleanup activity
+// @Summary Get dashboard statistics
+// @Description Totals, new users and snippets per day, active sessions, storage usage and the last cleanup run (admin only)
+// @Tags admin
+// @Produce json
+// @Param days query int false "Days covered by the time series (default 30, max 365)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/stats [get]
+func (s *Server) getAdminStats(c *gin.Context) {
+	days := 30
+	if daysStr := c.Query("days"); daysStr != "" {
+		d, err := strconv.Atoi(daysStr)
+		if err != nil || d < 1 || d > 365 {
+			respondError(c, http.StatusBadRequest, "days must be between 1 and 365")
+			return
+		}
+		days = d
+	}
+
+	stats, err := s.store.GetAdminStats(c.Request.Context(), days)
+	if err != nil {
+		log.Printf("Failed to collect admin stats: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to fetch statistics")
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, gin.H{
+		"stats":           stats,
+		"lastCleanup":     database.LastCleanupRun(),
+		"lastMaintenance": database.LastMaintenanceRun(),
+	})
+}
+
+// Admin user status filters
+const (
+	adminUserStatusAll     = "all"
+	adminUserStatusActive  = "active"
+	adminUserStatusDeleted = "deleted"
+)
+
+// adminUserFilter holds the parsed filters for the admin users list
+type adminUserFilter struct {
+	RegisteredAfter  *time.Time
+	RegisteredBefore *time.Time
+	Verified         *bool
+	Status           string
+	Search           string
+	Roles            []string
+	Limit            int
+	Offset           int
+}
+
+// parseAdminUserFilter reads status, verification, role, date range and pagination parameters
+func parseAdminUserFilter(c *gin.Context) (adminUserFilter, error) {
+	filter := adminUserFilter{Status: adminUserStatusAll, Limit: 50}
+
+	filter.Limit, filter.Offset = parseLimitOffset(c, 50, 100)
+
+	switch status := c.Query("status"); status {
+	case "":
+	case adminUserStatusAll, adminUserStatusActive, adminUserStatusDeleted:
+		filter.Status = status
+	default:
+		return filter, errors.New("status must be one of all, active, deleted")
+	}
+
+	if verifiedStr := c.Query("verified"); verifiedStr != "" {
+		verified, err := strconv.ParseBool(verifiedStr)
+		if err != nil {
+			return filter, errors.New("verified must be true or false")
+		}
+		filter.Verified = &verified
+	}
+
+	if rolesStr := c.Query("role"); rolesStr != "" {
+		for _, role := range strings.Split(rolesStr, ",") {
+			if role = strings.TrimSpace(role); role != "" {
+				filter.Roles = append(filter.Roles, role)
+			}
+		}
+	}
+
+	filter.Search = strings.TrimSpace(c.Query("search"))
+
+	var err error
+	if filter.RegisteredAfter, err = parseTimeQuery(c, "registered_after"); err != nil {
+		return filter, err
+	}
+	if filter.RegisteredBefore, err = parseTimeQuery(c, "registered_before"); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// buildAdminUserQuery builds the parameterized admin users query.
+// The total column counts all matching rows so clients can paginate.
+func buildAdminUserQuery(f adminUserFilter) (string, []interface{}) {
+	query := `
+		SELECT u.id, u.username, u.email, COALESCE(u.full_name, ''), u.created_at, u.updated_at,
+		       COALESCE(u.is_deleted, false), u.deleted_at, u.email_verified_at, u.last_login_at,
+		       ARRAY(
+		           SELECT r.name FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+		           WHERE ur.user_id = u.id ORDER BY r.name
+		       ),
+		       COUNT(*) OVER()
+		FROM users u
+		WHERE true
+	`
+	args := []interface{}{}
+	argPos := 1
+
+	switch f.Status {
+	case adminUserStatusActive:
+		query += " AND u.is_deleted = false"
+	case adminUserStatusDeleted:
+		query += " AND u.is_deleted = true"
+	}
+
+	if f.Verified != nil {
+		if *f.Verified {
+			query += " AND u.email_verified_at IS NOT NULL"
+		} else {
+			query += " AND u.email_verified_at IS NULL"
+		}
+	}
+
+	if len(f.Roles) > 0 {
+		query += ` AND EXISTS (
+			SELECT 1 FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+			WHERE ur.user_id = u.id AND r.name = ANY($` + strconv.Itoa(argPos) + `)
+		)`
+		args = append(args, pq.Array(f.Roles))
+		argPos++
+	}
+
+	if f.Search != "" {
+		query += " AND (u.username ILIKE $" + strconv.Itoa(argPos) + " OR u.email ILIKE $" + strconv.Itoa(argPos) + ")"
+		args = append(args, "%"+escapeLike(f.Search)+"%")
+		argPos++
+	}
+
+	if f.RegisteredAfter != nil {
+		query += " AND u.created_at >= $" + strconv.Itoa(argPos)
+		args = append(args, *f.RegisteredAfter)
+		argPos++
+	}
+
+	if f.RegisteredBefore != nil {
+		query += " AND u.created_at < $" + strconv.Itoa(argPos)
+		args = append(args, *f.RegisteredBefore)
+		argPos++
+	}
+
+	query += " ORDER BY u.created_at DESC, u.id DESC"
+	query += " LIMIT $" + strconv.Itoa(argPos) + " OFFSET $" + strconv.Itoa(argPos+1)
+	args = append(args, f.Limit, f.Offset)
+
+	return query, args
+}
+
+// listAdminUsers lists all users, including deleted ones, with admin-only filters
+// @Summary List users (admin)
+// @Description List users including deleted accounts, filtered by status, verification, roles and registration date (admin only)
+// @Tags admin
+// @Produce json
+// @Param status query string false "all (default), active or deleted"
+// @Param verified query bool false "Filter by email verification state"
+// @Param role query string false "Comma-separated role names; matches users with any of them"
+// @Param search query string false "Case-insensitive match on username or email"
+// @Param registered_after query string false "RFC3339 timestamp (inclusive)"
+// @Param registered_before query string false "RFC3339 timestamp (exclusive)"
+// @Param limit query int false "Limit results (default 50, max 100)"
+// @Param offset query int false "Offset for pagination"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/users [get]
+func (s *Server) listAdminUsers(c *gin.Context) {
+	filter, err := parseAdminUserFilter(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query, args := buildAdminUserQuery(filter)
+	rows, err := s.db.QueryContext(c.Request.Context(), query, args...)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch users")
+		return
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("error closing admin user rows: %v", err)
+		}
+	}()
+
+	total := 0
+	users := make([]models.AdminUser, 0, 10)
+	for rows.Next() {
+		var u models.AdminUser
+		var roles pq.StringArray
+		var deletedAt, verifiedAt, lastLoginAt sql.NullTime
+		if err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.FullName, &u.CreatedAt, &u.UpdatedAt,
+			&u.IsDeleted, &deletedAt, &verifiedAt, &lastLoginAt, &roles, &total); err != nil {
+			respondError(c, http.StatusInternalServerError, "Failed to scan user")
+			return
+		}
+		if deletedAt.Valid {
+			u.DeletedAt = &deletedAt.Time
+		}
+		if verifiedAt.Valid {
+			u.EmailVerifiedAt = &verifiedAt.Time
+			u.EmailVerified = true
+		}
+		if lastLoginAt.Valid {
+			u.LastLoginAt = &lastLoginAt.Time
+		}
+		u.Roles = roles
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		respondError(c, http.StatusInternalServerError, "Error iterating users")
+		return
+	}
+
+	respondPage(c, users, len(users), total, filter.Offset)
+}
+
+// parseAuditFilter reads action, actor, target and date range filters and pagination
+func parseAuditFilter(c *gin.Context) (models.AuditFilter, error) {
+	var filter models.AuditFilter
+	filter.Limit, filter.Offset = parseLimitOffset(c, 50, 100)
+
+	if actionsStr := c.Query("action"); actionsStr != "" {
+		for _, action := range strings.Split(actionsStr, ",") {
+			if action = strings.TrimSpace(action); action != "" {
+				filter.Actions = append(filter.Actions, action)
+			}
+		}
+	}
+
+	filter.ActorID = c.Query("actor_id")
+	if filter.ActorID != "" && !isValidUUID(filter.ActorID) {
+		return filter, errors.New("actor_id must be a UUID")
+	}
+	filter.TargetUserID = c.Query("target_user_id")
+	if filter.TargetUserID != "" && !isValidUUID(filter.TargetUserID) {
+		return filter, errors.New("target_user_id must be a UUID")
+	}
+	filter.TargetType = c.Query("target_type")
+
+	var err error
+	if filter.Since, err = parseTimeQuery(c, "since"); err != nil {
+		return filter, err
+	}
+	if filter.Until, err = parseTimeQuery(c, "until"); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// listAuditLog lists audited admin, account and session actions
+// @Summary List audit log (admin)
+// @Description List role changes, announcement changes, account deletions/purges and forced logouts, newest first (admin only)
+// @Tags admin
+// @Produce json
+// @Param action query string false "Comma-separated actions, e.g. role.assigned,session.idle_logged_out"
+// @Param actor_id query string false "User who performed the action"
+// @Param target_user_id query string false "User affected by the action"
+// @Param target_type query string false "user, session or announcement"
+// @Param since query string false "RFC3339 timestamp (inclusive)"
+// @Param until query string false "RFC3339 timestamp (exclusive)"
+// @Param limit query int false "Limit results (default 50, max 100)"
+// @Param offset query int false "Offset for pagination"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/audit-log [get]
+func (s *Server) listAuditLog(c *gin.Context) {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	entries, total, err := s.store.ListAuditLog(c.Request.Context(), filter)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch audit log")
+		return
+	}
+
+	respondPage(c, entries, len(entries), total, filter.Offset)
+}
+
+// getBackupStatus reports the backup configuration and the most recent runs
+// @Summary Get backup status
+// @Description Whether scheduled backups are enabled, the last run and the last successful run (admin only)
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/backups [get]
+func (s *Server) getBackupStatus(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	lastRun, err := s.store.GetLastBackupRun(ctx, "")
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch backup status")
+		return
+	}
+	lastSuccess, err := s.store.GetLastBackupRun(ctx, models.BackupSucceeded)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch backup status")
+		return
+	}
+
+	cfg := backup.LoadConfig()
+	status := gin.H{
+		"enabled":           cfg.Enabled(),
+		"lastRun":           lastRun,
+		"lastSuccessfulRun": lastSuccess,
+	}
+	if cfg.Enabled() {
+		status["bucket"] = cfg.Bucket
+		status["interval"] = cfg.Interval.String()
+		status["retention"] = cfg.Retention
+	}
+	respondSuccess(c, http.StatusOK, status)
+}
+
+// reindexTimeout bounds a reindex started from the admin API
+const reindexTimeout = time.Hour
+
+// reindexSearch rebuilds the external search index from the snippets table
+// @Summary Reindex search
+// @Description Send every snippet to the configured Meilisearch or Elasticsearch index in the background, e.g. after enabling it (admin only)
+// @Tags admin
+// @Produce json
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/search/reindex [post]
+func (s *Server) reindexSearch(c *gin.Context) {
+	cfg := search.LoadConfig()
+	if !cfg.External() {
+		respondError(c, http.StatusBadRequest, "No external search engine is configured")
+		return
+	}
+	backend, err := search.New(cfg, s.store)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Invalid search configuration")
+		return
+	}
+	if search.Reindexing() {
+		respondError(c, http.StatusConflict, "A reindex is already running")
+		return
+	}
+
+	// The reindex outlives the request, so it gets its own deadline
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), reindexTimeout)
+		defer cancel()
+		if err := backend.EnsureIndex(ctx); err != nil {
+			log.Printf("Search reindex: ensure index: %v", err)
+			return
+		}
+		n, err := search.Reindex(ctx, s.store, backend)
+		if err != nil {
+			log.Printf("Search reindex failed after %d snippets: %v", n, err)
+			return
+		}
+		log.Printf("Search reindex finished: %d snippets indexed into %s", n, backend.Name())
+	}()
+
+	s.recordAudit(c, models.AuditAction{Action: models.AuditSearchReindexed, TargetType: models.AuditTargetSearchIndex, TargetID: cfg.Index})
+	respondSuccess(c, http.StatusAccepted, gin.H{"message": "Reindex started", "engine": backend.Name()})
+}
+
+// reloadConfig re-reads the reloadable settings
+// @Summary Reload configuration
+// @Description Re-read CONFIG_FILE and apply the reloadable settings (rate limits, CORS origins, log level, retention windows) without a restart, like SIGHUP. Settings with invalid values keep their previous ones and are listed under failed (admin only).
+// @Tags admin
+// @Produce json
+// @Success 200 {object} config.Result
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/config/reload [post]
+func (s *Server) reloadConfig(c *gin.Context) {
+	result, err := config.Reload()
+	if err != nil {
+		log.Printf("Config reload failed: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to read configuration file")
+		return
+	}
+
+	s.recordAudit(c, models.AuditAction{
+		Action:     models.AuditConfigReloaded,
+		TargetType: models.AuditTargetConfig,
+		TargetID:   result.File,
+		Details:    map[string]interface{}{"applied": result.Applied, "failed": result.Failed},
+	})
+	respondSuccess(c, http.StatusOK, result)
+}
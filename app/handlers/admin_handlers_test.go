@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseAdminUserFilter(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		wantStatus  string
+		wantRoles   int
+		wantErr     bool
+		wantVerify  bool
+		wantInRange bool
+	}{
+		{name: "Defaults", query: "", wantStatus: adminUserStatusAll},
+		{name: "Deleted unverified admins", query: "status=deleted&verified=false&role=admin,%20tester", wantStatus: adminUserStatusDeleted, wantRoles: 2, wantVerify: true},
+		{name: "Date range", query: "registered_after=2025-01-01T00:00:00Z&registered_before=2025-02-01T00:00:00Z", wantStatus: adminUserStatusAll, wantInRange: true},
+		{name: "Invalid status", query: "status=banned", wantErr: true},
+		{name: "Invalid verified", query: "verified=maybe", wantErr: true},
+		{name: "Invalid date", query: "registered_after=last-week", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/admin/users?"+tt.query, nil)
+
+			filter, err := parseAdminUserFilter(c)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if filter.Status != tt.wantStatus {
+				t.Errorf("Status = %s, want %s", filter.Status, tt.wantStatus)
+			}
+			if len(filter.Roles) != tt.wantRoles {
+				t.Errorf("Roles = %v, want %d roles", filter.Roles, tt.wantRoles)
+			}
+			if (filter.Verified != nil) != tt.wantVerify {
+				t.Errorf("Verified set = %v, want %v", filter.Verified != nil, tt.wantVerify)
+			}
+			if (filter.RegisteredAfter != nil && filter.RegisteredBefore != nil) != tt.wantInRange {
+				t.Errorf("date range parsed = %v, want %v", filter.RegisteredAfter != nil, tt.wantInRange)
+			}
+		})
+	}
+}
+
+func TestBuildAdminUserQuery(t *testing.T) {
+	verified := true
+	after := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	query, args := buildAdminUserQuery(adminUserFilter{
+		Status:          adminUserStatusDeleted,
+		Verified:        &verified,
+		Roles:           []string{"admin"},
+		Search:          "bob",
+		RegisteredAfter: &after,
+		Limit:           25,
+		Offset:          50,
+	})
+
+	for _, fragment := range []string{
+		"u.is_deleted = true",
+		"u.email_verified_at IS NOT NULL",
+		"r.name = ANY($1)",
+		"u.username ILIKE $2 OR u.email ILIKE $2",
+		"u.created_at >= $3",
+		"LIMIT $4 OFFSET $5",
+	} {
+		if !strings.Contains(query, fragment) {
+			t.Errorf("query missing %q:\n%s", fragment, query)
+		}
+	}
+	if len(args) != 5 {
+		t.Errorf("expected 5 args, got %d", len(args))
+	}
+
+	// The default filter includes deleted users and adds no conditions
+	query, args = buildAdminUserQuery(adminUserFilter{Status: adminUserStatusAll, Limit: 50})
+	if strings.Contains(query, "is_deleted =") || len(args) != 2 {
+		t.Errorf("unexpected default query (%d args):\n%s", len(args), query)
+	}
+}
+
+func TestParseAuditFilter(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		wantActions int
+		wantLimit   int
+		wantErr     bool
+		wantInRange bool
+	}{
+		{name: "Defaults", query: "", wantLimit: 50},
+		{name: "Actions and actor", query: "action=role.assigned,%20role.revoked&actor_id=0b3c9a1e-6a5f-4c1b-9d2e-3f4a5b6c7d8e&limit=10", wantActions: 2, wantLimit: 10},
+		{name: "Date range", query: "since=2025-01-01T00:00:00Z&until=2025-02-01T00:00:00Z", wantLimit: 50, wantInRange: true},
+		{name: "Invalid actor", query: "actor_id=admin", wantErr: true},
+		{name: "Invalid target user", query: "target_user_id=1", wantErr: true},
+		{name: "Invalid date", query: "since=yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/admin/audit-log?"+tt.query, nil)
+
+			filter, err := parseAuditFilter(c)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(filter.Actions) != tt.wantActions {
+				t.Errorf("Actions = %v, want %d actions", filter.Actions, tt.wantActions)
+			}
+			if filter.Limit != tt.wantLimit {
+				t.Errorf("Limit = %d, want %d", filter.Limit, tt.wantLimit)
+			}
+			if (filter.Since != nil && filter.Until != nil) != tt.wantInRange {
+				t.Errorf("date range parsed = %v, want %v", filter.Since != nil, tt.wantInRange)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+// Package handlers provides announcement endpoints.
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+// normalizeAnnouncementRequest applies defaults and validates the active window
+func normalizeAnnouncementRequest(req *models.AnnouncementRequest, now time.Time) error {
+	if req.Severity == "" {
+		req.Severity = models.SeverityInfo
+	}
+	if req.EndsAt != nil {
+		start := now
+		if req.StartsAt != nil {
+			start = *req.StartsAt
+		}
+		if !req.EndsAt.After(start) {
+			return errors.New("endsAt must be after startsAt")
+		}
+	}
+	return nil
+}
+
+// getAnnouncements returns the currently active announcements
+// @Summary List active announcements
+// @Description Get announcements whose active window includes now, most severe first (public)
+// @Tags announcements
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /announcements [get]
+func (s *Server) getAnnouncements(c *gin.Context) {
+	announcements, err := s.store.GetActiveAnnouncements(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch announcements")
+		return
+	}
+
+	respondWithCount(c, announcements, len(announcements))
+}
+
+// listAllAnnouncements returns every announcement including scheduled and expired ones
+// @Summary List all announcements
+// @Description Get all announcements regardless of their active window (admin only)
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/announcements [get]
+func (s *Server) listAllAnnouncements(c *gin.Context) {
+	announcements, err := s.store.GetAllAnnouncements(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch announcements")
+		return
+	}
+
+	respondWithCount(c, announcements, len(announcements))
+}
+
+// createAnnouncement publishes a new announcement
+// @Summary Create announcement
+// @Description Create an announcement with a severity and optional active window (admin only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param announcement body models.AnnouncementRequest true "Announcement data"
+// @Success 201 {object} models.Announcement
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/announcements [post]
+func (s *Server) createAnnouncement(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	var req models.AnnouncementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := normalizeAnnouncementRequest(&req, s.now()); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	announcement, err := s.store.CreateAnnouncement(c.Request.Context(), req, userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to create announcement")
+		return
+	}
+
+	s.recordAudit(c, models.AuditAction{
+		Action:     models.AuditAnnouncementCreated,
+		TargetType: models.AuditTargetAnnouncement,
+		TargetID:   strconv.FormatInt(announcement.ID, 10),
+		Details:    gin.H{"title": announcement.Title, "severity": announcement.Severity},
+	})
+
+	respondSuccess(c, http.StatusCreated, announcement)
+}
+
+// updateAnnouncement replaces an announcement
+// @Summary Update announcement
+// @Description Replace an announcement's content, severity and active window (admin only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Announcement ID"
+// @Param announcement body models.AnnouncementRequest true "Announcement data"
+// @Success 200 {object} models.Announcement
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/announcements/{id} [put]
+func (s *Server) updateAnnouncement(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid announcement ID")
+		return
+	}
+
+	var req models.AnnouncementRequest
+	if bindErr := c.ShouldBindJSON(&req); bindErr != nil {
+		respondError(c, http.StatusBadRequest, bindErr.Error())
+		return
+	}
+	if normErr := normalizeAnnouncementRequest(&req, s.now()); normErr != nil {
+		respondError(c, http.StatusBadRequest, normErr.Error())
+		return
+	}
+
+	announcement, err := s.store.UpdateAnnouncement(c.Request.Context(), id, req)
+	if handleScanError(c, err, "Announcement not found") {
+		return
+	}
+
+	s.recordAudit(c, models.AuditAction{
+		Action:     models.AuditAnnouncementUpdated,
+		TargetType: models.AuditTargetAnnouncement,
+		TargetID:   strconv.FormatInt(announcement.ID, 10),
+		Details:    gin.H{"title": announcement.Title, "severity": announcement.Severity},
+	})
+
+	respondSuccess(c, http.StatusOK, announcement)
+}
+
+// deleteAnnouncement removes an announcement
+// @Summary Delete announcement
+// @Description Delete an announcement (admin only)
+// @Tags admin
+// @Produce json
+// @Param id path int true "Announcement ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/announcements/{id} [delete]
+func (s *Server) deleteAnnouncement(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid announcement ID")
+		return
+	}
+
+	err = s.store.DeleteAnnouncement(c.Request.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(c, http.StatusNotFound, "Announcement not found")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to delete announcement")
+		return
+	}
+
+	s.recordAudit(c, models.AuditAction{
+		Action:     models.AuditAnnouncementDeleted,
+		TargetType: models.AuditTargetAnnouncement,
+		TargetID:   strconv.FormatInt(id, 10),
+	})
+
+	respondSuccess(c, http.StatusOK, gin.H{"message": "Announcement deleted successfully"})
+}
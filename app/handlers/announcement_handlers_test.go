@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+func TestNormalizeAnnouncementRequest(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	tests := []struct {
+		req          models.AnnouncementRequest
+		name         string
+		wantSeverity string
+		wantErr      bool
+	}{
+		{name: "Defaults severity to info", req: models.AnnouncementRequest{Title: "t", Message: "m"}, wantSeverity: models.SeverityInfo},
+		{name: "Keeps severity", req: models.AnnouncementRequest{Severity: models.SeverityCritical}, wantSeverity: models.SeverityCritical},
+		{name: "Ends after now", req: models.AnnouncementRequest{EndsAt: &future}, wantSeverity: models.SeverityInfo},
+		{name: "Ends before now", req: models.AnnouncementRequest{EndsAt: &past}, wantErr: true},
+		{name: "Ends before start", req: models.AnnouncementRequest{StartsAt: &future, EndsAt: &now}, wantErr: true},
+		{name: "Scheduled window", req: models.AnnouncementRequest{StartsAt: &past, EndsAt: &now}, wantSeverity: models.SeverityInfo},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			err := normalizeAnnouncementRequest(&req, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if req.Severity != tt.wantSeverity {
+				t.Errorf("Severity = %s, want %s", req.Severity, tt.wantSeverity)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+// createAPIKey issues an API key for a third-party integration
+// @Summary Create API key
+// @Description Issue an API key for an integration, limited to the chosen scopes (snippets:read, snippets:write, usage:write) and its own rate limit in requests per minute (default 60, max 600). The key is only shown once; send it as "Authorization: Bearer snk_...".
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param key body models.CreateAPIKeyRequest true "Key name, scopes and rate limit"
+// @Success 201 {object} models.APIKey
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/api-keys [post]
+func (s *Server) createAPIKey(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	var req models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	key, err := s.store.CreateAPIKey(c.Request.Context(), userID, req)
+	if errors.Is(err, models.ErrAPIKeyLimit) {
+		respondError(c, http.StatusConflict, "You can have at most "+strconv.Itoa(models.APIKeyMaxPerUser)+" API keys")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to create API key")
+		return
+	}
+
+	respondSuccess(c, http.StatusCreated, key)
+}
+
+// getMyAPIKeys lists the authenticated user's API keys with their usage
+// @Summary List API keys
+// @Description Your active API keys with their scopes, rate limits, request counts and last use (the keys themselves are not included)
+// @Tags users
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/api-keys [get]
+func (s *Server) getMyAPIKeys(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	keys, err := s.store.GetUserAPIKeys(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch API keys")
+		return
+	}
+
+	respondWithCount(c, keys, len(keys))
+}
+
+// revokeAPIKey revokes one of the authenticated user's API keys
+// @Summary Revoke API key
+// @Description Revoke an API key; requests made with it are rejected from then on
+// @Tags users
+// @Produce json
+// @Param keyId path int true "API key ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/api-keys/{keyId} [delete]
+func (s *Server) revokeAPIKey(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("keyId"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid API key ID")
+		return
+	}
+
+	err = s.store.RevokeAPIKey(c.Request.Context(), userID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(c, http.StatusNotFound, "API key not found")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to revoke API key")
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, gin.H{"message": "API key revoked"})
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCreateAPIKeyValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "Missing name", body: `{"scopes":["snippets:read"]}`},
+		{name: "No scopes", body: `{"name":"Raycast","scopes":[]}`},
+		{name: "Unknown scope", body: `{"name":"Raycast","scopes":["users:write"]}`},
+		{name: "Rate limit too high", body: `{"name":"Raycast","scopes":["snippets:read"],"rateLimit":10000}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Set("user_id", "0b3c9a1e-6a5f-4c1b-9d2e-3f4a5b6c7d8e")
+			c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/users/me/api-keys", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			NewServer(nil, nil).createAPIKey(c)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400 (body %s)", w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+// Package handlers provides avatar upload and serving endpoints.
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/avatar"
+)
+
+// multipartOverhead leaves room for form boundaries and headers around the file
+const multipartOverhead = 64 << 10
+
+// uploadAvatar validates, resizes and stores the authenticated user's avatar
+// @Summary Upload avatar
+// @Description Upload a PNG, JPEG or GIF avatar (max 2 MiB, 32-4096px). The image is center-cropped, metadata is stripped and 64px/256px variants are generated.
+// @Tags users
+// @Accept multipart/form-data
+// @Produce json
+// @Param avatar formData file true "Avatar image"
+// @Success 200 {object} models.User
+// @Failure 400 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 415 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/profile/avatar [post]
+func (s *Server) uploadAvatar(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, avatar.MaxUploadBytes+multipartOverhead)
+	fileHeader, err := c.FormFile("avatar")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondError(c, http.StatusRequestEntityTooLarge, avatar.ErrTooLarge.Error())
+			return
+		}
+		respondError(c, http.StatusBadRequest, "Avatar file is required")
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Failed to read avatar")
+		return
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			log.Printf("error closing avatar upload: %v", closeErr)
+		}
+	}()
+
+	// Read one byte past the limit so oversized files are detected rather than truncated
+	data, err := io.ReadAll(io.LimitReader(file, avatar.MaxUploadBytes+1))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Failed to read avatar")
+		return
+	}
+
+	variants, err := avatar.Process(data)
+	switch {
+	case errors.Is(err, avatar.ErrTooLarge):
+		respondError(c, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	case errors.Is(err, avatar.ErrUnsupportedType):
+		respondError(c, http.StatusUnsupportedMediaType, err.Error())
+		return
+	case errors.Is(err, avatar.ErrDimensions), errors.Is(err, avatar.ErrCorrupt):
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		log.Printf("Failed to process avatar for user %s: %v", userID, err)
+		respondError(c, http.StatusInternalServerError, "Failed to process avatar")
+		return
+	}
+
+	user, err := s.store.SaveAvatar(c.Request.Context(), userID, variants)
+	if handleScanError(c, err, "User not found") {
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, user)
+}
+
+// deleteAvatar removes the authenticated user's avatar
+// @Summary Delete avatar
+// @Description Remove the uploaded avatar and clear avatarUrl
+// @Tags users
+// @Produce json
+// @Success 200 {object} models.User
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/profile/avatar [delete]
+func (s *Server) deleteAvatar(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	user, err := s.store.DeleteAvatar(c.Request.Context(), userID)
+	if handleScanError(c, err, "User not found") {
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, user)
+}
+
+// getAvatar serves a processed avatar variant
+// @Summary Get avatar image
+// @Description Serve a user's avatar at one of the generated sizes (64 or 256)
+// @Tags users
+// @Produce png
+// @Produce jpeg
+// @Param userId path string true "User ID"
+// @Param size path int true "Variant size in pixels"
+// @Success 200 {file} binary
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /avatars/{userId}/{size} [get]
+func (s *Server) getAvatar(c *gin.Context) {
+	size, err := strconv.Atoi(c.Param("size"))
+	if err != nil || !avatar.IsValidSize(size) {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("Invalid avatar size, expected one of %v", avatar.Sizes))
+		return
+	}
+
+	img, err := s.store.GetAvatar(c.Request.Context(), c.Param("userId"), size)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(c, http.StatusNotFound, "Avatar not found")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch avatar")
+		return
+	}
+
+	// URLs carry a version parameter, so variants can be cached aggressively
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Last-Modified", img.UpdatedAt.UTC().Format(http.TimeFormat))
+	c.Data(http.StatusOK, img.ContentType, img.Data)
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/billing"
+)
+
+// maxStripeWebhookBytes bounds webhook payloads; Stripe events are well under this
+const maxStripeWebhookBytes = 256 << 10
+
+// createCheckoutSession starts a Stripe Checkout for the premium subscription
+// @Summary Start premium checkout
+// @Description Create a Stripe Checkout session for the premium plan and return its URL. Premium is granted once Stripe confirms the subscription.
+// @Tags billing
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /billing/checkout [post]
+func (s *Server) createCheckoutSession(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	cfg := billing.LoadConfig()
+	if !cfg.Enabled() {
+		respondError(c, http.StatusServiceUnavailable, "Billing is not available")
+		return
+	}
+	ctx := c.Request.Context()
+
+	params := billing.CheckoutParams{
+		UserID:     userID,
+		PriceID:    cfg.PriceID,
+		SuccessURL: cfg.SuccessURL,
+		CancelURL:  cfg.CancelURL,
+	}
+	sub, err := s.store.GetUserSubscription(ctx, userID)
+	switch {
+	case err == nil:
+		if sub.Premium {
+			respondError(c, http.StatusConflict, "You already have an active subscription")
+			return
+		}
+		params.CustomerID = sub.CustomerID
+	case !errors.Is(err, sql.ErrNoRows):
+		respondError(c, http.StatusInternalServerError, "Failed to fetch subscription")
+		return
+	}
+
+	if params.CustomerID == "" {
+		err = s.db.QueryRowContext(ctx,
+			`SELECT email FROM users WHERE id = $1 AND is_deleted = false`, userID).Scan(&params.Email)
+		if handleScanError(c, err, "User not found") {
+			return
+		}
+	}
+
+	url, err := billing.NewStripe(cfg.SecretKey).CreateCheckoutSession(ctx, params)
+	if err != nil {
+		log.Printf("Failed to create checkout session for user %s: %v", userID, err)
+		respondError(c, http.StatusBadGateway, "Failed to start checkout")
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, gin.H{"url": url})
+}
+
+// getMySubscription returns the authenticated user's subscription state
+// @Summary Get my subscription
+// @Description Subscription status and whether it currently grants premium
+// @Tags billing
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /billing/subscription [get]
+func (s *Server) getMySubscription(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	sub, err := s.store.GetUserSubscription(c.Request.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondSuccess(c, http.StatusOK, gin.H{"premium": false, "subscription": nil})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch subscription")
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, gin.H{"premium": sub.Premium, "subscription": sub})
+}
+
+// stripeWebhook receives Stripe subscription events
+// @Summary Stripe webhook
+// @Description Receives signed Stripe events and grants or revokes premium to match subscription status. Called by Stripe, not clients.
+// @Tags billing
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]bool
+// @Failure 400 {object} ErrorResponse
+// @Router /billing/webhook [post]
+func (s *Server) stripeWebhook(c *gin.Context) {
+	cfg := billing.LoadConfig()
+	if cfg.WebhookSecret == "" {
+		respondError(c, http.StatusServiceUnavailable, "Billing is not available")
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxStripeWebhookBytes))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid payload")
+		return
+	}
+	event, err := billing.ParseEvent(payload, c.GetHeader("Stripe-Signature"), cfg.WebhookSecret, s.now())
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid signature")
+		return
+	}
+
+	// A failure returns 500 so Stripe retries; applying an event twice is harmless
+	if err := billing.HandleEvent(c.Request.Context(), s.store, event); err != nil {
+		log.Printf("Failed to handle Stripe event %s (%s): %v", event.ID, event.Type, err)
+		respondError(c, http.StatusInternalServerError, "Failed to process event")
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, gin.H{"received": true})
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestStripeWebhookRejectsUnsignedEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("STRIPE_WEBHOOK_SECRET", "whsec_test")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/billing/webhook",
+		strings.NewReader(`{"id":"evt_1","type":"customer.subscription.created"}`))
+	c.Request.Header.Set("Stripe-Signature", "t=1700000000,v1=deadbeef")
+
+	NewServer(nil, nil).stripeWebhook(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400 (body %s)", w.Code, w.Body.String())
+	}
+}
+
+func TestCheckoutUnavailableWithoutStripe(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("STRIPE_SECRET_KEY", "")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user_id", "0b3c9a1e-6a5f-4c1b-9d2e-3f4a5b6c7d8e")
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/billing/checkout", nil)
+
+	NewServer(nil, nil).createCheckoutSession(c)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503 (body %s)", w.Code, w.Body.String())
+	}
+}
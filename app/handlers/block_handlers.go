@@ -0,0 +1,102 @@
+// Package handlers provides user blocking endpoints.
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// blockUser blocks another user
+// @Summary Block user
+// @Description Block a user. Any follows between you are removed and they can no longer follow you.
+// @Tags blocks
+// @Produce json
+// @Param id path string true "User ID to block"
+// @Success 200 {object} map[string]interface{}
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/{id}/block [post]
+func (s *Server) blockUser(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	blockedID := c.Param("id")
+	if blockedID == userID {
+		respondError(c, http.StatusBadRequest, "You cannot block yourself")
+		return
+	}
+
+	created, err := s.store.BlockUser(c.Request.Context(), userID, blockedID)
+	if handleScanError(c, err, "User not found") {
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	respondSuccess(c, status, gin.H{"message": "User blocked", "blocked": true})
+}
+
+// unblockUser removes a block
+// @Summary Unblock user
+// @Description Unblock a previously blocked user
+// @Tags blocks
+// @Produce json
+// @Param id path string true "User ID to unblock"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/{id}/block [delete]
+func (s *Server) unblockUser(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	err := s.store.UnblockUser(c.Request.Context(), userID, c.Param("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(c, http.StatusNotFound, "User is not blocked")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to unblock user")
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, gin.H{"message": "User unblocked", "blocked": false})
+}
+
+// getMyBlocks lists the users the authenticated user has blocked
+// @Summary List blocked users
+// @Description Get the users you have blocked, most recent first
+// @Tags blocks
+// @Produce json
+// @Param limit query int false "Limit results (default 50, max 100)"
+// @Param offset query int false "Offset for pagination"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Success 200 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /users/me/blocks [get]
+func (s *Server) getMyBlocks(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	limit, offset := parseLimitOffset(c, 50, 100)
+	users, total, err := s.store.GetBlockedUsers(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch blocked users")
+		return
+	}
+
+	respondPage(c, users, len(users), total, offset)
+}
@@ -0,0 +1,147 @@
+// Package handlers provides admin broadcast email endpoints.
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+// createBroadcast queues an email to all or filtered users
+// @Summary Queue broadcast email
+// @Description Queue an email to every active user, or those matching roles, verification and registration date filters. Emails are sent in the background at a throttled rate (admin only).
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param broadcast body models.BroadcastRequest true "Subject, message and recipient filter"
+// @Success 201 {object} models.Broadcast
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/broadcasts [post]
+func (s *Server) createBroadcast(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	var req models.BroadcastRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Filter.RegisteredAfter != nil && req.Filter.RegisteredBefore != nil &&
+		!req.Filter.RegisteredBefore.After(*req.Filter.RegisteredAfter) {
+		respondError(c, http.StatusBadRequest, "registeredBefore must be after registeredAfter")
+		return
+	}
+
+	audit := auditAction(c, models.AuditAction{
+		Action:     models.AuditBroadcastCreated,
+		TargetType: models.AuditTargetBroadcast,
+		Details:    gin.H{"subject": req.Subject, "filter": req.Filter},
+	})
+	broadcast, err := s.store.CreateBroadcast(c.Request.Context(), req, userID, &audit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to queue broadcast")
+		return
+	}
+
+	respondSuccess(c, http.StatusCreated, broadcast)
+}
+
+// listBroadcasts lists broadcast emails with delivery counts
+// @Summary List broadcast emails
+// @Description List queued and completed broadcasts, newest first, with recipient, pending, sent and failed counts (admin only)
+// @Tags admin
+// @Produce json
+// @Param limit query int false "Limit results (default 20, max 100)"
+// @Param offset query int false "Offset for pagination"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/broadcasts [get]
+func (s *Server) listBroadcasts(c *gin.Context) {
+	limit, offset := parseLimitOffset(c, 20, 100)
+
+	broadcasts, total, err := s.store.ListBroadcasts(c.Request.Context(), limit, offset)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch broadcasts")
+		return
+	}
+
+	respondPage(c, broadcasts, len(broadcasts), total, offset)
+}
+
+// getBroadcast returns a broadcast with delivery counts
+// @Summary Get broadcast email
+// @Description Get a broadcast and its delivery progress (admin only)
+// @Tags admin
+// @Produce json
+// @Param id path int true "Broadcast ID"
+// @Success 200 {object} models.Broadcast
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/broadcasts/{id} [get]
+func (s *Server) getBroadcast(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid broadcast ID")
+		return
+	}
+
+	broadcast, err := s.store.GetBroadcast(c.Request.Context(), id)
+	if handleScanError(c, err, "Broadcast not found") {
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, broadcast)
+}
+
+// getBroadcastRecipients lists per-recipient delivery status
+// @Summary List broadcast recipients
+// @Description List each recipient's delivery status, attempts and last error (admin only)
+// @Tags admin
+// @Produce json
+// @Param id path int true "Broadcast ID"
+// @Param status query string false "pending, sending, sent or failed"
+// @Param limit query int false "Limit results (default 50, max 100)"
+// @Param offset query int false "Offset for pagination"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/broadcasts/{id}/recipients [get]
+func (s *Server) getBroadcastRecipients(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid broadcast ID")
+		return
+	}
+
+	status := c.Query("status")
+	switch status {
+	case "", models.BroadcastPending, models.BroadcastSending, models.BroadcastSent, models.BroadcastFailed:
+	default:
+		respondError(c, http.StatusBadRequest, "status must be one of pending, sending, sent, failed")
+		return
+	}
+
+	if _, err := s.store.GetBroadcast(c.Request.Context(), id); handleScanError(c, err, "Broadcast not found") {
+		return
+	}
+
+	limit, offset := parseLimitOffset(c, 50, 100)
+	recipients, total, err := s.store.GetBroadcastRecipients(c.Request.Context(), id, status, limit, offset)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch broadcast recipients")
+		return
+	}
+
+	respondPage(c, recipients, len(recipients), total, offset)
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+// bulkDeleteSnippets soft-deletes many of the authenticated user's snippets at once
+// @Summary Delete many snippets
+// @Description Move up to 100 of your snippets to the trash in one step. Each ID is checked on its own: the response reports which were deleted and why the others weren't, in request order. Each deleted snippet gets a history entry.
+// @Tags snippets
+// @Accept json
+// @Produce json
+// @Param request body models.BulkSnippetsRequest true "IDs of the snippets to delete"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /snippets/bulk-delete [post]
+func (s *Server) bulkDeleteSnippets(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	var req models.BulkSnippetsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	results, err := s.store.BulkDeleteSnippets(c.Request.Context(), userID, originSessionID(c), req.IDs)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to delete snippets")
+		return
+	}
+
+	deleted := 0
+	for _, result := range results {
+		if result.Success {
+			deleted++
+		}
+	}
+	respondSuccess(c, http.StatusOK, gin.H{
+		"results": results,
+		"deleted": deleted,
+	})
+}
+
+// bulkUpdateSnippets applies the same change to many of the authenticated user's snippets
+// @Summary Update many snippets
+// @Description Add a tag to, set the language of (language "" clears it) or move up to 100 of your snippets to a collection (collectionId 0 takes them out of theirs) in one step. Each ID is checked on its own: the response reports which were updated and why the others weren't, in request order. Each changed snippet gets a history entry.
+// @Tags snippets
+// @Accept json
+// @Produce json
+// @Param request body models.BulkUpdateRequest true "IDs of the snippets and the change to apply"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /snippets/bulk-update [post]
+func (s *Server) bulkUpdateSnippets(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	var req models.BulkUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.AddTag != nil {
+		tag := strings.TrimSpace(*req.AddTag)
+		if tag == "" {
+			respondError(c, http.StatusBadRequest, "addTag must not be empty")
+			return
+		}
+		req.AddTag = &tag
+	}
+	if req.AddTag == nil && req.CollectionID == nil && req.Language == nil {
+		respondError(c, http.StatusBadRequest, "No fields to update")
+		return
+	}
+
+	results, err := s.store.BulkUpdateSnippets(c.Request.Context(), userID, originSessionID(c), req)
+	if respondSnippetWriteError(c, err, "Failed to update snippets") {
+		return
+	}
+
+	updated := 0
+	for _, result := range results {
+		if result.Success {
+			updated++
+		}
+	}
+	respondSuccess(c, http.StatusOK, gin.H{
+		"results": results,
+		"updated": updated,
+	})
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/auth"
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"github.com/lib/pq"
+)
+
+func TestBulkDeleteSnippets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	const otherUserID = "223e4567-e89b-12d3-a456-426614174000"
+	if _, err := testDB.Exec(`
+		INSERT INTO users (id, username, email, password_hash, full_name, avatar_url)
+		VALUES ($1, 'otheruser', 'other@example.com', 'dummy-hash', '', '')
+	`, otherUserID); err != nil {
+		t.Fatalf("Failed to create other user: %v", err)
+	}
+	insert := func(shortcut, userID string) int64 {
+		var id int64
+		err := testDB.QueryRow(`
+			INSERT INTO snippets (label, shortcut, content, user_id) VALUES ($1, $1, 'code', $2)
+			RETURNING id
+		`, shortcut, userID).Scan(&id)
+		if err != nil {
+			t.Fatalf("Failed to insert test snippet: %v", err)
+		}
+		return id
+	}
+	first, second, others := insert("first", testUserID), insert("second", testUserID), insert("others", otherUserID)
+
+	s := NewServer(testDB, nil)
+	router := gin.New()
+	router.Use(auth.Middleware())
+	router.POST("/api/v1/snippets/bulk-delete", s.bulkDeleteSnippets)
+
+	body, _ := json.Marshal(models.BulkSnippetsRequest{IDs: []int64{first, others, second, first, 999999}})
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, "/api/v1/snippets/bulk-delete", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+generateTestJWT())
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Results []models.BulkResult `json:"results"`
+		Deleted int                 `json:"deleted"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	want := []models.BulkResult{
+		{ID: first, Success: true},
+		{ID: others, Error: "You don't have permission to access this snippet"},
+		{ID: second, Success: true},
+		{ID: 999999, Error: "Snippet not found"},
+	}
+	if response.Deleted != 2 || len(response.Results) != len(want) {
+		t.Fatalf("Expected 2 deleted and %d results, got %+v", len(want), response)
+	}
+	for i := range want {
+		if response.Results[i] != want[i] {
+			t.Errorf("Result %d: expected %+v, got %+v", i, want[i], response.Results[i])
+		}
+	}
+
+	var deleted, history int
+	if err := testDB.QueryRow(`SELECT COUNT(*) FROM snippets WHERE is_deleted`).Scan(&deleted); err != nil {
+		t.Fatalf("Failed to count deleted snippets: %v", err)
+	}
+	if err := testDB.QueryRow(`SELECT COUNT(*) FROM snippet_history WHERE change_type = 'soft_delete'`).Scan(&history); err != nil {
+		t.Fatalf("Failed to count history: %v", err)
+	}
+	if deleted != 2 || history != 2 {
+		t.Errorf("Expected 2 deleted snippets with history entries, got %d and %d", deleted, history)
+	}
+}
+
+func TestBulkUpdateSnippets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	var collectionID int64
+	if err := testDB.QueryRow(`
+		INSERT INTO collections (user_id, name) VALUES ($1, 'Work') RETURNING id
+	`, testUserID).Scan(&collectionID); err != nil {
+		t.Fatalf("Failed to insert collection: %v", err)
+	}
+	var plain, tagged, full int64
+	err := testDB.QueryRow(`
+		INSERT INTO snippets (label, shortcut, content, user_id) VALUES ('Plain', 'plain', 'code', $1)
+		RETURNING id
+	`, testUserID).Scan(&plain)
+	if err == nil {
+		err = testDB.QueryRow(`
+			INSERT INTO snippets (label, shortcut, content, tags, user_id, collection_id)
+			VALUES ('Tagged', 'tagged', 'code', ARRAY['sql'], $1, $2)
+			RETURNING id
+		`, testUserID, collectionID).Scan(&tagged)
+	}
+	if err == nil {
+		err = testDB.QueryRow(`
+			INSERT INTO snippets (label, shortcut, content, tags, user_id)
+			VALUES ('Full', 'full', 'code', ARRAY(SELECT 't' || n FROM generate_series(1, 20) n), $1)
+			RETURNING id
+		`, testUserID).Scan(&full)
+	}
+	if err != nil {
+		t.Fatalf("Failed to insert test snippets: %v", err)
+	}
+
+	s := NewServer(testDB, nil)
+	router := gin.New()
+	router.Use(auth.Middleware())
+	router.POST("/api/v1/snippets/bulk-update", s.bulkUpdateSnippets)
+
+	do := func(body interface{}) *httptest.ResponseRecorder {
+		raw, _ := json.Marshal(body)
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, "/api/v1/snippets/bulk-update", bytes.NewReader(raw))
+		req.Header.Set("Authorization", "Bearer "+generateTestJWT())
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(map[string]interface{}{"ids": []int64{plain}}); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without a change, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := do(map[string]interface{}{"ids": []int64{plain}, "collectionId": 999999}); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 moving to an unknown collection, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w := do(map[string]interface{}{"ids": []int64{plain, tagged, full}, "addTag": "sql", "collectionId": collectionID})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		Results []models.BulkResult `json:"results"`
+		Updated int                 `json:"updated"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.Updated != 2 || len(response.Results) != 3 || response.Results[2].Success {
+		t.Fatalf("Expected the snippet with 20 tags to fail and the others to succeed, got %+v", response)
+	}
+
+	var tags []string
+	var filedIn int64
+	if err := testDB.QueryRow(`SELECT tags, collection_id FROM snippets WHERE id = $1`, plain).Scan(pq.Array(&tags), &filedIn); err != nil {
+		t.Fatalf("Failed to read snippet: %v", err)
+	}
+	if len(tags) != 1 || tags[0] != "sql" || filedIn != collectionID {
+		t.Errorf("Expected the snippet tagged sql and filed, got %v in %d", tags, filedIn)
+	}
+
+	// Only the snippet that changed gets a new version
+	var versions int
+	if err := testDB.QueryRow(`
+		SELECT COUNT(*) FROM snippet_history WHERE change_type = 'edit' AND snippet_id = ANY($1)
+	`, pq.Array([]int64{plain, tagged, full})).Scan(&versions); err != nil {
+		t.Fatalf("Failed to count history: %v", err)
+	}
+	if versions != 1 {
+		t.Errorf("Expected 1 edit history entry, got %d", versions)
+	}
+}
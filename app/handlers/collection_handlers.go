@@ -0,0 +1,383 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+// getMyCollections lists the authenticated user's collections
+// @Summary List my collections
+// @Description List all your collections by name, with their parentId (null at the top level) and how many snippets each holds. Filter snippet listings by one with the collection query parameter.
+// @Tags collections
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /collections [get]
+func (s *Server) getMyCollections(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	collections, err := s.store.ListCollections(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch collections")
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, gin.H{
+		"collections": collections,
+		"count":       len(collections),
+	})
+}
+
+// createCollection creates a collection for the authenticated user
+// @Summary Create a collection
+// @Description Create a named collection to file snippets in, inside parentId if given. Names are unique among the collections of a parent, and collections nest up to 8 deep.
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Param request body models.CollectionRequest true "Collection name"
+// @Success 201 {object} models.Collection
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /collections [post]
+func (s *Server) createCollection(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	req, ok := bindCollectionRequest(c)
+	if !ok {
+		return
+	}
+
+	collection, err := s.store.CreateCollection(c.Request.Context(), userID, req.ParentID, req.Name)
+	if respondCollectionError(c, err, "Failed to create collection") {
+		return
+	}
+
+	respondSuccess(c, http.StatusCreated, collection)
+}
+
+// renameCollection renames one of the authenticated user's collections
+// @Summary Rename a collection
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Param id path int true "Collection ID"
+// @Param request body models.CollectionRequest true "New collection name"
+// @Success 200 {object} models.Collection
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /collections/{id} [put]
+func (s *Server) renameCollection(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid collection ID")
+		return
+	}
+	req, ok := bindCollectionRequest(c)
+	if !ok {
+		return
+	}
+
+	collection, err := s.store.RenameCollection(c.Request.Context(), userID, id, req.Name)
+	if respondCollectionError(c, err, "Failed to rename collection") {
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, collection)
+}
+
+// getCollectionTree returns one of the authenticated user's collections with its sub-collections
+// @Summary Get a collection tree
+// @Description A collection with all its sub-collections nested in children, each level by name. Every node has its path, the names from the top level down to it.
+// @Tags collections
+// @Produce json
+// @Param id path int true "Collection ID"
+// @Success 200 {object} models.CollectionTree
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /collections/{id}/tree [get]
+func (s *Server) getCollectionTree(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid collection ID")
+		return
+	}
+
+	tree, err := s.store.GetCollectionTree(c.Request.Context(), userID, id)
+	if respondCollectionError(c, err, "Failed to fetch collection tree") {
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, tree)
+}
+
+// moveCollection moves one of the authenticated user's collections, with its sub-collections
+// @Summary Move a collection
+// @Description Move a collection and its sub-collections inside another collection, or to the top level with a null parentId. A collection can't be moved into its own sub-tree.
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Param id path int true "Collection ID"
+// @Param request body models.MoveCollectionRequest true "New parent collection"
+// @Success 200 {object} models.Collection
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /collections/{id}/move [post]
+func (s *Server) moveCollection(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid collection ID")
+		return
+	}
+	var req models.MoveCollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	collection, err := s.store.MoveCollection(c.Request.Context(), userID, id, req.ParentID)
+	if respondCollectionError(c, err, "Failed to move collection") {
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, collection)
+}
+
+// deleteCollection deletes one of the authenticated user's collections
+// @Summary Delete a collection
+// @Description Delete a collection and all its sub-collections. Their snippets are kept and no longer filed in any collection.
+// @Tags collections
+// @Produce json
+// @Param id path int true "Collection ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /collections/{id} [delete]
+func (s *Server) deleteCollection(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid collection ID")
+		return
+	}
+
+	err = s.store.DeleteCollection(c.Request.Context(), userID, originSessionID(c), id)
+	if respondCollectionError(c, err, "Failed to delete collection") {
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, gin.H{"message": "Collection deleted successfully"})
+}
+
+// getCollectionMembers lists the members of a collection shared with the authenticated user
+// @Summary List collection members
+// @Description The users a collection is shared with and their roles, in the order they joined. Its creator and members can list them.
+// @Tags collections
+// @Produce json
+// @Param id path int true "Collection ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /collections/{id}/members [get]
+func (s *Server) getCollectionMembers(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid collection ID")
+		return
+	}
+
+	members, err := s.store.ListCollectionMembers(c.Request.Context(), id, userID)
+	if respondCollectionError(c, err, "Failed to fetch collection members") {
+		return
+	}
+
+	respondWithCount(c, members, len(members))
+}
+
+// setCollectionMember shares a collection with a user, or changes their role
+// @Summary Add or update a collection member
+// @Description Share a collection, and the collections inside it, with another user of the creator's organization. Viewers can read its snippets, editors can also change their label, content, tags and language, and owners can manage the members too. Writes by viewers are 403. Only the creator and owners can manage members.
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Param id path int true "Collection ID"
+// @Param userId path string true "User ID"
+// @Param request body models.CollectionMemberRequest true "Member role"
+// @Success 200 {object} models.CollectionMember
+// @Success 201 {object} models.CollectionMember
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "Collection has too many members"
+// @Security BearerAuth
+// @Router /collections/{id}/members/{userId} [put]
+func (s *Server) setCollectionMember(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid collection ID")
+		return
+	}
+	memberID := c.Param("userId")
+	if !isValidUUID(memberID) {
+		respondError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	var req models.CollectionMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	member, created, err := s.store.SetCollectionMember(c.Request.Context(), id, userID, memberID, req.Role)
+	if respondCollectionError(c, err, "Failed to update collection member") {
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	respondSuccess(c, status, member)
+}
+
+// removeCollectionMember removes a user from a collection's members
+// @Summary Remove a collection member
+// @Description Stop sharing a collection with a user. Owners can remove any member, and members can leave by removing themselves.
+// @Tags collections
+// @Produce json
+// @Param id path int true "Collection ID"
+// @Param userId path string true "User ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /collections/{id}/members/{userId} [delete]
+func (s *Server) removeCollectionMember(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid collection ID")
+		return
+	}
+	memberID := c.Param("userId")
+	if !isValidUUID(memberID) {
+		respondError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	err = s.store.RemoveCollectionMember(c.Request.Context(), id, userID, memberID)
+	if respondCollectionError(c, err, "Failed to remove collection member") {
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, gin.H{"message": "Collection member removed"})
+}
+
+// bindCollectionRequest binds a CollectionRequest with its name trimmed. On failure it
+// writes a 400 response and returns false.
+func bindCollectionRequest(c *gin.Context) (models.CollectionRequest, bool) {
+	var req models.CollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return req, false
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		respondError(c, http.StatusBadRequest, "name must not be blank")
+		return req, false
+	}
+	return req, true
+}
+
+// respondCollectionError writes the response for an error from a collection write,
+// reporting whether there was one
+func respondCollectionError(c *gin.Context, err error, failureMsg string) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, models.ErrCollectionNotFound):
+		respondError(c, http.StatusNotFound, "Collection not found")
+	case errors.Is(err, models.ErrCollectionExists):
+		respondError(c, http.StatusConflict, "You already have a collection with this name here")
+	case errors.Is(err, models.ErrCollectionCycle), errors.Is(err, models.ErrCollectionTooDeep),
+		errors.Is(err, models.ErrCollectionCreatorMember):
+		respondError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, models.ErrCollectionForbidden):
+		respondError(c, http.StatusForbidden, "Only the collection's owners can manage its members")
+	case errors.Is(err, models.ErrCollectionMemberNotFound):
+		respondError(c, http.StatusNotFound, "User is not a member of this collection")
+	case errors.Is(err, models.ErrCollectionMemberLimitReached):
+		respondError(c, http.StatusConflict, fmt.Sprintf("A collection can have up to %d members", models.MaxCollectionMembers))
+	case errors.Is(err, models.ErrShareUserNotFound):
+		respondError(c, http.StatusNotFound, "User not found")
+	case errors.Is(err, models.ErrBlocked):
+		respondError(c, http.StatusForbidden, "You cannot share this collection with this user")
+	default:
+		log.Printf("%s: %v", failureMsg, err)
+		respondError(c, http.StatusInternalServerError, failureMsg)
+	}
+	return true
+}
@@ -0,0 +1,319 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/auth"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+func TestCreateCollectionValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "Missing name", body: `{}`},
+		{name: "Blank name", body: `{"name": "   "}`},
+		{name: "Name too long", body: `{"name": "` + strings.Repeat("x", 101) + `"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Set("user_id", "0b3c9a1e-6a5f-4c1b-9d2e-3f4a5b6c7d8e")
+			c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/collections", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			NewServer(nil, nil).createCollection(c)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400 (body %s)", w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCollections(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	s := NewServer(testDB, nil)
+	router := gin.New()
+	router.Use(auth.Middleware())
+	router.GET("/api/v1/collections", s.getMyCollections)
+	router.POST("/api/v1/collections", s.createCollection)
+	router.PUT("/api/v1/collections/:id", s.renameCollection)
+	router.DELETE("/api/v1/collections/:id", s.deleteCollection)
+	router.GET("/api/v1/snippets", s.getCurrentUserSnippets)
+	router.POST("/api/v1/snippets", s.createSnippet)
+
+	token := generateTestJWT()
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequestWithContext(context.Background(), method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/api/v1/collections", `{"name": "Work"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var work models.Collection
+	if err := json.Unmarshal(w.Body.Bytes(), &work); err != nil {
+		t.Fatalf("Failed to parse collection: %v", err)
+	}
+	if w := do(http.MethodPost, "/api/v1/collections", `{"name": "Work"}`); w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for a duplicate name, got %d: %s", w.Code, w.Body.String())
+	}
+
+	workID := strconv.FormatInt(work.ID, 10)
+	if w := do(http.MethodPost, "/api/v1/snippets", `{"label": "Filed", "shortcut": "filed", "content": "x", "collectionId": `+workID+`}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 creating a filed snippet, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPost, "/api/v1/snippets", `{"label": "Loose", "shortcut": "loose", "content": "y"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 creating an unfiled snippet, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPost, "/api/v1/snippets", `{"label": "Lost", "shortcut": "lost", "content": "z", "collectionId": 999999}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown collection, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = do(http.MethodGet, "/api/v1/snippets?collection="+workID, "")
+	var page struct {
+		Items []models.Snippet `json:"items"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatalf("Failed to parse snippets: %v", err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Shortcut != "filed" {
+		t.Errorf("Expected only the filed snippet in the collection, got %+v", page.Items)
+	}
+
+	if w := do(http.MethodPut, "/api/v1/collections/"+workID, `{"name": "Job"}`); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 renaming, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = do(http.MethodGet, "/api/v1/collections", "")
+	var list struct {
+		Collections []models.Collection `json:"collections"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Failed to parse collections: %v", err)
+	}
+	if len(list.Collections) != 1 || list.Collections[0].Name != "Job" || list.Collections[0].SnippetCount != 1 {
+		t.Errorf("Expected the renamed collection with one snippet, got %+v", list.Collections)
+	}
+
+	if w := do(http.MethodDelete, "/api/v1/collections/"+workID, ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 deleting, got %d: %s", w.Code, w.Body.String())
+	}
+	var unfiled int
+	if err := testDB.QueryRow(`SELECT COUNT(*) FROM snippets WHERE user_id = $1 AND collection_id IS NULL`, testUserID).Scan(&unfiled); err != nil {
+		t.Fatalf("Failed to count unfiled snippets: %v", err)
+	}
+	if unfiled != 2 {
+		t.Errorf("Expected both snippets to be kept unfiled, got %d", unfiled)
+	}
+	if w := do(http.MethodDelete, "/api/v1/collections/"+workID, ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 deleting again, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestCollectionTree(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	s := NewServer(testDB, nil)
+	router := gin.New()
+	router.Use(auth.Middleware())
+	router.POST("/api/v1/collections", s.createCollection)
+	router.GET("/api/v1/collections/:id/tree", s.getCollectionTree)
+	router.POST("/api/v1/collections/:id/move", s.moveCollection)
+	router.DELETE("/api/v1/collections/:id", s.deleteCollection)
+
+	token := generateTestJWT()
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequestWithContext(context.Background(), method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	create := func(body string) string {
+		w := do(http.MethodPost, "/api/v1/collections", body)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201 creating %s, got %d: %s", body, w.Code, w.Body.String())
+		}
+		var col models.Collection
+		if err := json.Unmarshal(w.Body.Bytes(), &col); err != nil {
+			t.Fatalf("Failed to parse collection: %v", err)
+		}
+		return strconv.FormatInt(col.ID, 10)
+	}
+
+	work := create(`{"name": "Work"}`)
+	golang := create(`{"name": "Go", "parentId": ` + work + `}`)
+	http2 := create(`{"name": "HTTP", "parentId": ` + golang + `}`)
+	other := create(`{"name": "Other"}`)
+	// Names only need to be unique among siblings
+	create(`{"name": "Go", "parentId": ` + other + `}`)
+
+	w := do(http.MethodGet, "/api/v1/collections/"+golang+"/tree", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var tree models.CollectionTree
+	if err := json.Unmarshal(w.Body.Bytes(), &tree); err != nil {
+		t.Fatalf("Failed to parse tree: %v", err)
+	}
+	if !reflect.DeepEqual(tree.Path, []string{"Work", "Go"}) || len(tree.Children) != 1 ||
+		!reflect.DeepEqual(tree.Children[0].Path, []string{"Work", "Go", "HTTP"}) {
+		t.Errorf("Unexpected tree %+v", tree)
+	}
+
+	if w := do(http.MethodPost, "/api/v1/collections/"+work+"/move", `{"parentId": `+http2+`}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 moving a collection into its sub-tree, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPost, "/api/v1/collections/"+golang+"/move", `{"parentId": `+other+`}`); w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 moving next to a collection of the same name, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPost, "/api/v1/collections/"+http2+"/move", `{"parentId": null}`); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 moving to the top level, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if _, err := testDB.Exec(`INSERT INTO snippets (label, shortcut, content, user_id, collection_id) VALUES ('Filed', 'filed', 'x', $1, $2)`, testUserID, golang); err != nil {
+		t.Fatalf("Failed to insert test snippet: %v", err)
+	}
+	if w := do(http.MethodDelete, "/api/v1/collections/"+work, ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 deleting, got %d: %s", w.Code, w.Body.String())
+	}
+	var remaining int
+	if err := testDB.QueryRow(`SELECT COUNT(*) FROM collections WHERE user_id = $1`, testUserID).Scan(&remaining); err != nil {
+		t.Fatalf("Failed to count collections: %v", err)
+	}
+	// Work and Go are gone; HTTP was moved out and Other keeps its own Go
+	if remaining != 3 {
+		t.Errorf("Expected 3 collections left, got %d", remaining)
+	}
+	var filed sql.NullInt64
+	if err := testDB.QueryRow(`SELECT collection_id FROM snippets WHERE shortcut = 'filed'`).Scan(&filed); err != nil {
+		t.Fatalf("Failed to read snippet: %v", err)
+	}
+	if filed.Valid {
+		t.Errorf("Expected the snippet to be unfiled, got collection %d", filed.Int64)
+	}
+}
+
+func TestCollectionMembers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	const otherUserID = "223e4567-e89b-12d3-a456-426614174000"
+	if _, err := testDB.Exec(`
+		INSERT INTO users (id, username, email, password_hash, full_name, avatar_url)
+		VALUES ($1, 'otheruser', 'other@example.com', 'dummy-hash', '', '')
+	`, otherUserID); err != nil {
+		t.Fatalf("Failed to create other user: %v", err)
+	}
+	var parentID, childID, snippetID int64
+	err := testDB.QueryRow(`INSERT INTO collections (user_id, name) VALUES ($1, 'Team') RETURNING id`, otherUserID).Scan(&parentID)
+	if err == nil {
+		err = testDB.QueryRow(`
+			INSERT INTO collections (user_id, parent_id, name) VALUES ($1, $2, 'Shell') RETURNING id
+		`, otherUserID, parentID).Scan(&childID)
+	}
+	if err == nil {
+		err = testDB.QueryRow(`
+			INSERT INTO snippets (label, shortcut, content, user_id, collection_id) VALUES ('Deploy', 'deploy', 'make deploy', $1, $2)
+			RETURNING id
+		`, otherUserID, childID).Scan(&snippetID)
+	}
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %v", err)
+	}
+	if _, _, err := models.NewStore(testDB).SetCollectionMember(context.Background(), parentID, otherUserID, testUserID, models.CollectionRoleViewer); err != nil {
+		t.Fatalf("Failed to add test member: %v", err)
+	}
+
+	s := NewServer(testDB, nil)
+	router := gin.New()
+	router.Use(auth.Middleware())
+	router.GET("/api/v1/snippets/:id", s.getSnippet)
+	router.PUT("/api/v1/snippets/:id", s.updateSnippet)
+	router.GET("/api/v1/collections/:id/members", s.getCollectionMembers)
+	router.PUT("/api/v1/collections/:id/members/:userId", s.setCollectionMember)
+	router.DELETE("/api/v1/collections/:id/members/:userId", s.removeCollectionMember)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequestWithContext(context.Background(), method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+generateTestJWT())
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	snippetPath := "/api/v1/snippets/" + strconv.FormatInt(snippetID, 10)
+	membersPath := "/api/v1/collections/" + strconv.FormatInt(parentID, 10) + "/members"
+
+	// Membership of a collection covers the collections inside it
+	w := do(http.MethodGet, snippetPath, "")
+	var snippet models.Snippet
+	if err := json.Unmarshal(w.Body.Bytes(), &snippet); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Expected a viewer to read the snippet, got %d: %s", w.Code, w.Body.String())
+	}
+	if snippet.Ownership != models.ShareRoleViewer {
+		t.Errorf("Expected ownership viewer, got %q", snippet.Ownership)
+	}
+	if w := do(http.MethodPut, snippetPath, `{"content": "make release"}`); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for a viewer's write, got %d", w.Code)
+	}
+	if w := do(http.MethodPut, membersPath+"/"+testUserID, `{"role": "owner"}`); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for a viewer managing members, got %d", w.Code)
+	}
+
+	if _, _, err := models.NewStore(testDB).SetCollectionMember(context.Background(), parentID, otherUserID, testUserID, models.CollectionRoleEditor); err != nil {
+		t.Fatalf("Failed to update test member: %v", err)
+	}
+	if w := do(http.MethodPut, snippetPath, `{"content": "make release"}`); w.Code != http.StatusOK {
+		t.Errorf("Expected an editor to update the snippet, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = do(http.MethodGet, membersPath, "")
+	var members struct {
+		Items []models.CollectionMember `json:"items"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &members); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(members.Items) != 1 || members.Items[0].UserID != testUserID || members.Items[0].Role != models.CollectionRoleEditor {
+		t.Errorf("Expected the test user as an editor, got %+v", members.Items)
+	}
+
+	// Leaving the collection
+	if w := do(http.MethodDelete, membersPath+"/"+testUserID, ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 leaving the collection, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodGet, snippetPath, ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 after leaving, got %d", w.Code)
+	}
+}
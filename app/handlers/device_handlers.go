@@ -0,0 +1,102 @@
+// Package handlers provides push notification device registration endpoints.
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+// registerDevice registers a mobile device token for push notifications
+// @Summary Register push device
+// @Description Register an FCM or APNs device token. The device is registered for the login session of the access token, so it isn't notified about changes made in that session.
+// @Tags devices
+// @Accept json
+// @Produce json
+// @Param device body models.RegisterDeviceTokenRequest true "Device token"
+// @Success 201 {object} models.DeviceToken
+// @Failure 400 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/devices [post]
+func (s *Server) registerDevice(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	var req models.RegisterDeviceTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	device, err := s.store.RegisterDeviceToken(c.Request.Context(), userID, originSessionID(c), req)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to register device")
+		return
+	}
+
+	respondSuccess(c, http.StatusCreated, device)
+}
+
+// getMyDevices lists the authenticated user's push devices
+// @Summary List push devices
+// @Description Get devices registered for push notifications
+// @Tags devices
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /users/me/devices [get]
+func (s *Server) getMyDevices(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	devices, err := s.store.GetUserDeviceTokens(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch devices")
+		return
+	}
+
+	respondWithCount(c, devices, len(devices))
+}
+
+// deleteDevice unregisters a push device
+// @Summary Unregister push device
+// @Description Stop sending push notifications to a device
+// @Tags devices
+// @Produce json
+// @Param deviceId path int true "Device ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/devices/{deviceId} [delete]
+func (s *Server) deleteDevice(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("deviceId"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid device ID")
+		return
+	}
+
+	err = s.store.DeleteDeviceToken(c.Request.Context(), userID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(c, http.StatusNotFound, "Device not found")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to unregister device")
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, gin.H{"message": "Device unregistered successfully"})
+}
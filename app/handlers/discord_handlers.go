@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/discord"
+)
+
+// maxDiscordInteractionBytes bounds interaction payloads
+const maxDiscordInteractionBytes = 64 << 10
+
+// discordInteraction handles the /snippy Discord slash command
+// @Summary Discord interactions
+// @Description Interactions endpoint URL for the /snippy Discord command, authenticated by Discord's Ed25519 signature. Subcommands: `paste shortcut:<shortcut>` (with autocomplete) posts the linked user's snippet, `search query:<text>` lists matching snippets to the caller, `link code:<code>` links the Discord user to the Snippy account that created the link code, `unlink` removes the link.
+// @Tags integrations
+// @Accept json
+// @Produce json
+// @Success 200 {object} discord.Response
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /discord/interactions [post]
+func (s *Server) discordInteraction(c *gin.Context) {
+	cfg := discord.LoadConfig()
+	if !cfg.Enabled() {
+		respondError(c, http.StatusServiceUnavailable, "Discord integration is not available")
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxDiscordInteractionBytes))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid payload")
+		return
+	}
+	interaction, err := discord.ParseInteraction(payload, c.GetHeader("X-Signature-Timestamp"),
+		c.GetHeader("X-Signature-Ed25519"), cfg.PublicKey)
+	if errors.Is(err, discord.ErrInvalidSignature) {
+		// Discord checks that invalid signatures get a 401
+		respondError(c, http.StatusUnauthorized, "Invalid signature")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid payload")
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, discord.HandleInteraction(c.Request.Context(), s.store, interaction))
+}
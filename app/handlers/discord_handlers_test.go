@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDiscordInteractionAuthentication(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(body, signature string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/discord/interactions", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Request.Header.Set("X-Signature-Timestamp", "1700000000")
+		c.Request.Header.Set("X-Signature-Ed25519", signature)
+		NewServer(nil, nil).discordInteraction(c)
+		return w
+	}
+	ping := `{"type":1}`
+	valid := hex.EncodeToString(ed25519.Sign(private, []byte("1700000000"+ping)))
+
+	t.Setenv("DISCORD_PUBLIC_KEY", "")
+	if w := serve(ping, valid); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("without a public key: status %d, want 503", w.Code)
+	}
+
+	t.Setenv("DISCORD_PUBLIC_KEY", hex.EncodeToString(public))
+	if w := serve(ping, strings.Repeat("00", ed25519.SignatureSize)); w.Code != http.StatusUnauthorized {
+		t.Errorf("bad signature: status %d, want 401", w.Code)
+	}
+	if w := serve(ping, valid); w.Code != http.StatusOK || w.Body.String() != `{"type":1}` {
+		t.Errorf("ping: status %d, body %s, want a pong", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,300 @@
+// Package handlers provides oEmbed and embeddable views of public snippets.
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/middleware"
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"github.com/jheysaaz/snippy-backend/app/placeholder"
+)
+
+// Embed frame size bounds, in pixels
+const (
+	embedDefaultWidth = 600
+	embedMinHeight    = 120
+	embedMaxHeight    = 600
+	embedLineHeight   = 20
+)
+
+// embedCacheAge is how long consumers may cache an oEmbed response, in seconds
+const embedCacheAge = 3600
+
+// embedPathPattern matches the path of an embed or snippet URL and captures the snippet ID
+var embedPathPattern = regexp.MustCompile(`/snippets/(\d+)/?$`)
+
+// oEmbedResponse is an oEmbed 1.0 "rich" response
+type oEmbedResponse struct {
+	Type         string `json:"type" example:"rich"`
+	Version      string `json:"version" example:"1.0"`
+	Title        string `json:"title"`
+	AuthorName   string `json:"author_name"`
+	ProviderName string `json:"provider_name" example:"Snippy"`
+	ProviderURL  string `json:"provider_url"`
+	HTML         string `json:"html"`
+	CacheAge     int    `json:"cache_age"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+}
+
+// snippetEmbed is the JSON embed of a public snippet, for sites rendering it themselves
+type snippetEmbed struct {
+	CreatedAt    time.Time                 `json:"createdAt"`
+	UpdatedAt    time.Time                 `json:"updatedAt"`
+	Language     *string                   `json:"language,omitempty"`
+	Author       models.PublicProfile      `json:"author"`
+	Label        string                    `json:"label"`
+	Shortcut     string                    `json:"shortcut"`
+	Content      string                    `json:"content"`
+	EmbedURL     string                    `json:"embedUrl"`
+	OEmbedURL    string                    `json:"oembedUrl"`
+	Tags         []string                  `json:"tags"`
+	Placeholders []placeholder.Placeholder `json:"placeholders"`
+	ID           int64                     `json:"id"`
+}
+
+var embedTemplate = template.Must(template.New("embed").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Snippet.Label}} · Snippy</title>
+<link rel="alternate" type="application/json+oembed" href="{{.OEmbedURL}}" title="{{.Snippet.Label}}">
+<link rel="alternate" type="application/json" href="{{.JSONURL}}" title="{{.Snippet.Label}}">
+<style>
+body{margin:0;font:14px/1.4 -apple-system,BlinkMacSystemFont,"Segoe UI",sans-serif;color:#1f2328;background:#fff}
+.snippet{border:1px solid #d0d7de;border-radius:6px;overflow:hidden}
+header{display:flex;justify-content:space-between;gap:8px;padding:8px 12px;background:#f6f8fa;border-bottom:1px solid #d0d7de}
+h1{margin:0;font-size:14px;font-weight:600}
+code.shortcut,.language{color:#57606a}
+pre{margin:0;padding:12px;overflow:auto;font:13px/20px ui-monospace,SFMono-Regular,Menlo,monospace;white-space:pre-wrap}
+footer{padding:6px 12px;color:#57606a;font-size:12px;border-top:1px solid #d0d7de}
+</style>
+</head>
+<body>
+<div class="snippet">
+<header><h1>{{.Snippet.Label}}</h1><span>{{with .Snippet.Language}}<span class="language">{{.}}</span> {{end}}<code class="shortcut">{{.Snippet.Shortcut}}</code></span></header>
+<pre><code>{{.Snippet.Content}}</code></pre>
+<footer>by @{{.Author.Username}} on Snippy</footer>
+</div>
+</body>
+</html>
+`))
+
+// publicBaseURL is the API's public origin: PUBLIC_BASE_URL, or the request's own origin
+func publicBaseURL(c *gin.Context) string {
+	if base := strings.TrimRight(os.Getenv("PUBLIC_BASE_URL"), "/"); base != "" {
+		return base
+	}
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// embedURL is the URL of a snippet's embeddable view
+func embedURL(base string, id int64) string {
+	return base + "/embed/snippets/" + strconv.FormatInt(id, 10)
+}
+
+// oEmbedURL is the oEmbed endpoint's URL for a snippet's embeddable view
+func oEmbedURL(base string, id int64) string {
+	return base + "/api/v1/oembed?url=" + url.QueryEscape(embedURL(base, id))
+}
+
+// embedJSONURL is the URL of a snippet's JSON embed
+func embedJSONURL(base string, id int64) string {
+	return base + "/api/v1/public/snippets/" + strconv.FormatInt(id, 10)
+}
+
+// snippetIDFromEmbedURL extracts the snippet ID from one of this API's embed or snippet
+// URLs. URLs on other hosts are rejected.
+func snippetIDFromEmbedURL(raw, base string) (int64, bool) {
+	target, err := url.Parse(raw)
+	if err != nil {
+		return 0, false
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil || !strings.EqualFold(target.Host, baseURL.Host) {
+		return 0, false
+	}
+	match := embedPathPattern.FindStringSubmatch(target.Path)
+	if match == nil {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(match[1], 10, 64)
+	return id, err == nil
+}
+
+// embedHeight sizes the frame to the snippet's content
+func embedHeight(content string, maxHeight int) int {
+	height := embedMinHeight + embedLineHeight*(strings.Count(content, "\n")+1)
+	height = min(height, embedMaxHeight)
+	if maxHeight > 0 {
+		height = min(height, maxHeight)
+	}
+	return height
+}
+
+// getOEmbed describes a public snippet for oEmbed consumers
+// @Summary oEmbed
+// @Description oEmbed 1.0 endpoint for public snippets. Pass the snippet's embed URL (/embed/snippets/{id}); the response is a "rich" type whose html is an iframe of that view. Only JSON is supported.
+// @Tags public
+// @Produce json
+// @Param url query string true "Embed URL of a public snippet"
+// @Param maxwidth query int false "Maximum frame width"
+// @Param maxheight query int false "Maximum frame height"
+// @Param format query string false "Response format (json)"
+// @Success 200 {object} oEmbedResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 501 {object} ErrorResponse
+// @Router /oembed [get]
+func (s *Server) getOEmbed(c *gin.Context) {
+	if format := c.Query("format"); format != "" && format != "json" {
+		respondError(c, http.StatusNotImplemented, "Only the json format is supported")
+		return
+	}
+
+	base := publicBaseURL(c)
+	id, ok := snippetIDFromEmbedURL(c.Query("url"), base)
+	if !ok {
+		respondError(c, http.StatusNotFound, "Not an embeddable snippet URL")
+		return
+	}
+	maxWidth, _ := strconv.Atoi(c.Query("maxwidth"))
+	maxHeight, _ := strconv.Atoi(c.Query("maxheight"))
+
+	item, err := s.store.GetPublicSnippet(c.Request.Context(), middleware.OrgID(c), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(c, http.StatusNotFound, "Snippet not found")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch snippet")
+		return
+	}
+
+	width := embedDefaultWidth
+	if maxWidth > 0 {
+		width = min(width, maxWidth)
+	}
+	height := embedHeight(item.Snippet.Content, maxHeight)
+	frame := `<iframe src="` + template.HTMLEscapeString(embedURL(base, id)) + `" width="` + strconv.Itoa(width) +
+		`" height="` + strconv.Itoa(height) + `" frameborder="0" loading="lazy" title="` +
+		template.HTMLEscapeString(item.Snippet.Label) + `"></iframe>`
+
+	c.Header("Cache-Control", "public, max-age="+strconv.Itoa(embedCacheAge))
+	respondSuccess(c, http.StatusOK, oEmbedResponse{
+		Type:         "rich",
+		Version:      "1.0",
+		Title:        item.Snippet.Label,
+		AuthorName:   item.Author.Username,
+		ProviderName: "Snippy",
+		ProviderURL:  base,
+		HTML:         frame,
+		CacheAge:     embedCacheAge,
+		Width:        width,
+		Height:       height,
+	})
+}
+
+// getSnippetEmbed renders a public snippet as an HTML page that other sites can frame,
+// with oEmbed discovery. It is served at /embed/snippets/:id, outside the API.
+func (s *Server) getSnippetEmbed(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "Snippet not found")
+		return
+	}
+
+	item, err := s.store.GetPublicSnippet(c.Request.Context(), middleware.OrgID(c), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(c, http.StatusNotFound, "Snippet not found")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch snippet")
+		return
+	}
+
+	base := publicBaseURL(c)
+	var page bytes.Buffer
+	if err := embedTemplate.Execute(&page, gin.H{
+		"Snippet":   item.Snippet,
+		"Author":    item.Author,
+		"OEmbedURL": oEmbedURL(base, id),
+		"JSONURL":   embedJSONURL(base, id),
+	}); err != nil {
+		log.Printf("Failed to render snippet embed %d: %v", id, err)
+		respondError(c, http.StatusInternalServerError, "Failed to render snippet")
+		return
+	}
+
+	// The page is meant to be framed by other sites, but runs no scripts
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors *")
+	c.Header("Cache-Control", "public, max-age="+strconv.Itoa(embedCacheAge))
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+}
+
+// getPublicSnippetEmbed returns a public snippet as JSON, for sites that embed it with
+// their own markup instead of framing the HTML view
+// @Summary Public snippet embed
+// @Description The label, language, content, tags, placeholders and author of a public snippet, with the URLs of its HTML embed and oEmbed description. Any site may fetch it from the browser; responses are cacheable for an hour.
+// @Tags public
+// @Produce json
+// @Param id path int true "Snippet ID"
+// @Success 200 {object} snippetEmbed
+// @Failure 404 {object} ErrorResponse
+// @Router /public/snippets/{id} [get]
+func (s *Server) getPublicSnippetEmbed(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "Snippet not found")
+		return
+	}
+
+	item, err := s.store.GetPublicSnippet(c.Request.Context(), middleware.OrgID(c), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(c, http.StatusNotFound, "Snippet not found")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch snippet")
+		return
+	}
+
+	base := publicBaseURL(c)
+	snippet := item.Snippet
+	// Embeds are fetched by any site, without credentials, unlike the rest of the API
+	c.Header("Access-Control-Allow-Origin", "*")
+	c.Writer.Header().Del("Access-Control-Allow-Credentials")
+	c.Header("Cache-Control", "public, max-age="+strconv.Itoa(embedCacheAge))
+	respondSuccess(c, http.StatusOK, snippetEmbed{
+		ID:           snippet.ID,
+		Label:        snippet.Label,
+		Shortcut:     snippet.Shortcut,
+		Language:     snippet.Language,
+		Content:      snippet.Content,
+		Tags:         snippet.Tags,
+		Placeholders: snippet.Placeholders,
+		Author:       item.Author,
+		CreatedAt:    snippet.CreatedAt,
+		UpdatedAt:    snippet.UpdatedAt,
+		EmbedURL:     embedURL(base, id),
+		OEmbedURL:    oEmbedURL(base, id),
+	})
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSnippetIDFromEmbedURL(t *testing.T) {
+	base := "https://api.snippy.app"
+	tests := []struct {
+		url    string
+		wantID int64
+		wantOK bool
+	}{
+		{url: "https://api.snippy.app/embed/snippets/42", wantID: 42, wantOK: true},
+		{url: "https://API.snippy.app/embed/snippets/42/", wantID: 42, wantOK: true},
+		{url: "https://api.snippy.app/api/v1/snippets/7?ref=notion", wantID: 7, wantOK: true},
+		{url: "https://evil.example/embed/snippets/42"},
+		{url: "https://api.snippy.app/embed/snippets/abc"},
+		{url: "https://api.snippy.app/embed/snippets/42/history"},
+		{url: "://not a url"},
+	}
+
+	for _, tt := range tests {
+		id, ok := snippetIDFromEmbedURL(tt.url, base)
+		if ok != tt.wantOK || id != tt.wantID {
+			t.Errorf("snippetIDFromEmbedURL(%q) = %d, %v, want %d, %v", tt.url, id, ok, tt.wantID, tt.wantOK)
+		}
+	}
+}
+
+func TestEmbedHeight(t *testing.T) {
+	if got := embedHeight("one line", 0); got != embedMinHeight+embedLineHeight {
+		t.Errorf("one line height = %d, want %d", got, embedMinHeight+embedLineHeight)
+	}
+	long := ""
+	for i := 0; i < 100; i++ {
+		long += "line\n"
+	}
+	if got := embedHeight(long, 0); got != embedMaxHeight {
+		t.Errorf("long snippet height = %d, want the %d cap", got, embedMaxHeight)
+	}
+	if got := embedHeight(long, 200); got != 200 {
+		t.Errorf("height with maxheight 200 = %d, want 200", got)
+	}
+}
+
+func TestOEmbedValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("PUBLIC_BASE_URL", "https://api.snippy.app")
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{name: "XML format", query: "?url=https://api.snippy.app/embed/snippets/1&format=xml", want: http.StatusNotImplemented},
+		{name: "Missing URL", query: "", want: http.StatusNotFound},
+		{name: "Foreign URL", query: "?url=https://gist.github.com/embed/snippets/1", want: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/oembed"+tt.query, nil)
+
+			NewServer(nil, nil).getOEmbed(c)
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d (body %s)", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestEmbedURLs(t *testing.T) {
+	base := "https://api.snippy.app"
+	if got, want := oEmbedURL(base, 42), "https://api.snippy.app/api/v1/oembed?url=https%3A%2F%2Fapi.snippy.app%2Fembed%2Fsnippets%2F42"; got != want {
+		t.Errorf("oEmbedURL = %q, want %q", got, want)
+	}
+	if got, want := embedJSONURL(base, 42), "https://api.snippy.app/api/v1/public/snippets/42"; got != want {
+		t.Errorf("embedJSONURL = %q, want %q", got, want)
+	}
+}
+
+func TestPublicSnippetEmbedInvalidID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/public/snippets/abc", nil)
+	c.Params = gin.Params{{Key: "id", Value: "abc"}}
+
+	NewServer(nil, nil).getPublicSnippetEmbed(c)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
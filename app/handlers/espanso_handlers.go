@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-yaml"
+	"github.com/jheysaaz/snippy-backend/app/export"
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"github.com/jheysaaz/snippy-backend/app/snippetquery"
+)
+
+// espansoMatchFile is an Espanso match file (the package.yml of an Espanso package)
+type espansoMatchFile struct {
+	Matches []export.EspansoMatch `yaml:"matches"`
+}
+
+// buildEspansoMatchFile renders snippets as an Espanso match file
+func buildEspansoMatchFile(snippets []models.Snippet) ([]byte, error) {
+	file := espansoMatchFile{Matches: make([]export.EspansoMatch, 0, len(snippets))}
+	for i := range snippets {
+		file.Matches = append(file.Matches, export.NewEspansoMatch(&snippets[i]))
+	}
+	return yaml.Marshal(file)
+}
+
+// getEspansoMatches serves the authenticated user's snippets as an Espanso match file
+// @Summary Espanso match file
+// @Description Your snippets in Espanso's match format (shortcut as trigger, content as replacement, label as label), to be saved as an Espanso package's package.yml. Responses carry an ETag; send If-None-Match to get 304 when nothing changed.
+// @Tags snippets
+// @Produce application/yaml
+// @Param tag query string false "Only snippets with this tag"
+// @Success 200 {string} string "Espanso match file"
+// @Success 304 "Not modified"
+// @Failure 401 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /snippets/espanso [get]
+func (s *Server) getEspansoMatches(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	snippets, err := s.store.ListUserSnippets(c.Request.Context(), userID, snippetquery.Filter{Tag: c.Query("tag")})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch snippets")
+		return
+	}
+
+	body, err := buildEspansoMatchFile(snippets)
+	if err != nil {
+		log.Printf("Failed to encode Espanso matches: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to encode snippets")
+		return
+	}
+
+	// Espanso sync scripts poll this; let them skip unchanged downloads
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+	if strings.Contains(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/yaml; charset=utf-8", body)
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+func TestBuildEspansoMatchFile(t *testing.T) {
+	snippets := []models.Snippet{
+		{Label: "Signature", Shortcut: ":sig", Content: "Best,\nJane"},
+		{Shortcut: "addr", Content: "1 Main St: Apt \"2\""},
+	}
+
+	body, err := buildEspansoMatchFile(snippets)
+	if err != nil {
+		t.Fatalf("buildEspansoMatchFile: %v", err)
+	}
+
+	var file espansoMatchFile
+	if err := yaml.Unmarshal(body, &file); err != nil {
+		t.Fatalf("match file is not valid YAML: %v\n%s", err, body)
+	}
+	if len(file.Matches) != 2 {
+		t.Fatalf("got %d matches, want 2:\n%s", len(file.Matches), body)
+	}
+	for i, s := range snippets {
+		m := file.Matches[i]
+		if m.Trigger != s.Shortcut || m.Replace != s.Content || m.Label != s.Label {
+			t.Errorf("match %d = %+v, want trigger %q, replace %q, label %q", i, m, s.Shortcut, s.Content, s.Label)
+		}
+	}
+
+	empty, err := buildEspansoMatchFile(nil)
+	if err != nil {
+		t.Fatalf("buildEspansoMatchFile(nil): %v", err)
+	}
+	if string(empty) != "matches: []\n" {
+		t.Errorf("empty match file = %q, want an empty matches list", empty)
+	}
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"github.com/jheysaaz/snippy-backend/app/realtime"
+)
+
+// eventHeartbeat is how often an idle event stream sends a comment, so proxies and
+// clients don't take it for dead
+const eventHeartbeat = 30 * time.Second
+
+// SnippetEvent is the data of an event on the snippet event stream, named by its type
+type SnippetEvent struct {
+	ChangedAt time.Time `json:"changedAt"`
+	// Snippet is the snippet as changed; deletions only carry the ID
+	Snippet *models.Snippet `json:"snippet,omitempty"`
+	Type    string          `json:"type" example:"snippet.updated"`
+	ID      int64           `json:"id" example:"7"`
+}
+
+// snippetEvent converts a hub change to its event
+func snippetEvent(change realtime.Change) SnippetEvent {
+	evt := SnippetEvent{ChangedAt: change.ChangedAt, Type: change.EventType, ID: change.Snippet.ID}
+	if change.EventType != models.EventSnippetDeleted {
+		snippet := change.Snippet
+		evt.Snippet = &snippet
+	}
+	return evt
+}
+
+// missedSnippetEvents converts the changes since a sync to events, in the order GET
+// /snippets/sync lists them
+func missedSnippetEvents(changes *models.SnippetChanges) []SnippetEvent {
+	events := make([]SnippetEvent, 0, len(changes.Created)+len(changes.Updated)+len(changes.Deleted))
+	for _, snippet := range changes.Created {
+		events = append(events, snippetEvent(realtime.Change{Snippet: snippet, EventType: models.EventSnippetCreated, ChangedAt: snippet.CreatedAt}))
+	}
+	for _, snippet := range changes.Updated {
+		events = append(events, snippetEvent(realtime.Change{Snippet: snippet, EventType: models.EventSnippetUpdated, ChangedAt: snippet.UpdatedAt}))
+	}
+	for _, d := range changes.Deleted {
+		change := realtime.Change{Snippet: models.Snippet{ID: d.ID}, EventType: models.EventSnippetDeleted}
+		if d.DeletedAt != nil {
+			change.ChangedAt = *d.DeletedAt
+		}
+		events = append(events, snippetEvent(change))
+	}
+	return events
+}
+
+// streamSnippetEvents streams changes to the authenticated user's snippets as they're made
+// @Summary Stream snippet events
+// @Description Server-sent events pushing changes to your snippets as they're made on your other devices, so clients sync instantly instead of polling. Events are named snippet.created, snippet.updated or snippet.deleted, with a SnippetEvent as their JSON data; deletions only carry the ID. Changes made in the login session of the access token aren't echoed back. Changes made on any server instance are streamed, but delivery is best effort: changes made while disconnected aren't kept, and the stream ends when changes may have been missed. Reconnect whenever it ends, passing cursor or updated_since from your last sync to replay what you missed first, or sync after connecting. A change may arrive twice around a replay, so apply events by snippet ID. Snippets shared with you aren't streamed. An idle stream sends a comment every 30 seconds.
+// @Tags snippets
+// @Produce text/event-stream
+// @Param cursor query string false "Cursor from the last sync, to replay the changes since"
+// @Param updated_since query string false "RFC3339 timestamp to replay the changes since"
+// @Success 200 {object} SnippetEvent
+// @Failure 400 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /snippets/events [get]
+func (s *Server) streamSnippetEvents(c *gin.Context) {
+	if s.hub == nil {
+		respondError(c, http.StatusServiceUnavailable, "Snippet events are not available")
+		return
+	}
+
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	var since *models.SyncCursor
+	if c.Query("cursor") != "" || c.Query("updated_since") != "" {
+		cursor, ok := parseSyncCursor(c)
+		if !ok {
+			return
+		}
+		since = &cursor
+	}
+
+	// Subscribe before replaying so nothing committed in between is missed
+	ctx := c.Request.Context()
+	changes, unsubscribe := s.hub.Subscribe(userID, originSessionID(c))
+	defer unsubscribe()
+
+	var missed []SnippetEvent
+	if since != nil {
+		changed, err := s.store.GetSnippetChanges(ctx, userID, *since)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "Failed to fetch sync data")
+			return
+		}
+		missed = missedSnippetEvents(changed)
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	// Keep nginx from buffering the stream
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	for _, evt := range missed {
+		c.SSEvent(evt.Type, evt)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case change, ok := <-changes:
+			if !ok {
+				// The hub ended the stream: the server is shutting down, or changes may
+				// have been missed and the client should reconnect to replay them
+				return
+			}
+			evt := snippetEvent(change)
+			c.SSEvent(evt.Type, evt)
+		case <-heartbeat.C:
+			if _, err := io.WriteString(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/auth"
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"github.com/jheysaaz/snippy-backend/app/realtime"
+)
+
+// eventsToken is an access token for the test user in login session sessionID, signed
+// with the configured secret
+func eventsToken(t *testing.T, sessionID string) string {
+	t.Helper()
+	token, err := auth.GenerateSessionAccessToken(&models.User{ID: testUserID, Username: "testuser"}, nil, sessionID)
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+	return token
+}
+
+// snippetOutboxEvent is an outbox event for a change to one of the test user's snippets
+func snippetOutboxEvent(t *testing.T, eventType, originSessionID string, snippet models.Snippet) models.OutboxEvent {
+	t.Helper()
+	userID := testUserID
+	snippet.UserID = &userID
+	payload, err := json.Marshal(snippet)
+	if err != nil {
+		t.Fatalf("marshal payload: %v", err)
+	}
+	return models.OutboxEvent{EventType: eventType, AggregateType: models.AggregateSnippet, Payload: payload, OriginSessionID: &originSessionID, CreatedAt: time.Now()}
+}
+
+func TestStreamSnippetEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	hub := realtime.NewHub()
+	router := gin.New()
+	router.GET("/api/v1/snippets/events", auth.Middleware(), NewServer(nil, nil).WithHub(hub).streamSnippetEvents)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/snippets/events", nil)
+	req.Header.Set("Authorization", "Bearer "+eventsToken(t, "laptop"))
+	// The stream's session is the token's; a header naming another one is ignored
+	req.Header.Set("X-Session-ID", "phone")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET events: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status %d with Content-Type %q, want a 200 event stream", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	// The stream's own session isn't echoed its changes
+	ctx := context.Background()
+	_ = hub.Deliver(ctx, snippetOutboxEvent(t, models.EventSnippetUpdated, "laptop", models.Snippet{ID: 1, Label: "Mine"}))
+	_ = hub.Deliver(ctx, snippetOutboxEvent(t, models.EventSnippetUpdated, "phone", models.Snippet{ID: 2, Label: "Greeting"}))
+	_ = hub.Deliver(ctx, snippetOutboxEvent(t, models.EventSnippetDeleted, "phone", models.Snippet{ID: 3}))
+	hub.Close()
+
+	var names []string
+	var events []SnippetEvent
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name, ok := strings.CutPrefix(line, "event:"); ok {
+			names = append(names, name)
+		}
+		if data, ok := strings.CutPrefix(line, "data:"); ok {
+			var evt SnippetEvent
+			if err := json.Unmarshal([]byte(data), &evt); err != nil {
+				t.Fatalf("decode %q: %v", data, err)
+			}
+			events = append(events, evt)
+		}
+	}
+
+	if len(events) != 2 || strings.Join(names, ",") != models.EventSnippetUpdated+","+models.EventSnippetDeleted {
+		t.Fatalf("got events %v %+v, want the phone's update and delete", names, events)
+	}
+	if events[0].ID != 2 || events[0].Snippet == nil || events[0].Snippet.Label != "Greeting" {
+		t.Errorf("update event = %+v, want snippet 2 with the snippet", events[0])
+	}
+	if events[1].ID != 3 || events[1].Snippet != nil {
+		t.Errorf("delete event = %+v, want snippet 3 without the snippet", events[1])
+	}
+}
+
+func TestStreamSnippetEventsWithoutHub(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/api/v1/snippets/events", auth.Middleware(), NewServer(nil, nil).streamSnippetEvents)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/snippets/events", nil)
+	req.Header.Set("Authorization", "Bearer "+eventsToken(t, "laptop"))
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestMissedSnippetEvents(t *testing.T) {
+	deletedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	events := missedSnippetEvents(&models.SnippetChanges{
+		Created: []models.Snippet{{ID: 1}},
+		Updated: []models.Snippet{{ID: 2}},
+		Deleted: []models.DeletedSnippet{{ID: 3, DeletedAt: &deletedAt}},
+	})
+
+	want := []string{models.EventSnippetCreated, models.EventSnippetUpdated, models.EventSnippetDeleted}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(events), len(want))
+	}
+	for i, evt := range events {
+		if evt.Type != want[i] || evt.ID != int64(i+1) {
+			t.Errorf("event %d = %+v, want %s of snippet %d", i, evt, want[i], i+1)
+		}
+	}
+	if events[2].Snippet != nil || !events[2].ChangedAt.Equal(deletedAt) {
+		t.Errorf("delete event = %+v, want no snippet and the deletion time", events[2])
+	}
+}
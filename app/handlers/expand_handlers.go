@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/auth"
+	"github.com/jheysaaz/snippy-backend/app/middleware"
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"github.com/jheysaaz/snippy-backend/app/placeholder"
+)
+
+// expandShortcut resolves one of the authenticated user's shortcuts to its content
+// @Summary Expand shortcut
+// @Description Resolve a shortcut to its snippet's content in one call, for launchers and text expanders that look up on keystroke. With record=true the expansion counts as a snippet use (extension tokens need the usage:write scope for that).
+// @Tags snippets
+// @Produce json
+// @Param shortcut query string true "Shortcut to expand"
+// @Param record query bool false "Record the expansion as a snippet use"
+// @Success 200 {object} models.Expansion
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /expand [get]
+func (s *Server) expandShortcut(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	shortcut := c.Query("shortcut")
+	if shortcut == "" {
+		respondError(c, http.StatusBadRequest, "shortcut is required")
+		return
+	}
+
+	recordUse := false
+	if raw := c.Query("record"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "record must be true or false")
+			return
+		}
+		recordUse = parsed
+	}
+	if recordUse && !auth.HasTokenScope(c, models.ScopeUsageWrite) {
+		respondError(c, http.StatusForbidden, "Token does not allow this action")
+		return
+	}
+
+	expansion, err := s.store.ExpandShortcut(c.Request.Context(), userID, shortcut, recordUse)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(c, http.StatusNotFound, "Shortcut not found")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to expand shortcut")
+		return
+	}
+
+	// Snippets change between lookups; clients must not reuse a stale expansion
+	c.Header("Cache-Control", "no-store")
+	respondSuccess(c, http.StatusOK, expansion)
+}
+
+// getSnippetByShortcut returns the authenticated user's snippet with a shortcut
+// @Summary Get snippet by shortcut
+// @Description Resolve one of your shortcuts to the whole snippet, so text expanders can look it up directly instead of syncing every snippet. Shortcuts may contain slashes. Archived snippets aren't resolved.
+// @Tags snippets
+// @Produce json
+// @Param shortcut path string true "Shortcut"
+// @Success 200 {object} models.Snippet
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /snippets/by-shortcut/{shortcut} [get]
+func (s *Server) getSnippetByShortcut(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	// The route's catch-all keeps shortcuts with slashes whole, with a leading slash
+	shortcut := strings.TrimPrefix(c.Param("shortcut"), "/")
+	if shortcut == "" {
+		respondError(c, http.StatusBadRequest, "shortcut is required")
+		return
+	}
+
+	snippet, err := s.store.GetSnippetByShortcut(c.Request.Context(), userID, shortcut)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(c, http.StatusNotFound, "Shortcut not found")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch snippet")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	respondSuccess(c, http.StatusOK, snippet)
+}
+
+// renderSnippet expands a snippet's placeholders with the given values
+// @Summary Render snippet
+// @Description Expand the placeholders of a snippet you can read with the given values, so clients don't need their own templating. Values must suit each placeholder's type: numbers, true or false, dates as 2006-01-02, times as 15:04 and date-times as RFC 3339. The date, time, datetime and clipboard built-ins are left for the client to fill in.
+// @Tags snippets
+// @Accept json
+// @Produce json
+// @Param id path int true "Snippet ID"
+// @Param values body models.RenderSnippetRequest true "Placeholder values"
+// @Success 200 {object} models.Expansion
+// @Failure 400 {object} ErrorResponse "Missing or invalid placeholder values"
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /snippets/{id}/render [post]
+func (s *Server) renderSnippet(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid snippet ID")
+		return
+	}
+
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	var req models.RenderSnippetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	snippet, err := s.store.GetReadableSnippet(c.Request.Context(), userID, middleware.OrgID(c), id)
+	if handleScanError(c, err, "Snippet not found") {
+		return
+	}
+
+	content, err := placeholder.Render(snippet.Content, req.Values)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, models.Expansion{
+		Shortcut: snippet.Shortcut,
+		Label:    snippet.Label,
+		Content:  content,
+		ID:       snippet.ID,
+	})
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/auth"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+func TestExpandShortcutValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		query  string
+		scopes []string
+		want   int
+	}{
+		{name: "Missing shortcut", query: "", want: http.StatusBadRequest},
+		{name: "Invalid record flag", query: "?shortcut=sig&record=maybe", want: http.StatusBadRequest},
+		{name: "Recording without usage scope", query: "?shortcut=sig&record=true", scopes: []string{models.ScopeSnippetsRead}, want: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Set("user_id", "0b3c9a1e-6a5f-4c1b-9d2e-3f4a5b6c7d8e")
+			if tt.scopes != nil {
+				c.Set("token_scopes", tt.scopes)
+			}
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/expand"+tt.query, nil)
+
+			NewServer(nil, nil).expandShortcut(c)
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d (body %s)", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetSnippetByShortcut(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	var id int64
+	err := testDB.QueryRow(`
+		INSERT INTO snippets (label, shortcut, content, user_id) VALUES ('Checkout', 'git/co', 'git checkout', $1)
+		RETURNING id
+	`, testUserID).Scan(&id)
+	if err != nil {
+		t.Fatalf("Failed to insert test snippet: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(auth.Middleware())
+	router.GET("/api/v1/snippets/by-shortcut/*shortcut", NewServer(testDB, nil).getSnippetByShortcut)
+	get := func(shortcut string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/api/v1/snippets/by-shortcut/"+shortcut, nil)
+		req.Header.Set("Authorization", "Bearer "+generateTestJWT())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("git/co")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var snippet models.Snippet
+	if err := json.Unmarshal(w.Body.Bytes(), &snippet); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if snippet.ID != id || snippet.Content != "git checkout" {
+		t.Errorf("Expected snippet %d, got %+v", id, snippet)
+	}
+
+	if w := get("git"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown shortcut, got %d", w.Code)
+	}
+	if w := get(""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without a shortcut, got %d", w.Code)
+	}
+}
+
+func TestGetRecentSnippets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	ids := make(map[string]int64)
+	for _, shortcut := range []string{"old", "new", "unused", "archived"} {
+		var id int64
+		err := testDB.QueryRow(`
+			INSERT INTO snippets (label, shortcut, content, user_id) VALUES ($1, $1, 'content', $2)
+			RETURNING id
+		`, shortcut, testUserID).Scan(&id)
+		if err != nil {
+			t.Fatalf("Failed to insert test snippet: %v", err)
+		}
+		ids[shortcut] = id
+	}
+	_, err := testDB.Exec(`
+		INSERT INTO snippet_usage (snippet_id, user_id, used_at) VALUES
+			($1, $4, NOW() - INTERVAL '2 days'),
+			($1, $4, NOW() - INTERVAL '1 day'),
+			($2, $4, NOW() - INTERVAL '1 hour'),
+			($3, $4, NOW())
+	`, ids["old"], ids["new"], ids["archived"], testUserID)
+	if err != nil {
+		t.Fatalf("Failed to insert test usage: %v", err)
+	}
+	if _, err := testDB.Exec(`UPDATE snippets SET archived_at = NOW() WHERE id = $1`, ids["archived"]); err != nil {
+		t.Fatalf("Failed to archive test snippet: %v", err)
+	}
+
+	router := gin.New()
+	router.GET("/api/v1/snippets/recent", auth.Middleware(), NewServer(testDB, nil).getRecentSnippets)
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/api/v1/snippets/recent", nil)
+	req.Header.Set("Authorization", "Bearer "+generateTestJWT())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		Items []models.RecentSnippet `json:"items"`
+		Count int                    `json:"count"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.Count != 2 || len(response.Items) != 2 {
+		t.Fatalf("Expected 2 recent snippets, got %+v", response.Items)
+	}
+	if response.Items[0].ID != ids["new"] || response.Items[1].ID != ids["old"] {
+		t.Errorf("Expected new then old, got %d then %d", response.Items[0].ID, response.Items[1].ID)
+	}
+	if !response.Items[0].LastUsedAt.After(response.Items[1].LastUsedAt) {
+		t.Errorf("Expected lastUsedAt to be newest first, got %v and %v", response.Items[0].LastUsedAt, response.Items[1].LastUsedAt)
+	}
+}
@@ -0,0 +1,175 @@
+// Package handlers provides streamed snippet exports and downloads of pre-purge account exports.
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/export"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+// downloadAccountExport serves the final export archive of a purged account
+// @Summary Download account export
+// @Description Download the archive created before a deleted account was purged. The token comes from the emailed link.
+// @Tags users
+// @Produce application/zip
+// @Param token path string true "Download token"
+// @Success 200 {file} binary
+// @Failure 404 {object} ErrorResponse
+// @Router /exports/{token} [get]
+func (s *Server) downloadAccountExport(c *gin.Context) {
+	export, err := s.store.GetAccountExportByToken(c.Request.Context(), c.Param("token"))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(c, http.StatusNotFound, "Export not found or expired")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch export")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="snippy-export-%s.zip"`, export.Username))
+	c.Data(http.StatusOK, "application/zip", export.Archive)
+}
+
+// exportMySnippets streams the authenticated user's snippets as NDJSON
+// @Summary Export my snippets
+// @Description Stream every snippet with its version history as newline-delimited JSON, one snippet per line, in ID order. The export is written as it is read, so it works for accounts of any size; a truncated download ends without a trailing newline on its last line.
+// @Tags users
+// @Produce application/x-ndjson
+// @Success 200 {object} models.ExportedSnippet "One per line"
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/export [get]
+func (s *Server) exportMySnippets(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	// The response is only committed by the first snippet, so a failure before it can
+	// still be reported as a 500
+	started := false
+	start := func() {
+		started = true
+		c.Header("Cache-Control", "no-store")
+		c.Header("Content-Disposition", `attachment; filename="snippy-snippets.ndjson"`)
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Status(http.StatusOK)
+	}
+
+	w := export.NewNDJSONWriter(c.Writer)
+	err := s.store.StreamExportedSnippets(c.Request.Context(), userID, func(snippet *models.ExportedSnippet) error {
+		if !started {
+			start()
+		}
+		return w.Encode(snippet)
+	})
+	if err != nil {
+		if !started {
+			respondError(c, http.StatusInternalServerError, "Failed to export snippets")
+			return
+		}
+		log.Printf("Snippet export for user %s stopped after %d snippets: %v", userID, w.Lines(), err)
+		return
+	}
+	if !started {
+		start()
+		c.Writer.WriteHeaderNow()
+	}
+	w.Flush()
+}
+
+// exportSnippets streams an archive of the authenticated user's snippets
+// @Summary Export snippets archive
+// @Description Download every snippet with its version history, and the tags in use with their counts, as one JSON document: exportedAt, snippets (in ID order) and tags (most used first). format=zip wraps it in a zip archive, and format=vscode exports a VS Code .code-snippets file instead: the shortcut is the prefix, the content the body split into lines and the language the scope, with placeholders as tab stops. format=espanso exports an Espanso match file like GET /snippets/espanso, and format=textexpander a CSV for TextExpander's import (abbreviation, content, label, no header row), with placeholders as fill-in fields and literal percent signs doubled. The export is written as it is read from the database, so memory use doesn't grow with the account; a failure mid-download leaves the document unterminated.
+// @Tags snippets
+// @Produce json
+// @Produce application/zip
+// @Produce application/yaml
+// @Produce text/csv
+// @Param format query string false "json (default), zip, vscode, espanso or textexpander"
+// @Success 200 {file} binary
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /snippets/export [get]
+func (s *Server) exportSnippets(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	switch format {
+	case "json", "zip", "vscode", "espanso", "textexpander":
+	default:
+		respondError(c, http.StatusBadRequest, "format must be json, zip, vscode, espanso or textexpander")
+		return
+	}
+
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	// As with the NDJSON export, the response is only committed by the first write
+	started := false
+	start := func() {
+		started = true
+		c.Header("Cache-Control", "no-store")
+		switch format {
+		case "zip":
+			c.Header("Content-Disposition", `attachment; filename="snippy-export.zip"`)
+			c.Header("Content-Type", "application/zip")
+		case "vscode":
+			c.Header("Content-Disposition", `attachment; filename="`+export.VSCodeFileName+`"`)
+			c.Header("Content-Type", "application/json; charset=utf-8")
+		case "espanso":
+			c.Header("Content-Disposition", `attachment; filename="`+export.EspansoFileName+`"`)
+			c.Header("Content-Type", "application/yaml; charset=utf-8")
+		case "textexpander":
+			c.Header("Content-Disposition", `attachment; filename="`+export.TextExpanderFileName+`"`)
+			c.Header("Content-Type", "text/csv; charset=utf-8")
+		default:
+			c.Header("Content-Disposition", `attachment; filename="`+export.ArchiveFileName+`"`)
+			c.Header("Content-Type", "application/json; charset=utf-8")
+		}
+		c.Status(http.StatusOK)
+	}
+
+	var w export.Writer
+	switch format {
+	case "vscode":
+		w = export.NewVSCodeWriter(c.Writer)
+	case "espanso":
+		w = export.NewEspansoWriter(c.Writer)
+	case "textexpander":
+		w = export.NewTextExpanderWriter(c.Writer)
+	default:
+		w = export.NewArchiveWriter(c.Writer, format == "zip", s.now().UTC())
+	}
+	err := s.store.StreamExportedSnippets(c.Request.Context(), userID, func(snippet *models.ExportedSnippet) error {
+		if !started {
+			start()
+		}
+		return w.Encode(snippet)
+	})
+	if err != nil {
+		if !started {
+			respondError(c, http.StatusInternalServerError, "Failed to export snippets")
+			return
+		}
+		log.Printf("Snippet archive for user %s stopped after %d snippets: %v", userID, w.Snippets(), err)
+		return
+	}
+	if !started {
+		start()
+	}
+	if err := w.Close(); err != nil {
+		log.Printf("Failed to finish snippet archive for user %s: %v", userID, err)
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+// createExtensionToken exchanges the caller's session for a browser-extension token
+// @Summary Create browser-extension token
+// @Description Exchange a logged-in session for a long-lived token (valid one year) that can only read snippets and record snippet use. The token is only shown once; send it as "Authorization: Bearer snx_...". It does not refresh and cannot change your account or snippets.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body models.CreateExtensionTokenRequest false "Token name, e.g. the browser"
+// @Success 201 {object} models.ExtensionToken
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /auth/extension-token [post]
+func (s *Server) createExtensionToken(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	var req models.CreateExtensionTokenRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	token, err := s.store.CreateExtensionToken(c.Request.Context(), userID, req.Name)
+	if errors.Is(err, models.ErrExtensionTokenLimit) {
+		respondError(c, http.StatusConflict, "You can have at most "+strconv.Itoa(models.ExtensionTokenMaxPerUser)+" extension tokens")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to create extension token")
+		return
+	}
+
+	respondSuccess(c, http.StatusCreated, token)
+}
+
+// getMyExtensionTokens lists the authenticated user's active extension tokens
+// @Summary List browser-extension tokens
+// @Description Your active extension tokens with their scopes and last use (the tokens themselves are not included)
+// @Tags users
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/extension-tokens [get]
+func (s *Server) getMyExtensionTokens(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	tokens, err := s.store.GetUserExtensionTokens(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch extension tokens")
+		return
+	}
+
+	respondWithCount(c, tokens, len(tokens))
+}
+
+// revokeExtensionToken revokes one of the authenticated user's extension tokens
+// @Summary Revoke browser-extension token
+// @Description Revoke an extension token; the extension has to be connected again
+// @Tags users
+// @Produce json
+// @Param tokenId path int true "Extension token ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/extension-tokens/{tokenId} [delete]
+func (s *Server) revokeExtensionToken(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("tokenId"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid extension token ID")
+		return
+	}
+
+	err = s.store.RevokeExtensionToken(c.Request.Context(), userID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(c, http.StatusNotFound, "Extension token not found")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to revoke extension token")
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, gin.H{"message": "Extension token revoked"})
+}
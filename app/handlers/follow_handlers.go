@@ -0,0 +1,179 @@
+// Package handlers provides follow and feed endpoints.
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+// followUser follows another user
+// @Summary Follow user
+// @Description Follow a user to see their public snippets in your feed
+// @Tags follows
+// @Produce json
+// @Param id path string true "User ID to follow"
+// @Success 200 {object} map[string]interface{}
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/{id}/follow [post]
+func (s *Server) followUser(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	followeeID := c.Param("id")
+	if followeeID == userID {
+		respondError(c, http.StatusBadRequest, "You cannot follow yourself")
+		return
+	}
+
+	created, err := s.store.FollowUser(c.Request.Context(), userID, followeeID)
+	if errors.Is(err, models.ErrBlocked) {
+		respondError(c, http.StatusForbidden, "You cannot follow this user")
+		return
+	}
+	if handleScanError(c, err, "User not found") {
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	respondSuccess(c, status, gin.H{"message": "Following user", "following": true})
+}
+
+// unfollowUser stops following a user
+// @Summary Unfollow user
+// @Description Stop following a user
+// @Tags follows
+// @Produce json
+// @Param id path string true "User ID to unfollow"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/{id}/follow [delete]
+func (s *Server) unfollowUser(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	err := s.store.UnfollowUser(c.Request.Context(), userID, c.Param("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(c, http.StatusNotFound, "Not following this user")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to unfollow user")
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, gin.H{"message": "Unfollowed user", "following": false})
+}
+
+// getMyFollowing lists the users the authenticated user follows
+// @Summary List followed users
+// @Description Get the users you follow, most recently followed first
+// @Tags follows
+// @Produce json
+// @Param limit query int false "Limit results (default 50, max 100)"
+// @Param offset query int false "Offset for pagination"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Success 200 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /users/me/following [get]
+func (s *Server) getMyFollowing(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	limit, offset := parseLimitOffset(c, 50, 100)
+	users, total, err := s.store.GetFollowing(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch followed users")
+		return
+	}
+
+	respondPage(c, users, len(users), total, offset)
+}
+
+// getMyFollowers lists the users following the authenticated user
+// @Summary List followers
+// @Description Get the users following you, most recent first
+// @Tags follows
+// @Produce json
+// @Param limit query int false "Limit results (default 50, max 100)"
+// @Param offset query int false "Offset for pagination"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Success 200 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /users/me/followers [get]
+func (s *Server) getMyFollowers(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	limit, offset := parseLimitOffset(c, 50, 100)
+	users, total, err := s.store.GetFollowers(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch followers")
+		return
+	}
+
+	respondPage(c, users, len(users), total, offset)
+}
+
+// getFeed returns newly published public snippets from followed users
+// @Summary Get feed
+// @Description Get public snippets from users you follow, newest first. Pass nextBefore from the previous page as before to continue.
+// @Tags follows
+// @Produce json
+// @Param before query string false "Only snippets created before this time (RFC3339)"
+// @Param limit query int false "Limit results (default 20, max 100)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/feed [get]
+func (s *Server) getFeed(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	before, err := parseTimeQuery(c, "before")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, _ := parseLimitOffset(c, 20, 100)
+
+	items, err := s.store.GetFeed(c.Request.Context(), userID, before, limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch feed")
+		return
+	}
+
+	var nextBefore *time.Time
+	if len(items) == limit {
+		nextBefore = &items[len(items)-1].Snippet.CreatedAt
+	}
+
+	respondSuccess(c, http.StatusOK, gin.H{
+		"items":      items,
+		"count":      len(items),
+		"hasMore":    nextBefore != nil,
+		"nextBefore": nextBefore,
+	})
+}
@@ -0,0 +1,149 @@
+// Package handlers provides Git mirror endpoints.
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/gitsync"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+// validGitMirrorURL reports whether raw is an absolute https URL. Other transports (ssh,
+// file, plain http) are refused so a mirror can't reach the server's own files or leak
+// the token unencrypted.
+func validGitMirrorURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && u.Scheme == "https" && u.Host != ""
+}
+
+// getGitMirror returns the authenticated user's Git mirror
+// @Summary Get Git mirror
+// @Description Your Git mirror settings and the outcome of the last sync (the token is not included)
+// @Tags users
+// @Produce json
+// @Success 200 {object} models.GitMirror
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/git-mirror [get]
+func (s *Server) getGitMirror(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	mirror, err := s.store.GetGitMirror(c.Request.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(c, http.StatusNotFound, "Git mirror not configured")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch Git mirror")
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, mirror)
+}
+
+// saveGitMirror configures the authenticated user's Git mirror
+// @Summary Configure Git mirror
+// @Description Mirror your snippets to a Git repository over HTTPS, one Markdown file per snippet under snippets/ with its metadata in YAML front matter. Mode on_change (default) pushes about 30 seconds after changes; schedule pushes every intervalHours (default 24). The token, e.g. a personal access token with push access, is sent as the password; omit it to keep the stored one. The first sync starts right away.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param mirror body models.GitMirrorRequest true "Repository, branch, credentials and mode"
+// @Success 200 {object} models.GitMirror
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/git-mirror [put]
+func (s *Server) saveGitMirror(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	var req models.GitMirrorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !validGitMirrorURL(req.RepoURL) {
+		respondError(c, http.StatusBadRequest, "repoUrl must be an https URL")
+		return
+	}
+	if req.Branch != "" && !gitsync.ValidBranch(req.Branch) {
+		respondError(c, http.StatusBadRequest, "Invalid branch name")
+		return
+	}
+
+	mirror, err := s.store.SaveGitMirror(c.Request.Context(), userID, req)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to save Git mirror")
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, mirror)
+}
+
+// deleteGitMirror stops mirroring the authenticated user's snippets
+// @Summary Remove Git mirror
+// @Description Stop mirroring your snippets and forget the stored token. The repository and its history are left as they are.
+// @Tags users
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/git-mirror [delete]
+func (s *Server) deleteGitMirror(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	err := s.store.DeleteGitMirror(c.Request.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(c, http.StatusNotFound, "Git mirror not configured")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to remove Git mirror")
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, gin.H{"message": "Git mirror removed"})
+}
+
+// syncGitMirror queues an immediate sync of the authenticated user's Git mirror
+// @Summary Sync Git mirror now
+// @Description Push your snippets to the mirror without waiting for the next change or scheduled run. Poll GET /users/me/git-mirror for the result.
+// @Tags users
+// @Produce json
+// @Success 202 {object} map[string]string
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/git-mirror/sync [post]
+func (s *Server) syncGitMirror(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	err := s.store.RequestGitMirrorSync(c.Request.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(c, http.StatusNotFound, "Git mirror not configured")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to queue Git mirror sync")
+		return
+	}
+
+	respondSuccess(c, http.StatusAccepted, gin.H{"message": "Git mirror sync queued"})
+}
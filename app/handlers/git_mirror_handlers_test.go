@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestValidGitMirrorURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{url: "https://github.com/someone/snippets.git", want: true},
+		{url: "http://github.com/someone/snippets.git"},
+		{url: "file:///etc"},
+		{url: "git@github.com:someone/snippets.git"},
+		{url: "/srv/repos/snippets"},
+	}
+	for _, tt := range tests {
+		if got := validGitMirrorURL(tt.url); got != tt.want {
+			t.Errorf("validGitMirrorURL(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestSaveGitMirrorValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "Missing URL", body: `{"branch":"main"}`},
+		{name: "Unsupported scheme", body: `{"repoUrl":"file:///var/lib/snippy"}`},
+		{name: "Invalid branch", body: `{"repoUrl":"https://github.com/someone/snippets.git","branch":"a..b"}`},
+		{name: "Unknown mode", body: `{"repoUrl":"https://github.com/someone/snippets.git","mode":"hourly"}`},
+		{name: "Interval too long", body: `{"repoUrl":"https://github.com/someone/snippets.git","mode":"schedule","intervalHours":200}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Set("user_id", "0b3c9a1e-6a5f-4c1b-9d2e-3f4a5b6c7d8e")
+			c.Request = httptest.NewRequest(http.MethodPut, "/api/v1/users/me/git-mirror", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			NewServer(nil, nil).saveGitMirror(c)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400 (body %s)", w.Code, w.Body.String())
+			}
+		})
+	}
+}
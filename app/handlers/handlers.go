@@ -3,13 +3,17 @@ package handlers
 
 import (
 	"database/sql"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jheysaaz/snippy-backend/app/database"
+	"github.com/jheysaaz/snippy-backend/app/middleware"
 	"github.com/jheysaaz/snippy-backend/app/models"
+	"github.com/jheysaaz/snippy-backend/app/snippetquery"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lib/pq"
@@ -22,225 +26,144 @@ import (
 // @Accept json
 // @Produce json
 // @Param tag query string false "Filter by tag"
-// @Param search query string false "Search in label"
+// @Param search query string false "Search in label and description"
+// @Param collection query int false "Only snippets in this collection"
+// @Param favorites query bool false "Only starred snippets"
+// @Param language query string false "Only snippets in this language, such as go"
+// @Param include query string false "archived to also list archived snippets"
 // @Param limit query int false "Limit results (max 100)"
+// @Param offset query int false "Offset for pagination"
+// @Param cursor query string false "nextCursor of the previous page"
 // @Success 200 {object} map[string]interface{}
 // @Security BearerAuth
-func getSnippets(c *gin.Context) {
-	// Optional query parameters for filtering
-	tag := c.Query("tag")
-	search := c.Query("search")
-	limitStr := c.Query("limit")
-
-	// Build query with optional filters
-	query := `
-		SELECT id, label, shortcut, content, tags, user_id, created_at, updated_at
-		FROM snippets
-		WHERE is_deleted = false
-	`
-	args := []interface{}{}
-	argPos := 1
-
-	if tag != "" {
-		query += " AND $" + strconv.Itoa(argPos) + " = ANY(tags)"
-		args = append(args, tag)
-		argPos++
+func (s *Server) getSnippets(c *gin.Context) {
+	filter := snippetquery.FromQuery(c.Query)
+	q := snippetquery.New().Filter(filter).OrderBy("created_at DESC")
+	snippets, total, err := s.store.ListSnippetsPage(c.Request.Context(), q)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch snippets")
+		return
 	}
 
-	if search != "" {
-		// Use full-text search index for better performance
-		query += " AND to_tsvector('english', coalesce(label, '')) @@ plainto_tsquery('english', $" + strconv.Itoa(argPos) + ")"
-		args = append(args, search)
-		argPos++
-	}
+	respondPage(c, snippets, len(snippets), total, filter.Offset)
+}
 
-	query += " ORDER BY created_at DESC"
+// syncSnippets returns snippets changed since a sync cursor or timestamp for the authenticated user
+// @Summary Sync snippets since a cursor
+// @Description Returns snippets added/updated and deleted since the given cursor or timestamp, including those shared with you, each with its ownership. Snippets shared or unshared since come back as created or deleted. The response's cursor is the position of the last change; pass it as cursor next time so snippets changed at the same moment aren't missed. cursor takes precedence over updated_since.
+// @Tags snippets
+// @Produce json
+// @Param cursor query string false "Cursor from the previous sync"
+// @Param updated_since query string false "RFC3339 timestamp, for the first sync"
+// @Success 200 {object} models.SnippetChanges
+// @Failure 400 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /snippets/sync [get]
+func (s *Server) syncSnippets(c *gin.Context) {
+	since, ok := parseSyncCursor(c)
+	if !ok {
+		return
+	}
 
-	// Add limit if provided (max 100)
-	if limitStr != "" {
-		limit, err := strconv.Atoi(limitStr)
-		if err == nil && limit > 0 {
-			if limit > 100 {
-				limit = 100 // Cap at 100 for performance
-			}
-			query += " LIMIT $" + strconv.Itoa(argPos)
-			args = append(args, limit)
-		}
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
 	}
 
-	rows, err := database.DB.QueryContext(c.Request.Context(), query, args...)
+	// Single UNION ALL query for created, updated and deleted snippets
+	changes, err := s.store.GetSnippetChanges(c.Request.Context(), userID, since)
 	if err != nil {
-		respondError(c, http.StatusInternalServerError, "Failed to fetch snippets")
+		respondError(c, http.StatusInternalServerError, "Failed to fetch sync data")
 		return
 	}
-	defer func() {
-		if err := rows.Close(); err != nil {
-			log.Printf("error closing snippet rows: %v", err)
-		}
-	}()
 
-	snippets := make([]models.Snippet, 0, 10)
-	for rows.Next() {
-		snippet, err := models.ScanSnippet(rows)
-		if err != nil {
-			respondError(c, http.StatusInternalServerError, "Failed to scan snippet")
-			return
+	// Remember which device synced for the weekly digest
+	if sessionID := originSessionID(c); sessionID != "" {
+		if err := s.store.MarkSessionSynced(c.Request.Context(), sessionID, userID); err != nil {
+			log.Printf("Failed to record sync for session %s: %v", sessionID, err)
 		}
-		snippets = append(snippets, *snippet)
-	}
-
-	if err := rows.Err(); err != nil {
-		respondError(c, http.StatusInternalServerError, "Error iterating snippets")
-		return
 	}
 
-	respondWithCount(c, snippets, len(snippets))
+	respondSuccess(c, http.StatusOK, changes)
 }
 
-// syncSnippets returns snippets changed since a given timestamp for the authenticated user
-// @Summary Sync snippets since timestamp
-// @Description Returns snippets added/updated and deleted since the given timestamp
-// @Tags snippets
-// @Produce json
-// @Param updated_since query string true "RFC3339 timestamp"
-// @Success 200 {object} map[string]interface{}
-// @Failure 400 {object} map[string]string
-// @Security BearerAuth
-// @Router /snippets/sync [get]
-func syncSnippets(c *gin.Context) {
+// parseSyncCursor reads the position to sync from, the cursor query param or else
+// updated_since. It responds with 400 and returns false if neither is valid.
+func parseSyncCursor(c *gin.Context) (models.SyncCursor, bool) {
+	if cursor := c.Query("cursor"); cursor != "" {
+		since, err := models.ParseSyncCursor(cursor)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "cursor is invalid; sync again with updated_since")
+			return models.SyncCursor{}, false
+		}
+		return since, true
+	}
+
 	updatedSinceStr := c.Query("updated_since")
 	if updatedSinceStr == "" {
-		respondError(c, http.StatusBadRequest, "updated_since query param is required")
-		return
+		respondError(c, http.StatusBadRequest, "cursor or updated_since query param is required")
+		return models.SyncCursor{}, false
 	}
-
 	updatedSince, err := time.Parse(time.RFC3339, updatedSinceStr)
 	if err != nil {
 		respondError(c, http.StatusBadRequest, "updated_since must be RFC3339 format")
-		return
+		return models.SyncCursor{}, false
 	}
+	return models.SyncCursorSince(updatedSince), true
+}
 
+// pushSnippetChanges applies a batch of changes a client made to its snippets
+// @Summary Push snippet changes
+// @Description Apply up to 100 creates, updates and deletes of your own snippets, in order, in one transaction. Updates and deletes carry the snippet's baseUpdatedAt the change was made on; if the snippet changed or was deleted since, or a create or update takes a shortcut you already use, the change is skipped and reported as a conflict with the server's snippet. Any other error, such as a full quota, applies none of the changes. Applied changes come back with the snippet as stored, and reach your other devices like any other change.
+// @Tags snippets
+// @Accept json
+// @Produce json
+// @Param request body models.SyncPushRequest true "Changes to apply, in order"
+// @Success 200 {object} models.SyncPushResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 413 {object} ContentTooLargeResponse
+// @Security BearerAuth
+// @Router /snippets/sync [post]
+func (s *Server) pushSnippetChanges(c *gin.Context) {
 	userID, exists := getAuthUserID(c)
 	if !exists {
 		return
 	}
 
-	// Combined query using UNION ALL for single database round-trip
-	// This reduces 3 queries to 1, improving latency and reducing DB load
-	query := `
-		SELECT id, label, shortcut, content, tags, user_id, created_at, updated_at, 
-		       NULL::TIMESTAMP WITH TIME ZONE as deleted_at, 'created' as sync_type
-		FROM snippets
-		WHERE user_id = $1 AND is_deleted = false AND created_at > $2
-		
-		UNION ALL
-		
-		SELECT id, label, shortcut, content, tags, user_id, created_at, updated_at,
-		       NULL::TIMESTAMP WITH TIME ZONE as deleted_at, 'updated' as sync_type
-		FROM snippets
-		WHERE user_id = $1 AND is_deleted = false AND updated_at > $2 AND created_at <= $2
-		
-		UNION ALL
-		
-		SELECT id, '' as label, '' as shortcut, '' as content, ARRAY[]::TEXT[] as tags,
-		       user_id, created_at, updated_at, deleted_at, 'deleted' as sync_type
-		FROM snippets
-		WHERE user_id = $1 AND is_deleted = true AND deleted_at IS NOT NULL AND deleted_at > $2
-	`
-
-	rows, err := database.DB.QueryContext(c.Request.Context(), query, userID, updatedSince)
-	if err != nil {
-		respondError(c, http.StatusInternalServerError, "Failed to fetch sync data")
+	var req models.SyncPushRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
-	defer func() {
-		if closeErr := rows.Close(); closeErr != nil {
-			log.Printf("error closing sync rows: %v", closeErr)
-		}
-	}()
-
-	type deletedSnippet struct {
-		DeletedAt *time.Time `json:"deletedAt"`
-		ID        int64      `json:"id"`
-	}
-
-	created := make([]models.Snippet, 0, 10)
-	updated := make([]models.Snippet, 0, 10)
-	deleted := make([]deletedSnippet, 0, 10)
-
-	for rows.Next() {
-		var (
-			id        int64
-			label     string
-			shortcut  string
-			content   string
-			tags      pq.StringArray
-			rowUserID sql.NullString
-			createdAt time.Time
-			updatedAt time.Time
-			deletedAt sql.NullTime
-			syncType  string
-		)
-
-		if scanErr := rows.Scan(&id, &label, &shortcut, &content, &tags, &rowUserID,
-			&createdAt, &updatedAt, &deletedAt, &syncType); scanErr != nil {
-			respondError(c, http.StatusInternalServerError, "Failed to scan sync data")
+	for i := range req.Changes {
+		if err := req.Changes[i].Validate(); err != nil {
+			respondError(c, http.StatusBadRequest, "changes["+strconv.Itoa(i)+"]: "+err.Error())
 			return
 		}
-
-		switch syncType {
-		case "created", "updated":
-			snippet := models.Snippet{
-				ID:        id,
-				Label:     label,
-				Shortcut:  shortcut,
-				Content:   content,
-				Tags:      tags,
-				CreatedAt: createdAt,
-				UpdatedAt: updatedAt,
-			}
-			if rowUserID.Valid {
-				snippet.UserID = &rowUserID.String
-			}
-			if syncType == "created" {
-				created = append(created, snippet)
-			} else {
-				updated = append(updated, snippet)
-			}
-		case "deleted":
-			item := deletedSnippet{ID: id}
-			if deletedAt.Valid {
-				item.DeletedAt = &deletedAt.Time
-			}
-			deleted = append(deleted, item)
-		}
 	}
 
-	if err := rows.Err(); err != nil {
-		respondError(c, http.StatusInternalServerError, "Error iterating sync data")
+	result, err := s.store.PushSnippetChanges(c.Request.Context(), userID, originSessionID(c), req.Changes)
+	if respondSnippetWriteError(c, err, "Failed to apply sync changes") {
 		return
 	}
 
-	respondSuccess(c, http.StatusOK, gin.H{
-		"created": created,
-		"updated": updated,
-		"deleted": deleted,
-	})
+	respondSuccess(c, http.StatusOK, result)
 }
 
 // getSnippet retrieves a single snippet by ID
 // @Summary Get snippet by ID
-// @Description Get a single snippet by its ID
+// @Description Get a single snippet by its ID. Owners can get any of their snippets and users it is shared with can get it too, other users only public ones; anything else is 404.
 // @Tags snippets
 // @Accept json
 // @Produce json
 // @Param id path int true "Snippet ID"
 // @Success 200 {object} models.Snippet
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
 // @Security BearerAuth
 // @Router /snippets/{id} [get]
-func getSnippet(c *gin.Context) {
+func (s *Server) getSnippet(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
@@ -248,14 +171,12 @@ func getSnippet(c *gin.Context) {
 		return
 	}
 
-	query := `
-		SELECT id, label, shortcut, content, tags, user_id, created_at, updated_at
-		FROM snippets
-		WHERE id = $1 AND is_deleted = false
-	`
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
 
-	row := database.DB.QueryRowContext(c.Request.Context(), query, id)
-	snippet, err := models.ScanSnippet(row)
+	snippet, err := s.store.GetReadableSnippet(c.Request.Context(), userID, middleware.OrgID(c), id)
 	if handleScanError(c, err, "Snippet not found") {
 		return
 	}
@@ -271,14 +192,17 @@ func getSnippet(c *gin.Context) {
 // @Produce json
 // @Param snippet body models.CreateSnippetRequest true "Snippet data"
 // @Success 201 {object} models.Snippet
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse "Plan's snippet or storage quota reached"
+// @Failure 409 {object} ErrorResponse "You already have a snippet with this shortcut"
+// @Failure 413 {object} ContentTooLargeResponse "Content over the plan's size limit"
 // @Security BearerAuth
 // @Router /snippets [post]
-func createSnippet(c *gin.Context) {
+func (s *Server) createSnippet(c *gin.Context) {
 	var req models.CreateSnippetRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -288,30 +212,9 @@ func createSnippet(c *gin.Context) {
 		return
 	}
 
-	// Ensure tags is not nil
-	if req.Tags == nil {
-		req.Tags = []string{}
-	}
-
-	query := `
-		INSERT INTO snippets (label, shortcut, content, tags, user_id)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, label, shortcut, content, tags, user_id, created_at, updated_at
-	`
-
-	row := database.DB.QueryRowContext(
-		c.Request.Context(),
-		query,
-		req.Label,
-		req.Shortcut,
-		req.Content,
-		pq.Array(req.Tags),
-		userID, // Use authenticated user ID
-	)
-
-	snippet, err := models.ScanSnippet(row)
-	if err != nil {
-		respondError(c, http.StatusInternalServerError, "Failed to create snippet")
+	// Insert and record the domain event in one transaction so the event is never lost
+	snippet, err := s.store.CreateSnippet(c.Request.Context(), userID, originSessionID(c), req)
+	if respondSnippetWriteError(c, err, "Failed to create snippet") {
 		return
 	}
 
@@ -323,19 +226,21 @@ func createSnippet(c *gin.Context) {
 
 // updateSnippet updates an existing snippet
 // @Summary Update a snippet
-// @Description Update an existing snippet (owner only)
+// @Description Update an existing snippet (owner only). Editors of a snippet shared with you can change its label, content, tags and language.
 // @Tags snippets
 // @Accept json
 // @Produce json
 // @Param id path int true "Snippet ID"
 // @Param snippet body models.UpdateSnippetRequest true "Update data"
 // @Success 200 {object} models.Snippet
-// @Failure 400 {object} map[string]string
-// @Failure 403 {object} map[string]string
-// @Failure 404 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "You already have a snippet with this shortcut"
+// @Failure 413 {object} ContentTooLargeResponse "New content over the plan's size limit"
 // @Security BearerAuth
 // @Router /snippets/{id} [put]
-func updateSnippet(c *gin.Context) {
+func (s *Server) updateSnippet(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
@@ -349,229 +254,317 @@ func updateSnippet(c *gin.Context) {
 		return
 	}
 
-	// Check if snippet exists and belongs to user (using prepared statement)
-	snippetUserID, err := database.CheckSnippetOwnership(c.Request.Context(), id)
-	if err == sql.ErrNoRows {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Snippet not found"})
+	var req models.UpdateSnippetRequest
+	if bindErr := c.ShouldBindJSON(&req); bindErr != nil {
+		respondError(c, http.StatusBadRequest, bindErr.Error())
+		return
+	}
+
+	// Validate that at least one field is provided
+	if req.Label == nil && req.Shortcut == nil && req.Content == nil && req.Tags == nil && req.Visibility == nil &&
+		req.CollectionID == nil && req.Language == nil && req.Description == nil {
+		respondError(c, http.StatusBadRequest, "No fields to update")
 		return
 	}
+
+	snippet, err := s.store.UpdateSnippet(c.Request.Context(), id, userID, originSessionID(c), req)
+	if respondSnippetWriteError(c, err, "Failed to update snippet") {
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, snippet)
+}
+
+// favoriteSnippet stars or unstars a snippet
+// @Summary Star or unstar a snippet
+// @Description Mark a snippet as a favorite, or unmark it, for quick access with favorites=true on listings (owner only)
+// @Tags snippets
+// @Accept json
+// @Produce json
+// @Param id path int true "Snippet ID"
+// @Param request body models.FavoriteSnippetRequest true "Whether the snippet is a favorite"
+// @Success 200 {object} models.Snippet
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /snippets/{id}/favorite [put]
+func (s *Server) favoriteSnippet(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check snippet ownership"})
+		respondError(c, http.StatusBadRequest, "Invalid snippet ID")
 		return
 	}
 
-	// Verify ownership
-	if !snippetUserID.Valid || !checkOwnership(c, snippetUserID.String, userID, "snippet") {
+	userID, exists := getAuthUserID(c)
+	if !exists {
 		return
 	}
 
-	var req models.UpdateSnippetRequest
+	var req models.FavoriteSnippetRequest
 	if bindErr := c.ShouldBindJSON(&req); bindErr != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": bindErr.Error()})
+		respondError(c, http.StatusBadRequest, bindErr.Error())
 		return
 	}
 
-	// Validate that at least one field is provided
-	if req.Label == nil && req.Shortcut == nil && req.Content == nil && req.Tags == nil {
-		respondError(c, http.StatusBadRequest, "No fields to update")
+	snippet, err := s.store.SetSnippetFavorite(c.Request.Context(), id, userID, originSessionID(c), *req.Favorite)
+	if respondSnippetWriteError(c, err, "Failed to update snippet") {
 		return
 	}
 
-	// Prepare nullable parameters for static, parameterized UPDATE
-	labelVal := valueOrNilString(req.Label)
-	shortcutVal := valueOrNilString(req.Shortcut)
-	contentVal := valueOrNilString(req.Content)
-	tagsVal := arrayOrNilStringSlice(req.Tags)
-	changeNotes := valueOrNilString(req.ChangeNotes)
-
-	// Get current snippet data for history
-	var currentSnippet models.Snippet
-	var tags pq.StringArray
-	var currentUserID sql.NullString
-	currentQuery := `SELECT id, label, shortcut, content, tags, user_id, created_at, updated_at FROM snippets WHERE id = $1`
-	err = database.DB.QueryRowContext(c.Request.Context(), currentQuery, id).Scan(
-		&currentSnippet.ID,
-		&currentSnippet.Label,
-		&currentSnippet.Shortcut,
-		&currentSnippet.Content,
-		&tags,
-		&currentUserID,
-		&currentSnippet.CreatedAt,
-		&currentSnippet.UpdatedAt,
-	)
+	respondSuccess(c, http.StatusOK, snippet)
+}
+
+// keepSnippetHistory turns keeping all of a snippet's versions on or off
+// @Summary Keep a snippet's history forever
+// @Description Exempt a snippet's version history from retention, which otherwise deletes versions older than SNIPPET_VERSION_RETENTION_DAYS and all but the latest SNIPPET_MAX_VERSIONS, or stop exempting it. Turning it on requires the premium plan; after a downgrade the setting is kept but ignored (owner only).
+// @Tags snippets
+// @Accept json
+// @Produce json
+// @Param id path int true "Snippet ID"
+// @Param request body models.KeepHistorySnippetRequest true "Whether to keep all versions"
+// @Success 200 {object} models.Snippet
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /snippets/{id}/keep-history [put]
+func (s *Server) keepSnippetHistory(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		respondError(c, http.StatusInternalServerError, "Failed to fetch current snippet")
+		respondError(c, http.StatusBadRequest, "Invalid snippet ID")
 		return
 	}
-	// currentSnippet.Tags = tags // Unused, removed
 
-	// Static UPDATE using COALESCE to only update provided fields
-	query := `
-		UPDATE snippets
-		SET
-			label = COALESCE($1, label),
-			shortcut = COALESCE($2, shortcut),
-			content = COALESCE($3, content),
-			tags = COALESCE($4, tags)
-		WHERE id = $5 AND is_deleted = false
-		RETURNING id, label, shortcut, content, tags, user_id, created_at, updated_at
-	`
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
 
-	row := database.DB.QueryRowContext(c.Request.Context(), query, labelVal, shortcutVal, contentVal, tagsVal, id)
-	snippet, err := models.ScanSnippet(row)
-	if handleScanError(c, err, "Snippet not found") {
+	var req models.KeepHistorySnippetRequest
+	if bindErr := c.ShouldBindJSON(&req); bindErr != nil {
+		respondError(c, http.StatusBadRequest, bindErr.Error())
 		return
 	}
 
-	// Create history entry after successful update
-	historyQuery := `
-		INSERT INTO snippet_history (
-			snippet_id, version_number, label, shortcut, content, tags,
-			changed_by, change_type, change_notes
-		) VALUES (
-			$1, get_next_snippet_version($1), $2, $3, $4, $5, $6, 'edit', $7
-		)
-	`
-	_, err = database.DB.ExecContext(c.Request.Context(), historyQuery,
-		snippet.ID,
-		snippet.Label,
-		snippet.Shortcut,
-		snippet.Content,
-		pq.Array(snippet.Tags),
-		userID,
-		changeNotes,
-	)
-	if err != nil {
-		log.Printf("Failed to create snippet history: %v", err)
-		// Don't fail the request if history fails
+	snippet, err := s.store.SetSnippetKeepHistory(c.Request.Context(), id, userID, originSessionID(c), *req.KeepHistory)
+	if respondSnippetWriteError(c, err, "Failed to update snippet") {
+		return
 	}
 
 	respondSuccess(c, http.StatusOK, snippet)
 }
 
-// deleteSnippet deletes a snippet
-// @Summary Delete a snippet
-// @Description Delete a snippet (owner only)
+// pinSnippet pins a snippet to the top of the owner's listings
+// @Summary Pin a snippet
+// @Description Pin a snippet so it's listed first in GET /snippets (except searches), most recently pinned first. Up to 10 snippets can be pinned; pinning a pinned snippet keeps its place (owner only).
 // @Tags snippets
-// @Accept json
 // @Produce json
 // @Param id path int true "Snippet ID"
-// @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string
-// @Failure 403 {object} map[string]string
-// @Failure 404 {object} map[string]string
+// @Success 200 {object} models.Snippet
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Security BearerAuth
-// @Router /snippets/{id} [delete]
-func deleteSnippet(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
+// @Router /snippets/{id}/pin [post]
+func (s *Server) pinSnippet(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
 		respondError(c, http.StatusBadRequest, "Invalid snippet ID")
 		return
 	}
 
-	// Get authenticated user ID
 	userID, exists := getAuthUserID(c)
 	if !exists {
 		return
 	}
 
-	// Check if snippet exists and belongs to user (using prepared statement)
-	snippetUserID, err := database.CheckSnippetOwnership(c.Request.Context(), id)
-	if err == sql.ErrNoRows {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Snippet not found"})
+	snippet, err := s.store.PinSnippet(c.Request.Context(), id, userID, originSessionID(c))
+	if respondSnippetWriteError(c, err, "Failed to pin snippet") {
 		return
 	}
+
+	respondSuccess(c, http.StatusOK, snippet)
+}
+
+// unpinSnippet unpins a snippet
+// @Summary Unpin a snippet
+// @Tags snippets
+// @Produce json
+// @Param id path int true "Snippet ID"
+// @Success 200 {object} models.Snippet
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /snippets/{id}/pin [delete]
+func (s *Server) unpinSnippet(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check snippet ownership"})
+		respondError(c, http.StatusBadRequest, "Invalid snippet ID")
 		return
 	}
 
-	// Verify ownership
-	if !snippetUserID.Valid || !checkOwnership(c, snippetUserID.String, userID, "snippet") {
+	userID, exists := getAuthUserID(c)
+	if !exists {
 		return
 	}
 
-	// Get snippet data before deletion for history
-	var snippet models.Snippet
-	var tags pq.StringArray
-	var currentUserID sql.NullString
-	snippetQuery := `SELECT id, label, shortcut, content, tags, user_id, created_at, updated_at FROM snippets WHERE id = $1`
-	err = database.DB.QueryRowContext(c.Request.Context(), snippetQuery, id).Scan(
-		&snippet.ID,
-		&snippet.Label,
-		&snippet.Shortcut,
-		&snippet.Content,
-		&tags,
-		&currentUserID,
-		&snippet.CreatedAt,
-		&snippet.UpdatedAt,
-	)
-	if err != nil {
-		respondError(c, http.StatusInternalServerError, "Failed to fetch snippet")
+	snippet, err := s.store.UnpinSnippet(c.Request.Context(), id, userID, originSessionID(c))
+	if respondSnippetWriteError(c, err, "Failed to unpin snippet") {
 		return
 	}
-	snippet.Tags = tags
 
-	query := `UPDATE snippets SET is_deleted = true, deleted_at = NOW() WHERE id = $1 AND is_deleted = false`
+	respondSuccess(c, http.StatusOK, snippet)
+}
 
-	result, err := database.DB.ExecContext(c.Request.Context(), query, id)
+// archiveSnippet archives a snippet
+// @Summary Archive a snippet
+// @Description Hide a snippet from listings, search and sync without deleting it; synced devices get it as deleted. Archived snippets are kept out of the deletion and retention pipeline, can still be fetched by ID and are listed with include=archived. Archiving unpins the snippet (owner only).
+// @Tags snippets
+// @Produce json
+// @Param id path int true "Snippet ID"
+// @Success 200 {object} models.Snippet
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /snippets/{id}/archive [post]
+func (s *Server) archiveSnippet(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		respondError(c, http.StatusInternalServerError, "Failed to delete snippet")
+		respondError(c, http.StatusBadRequest, "Invalid snippet ID")
 		return
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	snippet, err := s.store.ArchiveSnippet(c.Request.Context(), id, userID, originSessionID(c))
+	if respondSnippetWriteError(c, err, "Failed to archive snippet") {
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, snippet)
+}
+
+// unarchiveSnippet unarchives a snippet
+// @Summary Unarchive a snippet
+// @Description Bring an archived snippet back to listings, search and sync (owner only)
+// @Tags snippets
+// @Produce json
+// @Param id path int true "Snippet ID"
+// @Success 200 {object} models.Snippet
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /snippets/{id}/archive [delete]
+func (s *Server) unarchiveSnippet(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		respondError(c, http.StatusInternalServerError, "Failed to verify deletion")
+		respondError(c, http.StatusBadRequest, "Invalid snippet ID")
 		return
 	}
 
-	if rowsAffected == 0 {
-		respondError(c, http.StatusNotFound, "Snippet not found")
+	userID, exists := getAuthUserID(c)
+	if !exists {
 		return
 	}
 
-	// Create history entry for soft delete
-	historyQuery := `
-		INSERT INTO snippet_history (
-			snippet_id, version_number, label, shortcut, content, tags,
-			changed_by, change_type, change_notes
-		) VALUES (
-			$1, get_next_snippet_version($1), $2, $3, $4, $5, $6, 'soft_delete', 'Snippet marked as deleted'
-		)
-	`
-	_, err = database.DB.ExecContext(c.Request.Context(), historyQuery,
-		snippet.ID,
-		snippet.Label,
-		snippet.Shortcut,
-		snippet.Content,
-		pq.Array(snippet.Tags),
-		userID,
-	)
+	snippet, err := s.store.UnarchiveSnippet(c.Request.Context(), id, userID, originSessionID(c))
+	if respondSnippetWriteError(c, err, "Failed to unarchive snippet") {
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, snippet)
+}
+
+// deleteSnippet deletes a snippet
+// @Summary Delete a snippet
+// @Description Delete a snippet (owner only)
+// @Tags snippets
+// @Accept json
+// @Produce json
+// @Param id path int true "Snippet ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /snippets/{id} [delete]
+func (s *Server) deleteSnippet(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		log.Printf("Failed to create snippet deletion history: %v", err)
-		// Don't fail the request if history fails
+		respondError(c, http.StatusBadRequest, "Invalid snippet ID")
+		return
+	}
+
+	// Get authenticated user ID
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	err = s.store.DeleteSnippet(c.Request.Context(), id, userID, originSessionID(c))
+	if respondSnippetWriteError(c, err, "Failed to delete snippet") {
+		return
 	}
 
 	respondSuccess(c, http.StatusOK, gin.H{"message": "Snippet deleted successfully"})
 }
 
+// historyFilter narrows a snippet's history to some change types and recent versions
+type historyFilter struct {
+	since       *time.Time
+	changeTypes []string
+}
+
+// parseHistoryFilter reads the changeType (comma-separated) and since (RFC 3339) query
+// parameters of a history listing
+func parseHistoryFilter(c *gin.Context) (historyFilter, error) {
+	var filter historyFilter
+	if raw := c.Query("changeType"); raw != "" {
+		for _, changeType := range strings.Split(raw, ",") {
+			changeType = strings.TrimSpace(changeType)
+			if !models.ValidChangeType(changeType) {
+				return filter, errors.New("changeType must be one of: create, edit, restore, soft_delete")
+			}
+			filter.changeTypes = append(filter.changeTypes, changeType)
+		}
+	}
+	if raw := c.Query("since"); raw != "" {
+		since, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return filter, errors.New("since must be an RFC 3339 timestamp")
+		}
+		filter.since = &since
+	}
+	return filter, nil
+}
+
 // getSnippetHistory retrieves version history for a snippet
 // @Summary Get snippet history
-// @Description Get all versions of a snippet with pagination (owner only)
+// @Description Get the versions of a snippet, newest first, one page at a time (owner only). changeType keeps only some kinds of change and since only versions recorded after a time, so snippets with hundreds of versions can be read in parts.
 // @Tags snippets
 // @Produce json
 // @Param id path int true "Snippet ID"
+// @Param changeType query string false "Comma-separated change types to keep: create, edit, restore, soft_delete"
+// @Param since query string false "Only versions recorded after this RFC 3339 time"
 // @Param limit query int false "Limit results (default 20, max 100)"
 // @Param offset query int false "Offset for pagination"
+// @Param cursor query string false "nextCursor of the previous page"
 // @Success 200 {object} map[string]interface{}
-// @Failure 400 {object} map[string]string
-// @Failure 403 {object} map[string]string
-// @Failure 404 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
 // @Security BearerAuth
 // @Router /snippets/{id}/history [get]
-func getSnippetHistory(c *gin.Context) {
+func (s *Server) getSnippetHistory(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
@@ -579,21 +572,11 @@ func getSnippetHistory(c *gin.Context) {
 		return
 	}
 
-	// Parse pagination params
-	limit := 20
-	offset := 0
-	if limitStr := c.Query("limit"); limitStr != "" {
-		if l, parseErr := strconv.Atoi(limitStr); parseErr == nil && l > 0 {
-			limit = l
-			if limit > 100 {
-				limit = 100
-			}
-		}
-	}
-	if offsetStr := c.Query("offset"); offsetStr != "" {
-		if o, parseErr := strconv.Atoi(offsetStr); parseErr == nil && o >= 0 {
-			offset = o
-		}
+	limit, offset := parseLimitOffset(c, 20, 100)
+	filter, err := parseHistoryFilter(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
 	}
 
 	// Get authenticated user ID
@@ -603,7 +586,7 @@ func getSnippetHistory(c *gin.Context) {
 	}
 
 	// Check if snippet exists and belongs to user (using prepared statement)
-	snippetUserID, err := database.CheckSnippetOwnership(c.Request.Context(), id)
+	snippetUserID, err := database.CheckSnippetOwnership(c.Request.Context(), s.db, id)
 	if err == sql.ErrNoRows {
 		respondError(c, http.StatusNotFound, "Snippet not found")
 		return
@@ -620,14 +603,16 @@ func getSnippetHistory(c *gin.Context) {
 	// Get history with pagination
 	query := `
 		SELECT id, snippet_id, version_number, label, shortcut, content, tags,
-		       changed_by, change_type, changed_at, change_notes
+		       changed_by, change_type, changed_at, change_notes, COUNT(*) OVER()
 		FROM snippet_history
 		WHERE snippet_id = $1
+		  AND ($4::text[] IS NULL OR change_type = ANY($4))
+		  AND ($5::timestamptz IS NULL OR changed_at > $5)
 		ORDER BY version_number DESC
 		LIMIT $2 OFFSET $3
 	`
 
-	rows, err := database.DB.QueryContext(c.Request.Context(), query, id, limit, offset)
+	rows, err := s.db.QueryContext(c.Request.Context(), query, id, limit, offset, pq.Array(filter.changeTypes), filter.since)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "Failed to fetch history")
 		return
@@ -638,6 +623,7 @@ func getSnippetHistory(c *gin.Context) {
 		}
 	}()
 
+	total := 0
 	history := make([]models.SnippetHistory, 0)
 	for rows.Next() {
 		var h models.SnippetHistory
@@ -656,6 +642,7 @@ func getSnippetHistory(c *gin.Context) {
 			&h.ChangeType,
 			&h.ChangedAt,
 			&changeNotes,
+			&total,
 		)
 		if err != nil {
 			respondError(c, http.StatusInternalServerError, "Failed to scan history")
@@ -675,33 +662,33 @@ func getSnippetHistory(c *gin.Context) {
 		return
 	}
 
-	respondWithCount(c, history, len(history))
+	respondPage(c, history, len(history), total, offset)
 }
 
 // restoreSnippetVersion restores a snippet to a previous version
 // @Summary Restore snippet version
-// @Description Restore a snippet to a specific version (owner only)
+// @Description Restore a snippet to a specific version (owner only). A deleted snippet comes back unpinned, and counts towards your plan's quota again.
 // @Tags snippets
 // @Accept json
 // @Produce json
 // @Param id path int true "Snippet ID"
 // @Param versionNumber path int true "Version Number"
 // @Success 200 {object} models.Snippet
-// @Failure 400 {object} map[string]string
-// @Failure 403 {object} map[string]string
-// @Failure 404 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "Another of your snippets has the version's shortcut"
+// @Failure 413 {object} ContentTooLargeResponse "The version's content is over your plan's size limit"
 // @Security BearerAuth
 // @Router /snippets/{id}/restore/{versionNumber} [post]
-func restoreSnippetVersion(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
+func (s *Server) restoreSnippetVersion(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
 		respondError(c, http.StatusBadRequest, "Invalid snippet ID")
 		return
 	}
 
-	versionStr := c.Param("versionNumber")
-	versionNumber, err := strconv.Atoi(versionStr)
+	versionNumber, err := strconv.Atoi(c.Param("versionNumber"))
 	if err != nil {
 		respondError(c, http.StatusBadRequest, "Invalid version number")
 		return
@@ -713,88 +700,72 @@ func restoreSnippetVersion(c *gin.Context) {
 		return
 	}
 
-	// Check if snippet exists and belongs to user (using prepared statement)
-	snippetUserID, err := database.CheckSnippetOwnership(c.Request.Context(), id)
-	if err == sql.ErrNoRows {
-		respondError(c, http.StatusNotFound, "Snippet not found")
+	snippet, err := s.store.RestoreSnippetVersion(c.Request.Context(), id, versionNumber, userID, originSessionID(c))
+	if respondSnippetWriteError(c, err, "Failed to restore snippet") {
 		return
 	}
+
+	respondSuccess(c, http.StatusOK, snippet)
+}
+
+// recordSnippetUse records that the authenticated user used one of their snippets
+// @Summary Record snippet use
+// @Description Record that a snippet was expanded; usage feeds the weekly digest's most used snippets
+// @Tags snippets
+// @Produce json
+// @Param id path int true "Snippet ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /snippets/{id}/use [post]
+func (s *Server) recordSnippetUse(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		respondError(c, http.StatusInternalServerError, "Failed to check snippet ownership")
+		respondError(c, http.StatusBadRequest, "Invalid snippet ID")
 		return
 	}
 
-	if !snippetUserID.Valid || !checkOwnership(c, snippetUserID.String, userID, "snippet") {
+	userID, exists := getAuthUserID(c)
+	if !exists {
 		return
 	}
 
-	// Get the historical version
-	var historicalVersion models.SnippetHistory
-	var tags pq.StringArray
-	historyQuery := `
-		SELECT label, shortcut, content, tags
-		FROM snippet_history
-		WHERE snippet_id = $1 AND version_number = $2
-	`
-	err = database.DB.QueryRowContext(c.Request.Context(), historyQuery, id, versionNumber).Scan(
-		&historicalVersion.Label,
-		&historicalVersion.Shortcut,
-		&historicalVersion.Content,
-		&tags,
-	)
+	err = s.store.RecordSnippetUse(c.Request.Context(), id, userID)
 	if err == sql.ErrNoRows {
-		respondError(c, http.StatusNotFound, "Version not found")
+		respondError(c, http.StatusNotFound, "Snippet not found")
 		return
 	}
 	if err != nil {
-		respondError(c, http.StatusInternalServerError, "Failed to fetch version")
+		respondError(c, http.StatusInternalServerError, "Failed to record snippet use")
 		return
 	}
-	historicalVersion.Tags = tags
 
-	// Restore the snippet to this version
-	updateQuery := `
-		UPDATE snippets
-		SET label = $1, shortcut = $2, content = $3, tags = $4, is_deleted = false, deleted_at = NULL
-		WHERE id = $5
-		RETURNING id, label, shortcut, content, tags, user_id, created_at, updated_at
-	`
-
-	row := database.DB.QueryRowContext(c.Request.Context(), updateQuery,
-		historicalVersion.Label,
-		historicalVersion.Shortcut,
-		historicalVersion.Content,
-		pq.Array(historicalVersion.Tags),
-		id,
-	)
+	respondSuccess(c, http.StatusOK, gin.H{"message": "Snippet use recorded"})
+}
 
-	snippet, err := models.ScanSnippet(row)
-	if handleScanError(c, err, "Failed to restore snippet") {
+// getRecentSnippets lists the authenticated user's most recently used snippets
+// @Summary List recently used snippets
+// @Description Your snippets ordered by when you last used them (as recorded by POST /snippets/{id}/use or /expand?record=true), last used first, for quick-pickers. Deleted and archived snippets are left out.
+// @Tags snippets
+// @Produce json
+// @Param limit query int false "Number of snippets (default 10, max 50)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /snippets/recent [get]
+func (s *Server) getRecentSnippets(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
 		return
 	}
 
-	// Create history entry for restore
-	historyInsert := `
-		INSERT INTO snippet_history (
-			snippet_id, version_number, label, shortcut, content, tags,
-			changed_by, change_type, change_notes
-		) VALUES (
-			$1, get_next_snippet_version($1), $2, $3, $4, $5, $6, 'restore', $7
-		)
-	`
-	changeNotes := "Restored to version " + versionStr
-	_, err = database.DB.ExecContext(c.Request.Context(), historyInsert,
-		snippet.ID,
-		snippet.Label,
-		snippet.Shortcut,
-		snippet.Content,
-		pq.Array(snippet.Tags),
-		userID,
-		changeNotes,
-	)
+	limit, _ := parseLimitOffset(c, models.DefaultRecentSnippets, models.MaxRecentSnippets)
+	recent, err := s.store.ListRecentSnippets(c.Request.Context(), userID, limit)
 	if err != nil {
-		log.Printf("Failed to create restore history: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to fetch recent snippets")
+		return
 	}
 
-	respondSuccess(c, http.StatusOK, snippet)
+	respondWithCount(c, recent, len(recent))
 }
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 
@@ -16,6 +17,9 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/jheysaaz/snippy-backend/app/auth"
 	"github.com/jheysaaz/snippy-backend/app/database"
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"github.com/jheysaaz/snippy-backend/app/placeholder"
+	"github.com/jheysaaz/snippy-backend/app/snippetquery"
 	_ "github.com/lib/pq"
 )
 
@@ -68,32 +72,96 @@ func setupTestDB(t *testing.T) *sql.DB {
 	}
 
 	// Clean up test data - drop in reverse dependency order
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS outbox_sink_deliveries")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS collection_members")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS snippet_shares")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS git_mirrors")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS integration_link_codes")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS integration_links")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS api_keys")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS extension_tokens")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS subscriptions")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS user_roles")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS roles")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS backup_runs")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS webhook_deliveries")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS webhooks")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS email_broadcast_recipients")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS email_broadcasts")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS audit_log")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS account_exports")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS notifications")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS device_tokens")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS notification_preferences")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS snippet_usage")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS user_blocks")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS follows")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS username_history")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS login_events")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS outbox_events")
 	_, _ = testDB.Exec("DROP TABLE IF EXISTS snippet_history")
 	_, _ = testDB.Exec("DROP TABLE IF EXISTS snippets")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS collections")
 	_, _ = testDB.Exec("DROP TABLE IF EXISTS refresh_tokens")
 	_, _ = testDB.Exec("DROP TABLE IF EXISTS sessions")
 	_, _ = testDB.Exec("DROP TABLE IF EXISTS users")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS organizations")
 
 	// Initialize schema with NEW structure (label, shortcut, content)
 	schema := `
 	CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+	CREATE EXTENSION IF NOT EXISTS pg_trgm;
+	CREATE EXTENSION IF NOT EXISTS unaccent;
+
+	CREATE TABLE IF NOT EXISTS organizations (
+		id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+		slug VARCHAR(63) NOT NULL UNIQUE,
+		name VARCHAR(255) NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);
+
+	INSERT INTO organizations (id, slug, name)
+	VALUES ('00000000-0000-0000-0000-000000000001', 'default', 'Default')
+	ON CONFLICT DO NOTHING;
 
 	CREATE TABLE IF NOT EXISTS users (
 		id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-		username VARCHAR(50) UNIQUE NOT NULL,
-		email VARCHAR(255) UNIQUE NOT NULL,
+		org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id),
+		username VARCHAR(50) NOT NULL,
+		email VARCHAR(255) NOT NULL,
 		password_hash TEXT NOT NULL,
 		full_name VARCHAR(255),
 		avatar_url TEXT,
+		email_verified_at TIMESTAMP WITH TIME ZONE,
+		last_login_at TIMESTAMP WITH TIME ZONE,
 		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
 		updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
 		is_deleted BOOLEAN NOT NULL DEFAULT FALSE,
-		deleted_at TIMESTAMP WITH TIME ZONE
+		deleted_at TIMESTAMP WITH TIME ZONE,
+		CONSTRAINT users_org_username_key UNIQUE (org_id, username),
+		CONSTRAINT users_org_email_key UNIQUE (org_id, email)
 	);
 
+	CREATE OR REPLACE FUNCTION set_org_id_from_user()
+	RETURNS TRIGGER AS $$
+	BEGIN
+		NEW.org_id := COALESCE((SELECT org_id FROM users WHERE id = NEW.user_id), NEW.org_id);
+		RETURN NEW;
+	END;
+	$$ LANGUAGE plpgsql;
+
+	CREATE OR REPLACE FUNCTION set_org_id_from_snippet()
+	RETURNS TRIGGER AS $$
+	BEGIN
+		NEW.org_id := COALESCE((SELECT org_id FROM snippets WHERE id = NEW.snippet_id), NEW.org_id);
+		RETURN NEW;
+	END;
+	$$ LANGUAGE plpgsql;
+
 	CREATE TABLE IF NOT EXISTS sessions (
 		id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
 		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id),
 		device_info TEXT,
 		ip_address_hash TEXT,
 		user_agent TEXT,
@@ -101,9 +169,16 @@ func setupTestDB(t *testing.T) *sql.DB {
 		last_activity TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
 		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
 		expires_at TIMESTAMP WITH TIME ZONE,
-		logged_out_at TIMESTAMP WITH TIME ZONE
+		logged_out_at TIMESTAMP WITH TIME ZONE,
+		last_synced_at TIMESTAMP WITH TIME ZONE
 	);
 
+	DROP TRIGGER IF EXISTS trigger_sessions_org_id ON sessions;
+	CREATE TRIGGER trigger_sessions_org_id
+		BEFORE INSERT ON sessions
+		FOR EACH ROW
+		EXECUTE FUNCTION set_org_id_from_user();
+
 	CREATE TABLE IF NOT EXISTS refresh_tokens (
 		id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
 		session_id UUID REFERENCES sessions(id) ON DELETE CASCADE,
@@ -113,6 +188,18 @@ func setupTestDB(t *testing.T) *sql.DB {
 		revoked BOOLEAN DEFAULT FALSE
 	);
 
+	CREATE TABLE IF NOT EXISTS collections (
+		id SERIAL PRIMARY KEY,
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		parent_id INTEGER REFERENCES collections(id) ON DELETE CASCADE,
+		name VARCHAR(100) NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_collections_user_parent_name ON collections(user_id, COALESCE(parent_id, 0), name);
+	CREATE INDEX IF NOT EXISTS idx_collections_parent_id ON collections(parent_id) WHERE parent_id IS NOT NULL;
+
 	CREATE TABLE IF NOT EXISTS snippets (
 		id SERIAL PRIMARY KEY,
 		label VARCHAR(255) NOT NULL,
@@ -120,15 +207,34 @@ func setupTestDB(t *testing.T) *sql.DB {
 		content TEXT NOT NULL,
 		tags TEXT[],
 		user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+		org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id),
+		visibility VARCHAR(20) NOT NULL DEFAULT 'private',
+		collection_id INTEGER REFERENCES collections(id) ON DELETE SET NULL,
+		is_favorite BOOLEAN NOT NULL DEFAULT FALSE,
+		pinned_at TIMESTAMP WITH TIME ZONE,
+		archived_at TIMESTAMP WITH TIME ZONE,
+		language VARCHAR(32),
+		placeholders JSONB NOT NULL DEFAULT '[]',
+		description TEXT,
+		keep_history BOOLEAN NOT NULL DEFAULT FALSE,
 		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
 		updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
 		is_deleted BOOLEAN NOT NULL DEFAULT FALSE,
 		deleted_at TIMESTAMP WITH TIME ZONE
 	);
 
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_snippets_user_shortcut_unique ON snippets(user_id, shortcut) WHERE is_deleted = false;
+
+	DROP TRIGGER IF EXISTS trigger_snippets_org_id ON snippets;
+	CREATE TRIGGER trigger_snippets_org_id
+		BEFORE INSERT ON snippets
+		FOR EACH ROW
+		EXECUTE FUNCTION set_org_id_from_user();
+
 	CREATE TABLE IF NOT EXISTS snippet_history (
 		id SERIAL PRIMARY KEY,
 		snippet_id INTEGER NOT NULL REFERENCES snippets(id) ON DELETE CASCADE,
+		org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id),
 		version_number INTEGER NOT NULL,
 		label VARCHAR(255) NOT NULL,
 		shortcut VARCHAR(50) NOT NULL,
@@ -140,10 +246,336 @@ func setupTestDB(t *testing.T) *sql.DB {
 		change_notes TEXT,
 		UNIQUE(snippet_id, version_number)
 	);
+
+	DROP TRIGGER IF EXISTS trigger_snippet_history_org_id ON snippet_history;
+	CREATE TRIGGER trigger_snippet_history_org_id
+		BEFORE INSERT ON snippet_history
+		FOR EACH ROW
+		EXECUTE FUNCTION set_org_id_from_snippet();
+
+	CREATE OR REPLACE FUNCTION get_next_snippet_version(p_snippet_id INTEGER)
+	RETURNS INTEGER AS $$
+		SELECT COALESCE(MAX(version_number), 0) + 1 FROM snippet_history WHERE snippet_id = p_snippet_id;
+	$$ LANGUAGE sql;
+
+	CREATE TABLE IF NOT EXISTS outbox_events (
+		id BIGSERIAL PRIMARY KEY,
+		event_type VARCHAR(100) NOT NULL,
+		aggregate_type VARCHAR(50) NOT NULL,
+		aggregate_id TEXT NOT NULL,
+		payload JSONB NOT NULL,
+		origin_session_id TEXT,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		next_attempt_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		delivered_at TIMESTAMP WITH TIME ZONE,
+		failed_at TIMESTAMP WITH TIME ZONE
+	);
+
+	CREATE TABLE IF NOT EXISTS login_events (
+		id BIGSERIAL PRIMARY KEY,
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		session_id UUID REFERENCES sessions(id) ON DELETE SET NULL,
+		method VARCHAR(20) NOT NULL,
+		success BOOLEAN NOT NULL,
+		failure_reason VARCHAR(50),
+		device_info TEXT,
+		ip_address_hash VARCHAR(64),
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS follows (
+		follower_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		followee_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (follower_id, followee_id)
+	);
+
+	CREATE TABLE IF NOT EXISTS user_blocks (
+		blocker_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		blocked_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (blocker_id, blocked_id)
+	);
+
+	CREATE TABLE IF NOT EXISTS snippet_usage (
+		id BIGSERIAL PRIMARY KEY,
+		snippet_id BIGINT NOT NULL REFERENCES snippets(id) ON DELETE CASCADE,
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		used_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS notification_preferences (
+		user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		weekly_digest BOOLEAN NOT NULL DEFAULT true,
+		timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+		digest_last_sent_at TIMESTAMP WITH TIME ZONE,
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS device_tokens (
+		id BIGSERIAL PRIMARY KEY,
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		session_id TEXT,
+		platform VARCHAR(10) NOT NULL,
+		token TEXT NOT NULL UNIQUE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		last_seen_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS notifications (
+		id BIGSERIAL PRIMARY KEY,
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		type VARCHAR(50) NOT NULL,
+		title VARCHAR(200) NOT NULL,
+		body TEXT NOT NULL DEFAULT '',
+		data JSONB,
+		read_at TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS username_history (
+		id BIGSERIAL PRIMARY KEY,
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		old_username VARCHAR(255) NOT NULL,
+		changed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS account_exports (
+		id BIGSERIAL PRIMARY KEY,
+		token_hash VARCHAR(64) NOT NULL UNIQUE,
+		user_id UUID NOT NULL,
+		username VARCHAR(50) NOT NULL,
+		archive BYTEA NOT NULL,
+		downloaded_at TIMESTAMP WITH TIME ZONE,
+		expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id BIGSERIAL PRIMARY KEY,
+		actor_id UUID,
+		action VARCHAR(100) NOT NULL,
+		target_type VARCHAR(50) NOT NULL,
+		target_id TEXT,
+		target_user_id UUID,
+		details JSONB,
+		ip_address_hash VARCHAR(64),
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS email_broadcasts (
+		id BIGSERIAL PRIMARY KEY,
+		subject VARCHAR(200) NOT NULL,
+		message TEXT NOT NULL,
+		filter JSONB NOT NULL DEFAULT '{}',
+		created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS email_broadcast_recipients (
+		broadcast_id BIGINT NOT NULL REFERENCES email_broadcasts(id) ON DELETE CASCADE,
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		email VARCHAR(255) NOT NULL,
+		username VARCHAR(50) NOT NULL,
+		status VARCHAR(20) NOT NULL DEFAULT 'pending',
+		attempts INT NOT NULL DEFAULT 0,
+		error TEXT,
+		next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		last_attempt_at TIMESTAMP WITH TIME ZONE,
+		sent_at TIMESTAMP WITH TIME ZONE,
+		PRIMARY KEY (broadcast_id, user_id)
+	);
+
+	CREATE TABLE IF NOT EXISTS webhooks (
+		id BIGSERIAL PRIMARY KEY,
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		url TEXT NOT NULL,
+		secret VARCHAR(100) NOT NULL,
+		events TEXT[] NOT NULL DEFAULT '{}',
+		description VARCHAR(200),
+		format VARCHAR(20) NOT NULL DEFAULT 'snippy' CHECK (format IN ('snippy', 'zapier')),
+		is_active BOOLEAN NOT NULL DEFAULT true,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id BIGSERIAL PRIMARY KEY,
+		webhook_id BIGINT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+		event_id BIGINT NOT NULL,
+		event_type VARCHAR(100) NOT NULL,
+		payload JSONB NOT NULL,
+		status VARCHAR(20) NOT NULL DEFAULT 'pending',
+		attempts INT NOT NULL DEFAULT 0,
+		response_status INT,
+		error TEXT,
+		next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		last_attempt_at TIMESTAMP WITH TIME ZONE,
+		delivered_at TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (webhook_id, event_id)
+	);
+
+	CREATE TABLE IF NOT EXISTS backup_runs (
+		id BIGSERIAL PRIMARY KEY,
+		status VARCHAR(20) NOT NULL,
+		started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+		finished_at TIMESTAMP WITH TIME ZONE NOT NULL,
+		object_key TEXT,
+		size_bytes BIGINT NOT NULL DEFAULT 0,
+		tables INT NOT NULL DEFAULT 0,
+		rows BIGINT NOT NULL DEFAULT 0,
+		pruned INT NOT NULL DEFAULT 0,
+		error TEXT
+	);
+
+	CREATE TABLE IF NOT EXISTS subscriptions (
+		user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		stripe_customer_id VARCHAR(100) NOT NULL UNIQUE,
+		stripe_subscription_id VARCHAR(100),
+		status VARCHAR(30) NOT NULL DEFAULT 'incomplete',
+		price_id VARCHAR(100),
+		current_period_end TIMESTAMP WITH TIME ZONE,
+		cancel_at_period_end BOOLEAN NOT NULL DEFAULT false,
+		stripe_event_at TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS roles (
+		id SERIAL PRIMARY KEY,
+		name VARCHAR(50) UNIQUE NOT NULL,
+		description TEXT,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);
+
+	INSERT INTO roles (name, description) VALUES
+		('admin', 'Administrator'), ('user', 'Standard user'), ('tester', 'Beta tester'), ('premium', 'Premium subscriber')
+	ON CONFLICT (name) DO NOTHING;
+
+	CREATE TABLE IF NOT EXISTS user_roles (
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+		assigned_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		assigned_by UUID REFERENCES users(id) ON DELETE SET NULL,
+		PRIMARY KEY (user_id, role_id)
+	);
+
+	-- Browser-extension tokens: long-lived, limited to reading snippets and reporting usage
+	CREATE TABLE IF NOT EXISTS extension_tokens (
+		id BIGSERIAL PRIMARY KEY,
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		token_hash VARCHAR(64) NOT NULL UNIQUE,
+		name VARCHAR(100) NOT NULL DEFAULT '',
+		scopes TEXT[] NOT NULL,
+		last_used_at TIMESTAMP WITH TIME ZONE,
+		expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+		revoked_at TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_extension_tokens_user_id ON extension_tokens(user_id);
+
+	-- API keys for third-party integrations, with their own scopes and rate limits
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id BIGSERIAL PRIMARY KEY,
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		key_hash VARCHAR(64) NOT NULL UNIQUE,
+		key_prefix VARCHAR(16) NOT NULL,
+		name VARCHAR(100) NOT NULL,
+		scopes TEXT[] NOT NULL,
+		rate_limit INTEGER NOT NULL,
+		request_count BIGINT NOT NULL DEFAULT 0,
+		last_used_at TIMESTAMP WITH TIME ZONE,
+		revoked_at TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
+
+	-- Chat identities (Slack per workspace, Discord) linked to Snippy users for slash commands
+	CREATE TABLE IF NOT EXISTS integration_links (
+		id BIGSERIAL PRIMARY KEY,
+		provider VARCHAR(20) NOT NULL,
+		workspace_id VARCHAR(32) NOT NULL DEFAULT '',
+		external_user_id VARCHAR(32) NOT NULL,
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (provider, workspace_id, external_user_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_integration_links_user_id ON integration_links(user_id);
+
+	-- One-time codes a user enters in Slack or Discord (/snippy link) to link their identity
+	CREATE TABLE IF NOT EXISTS integration_link_codes (
+		code_hash VARCHAR(64) PRIMARY KEY,
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_integration_link_codes_user_id ON integration_link_codes(user_id);
+
+	-- Git repositories users mirror their snippets to (one file per snippet)
+	CREATE TABLE IF NOT EXISTS git_mirrors (
+		user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		repo_url TEXT NOT NULL,
+		branch VARCHAR(255) NOT NULL DEFAULT 'main',
+		username VARCHAR(255) NOT NULL DEFAULT '',
+		token TEXT NOT NULL DEFAULT '',
+		mode VARCHAR(20) NOT NULL DEFAULT 'on_change' CHECK (mode IN ('on_change', 'schedule')),
+		interval_hours INTEGER NOT NULL DEFAULT 24 CHECK (interval_hours BETWEEN 1 AND 168),
+		next_sync_at TIMESTAMP WITH TIME ZONE,
+		sync_started_at TIMESTAMP WITH TIME ZONE,
+		last_synced_at TIMESTAMP WITH TIME ZONE,
+		last_commit VARCHAR(40),
+		last_error TEXT,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_git_mirrors_next_sync ON git_mirrors(next_sync_at) WHERE next_sync_at IS NOT NULL;
+
+	-- Create snippet_shares table granting other users access to a snippet; revoked grants
+	-- are kept until the snippet goes so sync can tell devices to drop it
+	CREATE TABLE IF NOT EXISTS snippet_shares (
+		snippet_id BIGINT NOT NULL REFERENCES snippets(id) ON DELETE CASCADE,
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		role VARCHAR(10) NOT NULL CHECK (role IN ('viewer', 'editor')),
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		revoked_at TIMESTAMP WITH TIME ZONE,
+		PRIMARY KEY (snippet_id, user_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_snippet_shares_user_id ON snippet_shares(user_id);
+
+	-- Create collection_members table sharing a collection, and the collections inside it,
+	-- with other users
+	CREATE TABLE IF NOT EXISTS collection_members (
+		collection_id INTEGER NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		role VARCHAR(10) NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+		created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (collection_id, user_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_collection_members_user_id ON collection_members(user_id);
+
+	CREATE TABLE IF NOT EXISTS outbox_sink_deliveries (
+		event_id BIGINT NOT NULL REFERENCES outbox_events(id) ON DELETE CASCADE,
+		sink VARCHAR(100) NOT NULL,
+		delivered_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (event_id, sink)
+	);
 	`
 	if _, execErr := testDB.Exec(schema); execErr != nil {
 		t.Fatalf("Failed to create test schema: %v", execErr)
 	}
+	if execErr := database.EnsureSearchLanguage(context.Background(), testDB, snippetquery.Language()); execErr != nil {
+		t.Fatalf("Failed to set up search: %v", execErr)
+	}
 
 	// Delete existing test user if it exists (from previous test runs)
 	_, _ = testDB.Exec(`DELETE FROM users WHERE username = 'testuser' OR email = 'test@example.com' OR id = $1`, testUserID)
@@ -160,11 +592,40 @@ func setupTestDB(t *testing.T) *sql.DB {
 	return testDB
 } // Clean up test database
 func cleanupTestDB(_ *testing.T, testDB *sql.DB) {
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS outbox_sink_deliveries")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS collection_members")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS snippet_shares")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS git_mirrors")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS integration_link_codes")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS integration_links")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS api_keys")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS extension_tokens")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS subscriptions")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS user_roles")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS roles")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS backup_runs")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS webhook_deliveries")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS webhooks")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS email_broadcast_recipients")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS email_broadcasts")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS audit_log")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS account_exports")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS notifications")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS device_tokens")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS notification_preferences")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS snippet_usage")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS user_blocks")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS follows")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS username_history")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS login_events")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS outbox_events")
 	_, _ = testDB.Exec("DROP TABLE IF EXISTS snippet_history")
 	_, _ = testDB.Exec("DROP TABLE IF EXISTS snippets")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS collections")
 	_, _ = testDB.Exec("DROP TABLE IF EXISTS refresh_tokens")
 	_, _ = testDB.Exec("DROP TABLE IF EXISTS sessions")
 	_, _ = testDB.Exec("DROP TABLE IF EXISTS users")
+	_, _ = testDB.Exec("DROP TABLE IF EXISTS organizations")
 	testDB.Close()
 }
 
@@ -231,6 +692,15 @@ func TestCreateSnippetValidation(t *testing.T) {
 			payload:        `{invalid json}`,
 			expectedStatus: http.StatusBadRequest,
 		},
+		{
+			name: "Unknown placeholder type",
+			payload: `{
+				"label": "Invoice",
+				"shortcut": "invoice",
+				"content": "Due {{due:dat}}"
+			}`,
+			expectedStatus: http.StatusBadRequest,
+		},
 	}
 
 	for _, tt := range tests {
@@ -239,12 +709,9 @@ func TestCreateSnippetValidation(t *testing.T) {
 			testDB := setupTestDB(t)
 			defer cleanupTestDB(t, testDB)
 
-			// Set database.DB for handlers
-			database.DB = testDB
-
 			router := gin.New()
 			// Add auth middleware for create endpoint
-			router.POST("/api/v1/snippets", auth.Middleware(), CreateSnippet)
+			router.POST("/api/v1/snippets", auth.Middleware(), NewServer(testDB, nil).createSnippet)
 
 			req, _ := http.NewRequestWithContext(context.Background(), "POST", "/api/v1/snippets", bytes.NewBufferString(tt.payload))
 			req.Header.Set("Content-Type", "application/json")
@@ -259,14 +726,77 @@ func TestCreateSnippetValidation(t *testing.T) {
 	}
 }
 
-func TestGetSnippetsEndpoint(t *testing.T) {
+func TestCreateSnippetDuplicateShortcut(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	testDB := setupTestDB(t)
 	defer cleanupTestDB(t, testDB)
 
-	// Set database.DB for handlers
-	database.DB = testDB
+	router := gin.New()
+	router.POST("/api/v1/snippets", auth.Middleware(), NewServer(testDB, nil).createSnippet)
+	create := func() *httptest.ResponseRecorder {
+		payload := `{"label": "Greeting", "shortcut": "hi", "content": "Hello!"}`
+		req, _ := http.NewRequestWithContext(context.Background(), "POST", "/api/v1/snippets", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+generateTestJWT())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := create(); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := create(); w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for a duplicate shortcut, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// A deleted snippet's shortcut can be reused
+	if _, err := testDB.Exec(`UPDATE snippets SET is_deleted = true, deleted_at = NOW() WHERE shortcut = 'hi'`); err != nil {
+		t.Fatalf("Failed to delete snippet: %v", err)
+	}
+	if w := create(); w.Code != http.StatusCreated {
+		t.Errorf("Expected status 201 reusing a deleted snippet's shortcut, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestCreateSnippetPlaceholders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	router := gin.New()
+	router.POST("/api/v1/snippets", auth.Middleware(), NewServer(testDB, nil).createSnippet)
+	payload := `{"label": "Reply", "shortcut": "reply", "content": "Hi {{name}}, see you on {{date}} at {{slot:time}}"}`
+	req, _ := http.NewRequestWithContext(context.Background(), "POST", "/api/v1/snippets", bytes.NewBufferString(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+generateTestJWT())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var snippet models.Snippet
+	if err := json.Unmarshal(w.Body.Bytes(), &snippet); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	want := []placeholder.Placeholder{
+		{Name: "name", Type: placeholder.TypeString},
+		{Name: "date", Type: placeholder.TypeDate},
+		{Name: "slot", Type: placeholder.TypeTime},
+	}
+	if !reflect.DeepEqual(snippet.Placeholders, want) {
+		t.Errorf("Expected placeholders %+v, got %+v", want, snippet.Placeholders)
+	}
+}
+
+func TestGetSnippetsEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
 
 	// Insert test data with NEW schema (label, shortcut, content)
 	// Use the same user_id as in generateTestJWT()
@@ -281,11 +811,13 @@ func TestGetSnippetsEndpoint(t *testing.T) {
 	}
 
 	router := gin.New()
-	router.GET("/api/v1/snippets", auth.Middleware(), GetCurrentUserSnippets)
+	router.GET("/api/v1/snippets", auth.Middleware(), NewServer(testDB, nil).getCurrentUserSnippets)
 
 	tests := []struct {
 		name           string
 		queryParams    string
+		firstShortcut  string
+		wantHighlight  string
 		expectedStatus int
 		checkCount     bool
 		expectedCount  int
@@ -308,7 +840,36 @@ func TestGetSnippetsEndpoint(t *testing.T) {
 			name:           "Search snippets",
 			queryParams:    "?search=Python",
 			expectedStatus: http.StatusOK,
-			checkCount:     false,
+			checkCount:     true,
+			expectedCount:  1,
+			firstShortcut:  "py-hello",
+			wantHighlight:  "<mark>Python</mark> Snippet",
+		},
+		{
+			name:           "Sort by label",
+			queryParams:    "?sort=label",
+			expectedStatus: http.StatusOK,
+			checkCount:     true,
+			expectedCount:  2,
+			firstShortcut:  "js-hello",
+		},
+		{
+			name:           "Sort by label descending",
+			queryParams:    "?sort=label&order=desc",
+			expectedStatus: http.StatusOK,
+			checkCount:     true,
+			expectedCount:  2,
+			firstShortcut:  "py-hello",
+		},
+		{
+			name:           "Unknown sort field",
+			queryParams:    "?sort=content",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unknown sort order",
+			queryParams:    "?sort=label&order=up",
+			expectedStatus: http.StatusBadRequest,
 		},
 	}
 
@@ -333,6 +894,21 @@ func TestGetSnippetsEndpoint(t *testing.T) {
 				if count != tt.expectedCount {
 					t.Errorf("Expected count %d, got %d", tt.expectedCount, count)
 				}
+				if tt.firstShortcut != "" {
+					first := response["items"].([]interface{})[0].(map[string]interface{})
+					if first["shortcut"] != tt.firstShortcut {
+						t.Errorf("Expected %s first, got %v", tt.firstShortcut, first["shortcut"])
+					}
+					if tt.wantHighlight != "" {
+						highlight, _ := first["highlight"].(map[string]interface{})
+						if highlight["label"] != tt.wantHighlight {
+							t.Errorf("Expected label highlight %q, got %v", tt.wantHighlight, highlight["label"])
+						}
+						if _, ok := first["rank"].(float64); !ok {
+							t.Errorf("Expected a rank, got %v", first["rank"])
+						}
+					}
+				}
 			}
 		})
 	}
@@ -344,8 +920,6 @@ func TestGetSingleSnippet(t *testing.T) {
 	testDB := setupTestDB(t)
 	defer cleanupTestDB(t, testDB)
 
-	database.DB = testDB
-
 	// Insert test snippet with NEW schema using matching user_id
 	var snippetID int64
 	err := testDB.QueryRow(`
@@ -357,8 +931,34 @@ func TestGetSingleSnippet(t *testing.T) {
 		t.Fatalf("Failed to insert test snippet: %v", err)
 	}
 
+	// Another user's private and public snippets
+	const otherUserID = "223e4567-e89b-12d3-a456-426614174000"
+	if _, err := testDB.Exec(`
+		INSERT INTO users (id, username, email, password_hash, full_name, avatar_url)
+		VALUES ($1, 'otheruser', 'other@example.com', 'dummy-hash', '', '')
+	`, otherUserID); err != nil {
+		t.Fatalf("Failed to create other user: %v", err)
+	}
+	var privateID, publicID int64
+	err = testDB.QueryRow(`
+		INSERT INTO snippets (label, shortcut, content, tags, user_id, visibility)
+		VALUES ('Private', 'other-private', 'secret', ARRAY['test'], $1, 'private')
+		RETURNING id
+	`, otherUserID).Scan(&privateID)
+	if err != nil {
+		t.Fatalf("Failed to insert private snippet: %v", err)
+	}
+	err = testDB.QueryRow(`
+		INSERT INTO snippets (label, shortcut, content, tags, user_id, visibility)
+		VALUES ('Public', 'other-public', 'shared', ARRAY['test'], $1, 'public')
+		RETURNING id
+	`, otherUserID).Scan(&publicID)
+	if err != nil {
+		t.Fatalf("Failed to insert public snippet: %v", err)
+	}
+
 	router := gin.New()
-	router.GET("/api/v1/snippets/:id", auth.Middleware(), GetSnippet)
+	router.GET("/api/v1/snippets/:id", auth.Middleware(), NewServer(testDB, nil).getSnippet)
 
 	tests := []struct {
 		name           string
@@ -375,6 +975,16 @@ func TestGetSingleSnippet(t *testing.T) {
 			snippetID:      "999",
 			expectedStatus: http.StatusNotFound,
 		},
+		{
+			name:           "Get another user's private snippet",
+			snippetID:      fmt.Sprintf("%d", privateID),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Get another user's public snippet",
+			snippetID:      fmt.Sprintf("%d", publicID),
+			expectedStatus: http.StatusOK,
+		},
 		{
 			name:           "Invalid snippet ID",
 			snippetID:      "invalid",
@@ -402,8 +1012,6 @@ func TestUpdateSnippet(t *testing.T) {
 	testDB := setupTestDB(t)
 	defer cleanupTestDB(t, testDB)
 
-	database.DB = testDB
-
 	// Insert test snippet with NEW schema using matching user_id
 	var snippetID int64
 	err := testDB.QueryRow(`
@@ -418,7 +1026,7 @@ func TestUpdateSnippet(t *testing.T) {
 	snippetIDStr := fmt.Sprintf("%d", snippetID)
 
 	router := gin.New()
-	router.PUT("/api/v1/snippets/:id", auth.Middleware(), UpdateSnippet)
+	router.PUT("/api/v1/snippets/:id", auth.Middleware(), NewServer(testDB, nil).updateSnippet)
 
 	tests := []struct {
 		name           string
@@ -480,8 +1088,6 @@ func TestDeleteSnippet(t *testing.T) {
 	testDB := setupTestDB(t)
 	defer cleanupTestDB(t, testDB)
 
-	database.DB = testDB
-
 	// Insert test snippet with NEW schema using matching user_id
 	var snippetID int64
 	err := testDB.QueryRow(`
@@ -494,7 +1100,7 @@ func TestDeleteSnippet(t *testing.T) {
 	}
 
 	router := gin.New()
-	router.DELETE("/api/v1/snippets/:id", auth.Middleware(), DeleteSnippet)
+	router.DELETE("/api/v1/snippets/:id", auth.Middleware(), NewServer(testDB, nil).deleteSnippet)
 
 	tests := []struct {
 		name           string
@@ -531,3 +1137,528 @@ func TestDeleteSnippet(t *testing.T) {
 		})
 	}
 }
+
+func TestFavoriteSnippet(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	var snippetID int64
+	err := testDB.QueryRow(`
+		INSERT INTO snippets (label, shortcut, content, tags, user_id)
+		VALUES ('Go-to', 'goto', 'code', ARRAY['test'], $1), ('Other', 'other', 'code', ARRAY['test'], $1)
+		RETURNING id
+	`, testUserID).Scan(&snippetID)
+	if err != nil {
+		t.Fatalf("Failed to insert test snippets: %v", err)
+	}
+	snippetIDStr := fmt.Sprintf("%d", snippetID)
+
+	s := NewServer(testDB, nil)
+	router := gin.New()
+	router.PUT("/api/v1/snippets/:id/favorite", auth.Middleware(), s.favoriteSnippet)
+	router.GET("/api/v1/snippets", auth.Middleware(), s.getCurrentUserSnippets)
+
+	tests := []struct {
+		name           string
+		snippetID      string
+		payload        string
+		expectedStatus int
+	}{
+		{name: "Missing favorite", snippetID: snippetIDStr, payload: `{}`, expectedStatus: http.StatusBadRequest},
+		{name: "Non-existent snippet", snippetID: "999999", payload: `{"favorite": true}`, expectedStatus: http.StatusNotFound},
+		{name: "Star", snippetID: snippetIDStr, payload: `{"favorite": true}`, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequestWithContext(context.Background(), "PUT", "/api/v1/snippets/"+tt.snippetID+"/favorite", bytes.NewBufferString(tt.payload))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+generateTestJWT())
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d. Response: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	req, _ := http.NewRequestWithContext(context.Background(), "GET", "/api/v1/snippets?favorites=true", nil)
+	req.Header.Set("Authorization", "Bearer "+generateTestJWT())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response struct {
+		Items []struct {
+			ID         int64 `json:"id"`
+			IsFavorite bool  `json:"isFavorite"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(response.Items) != 1 || response.Items[0].ID != snippetID || !response.Items[0].IsFavorite {
+		t.Errorf("Expected only the starred snippet, got %+v", response.Items)
+	}
+}
+
+func TestKeepSnippetHistory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	var snippetID int64
+	err := testDB.QueryRow(`
+		INSERT INTO snippets (label, shortcut, content, tags, user_id)
+		VALUES ('Keep', 'keep', 'code', ARRAY['test'], $1)
+		RETURNING id
+	`, testUserID).Scan(&snippetID)
+	if err != nil {
+		t.Fatalf("Failed to insert test snippet: %v", err)
+	}
+	path := fmt.Sprintf("/api/v1/snippets/%d/keep-history", snippetID)
+
+	s := NewServer(testDB, nil)
+	router := gin.New()
+	router.PUT("/api/v1/snippets/:id/keep-history", auth.Middleware(), s.keepSnippetHistory)
+
+	keep := func(payload string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequestWithContext(context.Background(), "PUT", path, bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+generateTestJWT())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := keep(`{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Missing keepHistory: expected status 400, got %d", w.Code)
+	}
+	if w := keep(`{"keepHistory": true}`); w.Code != http.StatusForbidden {
+		t.Errorf("Free plan: expected status 403, got %d. Response: %s", w.Code, w.Body.String())
+	}
+	if w := keep(`{"keepHistory": false}`); w.Code != http.StatusOK {
+		t.Errorf("Turning off on the free plan: expected status 200, got %d. Response: %s", w.Code, w.Body.String())
+	}
+
+	if _, err := testDB.Exec(`
+		INSERT INTO user_roles (user_id, role_id) SELECT $1, id FROM roles WHERE name = 'premium'
+	`, testUserID); err != nil {
+		t.Fatalf("Failed to grant premium: %v", err)
+	}
+	w := keep(`{"keepHistory": true}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Premium plan: expected status 200, got %d. Response: %s", w.Code, w.Body.String())
+	}
+	var snippet models.Snippet
+	if err := json.Unmarshal(w.Body.Bytes(), &snippet); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if !snippet.KeepHistory {
+		t.Errorf("Expected keepHistory to be set, got %+v", snippet)
+	}
+}
+
+func TestPinSnippet(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	var pinnedID int64
+	err := testDB.QueryRow(`
+		INSERT INTO snippets (label, shortcut, content, user_id, created_at)
+		VALUES ('Old', 'old', 'code', $1, NOW() - INTERVAL '1 day')
+		RETURNING id
+	`, testUserID).Scan(&pinnedID)
+	if err != nil {
+		t.Fatalf("Failed to insert test snippet: %v", err)
+	}
+	if _, err := testDB.Exec(`INSERT INTO snippets (label, shortcut, content, user_id) VALUES ('New', 'new', 'code', $1)`, testUserID); err != nil {
+		t.Fatalf("Failed to insert test snippet: %v", err)
+	}
+
+	s := NewServer(testDB, nil)
+	router := gin.New()
+	router.POST("/api/v1/snippets/:id/pin", auth.Middleware(), s.pinSnippet)
+	router.DELETE("/api/v1/snippets/:id/pin", auth.Middleware(), s.unpinSnippet)
+	router.GET("/api/v1/snippets", auth.Middleware(), s.getCurrentUserSnippets)
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequestWithContext(context.Background(), method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+generateTestJWT())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	firstShortcut := func() string {
+		var response struct {
+			Items []struct {
+				Shortcut string `json:"shortcut"`
+			} `json:"items"`
+		}
+		if err := json.Unmarshal(do("GET", "/api/v1/snippets").Body.Bytes(), &response); err != nil || len(response.Items) == 0 {
+			t.Fatalf("Failed to list snippets: %v", err)
+		}
+		return response.Items[0].Shortcut
+	}
+
+	pinPath := fmt.Sprintf("/api/v1/snippets/%d/pin", pinnedID)
+	if w := do("POST", pinPath); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 pinning, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := firstShortcut(); got != "old" {
+		t.Errorf("Expected the pinned snippet first, got %s", got)
+	}
+	if w := do("DELETE", pinPath); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 unpinning, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := firstShortcut(); got != "new" {
+		t.Errorf("Expected the newest snippet first after unpinning, got %s", got)
+	}
+
+	_, err = testDB.Exec(`
+		INSERT INTO snippets (label, shortcut, content, user_id, pinned_at)
+		SELECT 'Pinned', 'pinned-' || n, 'code', $1, NOW() FROM generate_series(1, $2) n
+	`, testUserID, models.MaxPinnedSnippets)
+	if err != nil {
+		t.Fatalf("Failed to insert pinned snippets: %v", err)
+	}
+	if w := do("POST", pinPath); w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 over the pin limit, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestArchiveSnippet(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	var snippetID int64
+	err := testDB.QueryRow(`
+		INSERT INTO snippets (label, shortcut, content, user_id, created_at, updated_at)
+		VALUES ('Stale', 'stale', 'code', $1, NOW() - INTERVAL '1 day', NOW() - INTERVAL '1 day')
+		RETURNING id
+	`, testUserID).Scan(&snippetID)
+	if err != nil {
+		t.Fatalf("Failed to insert test snippet: %v", err)
+	}
+	since := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	s := NewServer(testDB, nil)
+	router := gin.New()
+	router.POST("/api/v1/snippets/:id/archive", auth.Middleware(), s.archiveSnippet)
+	router.DELETE("/api/v1/snippets/:id/archive", auth.Middleware(), s.unarchiveSnippet)
+	router.GET("/api/v1/snippets", auth.Middleware(), s.getCurrentUserSnippets)
+	router.GET("/api/v1/snippets/sync", auth.Middleware(), s.syncSnippets)
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequestWithContext(context.Background(), method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+generateTestJWT())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	listed := func(path string) int {
+		var response struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		if err := json.Unmarshal(do("GET", path).Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to list snippets: %v", err)
+		}
+		return len(response.Items)
+	}
+
+	archivePath := fmt.Sprintf("/api/v1/snippets/%d/archive", snippetID)
+	if w := do("POST", archivePath); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 archiving, got %d: %s", w.Code, w.Body.String())
+	}
+	if n := listed("/api/v1/snippets"); n != 0 {
+		t.Errorf("Expected the archived snippet to be hidden, got %d snippets", n)
+	}
+	if n := listed("/api/v1/snippets?include=archived"); n != 1 {
+		t.Errorf("Expected include=archived to list the archived snippet, got %d snippets", n)
+	}
+
+	var changes struct {
+		Updated []json.RawMessage `json:"updated"`
+		Deleted []struct {
+			ID int64 `json:"id"`
+		} `json:"deleted"`
+	}
+	if err := json.Unmarshal(do("GET", "/api/v1/snippets/sync?updated_since="+since).Body.Bytes(), &changes); err != nil {
+		t.Fatalf("Failed to parse sync response: %v", err)
+	}
+	if len(changes.Updated) != 0 || len(changes.Deleted) != 1 || changes.Deleted[0].ID != snippetID {
+		t.Errorf("Expected sync to report the archived snippet as deleted, got %+v", changes)
+	}
+
+	if w := do("DELETE", archivePath); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 unarchiving, got %d: %s", w.Code, w.Body.String())
+	}
+	if n := listed("/api/v1/snippets"); n != 1 {
+		t.Errorf("Expected the unarchived snippet to be listed, got %d snippets", n)
+	}
+}
+
+func TestRestoreSnippetVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+	t.Setenv("QUOTA_MAX_SNIPPETS", "1")
+
+	var trashedID, liveID int64
+	err := testDB.QueryRow(`
+		INSERT INTO snippets (label, shortcut, content, user_id, pinned_at, is_deleted, deleted_at)
+		VALUES ('Trashed', 'trashed', 'latest', $1, NOW(), true, NOW())
+		RETURNING id
+	`, testUserID).Scan(&trashedID)
+	if err != nil {
+		t.Fatalf("Failed to insert test snippet: %v", err)
+	}
+	_, err = testDB.Exec(`
+		INSERT INTO snippet_history (snippet_id, version_number, label, shortcut, content, changed_by, change_type)
+		VALUES ($1, 1, 'Original', 'original', 'first', $2, 'create')
+	`, trashedID, testUserID)
+	if err != nil {
+		t.Fatalf("Failed to insert history: %v", err)
+	}
+	err = testDB.QueryRow(`
+		INSERT INTO snippets (label, shortcut, content, user_id) VALUES ('Live', 'live', 'code', $1)
+		RETURNING id
+	`, testUserID).Scan(&liveID)
+	if err != nil {
+		t.Fatalf("Failed to insert test snippet: %v", err)
+	}
+
+	s := NewServer(testDB, nil)
+	router := gin.New()
+	router.POST("/api/v1/snippets/:id/restore/:versionNumber", auth.Middleware(), s.restoreSnippetVersion)
+
+	restore := func(version int) *httptest.ResponseRecorder {
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, fmt.Sprintf("/api/v1/snippets/%d/restore/%d", trashedID, version), nil)
+		req.Header.Set("Authorization", "Bearer "+generateTestJWT())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := restore(2); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a missing version, got %d: %s", w.Code, w.Body.String())
+	}
+	// Restoring a trashed snippet brings it back, so it needs room in the plan
+	if w := restore(1); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403 at the snippet quota, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if _, err := testDB.Exec(`UPDATE snippets SET is_deleted = true, deleted_at = NOW() WHERE id = $1`, liveID); err != nil {
+		t.Fatalf("Failed to delete test snippet: %v", err)
+	}
+	w := restore(1)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 restoring, got %d: %s", w.Code, w.Body.String())
+	}
+	var snippet models.Snippet
+	if err := json.Unmarshal(w.Body.Bytes(), &snippet); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if snippet.Content != "first" || snippet.Shortcut != "original" {
+		t.Errorf("Expected version 1 restored, got %+v", snippet)
+	}
+	if snippet.PinnedAt != nil {
+		t.Error("Expected the restored snippet to come back unpinned")
+	}
+
+	var changeType string
+	var notes *string
+	if err := testDB.QueryRow(`
+		SELECT change_type, change_notes FROM snippet_history WHERE snippet_id = $1 AND version_number = 2
+	`, trashedID).Scan(&changeType, &notes); err != nil {
+		t.Fatalf("Failed to read history: %v", err)
+	}
+	if changeType != "restore" || notes == nil || *notes != "Restored to version 1" {
+		t.Errorf("Expected a restore history entry, got %s %v", changeType, notes)
+	}
+}
+
+func TestRestoreSnippetVersionContentTooLarge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+	t.Setenv("QUOTA_MAX_CONTENT_BYTES", "10")
+
+	// The version was saved under a higher limit, such as before a downgrade
+	var id int64
+	err := testDB.QueryRow(`
+		INSERT INTO snippets (label, shortcut, content, user_id) VALUES ('Short', 'short', 'tiny', $1)
+		RETURNING id
+	`, testUserID).Scan(&id)
+	if err != nil {
+		t.Fatalf("Failed to insert test snippet: %v", err)
+	}
+	_, err = testDB.Exec(`
+		INSERT INTO snippet_history (snippet_id, version_number, label, shortcut, content, changed_by, change_type)
+		VALUES ($1, 1, 'Long', 'long', 'twenty bytes of text', $2, 'create')
+	`, id, testUserID)
+	if err != nil {
+		t.Fatalf("Failed to insert history: %v", err)
+	}
+
+	s := NewServer(testDB, nil)
+	router := gin.New()
+	router.POST("/api/v1/snippets/:id/restore/:versionNumber", auth.Middleware(), s.restoreSnippetVersion)
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, fmt.Sprintf("/api/v1/snippets/%d/restore/1", id), nil)
+	req.Header.Set("Authorization", "Bearer "+generateTestJWT())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected status 413, got %d: %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		ContentBytes    int64 `json:"contentBytes"`
+		MaxContentBytes int64 `json:"maxContentBytes"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if body.ContentBytes != 20 || body.MaxContentBytes != 10 {
+		t.Errorf("Expected the version's size and the plan limit, got %s", w.Body.String())
+	}
+
+	var content string
+	if err := testDB.QueryRow(`SELECT content FROM snippets WHERE id = $1`, id).Scan(&content); err != nil {
+		t.Fatalf("Failed to read snippet: %v", err)
+	}
+	if content != "tiny" {
+		t.Errorf("Expected the snippet unchanged, got %q", content)
+	}
+}
+
+func TestPushSnippetChanges(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	var id int64
+	var base time.Time
+	err := testDB.QueryRow(`
+		INSERT INTO snippets (label, shortcut, content, user_id) VALUES ('Existing', 'existing', 'code', $1)
+		RETURNING id, updated_at
+	`, testUserID).Scan(&id, &base)
+	if err != nil {
+		t.Fatalf("Failed to insert test snippet: %v", err)
+	}
+
+	s := NewServer(testDB, nil)
+	router := gin.New()
+	router.POST("/api/v1/snippets/sync", auth.Middleware(), s.pushSnippetChanges)
+	push := func(changes []models.SyncPushChange) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(models.SyncPushRequest{Changes: changes})
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, "/api/v1/snippets/sync", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+generateTestJWT())
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := push([]models.SyncPushChange{{Op: models.SyncOpUpdate, ClientID: "u", ID: id}}); w.Code != http.StatusBadRequest {
+		t.Errorf("Update without a base: expected status 400, got %d", w.Code)
+	}
+
+	label, taken := "Edited", "existing"
+	w := push([]models.SyncPushChange{
+		{Op: models.SyncOpCreate, ClientID: "new", Create: &models.CreateSnippetRequest{Label: "New", Shortcut: "new", Content: "hello"}},
+		{Op: models.SyncOpCreate, ClientID: "dup", Create: &models.CreateSnippetRequest{Label: "Dup", Shortcut: taken, Content: "hello"}},
+		{Op: models.SyncOpUpdate, ClientID: "edit", ID: id, BaseUpdatedAt: &base, Update: &models.UpdateSnippetRequest{Label: &label}},
+		{Op: models.SyncOpDelete, ClientID: "stale", ID: id, BaseUpdatedAt: &base},
+		{Op: models.SyncOpDelete, ClientID: "gone", ID: 999999, BaseUpdatedAt: &base},
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var result models.SyncPushResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(result.Applied) != 3 || result.Applied[0].ClientID != "new" || result.Applied[0].Snippet == nil ||
+		result.Applied[1].ClientID != "edit" || result.Applied[1].Snippet.Label != label || result.Applied[2].ClientID != "gone" {
+		t.Errorf("Expected new, edit and gone applied, got %+v", result.Applied)
+	}
+	if len(result.Conflicts) != 2 ||
+		result.Conflicts[0].ClientID != "dup" || result.Conflicts[0].Reason != models.SyncConflictShortcutExists ||
+		result.Conflicts[1].ClientID != "stale" || result.Conflicts[1].Reason != models.SyncConflictChanged ||
+		result.Conflicts[1].Current == nil || result.Conflicts[1].Current.Label != label {
+		t.Errorf("Expected dup and stale conflicts, got %+v", result.Conflicts)
+	}
+
+	var live int
+	if err := testDB.QueryRow(`SELECT COUNT(*) FROM snippets WHERE NOT is_deleted`).Scan(&live); err != nil {
+		t.Fatalf("Failed to count snippets: %v", err)
+	}
+	if live != 2 {
+		t.Errorf("Expected the existing and new snippets, got %d", live)
+	}
+}
+
+func TestParseHistoryFilter(t *testing.T) {
+	parse := func(query string) (historyFilter, error) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/snippets/1/history?"+query, nil)
+		return parseHistoryFilter(c)
+	}
+
+	filter, err := parse("changeType=edit,%20restore&since=2024-03-01T12:00:00Z")
+	if err != nil {
+		t.Fatalf("parseHistoryFilter: %v", err)
+	}
+	if !reflect.DeepEqual(filter.changeTypes, []string{"edit", "restore"}) {
+		t.Errorf("changeTypes = %v, want edit and restore", filter.changeTypes)
+	}
+	if want := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC); filter.since == nil || !filter.since.Equal(want) {
+		t.Errorf("since = %v, want %v", filter.since, want)
+	}
+
+	if filter, err := parse(""); err != nil || filter.changeTypes != nil || filter.since != nil {
+		t.Errorf("empty filter = %+v, %v; want no conditions", filter, err)
+	}
+	for _, query := range []string{"changeType=rename", "changeType=edit,", "since=yesterday"} {
+		if _, err := parse(query); err == nil {
+			t.Errorf("parseHistoryFilter(%q) accepted an invalid filter", query)
+		}
+	}
+}
+
+func TestParseSyncCursor(t *testing.T) {
+	parse := func(query string) (models.SyncCursor, int, bool) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/snippets/sync?"+query, nil)
+		since, ok := parseSyncCursor(c)
+		return since, w.Code, ok
+	}
+
+	issued := models.SyncCursor{Time: time.Date(2024, 3, 1, 12, 0, 0, 500000, time.UTC), ID: 42}
+	since, _, ok := parse("cursor=" + issued.String() + "&updated_since=2020-01-01T00:00:00Z")
+	if !ok || since != issued {
+		t.Errorf("cursor = %+v, %v; want %+v, taking precedence over updated_since", since, ok, issued)
+	}
+	since, _, ok = parse("updated_since=2024-03-01T12:00:00Z")
+	if want := models.SyncCursorSince(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)); !ok || !since.Time.Equal(want.Time) || since.ID != want.ID {
+		t.Errorf("updated_since = %+v, %v; want %+v", since, ok, want)
+	}
+	for _, query := range []string{"", "cursor=not-a-cursor", "updated_since=yesterday"} {
+		if _, code, ok := parse(query); ok || code != http.StatusBadRequest {
+			t.Errorf("parseSyncCursor(%q) = %v with status %d, want a 400", query, ok, code)
+		}
+	}
+}
@@ -2,17 +2,42 @@ package handlers
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/apierror"
 	"github.com/jheysaaz/snippy-backend/app/auth"
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"github.com/jheysaaz/snippy-backend/app/placeholder"
 	"github.com/lib/pq"
 )
 
-// respondError sends a JSON error response
+// ErrorResponse documents the default error body; see apierror for the problem+json form
+type ErrorResponse struct {
+	Error string `json:"error" example:"Snippet not found"`
+}
+
+// ContentTooLargeResponse documents the 413 body for snippet content over the plan's size
+// limit. Upgrade names a plan with a higher limit (0 meaning unlimited), if there is one.
+type ContentTooLargeResponse struct {
+	Upgrade *struct {
+		Plan            string `json:"plan" example:"premium"`
+		MaxContentBytes int64  `json:"maxContentBytes" example:"512000"`
+	} `json:"upgrade,omitempty"`
+	Error           string `json:"error" example:"Snippet content is 40 KiB; your free plan allows up to 20 KiB. Upgrade to premium for up to 500 KiB"`
+	Plan            string `json:"plan" example:"free"`
+	ContentBytes    int64  `json:"contentBytes" example:"40960"`
+	MaxContentBytes int64  `json:"maxContentBytes" example:"20480"`
+}
+
+// respondError sends an error response, as a problem document if the client asked for one
 func respondError(c *gin.Context, status int, message string) {
-	c.JSON(status, gin.H{"error": message})
+	apierror.Respond(c, status, message)
 }
 
 // respondSuccess sends a JSON success response
@@ -28,6 +53,31 @@ func respondWithCount(c *gin.Context, items interface{}, count int) {
 	})
 }
 
+// pageInfo is the pagination metadata of a page of count items starting at offset, out
+// of total matching items. nextCursor, passed back as the cursor query parameter, fetches
+// the next page; it's null on the last one.
+func pageInfo(count, total, offset int) gin.H {
+	hasMore := offset+count < total
+	var nextCursor *string
+	if hasMore {
+		cursor := strconv.Itoa(offset + count)
+		nextCursor = &cursor
+	}
+	return gin.H{
+		"count":      count,
+		"total":      total,
+		"hasMore":    hasMore,
+		"nextCursor": nextCursor,
+	}
+}
+
+// respondPage sends one page of a list as items with its pagination metadata
+func respondPage(c *gin.Context, items interface{}, count, total, offset int) {
+	body := pageInfo(count, total, offset)
+	body["items"] = items
+	c.JSON(http.StatusOK, body)
+}
+
 // getAuthUserID retrieves authenticated user ID or sends unauthorized error
 func getAuthUserID(c *gin.Context) (string, bool) {
 	userID, exists := auth.GetUserIDFromContext(c)
@@ -110,3 +160,172 @@ func handleUserUniqueViolation(c *gin.Context, err error) bool {
 	}
 	return true
 }
+
+// rollbackTx rolls back a transaction unless it was already committed
+func rollbackTx(tx *sql.Tx) {
+	if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		log.Printf("error rolling back transaction: %v", err)
+	}
+}
+
+// originSessionID returns the login session a request comes from: the session of its
+// access token, as set by auth.Middleware. An X-Session-ID header naming another session
+// is ignored, so a client can't make its changes look like another device's and keep
+// them from being pushed there. Requests with a token without a session, such as an API
+// key, come from no session.
+func originSessionID(c *gin.Context) string {
+	return c.GetString("session_id")
+}
+
+// enqueueSnippetEvent writes a snippet domain event to the outbox within tx
+func enqueueSnippetEvent(c *gin.Context, tx *sql.Tx, eventType string, snippet *models.Snippet) error {
+	err := models.EnqueueEventFromSession(c.Request.Context(), tx, originSessionID(c), eventType, models.AggregateSnippet, strconv.FormatInt(snippet.ID, 10), snippet)
+	if err != nil {
+		log.Printf("Failed to enqueue %s event for snippet %d: %v", eventType, snippet.ID, err)
+	}
+	return err
+}
+
+// respondSnippetWriteError maps errors from snippet writes to responses.
+// It returns true if a response was sent.
+func respondSnippetWriteError(c *gin.Context, err error, failureMsg string) bool {
+	var tooLarge *models.ContentTooLargeError
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, sql.ErrNoRows):
+		respondError(c, http.StatusNotFound, "Snippet not found")
+	case errors.Is(err, models.ErrNotSnippetOwner):
+		respondError(c, http.StatusForbidden, "You don't have permission to access this snippet")
+	case errors.Is(err, models.ErrSnippetQuotaExceeded):
+		respondError(c, http.StatusForbidden, "Snippet limit reached for your plan")
+	case errors.Is(err, models.ErrStorageQuotaExceeded):
+		respondError(c, http.StatusForbidden, "Storage limit reached for your plan")
+	case errors.Is(err, models.ErrPremiumRequired):
+		respondError(c, http.StatusForbidden, "This feature requires the premium plan")
+	case errors.As(err, &tooLarge):
+		respondContentTooLarge(c, tooLarge)
+	case errors.Is(err, models.ErrPinLimitReached):
+		respondError(c, http.StatusConflict, fmt.Sprintf("You can pin up to %d snippets", models.MaxPinnedSnippets))
+	case errors.Is(err, models.ErrCollectionNotFound):
+		respondError(c, http.StatusBadRequest, "Collection not found")
+	case errors.Is(err, models.ErrShortcutExists):
+		respondError(c, http.StatusConflict, shortcutExistsMsg)
+	case errors.Is(err, placeholder.ErrInvalid):
+		respondError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, models.ErrInvalidLanguage):
+		respondError(c, http.StatusBadRequest, "Invalid language: "+err.Error())
+	case errors.Is(err, models.ErrSnippetVersionNotFound):
+		respondError(c, http.StatusNotFound, "Version not found")
+	case errors.Is(err, models.ErrSnippetNotDeleted):
+		respondError(c, http.StatusConflict, "Delete the snippet before purging it")
+	case errors.Is(err, models.ErrShareUserNotFound):
+		respondError(c, http.StatusNotFound, "User not found")
+	case errors.Is(err, models.ErrShareNotFound):
+		respondError(c, http.StatusNotFound, "Snippet is not shared with this user")
+	case errors.Is(err, models.ErrShareLimitReached):
+		respondError(c, http.StatusConflict, fmt.Sprintf("A snippet can be shared with up to %d users", models.MaxSnippetShares))
+	case errors.Is(err, models.ErrBlocked):
+		respondError(c, http.StatusForbidden, "You cannot share snippets with this user")
+	default:
+		log.Printf("%s: %v", failureMsg, err)
+		respondError(c, http.StatusInternalServerError, failureMsg)
+	}
+	return true
+}
+
+// shortcutExistsMsg explains a shortcut conflict: two snippets with one shortcut can't
+// both expand
+const shortcutExistsMsg = "You already have a snippet with this shortcut; choose a different shortcut or edit the existing snippet"
+
+// parseLimitOffset reads limit and offset query params, ignoring invalid values
+// and capping limit at maxLimit. A cursor from a previous page takes precedence over offset.
+func parseLimitOffset(c *gin.Context, defaultLimit, maxLimit int) (int, int) {
+	limit := defaultLimit
+	offset := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			limit = l
+			if limit > maxLimit {
+				limit = maxLimit
+			}
+		}
+	}
+	offsetStr := c.Query("cursor")
+	if offsetStr == "" {
+		offsetStr = c.Query("offset")
+	}
+	if offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+	return limit, offset
+}
+
+// auditAction fills in the acting user and client IP for an audit entry
+func auditAction(c *gin.Context, a models.AuditAction) models.AuditAction {
+	a.ActorID, _ = auth.GetUserIDFromContext(c)
+	a.IPAddress = c.ClientIP()
+	return a
+}
+
+// recordAudit writes an audit entry for an action that has already taken effect.
+// Failures are logged rather than failing the request.
+func (s *Server) recordAudit(c *gin.Context, a models.AuditAction) {
+	if err := models.RecordAudit(c.Request.Context(), s.db, auditAction(c, a)); err != nil {
+		log.Printf("Failed to record audit entry %s: %v", a.Action, err)
+	}
+}
+
+// isValidUUID reports whether s is a canonical hyphenated UUID
+func isValidUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, r := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if r != '-' {
+				return false
+			}
+		default:
+			if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// respondContentTooLarge answers 413 for content over the plan's size limit, with the
+// limit and, when upgrading raises it, the plan to upgrade to and its limit
+func respondContentTooLarge(c *gin.Context, err *models.ContentTooLargeError) {
+	message := fmt.Sprintf("Snippet content is %s; your %s plan allows up to %s", formatBytes(err.ContentBytes), err.Plan, formatBytes(err.MaxContentBytes))
+	details := gin.H{
+		"plan":            err.Plan,
+		"contentBytes":    err.ContentBytes,
+		"maxContentBytes": err.MaxContentBytes,
+	}
+	if err.UpgradePlan != "" {
+		details["upgrade"] = gin.H{"plan": err.UpgradePlan, "maxContentBytes": err.UpgradeMaxContentBytes}
+		if err.UpgradeMaxContentBytes == 0 {
+			message += fmt.Sprintf(". Upgrade to %s for unlimited snippet size", err.UpgradePlan)
+		} else {
+			message += fmt.Sprintf(". Upgrade to %s for up to %s", err.UpgradePlan, formatBytes(err.UpgradeMaxContentBytes))
+		}
+	}
+	apierror.RespondWithDetails(c, http.StatusRequestEntityTooLarge, message, details)
+}
+
+// formatBytes renders a size in bytes, KiB or MiB, such as 20 KiB or 1.5 MiB
+func formatBytes(n int64) string {
+	switch {
+	case n < 1<<10:
+		return fmt.Sprintf("%d bytes", n)
+	case n < 1<<20:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(n)/(1<<10)), ".0") + " KiB"
+	default:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(n)/(1<<20)), ".0") + " MiB"
+	}
+}
@@ -2,12 +2,14 @@ package handlers
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/models"
 )
 
 func init() {
@@ -61,6 +63,44 @@ func TestRespondWithCount(t *testing.T) {
 	}
 }
 
+func TestRespondPage(t *testing.T) {
+	tests := []struct {
+		wantCursor interface{}
+		name       string
+		count      int
+		total      int
+		offset     int
+		wantMore   bool
+	}{
+		{name: "first of several pages", count: 20, total: 45, offset: 0, wantMore: true, wantCursor: "20"},
+		{name: "middle page", count: 20, total: 45, offset: 20, wantMore: true, wantCursor: "40"},
+		{name: "last page", count: 5, total: 45, offset: 40, wantMore: false, wantCursor: nil},
+		{name: "empty list", count: 0, total: 0, offset: 0, wantMore: false, wantCursor: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			respondPage(c, make([]string, tt.count), tt.count, tt.total, tt.offset)
+
+			var body map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if body["total"] != float64(tt.total) || body["count"] != float64(tt.count) {
+				t.Errorf("total = %v, count = %v, want %d and %d", body["total"], body["count"], tt.total, tt.count)
+			}
+			if body["hasMore"] != tt.wantMore {
+				t.Errorf("hasMore = %v, want %v", body["hasMore"], tt.wantMore)
+			}
+			if body["nextCursor"] != tt.wantCursor {
+				t.Errorf("nextCursor = %v, want %v", body["nextCursor"], tt.wantCursor)
+			}
+		})
+	}
+}
+
 func TestHandleScanError(t *testing.T) {
 	tests := []struct {
 		err            error
@@ -175,6 +215,7 @@ func TestHandleUserUniqueViolation(t *testing.T) {
 	}{
 		{errors.New("duplicate key value violates unique constraint \"users_username_key\""), "duplicate username", true, false},
 		{errors.New("duplicate key value violates unique constraint \"users_email_key\""), "duplicate email", true, false},
+		{errors.New("duplicate key value violates unique constraint \"users_org_username_key\""), "duplicate username in organization", true, false},
 		{errors.New("generic database error"), "generic error", false, false},
 		{nil, "no error", false, true},
 	}
@@ -202,6 +243,97 @@ func TestHandleUserUniqueViolation(t *testing.T) {
 	}
 }
 
+func TestParseLimitOffset(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		expectedLimit  int
+		expectedOffset int
+	}{
+		{name: "defaults", query: "", expectedLimit: 20, expectedOffset: 0},
+		{name: "valid values", query: "limit=10&offset=30", expectedLimit: 10, expectedOffset: 30},
+		{name: "limit capped", query: "limit=500", expectedLimit: 100, expectedOffset: 0},
+		{name: "invalid values ignored", query: "limit=abc&offset=-5", expectedLimit: 20, expectedOffset: 0},
+		{name: "zero limit ignored", query: "limit=0", expectedLimit: 20, expectedOffset: 0},
+		{name: "cursor", query: "cursor=40", expectedLimit: 20, expectedOffset: 40},
+		{name: "cursor over offset", query: "cursor=40&offset=10", expectedLimit: 20, expectedOffset: 40},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil)
+
+			limit, offset := parseLimitOffset(c, 20, 100)
+
+			if limit != tt.expectedLimit {
+				t.Errorf("Expected limit %d, got %d", tt.expectedLimit, limit)
+			}
+			if offset != tt.expectedOffset {
+				t.Errorf("Expected offset %d, got %d", tt.expectedOffset, offset)
+			}
+		})
+	}
+}
+
+func TestIsValidUUID(t *testing.T) {
+	tests := map[string]bool{
+		"0b3c9a1e-6a5f-4c1b-9d2e-3f4a5b6c7d8e": true,
+		"0B3C9A1E-6A5F-4C1B-9D2E-3F4A5B6C7D8E": true,
+		"0b3c9a1e6a5f4c1b9d2e3f4a5b6c7d8e":     false,
+		"0b3c9a1e-6a5f-4c1b-9d2e-3f4a5b6c7d8z": false,
+		"":                                     false,
+	}
+	for input, want := range tests {
+		if got := isValidUUID(input); got != want {
+			t.Errorf("isValidUUID(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
+
+func TestRespondContentTooLarge(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/snippets", nil)
+
+	err := models.LoadQuotas().CheckContent(models.PlanFree, 2*models.DefaultMaxContentBytes)
+	if !respondSnippetWriteError(c, err, "Failed to create snippet") {
+		t.Fatal("expected a response")
+	}
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want 413", w.Code)
+	}
+	var body struct {
+		Error           string `json:"error"`
+		Plan            string `json:"plan"`
+		MaxContentBytes int64  `json:"maxContentBytes"`
+		Upgrade         *struct {
+			Plan            string `json:"plan"`
+			MaxContentBytes int64  `json:"maxContentBytes"`
+		} `json:"upgrade"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if body.Plan != models.PlanFree || body.MaxContentBytes != models.DefaultMaxContentBytes ||
+		body.Upgrade == nil || body.Upgrade.Plan != models.PlanPremium || body.Upgrade.MaxContentBytes != models.DefaultPremiumMaxContentBytes {
+		t.Errorf("body = %s, want the free limit and a premium upgrade hint", w.Body.String())
+	}
+	if want := "Snippet content is 40 KiB; your free plan allows up to 20 KiB. Upgrade to premium for up to 500 KiB"; body.Error != want {
+		t.Errorf("error = %q, want %q", body.Error, want)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{512: "512 bytes", 20 << 10: "20 KiB", 1536: "1.5 KiB", 3 << 20: "3 MiB"}
+	for n, want := range tests {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
 // Helper functions
 func strPtr(s string) *string {
 	return &s
@@ -220,3 +352,29 @@ func findSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+func TestOriginSessionID(t *testing.T) {
+	tests := []struct {
+		name, claim, header, want string
+	}{
+		{name: "token session", claim: "laptop", want: "laptop"},
+		{name: "matching header", claim: "laptop", header: "laptop", want: "laptop"},
+		{name: "other session in header", claim: "laptop", header: "phone", want: "laptop"},
+		{name: "token without session", header: "phone", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/snippets", nil)
+			if tt.claim != "" {
+				c.Set("session_id", tt.claim)
+			}
+			if tt.header != "" {
+				c.Request.Header.Set("X-Session-ID", tt.header)
+			}
+			if got := originSessionID(c); got != tt.want {
+				t.Errorf("originSessionID = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
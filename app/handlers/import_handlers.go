@@ -0,0 +1,185 @@
+// Package handlers provides snippet import endpoints.
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/importer"
+)
+
+// importSnippets creates snippets from another snippet manager's export
+// @Summary Import snippets
+// @Description Import snippets from Snippy's own JSON, zip or NDJSON export (snippy), a SnippetsLab JSON export (snippetslab), a Dash Snippets.dash library (dash) or Lepton's GitHub gists as JSON with file contents (lepton). Shortcuts are derived from labels where the format has none. Snippets whose shortcut you already have are skipped by default, so the same file can be imported again safely; conflict=rename imports them under a numbered shortcut and conflict=overwrite replaces your snippet. Every conflict is listed. dryRun=true previews the result without writing anything. The import is one transaction and stops at your plan's quota. Max 20 MiB.
+// @Tags snippets
+// @Accept multipart/form-data
+// @Produce json
+// @Param format formData string true "Export format" Enums(snippy, snippetslab, dash, lepton)
+// @Param file formData file true "Export file"
+// @Param conflict formData string false "Shortcut conflict strategy (default skip)" Enums(skip, rename, overwrite)
+// @Param dryRun formData bool false "Preview the import without writing"
+// @Success 200 {object} importer.Result
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /snippets/import [post]
+func (s *Server) importSnippets(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, importer.MaxUploadBytes+multipartOverhead)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondError(c, http.StatusRequestEntityTooLarge, "Export file must be at most 20 MiB")
+			return
+		}
+		respondError(c, http.StatusBadRequest, "Export file is required")
+		return
+	}
+
+	imp, ok := importer.Lookup(c.PostForm("format"))
+	if !ok {
+		respondError(c, http.StatusBadRequest, "format must be one of: "+strings.Join(importer.Formats(), ", "))
+		return
+	}
+	opts := importer.Options{Conflict: c.PostForm("conflict")}
+	if !importer.ValidConflict(opts.Conflict) {
+		respondError(c, http.StatusBadRequest, importer.ErrInvalidConflict.Error())
+		return
+	}
+	if raw := c.PostForm("dryRun"); raw != "" {
+		if opts.DryRun, err = strconv.ParseBool(raw); err != nil {
+			respondError(c, http.StatusBadRequest, "dryRun must be true or false")
+			return
+		}
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Failed to read export file")
+		return
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			log.Printf("error closing import upload: %v", closeErr)
+		}
+	}()
+
+	// Read one byte past the limit so a file just over it is refused, not truncated
+	data, err := io.ReadAll(io.LimitReader(file, importer.MaxUploadBytes+1))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Failed to read export file")
+		return
+	}
+	if len(data) > importer.MaxUploadBytes {
+		respondError(c, http.StatusRequestEntityTooLarge, "Export file must be at most 20 MiB")
+		return
+	}
+
+	snippets, err := imp.Parse(data)
+	if errors.Is(err, importer.ErrInvalidExport) {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to parse %s import: %v", imp.Format(), err)
+		respondError(c, http.StatusInternalServerError, "Failed to read export file")
+		return
+	}
+
+	s.respondImport(c, userID, snippets, opts)
+}
+
+// respondImport imports snippets for the user and responds with the result
+func (s *Server) respondImport(c *gin.Context, userID string, snippets []importer.Snippet, opts importer.Options) {
+	result, err := importer.Import(c.Request.Context(), s.store, userID, originSessionID(c), snippets, opts)
+	if err != nil {
+		log.Printf("Failed to import snippets: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to import snippets")
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, result)
+}
+
+// importGists creates snippets from GitHub gists
+// @Summary Import GitHub gists
+// @Description Import gists, either the ones at urls (gist URLs or IDs, up to 100) or, with only a token, every gist of the token's GitHub account (up to 300). The token is used for this import only and never stored. Each file becomes a snippet labelled with its file name, in the language GitHub detects (or guessed from the extension) and tagged with the hashtags of the gist's description. conflict and dryRun work as for /snippets/import. The import checks GitHub's rate limit as it goes and stops with a 429 before running out; Retry-After says when to try again.
+// @Tags snippets
+// @Accept json
+// @Produce json
+// @Param request body importer.GistImportRequest true "Gists to import"
+// @Success 200 {object} importer.Result
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse "Gist not found"
+// @Failure 429 {object} ErrorResponse "GitHub rate limit reached"
+// @Failure 502 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /snippets/import/gists [post]
+func (s *Server) importGists(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	var req importer.GistImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Token == "" && len(req.URLs) == 0 {
+		respondError(c, http.StatusBadRequest, importer.ErrGistSourceRequired.Error())
+		return
+	}
+	if len(req.URLs) > importer.MaxGistURLs {
+		respondError(c, http.StatusBadRequest, "At most "+strconv.Itoa(importer.MaxGistURLs)+" gist URLs can be imported at once")
+		return
+	}
+	ids := make([]string, 0, len(req.URLs))
+	for _, raw := range req.URLs {
+		id, ok := importer.GistIDFromURL(raw)
+		if !ok {
+			respondError(c, http.StatusBadRequest, importer.ErrInvalidGistURL.Error()+": "+raw)
+			return
+		}
+		ids = append(ids, id)
+	}
+	opts := importer.Options{Conflict: req.Conflict, DryRun: req.DryRun}
+	if !importer.ValidConflict(opts.Conflict) {
+		respondError(c, http.StatusBadRequest, importer.ErrInvalidConflict.Error())
+		return
+	}
+
+	snippets, err := importer.NewGistClient(importer.GitHubAPIURL(), req.Token).Fetch(c.Request.Context(), ids)
+	var rateLimited *importer.RateLimitError
+	switch {
+	case errors.As(err, &rateLimited):
+		c.Header("Retry-After", strconv.Itoa(max(int(time.Until(rateLimited.Reset).Seconds()), 1)))
+		respondError(c, http.StatusTooManyRequests, rateLimited.Error())
+		return
+	case errors.Is(err, importer.ErrGitHubUnauthorized):
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, importer.ErrGistNotFound):
+		respondError(c, http.StatusNotFound, err.Error())
+		return
+	case err != nil:
+		log.Printf("Failed to fetch gists: %v", err)
+		respondError(c, http.StatusBadGateway, "Failed to fetch gists from GitHub")
+		return
+	}
+
+	s.respondImport(c, userID, snippets, opts)
+}
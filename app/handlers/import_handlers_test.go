@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/importer"
+)
+
+func TestImportSnippetsValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		format  string
+		content string
+		fields  map[string]string
+		noFile  bool
+	}{
+		{name: "Missing file", format: "dash", noFile: true},
+		{name: "Unknown format", format: "evernote", content: "{}"},
+		{name: "Invalid export", format: "snippetslab", content: "not json"},
+		{name: "Not a Dash library", format: "dash", content: "{}"},
+		{name: "Unknown conflict strategy", format: "snippy", content: "[]", fields: map[string]string{"conflict": "merge"}},
+		{name: "Invalid dry run", format: "snippy", content: "[]", fields: map[string]string{"dryRun": "maybe"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body bytes.Buffer
+			form := multipart.NewWriter(&body)
+			if err := form.WriteField("format", tt.format); err != nil {
+				t.Fatal(err)
+			}
+			for field, value := range tt.fields {
+				if err := form.WriteField(field, value); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !tt.noFile {
+				part, err := form.CreateFormFile("file", "export")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := part.Write([]byte(tt.content)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := form.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Set("user_id", "0b3c9a1e-6a5f-4c1b-9d2e-3f4a5b6c7d8e")
+			c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/snippets/import", &body)
+			c.Request.Header.Set("Content-Type", form.FormDataContentType())
+
+			NewServer(nil, nil).importSnippets(c)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400 (body %s)", w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestImportSnippetsTooLarge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Just over the limit, but within the request's multipart allowance
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if err := form.WriteField("format", "snippy"); err != nil {
+		t.Fatal(err)
+	}
+	part, err := form.CreateFormFile("file", "export.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := part.Write(bytes.Repeat([]byte(" "), importer.MaxUploadBytes+1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := form.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user_id", "0b3c9a1e-6a5f-4c1b-9d2e-3f4a5b6c7d8e")
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/snippets/import", &body)
+	c.Request.Header.Set("Content-Type", form.FormDataContentType())
+
+	NewServer(nil, nil).importSnippets(c)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want 413 (body %s)", w.Code, w.Body.String())
+	}
+}
+
+func TestImportGistsValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tooMany := `{"urls": [` + strings.Repeat(`"abc",`, importer.MaxGistURLs) + `"abc"]}`
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "No token or URLs", body: `{}`},
+		{name: "Invalid URL", body: `{"urls": ["https://example.com/not-a-gist"]}`},
+		{name: "Too many URLs", body: tooMany},
+		{name: "Unknown conflict strategy", body: `{"urls": ["abc"], "conflict": "merge"}`},
+		{name: "Invalid JSON", body: `{"urls":`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Set("user_id", "0b3c9a1e-6a5f-4c1b-9d2e-3f4a5b6c7d8e")
+			c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/snippets/import/gists", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			NewServer(nil, nil).importGists(c)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400 (body %s)", w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+// Package handlers provides account links for the Slack and Discord integrations.
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// createIntegrationLinkCode issues a code for linking a Slack or Discord account
+// @Summary Create integration link code
+// @Description Create a one-time code, valid for 10 minutes, to link your Slack user (per workspace) or Discord user: run `/snippy link <code>` in Slack or `/snippy link code:<code>` in Discord. Creating a new code invalidates the previous one.
+// @Tags users
+// @Produce json
+// @Success 201 {object} map[string]interface{}
+// @Failure 401 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/integrations/link-code [post]
+func (s *Server) createIntegrationLinkCode(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	code, expiresAt, err := s.store.CreateIntegrationLinkCode(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to create link code")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	respondSuccess(c, http.StatusCreated, gin.H{
+		"code":      code,
+		"expiresAt": expiresAt,
+	})
+}
+
+// getMyIntegrationLinks lists the chat identities linked to the authenticated user
+// @Summary List integration links
+// @Description Slack users (by workspace) and Discord users that can post your snippets with /snippy
+// @Tags users
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/integrations/links [get]
+func (s *Server) getMyIntegrationLinks(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	links, err := s.store.GetUserIntegrationLinks(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch integration links")
+		return
+	}
+
+	respondWithCount(c, links, len(links))
+}
+
+// deleteIntegrationLink unlinks a chat identity from the authenticated user
+// @Summary Remove integration link
+// @Description Unlink a Slack or Discord user; /snippy stops working for them until they link again
+// @Tags users
+// @Produce json
+// @Param linkId path int true "Integration link ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/integrations/links/{linkId} [delete]
+func (s *Server) deleteIntegrationLink(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("linkId"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid integration link ID")
+		return
+	}
+
+	err = s.store.DeleteIntegrationLink(c.Request.Context(), userID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(c, http.StatusNotFound, "Integration link not found")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to remove integration link")
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, gin.H{"message": "Integration link removed"})
+}
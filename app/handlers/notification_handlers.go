@@ -0,0 +1,177 @@
+// Package handlers provides notification preference and in-app notification endpoints.
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+// getNotificationPreferences returns the authenticated user's notification preferences
+// @Summary Get notification preferences
+// @Description Get weekly digest and time zone preferences
+// @Tags users
+// @Produce json
+// @Success 200 {object} models.NotificationPreferences
+// @Security BearerAuth
+// @Router /users/me/notification-preferences [get]
+func (s *Server) getNotificationPreferences(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	prefs, err := s.store.GetNotificationPreferences(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch notification preferences")
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, prefs)
+}
+
+// updateNotificationPreferences changes the authenticated user's notification preferences
+// @Summary Update notification preferences
+// @Description Enable or disable the weekly digest and set the IANA time zone it is scheduled in
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param preferences body models.UpdateNotificationPreferencesRequest true "Preferences"
+// @Success 200 {object} models.NotificationPreferences
+// @Failure 400 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/notification-preferences [put]
+func (s *Server) updateNotificationPreferences(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	var req models.UpdateNotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.WeeklyDigest == nil && req.Timezone == nil {
+		respondError(c, http.StatusBadRequest, "No fields to update")
+		return
+	}
+	if req.Timezone != nil {
+		if _, err := time.LoadLocation(*req.Timezone); err != nil || *req.Timezone == "" {
+			respondError(c, http.StatusBadRequest, "timezone must be a valid IANA time zone")
+			return
+		}
+	}
+
+	prefs, err := s.store.UpdateNotificationPreferences(c.Request.Context(), userID, req)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to update notification preferences")
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, prefs)
+}
+
+// getNotifications lists the authenticated user's in-app notifications
+// @Summary List notifications
+// @Description Get in-app notifications, newest first, with the number of unread notifications
+// @Tags notifications
+// @Produce json
+// @Param unread query bool false "Only unread notifications"
+// @Param limit query int false "Limit results (default 20, max 100)"
+// @Param offset query int false "Offset for pagination"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /notifications [get]
+func (s *Server) getNotifications(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	unreadOnly := false
+	if raw := c.Query("unread"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "unread must be a boolean")
+			return
+		}
+		unreadOnly = parsed
+	}
+	limit, offset := parseLimitOffset(c, 20, 100)
+
+	notifications, total, err := s.store.GetNotifications(c.Request.Context(), userID, unreadOnly, limit, offset)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch notifications")
+		return
+	}
+	unread, err := s.store.CountUnreadNotifications(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch notifications")
+		return
+	}
+
+	body := pageInfo(len(notifications), total, offset)
+	body["items"] = notifications
+	body["unreadCount"] = unread
+	respondSuccess(c, http.StatusOK, body)
+}
+
+// markNotificationRead marks a notification as read
+// @Summary Mark notification read
+// @Description Mark one notification as read
+// @Tags notifications
+// @Produce json
+// @Param id path int true "Notification ID"
+// @Success 200 {object} models.Notification
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /notifications/{id}/read [post]
+func (s *Server) markNotificationRead(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid notification ID")
+		return
+	}
+
+	notification, err := s.store.MarkNotificationRead(c.Request.Context(), userID, id)
+	if handleScanError(c, err, "Notification not found") {
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, notification)
+}
+
+// markAllNotificationsRead marks every notification as read
+// @Summary Mark all notifications read
+// @Description Mark all unread notifications as read
+// @Tags notifications
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /notifications/read-all [post]
+func (s *Server) markAllNotificationsRead(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	updated, err := s.store.MarkAllNotificationsRead(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to mark notifications read")
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, gin.H{"updated": updated})
+}
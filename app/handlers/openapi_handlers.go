@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi2conv"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3gen"
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/apierror"
+	"github.com/jheysaaz/snippy-backend/docs"
+)
+
+var (
+	openAPIOnce sync.Once
+	openAPIDoc  []byte
+	openAPIErr  error
+)
+
+// getOpenAPI serves the API description as an OpenAPI 3 document, converted once from
+// the Swagger 2 document generated from the handler annotations
+func (s *Server) getOpenAPI(c *gin.Context) {
+	openAPIOnce.Do(func() {
+		openAPIDoc, openAPIErr = buildOpenAPI([]byte(docs.SwaggerInfo.ReadDoc()))
+	})
+	if openAPIErr != nil {
+		log.Printf("Failed to build OpenAPI document: %v", openAPIErr)
+		respondError(c, http.StatusInternalServerError, "OpenAPI document unavailable")
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", openAPIDoc)
+}
+
+// Schema names of the two error bodies
+const (
+	errorResponseRef = "#/components/schemas/handlers.ErrorResponse"
+	problemSchema    = "apierror.Problem"
+)
+
+// buildOpenAPI converts a Swagger 2 document to OpenAPI 3. Servers are reduced to the
+// base path so generated clients work against any host, the bearer token scheme, which
+// Swagger 2 can only express as an API key header, becomes an HTTP bearer scheme, and
+// every error response also offers the problem+json body.
+func buildOpenAPI(swagger []byte) ([]byte, error) {
+	var doc2 openapi2.T
+	if err := json.Unmarshal(swagger, &doc2); err != nil {
+		return nil, err
+	}
+	doc3, err := openapi2conv.ToV3(&doc2)
+	if err != nil {
+		return nil, err
+	}
+
+	doc3.Servers = openapi3.Servers{{URL: doc2.BasePath}}
+	if doc3.Components != nil {
+		if ref, ok := doc3.Components.SecuritySchemes["BearerAuth"]; ok {
+			ref.Value = openapi3.NewJWTSecurityScheme()
+			ref.Value.Description = "Access token from /auth/login or /auth/refresh"
+		}
+		if err := addProblemResponses(doc3); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(doc3)
+}
+
+// addProblemResponses adds an application/problem+json body next to each JSON error body
+func addProblemResponses(doc *openapi3.T) error {
+	schema, err := openapi3gen.NewSchemaRefForValue(apierror.Problem{}, nil)
+	if err != nil {
+		return err
+	}
+	if doc.Components.Schemas == nil {
+		doc.Components.Schemas = openapi3.Schemas{}
+	}
+	doc.Components.Schemas[problemSchema] = schema
+	problemRef := openapi3.NewSchemaRef("#/components/schemas/"+problemSchema, nil)
+
+	for _, item := range doc.Paths.Map() {
+		for _, op := range item.Operations() {
+			for _, resp := range op.Responses.Map() {
+				if resp.Value == nil {
+					continue
+				}
+				if media := resp.Value.Content.Get("application/json"); media != nil && media.Schema != nil && media.Schema.Ref == errorResponseRef {
+					resp.Value.Content[apierror.ProblemContentType] = openapi3.NewMediaType().WithSchemaRef(problemRef)
+				}
+			}
+		}
+	}
+	return nil
+}
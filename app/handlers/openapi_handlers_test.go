@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetOpenAPI(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+
+	NewServer(nil, nil).getOpenAPI(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+
+	doc, err := openapi3.NewLoader().LoadFromData(w.Body.Bytes())
+	if err != nil {
+		t.Fatalf("load document: %v", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		t.Fatalf("document is not valid OpenAPI 3: %v", err)
+	}
+
+	if len(doc.Servers) != 1 || doc.Servers[0].URL != "/api/v1" {
+		t.Errorf("servers = %+v, want the /api/v1 base path", doc.Servers)
+	}
+
+	bearer := doc.Components.SecuritySchemes["BearerAuth"]
+	if bearer == nil || bearer.Value.Type != "http" || bearer.Value.Scheme != "bearer" {
+		t.Errorf("BearerAuth = %+v, want an HTTP bearer scheme", bearer)
+	}
+
+	for _, name := range []string{"handlers.ErrorResponse", "apierror.Problem", "models.LoginResponse", "models.RefreshTokenResponse", "models.Snippet"} {
+		if doc.Components.Schemas[name] == nil {
+			t.Errorf("schema %s missing", name)
+		}
+	}
+
+	login := doc.Paths.Find("/auth/login")
+	if login == nil || login.Post == nil {
+		t.Fatal("POST /auth/login missing")
+	}
+	unauthorized := login.Post.Responses.Status(http.StatusUnauthorized)
+	if unauthorized == nil || unauthorized.Value.Content.Get("application/json").Schema.Ref != "#/components/schemas/handlers.ErrorResponse" {
+		t.Fatal("POST /auth/login 401 should use the ErrorResponse schema")
+	}
+	if problem := unauthorized.Value.Content.Get("application/problem+json"); problem == nil || problem.Schema.Ref != "#/components/schemas/apierror.Problem" {
+		t.Error("POST /auth/login 401 should offer the problem+json body")
+	}
+}
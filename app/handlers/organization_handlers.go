@@ -0,0 +1,73 @@
+// Package handlers provides organization (tenant) administration endpoints.
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+// listOrganizations lists the organizations hosted by the deployment
+// @Summary List organizations
+// @Description List all organizations (tenants) of the deployment (admin only)
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/organizations [get]
+func (s *Server) listOrganizations(c *gin.Context) {
+	orgs, err := s.store.ListOrganizations(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch organizations")
+		return
+	}
+
+	respondWithCount(c, orgs, len(orgs))
+}
+
+// createOrganization creates an organization
+// @Summary Create organization
+// @Description Create an organization (tenant). Its users sign up and log in with the X-Organization header set to its slug, or on the slug's subdomain of TENANT_BASE_DOMAIN. Slugs are lowercase letters, digits and dashes (admin only).
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param organization body models.CreateOrganizationRequest true "Organization data"
+// @Success 201 {object} models.Organization
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /admin/organizations [post]
+func (s *Server) createOrganization(c *gin.Context) {
+	var req models.CreateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !models.ValidOrgSlug(req.Slug) {
+		respondError(c, http.StatusBadRequest, "slug must be lowercase letters, digits and dashes, starting and ending with a letter or digit")
+		return
+	}
+
+	org, err := s.store.CreateOrganization(c.Request.Context(), req)
+	if errors.Is(err, models.ErrOrganizationSlugTaken) {
+		respondError(c, http.StatusConflict, "Organization slug already exists")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to create organization")
+		return
+	}
+
+	s.recordAudit(c, models.AuditAction{
+		Action:     models.AuditOrganizationCreated,
+		TargetType: models.AuditTargetOrganization,
+		TargetID:   org.ID,
+		Details:    gin.H{"slug": org.Slug, "name": org.Name},
+	})
+
+	respondSuccess(c, http.StatusCreated, org)
+}
@@ -0,0 +1,80 @@
+// Package handlers provides public, unauthenticated profile endpoints.
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/middleware"
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"github.com/jheysaaz/snippy-backend/app/snippetquery"
+)
+
+// getPublicProfile returns a user's public profile and their public snippets
+// @Summary Get public profile
+// @Description Get a user's public profile and public snippets by username (no authentication required)
+// @Tags public
+// @Produce json
+// @Param username path string true "Username"
+// @Param limit query int false "Limit snippets (default 50, max 100)"
+// @Param offset query int false "Offset for snippet pagination"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Success 200 {object} map[string]interface{}
+// @Success 307 "Redirect when username is a recently changed former username"
+// @Failure 404 {object} ErrorResponse
+// @Router /public/users/{username} [get]
+func (s *Server) getPublicProfile(c *gin.Context) {
+	username := c.Param("username")
+
+	limit, offset := parseLimitOffset(c, 50, 100)
+
+	var userID string
+	var profile models.PublicProfile
+	profileQuery := `
+		SELECT id, username, full_name, avatar_url, created_at
+		FROM users
+		WHERE username = $1 AND is_deleted = false AND org_id = $2
+	`
+	err := s.db.QueryRowContext(c.Request.Context(), profileQuery, username, middleware.OrgID(c)).Scan(
+		&userID,
+		&profile.Username,
+		&profile.FullName,
+		&profile.AvatarURL,
+		&profile.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Former usernames redirect to the account's current profile during the grace period
+		current, resolveErr := s.store.ResolveFormerUsername(c.Request.Context(), middleware.OrgID(c), username)
+		if resolveErr == nil {
+			target := "/api/v1/public/users/" + url.PathEscape(current)
+			if c.Request.URL.RawQuery != "" {
+				target += "?" + c.Request.URL.RawQuery
+			}
+			c.Redirect(http.StatusTemporaryRedirect, target)
+			return
+		}
+		if !errors.Is(resolveErr, sql.ErrNoRows) {
+			log.Printf("Failed to resolve former username %q: %v", username, resolveErr)
+		}
+	}
+	if handleScanError(c, err, "User not found") {
+		return
+	}
+
+	q := snippetquery.New().Where("user_id = ?", userID).Where("visibility = ?", models.VisibilityPublic).
+		Filter(snippetquery.Filter{Limit: limit, Offset: offset}).OrderBy("created_at DESC")
+	snippets, total, err := s.store.ListSnippetsPage(c.Request.Context(), q)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch public snippets")
+		return
+	}
+
+	body := pageInfo(len(snippets), total, offset)
+	body["user"] = profile
+	body["snippets"] = snippets
+	respondSuccess(c, http.StatusOK, body)
+}
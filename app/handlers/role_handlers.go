@@ -15,14 +15,14 @@ import (
 // @Produce json
 // @Param userId path string true "User ID"
 // @Success 200 {object} map[string]interface{}
-// @Failure 403 {object} map[string]string
-// @Failure 404 {object} map[string]string
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
 // @Security BearerAuth
 // @Router /admin/users/{userId}/roles [get]
-func getUserRoles(c *gin.Context) {
+func (s *Server) getUserRoles(c *gin.Context) {
 	userID := c.Param("userId")
 
-	roles, err := models.GetUserRoles(c.Request.Context(), userID)
+	roles, err := s.store.GetUserRoles(c.Request.Context(), userID)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "Failed to fetch user roles")
 		return
@@ -40,11 +40,11 @@ func getUserRoles(c *gin.Context) {
 // @Param userId path string true "User ID"
 // @Param role body object{roleName:string} true "Role to assign"
 // @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string
-// @Failure 403 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Security BearerAuth
 // @Router /admin/users/{userId}/roles [post]
-func assignUserRole(c *gin.Context) {
+func (s *Server) assignUserRole(c *gin.Context) {
 	userID := c.Param("userId")
 
 	var req struct {
@@ -62,12 +62,20 @@ func assignUserRole(c *gin.Context) {
 		return
 	}
 
-	err := models.AssignRole(c.Request.Context(), userID, req.RoleName, &adminUserID)
+	err := s.store.AssignRole(c.Request.Context(), userID, req.RoleName, &adminUserID)
 	if err != nil {
 		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
+	s.recordAudit(c, models.AuditAction{
+		Action:       models.AuditRoleAssigned,
+		TargetType:   models.AuditTargetUser,
+		TargetID:     userID,
+		TargetUserID: userID,
+		Details:      gin.H{"role": req.RoleName},
+	})
+
 	respondSuccess(c, http.StatusOK, gin.H{
 		"message": "Role assigned successfully",
 		"userId":  userID,
@@ -83,20 +91,28 @@ func assignUserRole(c *gin.Context) {
 // @Param userId path string true "User ID"
 // @Param roleName path string true "Role name"
 // @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string
-// @Failure 403 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Security BearerAuth
 // @Router /admin/users/{userId}/roles/{roleName} [delete]
-func revokeUserRole(c *gin.Context) {
+func (s *Server) revokeUserRole(c *gin.Context) {
 	userID := c.Param("userId")
 	roleName := c.Param("roleName")
 
-	err := models.RevokeRole(c.Request.Context(), userID, roleName)
+	err := s.store.RevokeRole(c.Request.Context(), userID, roleName)
 	if err != nil {
 		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
+	s.recordAudit(c, models.AuditAction{
+		Action:       models.AuditRoleRevoked,
+		TargetType:   models.AuditTargetUser,
+		TargetID:     userID,
+		TargetUserID: userID,
+		Details:      gin.H{"role": roleName},
+	})
+
 	respondSuccess(c, http.StatusOK, gin.H{
 		"message": "Role revoked successfully",
 		"userId":  userID,
@@ -112,13 +128,13 @@ func revokeUserRole(c *gin.Context) {
 // @Success 200 {object} map[string]interface{}
 // @Security BearerAuth
 // @Router /users/me/roles [get]
-func getMyRoles(c *gin.Context) {
+func (s *Server) getMyRoles(c *gin.Context) {
 	userID, exists := getAuthUserID(c)
 	if !exists {
 		return
 	}
 
-	roles, err := models.GetUserRoles(c.Request.Context(), userID)
+	roles, err := s.store.GetUserRoles(c.Request.Context(), userID)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "Failed to fetch roles")
 		return
@@ -134,8 +150,8 @@ func getMyRoles(c *gin.Context) {
 // @Produce json
 // @Success 200 {object} map[string]interface{}
 // @Router /roles [get]
-func getAllRoles(c *gin.Context) {
-	roles, err := models.GetAllRoles(c.Request.Context())
+func (s *Server) getAllRoles(c *gin.Context) {
+	roles, err := s.store.GetAllRoles(c.Request.Context())
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "Failed to fetch roles")
 		return
@@ -1,74 +1,309 @@
 package handlers
 
 import (
+	"net/http"
+
 	"github.com/gin-gonic/gin"
 	"github.com/jheysaaz/snippy-backend/app/auth"
+	"github.com/jheysaaz/snippy-backend/app/middleware"
+	"github.com/jheysaaz/snippy-backend/app/models"
 )
 
-// Public exports for route handlers
-
-// Auth handlers
-var (
-	Login              = login
-	CheckAvailability  = checkAvailability
-	RefreshAccessToken = refreshAccessToken
-	Logout             = logout
-	LogoutAll          = logoutAll
-	GetSessions        = getSessions
-	LogoutSession      = logoutSession
-)
+// RateLimits are the limiters applied to routes on top of the general per-IP limit
+type RateLimits struct {
+	// Strict limits authentication routes per IP
+	Strict *middleware.RateLimiter
+	// Refresh limits token refreshes per user
+	Refresh *middleware.RefreshRateLimiter
+	// APIKey holds API keys to their own per-key limit
+	APIKey *middleware.APIKeyRateLimiter
+}
 
-// User handlers
-var (
-	GetUsers   = getUsers
-	CreateUser = createUser
-	GetUser    = getUser
-	UpdateUser = updateUser
-	DeleteUser = deleteUser
-)
+// Register registers the API routes, the OpenAPI description and the snippet embeds on r
+func (s *Server) Register(r *gin.Engine, limits RateLimits) {
+	r.GET("/openapi.json", s.getOpenAPI)
 
-// Snippet handlers
-var (
-	GetSnippets           = getSnippets
-	SyncSnippets          = syncSnippets
-	CreateSnippet         = createSnippet
-	GetSnippet            = getSnippet
-	UpdateSnippet         = updateSnippet
-	DeleteSnippet         = deleteSnippet
-	GetUserSnippets       = getUserSnippets
-	GetSnippetHistory     = getSnippetHistory
-	RestoreSnippetVersion = restoreSnippetVersion
-)
+	// Embeddable views of public snippets (framed by other sites, see /api/v1/oembed)
+	r.GET("/embed/snippets/:id", s.getSnippetEmbed)
 
-// Role handlers
-var (
-	GetUserRoles   = getUserRoles
-	AssignUserRole = assignUserRole
-	RevokeUserRole = revokeUserRole
-	GetMyRoles     = getMyRoles
-	GetAllRoles    = getAllRoles
-)
+	// API routes
+	api := r.Group("/api/v1")
+	{
+		// Authentication routes (with strict rate limiting)
+		authRoutes := api.Group("/auth")
+		{
+			strictLimit := middleware.StrictRateLimitMiddleware(limits.Strict)
+			authRoutes.POST("/register", strictLimit, s.createUser)
+			authRoutes.POST("/login", strictLimit, s.login)
+			authRoutes.GET("/availability", strictLimit, s.checkAvailability)
+			authRoutes.POST("/logout", strictLimit, s.logout)
+			authRoutes.POST("/logout-all", strictLimit, s.logoutAll)
+
+			// Refreshes are limited per user rather than per IP
+			authRoutes.POST("/refresh", middleware.RefreshRateLimitMiddleware(limits.Refresh), s.refreshAccessToken)
+		}
+
+		// Protected auth routes (require authentication)
+		protectedAuth := api.Group("/auth")
+		protectedAuth.Use(auth.Middleware())
+		{
+			// Sensitive routes also check that the token's session hasn't been logged out
+			activeSession := auth.RequireActiveSession(s.store)
+
+			// Sessions endpoints restricted to tester/premium/admin users
+			protectedAuth.GET("/sessions", activeSession, middleware.SessionsAccess(s.store), s.getSessions)
+			protectedAuth.POST("/sessions/:sessionId", activeSession, middleware.SessionsAccess(s.store), s.logoutSession)
+
+			// Long-lived, read-only token for the browser extension
+			protectedAuth.POST("/extension-token", activeSession, s.createExtensionToken)
+		}
+
+		// Public role routes
+		api.GET("/roles", s.getAllRoles)
+
+		// Public avatar images (referenced from avatarUrl, loaded without auth headers)
+		api.GET("/avatars/:userId/:size", s.getAvatar)
+
+		// Public announcements (maintenance notices, release notes)
+		api.GET("/announcements", s.getAnnouncements)
+
+		// Final exports of purged accounts (the token in the emailed link is the credential)
+		api.GET("/exports/:token", s.downloadAccountExport)
+
+		// Stripe webhook (authenticated by its signature)
+		api.POST("/billing/webhook", s.stripeWebhook)
+
+		// Slack and Discord slash commands (authenticated by their signatures)
+		api.POST("/slack/commands", s.slackCommand)
+		api.POST("/discord/interactions", s.discordInteraction)
+
+		// Public profiles (no authentication)
+		public := api.Group("/public")
+		{
+			public.GET("/users/:username", s.getPublicProfile)
+			public.GET("/snippets/:id", s.getPublicSnippetEmbed)
+		}
+
+		// oEmbed for public snippets
+		api.GET("/oembed", s.getOEmbed)
+
+		// Snippet routes also open to browser-extension tokens and API keys. API keys are
+		// held to their own rate limit on top of the per-IP one.
+		keyLimit := middleware.APIKeyRateLimitMiddleware(limits.APIKey)
+		readSnippets := auth.ScopedMiddleware(s.store, models.ScopeSnippetsRead)
+		writeSnippets := auth.ScopedMiddleware(s.store, models.ScopeSnippetsWrite)
+		reportUsage := auth.ScopedMiddleware(s.store, models.ScopeUsageWrite)
+		scopedSnippets := api.Group("/snippets")
+		{
+			scopedSnippets.GET("/", readSnippets, keyLimit, s.getCurrentUserSnippets)
+			scopedSnippets.POST("/", writeSnippets, keyLimit, s.createSnippet)
+			scopedSnippets.POST("/import", writeSnippets, keyLimit, s.importSnippets)
+			scopedSnippets.POST("/import/gists", writeSnippets, keyLimit, s.importGists)
+			scopedSnippets.GET("/export", readSnippets, keyLimit, s.exportSnippets)
+			scopedSnippets.GET("/sync", readSnippets, keyLimit, s.syncSnippets)
+			scopedSnippets.POST("/sync", writeSnippets, keyLimit, s.pushSnippetChanges)
+			scopedSnippets.GET("/events", readSnippets, keyLimit, s.streamSnippetEvents)
+			scopedSnippets.GET("/search", readSnippets, keyLimit, s.searchSnippets)
+			scopedSnippets.GET("/espanso", readSnippets, keyLimit, s.getEspansoMatches)
+			scopedSnippets.GET("/tags", readSnippets, keyLimit, s.getSnippetTags)
+			scopedSnippets.POST("/tags/rename", writeSnippets, keyLimit, s.renameTag)
+			scopedSnippets.GET("/trash", readSnippets, keyLimit, s.getSnippetTrash)
+			scopedSnippets.GET("/recent", readSnippets, keyLimit, s.getRecentSnippets)
+			scopedSnippets.GET("/by-shortcut/*shortcut", readSnippets, keyLimit, s.getSnippetByShortcut)
+			scopedSnippets.POST("/bulk-delete", writeSnippets, keyLimit, s.bulkDeleteSnippets)
+			scopedSnippets.POST("/bulk-update", writeSnippets, keyLimit, s.bulkUpdateSnippets)
+			scopedSnippets.GET("/:id", readSnippets, keyLimit, s.getSnippet)
+			scopedSnippets.PUT("/:id", writeSnippets, keyLimit, s.updateSnippet)
+			scopedSnippets.DELETE("/:id", writeSnippets, keyLimit, s.deleteSnippet)
+			scopedSnippets.PUT("/:id/favorite", writeSnippets, keyLimit, s.favoriteSnippet)
+			scopedSnippets.PUT("/:id/keep-history", writeSnippets, keyLimit, s.keepSnippetHistory)
+			scopedSnippets.POST("/:id/pin", writeSnippets, keyLimit, s.pinSnippet)
+			scopedSnippets.DELETE("/:id/pin", writeSnippets, keyLimit, s.unpinSnippet)
+			scopedSnippets.POST("/:id/archive", writeSnippets, keyLimit, s.archiveSnippet)
+			scopedSnippets.DELETE("/:id/archive", writeSnippets, keyLimit, s.unarchiveSnippet)
+			scopedSnippets.POST("/:id/undelete", writeSnippets, keyLimit, s.undeleteSnippet)
+			scopedSnippets.DELETE("/:id/purge", writeSnippets, keyLimit, s.purgeSnippet)
+			scopedSnippets.POST("/:id/render", readSnippets, keyLimit, s.renderSnippet)
+			scopedSnippets.GET("/:id/shares", readSnippets, keyLimit, s.getSnippetShares)
+			scopedSnippets.PUT("/:id/shares/:userId", writeSnippets, keyLimit, s.shareSnippet)
+			scopedSnippets.DELETE("/:id/shares/:userId", writeSnippets, keyLimit, s.unshareSnippet)
+			scopedSnippets.POST("/:id/use", reportUsage, keyLimit, s.recordSnippetUse)
+		}
+
+		// Shortcut lookup for launchers and text expanders
+		api.GET("/expand", readSnippets, keyLimit, s.expandShortcut)
+
+		// Zapier polling triggers and REST hooks, authenticated with an API key
+		zapier := api.Group("/zapier", readSnippets, keyLimit)
+		{
+			zapier.GET("/me", s.zapierMe)
+			zapier.GET("/triggers/:trigger", s.getZapierTrigger)
+			zapier.POST("/hooks", s.subscribeZapierHook)
+			zapier.DELETE("/hooks/:id", s.unsubscribeZapierHook)
+		}
+
+		// Protected routes (require authentication)
+		protected := api.Group("")
+		protected.Use(auth.Middleware())
+		{
+			activeSession := auth.RequireActiveSession(s.store)
+
+			// User routes
+			users := protected.Group("/users")
+			{
+				users.GET("/", s.getUsers)
+				users.GET("/profile", s.getCurrentUser)
+				users.PUT("/profile", activeSession, s.updateCurrentUser)
+				users.POST("/profile/avatar", s.uploadAvatar)
+				users.DELETE("/profile/avatar", s.deleteAvatar)
+				users.GET("/me/roles", s.getMyRoles)
+				users.GET("/me/logins", s.getMyLogins)
+				users.GET("/me/usage", s.getMyUsage)
+				users.GET("/me/quota", s.getMyQuota)
+				users.GET("/me/export", activeSession, s.exportMySnippets)
+				users.GET("/me/following", s.getMyFollowing)
+				users.GET("/me/followers", s.getMyFollowers)
+				users.GET("/me/feed", s.getFeed)
+				users.GET("/me/blocks", s.getMyBlocks)
+				users.GET("/me/notification-preferences", s.getNotificationPreferences)
+				users.PUT("/me/notification-preferences", s.updateNotificationPreferences)
+				users.GET("/me/devices", s.getMyDevices)
+				users.POST("/me/devices", s.registerDevice)
+				users.DELETE("/me/devices/:deviceId", s.deleteDevice)
+				users.GET("/me/extension-tokens", s.getMyExtensionTokens)
+				users.DELETE("/me/extension-tokens/:tokenId", activeSession, s.revokeExtensionToken)
+				users.GET("/me/api-keys", activeSession, s.getMyAPIKeys)
+				users.POST("/me/api-keys", activeSession, s.createAPIKey)
+				users.DELETE("/me/api-keys/:keyId", activeSession, s.revokeAPIKey)
+				users.POST("/me/integrations/link-code", s.createIntegrationLinkCode)
+				users.GET("/me/integrations/links", s.getMyIntegrationLinks)
+				users.DELETE("/me/integrations/links/:linkId", s.deleteIntegrationLink)
+				users.GET("/me/git-mirror", s.getGitMirror)
+				users.PUT("/me/git-mirror", activeSession, s.saveGitMirror)
+				users.DELETE("/me/git-mirror", s.deleteGitMirror)
+				users.POST("/me/git-mirror/sync", s.syncGitMirror)
+				users.GET("/:id", s.getUser)
+				users.PUT("/:id", activeSession, s.updateUser)
+				users.DELETE("/:id", activeSession, s.deleteUser)
+				users.POST("/:id/follow", s.followUser)
+				users.DELETE("/:id/follow", s.unfollowUser)
+				users.POST("/:id/block", s.blockUser)
+				users.DELETE("/:id/block", s.unblockUser)
+			}
+
+			// Premium subscription through Stripe Checkout
+			billingRoutes := protected.Group("/billing")
+			{
+				billingRoutes.POST("/checkout", s.createCheckoutSession)
+				billingRoutes.GET("/subscription", s.getMySubscription)
+			}
+
+			// Notification routes
+			notifications := protected.Group("/notifications")
+			{
+				notifications.GET("/", s.getNotifications)
+				notifications.POST("/read-all", s.markAllNotificationsRead)
+				notifications.POST("/:id/read", s.markNotificationRead)
+			}
+
+			// Snippet routes
+			snippets := protected.Group("/snippets")
+			{
+				snippets.GET("/:id/history", s.getSnippetHistory)
+				snippets.POST("/:id/restore/:versionNumber", s.restoreSnippetVersion)
+			}
+
+			// Collections the user files snippets in
+			collections := protected.Group("/collections")
+			{
+				collections.GET("/", s.getMyCollections)
+				collections.POST("/", s.createCollection)
+				collections.PUT("/:id", s.renameCollection)
+				collections.GET("/:id/tree", s.getCollectionTree)
+				collections.POST("/:id/move", s.moveCollection)
+				collections.DELETE("/:id", s.deleteCollection)
+				collections.GET("/:id/members", s.getCollectionMembers)
+				collections.PUT("/:id/members/:userId", s.setCollectionMember)
+				collections.DELETE("/:id/members/:userId", s.removeCollectionMember)
+			}
+
+			// Webhook subscriptions for the user's own domain events
+			webhooks := protected.Group("/webhooks")
+			{
+				webhooks.GET("/", s.getMyWebhooks)
+				webhooks.POST("/", activeSession, s.createWebhook)
+				webhooks.DELETE("/:id", s.deleteWebhook)
+				webhooks.GET("/:id/deliveries", s.getWebhookDeliveries)
+			}
+
+			// Admin-only routes
+			admin := protected.Group("/admin")
+			admin.Use(activeSession, middleware.DefaultOrgOnly, middleware.AdminOnly(s.store))
+			{
+				// Organizations (tenants)
+				admin.GET("/organizations", s.listOrganizations)
+				admin.POST("/organizations", s.createOrganization)
+
+				// User management
+				admin.GET("/users", s.listAdminUsers)
+
+				// Role management
+				admin.GET("/users/:userId/roles", s.getUserRoles)
+				admin.POST("/users/:userId/roles", s.assignUserRole)
+				admin.DELETE("/users/:userId/roles/:roleName", s.revokeUserRole)
+
+				// Announcements
+				admin.GET("/announcements", s.listAllAnnouncements)
+				admin.POST("/announcements", s.createAnnouncement)
+				admin.PUT("/announcements/:id", s.updateAnnouncement)
+				admin.DELETE("/announcements/:id", s.deleteAnnouncement)
+
+				// Operational statistics
+				admin.GET("/stats", s.getAdminStats)
+				admin.GET("/stats/indexes", s.getIndexStats)
+
+				// Audit log (role changes, admin actions, forced logouts, account deletions)
+				admin.GET("/audit-log", s.listAuditLog)
+
+				// Scheduled backup status
+				admin.GET("/backups", s.getBackupStatus)
+
+				// Rebuild the external search index
+				admin.POST("/search/reindex", s.reindexSearch)
+
+				// Reload rate limits, CORS origins, log level and retention windows
+				admin.POST("/config/reload", s.reloadConfig)
+
+				// Broadcast email to all or filtered users
+				admin.GET("/broadcasts", s.listBroadcasts)
+				admin.POST("/broadcasts", s.createBroadcast)
+				admin.GET("/broadcasts/:id", s.getBroadcast)
+				admin.GET("/broadcasts/:id/recipients", s.getBroadcastRecipients)
+			}
+		}
+	}
+}
 
-// GetCurrentUser returns the currently authenticated user
+// getCurrentUser returns the currently authenticated user
 // @Summary Get current user profile
 // @Description Get the profile of the authenticated user
 // @Tags users
 // @Produce json
 // @Success 200 {object} models.User
-// @Failure 401 {object} map[string]string
+// @Failure 401 {object} ErrorResponse
 // @Security BearerAuth
 // @Router /users/profile [get]
-func GetCurrentUser(c *gin.Context) {
+func (s *Server) getCurrentUser(c *gin.Context) {
 	userID, exists := auth.GetUserIDFromContext(c)
 	if !exists {
-		c.JSON(401, gin.H{"error": "Unauthorized"})
+		respondError(c, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 	c.Params = []gin.Param{{Key: "id", Value: userID}}
-	getUser(c)
+	s.getUser(c)
 }
 
-// UpdateCurrentUser updates the currently authenticated user
+// updateCurrentUser updates the currently authenticated user
 // @Summary Update current user profile
 // @Description Update the profile of the authenticated user
 // @Tags users
@@ -76,38 +311,48 @@ func GetCurrentUser(c *gin.Context) {
 // @Produce json
 // @Param user body models.UpdateUserRequest true "Update data"
 // @Success 200 {object} models.User
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
 // @Security BearerAuth
 // @Router /users/profile [put]
-func UpdateCurrentUser(c *gin.Context) {
+func (s *Server) updateCurrentUser(c *gin.Context) {
 	userID, exists := auth.GetUserIDFromContext(c)
 	if !exists {
-		c.JSON(401, gin.H{"error": "Unauthorized"})
+		respondError(c, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 	c.Params = []gin.Param{{Key: "id", Value: userID}}
-	updateUser(c)
+	s.updateUser(c)
 }
 
-// GetCurrentUserSnippets returns snippets for the currently authenticated user
+// getCurrentUserSnippets returns snippets for the currently authenticated user
 // @Summary Get current user's snippets
-// @Description Get all snippets belonging to the authenticated user
+// @Description Get all snippets belonging to the authenticated user and those shared with them, each with its ownership (owner, viewer or editor). Favorites and collection filters only keep your own. With search, each item also has its rank and a highlight of the label and content with matches in <mark> tags (not HTML-escaped), best matches first unless sort is given.
 // @Tags snippets
 // @Produce json
 // @Param tag query string false "Filter by tag"
-// @Param search query string false "Search in label"
+// @Param search query string false "Full-text search in label and description"
+// @Param shortcut query string false "Exact shortcut"
+// @Param collection query int false "Only snippets in this collection"
+// @Param favorites query bool false "Only starred snippets"
+// @Param language query string false "Only snippets in this language, such as go"
+// @Param include query string false "archived to also list archived snippets"
 // @Param limit query int false "Limit results (max 100)"
+// @Param offset query int false "Offset for pagination"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Param sort query string false "Sort field (default createdAt, or rank when searching); pinned snippets come first unless searching" Enums(createdAt, updatedAt, label, shortcut)
+// @Param order query string false "Sort order (default desc for dates, asc for label and shortcut)" Enums(asc, desc)
 // @Success 200 {object} map[string]interface{}
-// @Failure 401 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
 // @Security BearerAuth
 // @Router /snippets [get]
-func GetCurrentUserSnippets(c *gin.Context) {
+func (s *Server) getCurrentUserSnippets(c *gin.Context) {
 	userID, exists := auth.GetUserIDFromContext(c)
 	if !exists {
-		c.JSON(401, gin.H{"error": "Unauthorized"})
+		respondError(c, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	getUserSnippets(c, userID)
+	s.getUserSnippets(c, userID)
 }
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"github.com/jheysaaz/snippy-backend/app/search"
+)
+
+// searchSnippets searches the authenticated user's snippets with the configured backend
+// @Summary Search snippets
+// @Description Ranked search of your snippets with tag facets. Uses Meilisearch or Elasticsearch when configured (typo tolerant, searches content too), otherwise Postgres full-text search on labels.
+// @Tags snippets
+// @Produce json
+// @Param q query string true "Search text"
+// @Param tag query string false "Only snippets with this tag"
+// @Param fuzzy query bool false "With Postgres, retry by trigram similarity when nothing matches, so typos still match"
+// @Param limit query int false "Results per page (default 20, max 100)"
+// @Param offset query int false "Results to skip"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /snippets/search [get]
+func (s *Server) searchSnippets(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		respondError(c, http.StatusBadRequest, "q is required")
+		return
+	}
+	fuzzy := false
+	if raw := c.Query("fuzzy"); raw != "" {
+		var err error
+		if fuzzy, err = strconv.ParseBool(raw); err != nil {
+			respondError(c, http.StatusBadRequest, "fuzzy must be true or false")
+			return
+		}
+	}
+	limit, offset := parseLimitOffset(c, 20, 100)
+	query := models.SnippetSearch{UserID: userID, Text: q, Tag: c.Query("tag"), Limit: limit, Offset: offset, Fuzzy: fuzzy}
+	ctx := c.Request.Context()
+
+	result, engine, err := search.Run(ctx, s.store, query)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to search snippets")
+		return
+	}
+
+	body := pageInfo(len(result.Snippets), result.Total, offset)
+	body["items"] = result.Snippets
+	body["facets"] = result.Facets
+	body["engine"] = engine
+	body["fuzzy"] = result.Fuzzy
+	respondSuccess(c, http.StatusOK, body)
+}
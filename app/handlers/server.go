@@ -0,0 +1,35 @@
+// Package handlers provides the Server the HTTP handlers belong to.
+package handlers
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"github.com/jheysaaz/snippy-backend/app/realtime"
+)
+
+// Server holds the dependencies of the HTTP handlers, which are its methods. Tests build
+// one around a test database and a fixed clock instead of swapping globals.
+type Server struct {
+	db    *sql.DB
+	store *models.Store
+	now   func() time.Time
+	hub   *realtime.Hub
+}
+
+// NewServer creates a Server querying db, directly and through a models.Store. A nil
+// clock uses time.Now.
+func NewServer(db *sql.DB, now func() time.Time) *Server {
+	if now == nil {
+		now = time.Now
+	}
+	return &Server{db: db, store: models.NewStore(db), now: now}
+}
+
+// WithHub streams the snippet changes hub fans out on GET /snippets/events. hub must be
+// fed by a realtime.Listener; without one the stream is unavailable.
+func (s *Server) WithHub(hub *realtime.Hub) *Server {
+	s.hub = hub
+	return s
+}
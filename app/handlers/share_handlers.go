@@ -0,0 +1,138 @@
+// Package handlers provides snippet sharing endpoints.
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+// getSnippetShares lists the users one of the authenticated user's snippets is shared with
+// @Summary List snippet shares
+// @Description The users a snippet is shared with and their roles, in the order they were granted access (owner only)
+// @Tags snippets
+// @Produce json
+// @Param id path int true "Snippet ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /snippets/{id}/shares [get]
+func (s *Server) getSnippetShares(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid snippet ID")
+		return
+	}
+
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	shares, err := s.store.ListSnippetShares(c.Request.Context(), id, userID)
+	if respondSnippetWriteError(c, err, "Failed to fetch snippet shares") {
+		return
+	}
+
+	respondWithCount(c, shares, len(shares))
+}
+
+// shareSnippet shares one of the authenticated user's snippets with another user
+// @Summary Share snippet
+// @Description Grant another user of your organization access to a snippet: viewers can read it and editors can also change its label, content, tags and language. It shows up in their listings and sync with ownership set to the role. Sharing again changes the role (owner only).
+// @Tags snippets
+// @Accept json
+// @Produce json
+// @Param id path int true "Snippet ID"
+// @Param userId path string true "User ID to share with"
+// @Param request body models.ShareSnippetRequest true "Role to grant"
+// @Success 200 {object} models.SnippetShare
+// @Success 201 {object} models.SnippetShare
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "Snippet shared with too many users"
+// @Security BearerAuth
+// @Router /snippets/{id}/shares/{userId} [put]
+func (s *Server) shareSnippet(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid snippet ID")
+		return
+	}
+
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	shareWith := c.Param("userId")
+	if !isValidUUID(shareWith) {
+		respondError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	if shareWith == userID {
+		respondError(c, http.StatusBadRequest, "You cannot share a snippet with yourself")
+		return
+	}
+
+	var req models.ShareSnippetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	share, created, err := s.store.ShareSnippet(c.Request.Context(), id, userID, shareWith, req.Role)
+	if respondSnippetWriteError(c, err, "Failed to share snippet") {
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	respondSuccess(c, status, share)
+}
+
+// unshareSnippet revokes a user's access to a snippet
+// @Summary Revoke snippet share
+// @Description Stop sharing a snippet with a user; their devices drop it on their next sync. Owners can revoke any share, and users can leave a snippet shared with them by revoking their own.
+// @Tags snippets
+// @Produce json
+// @Param id path int true "Snippet ID"
+// @Param userId path string true "User ID to revoke"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /snippets/{id}/shares/{userId} [delete]
+func (s *Server) unshareSnippet(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid snippet ID")
+		return
+	}
+
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	sharedWith := c.Param("userId")
+	if !isValidUUID(sharedWith) {
+		respondError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	err = s.store.UnshareSnippet(c.Request.Context(), id, userID, sharedWith)
+	if respondSnippetWriteError(c, err, "Failed to revoke snippet share") {
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, gin.H{"message": "Snippet share revoked"})
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/auth"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+func TestSnippetShares(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	const otherUserID = "223e4567-e89b-12d3-a456-426614174000"
+	if _, err := testDB.Exec(`
+		INSERT INTO users (id, username, email, password_hash, full_name, avatar_url)
+		VALUES ($1, 'otheruser', 'other@example.com', 'dummy-hash', '', '')
+	`, otherUserID); err != nil {
+		t.Fatalf("Failed to create other user: %v", err)
+	}
+	insert := func(shortcut, userID string) int64 {
+		var id int64
+		err := testDB.QueryRow(`
+			INSERT INTO snippets (label, shortcut, content, user_id) VALUES ($1, $1, 'code', $2)
+			RETURNING id
+		`, shortcut, userID).Scan(&id)
+		if err != nil {
+			t.Fatalf("Failed to insert test snippet: %v", err)
+		}
+		return id
+	}
+	mine, theirs := insert("mine", testUserID), insert("theirs", otherUserID)
+	before := time.Now().Add(-time.Minute)
+
+	s := NewServer(testDB, nil)
+	router := gin.New()
+	router.Use(auth.Middleware())
+	router.GET("/api/v1/snippets", s.getCurrentUserSnippets)
+	router.GET("/api/v1/snippets/sync", s.syncSnippets)
+	router.PUT("/api/v1/snippets/:id", s.updateSnippet)
+	router.PUT("/api/v1/snippets/:id/shares/:userId", s.shareSnippet)
+	router.DELETE("/api/v1/snippets/:id/shares/:userId", s.unshareSnippet)
+	do := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		var encoded []byte
+		if body != nil {
+			encoded, _ = json.Marshal(body)
+		}
+		req, _ := http.NewRequestWithContext(context.Background(), method, path, bytes.NewReader(encoded))
+		req.Header.Set("Authorization", "Bearer "+generateTestJWT())
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	snippetPath := func(id int64) string {
+		return "/api/v1/snippets/" + strconv.FormatInt(id, 10)
+	}
+
+	// Sharing one of your snippets
+	viewer := models.ShareSnippetRequest{Role: models.ShareRoleViewer}
+	if w := do(http.MethodPut, snippetPath(mine)+"/shares/"+otherUserID, viewer); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 for a new share, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPut, snippetPath(mine)+"/shares/"+otherUserID, viewer); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 sharing again, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPut, snippetPath(mine)+"/shares/"+testUserID, viewer); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 sharing with yourself, got %d", w.Code)
+	}
+	if w := do(http.MethodPut, snippetPath(theirs)+"/shares/"+otherUserID, viewer); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 sharing someone else's snippet, got %d", w.Code)
+	}
+
+	// A snippet shared with you
+	if _, _, err := models.NewStore(testDB).ShareSnippet(context.Background(), theirs, otherUserID, testUserID, models.ShareRoleEditor); err != nil {
+		t.Fatalf("Failed to share test snippet: %v", err)
+	}
+	w := do(http.MethodGet, "/api/v1/snippets", nil)
+	var page struct {
+		Items []models.Snippet `json:"items"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	ownership := make(map[int64]string)
+	for _, snippet := range page.Items {
+		ownership[snippet.ID] = snippet.Ownership
+	}
+	if ownership[mine] != models.OwnershipOwner || ownership[theirs] != models.ShareRoleEditor {
+		t.Errorf("Expected ownership owner and editor, got %v", ownership)
+	}
+
+	content := "edited"
+	if w := do(http.MethodPut, snippetPath(theirs), models.UpdateSnippetRequest{Content: &content}); w.Code != http.StatusOK {
+		t.Errorf("Expected an editor to update the content, got %d: %s", w.Code, w.Body.String())
+	}
+	shortcut := "renamed"
+	if w := do(http.MethodPut, snippetPath(theirs), models.UpdateSnippetRequest{Shortcut: &shortcut}); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for an editor changing the shortcut, got %d", w.Code)
+	}
+
+	// Leaving it drops it from sync
+	if w := do(http.MethodDelete, snippetPath(theirs)+"/shares/"+testUserID, nil); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 leaving a share, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodDelete, snippetPath(theirs)+"/shares/"+testUserID, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 leaving it again, got %d", w.Code)
+	}
+	w = do(http.MethodGet, "/api/v1/snippets/sync?updated_since="+before.UTC().Format(time.RFC3339), nil)
+	var changes models.SnippetChanges
+	if err := json.Unmarshal(w.Body.Bytes(), &changes); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(changes.Deleted) != 1 || changes.Deleted[0].ID != theirs {
+		t.Errorf("Expected the unshared snippet to sync as deleted, got %+v", changes.Deleted)
+	}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/slack"
+)
+
+// maxSlackCommandBytes bounds slash command payloads; Slack sends a few hundred bytes
+const maxSlackCommandBytes = 64 << 10
+
+// slackCommand handles the /snippy slash command
+// @Summary Slack slash command
+// @Description Request URL for the /snippy Slack slash command, authenticated by Slack's request signature. `/snippy <shortcut>` posts the linked user's snippet into the channel; `/snippy link <code>` links the Slack user to the Snippy account that created the link code; `/snippy unlink` removes the link.
+// @Tags integrations
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Success 200 {object} slack.Response
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /slack/commands [post]
+func (s *Server) slackCommand(c *gin.Context) {
+	cfg := slack.LoadConfig()
+	if !cfg.Enabled() {
+		respondError(c, http.StatusServiceUnavailable, "Slack integration is not available")
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxSlackCommandBytes))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid payload")
+		return
+	}
+	cmd, err := slack.ParseCommand(payload, c.GetHeader("X-Slack-Request-Timestamp"),
+		c.GetHeader("X-Slack-Signature"), cfg.SigningSecret, s.now())
+	if errors.Is(err, slack.ErrInvalidSignature) {
+		respondError(c, http.StatusUnauthorized, "Invalid signature")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid payload")
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, slack.HandleCommand(c.Request.Context(), s.store, cmd))
+}
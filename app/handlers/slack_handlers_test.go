@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSlackCommandAuthentication(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Signatures are checked against the server's clock
+	clock := func() time.Time { return time.Unix(1700000000, 0) }
+	srv := NewServer(nil, clock)
+
+	serve := func(timestamp, signature string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/slack/commands", strings.NewReader("team_id=T1&user_id=U1&text=sig"))
+		c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		c.Request.Header.Set("X-Slack-Request-Timestamp", timestamp)
+		c.Request.Header.Set("X-Slack-Signature", signature)
+		srv.slackCommand(c)
+		return w
+	}
+	now := fmt.Sprint(clock().Unix())
+
+	t.Setenv("SLACK_SIGNING_SECRET", "")
+	if w := serve(now, "v0=00"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("without a signing secret: status %d, want 503", w.Code)
+	}
+
+	t.Setenv("SLACK_SIGNING_SECRET", "slack_secret")
+	if w := serve(now, "v0=00"); w.Code != http.StatusUnauthorized {
+		t.Errorf("bad signature: status %d, want 401", w.Code)
+	}
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+// getSnippetTags lists the authenticated user's tags with how many snippets carry each
+// @Summary List my tags
+// @Description List the distinct tags on your snippets with per-tag snippet counts, most used first, for building tag filters
+// @Tags snippets
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /snippets/tags [get]
+func (s *Server) getSnippetTags(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	tags, err := s.store.ListUserTags(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch tags")
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, gin.H{
+		"tags":  tags,
+		"count": len(tags),
+	})
+}
+
+// renameTag renames or merges one of the authenticated user's tags on all their snippets
+// @Summary Rename or merge a tag
+// @Description Replace a tag with another on all your snippets in one step. Snippets that already have the new tag keep a single copy, so renaming to an existing tag merges the two. Each changed snippet gets a history entry.
+// @Tags snippets
+// @Accept json
+// @Produce json
+// @Param request body models.RenameTagRequest true "Tag to rename and its new name"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /snippets/tags/rename [post]
+func (s *Server) renameTag(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	var req models.RenameTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	from, to := strings.TrimSpace(req.From), strings.TrimSpace(req.To)
+	if from == "" || to == "" {
+		respondError(c, http.StatusBadRequest, "from and to are required")
+		return
+	}
+	if from == to {
+		respondError(c, http.StatusBadRequest, "from and to must differ")
+		return
+	}
+
+	snippets, err := s.store.RenameTag(c.Request.Context(), userID, originSessionID(c), from, to)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to rename tag")
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, gin.H{
+		"from":    from,
+		"to":      to,
+		"updated": len(snippets),
+	})
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/auth"
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"github.com/lib/pq"
+)
+
+func TestGetSnippetTags(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	_, err := testDB.Exec(`
+		INSERT INTO snippets (label, shortcut, content, tags, user_id, is_deleted)
+		VALUES
+			('Python hello', 'py-hello', 'print("hello")', ARRAY['python', 'basics'], $1, false),
+			('Python loop', 'py-loop', 'for x in y: pass', ARRAY['python'], $1, false),
+			('Deleted', 'old', 'gone', ARRAY['python', 'legacy'], $1, true)
+	`, testUserID)
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %v", err)
+	}
+
+	router := gin.New()
+	router.GET("/api/v1/snippets/tags", auth.Middleware(), NewServer(testDB, nil).getSnippetTags)
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/api/v1/snippets/tags", nil)
+	req.Header.Set("Authorization", "Bearer "+generateTestJWT())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		Tags  []models.TagFacet `json:"tags"`
+		Count int               `json:"count"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	want := []models.TagFacet{{Tag: "python", Count: 2}, {Tag: "basics", Count: 1}}
+	if response.Count != len(want) || len(response.Tags) != len(want) {
+		t.Fatalf("Expected tags %v, got %v", want, response.Tags)
+	}
+	for i := range want {
+		if response.Tags[i] != want[i] {
+			t.Errorf("Expected tag %d to be %v, got %v", i, want[i], response.Tags[i])
+		}
+	}
+}
+
+func TestRenameTagValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "Missing to", body: `{"from": "js"}`},
+		{name: "Blank from", body: `{"from": "  ", "to": "javascript"}`},
+		{name: "Same tag", body: `{"from": "js", "to": " js "}`},
+		{name: "Tag too long", body: `{"from": "js", "to": "` + strings.Repeat("x", 51) + `"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Set("user_id", "0b3c9a1e-6a5f-4c1b-9d2e-3f4a5b6c7d8e")
+			c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/snippets/tags/rename", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			NewServer(nil, nil).renameTag(c)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400 (body %s)", w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRenameTag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	_, err := testDB.Exec(`
+		INSERT INTO snippets (label, shortcut, content, tags, user_id)
+		VALUES
+			('Log', 'log', 'console.log()', ARRAY['js', 'debug'], $1),
+			('Both', 'both', 'x', ARRAY['javascript', 'web', 'js'], $1),
+			('Other', 'other', 'y', ARRAY['python'], $1)
+	`, testUserID)
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %v", err)
+	}
+
+	router := gin.New()
+	router.POST("/api/v1/snippets/tags/rename", auth.Middleware(), NewServer(testDB, nil).renameTag)
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, "/api/v1/snippets/tags/rename", strings.NewReader(`{"from": "js", "to": "javascript"}`))
+	req.Header.Set("Authorization", "Bearer "+generateTestJWT())
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response["updated"] != float64(2) {
+		t.Errorf("Expected 2 snippets updated, got %v", response["updated"])
+	}
+
+	for shortcut, want := range map[string][]string{
+		"log":   {"javascript", "debug"},
+		"both":  {"javascript", "web"},
+		"other": {"python"},
+	} {
+		var tags pq.StringArray
+		if err := testDB.QueryRow(`SELECT tags FROM snippets WHERE shortcut = $1`, shortcut).Scan(&tags); err != nil {
+			t.Fatalf("Failed to read tags of %s: %v", shortcut, err)
+		}
+		if !reflect.DeepEqual([]string(tags), want) {
+			t.Errorf("Expected %s to have tags %v, got %v", shortcut, want, tags)
+		}
+	}
+
+	var versions int
+	if err := testDB.QueryRow(`
+		SELECT COUNT(*) FROM snippet_history h JOIN snippets s ON s.id = h.snippet_id
+		WHERE s.shortcut = 'log' AND h.change_type = 'edit'
+	`).Scan(&versions); err != nil {
+		t.Fatalf("Failed to count history: %v", err)
+	}
+	if versions != 1 {
+		t.Errorf("Expected one edit history entry, got %d", versions)
+	}
+}
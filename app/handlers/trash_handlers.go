@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/database"
+)
+
+// getSnippetTrash lists the authenticated user's soft-deleted snippets
+// @Summary List deleted snippets
+// @Description Your soft-deleted snippets, most recently deleted first, with when each was deleted and when retention purges it for good. Restore one with POST /snippets/{id}/undelete before then.
+// @Tags snippets
+// @Produce json
+// @Param limit query int false "Results per page (default 20, max 100)"
+// @Param offset query int false "Results to skip"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /snippets/trash [get]
+func (s *Server) getSnippetTrash(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	limit, offset := parseLimitOffset(c, 20, 100)
+	retentionDays := database.LoadRetentionPolicy().SoftDeletedSnippetDays
+	trashed, total, err := s.store.ListTrashedSnippetsPage(c.Request.Context(), userID, retentionDays, limit, offset)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch deleted snippets")
+		return
+	}
+
+	respondPage(c, trashed, len(trashed), total, offset)
+}
+
+// undeleteSnippet restores a soft-deleted snippet from the trash
+// @Summary Restore a deleted snippet
+// @Description Bring a soft-deleted snippet back, as it was when deleted but unpinned. It counts towards your plan's quota again (owner only).
+// @Tags snippets
+// @Produce json
+// @Param id path int true "Snippet ID"
+// @Success 200 {object} models.Snippet
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "Another of your snippets has taken its shortcut"
+// @Security BearerAuth
+// @Router /snippets/{id}/undelete [post]
+func (s *Server) undeleteSnippet(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid snippet ID")
+		return
+	}
+
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	snippet, err := s.store.UndeleteSnippet(c.Request.Context(), id, userID, originSessionID(c))
+	if respondSnippetWriteError(c, err, "Failed to restore snippet") {
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, snippet)
+}
+
+// purgeSnippet permanently deletes a snippet from the trash
+// @Summary Purge a deleted snippet
+// @Description Permanently delete a soft-deleted snippet and its history now, instead of when retention purges it. This can't be undone (owner only).
+// @Tags snippets
+// @Produce json
+// @Param id path int true "Snippet ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /snippets/{id}/purge [delete]
+func (s *Server) purgeSnippet(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid snippet ID")
+		return
+	}
+
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	err = s.store.PurgeSnippet(c.Request.Context(), id, userID)
+	if respondSnippetWriteError(c, err, "Failed to purge snippet") {
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, gin.H{"message": "Snippet permanently deleted"})
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/auth"
+	"github.com/jheysaaz/snippy-backend/app/database"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+func TestSnippetTrash(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	var deletedID int64
+	err := testDB.QueryRow(`
+		INSERT INTO snippets (label, shortcut, content, user_id, is_deleted, deleted_at)
+		VALUES ('Oops', 'oops', 'code', $1, true, NOW() - INTERVAL '1 day')
+		RETURNING id
+	`, testUserID).Scan(&deletedID)
+	if err != nil {
+		t.Fatalf("Failed to insert test snippet: %v", err)
+	}
+	var keptID int64
+	err = testDB.QueryRow(`
+		INSERT INTO snippets (label, shortcut, content, user_id) VALUES ('Kept', 'kept', 'code', $1)
+		RETURNING id
+	`, testUserID).Scan(&keptID)
+	if err != nil {
+		t.Fatalf("Failed to insert test snippet: %v", err)
+	}
+
+	s := NewServer(testDB, nil)
+	router := gin.New()
+	router.Use(auth.Middleware())
+	router.GET("/api/v1/snippets/trash", s.getSnippetTrash)
+	router.POST("/api/v1/snippets/:id/undelete", s.undeleteSnippet)
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequestWithContext(context.Background(), method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+generateTestJWT())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	trash := func() []models.TrashedSnippet {
+		w := do(http.MethodGet, "/api/v1/snippets/trash")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var response struct {
+			Items []models.TrashedSnippet `json:"items"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		return response.Items
+	}
+
+	items := trash()
+	if len(items) != 1 || items[0].ID != deletedID {
+		t.Fatalf("Expected only the deleted snippet in the trash, got %+v", items)
+	}
+	retention := time.Duration(database.LoadRetentionPolicy().SoftDeletedSnippetDays) * 24 * time.Hour
+	if got := items[0].PurgeAt.Sub(items[0].DeletedAt); got != retention {
+		t.Errorf("Expected the snippet to be purged %v after deletion, got %v", retention, got)
+	}
+
+	if w := do(http.MethodPost, fmt.Sprintf("/api/v1/snippets/%d/undelete", keptID)); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 undeleting a snippet not in the trash, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPost, fmt.Sprintf("/api/v1/snippets/%d/undelete", deletedID)); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 undeleting, got %d: %s", w.Code, w.Body.String())
+	}
+	if items := trash(); len(items) != 0 {
+		t.Errorf("Expected the trash to be empty, got %+v", items)
+	}
+
+	var changeType string
+	if err := testDB.QueryRow(`
+		SELECT change_type FROM snippet_history WHERE snippet_id = $1 ORDER BY version_number DESC LIMIT 1
+	`, deletedID).Scan(&changeType); err != nil {
+		t.Fatalf("Failed to read history: %v", err)
+	}
+	if changeType != "restore" {
+		t.Errorf("Expected a restore history entry, got %s", changeType)
+	}
+}
+
+func TestPurgeSnippet(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	var deletedID, keptID int64
+	err := testDB.QueryRow(`
+		INSERT INTO snippets (label, shortcut, content, user_id, is_deleted, deleted_at)
+		VALUES ('Secret', 'secret', 'hunter2', $1, true, NOW())
+		RETURNING id
+	`, testUserID).Scan(&deletedID)
+	if err != nil {
+		t.Fatalf("Failed to insert test snippet: %v", err)
+	}
+	err = testDB.QueryRow(`
+		INSERT INTO snippets (label, shortcut, content, user_id) VALUES ('Kept', 'kept', 'code', $1)
+		RETURNING id
+	`, testUserID).Scan(&keptID)
+	if err != nil {
+		t.Fatalf("Failed to insert test snippet: %v", err)
+	}
+	_, err = testDB.Exec(`
+		INSERT INTO snippet_history (snippet_id, version_number, label, shortcut, content, changed_by, change_type)
+		VALUES ($1, 1, 'Secret', 'secret', 'hunter2', $2, 'create')
+	`, deletedID, testUserID)
+	if err != nil {
+		t.Fatalf("Failed to insert history: %v", err)
+	}
+
+	s := NewServer(testDB, nil)
+	router := gin.New()
+	router.Use(auth.Middleware())
+	router.DELETE("/api/v1/snippets/:id/purge", s.purgeSnippet)
+
+	purge := func(id int64) *httptest.ResponseRecorder {
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodDelete, fmt.Sprintf("/api/v1/snippets/%d/purge", id), nil)
+		req.Header.Set("Authorization", "Bearer "+generateTestJWT())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := purge(keptID); w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 purging a snippet not in the trash, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := purge(deletedID); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 purging, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := purge(deletedID); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 purging again, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var snippets, history int
+	if err := testDB.QueryRow(`SELECT COUNT(*) FROM snippets WHERE id = $1`, deletedID).Scan(&snippets); err != nil {
+		t.Fatalf("Failed to count snippets: %v", err)
+	}
+	if err := testDB.QueryRow(`SELECT COUNT(*) FROM snippet_history WHERE snippet_id = $1`, deletedID).Scan(&history); err != nil {
+		t.Fatalf("Failed to count history: %v", err)
+	}
+	if snippets != 0 || history != 0 {
+		t.Errorf("Expected the snippet and its history gone, got %d snippets and %d history rows", snippets, history)
+	}
+}
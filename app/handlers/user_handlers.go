@@ -3,55 +3,50 @@ package handlers
 
 import (
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jheysaaz/snippy-backend/app/auth"
-	"github.com/jheysaaz/snippy-backend/app/database"
+	"github.com/jheysaaz/snippy-backend/app/middleware"
 	"github.com/jheysaaz/snippy-backend/app/models"
+	"github.com/jheysaaz/snippy-backend/app/snippetquery"
 
 	"github.com/gin-gonic/gin"
 )
 
-// getUsers retrieves all users with pagination
+// getUsers retrieves users with pagination, search, date filters and sorting
 // @Summary List all users
-// @Description Get all users with pagination
+// @Description Get users with cursor or offset pagination, username/email search and created-date filters
 // @Tags users
 // @Produce json
 // @Param limit query int false "Limit results (default 50, max 100)"
-// @Param offset query int false "Offset for pagination"
+// @Param offset query int false "Offset for pagination (ignored when cursor is set)"
+// @Param cursor query string false "Opaque cursor from a previous response's nextCursor"
+// @Param search query string false "Case-insensitive match on username or email"
+// @Param created_after query string false "RFC3339 timestamp, only users created after it"
+// @Param created_before query string false "RFC3339 timestamp, only users created before it"
+// @Param sort query string false "newest (default), oldest or username"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
 // @Security BearerAuth
 // @Router /users [get]
-func getUsers(c *gin.Context) {
-	// Parse pagination params with defaults
-	limit := 50
-	offset := 0
-	if limitStr := c.Query("limit"); limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-			limit = l
-			if limit > 100 {
-				limit = 100
-			}
-		}
-	}
-	if offsetStr := c.Query("offset"); offsetStr != "" {
-		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
-			offset = o
-		}
+func (s *Server) getUsers(c *gin.Context) {
+	params, err := parseUserListParams(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	query := `
-		SELECT id, username, email, full_name, avatar_url, created_at, updated_at
-		FROM users
-		WHERE is_deleted = false
-		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2
-	`
+	params.OrgID = middleware.OrgID(c)
+	query, args := buildUserListQuery(params)
 
-	rows, err := database.DB.QueryContext(c.Request.Context(), query, limit, offset)
+	rows, err := s.db.QueryContext(c.Request.Context(), query, args...)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "Failed to fetch users")
 		return
@@ -77,7 +72,209 @@ func getUsers(c *gin.Context) {
 		return
 	}
 
-	respondWithCount(c, users, len(users))
+	// One extra row was fetched to detect whether another page exists
+	var nextCursor *string
+	if len(users) > params.Limit {
+		users = users[:params.Limit]
+		cursor := encodeUserCursor(users[len(users)-1])
+		nextCursor = &cursor
+	}
+
+	respondSuccess(c, http.StatusOK, gin.H{
+		"items":      users,
+		"count":      len(users),
+		"hasMore":    nextCursor != nil,
+		"nextCursor": nextCursor,
+	})
+}
+
+// User list sort orders
+const (
+	userSortNewest   = "newest"
+	userSortOldest   = "oldest"
+	userSortUsername = "username"
+)
+
+// userListParams holds the parsed query parameters for the users list
+type userListParams struct {
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Cursor        *userCursor
+	OrgID         string
+	Search        string
+	Sort          string
+	Limit         int
+	Offset        int
+}
+
+// userCursor marks the last row of a page for keyset pagination
+type userCursor struct {
+	CreatedAt time.Time `json:"t"`
+	Username  string    `json:"u"`
+	ID        string    `json:"id"`
+}
+
+// encodeUserCursor returns an opaque cursor pointing after the given user
+func encodeUserCursor(u models.User) string {
+	raw, err := json.Marshal(userCursor{CreatedAt: u.CreatedAt, Username: u.Username, ID: u.ID})
+	if err != nil {
+		// Marshalling a struct of strings and a time cannot fail
+		log.Printf("error encoding user cursor: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeUserCursor parses a cursor produced by encodeUserCursor
+func decodeUserCursor(s string) (*userCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var cur userCursor
+	if err := json.Unmarshal(raw, &cur); err != nil || cur.ID == "" {
+		return nil, errors.New("invalid cursor")
+	}
+	return &cur, nil
+}
+
+// parseUserListParams reads pagination, search, filter and sort parameters
+func parseUserListParams(c *gin.Context) (userListParams, error) {
+	params := userListParams{Limit: 50, Sort: userSortNewest}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			params.Limit = l
+			if params.Limit > 100 {
+				params.Limit = 100
+			}
+		}
+	}
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			params.Offset = o
+		}
+	}
+
+	switch sort := c.Query("sort"); sort {
+	case "":
+	case userSortNewest, userSortOldest, userSortUsername:
+		params.Sort = sort
+	default:
+		return params, errors.New("sort must be one of newest, oldest, username")
+	}
+
+	params.Search = strings.TrimSpace(c.Query("search"))
+
+	var err error
+	if params.CreatedAfter, err = parseTimeQuery(c, "created_after"); err != nil {
+		return params, err
+	}
+	if params.CreatedBefore, err = parseTimeQuery(c, "created_before"); err != nil {
+		return params, err
+	}
+
+	if cursorStr := c.Query("cursor"); cursorStr != "" {
+		cursor, err := decodeUserCursor(cursorStr)
+		if err != nil {
+			return params, err
+		}
+		params.Cursor = cursor
+		params.Offset = 0
+	}
+
+	return params, nil
+}
+
+// parseTimeQuery parses an optional RFC3339 query parameter
+func parseTimeQuery(c *gin.Context, name string) (*time.Time, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, errors.New(name + " must be RFC3339 format")
+	}
+	return &t, nil
+}
+
+// escapeLike escapes LIKE wildcards so user input matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// buildUserListQuery builds the parameterized users list query.
+// It fetches Limit+1 rows so the caller can tell whether another page exists.
+func buildUserListQuery(p userListParams) (string, []interface{}) {
+	query := `
+		SELECT id, username, email, full_name, avatar_url, created_at, updated_at
+		FROM users
+		WHERE is_deleted = false
+	`
+	args := []interface{}{}
+	argPos := 1
+
+	if p.Search != "" {
+		query += " AND (username ILIKE $" + strconv.Itoa(argPos) + " OR email ILIKE $" + strconv.Itoa(argPos) + ")"
+		args = append(args, "%"+escapeLike(p.Search)+"%")
+		argPos++
+	}
+
+	if p.CreatedAfter != nil {
+		query += " AND created_at > $" + strconv.Itoa(argPos)
+		args = append(args, *p.CreatedAfter)
+		argPos++
+	}
+
+	if p.CreatedBefore != nil {
+		query += " AND created_at < $" + strconv.Itoa(argPos)
+		args = append(args, *p.CreatedBefore)
+		argPos++
+	}
+
+	if p.OrgID != "" {
+		query += " AND org_id = $" + strconv.Itoa(argPos)
+		args = append(args, p.OrgID)
+		argPos++
+	}
+
+	// Keyset conditions match the ORDER BY so pages never skip or repeat rows
+	if p.Cursor != nil {
+		switch p.Sort {
+		case userSortOldest:
+			query += " AND (created_at, id) > ($" + strconv.Itoa(argPos) + ", $" + strconv.Itoa(argPos+1) + ")"
+			args = append(args, p.Cursor.CreatedAt, p.Cursor.ID)
+			argPos += 2
+		case userSortUsername:
+			query += " AND username > $" + strconv.Itoa(argPos)
+			args = append(args, p.Cursor.Username)
+			argPos++
+		default:
+			query += " AND (created_at, id) < ($" + strconv.Itoa(argPos) + ", $" + strconv.Itoa(argPos+1) + ")"
+			args = append(args, p.Cursor.CreatedAt, p.Cursor.ID)
+			argPos += 2
+		}
+	}
+
+	switch p.Sort {
+	case userSortOldest:
+		query += " ORDER BY created_at ASC, id ASC"
+	case userSortUsername:
+		query += " ORDER BY username ASC"
+	default:
+		query += " ORDER BY created_at DESC, id DESC"
+	}
+
+	query += " LIMIT $" + strconv.Itoa(argPos)
+	args = append(args, p.Limit+1)
+	argPos++
+
+	if p.Offset > 0 {
+		query += " OFFSET $" + strconv.Itoa(argPos)
+		args = append(args, p.Offset)
+	}
+
+	return query, args
 }
 
 // getUser retrieves a single user by ID
@@ -87,20 +284,21 @@ func getUsers(c *gin.Context) {
 // @Produce json
 // @Param id path string true "User ID"
 // @Success 200 {object} models.User
-// @Failure 404 {object} map[string]string
+// @Failure 404 {object} ErrorResponse
 // @Security BearerAuth
 // @Router /users/{id} [get]
-func getUser(c *gin.Context) {
-	id := c.Param("id")
-
-	query := `
-		SELECT id, username, email, full_name, avatar_url, created_at, updated_at
-		FROM users
-		WHERE id = $1 AND is_deleted = false
-	`
+func (s *Server) getUser(c *gin.Context) {
+	inOrg, err := s.store.UserInOrg(c.Request.Context(), c.Param("id"), middleware.OrgID(c))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch user")
+		return
+	}
+	if !inOrg {
+		respondError(c, http.StatusNotFound, "User not found")
+		return
+	}
 
-	row := database.DB.QueryRowContext(c.Request.Context(), query, id)
-	user, err := models.ScanUser(row)
+	user, err := s.store.GetCachedUser(c.Request.Context(), c.Param("id"))
 	if handleScanError(c, err, "User not found") {
 		return
 	}
@@ -116,13 +314,25 @@ func getUser(c *gin.Context) {
 // @Produce json
 // @Param user body models.CreateUserRequest true "User data"
 // @Success 201 {object} models.User
-// @Failure 400 {object} map[string]string
-// @Failure 409 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Router /auth/register [post]
-func createUser(c *gin.Context) {
+func (s *Server) createUser(c *gin.Context) {
 	var req models.CreateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Former usernames stay reserved for their previous owner during the grace period
+	orgID := middleware.OrgID(c)
+	reserved, err := models.IsUsernameReserved(c.Request.Context(), s.db, orgID, req.Username, "")
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to create user")
+		return
+	}
+	if reserved {
+		respondError(c, http.StatusConflict, "Username was recently used by another account")
 		return
 	}
 
@@ -134,12 +344,12 @@ func createUser(c *gin.Context) {
 	}
 
 	query := `
-		INSERT INTO users (username, email, password_hash, full_name, avatar_url)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO users (username, email, password_hash, full_name, avatar_url, org_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING id, username, email, full_name, avatar_url, created_at, updated_at
 	`
 
-	row := database.DB.QueryRowContext(
+	row := s.db.QueryRowContext(
 		c.Request.Context(),
 		query,
 		req.Username,
@@ -147,6 +357,7 @@ func createUser(c *gin.Context) {
 		passwordHash,
 		req.FullName,
 		req.AvatarURL,
+		orgID,
 	)
 
 	user, err := models.ScanUser(row)
@@ -172,12 +383,13 @@ func createUser(c *gin.Context) {
 // @Param id path string true "User ID"
 // @Param user body models.UpdateUserRequest true "Update data"
 // @Success 200 {object} models.User
-// @Failure 400 {object} map[string]string
-// @Failure 403 {object} map[string]string
-// @Failure 404 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Security BearerAuth
 // @Router /users/{id} [put]
-func updateUser(c *gin.Context) {
+func (s *Server) updateUser(c *gin.Context) {
 	id := c.Param("id")
 
 	// Ensure the authenticated user is updating their own profile
@@ -187,7 +399,7 @@ func updateUser(c *gin.Context) {
 
 	var req models.UpdateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -220,7 +432,40 @@ func updateUser(c *gin.Context) {
 		RETURNING id, username, email, full_name, avatar_url, created_at, updated_at
 	`
 
-	row := database.DB.QueryRowContext(c.Request.Context(), query, usernameVal, emailVal, passwordHashVal, fullNameVal, avatarURLVal, id)
+	ctx := c.Request.Context()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to update user")
+		return
+	}
+	defer rollbackTx(tx)
+
+	// Lock the row so the old username recorded in history is the one being replaced
+	var oldUsername string
+	err = tx.QueryRowContext(ctx, `SELECT username FROM users WHERE id = $1 AND is_deleted = false FOR UPDATE`, id).Scan(&oldUsername)
+	if err == sql.ErrNoRows {
+		respondError(c, http.StatusNotFound, "User not found")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to update user")
+		return
+	}
+
+	usernameChanged := req.Username != nil && *req.Username != oldUsername
+	if usernameChanged {
+		reserved, reserveErr := models.IsUsernameReserved(ctx, tx, middleware.OrgID(c), *req.Username, id)
+		if reserveErr != nil {
+			respondError(c, http.StatusInternalServerError, "Failed to update user")
+			return
+		}
+		if reserved {
+			respondError(c, http.StatusConflict, "Username was recently used by another account")
+			return
+		}
+	}
+
+	row := tx.QueryRowContext(ctx, query, usernameVal, emailVal, passwordHashVal, fullNameVal, avatarURLVal, id)
 	user, err := models.ScanUser(row)
 	if err == sql.ErrNoRows {
 		respondError(c, http.StatusNotFound, "User not found")
@@ -234,6 +479,19 @@ func updateUser(c *gin.Context) {
 		return
 	}
 
+	if usernameChanged {
+		if err := models.RecordUsernameChange(ctx, tx, id, oldUsername); err != nil {
+			respondError(c, http.StatusInternalServerError, "Failed to update user")
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to update user")
+		return
+	}
+	models.InvalidateUser(ctx, id)
+
 	respondSuccess(c, http.StatusOK, user)
 }
 
@@ -244,11 +502,11 @@ func updateUser(c *gin.Context) {
 // @Produce json
 // @Param id path string true "User ID"
 // @Success 200 {object} map[string]string
-// @Failure 403 {object} map[string]string
-// @Failure 404 {object} map[string]string
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
 // @Security BearerAuth
 // @Router /users/{id} [delete]
-func deleteUser(c *gin.Context) {
+func (s *Server) deleteUser(c *gin.Context) {
 	id := c.Param("id")
 
 	// Get authenticated user ID
@@ -263,14 +521,21 @@ func deleteUser(c *gin.Context) {
 	}
 
 	// Revoke all refresh tokens for the user (logout from all devices)
-	if err := models.RevokeAllUserTokens(c.Request.Context(), id); err != nil {
+	if err := s.store.RevokeAllUserTokens(c.Request.Context(), id); err != nil {
 		log.Printf("Failed to revoke all tokens for user %s: %v", id, err)
 		// Don't return error, continue with user deletion
 	}
 
 	query := `UPDATE users SET is_deleted = true, deleted_at = NOW() WHERE id = $1 AND is_deleted = false`
 
-	result, err := database.DB.ExecContext(c.Request.Context(), query, id)
+	tx, err := s.db.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to delete user")
+		return
+	}
+	defer rollbackTx(tx)
+
+	result, err := tx.ExecContext(c.Request.Context(), query, id)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "Failed to delete user")
 		return
@@ -287,80 +552,61 @@ func deleteUser(c *gin.Context) {
 		return
 	}
 
-	respondSuccess(c, http.StatusOK, gin.H{"message": "User deleted successfully"})
-}
-
-// getUserSnippets retrieves all snippets for a specific user
-func getUserSnippets(c *gin.Context, userID string) {
-
-	// Optional query parameters for filtering
-	tag := c.Query("tag")
-	search := c.Query("search")
-	limitStr := c.Query("limit")
-
-	// Build query with optional filters
-	query := `
-		SELECT id, label, shortcut, content, tags, user_id, created_at, updated_at
-		FROM snippets
-		WHERE user_id = $1 AND is_deleted = false
-	`
-	args := []interface{}{userID}
-	argPos := 2
-
-	if tag != "" {
-		query += " AND $" + strconv.Itoa(argPos) + " = ANY(tags)"
-		args = append(args, tag)
-		argPos++
+	if err := models.EnqueueEvent(c.Request.Context(), tx, models.EventUserDeleted, models.AggregateUser, id, gin.H{"userId": id}); err != nil {
+		log.Printf("Failed to enqueue %s event for user %s: %v", models.EventUserDeleted, id, err)
+		respondError(c, http.StatusInternalServerError, "Failed to delete user")
+		return
 	}
 
-	if search != "" {
-		// Use full-text search index for better performance
-		query += " AND to_tsvector('english', coalesce(label, '')) @@ plainto_tsquery('english', $" + strconv.Itoa(argPos) + ")"
-		args = append(args, search)
-		argPos++
+	if err := models.RecordAudit(c.Request.Context(), tx, auditAction(c, models.AuditAction{
+		Action:       models.AuditAccountDeleted,
+		TargetType:   models.AuditTargetUser,
+		TargetID:     id,
+		TargetUserID: id,
+	})); err != nil {
+		log.Printf("Failed to record audit entry for deletion of user %s: %v", id, err)
+		respondError(c, http.StatusInternalServerError, "Failed to delete user")
+		return
 	}
 
-	query += " ORDER BY created_at DESC"
-
-	// Add limit if provided (max 100)
-	if limitStr != "" {
-		limit, err := strconv.Atoi(limitStr)
-		if err == nil && limit > 0 {
-			if limit > 100 {
-				limit = 100 // Cap at 100 for performance
-			}
-			query += " LIMIT $" + strconv.Itoa(argPos)
-			args = append(args, limit)
-		}
+	if err := tx.Commit(); err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to delete user")
+		return
 	}
+	models.InvalidateUser(c.Request.Context(), id)
+
+	respondSuccess(c, http.StatusOK, gin.H{"message": "User deleted successfully"})
+}
 
-	rows, err := database.DB.QueryContext(c.Request.Context(), query, args...)
+// getUserSnippets retrieves all snippets for a specific user
+func (s *Server) getUserSnippets(c *gin.Context, userID string) {
+	filter := snippetquery.FromQuery(c.Query)
+	sort, err := snippetquery.ParseSort(c.Query("sort"), c.Query("order"))
 	if err != nil {
-		respondError(c, http.StatusInternalServerError, "Failed to fetch user snippets")
+		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
-	defer func() {
-		if err := rows.Close(); err != nil {
-			log.Printf("error closing user snippets rows: %v", err)
-		}
-	}()
+	filter.Sort = sort
+	filter.IncludeShared = true
 
-	snippets := make([]models.Snippet, 0, 10)
-	for rows.Next() {
-		snippet, err := models.ScanSnippet(rows)
+	// Search hits carry their rank and highlighted matches
+	if filter.Search != "" {
+		hits, total, err := s.store.ListUserSnippetHitsPage(c.Request.Context(), userID, filter)
 		if err != nil {
-			respondError(c, http.StatusInternalServerError, "Failed to scan snippet")
+			respondError(c, http.StatusInternalServerError, "Failed to fetch user snippets")
 			return
 		}
-		snippets = append(snippets, *snippet)
+		respondPage(c, hits, len(hits), total, filter.Offset)
+		return
 	}
 
-	if err := rows.Err(); err != nil {
-		respondError(c, http.StatusInternalServerError, "Error iterating snippets")
+	snippets, total, err := s.store.ListUserSnippetsPage(c.Request.Context(), userID, filter)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch user snippets")
 		return
 	}
 
-	respondWithCount(c, snippets, len(snippets))
+	respondPage(c, snippets, len(snippets), total, filter.Offset)
 }
 
 // checkAvailability verifies if a username or email is available for registration
@@ -371,9 +617,9 @@ func getUserSnippets(c *gin.Context, userID string) {
 // @Param username query string false "Username to check"
 // @Param email query string false "Email to check"
 // @Success 200 {object} map[string]bool
-// @Failure 400 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
 // @Router /auth/availability [get]
-func checkAvailability(c *gin.Context) {
+func (s *Server) checkAvailability(c *gin.Context) {
 	username := strings.TrimSpace(c.Query("username"))
 	email := strings.TrimSpace(c.Query("email"))
 
@@ -382,8 +628,9 @@ func checkAvailability(c *gin.Context) {
 		return
 	}
 
+	orgID := middleware.OrgID(c)
 	conditions := make([]string, 0, 2)
-	args := make([]interface{}, 0, 2)
+	args := []interface{}{orgID}
 
 	if username != "" {
 		conditions = append(conditions, "username = $"+strconv.Itoa(len(args)+1))
@@ -398,13 +645,13 @@ func checkAvailability(c *gin.Context) {
 	query := `
 		SELECT username, email
 		FROM users
-		WHERE is_deleted = false AND (` + strings.Join(conditions, " OR ") + `)
+		WHERE is_deleted = false AND org_id = $1 AND (` + strings.Join(conditions, " OR ") + `)
 		LIMIT 1
 	`
 
 	var existingUsername sql.NullString
 	var existingEmail sql.NullString
-	err := database.DB.QueryRowContext(c.Request.Context(), query, args...).Scan(&existingUsername, &existingEmail)
+	err := s.db.QueryRowContext(c.Request.Context(), query, args...).Scan(&existingUsername, &existingEmail)
 	if err != nil && err != sql.ErrNoRows {
 		respondError(c, http.StatusInternalServerError, "Failed to check availability")
 		return
@@ -413,6 +660,14 @@ func checkAvailability(c *gin.Context) {
 	var usernameAvailable *bool
 	if username != "" {
 		available := !existingUsername.Valid || existingUsername.String != username
+		if available {
+			reserved, reserveErr := models.IsUsernameReserved(c.Request.Context(), s.db, orgID, username, "")
+			if reserveErr != nil {
+				respondError(c, http.StatusInternalServerError, "Failed to check availability")
+				return
+			}
+			available = !reserved
+		}
 		usernameAvailable = &available
 	}
 
@@ -441,13 +696,13 @@ func checkAvailability(c *gin.Context) {
 // @Produce json
 // @Param credentials body models.LoginRequest true "Login credentials"
 // @Success 200 {object} models.LoginResponse
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
 // @Router /auth/login [post]
-func login(c *gin.Context) {
+func (s *Server) login(c *gin.Context) {
 	var req models.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -455,10 +710,11 @@ func login(c *gin.Context) {
 	query := `
 		SELECT id, username, email, password_hash, full_name, avatar_url, created_at, updated_at
 		FROM users
-		WHERE (username = $1 OR email = $1) AND is_deleted = false
+		WHERE (username = $1 OR email = $1) AND is_deleted = false AND org_id = $2
 	`
 
-	row := database.DB.QueryRowContext(c.Request.Context(), query, req.Login)
+	orgID := middleware.OrgID(c)
+	row := s.db.QueryRowContext(c.Request.Context(), query, req.Login, orgID)
 	user, err := models.ScanUserForAuth(row)
 	if err == sql.ErrNoRows {
 		respondError(c, http.StatusUnauthorized, "Invalid username/email or password")
@@ -469,40 +725,32 @@ func login(c *gin.Context) {
 		respondError(c, http.StatusInternalServerError, "Failed to authenticate")
 		return
 	}
+	user.OrgID = orgID
 
 	// Check password
 	if !auth.CheckPassword(req.Password, user.PasswordHash) {
+		s.recordLogin(c, models.LoginAttempt{
+			UserID:        user.ID,
+			Method:        models.LoginMethodPassword,
+			FailureReason: models.LoginFailureInvalidPassword,
+		})
 		respondError(c, http.StatusUnauthorized, "Invalid username/email or password")
 		return
 	}
 
 	// Get user roles for JWT
-	roles, err := models.GetUserRoleNames(c.Request.Context(), user.ID)
+	roles, err := s.store.GetUserRoleNames(c.Request.Context(), user.ID)
 	if err != nil {
 		log.Printf("Failed to fetch roles for user %q: %v", user.ID, err)
 		roles = []string{} // Continue with empty roles on error
 	}
 
-	// Generate JWT access token with roles (short-lived)
-	accessToken, err := auth.GenerateAccessTokenWithRoles(user, roles)
-	if err != nil {
-		respondError(c, http.StatusInternalServerError, "Failed to generate access token")
-		return
-	}
-
 	// Get device info from user agent (optional)
 	deviceInfo := c.GetHeader("User-Agent")
 
 	// Get client IP address
 	clientIP := c.ClientIP()
 
-	// Create a session for this login
-	session, err := models.CreateSession(c.Request.Context(), user.ID, deviceInfo, clientIP, c.GetHeader("User-Agent"))
-	if err != nil {
-		log.Printf("failed to create session for user %q: %v", user.ID, err)
-		// Don't fail login if session creation fails, just log it
-	}
-
 	// Generate refresh token (long-lived)
 	refreshToken, err := models.GenerateRefreshToken()
 	if err != nil {
@@ -510,14 +758,24 @@ func login(c *gin.Context) {
 		return
 	}
 
-	// Store refresh token bound to session
-	if session != nil {
-		if errStore := models.StoreRefreshToken(c.Request.Context(), session.ID, refreshToken); errStore != nil {
-			log.Printf("failed to store refresh token for session %q: %v", session.ID, errStore)
-			// Continue without failing login
-		}
+	// Create a session for this login, storing the refresh token against it
+	session, err := s.store.CreateSession(c.Request.Context(), user.ID, deviceInfo, clientIP, c.GetHeader("User-Agent"), refreshToken)
+	if err != nil {
+		log.Printf("failed to create session for user %q: %v", user.ID, err)
+		respondError(c, http.StatusInternalServerError, "Failed to create session")
+		return
 	}
 
+	// Generate JWT access token with roles (short-lived), bound to the session
+	accessToken, err := auth.GenerateSessionAccessToken(user, roles, session.ID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to generate access token")
+		return
+	}
+
+	s.notifyNewLoginDevice(c, user.ID, deviceInfo)
+	s.recordLogin(c, models.LoginAttempt{UserID: user.ID, SessionID: session.ID, Method: models.LoginMethodPassword, Success: true})
+
 	// Set refresh token as HTTP-only secure cookie
 	c.SetCookie(
 		"refresh_token", // name
@@ -539,6 +797,85 @@ func login(c *gin.Context) {
 	respondSuccess(c, http.StatusOK, response)
 }
 
+// recordLogin stores a login attempt with the request's device and IP; failures are only logged
+func (s *Server) recordLogin(c *gin.Context, attempt models.LoginAttempt) {
+	attempt.DeviceInfo = c.GetHeader("User-Agent")
+	attempt.IPAddress = c.ClientIP()
+	if err := s.store.RecordLogin(c.Request.Context(), attempt); err != nil {
+		log.Printf("failed to record %s login for user %q: %v", attempt.Method, attempt.UserID, err)
+	}
+}
+
+// notifyNewLoginDevice creates a notification when a user logs in from a device they haven't used before.
+// It must run before the login is recorded.
+func (s *Server) notifyNewLoginDevice(c *gin.Context, userID, deviceInfo string) {
+	ctx := c.Request.Context()
+	isNew, err := s.store.IsNewLoginDevice(ctx, userID, deviceInfo)
+	if err != nil {
+		log.Printf("failed to check login device for user %q: %v", userID, err)
+		return
+	}
+	if !isNew {
+		return
+	}
+
+	device := describeUserAgent(deviceInfo)
+	err = models.CreateNotification(ctx, s.db, userID, models.NotificationNewLogin,
+		"New login from "+device,
+		"Your account was accessed from "+device+". If this wasn't you, log out of all sessions and change your password.",
+		gin.H{"device": device, "at": s.now().UTC()})
+	if err != nil {
+		log.Printf("failed to create new login notification for user %q: %v", userID, err)
+	}
+}
+
+// describeUserAgent turns a User-Agent header into a short "Browser on OS" description
+func describeUserAgent(ua string) string {
+	browsers := []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	}
+	systems := []struct{ token, name string }{
+		{"Windows", "Windows"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Android", "Android"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	}
+
+	browser := ""
+	for _, b := range browsers {
+		if strings.Contains(ua, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	system := ""
+	for _, sys := range systems {
+		if strings.Contains(ua, sys.token) {
+			system = sys.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return "a device running " + system
+	default:
+		return "an unknown device"
+	}
+}
+
 // refreshAccessToken generates a new access token using a valid refresh token
 // @Summary Refresh access token
 // @Description Get a new access token using a refresh token
@@ -547,10 +884,11 @@ func login(c *gin.Context) {
 // @Produce json
 // @Param token body models.RefreshTokenRequest true "Refresh token"
 // @Success 200 {object} models.RefreshTokenResponse
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
 // @Router /auth/refresh [post]
-func refreshAccessToken(c *gin.Context) {
+func (s *Server) refreshAccessToken(c *gin.Context) {
 	// Try to get refresh token from cookie first, then from request body
 	refreshToken, err := c.Cookie("refresh_token")
 	if err != nil {
@@ -564,7 +902,7 @@ func refreshAccessToken(c *gin.Context) {
 	}
 
 	// Validate refresh token
-	rt, err := models.ValidateRefreshToken(c.Request.Context(), refreshToken)
+	rt, err := s.store.ValidateRefreshToken(c.Request.Context(), refreshToken)
 	if err != nil {
 		switch err {
 		case models.ErrTokenExpired:
@@ -581,10 +919,12 @@ func refreshAccessToken(c *gin.Context) {
 	query := `
 		SELECT id, username, email, full_name, avatar_url, created_at, updated_at
 		FROM users
-		WHERE id = $1 AND is_deleted = false
+		WHERE id = $1 AND is_deleted = false AND org_id = $2
 	`
 
-	row := database.DB.QueryRowContext(c.Request.Context(), query, rt.UserID)
+	// Refresh tokens only work in their user's organization
+	orgID := middleware.OrgID(c)
+	row := s.db.QueryRowContext(c.Request.Context(), query, rt.UserID, orgID)
 	user, err := models.ScanUser(row)
 	if err == sql.ErrNoRows {
 		respondError(c, http.StatusUnauthorized, "User not found")
@@ -594,23 +934,32 @@ func refreshAccessToken(c *gin.Context) {
 		respondError(c, http.StatusInternalServerError, "Failed to fetch user")
 		return
 	}
+	user.OrgID = orgID
 
 	// Get user roles for JWT
-	roles, err := models.GetUserRoleNames(c.Request.Context(), user.ID)
+	roles, err := s.store.GetUserRoleNames(c.Request.Context(), user.ID)
 	if err != nil {
 		log.Printf("Failed to fetch roles for user %q: %v", user.ID, err)
 		roles = []string{} // Continue with empty roles on error
 	}
 
-	// Generate new access token with roles
-	accessToken, err := auth.GenerateAccessTokenWithRoles(user, roles)
+	// Generate new access token with roles for the refresh token's session
+	accessToken, err := auth.GenerateSessionAccessToken(user, roles, rt.SessionID)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "Failed to generate access token")
 		return
 	}
 
+	// Token-only logins are part of the login history too
+	s.recordLogin(c, models.LoginAttempt{
+		UserID:    user.ID,
+		SessionID: rt.SessionID,
+		Method:    models.LoginMethodRefresh,
+		Success:   true,
+	})
+
 	// Rotate refresh token: revoke old and issue a new one for same session
-	if revokeErr := models.RevokeRefreshToken(c.Request.Context(), refreshToken); revokeErr != nil {
+	if revokeErr := s.store.RevokeRefreshToken(c.Request.Context(), refreshToken); revokeErr != nil {
 		log.Printf("failed to revoke used refresh token: %v", revokeErr)
 		// continue; not fatal for issuing access token
 	}
@@ -619,7 +968,7 @@ func refreshAccessToken(c *gin.Context) {
 	if err == nil {
 		// Store new refresh token for the same session
 		if rt.SessionID != "" {
-			if storeErr := models.StoreRefreshToken(c.Request.Context(), rt.SessionID, newRefreshToken); storeErr != nil {
+			if storeErr := s.store.StoreRefreshToken(c.Request.Context(), rt.SessionID, newRefreshToken); storeErr != nil {
 				log.Printf("failed to store new refresh token: %v", storeErr)
 			}
 		}
@@ -652,9 +1001,9 @@ func refreshAccessToken(c *gin.Context) {
 // @Produce json
 // @Param token body models.RefreshTokenRequest true "Refresh token to revoke"
 // @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
 // @Router /auth/logout [post]
-func logout(c *gin.Context) {
+func (s *Server) logout(c *gin.Context) {
 	// Try to get refresh token from cookie first, then from request body
 	refreshToken, err := c.Cookie("refresh_token")
 	if err != nil {
@@ -668,7 +1017,7 @@ func logout(c *gin.Context) {
 	}
 
 	// Revoke the refresh token
-	if err := models.RevokeRefreshToken(c.Request.Context(), refreshToken); err != nil {
+	if err := s.store.RevokeRefreshToken(c.Request.Context(), refreshToken); err != nil {
 		respondError(c, http.StatusInternalServerError, "Failed to logout")
 		return
 	}
@@ -689,13 +1038,13 @@ func logout(c *gin.Context) {
 
 // logoutAll revokes all refresh tokens for the authenticated user
 // @Summary Logout from all devices
-// @Description Revoke all refresh tokens for the authenticated user
+// @Description Revoke all refresh tokens and browser-extension tokens for the authenticated user
 // @Tags auth
 // @Produce json
 // @Success 200 {object} map[string]string
 // @Security BearerAuth
 // @Router /auth/logout-all [post]
-func logoutAll(c *gin.Context) {
+func (s *Server) logoutAll(c *gin.Context) {
 	// Get user ID from JWT token in context (set by AuthMiddleware)
 	userID, exists := getAuthUserID(c)
 	if !exists {
@@ -703,17 +1052,22 @@ func logoutAll(c *gin.Context) {
 	}
 
 	// Revoke all tokens for this user
-	if err := models.RevokeAllUserTokens(c.Request.Context(), userID); err != nil {
+	if err := s.store.RevokeAllUserTokens(c.Request.Context(), userID); err != nil {
 		log.Printf("Failed to revoke all tokens for user %v: %v", userID, err)
 		respondError(c, http.StatusInternalServerError, "Failed to logout from all devices")
 		return
 	}
 
 	// Logout all sessions for this user
-	if err := models.LogoutAllUserSessions(c.Request.Context(), userID); err != nil {
+	if err := s.store.LogoutAllUserSessions(c.Request.Context(), userID); err != nil {
 		log.Printf("Failed to logout all sessions for user %v: %v", userID, err)
 	}
 
+	// Browser extensions are signed out too
+	if err := s.store.RevokeAllUserExtensionTokens(c.Request.Context(), userID); err != nil {
+		log.Printf("Failed to revoke extension tokens for user %v: %v", userID, err)
+	}
+
 	respondSuccess(c, http.StatusOK, gin.H{"message": "Logged out from all devices successfully"})
 }
 
@@ -725,13 +1079,13 @@ func logoutAll(c *gin.Context) {
 // @Success 200 {object} map[string]interface{}
 // @Security BearerAuth
 // @Router /auth/sessions [get]
-func getSessions(c *gin.Context) {
+func (s *Server) getSessions(c *gin.Context) {
 	userID, exists := getAuthUserID(c)
 	if !exists {
 		return
 	}
 
-	sessions, err := models.GetUserSessions(c.Request.Context(), userID)
+	sessions, err := s.store.GetUserSessions(c.Request.Context(), userID)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "Failed to fetch sessions")
 		return
@@ -747,11 +1101,11 @@ func getSessions(c *gin.Context) {
 // @Produce json
 // @Param sessionId path string true "Session ID"
 // @Success 200 {object} map[string]string
-// @Failure 403 {object} map[string]string
-// @Failure 404 {object} map[string]string
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
 // @Security BearerAuth
 // @Router /auth/sessions/{sessionId} [post]
-func logoutSession(c *gin.Context) {
+func (s *Server) logoutSession(c *gin.Context) {
 	sessionID := c.Param("sessionId")
 	userID, exists := getAuthUserID(c)
 	if !exists {
@@ -759,7 +1113,7 @@ func logoutSession(c *gin.Context) {
 	}
 
 	// Get the session to verify ownership
-	session, err := models.GetSessionByID(c.Request.Context(), sessionID)
+	session, err := s.store.GetSessionByID(c.Request.Context(), sessionID)
 	if err != nil {
 		respondError(c, http.StatusNotFound, "Session not found")
 		return
@@ -772,15 +1126,114 @@ func logoutSession(c *gin.Context) {
 	}
 
 	// Logout the session
-	if err := models.LogoutSession(c.Request.Context(), sessionID); err != nil {
+	if err := s.store.LogoutSession(c.Request.Context(), sessionID); err != nil {
 		respondError(c, http.StatusInternalServerError, "Failed to logout session")
 		return
 	}
 
 	// Revoke all refresh tokens for this session
-	if err := models.RevokeAllSessionTokens(c.Request.Context(), sessionID); err != nil {
+	if err := s.store.RevokeAllSessionTokens(c.Request.Context(), sessionID); err != nil {
 		log.Printf("Failed to revoke tokens for session %s: %v", sessionID, err)
 	}
 
+	s.recordAudit(c, models.AuditAction{
+		Action:       models.AuditSessionLoggedOut,
+		TargetType:   models.AuditTargetSession,
+		TargetID:     sessionID,
+		TargetUserID: userID,
+	})
+
 	respondSuccess(c, http.StatusOK, gin.H{"message": "Session logged out successfully"})
 }
+
+// getMyLogins returns the authenticated user's recent login history
+// @Summary Get my login history
+// @Description Get recent successful and failed logins (timestamp, method, device, hashed IP) and the last login time
+// @Tags users
+// @Produce json
+// @Param limit query int false "Limit results (default 20, max 100)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/logins [get]
+func (s *Server) getMyLogins(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	limit := 20
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			limit = l
+			if limit > 100 {
+				limit = 100
+			}
+		}
+	}
+
+	lastLoginAt, err := s.store.GetLastLoginAt(c.Request.Context(), userID)
+	if handleScanError(c, err, "User not found") {
+		return
+	}
+
+	logins, err := s.store.GetRecentLogins(c.Request.Context(), userID, limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch login history")
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, gin.H{
+		"lastLoginAt": lastLoginAt,
+		"items":       logins,
+		"count":       len(logins),
+	})
+}
+
+// getMyUsage returns the authenticated user's storage usage and quota consumption
+// @Summary Get my account usage
+// @Description Get snippet count, total content bytes, history entries, active sessions, plan and quota consumption for a usage meter
+// @Tags users
+// @Produce json
+// @Success 200 {object} models.UsageReport
+// @Failure 401 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/usage [get]
+func (s *Server) getMyUsage(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	report, err := s.store.GetUsageReport(c.Request.Context(), userID, models.LoadQuotas())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch account usage")
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, report)
+}
+
+// getMyQuota returns the authenticated user's snippet count and content against their plan's quota
+// @Summary Get my quota
+// @Description Get your plan, how many snippets you have and how much content they hold against the plan's limits, and how many more snippets you can create (null when unlimited). Free accounts may keep 200 snippets by default; premium subscribers and admins are unlimited. Creating or importing snippets past the limit is refused.
+// @Tags users
+// @Produce json
+// @Success 200 {object} models.QuotaReport
+// @Failure 401 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/quota [get]
+func (s *Server) getMyQuota(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	report, err := s.store.GetQuotaReport(c.Request.Context(), userID, models.LoadQuotas())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch quota")
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, report)
+}
@@ -6,12 +6,12 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
-	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jheysaaz/snippy-backend/app/auth"
-	"github.com/jheysaaz/snippy-backend/app/database"
 	"github.com/jheysaaz/snippy-backend/app/models"
 )
 
@@ -24,19 +24,6 @@ func TestLoginWithUsernameOrEmail(t *testing.T) {
 		}
 	}()
 
-	// Initialize database
-	dbURL := getHandlersTestDBURL()
-	os.Setenv("DATABASE_URL", dbURL)
-	defer os.Unsetenv("DATABASE_URL")
-
-	if err := database.Init(); err != nil {
-		t.Fatalf("Failed to initialize database: %v", err)
-	}
-	defer database.DB.Close()
-
-	// Set database.DB for handlers
-	database.DB = testDB
-
 	// Clean up any existing test users
 	_, _ = testDB.Exec(`DELETE FROM users WHERE username = 'testuser' OR email = 'test@example.com'`)
 
@@ -55,7 +42,7 @@ func TestLoginWithUsernameOrEmail(t *testing.T) {
 	}
 
 	router := gin.New()
-	router.POST("/api/v1/auth/login", Login)
+	router.POST("/api/v1/auth/login", NewServer(testDB, nil).login)
 
 	tests := []struct {
 		name           string
@@ -144,6 +131,19 @@ func TestLoginWithUsernameOrEmail(t *testing.T) {
 					if refreshTokenCookie.MaxAge <= 0 {
 						t.Errorf("Expected refresh_token cookie to have positive MaxAge, got %d", refreshTokenCookie.MaxAge)
 					}
+
+					// The refresh token belongs to the session the access token was issued for
+					claims, err := auth.ValidateToken(response.AccessToken)
+					if err != nil {
+						t.Fatalf("Failed to validate access token: %v", err)
+					}
+					rt, err := models.NewStore(testDB).ValidateRefreshToken(context.Background(), refreshTokenCookie.Value)
+					if err != nil {
+						t.Fatalf("Refresh token was not stored: %v", err)
+					}
+					if claims.SessionID == "" || rt.SessionID != claims.SessionID {
+						t.Errorf("Refresh token session = %q, access token session = %q", rt.SessionID, claims.SessionID)
+					}
 				}
 
 				if response.User == nil {
@@ -169,17 +169,6 @@ func TestCheckAvailability(t *testing.T) {
 		}
 	}()
 
-	dbURL := getHandlersTestDBURL()
-	os.Setenv("DATABASE_URL", dbURL)
-	defer os.Unsetenv("DATABASE_URL")
-
-	if err := database.Init(); err != nil {
-		t.Fatalf("Failed to initialize database: %v", err)
-	}
-	defer database.DB.Close()
-
-	database.DB = testDB
-
 	// Create a test user
 	hashedPassword, _ := auth.HashPassword("testpassword123")
 	_, _ = testDB.Exec(`
@@ -188,7 +177,7 @@ func TestCheckAvailability(t *testing.T) {
 	`, hashedPassword)
 
 	router := gin.New()
-	router.GET("/api/v1/auth/availability", CheckAvailability)
+	router.GET("/api/v1/auth/availability", NewServer(testDB, nil).checkAvailability)
 
 	tests := []struct {
 		usernameAvailable *bool
@@ -274,3 +263,129 @@ func TestCheckAvailability(t *testing.T) {
 func boolPtr(b bool) *bool {
 	return &b
 }
+
+func TestParseUserListParams(t *testing.T) {
+	cursor := encodeUserCursor(models.User{ID: "u1", Username: "alice", CreatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)})
+
+	tests := []struct {
+		name       string
+		query      string
+		wantSort   string
+		wantLimit  int
+		wantOffset int
+		wantErr    bool
+		wantCursor bool
+	}{
+		{name: "Defaults", query: "", wantSort: userSortNewest, wantLimit: 50},
+		{name: "Limit capped", query: "limit=500", wantSort: userSortNewest, wantLimit: 100},
+		{name: "Offset", query: "offset=20&sort=username", wantSort: userSortUsername, wantLimit: 50, wantOffset: 20},
+		{name: "Cursor overrides offset", query: "offset=20&cursor=" + cursor, wantSort: userSortNewest, wantLimit: 50, wantCursor: true},
+		{name: "Invalid sort", query: "sort=email", wantErr: true},
+		{name: "Invalid date", query: "created_after=yesterday", wantErr: true},
+		{name: "Invalid cursor", query: "cursor=not-a-cursor", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/users?"+tt.query, nil)
+
+			params, err := parseUserListParams(c)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if params.Sort != tt.wantSort || params.Limit != tt.wantLimit || params.Offset != tt.wantOffset {
+				t.Errorf("got sort=%s limit=%d offset=%d, want sort=%s limit=%d offset=%d",
+					params.Sort, params.Limit, params.Offset, tt.wantSort, tt.wantLimit, tt.wantOffset)
+			}
+			if (params.Cursor != nil) != tt.wantCursor {
+				t.Errorf("cursor present = %v, want %v", params.Cursor != nil, tt.wantCursor)
+			}
+		})
+	}
+}
+
+func TestUserCursorRoundTrip(t *testing.T) {
+	user := models.User{ID: "123e4567-e89b-12d3-a456-426614174000", Username: "alice", CreatedAt: time.Now().UTC().Truncate(time.Microsecond)}
+
+	cursor, err := decodeUserCursor(encodeUserCursor(user))
+	if err != nil {
+		t.Fatalf("decodeUserCursor failed: %v", err)
+	}
+	if cursor.ID != user.ID || cursor.Username != user.Username || !cursor.CreatedAt.Equal(user.CreatedAt) {
+		t.Errorf("cursor = %+v, want values from %+v", cursor, user)
+	}
+}
+
+func TestBuildUserListQuery(t *testing.T) {
+	after := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	query, args := buildUserListQuery(userListParams{
+		Search:       "a_b%",
+		CreatedAfter: &after,
+		Cursor:       &userCursor{ID: "u1", CreatedAt: after},
+		Sort:         userSortNewest,
+		Limit:        10,
+	})
+
+	for _, fragment := range []string{
+		"(username ILIKE $1 OR email ILIKE $1)",
+		"created_at > $2",
+		"(created_at, id) < ($3, $4)",
+		"ORDER BY created_at DESC, id DESC",
+		"LIMIT $5",
+	} {
+		if !strings.Contains(query, fragment) {
+			t.Errorf("query missing %q:\n%s", fragment, query)
+		}
+	}
+	if strings.Contains(query, "OFFSET") {
+		t.Error("query should not use OFFSET when offset is zero")
+	}
+	if len(args) != 5 {
+		t.Fatalf("expected 5 args, got %d", len(args))
+	}
+	if args[0] != `%a\_b\%%` {
+		t.Errorf("search pattern = %v, want wildcards escaped", args[0])
+	}
+	if args[4] != 11 {
+		t.Errorf("limit arg = %v, want 11 (one extra row)", args[4])
+	}
+
+	query, _ = buildUserListQuery(userListParams{Sort: userSortUsername, Limit: 10, Offset: 30, Cursor: &userCursor{ID: "u1", Username: "bob"}})
+	if !strings.Contains(query, "username > $1") || !strings.Contains(query, "ORDER BY username ASC") || !strings.Contains(query, "OFFSET $3") {
+		t.Errorf("unexpected username-sorted query:\n%s", query)
+	}
+
+	query, args = buildUserListQuery(userListParams{OrgID: "org-1", Search: "bob", Sort: userSortNewest, Limit: 10})
+	if !strings.Contains(query, "AND org_id = $2") || len(args) != 3 || args[1] != "org-1" {
+		t.Errorf("unexpected organization-scoped query (args %v):\n%s", args, query)
+	}
+}
+
+func TestDescribeUserAgent(t *testing.T) {
+	tests := []struct {
+		ua       string
+		expected string
+	}{
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36", "Chrome on Windows"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0", "Edge on Windows"},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Safari/605.1.15", "Safari on macOS"},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1", "Safari on iOS"},
+		{"Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0", "Firefox on Linux"},
+		{"curl/8.4.0", "curl"},
+		{"", "an unknown device"},
+	}
+
+	for _, tt := range tests {
+		if got := describeUserAgent(tt.ua); got != tt.expected {
+			t.Errorf("describeUserAgent(%q) = %q, want %q", tt.ua, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Package handlers provides webhook subscription endpoints.
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/auth"
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"github.com/jheysaaz/snippy-backend/app/webhook"
+)
+
+// createWebhook registers a webhook for the authenticated user's events
+// @Summary Register webhook
+// @Description Register a URL to receive your snippet and account events. Deliveries are signed with the returned secret (X-Snippy-Signature: sha256=HMAC of the body), which is only shown once. An empty event list subscribes to every event. The URL must not point to a loopback, link-local or private address, and redirects aren't followed.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body models.CreateWebhookRequest true "URL, events and description"
+// @Success 201 {object} models.Webhook
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /webhooks [post]
+func (s *Server) createWebhook(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	var req models.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := webhook.ValidateURL(c.Request.Context(), req.URL); err != nil {
+		respondError(c, http.StatusBadRequest, "url "+err.Error())
+		return
+	}
+
+	secret, err := auth.GenerateRandomToken(32)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to generate webhook secret")
+		return
+	}
+
+	hook, err := s.store.CreateWebhook(c.Request.Context(), userID, secret, req)
+	if errors.Is(err, models.ErrWebhookLimit) {
+		respondError(c, http.StatusConflict, "You can register at most "+strconv.Itoa(models.WebhookMaxPerUser)+" webhooks")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to register webhook")
+		return
+	}
+
+	respondSuccess(c, http.StatusCreated, hook)
+}
+
+// getMyWebhooks lists the authenticated user's webhooks
+// @Summary List webhooks
+// @Description Get your registered webhooks (secrets are not included)
+// @Tags webhooks
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /webhooks [get]
+func (s *Server) getMyWebhooks(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	webhooks, err := s.store.GetUserWebhooks(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch webhooks")
+		return
+	}
+
+	respondWithCount(c, webhooks, len(webhooks))
+}
+
+// deleteWebhook removes a webhook and its delivery log
+// @Summary Delete webhook
+// @Description Stop sending events to a webhook
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /webhooks/{id} [delete]
+func (s *Server) deleteWebhook(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+
+	err = s.store.DeleteWebhook(c.Request.Context(), userID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(c, http.StatusNotFound, "Webhook not found")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to delete webhook")
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// getWebhookDeliveries lists a webhook's recent deliveries
+// @Summary List webhook deliveries
+// @Description Recent deliveries with status, attempts, last response status and error, newest first
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param status query string false "pending, sending, succeeded or failed"
+// @Param limit query int false "Limit results (default 50, max 100)"
+// @Param offset query int false "Offset for pagination"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /webhooks/{id}/deliveries [get]
+func (s *Server) getWebhookDeliveries(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+
+	status := c.Query("status")
+	switch status {
+	case "", models.WebhookPending, models.WebhookSending, models.WebhookSucceeded, models.WebhookFailed:
+	default:
+		respondError(c, http.StatusBadRequest, "status must be one of pending, sending, succeeded, failed")
+		return
+	}
+
+	if _, err := s.store.GetUserWebhook(c.Request.Context(), userID, id); handleScanError(c, err, "Webhook not found") {
+		return
+	}
+
+	limit, offset := parseLimitOffset(c, 50, 100)
+	deliveries, total, err := s.store.GetWebhookDeliveries(c.Request.Context(), id, status, limit, offset)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch webhook deliveries")
+		return
+	}
+
+	respondPage(c, deliveries, len(deliveries), total, offset)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCreateWebhookValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "Missing URL", body: `{"events":["snippet.created"]}`},
+		{name: "Unknown event", body: `{"url":"https://example.com/hook","events":["snippet.viewed"]}`},
+		{name: "Unsupported scheme", body: `{"url":"ftp://example.com/hook"}`},
+		{name: "Metadata address", body: `{"url":"http://169.254.169.254/latest/meta-data/"}`},
+		{name: "Private address", body: `{"url":"http://10.0.0.7:8080/hook"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Set("user_id", "0b3c9a1e-6a5f-4c1b-9d2e-3f4a5b6c7d8e")
+			c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/webhooks", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			NewServer(nil, nil).createWebhook(c)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400 (body %s)", w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestSubscribeZapierHookValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "Missing hook URL", body: `{"trigger":"new_snippet"}`},
+		{name: "Unknown trigger", body: `{"hookUrl":"https://hooks.zapier.com/1","trigger":"deleted_snippet"}`},
+		{name: "Unsupported scheme", body: `{"hookUrl":"ftp://hooks.zapier.com/1","trigger":"new_snippet"}`},
+		{name: "Loopback address", body: `{"hookUrl":"http://127.0.0.1:6379/","trigger":"new_snippet"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Set("user_id", "0b3c9a1e-6a5f-4c1b-9d2e-3f4a5b6c7d8e")
+			c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/zapier/hooks", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			NewServer(nil, nil).subscribeZapierHook(c)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400 (body %s)", w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+// Package handlers provides Zapier trigger endpoints.
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/auth"
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"github.com/jheysaaz/snippy-backend/app/webhook"
+)
+
+// zapierMe identifies the API key's user, for Zapier's connection test
+// @Summary Zapier connection test
+// @Description Returns the user an API key belongs to. Zapier calls this to test a connection and labels it with the username.
+// @Tags zapier
+// @Produce json
+// @Success 200 {object} models.User
+// @Failure 401 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /zapier/me [get]
+func (s *Server) zapierMe(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	user, err := s.store.GetCachedUser(c.Request.Context(), userID)
+	if handleScanError(c, err, "User not found") {
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, user)
+}
+
+// getZapierTrigger polls a Zapier trigger
+// @Summary Poll Zapier trigger
+// @Description The newest items of a trigger, newest first, as a bare array (Zapier deduplicates them by id). new_snippet has an item per snippet; updated_snippet has an item per snippet's latest update.
+// @Tags zapier
+// @Produce json
+// @Param trigger path string true "Trigger" Enums(new_snippet, updated_snippet)
+// @Success 200 {array} models.ZapierItem
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /zapier/triggers/{trigger} [get]
+func (s *Server) getZapierTrigger(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	trigger := c.Param("trigger")
+	if _, ok := models.ZapierTriggerEvents[trigger]; !ok {
+		respondError(c, http.StatusNotFound, "Unknown trigger")
+		return
+	}
+
+	items, err := s.store.ListZapierItems(c.Request.Context(), userID, trigger)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to fetch trigger items")
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, items)
+}
+
+// subscribeZapierHook subscribes a Zapier REST hook to a trigger
+// @Summary Subscribe Zapier hook
+// @Description Sends each new item of the trigger to hookUrl, in the same shape polling returns. The hook counts towards your webhook limit and is removed when Zapier answers 410 Gone. Like webhook URLs, hookUrl must not point to a loopback, link-local or private address.
+// @Tags zapier
+// @Accept json
+// @Produce json
+// @Param hook body models.ZapierSubscribeRequest true "Hook URL and trigger"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /zapier/hooks [post]
+func (s *Server) subscribeZapierHook(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	var req models.ZapierSubscribeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := webhook.ValidateURL(c.Request.Context(), req.HookURL); err != nil {
+		respondError(c, http.StatusBadRequest, "hookUrl "+err.Error())
+		return
+	}
+
+	secret, err := auth.GenerateRandomToken(32)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to generate webhook secret")
+		return
+	}
+
+	hook, err := s.store.CreateZapierHook(c.Request.Context(), userID, secret, req)
+	if errors.Is(err, models.ErrWebhookLimit) {
+		respondError(c, http.StatusConflict, "You can register at most "+strconv.Itoa(models.WebhookMaxPerUser)+" webhooks")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to subscribe hook")
+		return
+	}
+
+	respondSuccess(c, http.StatusCreated, gin.H{"id": hook.ID, "trigger": req.Trigger})
+}
+
+// unsubscribeZapierHook removes a Zapier REST hook
+// @Summary Unsubscribe Zapier hook
+// @Description Stop sending a trigger's items to a Zapier hook
+// @Tags zapier
+// @Produce json
+// @Param id path int true "Hook ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /zapier/hooks/{id} [delete]
+func (s *Server) unsubscribeZapierHook(c *gin.Context) {
+	userID, exists := getAuthUserID(c)
+	if !exists {
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid hook ID")
+		return
+	}
+
+	err = s.store.DeleteWebhook(c.Request.Context(), userID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(c, http.StatusNotFound, "Hook not found")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Failed to unsubscribe hook")
+		return
+	}
+
+	respondSuccess(c, http.StatusOK, gin.H{"message": "Hook unsubscribed successfully"})
+}
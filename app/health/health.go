@@ -0,0 +1,202 @@
+// Package health reports the server's build, uptime and the status of the services it
+// depends on, for load balancers, container orchestrators and monitoring.
+package health
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Build information, set at build time with
+// -ldflags "-X github.com/jheysaaz/snippy-backend/app/health.Version=..." (see the Makefile).
+// Commit and BuildTime fall back to the VCS information Go embeds in the binary.
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Statuses of the server and of each check. A failing optional dependency degrades the
+// server, which keeps serving; a failing required one takes it down.
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusDown     = "down"
+)
+
+const (
+	// checkTimeout bounds each dependency check
+	checkTimeout = 2 * time.Second
+
+	// resultTTL is how long check results are reused, so frequent probes don't open a
+	// connection to every dependency (the SMTP server in particular) each time
+	resultTTL = 10 * time.Second
+)
+
+// Check is a dependency check
+type Check struct {
+	Check func(ctx context.Context) error
+	Name  string
+	// Required dependencies take the server down when they fail (the database);
+	// others only degrade it (the cache, email)
+	Required bool
+}
+
+// CheckResult is the outcome of one check
+type CheckResult struct {
+	Status    string `json:"status"`
+	LatencyMS int64  `json:"latencyMs"`
+}
+
+// Report is the health endpoint's response
+type Report struct {
+	Checks        map[string]CheckResult `json:"checks"`
+	Status        string                 `json:"status"`
+	Version       string                 `json:"version"`
+	Commit        string                 `json:"commit,omitempty"`
+	BuildTime     string                 `json:"buildTime,omitempty"`
+	Uptime        string                 `json:"uptime"`
+	UptimeSeconds int64                  `json:"uptimeSeconds"`
+}
+
+// Checker runs dependency checks
+type Checker struct {
+	started   time.Time
+	checkedAt time.Time
+	results   map[string]CheckResult
+	status    string
+	commit    string
+	buildTime string
+	checks    []Check
+	mu        sync.Mutex
+}
+
+// New returns a checker for checks, measuring uptime from now
+func New(checks ...Check) *Checker {
+	commit, buildTime := Commit, BuildTime
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && commit == "":
+				commit = setting.Value
+			case setting.Key == "vcs.time" && buildTime == "":
+				buildTime = setting.Value
+			}
+		}
+	}
+	return &Checker{started: time.Now(), commit: commit, buildTime: buildTime, checks: checks}
+}
+
+// Report returns the server's health, running the checks concurrently unless they ran
+// in the last 10 seconds
+func (hc *Checker) Report(ctx context.Context) Report {
+	hc.mu.Lock()
+	if hc.results == nil || time.Since(hc.checkedAt) > resultTTL {
+		hc.status, hc.results = hc.run(ctx)
+		hc.checkedAt = time.Now()
+	}
+	status, results := hc.status, hc.results
+	hc.mu.Unlock()
+
+	uptime := time.Since(hc.started)
+	return Report{
+		Status:        status,
+		Version:       Version,
+		Commit:        hc.commit,
+		BuildTime:     hc.buildTime,
+		Uptime:        uptime.Round(time.Second).String(),
+		UptimeSeconds: int64(uptime / time.Second),
+		Checks:        results,
+	}
+}
+
+// run runs all checks concurrently, returning the overall status and each check's result
+func (hc *Checker) run(ctx context.Context) (string, map[string]CheckResult) {
+	status := StatusOK
+	results := make(map[string]CheckResult, len(hc.checks))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range hc.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.Check(checkCtx)
+			result := CheckResult{Status: StatusOK, LatencyMS: time.Since(start).Milliseconds()}
+			if err != nil {
+				// Errors are only logged, since they can name internal hosts
+				log.Printf("Health check %s failed: %v", check.Name, err)
+				result.Status = StatusDown
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			results[check.Name] = result
+			switch {
+			case err == nil:
+			case check.Required:
+				status = StatusDown
+			case status == StatusOK:
+				status = StatusDegraded
+			}
+		}()
+	}
+	wg.Wait()
+	return status, results
+}
+
+// Handler responds with the health report: 200 when the server is ok or degraded,
+// 503 when it's down
+func (hc *Checker) Handler(c *gin.Context) {
+	report := hc.Report(c.Request.Context())
+	status := http.StatusOK
+	if report.Status == StatusDown {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
+// Probe requests the health endpoint at url and returns an error unless it responds 200
+// (ok or degraded). Over HTTPS, serverName is the host name the certificate is checked
+// against, for probing a local address.
+func Probe(ctx context.Context, url, serverName string) error {
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{ServerName: serverName, MinVersion: tls.VersionTLS12},
+	}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			log.Printf("error closing health response: %v", closeErr)
+		}
+	}()
+
+	var report Report
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&report); err != nil {
+		return fmt.Errorf("invalid health response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server is %s (status %d)", report.Status, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ok := func(context.Context) error { return nil }
+	failing := func(context.Context) error { return errors.New("connection refused") }
+
+	tests := []struct {
+		name       string
+		wantStatus string
+		checks     []Check
+		wantCode   int
+	}{
+		{name: "No checks", checks: nil, wantCode: http.StatusOK, wantStatus: StatusOK},
+		{name: "All ok", checks: []Check{{Name: "database", Check: ok, Required: true}, {Name: "redis", Check: ok}}, wantCode: http.StatusOK, wantStatus: StatusOK},
+		{name: "Optional down", checks: []Check{{Name: "database", Check: ok, Required: true}, {Name: "redis", Check: failing}}, wantCode: http.StatusOK, wantStatus: StatusDegraded},
+		{name: "Required down", checks: []Check{{Name: "database", Check: failing, Required: true}, {Name: "redis", Check: failing}}, wantCode: http.StatusServiceUnavailable, wantStatus: StatusDown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/health", New(tt.checks...).Handler)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+			if w.Code != tt.wantCode {
+				t.Fatalf("status code = %d, want %d", w.Code, tt.wantCode)
+			}
+
+			var report Report
+			if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+				t.Fatalf("invalid report: %v", err)
+			}
+			if report.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", report.Status, tt.wantStatus)
+			}
+			if report.Version != Version || report.Uptime == "" {
+				t.Errorf("version, uptime = %q, %q", report.Version, report.Uptime)
+			}
+			if len(report.Checks) != len(tt.checks) {
+				t.Errorf("checks = %v, want %d", report.Checks, len(tt.checks))
+			}
+			for _, check := range tt.checks {
+				if _, ok := report.Checks[check.Name]; !ok {
+					t.Errorf("check %s missing from report", check.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestProbe(t *testing.T) {
+	for name, tt := range map[string]struct {
+		check   func(context.Context) error
+		wantErr bool
+	}{
+		"Healthy": {check: func(context.Context) error { return nil }},
+		"Down":    {check: func(context.Context) error { return errors.New("no database") }, wantErr: true},
+	} {
+		router := gin.New()
+		router.GET("/health", New(Check{Name: "database", Check: tt.check, Required: true}).Handler)
+		server := httptest.NewServer(router)
+
+		err := Probe(context.Background(), server.URL+"/health", "")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Probe = %v, wantErr %v", name, err, tt.wantErr)
+		}
+		server.Close()
+	}
+
+	if err := Probe(context.Background(), "http://127.0.0.1:1/health", ""); err == nil {
+		t.Error("Probe of a closed port succeeded")
+	}
+}
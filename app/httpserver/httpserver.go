@@ -0,0 +1,81 @@
+// Package httpserver builds the HTTP servers the API listens with.
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	// readHeaderTimeout bounds how long a client may take to send request headers
+	readHeaderTimeout = 10 * time.Second
+
+	// ShutdownTimeout bounds how long in-flight requests may take to finish on shutdown
+	ShutdownTimeout = 30 * time.Second
+)
+
+// New returns a server for handler on addr speaking HTTP/1.1 and HTTP/2. Over TLS,
+// HTTP/2 is negotiated with ALPN. Without TLS, HTTP/2 is only spoken in cleartext (h2c,
+// with prior knowledge) when h2c is set, for deployments behind a proxy that forwards
+// HTTP/2 to the API.
+func New(addr string, handler http.Handler, h2c bool) *http.Server {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(h2c)
+
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		Protocols:         protocols,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+}
+
+// H2CFromEnv reports whether H2C=true enables cleartext HTTP/2
+func H2CFromEnv() bool {
+	return os.Getenv("H2C") == "true"
+}
+
+// Listen returns activated[i], a socket inherited from systemd socket activation, or
+// a new TCP listener on port when fewer sockets were passed
+func Listen(activated []net.Listener, i int, port string) (net.Listener, error) {
+	if i < len(activated) {
+		return activated[i], nil
+	}
+	return net.Listen("tcp", ":"+port)
+}
+
+// Serve serves srv on lis, over TLS when srv.TLSConfig is set, until ctx is done. It
+// then stops accepting connections and waits up to 30 seconds for in-flight requests,
+// returning nil after a graceful shutdown.
+func Serve(ctx context.Context, srv *http.Server, lis net.Listener) error {
+	errc := make(chan error, 1)
+	go func() {
+		if srv.TLSConfig != nil {
+			errc <- srv.ServeTLS(lis, "", "")
+			return
+		}
+		errc <- srv.Serve(lis)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNewH2C(t *testing.T) {
+	for _, h2c := range []bool{true, false} {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("listen: %v", err)
+		}
+		server := New(lis.Addr().String(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Proto", r.Proto)
+		}), h2c)
+		go func() {
+			if err := server.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				t.Errorf("serve: %v", err)
+			}
+		}()
+
+		// A client with prior knowledge of cleartext HTTP/2
+		protocols := new(http.Protocols)
+		protocols.SetUnencryptedHTTP2(true)
+		client := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://"+lis.Addr().String()+"/", nil)
+		resp, err := client.Do(req)
+		switch {
+		case h2c && err != nil:
+			t.Errorf("h2c request failed: %v", err)
+		case h2c && resp.Header.Get("X-Proto") != "HTTP/2.0":
+			t.Errorf("h2c request served as %q, want HTTP/2.0", resp.Header.Get("X-Proto"))
+		case !h2c && err == nil:
+			t.Errorf("h2c request served as %q with h2c disabled", resp.Header.Get("X-Proto"))
+		}
+		if resp != nil {
+			if err := resp.Body.Close(); err != nil {
+				t.Errorf("close body: %v", err)
+			}
+		}
+
+		// HTTP/1.1 keeps working either way
+		req, _ = http.NewRequestWithContext(context.Background(), http.MethodGet, "http://"+lis.Addr().String()+"/", nil)
+		resp, err = http.DefaultClient.Do(req)
+		if err != nil || resp.Header.Get("X-Proto") != "HTTP/1.1" {
+			t.Errorf("HTTP/1.1 request (h2c %v) = %v, %v", h2c, resp, err)
+		}
+		if resp != nil {
+			if err := resp.Body.Close(); err != nil {
+				t.Errorf("close body: %v", err)
+			}
+		}
+
+		if err := server.Close(); err != nil {
+			t.Errorf("close: %v", err)
+		}
+	}
+}
+
+func TestServeShutdown(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	started := make(chan struct{})
+	server := New(lis.Addr().String(), http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+	}), false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- Serve(ctx, server, lis) }()
+
+	// A request in flight when shutdown starts still completes
+	respc := make(chan *http.Response, 1)
+	go func() {
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://"+lis.Addr().String()+"/", nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Errorf("request: %v", err)
+		}
+		respc <- resp
+	}()
+	<-started
+	cancel()
+
+	if err := <-served; err != nil {
+		t.Errorf("Serve = %v, want nil after shutdown", err)
+	}
+	if resp := <-respc; resp != nil {
+		if resp.StatusCode != http.StatusNoContent {
+			t.Errorf("in-flight request status = %d, want 204", resp.StatusCode)
+		}
+		if err := resp.Body.Close(); err != nil {
+			t.Errorf("close body: %v", err)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package httpserver
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultHTTPSPort is the port redirects point to unless configured otherwise
+	DefaultHTTPSPort = "443"
+
+	// hstsPreloadMinAge is the shortest max-age the HSTS preload list accepts
+	hstsPreloadMinAge = 365 * 24 * time.Hour
+)
+
+// RedirectConfig configures the listener redirecting plain HTTP to HTTPS
+type RedirectConfig struct {
+	// Port is the plain HTTP port to redirect from; empty disables the redirect listener
+	Port string
+	// HTTPSPort is the port redirect URLs point to
+	HTTPSPort string
+	// Exclude are path prefixes served over plain HTTP instead of redirected, such as
+	// /.well-known/acme-challenge/ for a certificate client using webroot validation
+	Exclude []string
+}
+
+// LoadRedirectConfig reads REDIRECT_* settings from the environment
+func LoadRedirectConfig() RedirectConfig {
+	cfg := RedirectConfig{
+		Port:      os.Getenv("REDIRECT_PORT"),
+		HTTPSPort: os.Getenv("REDIRECT_HTTPS_PORT"),
+		Exclude:   RedirectExcludeFromEnv(),
+	}
+	if cfg.HTTPSPort == "" {
+		cfg.HTTPSPort = DefaultHTTPSPort
+	}
+	return cfg
+}
+
+// RedirectExcludeFromEnv returns the comma-separated path prefixes in REDIRECT_EXCLUDE
+func RedirectExcludeFromEnv() []string {
+	var exclude []string
+	for _, prefix := range strings.Split(os.Getenv("REDIRECT_EXCLUDE"), ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			exclude = append(exclude, prefix)
+		}
+	}
+	return exclude
+}
+
+// Redirect permanently redirects GET and HEAD requests to the same URL over HTTPS on
+// cfg.HTTPSPort. Other methods get 400, since redirecting would drop their body.
+// Requests for excluded paths are served by next.
+func Redirect(cfg RedirectConfig, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, prefix := range cfg.Exclude {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Use HTTPS", http.StatusBadRequest)
+			return
+		}
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if cfg.HTTPSPort != "" && cfg.HTTPSPort != DefaultHTTPSPort {
+			host = net.JoinHostPort(host, cfg.HTTPSPort)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
+
+// HSTSConfig configures the Strict-Transport-Security header. It's disabled when MaxAge is 0.
+type HSTSConfig struct {
+	MaxAge            time.Duration
+	IncludeSubdomains bool
+	// Preload asks browsers' HSTS preload lists to include the domain, which requires
+	// IncludeSubdomains and a max-age of at least a year
+	Preload bool
+}
+
+// LoadHSTSConfig reads HSTS_* settings from the environment
+func LoadHSTSConfig() (HSTSConfig, error) {
+	var cfg HSTSConfig
+	if value := os.Getenv("HSTS_MAX_AGE"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 {
+			return HSTSConfig{}, errors.New("HSTS_MAX_AGE must be a number of seconds")
+		}
+		cfg.MaxAge = time.Duration(seconds) * time.Second
+	}
+	cfg.IncludeSubdomains = os.Getenv("HSTS_INCLUDE_SUBDOMAINS") == "true"
+	cfg.Preload = os.Getenv("HSTS_PRELOAD") == "true"
+
+	if cfg.Preload && (!cfg.IncludeSubdomains || cfg.MaxAge < hstsPreloadMinAge) {
+		return HSTSConfig{}, errors.New("HSTS_PRELOAD requires HSTS_INCLUDE_SUBDOMAINS=true and HSTS_MAX_AGE of at least 31536000")
+	}
+	return cfg, nil
+}
+
+// Header returns the Strict-Transport-Security header value
+func (cfg HSTSConfig) Header() string {
+	value := "max-age=" + strconv.FormatInt(int64(cfg.MaxAge/time.Second), 10)
+	if cfg.IncludeSubdomains {
+		value += "; includeSubDomains"
+	}
+	if cfg.Preload {
+		value += "; preload"
+	}
+	return value
+}
+
+// HSTS sets the Strict-Transport-Security header on responses to HTTPS requests, made
+// directly or through a proxy setting X-Forwarded-Proto. Browsers ignore it over plain HTTP.
+func HSTS(cfg HSTSConfig, next http.Handler) http.Handler {
+	if cfg.MaxAge == 0 {
+		return next
+	}
+	header := cfg.Header()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https") {
+			w.Header().Set("Strict-Transport-Security", header)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,100 @@
+package httpserver
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRedirect(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	tests := []struct {
+		name      string
+		method    string
+		target    string
+		httpsPort string
+		wantURL   string
+		wantCode  int
+	}{
+		{"Default port", http.MethodGet, "http://snippy.example.com/api/v1/health?x=1", "443", "https://snippy.example.com/api/v1/health?x=1", http.StatusMovedPermanently},
+		{"Custom port", http.MethodGet, "http://snippy.example.com:8080/", "8443", "https://snippy.example.com:8443/", http.StatusMovedPermanently},
+		{"POST", http.MethodPost, "http://snippy.example.com/api/v1/auth/login", "443", "", http.StatusBadRequest},
+		{"Excluded path", http.MethodGet, "http://snippy.example.com/.well-known/acme-challenge/token", "443", "", http.StatusTeapot},
+		{"Excluded path POST", http.MethodPost, "http://snippy.example.com/.well-known/acme-challenge/token", "443", "", http.StatusTeapot},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := RedirectConfig{HTTPSPort: tt.httpsPort, Exclude: []string{"/.well-known/acme-challenge/"}}
+			w := httptest.NewRecorder()
+			Redirect(cfg, next).ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if got := w.Header().Get("Location"); got != tt.wantURL {
+				t.Errorf("Location = %q, want %q", got, tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestLoadHSTSConfig(t *testing.T) {
+	tests := []struct {
+		name              string
+		maxAge            string
+		includeSubdomains string
+		preload           string
+		wantHeader        string
+		wantErr           bool
+	}{
+		{name: "Disabled"},
+		{name: "Max age", maxAge: "86400", wantHeader: "max-age=86400"},
+		{name: "Subdomains", maxAge: "86400", includeSubdomains: "true", wantHeader: "max-age=86400; includeSubDomains"},
+		{name: "Preload", maxAge: "63072000", includeSubdomains: "true", preload: "true", wantHeader: "max-age=63072000; includeSubDomains; preload"},
+		{name: "Preload too short", maxAge: "86400", includeSubdomains: "true", preload: "true", wantErr: true},
+		{name: "Preload without subdomains", maxAge: "63072000", preload: "true", wantErr: true},
+		{name: "Invalid max age", maxAge: "1y", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HSTS_MAX_AGE", tt.maxAge)
+			t.Setenv("HSTS_INCLUDE_SUBDOMAINS", tt.includeSubdomains)
+			t.Setenv("HSTS_PRELOAD", tt.preload)
+
+			cfg, err := LoadHSTSConfig()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantHeader != "" && cfg.Header() != tt.wantHeader {
+				t.Errorf("Header() = %q, want %q", cfg.Header(), tt.wantHeader)
+			}
+		})
+	}
+}
+
+func TestHSTS(t *testing.T) {
+	handler := HSTS(HSTSConfig{MaxAge: time.Hour}, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	for name, tt := range map[string]struct {
+		prepare func(r *http.Request)
+		want    bool
+	}{
+		"Plain HTTP": {prepare: func(*http.Request) {}},
+		"TLS":        {prepare: func(r *http.Request) { r.TLS = &tls.ConnectionState{} }, want: true},
+		"Proxied":    {prepare: func(r *http.Request) { r.Header.Set("X-Forwarded-Proto", "https") }, want: true},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		tt.prepare(req)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if got := w.Header().Get("Strict-Transport-Security") != ""; got != tt.want {
+			t.Errorf("%s: header set = %v, want %v", name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	// Registers the pure-Go "sqlite" driver used to read Dash libraries
+	_ "modernc.org/sqlite"
+)
+
+// sqliteHeader starts every SQLite database file
+var sqliteHeader = []byte("SQLite format 3\x00")
+
+// dash reads a Dash snippet library (the Snippets.dash SQLite file). A Dash snippet's
+// title is its abbreviation, so it becomes both the label and the shortcut. Placeholders
+// such as __name__ and @clipboard are kept as they are.
+type dash struct{}
+
+func (dash) Format() string { return "dash" }
+
+func (dash) Parse(data []byte) ([]Snippet, error) {
+	if !bytes.HasPrefix(data, sqliteHeader) {
+		return nil, invalidExport("dash", errors.New("not a SQLite database"))
+	}
+
+	// SQLite reads from a file, so the upload is written to a temporary one
+	f, err := os.CreateTemp("", "snippy-dash-*.dash")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if removeErr := os.Remove(f.Name()); removeErr != nil {
+			log.Printf("error removing Dash import file: %v", removeErr)
+		}
+	}()
+	if _, err := f.Write(data); err != nil {
+		if closeErr := f.Close(); closeErr != nil {
+			log.Printf("error closing Dash import file: %v", closeErr)
+		}
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite", "file:"+f.Name()+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			log.Printf("error closing Dash library: %v", closeErr)
+		}
+	}()
+
+	snippets, err := readDashSnippets(context.Background(), db)
+	if err != nil {
+		return nil, invalidExport("dash", err)
+	}
+	return snippets, nil
+}
+
+// readDashTags returns the tags of each snippet in a Dash library, by snippet ID
+func readDashTags(ctx context.Context, db *sql.DB) (map[int64][]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT i.sid, t.tag
+		FROM tagsIndex i
+		JOIN tags t ON t.tid = i.tid
+		ORDER BY t.tag
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("read tags: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Printf("error closing Dash tag rows: %v", closeErr)
+		}
+	}()
+
+	tags := make(map[int64][]string)
+	for rows.Next() {
+		var sid int64
+		var tag string
+		if err := rows.Scan(&sid, &tag); err != nil {
+			return nil, err
+		}
+		tags[sid] = append(tags[sid], tag)
+	}
+	return tags, rows.Err()
+}
+
+// readDashSnippets reads the snippets and their tags from a Dash library
+func readDashSnippets(ctx context.Context, db *sql.DB) ([]Snippet, error) {
+	tags, err := readDashTags(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT sid, COALESCE(title, ''), COALESCE(body, '')
+		FROM snippets
+		ORDER BY sid
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("read snippets: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Printf("error closing Dash snippet rows: %v", closeErr)
+		}
+	}()
+
+	snippets := make([]Snippet, 0)
+	for rows.Next() {
+		var sid int64
+		var s Snippet
+		if err := rows.Scan(&sid, &s.Label, &s.Content); err != nil {
+			return nil, err
+		}
+		s.Shortcut = s.Label
+		s.Tags = tags[sid]
+		snippets = append(snippets, s)
+	}
+	return snippets, rows.Err()
+}
@@ -0,0 +1,426 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultGitHubAPIURL is GitHub's REST API; GITHUB_API_URL points elsewhere, such as
+	// a GitHub Enterprise Server
+	defaultGitHubAPIURL = "https://api.github.com"
+
+	// MaxGists caps how many gists one import pulls
+	MaxGists = 300
+
+	// MaxGistURLs caps how many gist URLs one import names
+	MaxGistURLs = 100
+
+	// gistsPerPage is the page size of the gist listing, GitHub's maximum
+	gistsPerPage = 100
+
+	// gistFetchConcurrency is how many gists or files are downloaded at a time
+	gistFetchConcurrency = 4
+
+	// maxGistFileBytes caps a downloaded file at maxContentBytes, plus one so longer files
+	// are still recognized as too long
+	maxGistFileBytes = maxContentBytes + 1
+)
+
+var (
+	// ErrGistSourceRequired is returned when a gist import names neither a token nor URLs
+	ErrGistSourceRequired = errors.New("a GitHub token or gist URLs are required")
+	// ErrInvalidGistURL is returned (wrapped) for a URL that doesn't name a gist
+	ErrInvalidGistURL = errors.New("invalid gist URL")
+	// ErrGistNotFound is returned (wrapped) for a gist that doesn't exist or the token
+	// can't read
+	ErrGistNotFound = errors.New("gist not found")
+	// ErrGitHubUnauthorized is returned when GitHub rejects the token
+	ErrGitHubUnauthorized = errors.New("GitHub rejected the token")
+)
+
+// RateLimitError is returned when GitHub's rate limit doesn't leave enough requests for
+// the import
+type RateLimitError struct {
+	// Reset is when GitHub allows requests again
+	Reset time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return "GitHub rate limit reached until " + e.Reset.UTC().Format(time.RFC3339)
+}
+
+// GistImportRequest imports gists: those of the token's account, or the ones at URLs
+type GistImportRequest struct {
+	// Token is a GitHub token, used only for this import; with no URLs, all of its
+	// account's gists are imported
+	Token string `json:"token"`
+	// Conflict is the shortcut conflict strategy: skip (default), rename or overwrite
+	Conflict string `json:"conflict"`
+	// URLs are gist URLs or IDs to import
+	URLs   []string `json:"urls"`
+	DryRun bool     `json:"dryRun"`
+}
+
+// gistHashtag matches the #tags of a gist description
+var gistHashtag = regexp.MustCompile(`(?:^|\s)#([\p{L}\p{N}_+-]+)`)
+
+// gistExtensionLanguages guesses a file's language from its extension when GitHub
+// doesn't detect one
+var gistExtensionLanguages = map[string]string{
+	".bash": "bash", ".c": "c", ".cpp": "c++", ".cs": "c#", ".css": "css", ".go": "go",
+	".html": "html", ".java": "java", ".js": "javascript", ".json": "json", ".kt": "kotlin",
+	".md": "markdown", ".php": "php", ".py": "python", ".rb": "ruby", ".rs": "rust",
+	".sh": "bash", ".sql": "sql", ".swift": "swift", ".ts": "typescript", ".yaml": "yaml",
+	".yml": "yaml", ".zsh": "bash",
+}
+
+type gist struct {
+	Files       map[string]gistFile `json:"files"`
+	ID          string              `json:"id"`
+	Description string              `json:"description"`
+}
+
+type gistFile struct {
+	Filename  string  `json:"filename"`
+	Language  *string `json:"language"`
+	RawURL    string  `json:"raw_url"`
+	Content   *string `json:"content"`
+	Truncated bool    `json:"truncated"`
+}
+
+// GistClient pulls gists from GitHub's REST API. It tracks GitHub's rate limit from the
+// responses and stops before running out, rather than failing halfway.
+type GistClient struct {
+	reset     time.Time
+	http      *http.Client
+	baseURL   string
+	token     string
+	mu        sync.Mutex
+	remaining int
+}
+
+// NewGistClient creates a client for the API at baseURL, authenticating with token if set
+func NewGistClient(baseURL, token string) *GistClient {
+	return &GistClient{
+		http:      &http.Client{Timeout: 30 * time.Second},
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		token:     token,
+		remaining: -1,
+	}
+}
+
+// GitHubAPIURL returns GITHUB_API_URL, or GitHub's public API
+func GitHubAPIURL() string {
+	if base := strings.TrimRight(os.Getenv("GITHUB_API_URL"), "/"); base != "" {
+		return base
+	}
+	return defaultGitHubAPIURL
+}
+
+// GistIDFromURL returns the ID of the gist at a gist.github.com, raw or API URL, or of a
+// bare ID
+func GistIDFromURL(raw string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "/") {
+		return raw, isGistID(raw)
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "", false
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	var id string
+	switch {
+	case len(parts) >= 2 && parts[0] == "gists":
+		// api.github.com/gists/<id>
+		id = parts[1]
+	case len(parts) == 1:
+		// gist.github.com/<id>
+		id = parts[0]
+	default:
+		// gist.github.com/<user>/<id>, optionally with a revision or raw file after it
+		id = parts[1]
+	}
+	id = strings.TrimSuffix(id, ".git")
+	return id, isGistID(id)
+}
+
+// isGistID reports whether s looks like a gist ID: hexadecimal, or digits for old gists
+func isGistID(s string) bool {
+	if s == "" || len(s) > 64 {
+		return false
+	}
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Fetch returns the snippets of the gists with ids or, with no ids, of every gist of the
+// token's account up to MaxGists. Each file of a gist becomes a snippet labelled with its
+// file name, in the language GitHub detected (or guessed from its extension), tagged
+// with the #hashtags of the gist's description.
+func (c *GistClient) Fetch(ctx context.Context, ids []string) ([]Snippet, error) {
+	var gists []gist
+	if len(ids) == 0 {
+		listed, err := c.listGists(ctx)
+		if err != nil {
+			return nil, err
+		}
+		gists = listed
+	} else {
+		if err := c.reserve(len(ids)); err != nil {
+			return nil, err
+		}
+		gists = make([]gist, len(ids))
+		err := forEach(len(ids), func(i int) error {
+			return c.getJSON(ctx, "/gists/"+url.PathEscape(ids[i]), &gists[i])
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Listings carry no file contents and large files are truncated; their raw URLs
+	// aren't held to the API's rate limit
+	type download struct {
+		gist    int
+		file    string
+		content string
+	}
+	var downloads []download
+	for i, g := range gists {
+		for name, f := range g.Files {
+			if f.Content == nil || f.Truncated {
+				downloads = append(downloads, download{gist: i, file: name})
+			}
+		}
+	}
+	err := forEach(len(downloads), func(i int) error {
+		d := &downloads[i]
+		content, err := c.download(ctx, gists[d.gist].Files[d.file].RawURL)
+		if err != nil {
+			return fmt.Errorf("download %s of gist %s: %w", d.file, gists[d.gist].ID, err)
+		}
+		d.content = content
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range downloads {
+		f := gists[d.gist].Files[d.file]
+		f.Content = &d.content
+		gists[d.gist].Files[d.file] = f
+	}
+
+	snippets := make([]Snippet, 0, len(gists))
+	for _, g := range gists {
+		snippets = append(snippets, gistSnippets(g)...)
+	}
+	return snippets, nil
+}
+
+// gistSnippets converts each file of a gist to a snippet, in file name order
+func gistSnippets(g gist) []Snippet {
+	var tags []string
+	for _, match := range gistHashtag.FindAllStringSubmatch(g.Description, -1) {
+		tags = append(tags, match[1])
+	}
+
+	names := make([]string, 0, len(g.Files))
+	for name := range g.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	snippets := make([]Snippet, 0, len(names))
+	for _, name := range names {
+		f := g.Files[name]
+		label := f.Filename
+		if label == "" {
+			label = name
+		}
+		snippet := Snippet{Label: label, Language: gistLanguage(label, f.Language), Tags: tags}
+		if f.Content != nil {
+			snippet.Content = *f.Content
+		}
+		snippets = append(snippets, snippet)
+	}
+	return snippets
+}
+
+// gistLanguage maps the language GitHub detected for a file, such as "Go" or
+// "Objective-C++", to a snippet language, falling back to the file's extension
+func gistLanguage(filename string, detected *string) string {
+	if detected != nil && *detected != "" {
+		return strings.ReplaceAll(strings.ToLower(*detected), " ", "-")
+	}
+	return gistExtensionLanguages[strings.ToLower(path.Ext(filename))]
+}
+
+// listGists pages through the token's gists, up to MaxGists
+func (c *GistClient) listGists(ctx context.Context) ([]gist, error) {
+	if c.token == "" {
+		return nil, ErrGistSourceRequired
+	}
+	gists := make([]gist, 0)
+	for page := 1; len(gists) < MaxGists; page++ {
+		if err := c.reserve(1); err != nil {
+			return nil, err
+		}
+		var batch []gist
+		query := "?per_page=" + strconv.Itoa(gistsPerPage) + "&page=" + strconv.Itoa(page)
+		if err := c.getJSON(ctx, "/gists"+query, &batch); err != nil {
+			return nil, err
+		}
+		gists = append(gists, batch...)
+		if len(batch) < gistsPerPage {
+			break
+		}
+	}
+	if len(gists) > MaxGists {
+		gists = gists[:MaxGists]
+	}
+	return gists, nil
+}
+
+// reserve checks the last known rate limit leaves n requests, so an import doesn't start
+// what it can't finish
+func (c *GistClient) reserve(n int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.remaining >= 0 && c.remaining < n && time.Now().Before(c.reset) {
+		return &RateLimitError{Reset: c.reset}
+	}
+	return nil
+}
+
+// getJSON decodes the API response to GET path into v
+func (c *GistClient) getJSON(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	limited := c.track(resp)
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return json.NewDecoder(resp.Body).Decode(v)
+	case resp.StatusCode == http.StatusUnauthorized:
+		return ErrGitHubUnauthorized
+	case limited != nil && (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests):
+		return limited
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%w: %s", ErrGistNotFound, strings.TrimPrefix(path, "/gists/"))
+	}
+	return fmt.Errorf("GitHub API %s: status %d", path, resp.StatusCode)
+}
+
+// track records the rate limit headers of resp. It returns a RateLimitError when the
+// response says the limit is exhausted: no requests remaining, or a Retry-After.
+func (c *GistClient) track(resp *http.Response) *RateLimitError {
+	now := time.Now()
+	if retry, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		return &RateLimitError{Reset: now.Add(time.Duration(retry) * time.Second)}
+	}
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return nil
+	}
+	reset := now
+	if unix, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		reset = time.Unix(unix, 0)
+	}
+
+	c.mu.Lock()
+	c.remaining, c.reset = remaining, reset
+	c.mu.Unlock()
+	if remaining == 0 {
+		return &RateLimitError{Reset: reset}
+	}
+	return nil
+}
+
+// download returns the content of a gist file from its raw URL, cut at maxGistFileBytes
+func (c *GistClient) download(ctx context.Context, rawURL string) (string, error) {
+	if rawURL == "" {
+		return "", nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxGistFileBytes))
+	return string(data), err
+}
+
+// forEach calls fn for 0..n-1, gistFetchConcurrency at a time, and returns its first error
+func forEach(n int, fn func(i int) error) error {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	sem := make(chan struct{}, gistFetchConcurrency)
+	for i := 0; i < n; i++ {
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			break
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := fn(i); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	return firstErr
+}
@@ -0,0 +1,348 @@
+// Package importer reads other snippet managers' exports and creates the snippets they
+// contain. Each format is an Importer registered under its name; adding a format means
+// implementing Parse and registering it in init.
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"github.com/jheysaaz/snippy-backend/app/snippetquery"
+)
+
+// MaxUploadBytes caps the size of an export file
+const MaxUploadBytes = 20 << 20
+
+// Limits of CreateSnippetRequest that imported snippets are fitted to. Content is also
+// held to the size limit of the user's plan; maxContentBytes caps it whatever the plan.
+const (
+	maxLabelLength       = 255
+	maxShortcutLength    = 50
+	maxContentBytes      = 1 << 20 // 1 MiB
+	maxDescriptionLength = 10000
+	maxTags              = 20
+	maxTagLength         = 50
+)
+
+// Skip reasons
+const (
+	ReasonEmpty          = "empty content"
+	ReasonTooLong        = "content larger than 1 MiB"
+	ReasonContentLimit   = "content over your plan's size limit"
+	ReasonShortcutExists = "shortcut already exists"
+	ReasonQuota          = "plan quota reached"
+)
+
+// Shortcut conflict strategies: an imported snippet whose shortcut the user already has
+// is skipped, imported under a free shortcut, or replaces the existing snippet
+const (
+	ConflictSkip      = "skip"
+	ConflictRename    = "rename"
+	ConflictOverwrite = "overwrite"
+)
+
+var (
+	// ErrInvalidExport is returned (wrapped) when an export can't be read in its format
+	ErrInvalidExport = errors.New("invalid export file")
+	// ErrInvalidConflict is returned for a conflict strategy other than skip, rename or overwrite
+	ErrInvalidConflict = errors.New("conflict must be one of: skip, rename, overwrite")
+)
+
+// Snippet is a snippet read from an export, before it is fitted to Snippy's limits
+type Snippet struct {
+	Label       string
+	Shortcut    string
+	Content     string
+	Language    string
+	Description string
+	Tags        []string
+}
+
+// Options control how an export is imported
+type Options struct {
+	// Conflict is the strategy for shortcuts the user already has; empty means ConflictSkip
+	Conflict string
+	// DryRun previews the import without writing anything
+	DryRun bool
+}
+
+// Importer reads one export format
+type Importer interface {
+	// Format is the name clients select the importer by
+	Format() string
+	// Parse returns the snippets in an export file. Errors about the file's contents
+	// wrap ErrInvalidExport.
+	Parse(data []byte) ([]Snippet, error)
+}
+
+var importers = map[string]Importer{}
+
+// Register makes an importer available under its format name. It panics if the name is
+// already taken.
+func Register(imp Importer) {
+	if _, exists := importers[imp.Format()]; exists {
+		panic("importer: format " + imp.Format() + " registered twice")
+	}
+	importers[imp.Format()] = imp
+}
+
+// Lookup returns the importer for format
+func Lookup(format string) (Importer, bool) {
+	imp, ok := importers[strings.ToLower(format)]
+	return imp, ok
+}
+
+// Formats lists the registered format names, sorted
+func Formats() []string {
+	formats := make([]string, 0, len(importers))
+	for format := range importers {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+func init() {
+	Register(snippetsLab{})
+	Register(dash{})
+	Register(lepton{})
+	Register(snippy{})
+}
+
+// invalidExport wraps ErrInvalidExport with what was wrong
+func invalidExport(format string, err error) error {
+	return fmt.Errorf("%w: %s: %v", ErrInvalidExport, format, err)
+}
+
+// Skipped is an exported snippet that was not imported
+type Skipped struct {
+	Label    string `json:"label"`
+	Shortcut string `json:"shortcut"`
+	Reason   string `json:"reason"`
+}
+
+// Conflict is an exported snippet whose shortcut the user already has, or that an earlier
+// snippet of the export took, and how it was resolved
+type Conflict struct {
+	Label    string `json:"label"`
+	Shortcut string `json:"shortcut"`
+	// Resolution is the strategy applied: skip, rename or overwrite
+	Resolution string `json:"resolution"`
+	// NewShortcut is the shortcut a renamed snippet was imported under
+	NewShortcut string `json:"newShortcut,omitempty"`
+	// ExistingID is the user's snippet with the shortcut; 0 when it's another snippet of
+	// the export
+	ExistingID int64 `json:"existingId,omitempty"`
+}
+
+// Result reports what an import did, or would do in a dry run
+type Result struct {
+	Skipped   []Skipped  `json:"skipped"`
+	Conflicts []Conflict `json:"conflicts"`
+	Imported  int        `json:"imported"`
+	// Overwritten counts the existing snippets replaced by imported ones
+	Overwritten int `json:"overwritten"`
+	// QuotaReached is set when the plan's snippet or storage quota stopped the import
+	QuotaReached bool `json:"quotaReached"`
+	// DryRun is set when nothing was written
+	DryRun bool `json:"dryRun"`
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// shortcutFrom turns s into a shortcut: lowercase with runs of spaces and punctuation
+// replaced by a dash
+func shortcutFrom(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+			continue
+		}
+		dash = true
+	}
+	return truncate(b.String(), maxShortcutLength)
+}
+
+// Normalize fits an exported snippet to Snippy's limits: the label falls back to the
+// shortcut or the content's first line, the shortcut is derived from the label when
+// missing and never contains spaces, and tags are deduplicated and capped. It returns a
+// skip reason when the snippet can't be imported.
+func Normalize(s Snippet) (models.CreateSnippetRequest, string) {
+	content := strings.TrimRight(s.Content, "\r\n")
+	if strings.TrimSpace(content) == "" {
+		return models.CreateSnippetRequest{}, ReasonEmpty
+	}
+	if len(content) > maxContentBytes {
+		return models.CreateSnippetRequest{}, ReasonTooLong
+	}
+
+	shortcut := strings.Join(strings.Fields(s.Shortcut), "-")
+	label := strings.TrimSpace(s.Label)
+	if label == "" {
+		label = shortcut
+	}
+	if label == "" {
+		label, _, _ = strings.Cut(strings.TrimSpace(content), "\n")
+	}
+	if shortcut == "" {
+		shortcut = shortcutFrom(label)
+	}
+	if shortcut == "" {
+		shortcut = "snippet"
+	}
+
+	tags := make([]string, 0, len(s.Tags))
+	seen := make(map[string]bool, len(s.Tags))
+	for _, tag := range s.Tags {
+		tag = truncate(strings.TrimSpace(tag), maxTagLength)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+		if len(tags) == maxTags {
+			break
+		}
+	}
+
+	req := models.CreateSnippetRequest{
+		Label:    truncate(strings.TrimSpace(label), maxLabelLength),
+		Shortcut: truncate(shortcut, maxShortcutLength),
+		Content:  content,
+		Tags:     tags,
+	}
+	if language := strings.TrimSpace(s.Language); language != "" {
+		req.Language = &language
+	}
+	if description := strings.TrimSpace(s.Description); description != "" {
+		description = truncate(description, maxDescriptionLength)
+		req.Description = &description
+	}
+	return req, ""
+}
+
+// ValidConflict reports whether conflict is a conflict strategy; "" is ConflictSkip
+func ValidConflict(conflict string) bool {
+	switch conflict {
+	case "", ConflictSkip, ConflictRename, ConflictOverwrite:
+		return true
+	}
+	return false
+}
+
+// freeShortcut returns shortcut with the first numeric suffix (-2, -3, ...) that isn't
+// taken, shortened so it stays within the shortcut length limit
+func freeShortcut(shortcut string, taken func(string) bool) string {
+	for n := 2; ; n++ {
+		suffix := "-" + strconv.Itoa(n)
+		candidate := truncate(shortcut, maxShortcutLength-len(suffix)) + suffix
+		if !taken(candidate) {
+			return candidate
+		}
+	}
+}
+
+// Import creates the user's snippets in store from an export. Snippets whose shortcut
+// the user already has are resolved by opts.Conflict: skipped (the default, so importing
+// a file twice doesn't duplicate it), imported under the shortcut with a numeric suffix,
+// or written over the existing snippet. A shortcut repeated within the export is taken by its
+// first snippet; later ones are renamed under ConflictRename and skipped otherwise. The
+// import stops when the user's plan quota is full; the remaining snippets are reported as
+// skipped. Everything is written in a single transaction with multi-row inserts, so a
+// large export takes a few round trips, and opts.DryRun rolls it back to preview the
+// result.
+func Import(ctx context.Context, store *models.Store, userID, originSessionID string, snippets []Snippet, opts Options) (*Result, error) {
+	if !ValidConflict(opts.Conflict) {
+		return nil, ErrInvalidConflict
+	}
+	existing, err := store.ListUserSnippets(ctx, userID, snippetquery.Filter{IncludeArchived: true})
+	if err != nil {
+		return nil, err
+	}
+	plan, err := store.GetUserPlan(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	quotas := models.LoadQuotas()
+	// shortcuts maps the user's shortcuts to their snippets, and those taken by the
+	// export to 0
+	shortcuts := make(map[string]int64, len(existing))
+	for _, s := range existing {
+		shortcuts[s.Shortcut] = s.ID
+	}
+	taken := func(shortcut string) bool {
+		_, ok := shortcuts[shortcut]
+		return ok
+	}
+
+	result := &Result{Skipped: make([]Skipped, 0), Conflicts: make([]Conflict, 0), DryRun: opts.DryRun}
+	reqs := make([]models.CreateSnippetRequest, 0, len(snippets))
+	overwrites := make([]models.SnippetOverwrite, 0)
+	for _, s := range snippets {
+		req, reason := Normalize(s)
+		if reason == "" && quotas.CheckContent(plan, int64(len(req.Content))) != nil {
+			reason = ReasonContentLimit
+		}
+		if reason == "" && taken(req.Shortcut) {
+			existingID := shortcuts[req.Shortcut]
+			conflict := Conflict{Label: req.Label, Shortcut: req.Shortcut, ExistingID: existingID, Resolution: ConflictSkip}
+			switch {
+			case opts.Conflict == ConflictRename:
+				conflict.Resolution = ConflictRename
+				conflict.NewShortcut = freeShortcut(req.Shortcut, taken)
+				req.Shortcut = conflict.NewShortcut
+			case opts.Conflict == ConflictOverwrite && existingID != 0:
+				conflict.Resolution = ConflictOverwrite
+				overwrites = append(overwrites, models.SnippetOverwrite{ID: existingID, Request: req})
+				// Later snippets with the shortcut conflict with this one, not the existing
+				shortcuts[req.Shortcut] = 0
+				result.Conflicts = append(result.Conflicts, conflict)
+				continue
+			default:
+				reason = ReasonShortcutExists
+			}
+			result.Conflicts = append(result.Conflicts, conflict)
+		}
+		if reason != "" {
+			label, shortcut := req.Label, req.Shortcut
+			if label == "" {
+				label, shortcut = s.Label, s.Shortcut
+			}
+			result.Skipped = append(result.Skipped, Skipped{Label: label, Shortcut: shortcut, Reason: reason})
+			continue
+		}
+		shortcuts[req.Shortcut] = 0
+		reqs = append(reqs, req)
+	}
+
+	created, overwritten, err := store.ImportSnippets(ctx, userID, originSessionID, reqs, overwrites, opts.DryRun)
+	if err != nil {
+		return result, err
+	}
+	result.Imported = len(created)
+	result.Overwritten = len(overwritten)
+	for _, req := range reqs[len(created):] {
+		result.QuotaReached = true
+		result.Skipped = append(result.Skipped, Skipped{Label: req.Label, Shortcut: req.Shortcut, Reason: ReasonQuota})
+	}
+	return result, nil
+}
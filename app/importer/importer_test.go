@@ -0,0 +1,399 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormats(t *testing.T) {
+	if got, want := Formats(), []string{"dash", "lepton", "snippetslab", "snippy"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Formats() = %v, want %v", got, want)
+	}
+	if imp, ok := Lookup("SnippetsLab"); !ok || imp.Format() != "snippetslab" {
+		t.Errorf("Lookup(SnippetsLab) = %v, %v; want the snippetslab importer", imp, ok)
+	}
+	if _, ok := Lookup("evernote"); ok {
+		t.Error("Lookup(evernote) found an importer")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name       string
+		in         Snippet
+		wantLabel  string
+		wantShort  string
+		wantTags   []string
+		wantReason string
+	}{
+		{
+			name:      "Shortcut from label",
+			in:        Snippet{Label: " Email signature! ", Content: "Best,\nMe\n"},
+			wantLabel: "Email signature!",
+			wantShort: "email-signature",
+			wantTags:  []string{},
+		},
+		{
+			name:      "Spaces in shortcut",
+			in:        Snippet{Shortcut: "my sig", Content: "x", Tags: []string{"a", " a ", "", "b"}},
+			wantLabel: "my-sig",
+			wantShort: "my-sig",
+			wantTags:  []string{"a", "b"},
+		},
+		{
+			name:      "Label from content",
+			in:        Snippet{Content: "\n  first line\nsecond"},
+			wantLabel: "first line",
+			wantShort: "first-line",
+			wantTags:  []string{},
+		},
+		{
+			name:      "Symbols only",
+			in:        Snippet{Label: "???", Content: "x"},
+			wantLabel: "???",
+			wantShort: "snippet",
+			wantTags:  []string{},
+		},
+		{name: "Empty", in: Snippet{Label: "Empty", Content: " \n"}, wantReason: ReasonEmpty},
+		{name: "Too long", in: Snippet{Label: "Big", Content: strings.Repeat("x", maxContentBytes+1)}, wantReason: ReasonTooLong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, reason := Normalize(tt.in)
+			if reason != tt.wantReason {
+				t.Fatalf("reason = %q, want %q", reason, tt.wantReason)
+			}
+			if reason != "" {
+				return
+			}
+			if req.Label != tt.wantLabel || req.Shortcut != tt.wantShort || !reflect.DeepEqual(req.Tags, tt.wantTags) {
+				t.Errorf("Normalize = label %q, shortcut %q, tags %v; want %q, %q, %v",
+					req.Label, req.Shortcut, req.Tags, tt.wantLabel, tt.wantShort, tt.wantTags)
+			}
+		})
+	}
+
+	if req, _ := Normalize(Snippet{Label: "Go", Content: "x", Language: " go "}); req.Language == nil || *req.Language != "go" {
+		t.Errorf("language = %v, want go", req.Language)
+	}
+	if req, _ := Normalize(Snippet{Label: "Go", Content: "x", Description: " \n "}); req.Description != nil {
+		t.Errorf("blank description = %q, want none", *req.Description)
+	}
+
+	long, _ := Normalize(Snippet{Label: strings.Repeat("é", 300), Content: "x", Tags: make([]string, 0)})
+	if len([]rune(long.Label)) != maxLabelLength || len([]rune(long.Shortcut)) != maxShortcutLength {
+		t.Errorf("long label = %d, shortcut = %d runes; want %d and %d",
+			len([]rune(long.Label)), len([]rune(long.Shortcut)), maxLabelLength, maxShortcutLength)
+	}
+}
+
+func TestSnippetsLabParse(t *testing.T) {
+	export := `{"contents": {
+		"folders": [{"uuid": "F1", "title": "Shell"}],
+		"tags": [{"uuid": "T1", "title": "git"}],
+		"snippets": [
+			{"title": "Undo commit", "folder": "F1", "tags": ["T1", "missing"],
+			 "fragments": [{"title": "Fragment", "content": "git reset HEAD~1", "language": "Bash"}]},
+			{"title": "Docker", "folder": null, "tags": [],
+			 "fragments": [{"title": "Build", "content": "docker build ."}, {"title": "Run", "content": "docker run"}]}
+		]
+	}}`
+
+	got, err := snippetsLab{}.Parse([]byte(export))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want := []Snippet{
+		{Label: "Undo commit", Content: "git reset HEAD~1", Tags: []string{"Shell", "git"}},
+		{Label: "Docker - Build", Content: "docker build ."},
+		{Label: "Docker - Run", Content: "docker run"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse = %+v, want %+v", got, want)
+	}
+
+	if _, err := (snippetsLab{}).Parse([]byte("not json")); !errors.Is(err, ErrInvalidExport) {
+		t.Errorf("Parse(not json) = %v, want ErrInvalidExport", err)
+	}
+}
+
+func TestDashParse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Snippets.dash")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE snippets (sid INTEGER PRIMARY KEY, title TEXT, body TEXT, syntax VARCHAR(20), usageCount INTEGER)`,
+		`CREATE TABLE tags (tid INTEGER PRIMARY KEY, tag TEXT UNIQUE)`,
+		`CREATE TABLE tagsIndex (tid INTEGER, sid INTEGER)`,
+		`INSERT INTO snippets VALUES (1, 'sig', 'Best, __name__', 'Standard', 3), (2, 'todo', '// TODO: @cursor', 'Go', 0)`,
+		`INSERT INTO tags VALUES (1, 'work'), (2, 'email')`,
+		`INSERT INTO tagsIndex VALUES (1, 1), (2, 1)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	got, err := dash{}.Parse(data)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want := []Snippet{
+		{Label: "sig", Shortcut: "sig", Content: "Best, __name__", Tags: []string{"email", "work"}},
+		{Label: "todo", Shortcut: "todo", Content: "// TODO: @cursor"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse = %+v, want %+v", got, want)
+	}
+
+	if _, err := (dash{}).Parse([]byte("{}")); !errors.Is(err, ErrInvalidExport) {
+		t.Errorf("Parse(json) = %v, want ErrInvalidExport", err)
+	}
+}
+
+func TestLeptonParse(t *testing.T) {
+	gists := `[
+		{"description": "[Greeting] Say hello #tags: email, work",
+		 "files": {"hello.txt": {"filename": "hello.txt", "content": "Hello!"}}},
+		{"description": "Plain description",
+		 "files": {"b.sh": {"content": "echo b"}, "a.sh": {"content": "echo a"}}},
+		{"description": "",
+		 "files": {"notes.md": {"filename": "notes.md", "content": "# Notes"}}}
+	]`
+
+	got, err := lepton{}.Parse([]byte(gists))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want := []Snippet{
+		{Label: "Greeting", Content: "Hello!", Tags: []string{"email", "work"}},
+		{Label: "Plain description - a.sh", Content: "echo a"},
+		{Label: "Plain description - b.sh", Content: "echo b"},
+		{Label: "notes.md", Content: "# Notes"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse = %+v, want %+v", got, want)
+	}
+
+	single, err := lepton{}.Parse([]byte(`{"description": "[One]", "files": {"x": {"content": "1"}}}`))
+	if err != nil || len(single) != 1 || single[0].Label != "One" {
+		t.Errorf("Parse(single gist) = %+v, %v; want one snippet labelled One", single, err)
+	}
+
+	if _, err := (lepton{}).Parse([]byte("plain text")); !errors.Is(err, ErrInvalidExport) {
+		t.Errorf("Parse(text) = %v, want ErrInvalidExport", err)
+	}
+}
+
+func TestSnippyParse(t *testing.T) {
+	want := []Snippet{
+		{Label: "Greeting", Shortcut: "hi", Content: "Hello!", Language: "markdown", Tags: []string{"email"}},
+		{Label: "Bye", Shortcut: "bye", Content: "Bye!", Description: "Sign-off for *emails*"},
+	}
+	archive := `{"exportedAt": "2024-03-01T12:00:00Z", "snippets": [
+		{"id": 1, "label": "Greeting", "shortcut": "hi", "content": "Hello!", "language": "markdown", "tags": ["email"], "history": []},
+		{"id": 2, "label": "Bye", "shortcut": "bye", "content": "Bye!", "description": "Sign-off for *emails*", "history": []}
+	], "tags": [{"name": "email", "count": 1}]}`
+	ndjson := `{"id": 1, "label": "Greeting", "shortcut": "hi", "content": "Hello!", "language": "markdown", "tags": ["email"]}
+
+{"id": 2, "label": "Bye", "shortcut": "bye", "content": "Bye!", "description": "Sign-off for *emails*"}
+`
+	array := `[{"label": "Greeting", "shortcut": "hi", "content": "Hello!", "language": "markdown", "tags": ["email"]},
+		{"label": "Bye", "shortcut": "bye", "content": "Bye!", "description": "Sign-off for *emails*"}]`
+
+	var zipped bytes.Buffer
+	zw := zip.NewWriter(&zipped)
+	fw, err := zw.Create("snippy-export.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fw.Write([]byte(archive)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, data := range map[string][]byte{
+		"Archive": []byte(archive),
+		"NDJSON":  []byte(ndjson),
+		"Array":   []byte(array),
+		"Zip":     zipped.Bytes(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := snippy{}.Parse(data)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Parse = %+v, want %+v", got, want)
+			}
+		})
+	}
+
+	for _, data := range []string{"plain text", "{not json", `{"label": "a"}` + "\nnot json"} {
+		if _, err := (snippy{}).Parse([]byte(data)); !errors.Is(err, ErrInvalidExport) {
+			t.Errorf("Parse(%q) = %v, want ErrInvalidExport", data, err)
+		}
+	}
+}
+
+func TestFreeShortcut(t *testing.T) {
+	taken := map[string]bool{"sig": true, "sig-2": true}
+	if got := freeShortcut("sig", func(s string) bool { return taken[s] }); got != "sig-3" {
+		t.Errorf("freeShortcut(sig) = %q, want sig-3", got)
+	}
+	long := strings.Repeat("x", maxShortcutLength)
+	got := freeShortcut(long, func(string) bool { return false })
+	if len(got) != maxShortcutLength || !strings.HasSuffix(got, "-2") {
+		t.Errorf("freeShortcut(long) = %q, want %d characters ending in -2", got, maxShortcutLength)
+	}
+}
+
+func TestGistIDFromURL(t *testing.T) {
+	tests := []struct {
+		url    string
+		wantID string
+		wantOK bool
+	}{
+		{url: "https://gist.github.com/octocat/aa5a315d61ae9438b18d", wantID: "aa5a315d61ae9438b18d", wantOK: true},
+		{url: "https://gist.github.com/aa5a315d61ae9438b18d", wantID: "aa5a315d61ae9438b18d", wantOK: true},
+		{url: "https://gist.github.com/aa5a315d61ae9438b18d.git", wantID: "aa5a315d61ae9438b18d", wantOK: true},
+		{url: "https://api.github.com/gists/aa5a315d61ae9438b18d", wantID: "aa5a315d61ae9438b18d", wantOK: true},
+		{url: "https://gist.githubusercontent.com/octocat/aa5a315d61ae9438b18d/raw/hello.sh", wantID: "aa5a315d61ae9438b18d", wantOK: true},
+		{url: " aa5a315d61ae9438b18d ", wantID: "aa5a315d61ae9438b18d", wantOK: true},
+		{url: "https://gist.github.com/octocat"},
+		{url: "not a gist"},
+	}
+	for _, tt := range tests {
+		id, ok := GistIDFromURL(tt.url)
+		if ok != tt.wantOK || (ok && id != tt.wantID) {
+			t.Errorf("GistIDFromURL(%q) = %q, %v; want %q, %v", tt.url, id, ok, tt.wantID, tt.wantOK)
+		}
+	}
+}
+
+func TestGistLanguage(t *testing.T) {
+	detected := "Objective-C++"
+	if got := gistLanguage("main.mm", &detected); got != "objective-c++" {
+		t.Errorf("detected language = %q, want objective-c++", got)
+	}
+	notebook := "Jupyter Notebook"
+	if got := gistLanguage("a.ipynb", &notebook); got != "jupyter-notebook" {
+		t.Errorf("language with a space = %q, want jupyter-notebook", got)
+	}
+	if got := gistLanguage("deploy.SH", nil); got != "bash" {
+		t.Errorf("language from extension = %q, want bash", got)
+	}
+	if got := gistLanguage("README", nil); got != "" {
+		t.Errorf("language without extension = %q, want none", got)
+	}
+}
+
+func TestGistClientFetch(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "10")
+		w.Header().Set("X-RateLimit-Reset", "4102444800")
+		switch r.URL.Path {
+		case "/gists":
+			if r.Header.Get("Authorization") != "Bearer ghp_test" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprintf(w, `[{"id": "abc1", "description": "Shell helpers #bash #ops", "files": {
+				"b.sh": {"filename": "b.sh", "language": "Shell", "raw_url": "%[1]s/raw/b.sh"},
+				"a.py": {"filename": "a.py", "language": "Python", "raw_url": "%[1]s/raw/a.py"}}}]`, server.URL)
+		case "/gists/abc2":
+			fmt.Fprint(w, `{"id": "abc2", "description": "", "files": {
+				"notes.md": {"filename": "notes.md", "language": null, "content": "# Notes"}}}`)
+		case "/raw/a.py":
+			fmt.Fprint(w, "print('a')")
+		case "/raw/b.sh":
+			fmt.Fprint(w, "echo b")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	listed, err := NewGistClient(server.URL, "ghp_test").Fetch(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Fetch(token): %v", err)
+	}
+	want := []Snippet{
+		{Label: "a.py", Content: "print('a')", Language: "python", Tags: []string{"bash", "ops"}},
+		{Label: "b.sh", Content: "echo b", Language: "shell", Tags: []string{"bash", "ops"}},
+	}
+	if !reflect.DeepEqual(listed, want) {
+		t.Errorf("Fetch(token) = %+v, want %+v", listed, want)
+	}
+
+	byURL, err := NewGistClient(server.URL, "").Fetch(context.Background(), []string{"abc2"})
+	if err != nil {
+		t.Fatalf("Fetch(ids): %v", err)
+	}
+	if len(byURL) != 1 || byURL[0].Label != "notes.md" || byURL[0].Content != "# Notes" || byURL[0].Language != "markdown" {
+		t.Errorf("Fetch(ids) = %+v, want notes.md in markdown", byURL)
+	}
+
+	if _, err := NewGistClient(server.URL, "").Fetch(context.Background(), []string{"f00"}); !errors.Is(err, ErrGistNotFound) {
+		t.Errorf("Fetch(missing) = %v, want ErrGistNotFound", err)
+	}
+	if _, err := NewGistClient(server.URL, "wrong").Fetch(context.Background(), nil); !errors.Is(err, ErrGitHubUnauthorized) {
+		t.Errorf("Fetch(wrong token) = %v, want ErrGitHubUnauthorized", err)
+	}
+	if _, err := NewGistClient(server.URL, "").Fetch(context.Background(), nil); !errors.Is(err, ErrGistSourceRequired) {
+		t.Errorf("Fetch(no token) = %v, want ErrGistSourceRequired", err)
+	}
+
+	// Having seen 10 requests left, the client refuses to start 11 gist fetches
+	client := NewGistClient(server.URL, "")
+	if _, err := client.Fetch(context.Background(), []string{"abc2"}); err != nil {
+		t.Fatalf("Fetch(ids): %v", err)
+	}
+	ids := make([]string, 11)
+	for i := range ids {
+		ids[i] = "abc2"
+	}
+	var rateLimited *RateLimitError
+	if _, err := client.Fetch(context.Background(), ids); !errors.As(err, &rateLimited) {
+		t.Errorf("Fetch(11 ids) = %v, want a RateLimitError", err)
+	}
+}
+
+func TestGistClientRateLimited(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", "4102444800")
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	_, err := NewGistClient(server.URL, "").Fetch(context.Background(), []string{"abc"})
+	var rateLimited *RateLimitError
+	if !errors.As(err, &rateLimited) || rateLimited.Reset.Unix() != 4102444800 {
+		t.Errorf("Fetch = %v, want a RateLimitError until the reset", err)
+	}
+}
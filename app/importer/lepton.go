@@ -0,0 +1,98 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// leptonDescription splits Lepton's gist description, "[title] description #tags: a, b",
+// into its title and tags
+var leptonDescription = regexp.MustCompile(`(?s)^\s*(?:\[(.*?)\])?(.*?)(?:#tags:(.*))?$`)
+
+// lepton reads the GitHub gists Lepton keeps snippets in: a JSON array of gists, or a
+// single gist, as returned by the GitHub API with file contents (GET /gists/{id}). Each
+// file of a gist becomes a snippet.
+type lepton struct{}
+
+type leptonGist struct {
+	Files       map[string]leptonFile `json:"files"`
+	Description string                `json:"description"`
+}
+
+type leptonFile struct {
+	Filename string `json:"filename"`
+	Content  string `json:"content"`
+}
+
+func (lepton) Format() string { return "lepton" }
+
+func (lepton) Parse(data []byte) ([]Snippet, error) {
+	var gists []leptonGist
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		if err := json.Unmarshal(trimmed, &gists); err != nil {
+			return nil, invalidExport("lepton", err)
+		}
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		var gist leptonGist
+		if err := json.Unmarshal(trimmed, &gist); err != nil {
+			return nil, invalidExport("lepton", err)
+		}
+		gists = []leptonGist{gist}
+	default:
+		return nil, invalidExport("lepton", errors.New("expected a gist or an array of gists"))
+	}
+
+	snippets := make([]Snippet, 0, len(gists))
+	for _, gist := range gists {
+		title, tags := parseLeptonDescription(gist.Description)
+
+		names := make([]string, 0, len(gist.Files))
+		for name := range gist.Files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			file := gist.Files[name]
+			if file.Filename == "" {
+				file.Filename = name
+			}
+			label := title
+			switch {
+			case label == "":
+				label = file.Filename
+			case len(names) > 1:
+				label += " - " + file.Filename
+			}
+			snippets = append(snippets, Snippet{Label: label, Content: file.Content, Tags: tags})
+		}
+	}
+	return snippets, nil
+}
+
+// parseLeptonDescription returns the title (or the plain description) and the tags of a
+// Lepton gist description
+func parseLeptonDescription(description string) (string, []string) {
+	match := leptonDescription.FindStringSubmatch(description)
+	if match == nil {
+		return strings.TrimSpace(description), nil
+	}
+
+	title := strings.TrimSpace(match[1])
+	if title == "" {
+		title = strings.TrimSpace(match[2])
+	}
+	var tags []string
+	for _, tag := range strings.Split(match[3], ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return title, tags
+}
@@ -0,0 +1,74 @@
+package importer
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// snippetsLab reads SnippetsLab's JSON export (File > Export > JSON). Folders and tags
+// are referenced by UUID; both become tags. A snippet's fragments are imported as
+// separate snippets.
+type snippetsLab struct{}
+
+type snippetsLabExport struct {
+	Contents struct {
+		Folders []struct {
+			UUID  string `json:"uuid"`
+			Title string `json:"title"`
+		} `json:"folders"`
+		Tags []struct {
+			UUID  string `json:"uuid"`
+			Title string `json:"title"`
+		} `json:"tags"`
+		Snippets []struct {
+			Folder    *string  `json:"folder"`
+			Title     string   `json:"title"`
+			Tags      []string `json:"tags"`
+			Fragments []struct {
+				Title   string `json:"title"`
+				Content string `json:"content"`
+			} `json:"fragments"`
+		} `json:"snippets"`
+	} `json:"contents"`
+}
+
+func (snippetsLab) Format() string { return "snippetslab" }
+
+func (snippetsLab) Parse(data []byte) ([]Snippet, error) {
+	var export snippetsLabExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, invalidExport("snippetslab", err)
+	}
+
+	folders := make(map[string]string, len(export.Contents.Folders))
+	for _, f := range export.Contents.Folders {
+		folders[f.UUID] = f.Title
+	}
+	tagNames := make(map[string]string, len(export.Contents.Tags))
+	for _, t := range export.Contents.Tags {
+		tagNames[t.UUID] = t.Title
+	}
+
+	snippets := make([]Snippet, 0, len(export.Contents.Snippets))
+	for _, s := range export.Contents.Snippets {
+		var tags []string
+		if s.Folder != nil && folders[*s.Folder] != "" {
+			tags = append(tags, folders[*s.Folder])
+		}
+		for _, uuid := range s.Tags {
+			if name := tagNames[uuid]; name != "" {
+				tags = append(tags, name)
+			}
+		}
+
+		for _, fragment := range s.Fragments {
+			label := s.Title
+			// With several fragments, each label adds the fragment title to tell them apart
+			if len(s.Fragments) > 1 && strings.TrimSpace(fragment.Title) != "" {
+				label += " - " + strings.TrimSpace(fragment.Title)
+			}
+			snippets = append(snippets, Snippet{Label: label, Content: fragment.Content, Tags: tags})
+		}
+	}
+	return snippets, nil
+}
@@ -0,0 +1,141 @@
+package importer
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"path"
+)
+
+// maxSnippyUnzippedBytes caps how much of a zipped Snippy archive is read, so a small zip
+// can't expand without bound
+const maxSnippyUnzippedBytes = 4 * MaxUploadBytes
+
+// zipHeader starts every zip file
+var zipHeader = []byte("PK\x03\x04")
+
+// snippy reads Snippy's own exports: the JSON archive of GET /snippets/export, zipped or
+// not, and the NDJSON of GET /users/me/export. A plain JSON array of snippets is also
+// accepted. Labels, shortcuts, content, tags, languages and descriptions are imported;
+// version history is not.
+type snippy struct{}
+
+type snippySnippet struct {
+	Language    *string  `json:"language"`
+	Description *string  `json:"description"`
+	Label       string   `json:"label"`
+	Shortcut    string   `json:"shortcut"`
+	Content     string   `json:"content"`
+	Tags        []string `json:"tags"`
+}
+
+func (snippy) Format() string { return "snippy" }
+
+func (snippy) Parse(data []byte) ([]Snippet, error) {
+	if bytes.HasPrefix(data, zipHeader) {
+		unzipped, err := unzipSnippyArchive(data)
+		if err != nil {
+			return nil, invalidExport("snippy", err)
+		}
+		data = unzipped
+	}
+
+	var exported []snippySnippet
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		if err := json.Unmarshal(trimmed, &exported); err != nil {
+			return nil, invalidExport("snippy", err)
+		}
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		var err error
+		if exported, err = parseSnippyObjects(trimmed); err != nil {
+			return nil, invalidExport("snippy", err)
+		}
+	default:
+		return nil, invalidExport("snippy", errors.New("expected a Snippy JSON or NDJSON export"))
+	}
+
+	snippets := make([]Snippet, 0, len(exported))
+	for _, s := range exported {
+		snippet := Snippet{Label: s.Label, Shortcut: s.Shortcut, Content: s.Content, Tags: s.Tags}
+		if s.Language != nil {
+			snippet.Language = *s.Language
+		}
+		if s.Description != nil {
+			snippet.Description = *s.Description
+		}
+		snippets = append(snippets, snippet)
+	}
+	return snippets, nil
+}
+
+// parseSnippyObjects reads a JSON archive, whose snippets are under "snippets", or NDJSON
+// with one snippet per line
+func parseSnippyObjects(data []byte) ([]snippySnippet, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var first json.RawMessage
+	if err := dec.Decode(&first); err != nil {
+		return nil, err
+	}
+	var archive struct {
+		Snippets *[]snippySnippet `json:"snippets"`
+	}
+	if err := json.Unmarshal(first, &archive); err != nil {
+		return nil, err
+	}
+	if archive.Snippets != nil {
+		return *archive.Snippets, nil
+	}
+
+	snippets := make([]snippySnippet, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxUploadBytes)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var s snippySnippet
+		if err := json.Unmarshal(line, &s); err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, s)
+	}
+	return snippets, scanner.Err()
+}
+
+// unzipSnippyArchive returns the first JSON file of a zipped archive
+func unzipSnippyArchive(data []byte) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range zr.File {
+		if path.Ext(f.Name) != ".json" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			if closeErr := rc.Close(); closeErr != nil {
+				log.Printf("error closing Snippy archive entry: %v", closeErr)
+			}
+		}()
+		unzipped, err := io.ReadAll(io.LimitReader(rc, maxSnippyUnzippedBytes+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(unzipped) > maxSnippyUnzippedBytes {
+			return nil, errors.New("archive too large once unzipped")
+		}
+		return unzipped, nil
+	}
+	return nil, errors.New("no JSON file in the zip")
+}
@@ -0,0 +1,327 @@
+// Package mailer sends transactional email over SMTP, rendered from HTML and text templates.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPort     = 587
+	defaultFrom     = "Snippy <no-reply@snippy.local>"
+	defaultAttempts = 3
+	baseRetryDelay  = 2 * time.Second
+	dialTimeout     = 10 * time.Second
+)
+
+// ErrNoRecipient is returned when a message has no To address
+var ErrNoRecipient = errors.New("mailer: message has no recipient")
+
+// Message is an email with a plain text body and an optional HTML alternative
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers email messages
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Config holds SMTP settings
+type Config struct {
+	Host     string
+	Username string
+	Password string
+	From     string
+	Port     int
+	Attempts int
+	// LogOnly logs messages instead of sending them (development)
+	LogOnly bool
+}
+
+// LoadConfig reads SMTP settings from the environment (SMTP_HOST, SMTP_PORT,
+// SMTP_USERNAME, SMTP_PASSWORD, SMTP_FROM, MAIL_MODE). MAIL_MODE=log, or an
+// unset SMTP_HOST, selects log-only mode.
+func LoadConfig() Config {
+	cfg := Config{
+		Host:     os.Getenv("SMTP_HOST"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+		Port:     defaultPort,
+		Attempts: defaultAttempts,
+	}
+	if cfg.From == "" {
+		cfg.From = defaultFrom
+	}
+	if raw := os.Getenv("SMTP_PORT"); raw != "" {
+		port, err := strconv.Atoi(raw)
+		if err != nil || port <= 0 {
+			log.Printf("Ignoring invalid SMTP_PORT %q, using %d", raw, defaultPort)
+		} else {
+			cfg.Port = port
+		}
+	}
+	cfg.LogOnly = os.Getenv("MAIL_MODE") == "log" || cfg.Host == ""
+	return cfg
+}
+
+// New returns a mailer for cfg: a LogMailer in log-only mode, otherwise an SMTP mailer with retry
+func New(cfg Config) Mailer {
+	if cfg.LogOnly {
+		return LogMailer{}
+	}
+	return &SMTPMailer{cfg: cfg, retryDelay: baseRetryDelay}
+}
+
+// LogMailer logs messages instead of sending them
+type LogMailer struct{}
+
+// Send logs the message recipient, subject and text body
+func (LogMailer) Send(_ context.Context, msg Message) error {
+	if msg.To == "" {
+		return ErrNoRecipient
+	}
+	log.Printf("Email (log only) to %s: %s\n%s", msg.To, msg.Subject, msg.Text)
+	return nil
+}
+
+// SMTPMailer sends messages through an SMTP server, using STARTTLS when offered
+type SMTPMailer struct {
+	cfg        Config
+	retryDelay time.Duration
+}
+
+// Send delivers msg, retrying transient failures with exponential backoff
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if msg.To == "" {
+		return ErrNoRecipient
+	}
+
+	data, err := BuildMessage(m.cfg.From, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	attempts := m.cfg.Attempts
+	if attempts <= 0 {
+		attempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		err = m.deliver(ctx, msg.To, data)
+		if err == nil || attempt >= attempts || !isTransient(err) {
+			return err
+		}
+
+		delay := m.retryDelay << (attempt - 1)
+		log.Printf("SMTP send to %s failed (attempt %d/%d), retrying in %v: %v", msg.To, attempt, attempts, delay, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Ping checks the SMTP server accepts connections and greets, without sending anything
+func (m *SMTPMailer) Ping(ctx context.Context) error {
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			_ = conn.Close()
+			return err
+		}
+	}
+
+	client, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	return client.Quit()
+}
+
+// deliver runs a single SMTP transaction
+func (m *SMTPMailer) deliver(ctx context.Context, to string, data []byte) error {
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			_ = conn.Close()
+			return err
+		}
+	}
+
+	client, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer func() {
+		if closeErr := client.Close(); closeErr != nil && !errors.Is(closeErr, net.ErrClosed) {
+			log.Printf("error closing SMTP connection: %v", closeErr)
+		}
+	}()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.cfg.Host, MinVersion: tls.VersionTLS12}); err != nil {
+			return err
+		}
+	}
+	if m.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)); err != nil {
+			return err
+		}
+	}
+
+	from, err := envelopeAddress(m.cfg.From)
+	if err != nil {
+		return err
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		_ = w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// isTransient reports whether a send error is worth retrying: network errors
+// and 4xx SMTP replies are, 5xx (permanent) replies are not
+func isTransient(err error) bool {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return protoErr.Code >= 400 && protoErr.Code < 500
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// envelopeAddress extracts the bare address from a "Name <addr>" From header
+func envelopeAddress(from string) (string, error) {
+	if i := strings.LastIndex(from, "<"); i >= 0 {
+		j := strings.LastIndex(from, ">")
+		if j < i {
+			return "", fmt.Errorf("mailer: invalid from address %q", from)
+		}
+		return from[i+1 : j], nil
+	}
+	return strings.TrimSpace(from), nil
+}
+
+// BuildMessage encodes msg as an RFC 5322 message. Messages with an HTML body
+// are sent as multipart/alternative with the text part first.
+func BuildMessage(from string, msg Message, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+
+	headers := []struct{ key, value string }{
+		{"From", from},
+		{"To", msg.To},
+		{"Subject", mime.QEncoding.Encode("utf-8", msg.Subject)},
+		{"Date", now.Format(time.RFC1123Z)},
+		{"Message-ID", messageID(from)},
+		{"MIME-Version", "1.0"},
+	}
+	for _, h := range headers {
+		fmt.Fprintf(&buf, "%s: %s\r\n", h.key, h.value)
+	}
+
+	if msg.HTML == "" {
+		buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQuotedPrintable(&buf, msg.Text); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", mw.Boundary())
+
+	parts := []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	}
+	for _, p := range parts {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(pw, p.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeQuotedPrintable writes body with quoted-printable encoding
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// messageID generates a unique Message-ID in the sender's domain
+func messageID(from string) string {
+	domain := "snippy.local"
+	if addr, err := envelopeAddress(from); err == nil {
+		if at := strings.LastIndex(addr, "@"); at >= 0 && at < len(addr)-1 {
+			domain = addr[at+1:]
+		}
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("<%d@%s>", time.Now().UnixNano(), domain)
+	}
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain)
+}
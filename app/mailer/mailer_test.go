@@ -0,0 +1,220 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("SMTP_HOST", "smtp.example.com")
+	t.Setenv("SMTP_PORT", "2525")
+	t.Setenv("SMTP_FROM", "")
+	t.Setenv("MAIL_MODE", "")
+
+	cfg := LoadConfig()
+	if cfg.Host != "smtp.example.com" || cfg.Port != 2525 {
+		t.Errorf("Expected smtp.example.com:2525, got %s:%d", cfg.Host, cfg.Port)
+	}
+	if cfg.From != defaultFrom {
+		t.Errorf("Expected default from address, got %q", cfg.From)
+	}
+	if cfg.LogOnly {
+		t.Error("Expected SMTP mode when SMTP_HOST is set")
+	}
+
+	t.Setenv("MAIL_MODE", "log")
+	if !LoadConfig().LogOnly {
+		t.Error("Expected log-only mode when MAIL_MODE=log")
+	}
+
+	t.Setenv("MAIL_MODE", "")
+	t.Setenv("SMTP_HOST", "")
+	if !LoadConfig().LogOnly {
+		t.Error("Expected log-only mode without SMTP_HOST")
+	}
+}
+
+func TestNewLogOnly(t *testing.T) {
+	if _, ok := New(Config{LogOnly: true}).(LogMailer); !ok {
+		t.Error("Expected LogMailer in log-only mode")
+	}
+	if err := (LogMailer{}).Send(context.Background(), Message{Subject: "hi"}); err != ErrNoRecipient {
+		t.Errorf("Expected ErrNoRecipient, got %v", err)
+	}
+}
+
+func TestBuildMessage(t *testing.T) {
+	now := time.Date(2024, 1, 8, 9, 0, 0, 0, time.UTC)
+
+	plain, err := BuildMessage("Snippy <no-reply@snippy.app>", Message{To: "a@example.com", Subject: "Hello", Text: "Body"}, now)
+	if err != nil {
+		t.Fatalf("BuildMessage failed: %v", err)
+	}
+	for _, want := range []string{"From: Snippy <no-reply@snippy.app>\r\n", "To: a@example.com\r\n", "Subject: Hello\r\n", "Content-Type: text/plain; charset=utf-8", "@snippy.app>"} {
+		if !strings.Contains(string(plain), want) {
+			t.Errorf("Expected plain message to contain %q", want)
+		}
+	}
+
+	alt, err := BuildMessage("no-reply@snippy.app", Message{To: "a@example.com", Subject: "Ünïcode", Text: "Body", HTML: "<p>Body</p>"}, now)
+	if err != nil {
+		t.Fatalf("BuildMessage failed: %v", err)
+	}
+	for _, want := range []string{"multipart/alternative", "text/plain; charset=utf-8", "text/html; charset=utf-8", "<p>Body</p>", "=?utf-8?q?"} {
+		if !strings.Contains(string(alt), want) {
+			t.Errorf("Expected multipart message to contain %q", want)
+		}
+	}
+}
+
+func TestEnvelopeAddress(t *testing.T) {
+	tests := map[string]string{
+		"Snippy <no-reply@snippy.app>": "no-reply@snippy.app",
+		"no-reply@snippy.app":          "no-reply@snippy.app",
+	}
+	for in, want := range tests {
+		got, err := envelopeAddress(in)
+		if err != nil || got != want {
+			t.Errorf("envelopeAddress(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := envelopeAddress("broken >x<"); err == nil {
+		t.Error("Expected error for malformed address")
+	}
+}
+
+func TestRenderDigestTemplate(t *testing.T) {
+	msg, err := Render("digest", map[string]interface{}{
+		"Username": "<alice>",
+		"Start":    "Jan 1",
+		"End":      "Jan 8, 2024",
+		"Digest":   map[string]interface{}{"SnippetsAdded": 2},
+		"Devices":  nil,
+	})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if msg.Subject != "Your weekly Snippy digest: 2 new snippets" {
+		t.Errorf("Unexpected subject %q", msg.Subject)
+	}
+	if !strings.Contains(msg.Text, "Hi <alice>") {
+		t.Errorf("Expected text body to contain raw username, got %s", msg.Text)
+	}
+	if !strings.Contains(msg.HTML, "Hi &lt;alice&gt;") {
+		t.Errorf("Expected HTML body to escape username, got %s", msg.HTML)
+	}
+
+	if _, err := Render("missing", nil); err == nil {
+		t.Error("Expected error for missing template")
+	}
+}
+
+// fakeSMTPServer accepts connections and answers a minimal SMTP dialogue.
+// The first failGreetings connections are rejected with a 421 greeting.
+func fakeSMTPServer(t *testing.T, failGreetings int) (string, int, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	received := make(chan string, 1)
+	go func() {
+		for conn := 0; ; conn++ {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			if conn < failGreetings {
+				_, _ = c.Write([]byte("421 try again later\r\n"))
+				_ = c.Close()
+				continue
+			}
+			serveSMTP(c, received)
+		}
+	}()
+
+	host, portStr, _ := net.SplitHostPort(ln.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	return host, port, received
+}
+
+func serveSMTP(c net.Conn, received chan<- string) {
+	defer func() { _ = c.Close() }()
+	r := bufio.NewReader(c)
+	reply := func(s string) { _, _ = c.Write([]byte(s + "\r\n")) }
+
+	reply("220 fake ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 fake")
+		case strings.HasPrefix(cmd, "DATA"):
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			received <- data.String()
+			reply("250 queued")
+		case strings.HasPrefix(cmd, "QUIT"):
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func TestSMTPMailerSendRetries(t *testing.T) {
+	host, port, received := fakeSMTPServer(t, 1)
+	m := &SMTPMailer{
+		cfg:        Config{Host: host, Port: port, From: "no-reply@snippy.app", Attempts: 2},
+		retryDelay: time.Millisecond,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.Send(ctx, Message{To: "a@example.com", Subject: "Hello", Text: "Body"}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	select {
+	case data := <-received:
+		if !strings.Contains(data, "Subject: Hello") {
+			t.Errorf("Expected delivered message to contain subject, got %s", data)
+		}
+	case <-ctx.Done():
+		t.Fatal("Message was not delivered")
+	}
+}
+
+func TestSMTPMailerGivesUp(t *testing.T) {
+	host, port, _ := fakeSMTPServer(t, 3)
+	m := &SMTPMailer{
+		cfg:        Config{Host: host, Port: port, From: "no-reply@snippy.app", Attempts: 2},
+		retryDelay: time.Millisecond,
+	}
+
+	if err := m.Send(context.Background(), Message{To: "a@example.com", Subject: "Hello", Text: "Body"}); err == nil {
+		t.Error("Expected error after exhausting attempts")
+	}
+}
@@ -0,0 +1,49 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Each email has templates/<name>.txt, which must define a "subject" template,
+// and optionally templates/<name>.html for the HTML alternative.
+//
+//go:embed templates/*
+var templateFS embed.FS
+
+// Render builds a message from the named templates. The caller sets To.
+func Render(name string, data interface{}) (Message, error) {
+	textTmpl, err := texttemplate.ParseFS(templateFS, "templates/"+name+".txt")
+	if err != nil {
+		return Message{}, fmt.Errorf("mailer: parse text template %s: %w", name, err)
+	}
+
+	var subject, text bytes.Buffer
+	if err := textTmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, fmt.Errorf("mailer: render subject %s: %w", name, err)
+	}
+	if err := textTmpl.Execute(&text, data); err != nil {
+		return Message{}, fmt.Errorf("mailer: render text %s: %w", name, err)
+	}
+
+	msg := Message{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    text.String(),
+	}
+
+	htmlTmpl, err := htmltemplate.ParseFS(templateFS, "templates/"+name+".html")
+	if err != nil {
+		// HTML is optional
+		return msg, nil
+	}
+	var html bytes.Buffer
+	if err := htmlTmpl.Execute(&html, data); err != nil {
+		return Message{}, fmt.Errorf("mailer: render html %s: %w", name, err)
+	}
+	msg.HTML = html.String()
+	return msg, nil
+}
@@ -0,0 +1,93 @@
+// Package middleware provides per-API-key rate limiting.
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/apierror"
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"golang.org/x/time/rate"
+)
+
+// APIKeyRateLimiter stores a rate limiter per API key, each with the key's own limit
+type APIKeyRateLimiter struct {
+	keys map[int64]*keyLimiter
+	mu   sync.Mutex
+}
+
+type keyLimiter struct {
+	limiter   *rate.Limiter
+	lastSeen  time.Time
+	perMinute int
+}
+
+// NewAPIKeyRateLimiter creates a new API key rate limiter
+func NewAPIKeyRateLimiter() *APIKeyRateLimiter {
+	kl := &APIKeyRateLimiter{keys: make(map[int64]*keyLimiter)}
+
+	// Clean up idle keys every minute
+	go kl.cleanupKeys()
+
+	return kl
+}
+
+// allow takes a request from key id's budget of perMinute requests per minute and
+// returns whether it was allowed and how many requests remain
+func (kl *APIKeyRateLimiter) allow(id int64, perMinute int) (bool, int) {
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+
+	k, exists := kl.keys[id]
+	// A changed limit takes effect with a fresh budget
+	if !exists || k.perMinute != perMinute {
+		k = &keyLimiter{limiter: rate.NewLimiter(rate.Limit(float64(perMinute)/60), perMinute), perMinute: perMinute}
+		kl.keys[id] = k
+	}
+	k.lastSeen = time.Now()
+
+	allowed := k.limiter.Allow()
+	return allowed, int(k.limiter.Tokens())
+}
+
+// cleanupKeys removes keys that haven't been used for > 3 minutes
+func (kl *APIKeyRateLimiter) cleanupKeys() {
+	for {
+		time.Sleep(time.Minute)
+
+		kl.mu.Lock()
+		for id, k := range kl.keys {
+			if time.Since(k.lastSeen) > 3*time.Minute {
+				delete(kl.keys, id)
+			}
+		}
+		kl.mu.Unlock()
+	}
+}
+
+// APIKeyRateLimitMiddleware enforces the rate limit of the API key a request was made
+// with. It must run after auth.ScopedMiddleware; other requests pass through.
+func APIKeyRateLimitMiddleware(kl *APIKeyRateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get("api_key")
+		key, ok := value.(*models.APIKey)
+		if !exists || !ok {
+			c.Next()
+			return
+		}
+
+		allowed, remaining := kl.allow(key.ID, key.RateLimit)
+		c.Header("X-RateLimit-Limit", strconv.Itoa(key.RateLimit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(max(remaining, 0)))
+		if !allowed {
+			apierror.Respond(c, http.StatusTooManyRequests, "API key rate limit exceeded. Please try again later.")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
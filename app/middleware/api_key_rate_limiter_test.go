@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+func TestAPIKeyRateLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := NewAPIKeyRateLimiter()
+	keys := map[string]*models.APIKey{
+		"small": {ID: 1, RateLimit: 2},
+		"large": {ID: 2, RateLimit: 5},
+	}
+	router := gin.New()
+	router.GET("/test", func(c *gin.Context) {
+		if key, ok := keys[c.Query("key")]; ok {
+			c.Set("api_key", key)
+		}
+	}, APIKeyRateLimitMiddleware(limiter), func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	serve := func(key string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequestWithContext(context.Background(), "GET", "/test?key="+key, nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Each key gets its own budget, regardless of sharing an IP
+	for i := 0; i < 2; i++ {
+		if w := serve("small"); w.Code != http.StatusOK {
+			t.Fatalf("small key request %d: status %d", i+1, w.Code)
+		}
+	}
+	w := serve("small")
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("small key over its limit: status %d, want 429", w.Code)
+	}
+	if w.Header().Get("X-RateLimit-Limit") != "2" || w.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("rate limit headers = %q/%q, want 2/0", w.Header().Get("X-RateLimit-Limit"), w.Header().Get("X-RateLimit-Remaining"))
+	}
+	for i := 0; i < 5; i++ {
+		if w := serve("large"); w.Code != http.StatusOK {
+			t.Fatalf("large key request %d: status %d", i+1, w.Code)
+		}
+	}
+
+	// Requests without an API key aren't limited here
+	for i := 0; i < 10; i++ {
+		if w := serve(""); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "" {
+			t.Fatalf("request without key: status %d, limit header %q", w.Code, w.Header().Get("X-RateLimit-Limit"))
+		}
+	}
+
+	// Raising a key's limit gives it the new budget
+	keys["small"].RateLimit = 3
+	if w := serve("small"); w.Code != http.StatusOK {
+		t.Errorf("small key after raising its limit: status %d, want 200", w.Code)
+	}
+}
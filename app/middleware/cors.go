@@ -0,0 +1,72 @@
+// Package middleware provides CORS handling with reloadable allowed origins.
+package middleware
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORSPolicy holds the origins allowed to make credentialed cross-origin requests
+type CORSPolicy struct {
+	origins []string
+	mu      sync.RWMutex
+}
+
+// NewCORSPolicy creates a policy for comma-separated origins
+func NewCORSPolicy(origins string) *CORSPolicy {
+	p := &CORSPolicy{}
+	p.SetOrigins(origins)
+	return p
+}
+
+// SetOrigins replaces the allowed origins with comma-separated origins
+func (p *CORSPolicy) SetOrigins(origins string) {
+	var list []string
+	for _, origin := range strings.Split(origins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			list = append(list, origin)
+		}
+	}
+
+	p.mu.Lock()
+	p.origins = list
+	p.mu.Unlock()
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for a request from origin.
+// A single configured origin is always sent; among several, the request's own origin is
+// echoed back if it's allowed, since the header can only name one.
+func (p *CORSPolicy) allowOrigin(origin string) string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if len(p.origins) == 1 {
+		return p.origins[0]
+	}
+	for _, allowed := range p.origins {
+		if allowed == origin {
+			return origin
+		}
+	}
+	return ""
+}
+
+// CORSMiddleware sets CORS headers from the policy and answers preflight requests
+func CORSMiddleware(p *CORSPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Vary", "Origin")
+		if origin := p.allowOrigin(c.GetHeader("Origin")); origin != "" {
+			c.Header("Access-Control-Allow-Origin", origin)
+		}
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, X-Organization")
+		c.Header("Access-Control-Allow-Credentials", "true")
+		if c.Request.Method == "OPTIONS" {
+			c.AbortWithStatus(204)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	policy := NewCORSPolicy("https://app.example.com")
+	router := gin.New()
+	router.Use(CORSMiddleware(policy))
+	router.GET("/test", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	allowOrigin := func(origin string) string {
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Header().Get("Access-Control-Allow-Origin")
+	}
+
+	// A single origin is always sent
+	if got := allowOrigin("https://other.example.com"); got != "https://app.example.com" {
+		t.Errorf("single origin: Allow-Origin = %q", got)
+	}
+
+	// Among several, the request's origin is echoed only if it's allowed
+	policy.SetOrigins("https://app.example.com, https://admin.example.com")
+	if got := allowOrigin("https://admin.example.com"); got != "https://admin.example.com" {
+		t.Errorf("allowed origin: Allow-Origin = %q", got)
+	}
+	if got := allowOrigin("https://evil.example.com"); got != "" {
+		t.Errorf("other origin: Allow-Origin = %q, want none", got)
+	}
+
+	req := httptest.NewRequest(http.MethodOptions, "/test", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("preflight status = %d, want 204", w.Code)
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/apierror"
+	"github.com/jheysaaz/snippy-backend/app/config"
 	"golang.org/x/time/rate"
 )
 
@@ -45,6 +47,20 @@ func NewRateLimiter(r rate.Limit, b int) *RateLimiter {
 	return rl
 }
 
+// RateLimitFromEnv reads a limit from <prefix>_RPS (requests a second) and <prefix>_BURST,
+// defaulting to rps requests a second with an equal burst
+func RateLimitFromEnv(prefix string, rps int) (rate.Limit, int, error) {
+	r, err := config.PositiveInt(prefix+"_RPS", rps)
+	if err != nil {
+		return 0, 0, err
+	}
+	burst, err := config.PositiveInt(prefix+"_BURST", r)
+	if err != nil {
+		return 0, 0, err
+	}
+	return rate.Limit(r), burst, nil
+}
+
 // getVisitor returns the rate limiter for the given IP
 func (rl *RateLimiter) getVisitor(ip string) *rate.Limiter {
 	rl.mu.Lock()
@@ -64,6 +80,18 @@ func (rl *RateLimiter) getVisitor(ip string) *rate.Limiter {
 	return v.limiter
 }
 
+// SetLimit changes the rate and burst of every visitor, current and future
+func (rl *RateLimiter) SetLimit(r rate.Limit, b int) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.rate, rl.burst = r, b
+	for _, v := range rl.visitors {
+		v.limiter.SetLimit(r)
+		v.limiter.SetBurst(b)
+	}
+}
+
 // cleanupVisitors removes hashed visitor entries that haven't been seen for > 3 minutes
 func (rl *RateLimiter) cleanupVisitors() {
 	for {
@@ -86,9 +114,7 @@ func RateLimitMiddleware(rl *RateLimiter) gin.HandlerFunc {
 		limiter := rl.getVisitor(ip)
 
 		if !limiter.Allow() {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error": "Rate limit exceeded. Please try again later.",
-			})
+			apierror.Respond(c, http.StatusTooManyRequests, "Rate limit exceeded. Please try again later.")
 			c.Abort()
 			return
 		}
@@ -104,9 +130,7 @@ func StrictRateLimitMiddleware(rl *RateLimiter) gin.HandlerFunc {
 		limiter := rl.getVisitor(ip)
 
 		if !limiter.Allow() {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error": "Too many attempts. Please try again in a few minutes.",
-			})
+			apierror.Respond(c, http.StatusTooManyRequests, "Too many attempts. Please try again in a few minutes.")
 			c.Abort()
 			return
 		}
@@ -165,3 +165,37 @@ func TestVisitorCleanup(t *testing.T) {
 		t.Errorf("Expected status 200, got %d", w2.Code)
 	}
 }
+
+func TestRateLimiterSetLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := NewRateLimiter(rate.Limit(1), 1)
+	router := gin.New()
+	router.Use(RateLimitMiddleware(limiter))
+	router.GET("/test", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	request := func() int {
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/test", nil)
+		req.RemoteAddr = "192.168.1.1:1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := request(); code != http.StatusOK {
+		t.Fatalf("first request = %d, want 200", code)
+	}
+	if code := request(); code != http.StatusTooManyRequests {
+		t.Fatalf("second request = %d, want 429", code)
+	}
+
+	// Raising the limit applies to the existing visitor too
+	limiter.SetLimit(rate.Inf, 10)
+	for i := 0; i < 5; i++ {
+		if code := request(); code != http.StatusOK {
+			t.Fatalf("request %d after SetLimit = %d, want 200", i, code)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Package middleware provides rate limiting of token refreshes per user.
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/apierror"
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"golang.org/x/time/rate"
+)
+
+// maxRefreshBody bounds how much of a refresh request body is read to find the token
+const maxRefreshBody = 64 << 10
+
+// RefreshRateLimiter limits token refreshes per user instead of per IP, so users sharing
+// an office's address don't throttle each other. Tokens that match no user share one
+// budget, which attackers can't escape by rotating addresses.
+type RefreshRateLimiter struct {
+	users   *RateLimiter
+	unknown *rate.Limiter
+	lookup  func(ctx context.Context, token string) (string, error)
+}
+
+// NewRefreshRateLimiter allows each user 10 refreshes a minute (burst 10), and unknown
+// tokens 60 a minute together (burst 30), finding the users of tokens in store
+func NewRefreshRateLimiter(store *models.Store) *RefreshRateLimiter {
+	return &RefreshRateLimiter{
+		users:   NewRateLimiter(rate.Every(6*time.Second), 10),
+		unknown: rate.NewLimiter(rate.Every(time.Second), 30),
+		lookup:  store.RefreshTokenUserID,
+	}
+}
+
+// refreshToken returns the refresh token of a request, from the cookie or the JSON body.
+// The body is left in place for the handler.
+func refreshToken(c *gin.Context) string {
+	if token, err := c.Cookie("refresh_token"); err == nil && token != "" {
+		return token
+	}
+	if c.Request.Body == nil {
+		return ""
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxRefreshBody))
+	if err != nil {
+		return ""
+	}
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+
+	var req models.RefreshTokenRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return ""
+	}
+	return req.RefreshToken
+}
+
+// RefreshRateLimitMiddleware enforces the refresh rate limit of the user owning the
+// request's refresh token. Requests without a token pass through to be rejected by the handler.
+func RefreshRateLimitMiddleware(rl *RefreshRateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := refreshToken(c)
+		if token == "" {
+			c.Next()
+			return
+		}
+
+		userID, err := rl.lookup(c.Request.Context(), token)
+		var allowed bool
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			allowed = rl.unknown.Allow()
+		case err != nil:
+			// Don't lock everyone out when the lookup fails; the handler validates the token anyway
+			log.Printf("Failed to look up refresh token owner: %v", err)
+			allowed = true
+		default:
+			allowed = rl.users.getVisitor(userID).Allow()
+		}
+
+		if !allowed {
+			apierror.Respond(c, http.StatusTooManyRequests, "Too many token refreshes. Please try again in a few minutes.")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+func TestRefreshRateLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	rl := &RefreshRateLimiter{
+		users:   NewRateLimiter(rate.Every(time.Hour), 2),
+		unknown: rate.NewLimiter(rate.Every(time.Hour), 1),
+		lookup: func(_ context.Context, token string) (string, error) {
+			switch token {
+			case "alice-token", "alice-other-token":
+				return "alice", nil
+			case "bob-token":
+				return "bob", nil
+			}
+			return "", sql.ErrNoRows
+		},
+	}
+
+	router := gin.New()
+	router.POST("/refresh", RefreshRateLimitMiddleware(rl), func(c *gin.Context) {
+		// The handler still sees the body
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.String(http.StatusOK, string(body))
+	})
+
+	refresh := func(token, ip string, cookie bool) int {
+		req := httptest.NewRequest(http.MethodPost, "/refresh", strings.NewReader(`{"refreshToken":"`+token+`"}`))
+		if cookie {
+			req = httptest.NewRequest(http.MethodPost, "/refresh", nil)
+			req.AddCookie(&http.Cookie{Name: "refresh_token", Value: token})
+		}
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code == http.StatusOK && !cookie && !strings.Contains(w.Body.String(), token) {
+			t.Errorf("handler got body %q", w.Body.String())
+		}
+		return w.Code
+	}
+
+	// Alice's budget is shared by her tokens, whatever address they come from
+	if code := refresh("alice-token", "10.0.0.1", false); code != http.StatusOK {
+		t.Errorf("first refresh = %d, want 200", code)
+	}
+	if code := refresh("alice-other-token", "10.0.0.2", true); code != http.StatusOK {
+		t.Errorf("second refresh = %d, want 200", code)
+	}
+	if code := refresh("alice-token", "10.0.0.3", false); code != http.StatusTooManyRequests {
+		t.Errorf("third refresh = %d, want 429", code)
+	}
+
+	// Bob behind the same address isn't affected
+	if code := refresh("bob-token", "10.0.0.1", false); code != http.StatusOK {
+		t.Errorf("other user's refresh = %d, want 200", code)
+	}
+
+	// Unknown tokens share one budget across addresses
+	if code := refresh("guess-1", "10.0.1.1", false); code != http.StatusOK {
+		t.Errorf("first unknown token = %d, want 200", code)
+	}
+	if code := refresh("guess-2", "10.0.1.2", false); code != http.StatusTooManyRequests {
+		t.Errorf("second unknown token = %d, want 429", code)
+	}
+}
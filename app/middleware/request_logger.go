@@ -0,0 +1,60 @@
+// Package middleware provides request logging with a reloadable level.
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Request log levels: debug and info log every request, warn only those that failed
+// (4xx and 5xx), error only server errors (5xx)
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+// RequestLogger decides which requests are logged
+type RequestLogger struct {
+	// minStatus is the lowest status code that is logged
+	minStatus atomic.Int32
+}
+
+// NewRequestLogger creates a request logger at level, or info if it's empty or invalid
+func NewRequestLogger(level string) *RequestLogger {
+	rl := &RequestLogger{}
+	if err := rl.SetLevel(level); err != nil {
+		log.Printf("Ignoring invalid log level: %v", err)
+	}
+	return rl
+}
+
+// SetLevel changes the log level; an empty level means info
+func (rl *RequestLogger) SetLevel(level string) error {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "", LogLevelInfo, LogLevelDebug:
+		rl.minStatus.Store(0)
+	case LogLevelWarn:
+		rl.minStatus.Store(http.StatusBadRequest)
+	case LogLevelError:
+		rl.minStatus.Store(http.StatusInternalServerError)
+	default:
+		return fmt.Errorf("LOG_LEVEL must be one of debug, info, warn, error, got %q", level)
+	}
+	return nil
+}
+
+// RequestLogMiddleware logs requests at or above the logger's level
+func RequestLogMiddleware(rl *RequestLogger) gin.HandlerFunc {
+	return gin.LoggerWithConfig(gin.LoggerConfig{
+		Skip: func(c *gin.Context) bool {
+			return int32(c.Writer.Status()) < rl.minStatus.Load()
+		},
+	})
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import "testing"
+
+func TestRequestLoggerSetLevel(t *testing.T) {
+	rl := NewRequestLogger("verbose")
+	if got := rl.minStatus.Load(); got != 0 {
+		t.Errorf("invalid initial level: min status = %d, want 0 (info)", got)
+	}
+	for level, want := range map[string]int32{"debug": 0, "INFO": 0, "warn": 400, "error": 500} {
+		if err := rl.SetLevel(level); err != nil {
+			t.Errorf("SetLevel(%q): %v", level, err)
+		}
+		if got := rl.minStatus.Load(); got != want {
+			t.Errorf("SetLevel(%q): min status = %d, want %d", level, got, want)
+		}
+	}
+	if err := rl.SetLevel(LogLevelError); err != nil {
+		t.Fatal(err)
+	}
+	if err := rl.SetLevel("verbose"); err == nil {
+		t.Error("SetLevel accepted an unknown level")
+	}
+	if got := rl.minStatus.Load(); got != 500 {
+		t.Errorf("invalid level changed min status to %d", got)
+	}
+}
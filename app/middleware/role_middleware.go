@@ -5,35 +5,36 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/apierror"
 	"github.com/jheysaaz/snippy-backend/app/models"
 )
 
 // RequireRole middleware ensures the authenticated user has the specified role.
-func RequireRole(roleName string) gin.HandlerFunc {
+func RequireRole(store *models.Store, roleName string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID, exists := c.Get("user_id")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			apierror.Respond(c, http.StatusUnauthorized, "Unauthorized")
 			c.Abort()
 			return
 		}
 
 		userIDStr, ok := userID.(string)
 		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user context"})
+			apierror.Respond(c, http.StatusUnauthorized, "Invalid user context")
 			c.Abort()
 			return
 		}
 
-		hasRole, err := models.HasRole(c.Request.Context(), userIDStr, roleName)
+		hasRole, err := store.HasRole(c.Request.Context(), userIDStr, roleName)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check user role"})
+			apierror.Respond(c, http.StatusInternalServerError, "Failed to check user role")
 			c.Abort()
 			return
 		}
 
 		if !hasRole {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			apierror.Respond(c, http.StatusForbidden, "Insufficient permissions")
 			c.Abort()
 			return
 		}
@@ -43,31 +44,31 @@ func RequireRole(roleName string) gin.HandlerFunc {
 }
 
 // RequireAnyRole middleware ensures the authenticated user has at least one of the specified roles.
-func RequireAnyRole(roleNames ...string) gin.HandlerFunc {
+func RequireAnyRole(store *models.Store, roleNames ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID, exists := c.Get("user_id")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			apierror.Respond(c, http.StatusUnauthorized, "Unauthorized")
 			c.Abort()
 			return
 		}
 
 		userIDStr, ok := userID.(string)
 		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user context"})
+			apierror.Respond(c, http.StatusUnauthorized, "Invalid user context")
 			c.Abort()
 			return
 		}
 
-		hasRole, err := models.HasAnyRole(c.Request.Context(), userIDStr, roleNames)
+		hasRole, err := store.HasAnyRole(c.Request.Context(), userIDStr, roleNames)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check user roles"})
+			apierror.Respond(c, http.StatusInternalServerError, "Failed to check user roles")
 			c.Abort()
 			return
 		}
 
 		if !hasRole {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			apierror.Respond(c, http.StatusForbidden, "Insufficient permissions")
 			c.Abort()
 			return
 		}
@@ -77,41 +78,47 @@ func RequireAnyRole(roleNames ...string) gin.HandlerFunc {
 }
 
 // AdminOnly is a convenience middleware that requires admin role.
-var AdminOnly = RequireRole(models.RoleAdmin)
+func AdminOnly(store *models.Store) gin.HandlerFunc {
+	return RequireRole(store, models.RoleAdmin)
+}
 
 // PremiumOnly is a convenience middleware that requires premium role.
-var PremiumOnly = RequireRole(models.RolePremium)
+func PremiumOnly(store *models.Store) gin.HandlerFunc {
+	return RequireRole(store, models.RolePremium)
+}
 
 // TesterOrAdmin is a convenience middleware that requires tester or admin role.
-var TesterOrAdmin = RequireAnyRole(models.RoleTester, models.RoleAdmin)
+func TesterOrAdmin(store *models.Store) gin.HandlerFunc {
+	return RequireAnyRole(store, models.RoleTester, models.RoleAdmin)
+}
 
 // RequirePermission middleware ensures the user has a specific permission.
 // Permissions are feature flags that map to roles (e.g., "sessions_access").
-func RequirePermission(permission string) gin.HandlerFunc {
+func RequirePermission(store *models.Store, permission string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID, exists := c.Get("user_id")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			apierror.Respond(c, http.StatusUnauthorized, "Unauthorized")
 			c.Abort()
 			return
 		}
 
 		userIDStr, ok := userID.(string)
 		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user context"})
+			apierror.Respond(c, http.StatusUnauthorized, "Invalid user context")
 			c.Abort()
 			return
 		}
 
-		hasPermission, err := models.HasPermission(c.Request.Context(), userIDStr, permission)
+		hasPermission, err := store.HasPermission(c.Request.Context(), userIDStr, permission)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+			apierror.Respond(c, http.StatusInternalServerError, "Failed to check permissions")
 			c.Abort()
 			return
 		}
 
 		if !hasPermission {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			apierror.Respond(c, http.StatusForbidden, "Insufficient permissions")
 			c.Abort()
 			return
 		}
@@ -121,4 +128,6 @@ func RequirePermission(permission string) gin.HandlerFunc {
 }
 
 // SessionsAccess requires sessions_access permission (tester, premium, or admin).
-var SessionsAccess = RequirePermission("sessions_access")
+func SessionsAccess(store *models.Store) gin.HandlerFunc {
+	return RequirePermission(store, "sessions_access")
+}
@@ -0,0 +1,163 @@
+// Package middleware provides organization (tenant) resolution.
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/apierror"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+// OrgHeader names the organization of a request by its slug, taking precedence over the host
+const OrgHeader = "X-Organization"
+
+// orgCacheTTL is how long an organization slug lookup is reused
+const orgCacheTTL = time.Minute
+
+// TenantResolver maps requests to organizations, by the X-Organization header or by the
+// subdomain of the base domain (acme.snippy.example for base domain snippy.example).
+// Requests naming neither belong to the default organization.
+type TenantResolver struct {
+	orgs       map[string]cachedOrg
+	lookup     func(ctx context.Context, slug string) (string, error)
+	baseDomain string
+	mu         sync.Mutex
+}
+
+type cachedOrg struct {
+	expiresAt time.Time
+	id        string
+}
+
+// NewTenantResolver creates a tenant resolver for subdomains of baseDomain, looking
+// organizations up in store. An empty base domain only resolves organizations named by
+// the X-Organization header.
+func NewTenantResolver(store *models.Store, baseDomain string) *TenantResolver {
+	return &TenantResolver{
+		orgs:       make(map[string]cachedOrg),
+		baseDomain: strings.ToLower(strings.Trim(baseDomain, ".")),
+		lookup: func(ctx context.Context, slug string) (string, error) {
+			org, err := store.GetOrganizationBySlug(ctx, slug)
+			if err != nil {
+				return "", err
+			}
+			return org.ID, nil
+		},
+	}
+}
+
+// Resolve returns the ID of the organization named by header, an X-Organization value,
+// or else by the subdomain of host; the default organization if they name none. It's
+// shared by HTTP and gRPC, which pass their x-organization metadata and authority.
+// Returns sql.ErrNoRows for an unknown or invalid organization.
+func (tr *TenantResolver) Resolve(ctx context.Context, header, host string) (string, error) {
+	slug := tr.slug(header, host)
+	if slug == "" {
+		return models.DefaultOrgID, nil
+	}
+	if !models.ValidOrgSlug(slug) {
+		return "", sql.ErrNoRows
+	}
+	return tr.orgID(ctx, slug)
+}
+
+// slug returns the organization slug named by header or host, or "" if they name none
+func (tr *TenantResolver) slug(header, host string) string {
+	if slug := strings.TrimSpace(header); slug != "" {
+		return strings.ToLower(slug)
+	}
+	if tr.baseDomain == "" {
+		return ""
+	}
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	label, ok := strings.CutSuffix(host, "."+tr.baseDomain)
+	if !ok || label == "" || strings.Contains(label, ".") || label == "www" {
+		return ""
+	}
+	return label
+}
+
+// orgID returns the ID of the organization with slug, from the cache when possible.
+// Returns sql.ErrNoRows if there's no such organization.
+func (tr *TenantResolver) orgID(ctx context.Context, slug string) (string, error) {
+	if slug == models.DefaultOrgSlug {
+		return models.DefaultOrgID, nil
+	}
+
+	tr.mu.Lock()
+	cached, ok := tr.orgs[slug]
+	if ok && !time.Now().Before(cached.expiresAt) {
+		delete(tr.orgs, slug)
+		ok = false
+	}
+	tr.mu.Unlock()
+	if ok {
+		return cached.id, nil
+	}
+
+	id, err := tr.lookup(ctx, slug)
+	if err != nil {
+		return "", err
+	}
+
+	// Only existing organizations are cached, so the cache is bounded by their number
+	// however many slugs clients make up
+	tr.mu.Lock()
+	tr.orgs[slug] = cachedOrg{id: id, expiresAt: time.Now().Add(orgCacheTTL)}
+	tr.mu.Unlock()
+	return id, nil
+}
+
+// TenantMiddleware sets the request's organization ID under "org_id", responding 404
+// if it names an unknown organization
+func TenantMiddleware(tr *TenantResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orgID, err := tr.Resolve(c.Request.Context(), c.GetHeader(OrgHeader), c.Request.Host)
+		if errors.Is(err, sql.ErrNoRows) {
+			apierror.Respond(c, http.StatusNotFound, "Organization not found")
+			c.Abort()
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to resolve organization: %v", err)
+			apierror.Respond(c, http.StatusInternalServerError, "Failed to resolve organization")
+			c.Abort()
+			return
+		}
+
+		c.Set("org_id", orgID)
+		c.Next()
+	}
+}
+
+// OrgID returns the organization of the request, the default one if TenantMiddleware didn't run
+func OrgID(c *gin.Context) string {
+	if orgID := c.GetString("org_id"); orgID != "" {
+		return orgID
+	}
+	return models.DefaultOrgID
+}
+
+// DefaultOrgOnly restricts routes to the default organization. Deployment administration
+// spans all organizations, so it's only available to the deployment's own administrators.
+func DefaultOrgOnly(c *gin.Context) {
+	if OrgID(c) != models.DefaultOrgID {
+		apierror.Respond(c, http.StatusForbidden, "Only available in the default organization")
+		c.Abort()
+		return
+	}
+	c.Next()
+}
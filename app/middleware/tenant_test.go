@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+func TestTenantMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const acmeOrgID = "7d1f2c3b-4a5e-4f60-8b7a-9c8d7e6f5a4b"
+	lookups := 0
+	tr := NewTenantResolver(nil, "snippy.example")
+	tr.lookup = func(_ context.Context, slug string) (string, error) {
+		lookups++
+		if slug == "acme" {
+			return acmeOrgID, nil
+		}
+		return "", sql.ErrNoRows
+	}
+
+	router := gin.New()
+	router.Use(TenantMiddleware(tr))
+	router.GET("/org", func(c *gin.Context) {
+		c.String(http.StatusOK, OrgID(c))
+	})
+
+	tests := []struct {
+		name       string
+		host       string
+		header     string
+		wantStatus int
+		wantOrg    string
+	}{
+		{name: "No organization", host: "api.example.com", wantStatus: http.StatusOK, wantOrg: models.DefaultOrgID},
+		{name: "Base domain", host: "snippy.example", wantStatus: http.StatusOK, wantOrg: models.DefaultOrgID},
+		{name: "Subdomain", host: "acme.snippy.example", wantStatus: http.StatusOK, wantOrg: acmeOrgID},
+		{name: "Subdomain with port", host: "ACME.snippy.example:8080", wantStatus: http.StatusOK, wantOrg: acmeOrgID},
+		{name: "www", host: "www.snippy.example", wantStatus: http.StatusOK, wantOrg: models.DefaultOrgID},
+		{name: "Nested subdomain", host: "a.acme.snippy.example", wantStatus: http.StatusOK, wantOrg: models.DefaultOrgID},
+		{name: "Header", host: "api.example.com", header: "acme", wantStatus: http.StatusOK, wantOrg: acmeOrgID},
+		{name: "Header wins over host", host: "other.snippy.example", header: "default", wantStatus: http.StatusOK, wantOrg: models.DefaultOrgID},
+		{name: "Unknown subdomain", host: "other.snippy.example", wantStatus: http.StatusNotFound},
+		{name: "Invalid slug", host: "api.example.com", header: "Not a slug", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/org", nil)
+			req.Host = tt.host
+			if tt.header != "" {
+				req.Header.Set(OrgHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantOrg != "" && w.Body.String() != tt.wantOrg {
+				t.Errorf("org = %q, want %q", w.Body.String(), tt.wantOrg)
+			}
+		})
+	}
+
+	// acme was looked up once, later requests being served from the cache, and other once
+	if lookups != 2 {
+		t.Errorf("lookups = %d, want 2", lookups)
+	}
+
+	// Unknown slugs aren't cached, so made-up ones can't grow the cache
+	for _, slug := range []string{"other", "bogus-1", "bogus-2"} {
+		if _, err := tr.Resolve(context.Background(), slug, ""); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("Resolve(%q) error = %v, want sql.ErrNoRows", slug, err)
+		}
+	}
+	if len(tr.orgs) != 1 {
+		t.Errorf("cached %d organizations, want only acme", len(tr.orgs))
+	}
+
+	// Expired entries are dropped and looked up again
+	tr.orgs["acme"] = cachedOrg{id: acmeOrgID, expiresAt: time.Now().Add(-time.Second)}
+	if id, err := tr.Resolve(context.Background(), "acme", ""); err != nil || id != acmeOrgID {
+		t.Errorf("Resolve(acme) = %q, %v after expiry", id, err)
+	}
+	if lookups != 6 {
+		t.Errorf("lookups = %d, want 6", lookups)
+	}
+}
+
+func TestDefaultOrgOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for orgID, want := range map[string]int{
+		"":                                     http.StatusOK,
+		models.DefaultOrgID:                    http.StatusOK,
+		"7d1f2c3b-4a5e-4f60-8b7a-9c8d7e6f5a4b": http.StatusForbidden,
+	} {
+		router := gin.New()
+		router.GET("/admin", func(c *gin.Context) {
+			if orgID != "" {
+				c.Set("org_id", orgID)
+			}
+		}, DefaultOrgOnly, func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin", nil))
+		if w.Code != want {
+			t.Errorf("org %q: status = %d, want %d", orgID, w.Code, want)
+		}
+	}
+}
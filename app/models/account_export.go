@@ -0,0 +1,198 @@
+// Package models provides final account exports for users about to be purged.
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// AccountExportData is everything exported for an account before it is purged
+type AccountExportData struct {
+	ExportedAt time.Time         `json:"exportedAt"`
+	User       *User             `json:"user"`
+	Snippets   []ExportedSnippet `json:"snippets"`
+}
+
+// ExportedSnippet is a snippet with its full version history
+type ExportedSnippet struct {
+	History []SnippetHistory `json:"history"`
+	Snippet
+}
+
+// AccountExport is a stored export archive, downloadable by token until it expires.
+// The row outlives the user it was created for.
+type AccountExport struct {
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+	DownloadedAt *time.Time
+	UserID       string
+	Username     string
+	Archive      []byte
+}
+
+// GetAccountExportData collects a user's profile, non-deleted snippets and their history.
+// Soft-deleted users are included, since exports run just before they are purged.
+func (st *Store) GetAccountExportData(ctx context.Context, userID string) (*AccountExportData, error) {
+	user, err := ScanUser(st.db.QueryRowContext(ctx, `
+		SELECT id, username, email, full_name, avatar_url, created_at, updated_at
+		FROM users
+		WHERE id = $1
+	`, userID))
+	if err != nil {
+		return nil, err
+	}
+
+	data := &AccountExportData{ExportedAt: time.Now().UTC(), User: user, Snippets: make([]ExportedSnippet, 0)}
+	err = st.StreamExportedSnippets(ctx, userID, func(s *ExportedSnippet) error {
+		data.Snippets = append(data.Snippets, *s)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// StreamExportedSnippets calls fn with each of a user's non-deleted snippets and its
+// history, in ID order, stopping at fn's first error. Snippets and history are read from
+// two cursors side by side, so only one snippet's history is held at a time however large
+// the account is.
+func (st *Store) StreamExportedSnippets(ctx context.Context, userID string, fn func(*ExportedSnippet) error) error {
+	rows, err := st.db.QueryContext(ctx, `
+		SELECT `+snippetColumns+`
+		FROM snippets
+		WHERE user_id = $1 AND is_deleted = false
+		ORDER BY id
+	`, userID)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing export snippet rows: %v\n", closeErr)
+		}
+	}()
+
+	history, err := st.db.QueryContext(ctx, `
+		SELECT h.id, h.snippet_id, h.version_number, h.label, h.shortcut, h.content, h.tags,
+		       h.changed_by, h.change_type, h.changed_at, h.change_notes
+		FROM snippet_history h
+		JOIN snippets s ON s.id = h.snippet_id
+		WHERE s.user_id = $1 AND s.is_deleted = false
+		ORDER BY h.snippet_id, h.version_number
+	`, userID)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := history.Close(); closeErr != nil {
+			fmt.Printf("error closing export history rows: %v\n", closeErr)
+		}
+	}()
+
+	// next is the first history entry not yet attached to a snippet
+	var next *SnippetHistory
+	advance := func() error {
+		next = nil
+		if !history.Next() {
+			return history.Err()
+		}
+		h, err := scanExportHistory(history)
+		if err != nil {
+			return err
+		}
+		next = h
+		return nil
+	}
+	if err := advance(); err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		s, err := ScanSnippet(rows)
+		if err != nil {
+			return err
+		}
+		exported := ExportedSnippet{Snippet: *s, History: make([]SnippetHistory, 0)}
+		// Entries of snippets deleted between the two queries are skipped
+		for next != nil && next.SnippetID <= s.ID {
+			if next.SnippetID == s.ID {
+				exported.History = append(exported.History, *next)
+			}
+			if err := advance(); err != nil {
+				return err
+			}
+		}
+		if err := fn(&exported); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// scanExportHistory scans a snippet history row of an export
+func scanExportHistory(rows *sql.Rows) (*SnippetHistory, error) {
+	var h SnippetHistory
+	var tags pq.StringArray
+	var changeNotes sql.NullString
+	if err := rows.Scan(&h.ID, &h.SnippetID, &h.VersionNumber, &h.Label, &h.Shortcut, &h.Content, &tags,
+		&h.ChangedBy, &h.ChangeType, &h.ChangedAt, &changeNotes); err != nil {
+		return nil, err
+	}
+	h.Tags = tags
+	if changeNotes.Valid {
+		h.ChangeNotes = &changeNotes.String
+	}
+	return &h, nil
+}
+
+// SaveAccountExport stores an export archive and returns the download token and expiry.
+// Only a hash of the token is stored.
+func (st *Store) SaveAccountExport(ctx context.Context, user *User, archive []byte, ttl time.Duration) (string, time.Time, error) {
+	token, err := GenerateRefreshToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	_, err = st.db.ExecContext(ctx, `
+		INSERT INTO account_exports (token_hash, user_id, username, archive, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, hashExportToken(token), user.ID, user.Username, archive, expiresAt)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// GetAccountExportByToken returns an unexpired export and records the download.
+// Returns sql.ErrNoRows if the token is unknown or expired.
+func (st *Store) GetAccountExportByToken(ctx context.Context, token string) (*AccountExport, error) {
+	var e AccountExport
+	var downloadedAt sql.NullTime
+	err := st.db.QueryRowContext(ctx, `
+		UPDATE account_exports
+		SET downloaded_at = COALESCE(downloaded_at, NOW())
+		WHERE token_hash = $1 AND expires_at > NOW()
+		RETURNING user_id, username, archive, created_at, expires_at, downloaded_at
+	`, hashExportToken(token)).Scan(&e.UserID, &e.Username, &e.Archive, &e.CreatedAt, &e.ExpiresAt, &downloadedAt)
+	if err != nil {
+		return nil, err
+	}
+	if downloadedAt.Valid {
+		e.DownloadedAt = &downloadedAt.Time
+	}
+	return &e, nil
+}
+
+// hashExportToken hashes a download token for storage and lookup
+func hashExportToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
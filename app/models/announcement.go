@@ -0,0 +1,141 @@
+// Package models provides admin-managed announcements.
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Announcement severities
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Announcement is a notice shown to clients during its active window
+type Announcement struct {
+	StartsAt  time.Time  `json:"startsAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+	EndsAt    *time.Time `json:"endsAt,omitempty"`
+	CreatedBy *string    `json:"createdBy,omitempty"`
+	Title     string     `json:"title"`
+	Message   string     `json:"message"`
+	Severity  string     `json:"severity"`
+	ID        int64      `json:"id"`
+}
+
+// AnnouncementRequest creates or replaces an announcement.
+// StartsAt defaults to now; a nil EndsAt keeps the announcement active until removed.
+type AnnouncementRequest struct {
+	StartsAt *time.Time `json:"startsAt,omitempty"`
+	EndsAt   *time.Time `json:"endsAt,omitempty"`
+	Title    string     `json:"title" binding:"required,max=200"`
+	Message  string     `json:"message" binding:"required,max=5000"`
+	Severity string     `json:"severity" binding:"omitempty,oneof=info warning critical"`
+}
+
+const announcementColumns = `id, title, message, severity, starts_at, ends_at, created_by, created_at, updated_at`
+
+// scanAnnouncement scans a database row into an Announcement
+func scanAnnouncement(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*Announcement, error) {
+	var a Announcement
+	var endsAt sql.NullTime
+	var createdBy sql.NullString
+
+	if err := scanner.Scan(&a.ID, &a.Title, &a.Message, &a.Severity, &a.StartsAt, &endsAt, &createdBy, &a.CreatedAt, &a.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if endsAt.Valid {
+		a.EndsAt = &endsAt.Time
+	}
+	if createdBy.Valid {
+		a.CreatedBy = &createdBy.String
+	}
+	return &a, nil
+}
+
+// queryAnnouncements runs a query returning announcement rows
+func (st *Store) queryAnnouncements(ctx context.Context, query string, args ...interface{}) ([]Announcement, error) {
+	rows, err := st.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing announcement rows: %v\n", closeErr)
+		}
+	}()
+
+	announcements := make([]Announcement, 0)
+	for rows.Next() {
+		a, err := scanAnnouncement(rows)
+		if err != nil {
+			return nil, err
+		}
+		announcements = append(announcements, *a)
+	}
+	return announcements, rows.Err()
+}
+
+// GetActiveAnnouncements returns announcements whose window contains the current time, most severe first.
+func (st *Store) GetActiveAnnouncements(ctx context.Context) ([]Announcement, error) {
+	return st.queryAnnouncements(ctx, `
+		SELECT `+announcementColumns+`
+		FROM announcements
+		WHERE starts_at <= NOW() AND (ends_at IS NULL OR ends_at > NOW())
+		ORDER BY CASE severity WHEN 'critical' THEN 0 WHEN 'warning' THEN 1 ELSE 2 END, starts_at DESC
+	`)
+}
+
+// GetAllAnnouncements returns every announcement including scheduled and expired ones.
+func (st *Store) GetAllAnnouncements(ctx context.Context) ([]Announcement, error) {
+	return st.queryAnnouncements(ctx, `
+		SELECT `+announcementColumns+`
+		FROM announcements
+		ORDER BY starts_at DESC
+	`)
+}
+
+// CreateAnnouncement stores a new announcement.
+func (st *Store) CreateAnnouncement(ctx context.Context, req AnnouncementRequest, createdBy string) (*Announcement, error) {
+	row := st.db.QueryRowContext(ctx, `
+		INSERT INTO announcements (title, message, severity, starts_at, ends_at, created_by)
+		VALUES ($1, $2, $3, COALESCE($4, NOW()), $5, $6)
+		RETURNING `+announcementColumns,
+		req.Title, req.Message, req.Severity, req.StartsAt, req.EndsAt, createdBy)
+	return scanAnnouncement(row)
+}
+
+// UpdateAnnouncement replaces an announcement's content and window.
+// Returns sql.ErrNoRows if it doesn't exist.
+func (st *Store) UpdateAnnouncement(ctx context.Context, id int64, req AnnouncementRequest) (*Announcement, error) {
+	row := st.db.QueryRowContext(ctx, `
+		UPDATE announcements
+		SET title = $1, message = $2, severity = $3, starts_at = COALESCE($4, starts_at), ends_at = $5
+		WHERE id = $6
+		RETURNING `+announcementColumns,
+		req.Title, req.Message, req.Severity, req.StartsAt, req.EndsAt, id)
+	return scanAnnouncement(row)
+}
+
+// DeleteAnnouncement removes an announcement. Returns sql.ErrNoRows if it doesn't exist.
+func (st *Store) DeleteAnnouncement(ctx context.Context, id int64) error {
+	result, err := st.db.ExecContext(ctx, `DELETE FROM announcements WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
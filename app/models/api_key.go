@@ -0,0 +1,200 @@
+// Package models provides API keys for third-party integrations.
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// APIKeyScopes are the scopes an API key can be issued with
+var APIKeyScopes = []string{ScopeSnippetsRead, ScopeSnippetsWrite, ScopeUsageWrite}
+
+const (
+	// APIKeyPrefix starts every API key, telling it apart from other tokens
+	APIKeyPrefix = "snk_"
+
+	// APIKeyMaxPerUser is how many unrevoked API keys a user may hold
+	APIKeyMaxPerUser = 20
+
+	// DefaultAPIKeyRateLimit is a key's rate limit, in requests per minute, unless set
+	DefaultAPIKeyRateLimit = 60
+	// MaxAPIKeyRateLimit is the highest rate limit a key can be given
+	MaxAPIKeyRateLimit = 600
+
+	// apiKeyDisplayLength is how much of a key is kept to tell keys apart in listings
+	apiKeyDisplayLength = len(APIKeyPrefix) + 8
+)
+
+// ErrAPIKeyLimit is returned when a user already has APIKeyMaxPerUser keys
+var ErrAPIKeyLimit = errors.New("api key limit reached")
+
+// APIKey is a credential an integration uses on the owner's behalf
+type APIKey struct {
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	// Key is only returned when the key is created
+	Key    string `json:"key,omitempty"`
+	UserID string `json:"-"`
+	Name   string `json:"name"`
+	// Prefix is the start of the key, to recognise it by
+	Prefix string   `json:"prefix"`
+	Scopes []string `json:"scopes"`
+	// RateLimit is the number of requests per minute the key may make
+	RateLimit int `json:"rateLimit"`
+	// RequestCount is the number of authenticated requests made with the key
+	RequestCount int64 `json:"requestCount"`
+	ID           int64 `json:"id"`
+}
+
+// CreateAPIKeyRequest describes a new API key
+type CreateAPIKeyRequest struct {
+	Name      string   `json:"name" binding:"required,max=100"`
+	Scopes    []string `json:"scopes" binding:"required,min=1,dive,oneof=snippets:read snippets:write usage:write"`
+	RateLimit int      `json:"rateLimit" binding:"omitempty,min=1,max=600"` // Requests per minute, default 60
+}
+
+// HasScope reports whether the key grants scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAPIKey reports whether a bearer token is an API key
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// hashAPIKey hashes an API key for storage and lookup
+func hashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+const apiKeyColumns = `id, user_id, name, key_prefix, scopes, rate_limit, request_count, last_used_at, created_at`
+
+// scanAPIKey scans a database row into an APIKey
+func scanAPIKey(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*APIKey, error) {
+	var k APIKey
+	var scopes pq.StringArray
+	var lastUsedAt sql.NullTime
+	if err := scanner.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &scopes, &k.RateLimit, &k.RequestCount, &lastUsedAt, &k.CreatedAt); err != nil {
+		return nil, err
+	}
+	k.Scopes = scopes
+	if lastUsedAt.Valid {
+		k.LastUsedAt = &lastUsedAt.Time
+	}
+	return &k, nil
+}
+
+// CreateAPIKey issues an API key for userID. The returned key carries the secret, which
+// is only stored hashed. Returns ErrAPIKeyLimit when the user already has
+// APIKeyMaxPerUser active keys.
+func (st *Store) CreateAPIKey(ctx context.Context, userID string, req CreateAPIKeyRequest) (*APIKey, error) {
+	if req.RateLimit == 0 {
+		req.RateLimit = DefaultAPIKeyRateLimit
+	}
+
+	secret, err := GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	key := APIKeyPrefix + strings.TrimRight(secret, "=")
+
+	// Revoked keys don't count toward the limit. Counting in the insert's WHERE clause leaves
+	// concurrent creates little room to both slip under it.
+	row := st.db.QueryRowContext(ctx, `
+		INSERT INTO api_keys (user_id, key_hash, key_prefix, name, scopes, rate_limit)
+		SELECT $1, $2, $3, $4, $5, $6
+		WHERE (
+			SELECT COUNT(*) FROM api_keys WHERE user_id = $1 AND revoked_at IS NULL
+		) < $7
+		RETURNING `+apiKeyColumns,
+		userID, hashAPIKey(key), key[:apiKeyDisplayLength], req.Name,
+		pq.Array(req.Scopes), req.RateLimit, APIKeyMaxPerUser)
+	k, err := scanAPIKey(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPIKeyLimit
+	}
+	if err != nil {
+		return nil, err
+	}
+	k.Key = key
+	return k, nil
+}
+
+// ValidateAPIKey returns the active key matching key and counts the request against it.
+// Returns sql.ErrNoRows if it doesn't exist, was revoked, or its owner was deleted or isn't in orgID.
+func (st *Store) ValidateAPIKey(ctx context.Context, key, orgID string) (*APIKey, error) {
+	return scanAPIKey(st.db.QueryRowContext(ctx, `
+		UPDATE api_keys k
+		SET request_count = k.request_count + 1, last_used_at = NOW()
+		FROM users u
+		WHERE k.key_hash = $1 AND k.revoked_at IS NULL
+			AND u.id = k.user_id AND u.is_deleted = false AND u.org_id = $2
+		RETURNING k.id, k.user_id, k.name, k.key_prefix, k.scopes, k.rate_limit,
+			k.request_count, k.last_used_at, k.created_at
+	`, hashAPIKey(key), orgID))
+}
+
+// GetUserAPIKeys lists a user's active API keys with their usage, newest first
+func (st *Store) GetUserAPIKeys(ctx context.Context, userID string) ([]APIKey, error) {
+	rows, err := st.db.QueryContext(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing api key rows: %v\n", closeErr)
+		}
+	}()
+
+	keys := make([]APIKey, 0)
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *k)
+	}
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey revokes one of a user's API keys. Returns sql.ErrNoRows if the user has
+// no such active key.
+func (st *Store) RevokeAPIKey(ctx context.Context, userID string, id int64) error {
+	result, err := st.db.ExecContext(ctx, `
+		UPDATE api_keys SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, id, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
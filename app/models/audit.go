@@ -0,0 +1,209 @@
+// Package models provides the admin audit log.
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Audit actions. System actions (idle logout, purge, billing changes) have no actor.
+const (
+	AuditRoleAssigned         = "role.assigned"
+	AuditRoleRevoked          = "role.revoked"
+	AuditAnnouncementCreated  = "announcement.created"
+	AuditAnnouncementUpdated  = "announcement.updated"
+	AuditAnnouncementDeleted  = "announcement.deleted"
+	AuditSessionLoggedOut     = "session.logged_out"
+	AuditSessionIdleLoggedOut = "session.idle_logged_out"
+	AuditAccountDeleted       = "account.deleted"
+	AuditAccountPurged        = "account.purged"
+	AuditBroadcastCreated     = "broadcast.created"
+	AuditPremiumGranted       = "premium.granted"
+	AuditPremiumRevoked       = "premium.revoked"
+	AuditSearchReindexed      = "search.reindexed"
+	AuditOrganizationCreated  = "organization.created"
+	AuditConfigReloaded       = "config.reloaded"
+)
+
+// Audit target types
+const (
+	AuditTargetUser         = "user"
+	AuditTargetSession      = "session"
+	AuditTargetAnnouncement = "announcement"
+	AuditTargetBroadcast    = "broadcast"
+	AuditTargetSearchIndex  = "search_index"
+	AuditTargetOrganization = "organization"
+	AuditTargetConfig       = "config"
+)
+
+// AuditAction is a single audited action to record
+type AuditAction struct {
+	Details      map[string]interface{}
+	ActorID      string // empty for system actions
+	Action       string
+	TargetType   string
+	TargetID     string
+	TargetUserID string // the user affected, if any
+	IPAddress    string
+}
+
+// AuditEntry is a recorded audit log row. Actor and target IDs have no foreign keys
+// so entries survive account purges.
+type AuditEntry struct {
+	CreatedAt     time.Time       `json:"createdAt"`
+	ActorID       *string         `json:"actorId,omitempty"`
+	ActorUsername *string         `json:"actorUsername,omitempty"`
+	TargetID      *string         `json:"targetId,omitempty"`
+	TargetUserID  *string         `json:"targetUserId,omitempty"`
+	IPAddressHash *string         `json:"ipAddressHash,omitempty"`
+	Details       json.RawMessage `json:"details,omitempty" swaggertype:"object"`
+	Action        string          `json:"action"`
+	TargetType    string          `json:"targetType"`
+	ID            int64           `json:"id"`
+}
+
+// AuditFilter narrows an audit log listing. Zero values match everything.
+type AuditFilter struct {
+	Since        *time.Time
+	Until        *time.Time
+	Actions      []string
+	ActorID      string
+	TargetUserID string
+	TargetType   string
+	Limit        int
+	Offset       int
+}
+
+// RecordAudit writes an audit entry using exec, so it can join the caller's transaction.
+func RecordAudit(ctx context.Context, exec Execer, a AuditAction) error {
+	var details interface{}
+	if a.Details != nil {
+		raw, err := json.Marshal(a.Details)
+		if err != nil {
+			return fmt.Errorf("marshal audit details: %w", err)
+		}
+		details = raw
+	}
+
+	var ipHash interface{}
+	if a.IPAddress != "" {
+		ipHash = hashIP(a.IPAddress)
+	}
+
+	_, err := exec.ExecContext(ctx, `
+		INSERT INTO audit_log (actor_id, action, target_type, target_id, target_user_id, details, ip_address_hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, nullIfEmpty(a.ActorID), a.Action, a.TargetType, nullIfEmpty(a.TargetID), nullIfEmpty(a.TargetUserID), details, ipHash)
+	return err
+}
+
+// ListAuditLog returns matching audit entries, newest first, and the total match count.
+func (st *Store) ListAuditLog(ctx context.Context, f AuditFilter) ([]AuditEntry, int, error) {
+	query, args := buildAuditLogQuery(f)
+	rows, err := st.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing audit log rows: %v\n", closeErr)
+		}
+	}()
+
+	total := 0
+	entries := make([]AuditEntry, 0)
+	for rows.Next() {
+		var e AuditEntry
+		var actorID, actorUsername, targetID, targetUserID, ipHash sql.NullString
+		var details []byte
+		if err := rows.Scan(&e.ID, &actorID, &actorUsername, &e.Action, &e.TargetType, &targetID,
+			&targetUserID, &details, &ipHash, &e.CreatedAt, &total); err != nil {
+			return nil, 0, err
+		}
+		e.ActorID = nullStringPtr(actorID)
+		e.ActorUsername = nullStringPtr(actorUsername)
+		e.TargetID = nullStringPtr(targetID)
+		e.TargetUserID = nullStringPtr(targetUserID)
+		e.IPAddressHash = nullStringPtr(ipHash)
+		if details != nil {
+			e.Details = details
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, total, rows.Err()
+}
+
+// buildAuditLogQuery builds the parameterized audit log query. The total column
+// counts all matching rows so clients can paginate.
+func buildAuditLogQuery(f AuditFilter) (string, []interface{}) {
+	query := `
+		SELECT a.id, a.actor_id, u.username, a.action, a.target_type, a.target_id,
+		       a.target_user_id, a.details, a.ip_address_hash, a.created_at,
+		       COUNT(*) OVER()
+		FROM audit_log a
+		LEFT JOIN users u ON u.id = a.actor_id
+		WHERE true
+	`
+	args := []interface{}{}
+	argPos := 1
+
+	if len(f.Actions) > 0 {
+		query += " AND a.action = ANY($" + strconv.Itoa(argPos) + ")"
+		args = append(args, pq.Array(f.Actions))
+		argPos++
+	}
+	if f.ActorID != "" {
+		query += " AND a.actor_id = $" + strconv.Itoa(argPos)
+		args = append(args, f.ActorID)
+		argPos++
+	}
+	if f.TargetUserID != "" {
+		query += " AND a.target_user_id = $" + strconv.Itoa(argPos)
+		args = append(args, f.TargetUserID)
+		argPos++
+	}
+	if f.TargetType != "" {
+		query += " AND a.target_type = $" + strconv.Itoa(argPos)
+		args = append(args, f.TargetType)
+		argPos++
+	}
+	if f.Since != nil {
+		query += " AND a.created_at >= $" + strconv.Itoa(argPos)
+		args = append(args, *f.Since)
+		argPos++
+	}
+	if f.Until != nil {
+		query += " AND a.created_at < $" + strconv.Itoa(argPos)
+		args = append(args, *f.Until)
+		argPos++
+	}
+
+	query += " ORDER BY a.created_at DESC, a.id DESC"
+	query += " LIMIT $" + strconv.Itoa(argPos) + " OFFSET $" + strconv.Itoa(argPos+1)
+	args = append(args, f.Limit, f.Offset)
+
+	return query, args
+}
+
+// nullIfEmpty maps an empty string to SQL NULL
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// nullStringPtr converts a sql.NullString to *string
+func nullStringPtr(ns sql.NullString) *string {
+	if !ns.Valid {
+		return nil
+	}
+	return &ns.String
+}
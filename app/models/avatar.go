@@ -0,0 +1,123 @@
+// Package models provides storage for processed user avatars.
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jheysaaz/snippy-backend/app/avatar"
+)
+
+// Avatar is a stored avatar variant
+type Avatar struct {
+	UpdatedAt   time.Time
+	ContentType string
+	Data        []byte
+	Size        int
+}
+
+// AvatarURL returns the public URL of a user's avatar variant.
+// The version parameter changes on every upload so clients and CDNs don't serve stale images.
+func AvatarURL(userID string, size int, version time.Time) string {
+	return fmt.Sprintf("/api/v1/avatars/%s/%d?v=%d", userID, size, version.Unix())
+}
+
+// SaveAvatar replaces a user's avatar variants and points avatar_url at the default size.
+// Returns sql.ErrNoRows if the user doesn't exist.
+func (st *Store) SaveAvatar(ctx context.Context, userID string, variants []avatar.Variant) (*User, error) {
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			fmt.Printf("error rolling back avatar transaction: %v\n", rbErr)
+		}
+	}()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_avatars WHERE user_id = $1`, userID); err != nil {
+		return nil, err
+	}
+
+	for _, v := range variants {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO user_avatars (user_id, size, content_type, data)
+			VALUES ($1, $2, $3, $4)
+		`, userID, v.Size, v.ContentType, v.Data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	query := `
+		UPDATE users
+		SET avatar_url = $1, updated_at = NOW()
+		WHERE id = $2 AND is_deleted = false
+		RETURNING id, username, email, full_name, avatar_url, created_at, updated_at
+	`
+	user, err := ScanUser(tx.QueryRowContext(ctx, query, AvatarURL(userID, avatar.DefaultSize, time.Now()), userID))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	InvalidateUser(ctx, userID)
+	return user, nil
+}
+
+// GetAvatar returns a single avatar variant for a user.
+func (st *Store) GetAvatar(ctx context.Context, userID string, size int) (*Avatar, error) {
+	query := `
+		SELECT a.size, a.content_type, a.data, a.updated_at
+		FROM user_avatars a
+		JOIN users u ON u.id = a.user_id
+		WHERE a.user_id = $1 AND a.size = $2 AND u.is_deleted = false
+	`
+
+	var a Avatar
+	err := st.db.QueryRowContext(ctx, query, userID, size).Scan(&a.Size, &a.ContentType, &a.Data, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// DeleteAvatar removes a user's stored avatar and clears avatar_url.
+// Returns sql.ErrNoRows if the user doesn't exist.
+func (st *Store) DeleteAvatar(ctx context.Context, userID string) (*User, error) {
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			fmt.Printf("error rolling back avatar transaction: %v\n", rbErr)
+		}
+	}()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_avatars WHERE user_id = $1`, userID); err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE users
+		SET avatar_url = '', updated_at = NOW()
+		WHERE id = $1 AND is_deleted = false
+		RETURNING id, username, email, full_name, avatar_url, created_at, updated_at
+	`
+	user, err := ScanUser(tx.QueryRowContext(ctx, query, userID))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	InvalidateUser(ctx, userID)
+	return user, nil
+}
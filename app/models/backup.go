@@ -0,0 +1,72 @@
+// Package models provides the history of scheduled database backups.
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Backup run outcomes
+const (
+	BackupSucceeded = "succeeded"
+	BackupFailed    = "failed"
+)
+
+// BackupRun is one scheduled backup attempt
+type BackupRun struct {
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	ObjectKey  *string   `json:"objectKey,omitempty"`
+	Error      *string   `json:"error,omitempty"`
+	Status     string    `json:"status"`
+	ID         int64     `json:"id"`
+	SizeBytes  int64     `json:"sizeBytes"`
+	Tables     int       `json:"tables"`
+	Rows       int64     `json:"rows"`
+	Pruned     int       `json:"pruned"`
+}
+
+const backupRunColumns = `id, status, started_at, finished_at, object_key, size_bytes, tables, rows, pruned, error`
+
+// scanBackupRun scans a database row into a BackupRun
+func scanBackupRun(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*BackupRun, error) {
+	var r BackupRun
+	var objectKey, runErr sql.NullString
+	if err := scanner.Scan(&r.ID, &r.Status, &r.StartedAt, &r.FinishedAt, &objectKey, &r.SizeBytes,
+		&r.Tables, &r.Rows, &r.Pruned, &runErr); err != nil {
+		return nil, err
+	}
+	r.ObjectKey = nullStringPtr(objectKey)
+	r.Error = nullStringPtr(runErr)
+	return &r, nil
+}
+
+// RecordBackupRun stores the outcome of a backup, filling in its ID
+func (st *Store) RecordBackupRun(ctx context.Context, run *BackupRun) error {
+	return st.db.QueryRowContext(ctx, `
+		INSERT INTO backup_runs (status, started_at, finished_at, object_key, size_bytes, tables, rows, pruned, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`, run.Status, run.StartedAt, run.FinishedAt, run.ObjectKey, run.SizeBytes,
+		run.Tables, run.Rows, run.Pruned, run.Error).Scan(&run.ID)
+}
+
+// GetLastBackupRun returns the most recent run with the given status, or any status when
+// status is empty. Returns nil when there is none.
+func (st *Store) GetLastBackupRun(ctx context.Context, status string) (*BackupRun, error) {
+	run, err := scanBackupRun(st.db.QueryRowContext(ctx, `
+		SELECT `+backupRunColumns+`
+		FROM backup_runs
+		WHERE $1 = '' OR status = $1
+		ORDER BY started_at DESC
+		LIMIT 1
+	`, status))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return run, err
+}
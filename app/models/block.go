@@ -0,0 +1,135 @@
+// Package models provides user blocking.
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrBlocked is returned when an interaction is refused because one user blocked the other
+var ErrBlocked = errors.New("user is blocked")
+
+// BlockedUser is a user in the authenticated user's block list
+type BlockedUser struct {
+	BlockedAt time.Time `json:"blockedAt"`
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	FullName  string    `json:"fullName"`
+	AvatarURL string    `json:"avatarUrl"`
+}
+
+// blockBetweenCondition matches a block in either direction between users $1 and $2
+const blockBetweenCondition = `
+	EXISTS (
+		SELECT 1 FROM user_blocks
+		WHERE (blocker_id = $1 AND blocked_id = $2)
+		   OR (blocker_id = $2 AND blocked_id = $1)
+	)
+`
+
+// BlockUser makes blockerID block blockedID and removes any follows, snippet shares and
+// collection memberships between them.
+// It reports whether a new block was created. Returns sql.ErrNoRows if the user doesn't exist
+// or is in another organization.
+func (st *Store) BlockUser(ctx context.Context, blockerID, blockedID string) (bool, error) {
+	query := `
+		WITH target AS (
+			SELECT id FROM users WHERE id = $2 AND is_deleted = false
+			  AND org_id = (SELECT org_id FROM users WHERE id = $1)
+		), inserted AS (
+			INSERT INTO user_blocks (blocker_id, blocked_id)
+			SELECT $1, id FROM target
+			ON CONFLICT DO NOTHING
+			RETURNING 1
+		), unfollowed AS (
+			DELETE FROM follows
+			WHERE EXISTS (SELECT 1 FROM target)
+			  AND ((follower_id = $1 AND followee_id = $2) OR (follower_id = $2 AND followee_id = $1))
+		), unshared AS (
+			UPDATE snippet_shares SET revoked_at = NOW(), updated_at = NOW()
+			WHERE EXISTS (SELECT 1 FROM target) AND revoked_at IS NULL AND (
+				(user_id = $2 AND snippet_id IN (SELECT id FROM snippets WHERE user_id = $1)) OR
+				(user_id = $1 AND snippet_id IN (SELECT id FROM snippets WHERE user_id = $2))
+			)
+		), removed_members AS (
+			DELETE FROM collection_members
+			WHERE EXISTS (SELECT 1 FROM target) AND (
+				(user_id = $2 AND collection_id IN (SELECT id FROM collections WHERE user_id = $1)) OR
+				(user_id = $1 AND collection_id IN (SELECT id FROM collections WHERE user_id = $2))
+			)
+		)
+		SELECT EXISTS (SELECT 1 FROM target), EXISTS (SELECT 1 FROM inserted)
+	`
+
+	var found, created bool
+	if err := st.db.QueryRowContext(ctx, query, blockerID, blockedID).Scan(&found, &created); err != nil {
+		return false, err
+	}
+	if !found {
+		return false, sql.ErrNoRows
+	}
+	return created, nil
+}
+
+// UnblockUser removes a block. Returns sql.ErrNoRows if blockerID hadn't blocked blockedID.
+func (st *Store) UnblockUser(ctx context.Context, blockerID, blockedID string) error {
+	result, err := st.db.ExecContext(ctx, `
+		DELETE FROM user_blocks WHERE blocker_id = $1 AND blocked_id = $2
+	`, blockerID, blockedID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// IsBlockedBetween reports whether either user has blocked the other.
+// Subsystems that deliver content from one user to another (follows, shares) must check this.
+func IsBlockedBetween(ctx context.Context, q RowQueryer, userID, otherUserID string) (bool, error) {
+	var blocked bool
+	err := q.QueryRowContext(ctx, `SELECT `+blockBetweenCondition, userID, otherUserID).Scan(&blocked)
+	return blocked, err
+}
+
+// GetBlockedUsers returns a page of the users userID has blocked, most recent first, and
+// how many they have blocked in all.
+func (st *Store) GetBlockedUsers(ctx context.Context, userID string, limit, offset int) ([]BlockedUser, int, error) {
+	query := `
+		SELECT u.id, u.username, u.full_name, u.avatar_url, b.created_at, COUNT(*) OVER()
+		FROM user_blocks b
+		JOIN users u ON u.id = b.blocked_id
+		WHERE b.blocker_id = $1
+		ORDER BY b.created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := st.db.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing blocked user rows: %v\n", closeErr)
+		}
+	}()
+
+	total := 0
+	users := make([]BlockedUser, 0)
+	for rows.Next() {
+		var u BlockedUser
+		if err := rows.Scan(&u.ID, &u.Username, &u.FullName, &u.AvatarURL, &u.BlockedAt, &total); err != nil {
+			return nil, 0, err
+		}
+		users = append(users, u)
+	}
+	return users, total, rows.Err()
+}
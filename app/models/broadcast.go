@@ -0,0 +1,369 @@
+// Package models provides admin broadcast emails and their per-recipient delivery status.
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Broadcast recipient delivery states
+const (
+	BroadcastPending = "pending"
+	BroadcastSending = "sending"
+	BroadcastSent    = "sent"
+	BroadcastFailed  = "failed"
+)
+
+// Broadcast statuses, derived from recipient states
+const (
+	BroadcastStatusSending   = "sending"
+	BroadcastStatusCompleted = "completed"
+)
+
+// BroadcastMaxAttempts is how many times delivery to a recipient is tried
+const BroadcastMaxAttempts = 3
+
+// broadcastStaleSending is how long a recipient may stay "sending" before another
+// worker reclaims it (e.g. after a crash mid-send)
+const broadcastStaleSending = 10 * time.Minute
+
+// BroadcastFilter selects recipients. Deleted users are always excluded; empty
+// fields match every user.
+type BroadcastFilter struct {
+	RegisteredAfter  *time.Time `json:"registeredAfter,omitempty"`
+	RegisteredBefore *time.Time `json:"registeredBefore,omitempty"`
+	Verified         *bool      `json:"verified,omitempty"`
+	Roles            []string   `json:"roles,omitempty"`
+}
+
+// BroadcastRequest queues a broadcast email
+type BroadcastRequest struct {
+	Filter  BroadcastFilter `json:"filter"`
+	Subject string          `json:"subject" binding:"required,max=200"`
+	Message string          `json:"message" binding:"required,max=20000"`
+}
+
+// Broadcast is a queued admin email with delivery counts
+type Broadcast struct {
+	CreatedAt   time.Time       `json:"createdAt"`
+	CompletedAt *time.Time      `json:"completedAt,omitempty"`
+	CreatedBy   *string         `json:"createdBy,omitempty"`
+	Filter      BroadcastFilter `json:"filter"`
+	Subject     string          `json:"subject"`
+	Message     string          `json:"message"`
+	Status      string          `json:"status"`
+	ID          int64           `json:"id"`
+	Recipients  int             `json:"recipients"`
+	Pending     int             `json:"pending"`
+	Sent        int             `json:"sent"`
+	Failed      int             `json:"failed"`
+}
+
+// BroadcastRecipient is the delivery status of a broadcast for one user
+type BroadcastRecipient struct {
+	SentAt        *time.Time `json:"sentAt,omitempty"`
+	LastAttemptAt *time.Time `json:"lastAttemptAt,omitempty"`
+	Error         *string    `json:"error,omitempty"`
+	UserID        string     `json:"userId"`
+	Email         string     `json:"email"`
+	Username      string     `json:"username"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+}
+
+// BroadcastDelivery is a claimed recipient ready to be emailed
+type BroadcastDelivery struct {
+	UserID      string
+	Email       string
+	Username    string
+	Subject     string
+	Message     string
+	BroadcastID int64
+	Attempts    int
+}
+
+const broadcastColumns = `
+	b.id, b.subject, b.message, b.filter, b.created_by, b.created_at,
+	COUNT(r.user_id),
+	COUNT(r.user_id) FILTER (WHERE r.status IN ('pending', 'sending')),
+	COUNT(r.user_id) FILTER (WHERE r.status = 'sent'),
+	COUNT(r.user_id) FILTER (WHERE r.status = 'failed'),
+	MAX(COALESCE(r.sent_at, r.last_attempt_at))
+`
+
+// scanBroadcast scans a broadcast row with aggregated recipient counts
+func scanBroadcast(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*Broadcast, error) {
+	var b Broadcast
+	var filter []byte
+	var createdBy sql.NullString
+	var lastActivity sql.NullTime
+	if err := scanner.Scan(&b.ID, &b.Subject, &b.Message, &filter, &createdBy, &b.CreatedAt,
+		&b.Recipients, &b.Pending, &b.Sent, &b.Failed, &lastActivity); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(filter, &b.Filter); err != nil {
+		return nil, fmt.Errorf("decode broadcast filter: %w", err)
+	}
+	if createdBy.Valid {
+		b.CreatedBy = &createdBy.String
+	}
+	b.Status = BroadcastStatusSending
+	if b.Pending == 0 {
+		b.Status = BroadcastStatusCompleted
+		if lastActivity.Valid {
+			b.CompletedAt = &lastActivity.Time
+		} else {
+			b.CompletedAt = &b.CreatedAt
+		}
+	}
+	return &b, nil
+}
+
+// buildBroadcastRecipientQuery selects the users matching a filter, starting
+// placeholders at argPos
+func buildBroadcastRecipientQuery(f BroadcastFilter, argPos int) (string, []interface{}) {
+	query := `
+		SELECT u.id, u.email, u.username
+		FROM users u
+		WHERE u.is_deleted = false
+	`
+	args := []interface{}{}
+
+	if f.Verified != nil {
+		if *f.Verified {
+			query += " AND u.email_verified_at IS NOT NULL"
+		} else {
+			query += " AND u.email_verified_at IS NULL"
+		}
+	}
+	if len(f.Roles) > 0 {
+		query += ` AND EXISTS (
+			SELECT 1 FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+			WHERE ur.user_id = u.id AND r.name = ANY($` + strconv.Itoa(argPos) + `)
+		)`
+		args = append(args, pq.Array(f.Roles))
+		argPos++
+	}
+	if f.RegisteredAfter != nil {
+		query += " AND u.created_at >= $" + strconv.Itoa(argPos)
+		args = append(args, *f.RegisteredAfter)
+		argPos++
+	}
+	if f.RegisteredBefore != nil {
+		query += " AND u.created_at < $" + strconv.Itoa(argPos)
+		args = append(args, *f.RegisteredBefore)
+	}
+
+	return query, args
+}
+
+// CreateBroadcast queues a broadcast and snapshots its recipients in one transaction.
+// The audit entry, if given, is recorded in the same transaction.
+func (st *Store) CreateBroadcast(ctx context.Context, req BroadcastRequest, createdBy string, audit *AuditAction) (*Broadcast, error) {
+	filter, err := json.Marshal(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			fmt.Printf("error rolling back broadcast transaction: %v\n", rbErr)
+		}
+	}()
+
+	var id int64
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO email_broadcasts (subject, message, filter, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, req.Subject, req.Message, filter, createdBy).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients, args := buildBroadcastRecipientQuery(req.Filter, 2)
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO email_broadcast_recipients (broadcast_id, user_id, email, username)
+		SELECT $1, m.id, m.email, m.username FROM (`+recipients+`) m
+	`, append([]interface{}{id}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	if audit != nil {
+		audit.TargetID = strconv.FormatInt(id, 10)
+		if err := RecordAudit(ctx, tx, *audit); err != nil {
+			return nil, err
+		}
+	}
+
+	broadcast, err := scanBroadcast(tx.QueryRowContext(ctx, `
+		SELECT `+broadcastColumns+`
+		FROM email_broadcasts b
+		LEFT JOIN email_broadcast_recipients r ON r.broadcast_id = b.id
+		WHERE b.id = $1
+		GROUP BY b.id
+	`, id))
+	if err != nil {
+		return nil, err
+	}
+
+	return broadcast, tx.Commit()
+}
+
+// GetBroadcast returns a broadcast with delivery counts. Returns sql.ErrNoRows if it doesn't exist.
+func (st *Store) GetBroadcast(ctx context.Context, id int64) (*Broadcast, error) {
+	return scanBroadcast(st.db.QueryRowContext(ctx, `
+		SELECT `+broadcastColumns+`
+		FROM email_broadcasts b
+		LEFT JOIN email_broadcast_recipients r ON r.broadcast_id = b.id
+		WHERE b.id = $1
+		GROUP BY b.id
+	`, id))
+}
+
+// ListBroadcasts returns a page of broadcasts newest first and the total number of broadcasts
+func (st *Store) ListBroadcasts(ctx context.Context, limit, offset int) ([]Broadcast, int, error) {
+	rows, err := st.db.QueryContext(ctx, `
+		SELECT `+broadcastColumns+`, COUNT(*) OVER()
+		FROM email_broadcasts b
+		LEFT JOIN email_broadcast_recipients r ON r.broadcast_id = b.id
+		GROUP BY b.id
+		ORDER BY b.created_at DESC, b.id DESC
+		LIMIT $1 OFFSET $2
+	`, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing broadcast rows: %v\n", closeErr)
+		}
+	}()
+
+	total := 0
+	broadcasts := make([]Broadcast, 0)
+	for rows.Next() {
+		b, err := scanBroadcast(totalScanner{rows: rows, total: &total})
+		if err != nil {
+			return nil, 0, err
+		}
+		broadcasts = append(broadcasts, *b)
+	}
+	return broadcasts, total, rows.Err()
+}
+
+// GetBroadcastRecipients returns a page of per-recipient delivery status, optionally filtered
+// by status, and the total number of matching recipients
+func (st *Store) GetBroadcastRecipients(ctx context.Context, broadcastID int64, status string, limit, offset int) ([]BroadcastRecipient, int, error) {
+	rows, err := st.db.QueryContext(ctx, `
+		SELECT user_id, email, username, status, attempts, error, last_attempt_at, sent_at, COUNT(*) OVER()
+		FROM email_broadcast_recipients
+		WHERE broadcast_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY username
+		LIMIT $3 OFFSET $4
+	`, broadcastID, status, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing broadcast recipient rows: %v\n", closeErr)
+		}
+	}()
+
+	total := 0
+	recipients := make([]BroadcastRecipient, 0)
+	for rows.Next() {
+		var r BroadcastRecipient
+		var errMsg sql.NullString
+		var lastAttempt, sentAt sql.NullTime
+		if err := rows.Scan(&r.UserID, &r.Email, &r.Username, &r.Status, &r.Attempts, &errMsg, &lastAttempt, &sentAt, &total); err != nil {
+			return nil, 0, err
+		}
+		r.Error = nullStringPtr(errMsg)
+		if lastAttempt.Valid {
+			r.LastAttemptAt = &lastAttempt.Time
+		}
+		if sentAt.Valid {
+			r.SentAt = &sentAt.Time
+		}
+		recipients = append(recipients, r)
+	}
+	return recipients, total, rows.Err()
+}
+
+// ClaimBroadcastDeliveries marks up to limit due recipients as sending and returns them.
+// SKIP LOCKED lets several instances send without double delivery.
+func (st *Store) ClaimBroadcastDeliveries(ctx context.Context, limit int) ([]BroadcastDelivery, error) {
+	rows, err := st.db.QueryContext(ctx, `
+		UPDATE email_broadcast_recipients r
+		SET status = 'sending', attempts = r.attempts + 1, last_attempt_at = NOW()
+		FROM email_broadcasts b
+		WHERE b.id = r.broadcast_id
+		  AND (r.broadcast_id, r.user_id) IN (
+			SELECT broadcast_id, user_id
+			FROM email_broadcast_recipients
+			WHERE (status = 'pending' AND next_attempt_at <= NOW())
+			   OR (status = 'sending' AND last_attempt_at < NOW() - make_interval(secs => $2))
+			ORDER BY broadcast_id, next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		  )
+		RETURNING r.broadcast_id, r.user_id, r.email, r.username, r.attempts, b.subject, b.message
+	`, limit, broadcastStaleSending.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing broadcast delivery rows: %v\n", closeErr)
+		}
+	}()
+
+	deliveries := make([]BroadcastDelivery, 0)
+	for rows.Next() {
+		var d BroadcastDelivery
+		if err := rows.Scan(&d.BroadcastID, &d.UserID, &d.Email, &d.Username, &d.Attempts, &d.Subject, &d.Message); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// MarkBroadcastDelivered records a successful send
+func (st *Store) MarkBroadcastDelivered(ctx context.Context, d BroadcastDelivery) error {
+	_, err := st.db.ExecContext(ctx, `
+		UPDATE email_broadcast_recipients
+		SET status = 'sent', sent_at = NOW(), error = NULL
+		WHERE broadcast_id = $1 AND user_id = $2
+	`, d.BroadcastID, d.UserID)
+	return err
+}
+
+// MarkBroadcastFailed records a failed send. The recipient is retried with a growing
+// delay until BroadcastMaxAttempts is reached, then marked failed.
+func (st *Store) MarkBroadcastFailed(ctx context.Context, d BroadcastDelivery, sendErr error) error {
+	_, err := st.db.ExecContext(ctx, `
+		UPDATE email_broadcast_recipients
+		SET status = CASE WHEN attempts >= $3 THEN 'failed' ELSE 'pending' END,
+		    next_attempt_at = NOW() + make_interval(mins => attempts * 5),
+		    error = $4
+		WHERE broadcast_id = $1 AND user_id = $2
+	`, d.BroadcastID, d.UserID, BroadcastMaxAttempts, sendErr.Error())
+	return err
+}
@@ -0,0 +1,301 @@
+// Package models provides bulk changes to many of a user's snippets at once.
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// MaxBulkSnippets caps how many snippets a bulk request can change
+const MaxBulkSnippets = 100
+
+// BulkSnippetsRequest names the snippets a bulk request changes
+type BulkSnippetsRequest struct {
+	IDs []int64 `json:"ids" binding:"required,min=1,max=100,dive,min=1"`
+}
+
+// BulkUpdateRequest applies the same change to many snippets. At least one of AddTag,
+// CollectionID and Language is required.
+type BulkUpdateRequest struct {
+	// AddTag adds a tag to each snippet that doesn't have it yet
+	AddTag *string `json:"addTag,omitempty" binding:"omitempty,max=50"`
+	// CollectionID moves each snippet to one of the user's collections; 0 unfiles them
+	CollectionID *int64 `json:"collectionId,omitempty"`
+	// Language sets each snippet's programming language; "" clears it
+	Language *string `json:"language,omitempty" binding:"omitempty,max=32"`
+	IDs      []int64 `json:"ids" binding:"required,min=1,max=100,dive,min=1"`
+}
+
+// maxSnippetTags is how many tags a snippet can have, as CreateSnippetRequest validates
+const maxSnippetTags = 20
+
+// BulkResult reports whether a bulk request changed one snippet, and why not
+type BulkResult struct {
+	Error   string `json:"error,omitempty"`
+	ID      int64  `json:"id"`
+	Success bool   `json:"success"`
+}
+
+// Reasons a bulk request skips a snippet, matching the errors of the single-snippet routes
+const (
+	bulkErrNotFound    = "Snippet not found"
+	bulkErrForbidden   = "You don't have permission to access this snippet"
+	bulkErrTooManyTags = "Snippet already has the maximum number of tags"
+)
+
+// BulkDeleteSnippets soft-deletes those of ids that are the user's non-deleted snippets
+// in one transaction, with one history entry and domain event per deleted snippet. It
+// returns a result per ID, in request order with duplicates dropped: IDs that don't
+// exist or are already deleted and other users' snippets are reported as failures.
+func (st *Store) BulkDeleteSnippets(ctx context.Context, userID, originSessionID string, ids []int64) ([]BulkResult, error) {
+	ids = uniqueIDs(ids)
+
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rollbackSnippetTx(tx)
+
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE snippets SET is_deleted = true, deleted_at = NOW()
+		WHERE id = ANY($1) AND user_id = $2 AND is_deleted = false
+		RETURNING `+snippetColumns,
+		pq.Array(ids), userID)
+	if err != nil {
+		return nil, err
+	}
+	snippets, err := collectSnippets(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := make(map[int64]bool, len(snippets))
+	if len(snippets) > 0 {
+		deletedIDs := make([]int64, len(snippets))
+		events := make([]AggregateEvent, len(snippets))
+		for i := range snippets {
+			deleted[snippets[i].ID] = true
+			deletedIDs[i] = snippets[i].ID
+			events[i] = AggregateEvent{AggregateID: strconv.FormatInt(snippets[i].ID, 10), Payload: &snippets[i]}
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO snippet_history (
+				snippet_id, version_number, label, shortcut, content, tags,
+				changed_by, change_type, change_notes
+			)
+			SELECT id, get_next_snippet_version(id), label, shortcut, content, tags, $2, 'soft_delete', $3
+			FROM snippets
+			WHERE id = ANY($1)
+		`, pq.Array(deletedIDs), userID, "Snippet marked as deleted in a bulk delete")
+		if err != nil {
+			return nil, fmt.Errorf("record bulk delete history: %w", err)
+		}
+
+		if err := EnqueueEventsFromSession(ctx, tx, originSessionID, EventSnippetDeleted, AggregateSnippet, events); err != nil {
+			return nil, fmt.Errorf("enqueue %s events: %w", EventSnippetDeleted, err)
+		}
+	}
+
+	failures := make(map[int64]string)
+	if err := markOthersSnippets(ctx, tx, userID, ids, failures); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	for id := range deleted {
+		InvalidateSnippet(ctx, id)
+	}
+	return bulkResults(ids, deleted, failures), nil
+}
+
+// BulkUpdateSnippets applies req's change to those of req.IDs that are the user's
+// non-deleted snippets in one transaction, with one history entry and domain event per
+// changed snippet. Snippets the change leaves as they are succeed without a new version;
+// adding a tag to a snippet that already has the maximum number fails for that snippet.
+// It returns a result per ID like BulkDeleteSnippets, ErrCollectionNotFound if the
+// target collection isn't one of the user's and ErrInvalidLanguage for an invalid language.
+func (st *Store) BulkUpdateSnippets(ctx context.Context, userID, originSessionID string, req BulkUpdateRequest) ([]BulkResult, error) {
+	if err := normalizeLanguageField(req.Language); err != nil {
+		return nil, err
+	}
+	ids := uniqueIDs(req.IDs)
+
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rollbackSnippetTx(tx)
+
+	if req.CollectionID != nil && *req.CollectionID != 0 {
+		if err := lockCollection(ctx, tx, userID, *req.CollectionID, "FOR KEY SHARE"); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, tags, collection_id, COALESCE(language, '') FROM snippets
+		WHERE id = ANY($1) AND user_id = $2 AND is_deleted = false
+		FOR UPDATE
+	`, pq.Array(ids), userID)
+	if err != nil {
+		return nil, err
+	}
+	succeeded := make(map[int64]bool, len(ids))
+	failures := make(map[int64]string)
+	var changeIDs []int64
+	for rows.Next() {
+		var id int64
+		var tags []string
+		var collectionID sql.NullInt64
+		var language string
+		if err := rows.Scan(&id, pq.Array(&tags), &collectionID, &language); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		addsTag := req.AddTag != nil && !slices.Contains(tags, *req.AddTag)
+		if addsTag && len(tags) >= maxSnippetTags {
+			failures[id] = bulkErrTooManyTags
+			continue
+		}
+		moves := req.CollectionID != nil && collectionID.Int64 != *req.CollectionID
+		setsLanguage := req.Language != nil && language != *req.Language
+		succeeded[id] = true
+		if addsTag || moves || setsLanguage {
+			changeIDs = append(changeIDs, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(changeIDs) > 0 {
+		rows, err = tx.QueryContext(ctx, `
+			UPDATE snippets SET
+				tags = CASE WHEN $2::text IS NULL OR $2 = ANY(tags) THEN tags
+					ELSE array_append(COALESCE(tags, '{}'), $2) END,
+				collection_id = CASE WHEN $3::bigint IS NULL THEN collection_id ELSE NULLIF($3, 0) END,
+				language = CASE WHEN $4::text IS NULL THEN language ELSE NULLIF($4, '') END
+			WHERE id = ANY($1)
+			RETURNING `+snippetColumns,
+			pq.Array(changeIDs), req.AddTag, req.CollectionID, req.Language)
+		if err != nil {
+			return nil, err
+		}
+		snippets, err := collectSnippets(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		events := make([]AggregateEvent, len(snippets))
+		for i := range snippets {
+			events[i] = AggregateEvent{AggregateID: strconv.FormatInt(snippets[i].ID, 10), Payload: &snippets[i]}
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO snippet_history (
+				snippet_id, version_number, label, shortcut, content, tags,
+				changed_by, change_type, change_notes
+			)
+			SELECT id, get_next_snippet_version(id), label, shortcut, content, tags, $2, 'edit', $3
+			FROM snippets
+			WHERE id = ANY($1)
+		`, pq.Array(changeIDs), userID, bulkUpdateNotes(req))
+		if err != nil {
+			return nil, fmt.Errorf("record bulk update history: %w", err)
+		}
+		if err := EnqueueEventsFromSession(ctx, tx, originSessionID, EventSnippetUpdated, AggregateSnippet, events); err != nil {
+			return nil, fmt.Errorf("enqueue %s events: %w", EventSnippetUpdated, err)
+		}
+	}
+
+	if err := markOthersSnippets(ctx, tx, userID, ids, failures); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	for _, id := range changeIDs {
+		InvalidateSnippet(ctx, id)
+	}
+	return bulkResults(ids, succeeded, failures), nil
+}
+
+// bulkUpdateNotes describes req's change for the history entries it writes
+func bulkUpdateNotes(req BulkUpdateRequest) string {
+	var changes []string
+	if req.AddTag != nil {
+		changes = append(changes, fmt.Sprintf("added tag %q", *req.AddTag))
+	}
+	if req.CollectionID != nil {
+		if *req.CollectionID == 0 {
+			changes = append(changes, "removed from its collection")
+		} else {
+			changes = append(changes, fmt.Sprintf("moved to collection %d", *req.CollectionID))
+		}
+	}
+	if req.Language != nil {
+		if *req.Language == "" {
+			changes = append(changes, "cleared language")
+		} else {
+			changes = append(changes, fmt.Sprintf("set language to %s", *req.Language))
+		}
+	}
+	return "Bulk update: " + strings.Join(changes, ", ")
+}
+
+// markOthersSnippets records in failures which of ids are snippets belonging to other users
+func markOthersSnippets(ctx context.Context, tx *sql.Tx, userID string, ids []int64, failures map[int64]string) error {
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM snippets WHERE id = ANY($1) AND user_id <> $2`, pq.Array(ids), userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		failures[id] = bulkErrForbidden
+	}
+	return rows.Err()
+}
+
+// bulkResults reports each of ids as succeeded if it's in succeeded, or failed with the
+// reason in failures, defaulting to a missing snippet
+func bulkResults(ids []int64, succeeded map[int64]bool, failures map[int64]string) []BulkResult {
+	results := make([]BulkResult, len(ids))
+	for i, id := range ids {
+		results[i] = BulkResult{ID: id, Success: succeeded[id]}
+		if !succeeded[id] {
+			results[i].Error = failures[id]
+			if results[i].Error == "" {
+				results[i].Error = bulkErrNotFound
+			}
+		}
+	}
+	return results
+}
+
+// uniqueIDs returns ids without duplicates, keeping the first of each
+func uniqueIDs(ids []int64) []int64 {
+	seen := make(map[int64]bool, len(ids))
+	unique := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,74 @@
+// Package models provides cached point lookups and their invalidation.
+package models
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/jheysaaz/snippy-backend/app/cache"
+)
+
+// Cache TTLs. Writes invalidate entries as they commit; the TTL bounds how long a missed
+// invalidation, or a read racing a write, can serve stale data.
+const (
+	userCacheTTL    = time.Minute
+	rolesCacheTTL   = 30 * time.Second
+	snippetCacheTTL = time.Minute
+)
+
+func userCacheKey(userID string) string  { return "user:" + userID }
+func rolesCacheKey(userID string) string { return "roles:" + userID }
+func snippetCacheKey(id int64) string    { return "snippet:" + strconv.FormatInt(id, 10) }
+
+// GetCachedUser returns a non-deleted user by ID, from the cache when possible.
+// Returns sql.ErrNoRows if the user doesn't exist.
+func (st *Store) GetCachedUser(ctx context.Context, userID string) (*User, error) {
+	var user User
+	if cache.GetJSON(ctx, userCacheKey(userID), &user) {
+		return &user, nil
+	}
+
+	u, err := ScanUser(st.db.QueryRowContext(ctx, `
+		SELECT id, username, email, full_name, avatar_url, created_at, updated_at
+		FROM users
+		WHERE id = $1 AND is_deleted = false
+	`, userID))
+	if err != nil {
+		return nil, err
+	}
+	cache.SetJSON(ctx, userCacheKey(userID), u, userCacheTTL)
+	return u, nil
+}
+
+// GetCachedSnippet returns a non-deleted snippet regardless of owner, from the cache when
+// possible. Returns sql.ErrNoRows if it doesn't exist or was deleted. Use GetSnippet
+// where the latest committed state matters, such as indexing.
+func (st *Store) GetCachedSnippet(ctx context.Context, id int64) (*Snippet, error) {
+	var snippet Snippet
+	if cache.GetJSON(ctx, snippetCacheKey(id), &snippet) {
+		return &snippet, nil
+	}
+
+	s, err := st.GetSnippet(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	cache.SetJSON(ctx, snippetCacheKey(id), s, snippetCacheTTL)
+	return s, nil
+}
+
+// InvalidateUser drops the cached user; call after committing a change to the users row
+func InvalidateUser(ctx context.Context, userID string) {
+	cache.Delete(ctx, userCacheKey(userID))
+}
+
+// InvalidateUserRoles drops the user's cached role names; call after committing a role change
+func InvalidateUserRoles(ctx context.Context, userID string) {
+	cache.Delete(ctx, rolesCacheKey(userID))
+}
+
+// InvalidateSnippet drops the cached snippet; call after committing a change to it
+func InvalidateSnippet(ctx context.Context, id int64) {
+	cache.Delete(ctx, snippetCacheKey(id))
+}
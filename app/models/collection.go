@@ -0,0 +1,409 @@
+// Package models provides collections, the folders users file their snippets in.
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// MaxCollectionDepth is how deeply collections can nest; a top-level collection is at depth 1
+const MaxCollectionDepth = 8
+
+var (
+	// ErrCollectionNotFound is returned for a collection that doesn't exist or belongs to
+	// another user
+	ErrCollectionNotFound = errors.New("collection not found")
+	// ErrCollectionExists is returned when the parent already has a collection of that name
+	ErrCollectionExists = errors.New("collection already exists")
+	// ErrCollectionCycle is returned when moving a collection into itself or one of its
+	// sub-collections
+	ErrCollectionCycle = errors.New("collection cannot be moved into itself or its sub-collections")
+	// ErrCollectionTooDeep is returned when a collection would nest deeper than
+	// MaxCollectionDepth
+	ErrCollectionTooDeep = errors.New("collections nest too deeply")
+)
+
+// Collection is a named group of a user's snippets. Collections nest: ParentID is the
+// collection it's in, or nil at the top level.
+type Collection struct {
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+	ParentID     *int64    `json:"parentId"`
+	UserID       string    `json:"-"`
+	Name         string    `json:"name"`
+	ID           int64     `json:"id"`
+	SnippetCount int       `json:"snippetCount"`
+}
+
+// CollectionTree is a collection with its sub-collections. Path holds the names of the
+// collections from the top level down to this one.
+type CollectionTree struct {
+	Collection
+	Path     []string          `json:"path"`
+	Children []*CollectionTree `json:"children"`
+}
+
+// CollectionRequest creates or renames a collection
+type CollectionRequest struct {
+	// ParentID creates the collection inside another; ignored on rename
+	ParentID *int64 `json:"parentId,omitempty"`
+	Name     string `json:"name" binding:"required,max=100"`
+}
+
+// MoveCollectionRequest moves a collection, with its sub-collections, under another
+// collection. A null or missing parentId moves it to the top level.
+type MoveCollectionRequest struct {
+	ParentID *int64 `json:"parentId"`
+}
+
+// collectionColumns lists the columns read by scanCollection, in order. The snippet
+// count is added by the query.
+const collectionColumns = `c.id, c.user_id, c.parent_id, c.name, c.created_at, c.updated_at`
+
+// collectionSnippetCount counts the snippets listed in collection c: not deleted or archived
+const collectionSnippetCount = `(SELECT COUNT(*) FROM snippets s WHERE s.collection_id = c.id AND s.is_deleted = false AND s.archived_at IS NULL)`
+
+// collectionSubtree is a recursive CTE of the IDs and depths (the collection at 0) of
+// the collection $1 and its descendants, owned by $2
+const collectionSubtree = `
+	WITH RECURSIVE subtree AS (
+		SELECT id, 0 AS depth FROM collections WHERE id = $1 AND user_id = $2
+		UNION ALL
+		SELECT c.id, subtree.depth + 1 FROM collections c JOIN subtree ON c.parent_id = subtree.id
+	)`
+
+// scanCollection scans collectionColumns followed by the snippet count
+func scanCollection(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*Collection, error) {
+	var col Collection
+	var parentID sql.NullInt64
+	if err := scanner.Scan(&col.ID, &col.UserID, &parentID, &col.Name, &col.CreatedAt, &col.UpdatedAt, &col.SnippetCount); err != nil {
+		return nil, err
+	}
+	if parentID.Valid {
+		col.ParentID = &parentID.Int64
+	}
+	return &col, nil
+}
+
+// ListCollections returns all the user's collections by name, with their parents and
+// snippet counts
+func (st *Store) ListCollections(ctx context.Context, userID string) ([]Collection, error) {
+	rows, err := st.db.QueryContext(ctx, `
+		SELECT `+collectionColumns+`, `+collectionSnippetCount+`
+		FROM collections c
+		WHERE c.user_id = $1
+		ORDER BY lower(c.name), c.id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing collection rows: %v\n", closeErr)
+		}
+	}()
+
+	collections := make([]Collection, 0)
+	for rows.Next() {
+		col, err := scanCollection(rows)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, *col)
+	}
+	return collections, rows.Err()
+}
+
+// GetCollectionTree returns one of the user's collections with all its sub-collections,
+// each level by name. Returns ErrCollectionNotFound if they have no such collection.
+func (st *Store) GetCollectionTree(ctx context.Context, userID string, id int64) (*CollectionTree, error) {
+	path, err := st.collectionPath(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := st.db.QueryContext(ctx, collectionSubtree+`
+		SELECT `+collectionColumns+`, `+collectionSnippetCount+`
+		FROM subtree JOIN collections c ON c.id = subtree.id
+		ORDER BY subtree.depth, lower(c.name), c.id
+	`, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing collection tree rows: %v\n", closeErr)
+		}
+	}()
+
+	// Rows come level by level, so each collection's parent is already in the tree
+	var root *CollectionTree
+	nodes := make(map[int64]*CollectionTree)
+	for rows.Next() {
+		col, err := scanCollection(rows)
+		if err != nil {
+			return nil, err
+		}
+		node := &CollectionTree{Collection: *col, Children: []*CollectionTree{}}
+		nodes[col.ID] = node
+		if root == nil {
+			node.Path = path
+			root = node
+			continue
+		}
+		parent := nodes[*col.ParentID]
+		node.Path = append(append([]string{}, parent.Path...), col.Name)
+		parent.Children = append(parent.Children, node)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if root == nil {
+		return nil, ErrCollectionNotFound
+	}
+	return root, nil
+}
+
+// collectionPath returns the names of the collections from the top level down to one of
+// the user's collections. Returns ErrCollectionNotFound if they have no such collection.
+func (st *Store) collectionPath(ctx context.Context, userID string, id int64) ([]string, error) {
+	var path pq.StringArray
+	err := st.db.QueryRowContext(ctx, `
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id, name, 0 AS height FROM collections WHERE id = $1 AND user_id = $2
+			UNION ALL
+			SELECT c.id, c.parent_id, c.name, ancestors.height + 1
+			FROM collections c JOIN ancestors ON c.id = ancestors.parent_id
+		)
+		SELECT array_agg(name ORDER BY height DESC) FROM ancestors
+	`, id, userID).Scan(&path)
+	if err != nil {
+		return nil, err
+	}
+	if len(path) == 0 {
+		return nil, ErrCollectionNotFound
+	}
+	return path, nil
+}
+
+// CreateCollection creates a collection for the user, inside parentID if it isn't nil.
+// Returns ErrCollectionExists if the parent already has one of that name,
+// ErrCollectionNotFound if the parent isn't one of the user's and ErrCollectionTooDeep if
+// it's already at MaxCollectionDepth.
+func (st *Store) CreateCollection(ctx context.Context, userID string, parentID *int64, name string) (*Collection, error) {
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rollbackSnippetTx(tx)
+
+	if parentID != nil {
+		if err := lockCollection(ctx, tx, userID, *parentID, "FOR KEY SHARE"); err != nil {
+			return nil, err
+		}
+		depth, err := collectionDepth(ctx, tx, *parentID)
+		if err != nil {
+			return nil, err
+		}
+		if depth >= MaxCollectionDepth {
+			return nil, ErrCollectionTooDeep
+		}
+	}
+
+	col, err := scanCollection(tx.QueryRowContext(ctx, `
+		INSERT INTO collections AS c (user_id, parent_id, name)
+		VALUES ($1, $2, $3)
+		RETURNING `+collectionColumns+`, 0
+	`, userID, parentID, name))
+	if err != nil {
+		return nil, collectionWriteError(err)
+	}
+	return col, tx.Commit()
+}
+
+// RenameCollection renames one of the user's collections. Returns ErrCollectionNotFound
+// if they have no such collection and ErrCollectionExists if the name is taken among its
+// siblings.
+func (st *Store) RenameCollection(ctx context.Context, userID string, id int64, name string) (*Collection, error) {
+	col, err := scanCollection(st.db.QueryRowContext(ctx, `
+		UPDATE collections c
+		SET name = $3, updated_at = NOW()
+		WHERE c.id = $1 AND c.user_id = $2
+		RETURNING `+collectionColumns+`, `+collectionSnippetCount,
+		id, userID, name))
+	if err != nil {
+		return nil, collectionWriteError(err)
+	}
+	return col, nil
+}
+
+// MoveCollection moves one of the user's collections, with its sub-collections, inside
+// parentID, or to the top level if it's nil. Returns ErrCollectionNotFound if either
+// isn't one of the user's collections, ErrCollectionCycle if parentID is the collection
+// or one of its sub-collections, ErrCollectionTooDeep if the moved tree would nest deeper
+// than MaxCollectionDepth and ErrCollectionExists if the new parent already has a
+// collection of that name.
+func (st *Store) MoveCollection(ctx context.Context, userID string, id int64, parentID *int64) (*Collection, error) {
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rollbackSnippetTx(tx)
+
+	// Moves lock all the user's collections, so two concurrent moves can't each put a
+	// collection inside the other
+	if _, err := tx.ExecContext(ctx, `SELECT id FROM collections WHERE user_id = $1 ORDER BY id FOR UPDATE`, userID); err != nil {
+		return nil, err
+	}
+
+	var height int
+	var inSubtree bool
+	err = tx.QueryRowContext(ctx, collectionSubtree+`
+		SELECT COALESCE(MAX(depth), -1), COALESCE(bool_or(id = $3), false) FROM subtree
+	`, id, userID, parentID).Scan(&height, &inSubtree)
+	if err != nil {
+		return nil, err
+	}
+	if height < 0 {
+		return nil, ErrCollectionNotFound
+	}
+	if inSubtree {
+		return nil, ErrCollectionCycle
+	}
+
+	depth := 0
+	if parentID != nil {
+		if err := lockCollection(ctx, tx, userID, *parentID, "FOR KEY SHARE"); err != nil {
+			return nil, err
+		}
+		if depth, err = collectionDepth(ctx, tx, *parentID); err != nil {
+			return nil, err
+		}
+	}
+	if depth+1+height > MaxCollectionDepth {
+		return nil, ErrCollectionTooDeep
+	}
+
+	col, err := scanCollection(tx.QueryRowContext(ctx, `
+		UPDATE collections c
+		SET parent_id = $2, updated_at = NOW()
+		WHERE c.id = $1
+		RETURNING `+collectionColumns+`, `+collectionSnippetCount,
+		id, parentID))
+	if err != nil {
+		return nil, collectionWriteError(err)
+	}
+	return col, tx.Commit()
+}
+
+// collectionDepth returns how deeply a collection is nested, 1 at the top level
+func collectionDepth(ctx context.Context, tx *sql.Tx, id int64) (int, error) {
+	var depth int
+	err := tx.QueryRowContext(ctx, `
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM collections WHERE id = $1
+			UNION ALL
+			SELECT c.id, c.parent_id FROM collections c JOIN ancestors ON c.id = ancestors.parent_id
+		)
+		SELECT COUNT(*) FROM ancestors
+	`, id).Scan(&depth)
+	return depth, err
+}
+
+// collectionWriteError maps the errors of a collection insert or update
+func collectionWriteError(err error) error {
+	var pqErr *pq.Error
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrCollectionNotFound
+	case errors.As(err, &pqErr) && pqErr.Code == "23505":
+		return ErrCollectionExists
+	}
+	return err
+}
+
+// DeleteCollection deletes one of the user's collections and all its sub-collections.
+// Their snippets are kept and become unfiled; each gets a snippet.updated event so synced
+// clients see the change. Returns ErrCollectionNotFound if they have no such collection.
+func (st *Store) DeleteCollection(ctx context.Context, userID, originSessionID string, id int64) error {
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer rollbackSnippetTx(tx)
+
+	// Lock the whole sub-tree, so no snippet is filed in it while it's deleted
+	var ids pq.Int64Array
+	err = tx.QueryRowContext(ctx, collectionSubtree+`,
+		locked AS (
+			SELECT c.id FROM collections c JOIN subtree ON c.id = subtree.id FOR UPDATE OF c
+		)
+		SELECT array_agg(id) FROM locked
+	`, id, userID).Scan(&ids)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return ErrCollectionNotFound
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE snippets
+		SET collection_id = NULL
+		WHERE collection_id = ANY($1)
+		RETURNING `+snippetColumns, ids)
+	if err != nil {
+		return err
+	}
+	snippets, err := collectSnippets(rows)
+	if err != nil {
+		return err
+	}
+
+	// Sub-collections go with it through the parent_id cascade
+	if _, err := tx.ExecContext(ctx, `DELETE FROM collections WHERE id = $1`, id); err != nil {
+		return err
+	}
+
+	events := make([]AggregateEvent, 0, len(snippets))
+	for i := range snippets {
+		if snippets[i].IsDeleted {
+			continue
+		}
+		events = append(events, AggregateEvent{AggregateID: strconv.FormatInt(snippets[i].ID, 10), Payload: &snippets[i]})
+	}
+	if err := EnqueueEventsFromSession(ctx, tx, originSessionID, EventSnippetUpdated, AggregateSnippet, events); err != nil {
+		return fmt.Errorf("enqueue %s events: %w", EventSnippetUpdated, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for _, s := range snippets {
+		InvalidateSnippet(ctx, s.ID)
+	}
+	return nil
+}
+
+// lockCollection locks one of the user's collections within tx: FOR KEY SHARE while a
+// snippet or collection is filed in it, so nothing can be filed in a collection being
+// deleted. Returns ErrCollectionNotFound if they have no
+// such collection.
+func lockCollection(ctx context.Context, tx *sql.Tx, userID string, id int64, lock string) error {
+	var found int64
+	err := tx.QueryRowContext(ctx, `
+		SELECT id FROM collections WHERE id = $1 AND user_id = $2 `+lock,
+		id, userID).Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrCollectionNotFound
+	}
+	return err
+}
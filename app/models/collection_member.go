@@ -0,0 +1,249 @@
+// Package models provides the members of shared collections and their roles.
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Collection member roles. Viewers can read the collection's snippets and editors can
+// also change them like the editors of a shared snippet. Owners can manage the members
+// too, as the collection's creator can.
+const (
+	CollectionRoleOwner  = "owner"
+	CollectionRoleEditor = ShareRoleEditor
+	CollectionRoleViewer = ShareRoleViewer
+)
+
+// MaxCollectionMembers is how many members a collection can have
+const MaxCollectionMembers = 50
+
+var (
+	// ErrCollectionForbidden is returned when a member who isn't an owner manages the
+	// collection's members
+	ErrCollectionForbidden = errors.New("only the collection's owners can manage its members")
+	// ErrCollectionMemberNotFound is returned when removing a user who isn't a member
+	ErrCollectionMemberNotFound = errors.New("collection member not found")
+	// ErrCollectionMemberLimitReached is returned when adding a member would exceed
+	// MaxCollectionMembers
+	ErrCollectionMemberLimitReached = errors.New("collection member limit reached")
+	// ErrCollectionCreatorMember is returned when adding the collection's creator as a member
+	ErrCollectionCreatorMember = errors.New("the collection's creator is always its owner")
+)
+
+// CollectionMember is a user a collection is shared with
+type CollectionMember struct {
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	UserID    string    `json:"userId"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+}
+
+// CollectionMemberRequest adds a member to a collection or changes their role
+type CollectionMemberRequest struct {
+	Role string `json:"role" binding:"required,oneof=owner editor viewer"`
+}
+
+// collectionRoleRank orders member roles m.role from the strongest
+const collectionRoleRank = `CASE m.role WHEN 'owner' THEN 0 WHEN 'editor' THEN 1 ELSE 2 END`
+
+// CollectionRole returns userID's role on a collection: CollectionRoleOwner for its
+// creator, otherwise the strongest of their member roles on it and the collections it's
+// in, or "" if it isn't shared with them. Returns ErrCollectionNotFound if the collection
+// doesn't exist.
+func CollectionRole(ctx context.Context, q RowQueryer, id int64, userID string) (string, error) {
+	var creator sql.NullBool
+	var role string
+	err := q.QueryRowContext(ctx, `
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM collections WHERE id = $1
+			UNION ALL
+			SELECT c.id, c.parent_id FROM collections c JOIN ancestors a ON c.id = a.parent_id
+		)
+		SELECT
+			(SELECT user_id = $2 FROM collections WHERE id = $1),
+			COALESCE((
+				SELECT m.role FROM collection_members m
+				WHERE m.user_id = $2 AND m.collection_id IN (SELECT id FROM ancestors)
+				ORDER BY `+collectionRoleRank+` LIMIT 1
+			), '')
+	`, id, userID).Scan(&creator, &role)
+	if err != nil {
+		return "", err
+	}
+	if !creator.Valid {
+		return "", ErrCollectionNotFound
+	}
+	if creator.Bool {
+		return CollectionRoleOwner, nil
+	}
+	return role, nil
+}
+
+// checkCollectionManager verifies userID may manage a collection's members. Returns
+// ErrCollectionNotFound if the collection doesn't exist or isn't shared with them and
+// ErrCollectionForbidden if they're a member but not an owner.
+func checkCollectionManager(ctx context.Context, q RowQueryer, id int64, userID string) error {
+	role, err := CollectionRole(ctx, q, id, userID)
+	switch {
+	case err != nil:
+		return err
+	case role == "":
+		return ErrCollectionNotFound
+	case role != CollectionRoleOwner:
+		return ErrCollectionForbidden
+	}
+	return nil
+}
+
+// SetCollectionMember adds userID to a collection as role, or changes their role, and
+// reports whether they're a new member. Only the collection's owners can. Returns
+// ErrCollectionNotFound if the collection doesn't exist or isn't shared with actorID,
+// ErrCollectionForbidden if actorID isn't one of its owners, ErrCollectionCreatorMember
+// for the collection's creator, ErrShareUserNotFound if userID isn't a user of the
+// creator's organization, ErrBlocked if the creator and userID blocked one another and
+// ErrCollectionMemberLimitReached if the collection has MaxCollectionMembers already.
+func (st *Store) SetCollectionMember(ctx context.Context, id int64, actorID, userID, role string) (*CollectionMember, bool, error) {
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rollbackSnippetTx(tx)
+
+	// Locking the collection serializes its member changes, so concurrent ones can't pass
+	// the limit together
+	var creatorID string
+	err = tx.QueryRowContext(ctx, `SELECT user_id FROM collections WHERE id = $1 FOR UPDATE`, id).Scan(&creatorID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, ErrCollectionNotFound
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if err := checkCollectionManager(ctx, tx, id, actorID); err != nil {
+		return nil, false, err
+	}
+	if userID == creatorID {
+		return nil, false, ErrCollectionCreatorMember
+	}
+
+	member := CollectionMember{UserID: userID, Role: role}
+	var blocked bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT username, `+blockBetweenCondition+`
+		FROM users WHERE id = $2 AND is_deleted = false
+		  AND org_id = (SELECT org_id FROM users WHERE id = $1)
+	`, creatorID, userID).Scan(&member.Username, &blocked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, ErrShareUserNotFound
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if blocked {
+		return nil, false, ErrBlocked
+	}
+
+	var existing bool
+	var others int
+	err = tx.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*) FILTER (WHERE user_id = $2) > 0,
+			COUNT(*) FILTER (WHERE user_id <> $2)
+		FROM collection_members
+		WHERE collection_id = $1
+	`, id, userID).Scan(&existing, &others)
+	if err != nil {
+		return nil, false, err
+	}
+	if !existing && others >= MaxCollectionMembers {
+		return nil, false, ErrCollectionMemberLimitReached
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO collection_members (collection_id, user_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (collection_id, user_id) DO UPDATE SET
+			role = EXCLUDED.role,
+			updated_at = CASE WHEN collection_members.role = EXCLUDED.role THEN collection_members.updated_at ELSE NOW() END
+		RETURNING created_at, updated_at
+	`, id, userID, role).Scan(&member.CreatedAt, &member.UpdatedAt)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+	return &member, !existing, nil
+}
+
+// RemoveCollectionMember removes userID from a collection's members. The collection's
+// owners can remove anyone and members can leave. Returns ErrCollectionNotFound if the
+// collection doesn't exist or isn't shared with actorID, ErrCollectionForbidden if
+// actorID may not remove the member and ErrCollectionMemberNotFound if userID isn't one.
+func (st *Store) RemoveCollectionMember(ctx context.Context, id int64, actorID, userID string) error {
+	if actorID != userID {
+		if err := checkCollectionManager(ctx, st.db, id, actorID); err != nil {
+			return err
+		}
+	}
+
+	result, err := st.db.ExecContext(ctx, `
+		DELETE FROM collection_members WHERE collection_id = $1 AND user_id = $2
+	`, id, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrCollectionMemberNotFound
+	}
+	return nil
+}
+
+// ListCollectionMembers returns the members of a collection, in the order they joined.
+// Its creator and members can list them. Returns ErrCollectionNotFound if the collection
+// doesn't exist or isn't shared with userID.
+func (st *Store) ListCollectionMembers(ctx context.Context, id int64, userID string) ([]CollectionMember, error) {
+	role, err := CollectionRole(ctx, st.db, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role == "" {
+		return nil, ErrCollectionNotFound
+	}
+
+	rows, err := st.db.QueryContext(ctx, `
+		SELECT m.user_id, u.username, m.role, m.created_at, m.updated_at
+		FROM collection_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.collection_id = $1 AND u.is_deleted = false
+		ORDER BY m.created_at, u.username
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing collection member rows: %v\n", closeErr)
+		}
+	}()
+
+	members := make([]CollectionMember, 0)
+	for rows.Next() {
+		var member CollectionMember
+		if err := rows.Scan(&member.UserID, &member.Username, &member.Role, &member.CreatedAt, &member.UpdatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
@@ -0,0 +1,135 @@
+// Package models provides push notification device tokens.
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Push platforms
+const (
+	PlatformFCM  = "fcm"
+	PlatformAPNs = "apns"
+)
+
+// DeviceToken is a mobile device registered for push notifications
+type DeviceToken struct {
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	SessionID  *string   `json:"sessionId,omitempty"`
+	Platform   string    `json:"platform"`
+	Token      string    `json:"token"`
+	UserID     string    `json:"-"`
+	ID         int64     `json:"id"`
+}
+
+// RegisterDeviceTokenRequest registers a device for push notifications
+type RegisterDeviceTokenRequest struct {
+	Platform string `json:"platform" binding:"required,oneof=fcm apns"`
+	Token    string `json:"token" binding:"required,max=4096"`
+}
+
+const deviceTokenColumns = `id, user_id, session_id, platform, token, created_at, last_seen_at`
+
+// scanDeviceToken scans a database row into a DeviceToken
+func scanDeviceToken(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*DeviceToken, error) {
+	var d DeviceToken
+	var sessionID sql.NullString
+	if err := scanner.Scan(&d.ID, &d.UserID, &sessionID, &d.Platform, &d.Token, &d.CreatedAt, &d.LastSeenAt); err != nil {
+		return nil, err
+	}
+	if sessionID.Valid {
+		d.SessionID = &sessionID.String
+	}
+	return &d, nil
+}
+
+// RegisterDeviceToken stores a device token for userID. Tokens are unique per device, so
+// registering an existing token moves it to the current user and session.
+func (st *Store) RegisterDeviceToken(ctx context.Context, userID, sessionID string, req RegisterDeviceTokenRequest) (*DeviceToken, error) {
+	var session interface{}
+	if sessionID != "" {
+		session = sessionID
+	}
+
+	row := st.db.QueryRowContext(ctx, `
+		INSERT INTO device_tokens (user_id, session_id, platform, token)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (token) DO UPDATE SET
+			user_id = EXCLUDED.user_id,
+			session_id = EXCLUDED.session_id,
+			platform = EXCLUDED.platform,
+			last_seen_at = NOW()
+		RETURNING `+deviceTokenColumns,
+		userID, session, req.Platform, req.Token)
+	return scanDeviceToken(row)
+}
+
+// GetUserDeviceTokens returns a user's registered devices, most recently seen first.
+func (st *Store) GetUserDeviceTokens(ctx context.Context, userID string) ([]DeviceToken, error) {
+	return st.queryDeviceTokens(ctx, `
+		SELECT `+deviceTokenColumns+`
+		FROM device_tokens
+		WHERE user_id = $1
+		ORDER BY last_seen_at DESC
+	`, userID)
+}
+
+// GetPushTargets returns the devices to notify about a change by userID,
+// excluding the session that made it (pass "" to notify every device).
+func (st *Store) GetPushTargets(ctx context.Context, userID, excludeSessionID string) ([]DeviceToken, error) {
+	return st.queryDeviceTokens(ctx, `
+		SELECT `+deviceTokenColumns+`
+		FROM device_tokens
+		WHERE user_id = $1 AND ($2 = '' OR session_id IS NULL OR session_id <> $2)
+	`, userID, excludeSessionID)
+}
+
+// queryDeviceTokens runs a query returning device token rows
+func (st *Store) queryDeviceTokens(ctx context.Context, query string, args ...interface{}) ([]DeviceToken, error) {
+	rows, err := st.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing device token rows: %v\n", closeErr)
+		}
+	}()
+
+	tokens := make([]DeviceToken, 0)
+	for rows.Next() {
+		d, err := scanDeviceToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *d)
+	}
+	return tokens, rows.Err()
+}
+
+// DeleteDeviceToken unregisters one of a user's devices. Returns sql.ErrNoRows if it doesn't exist.
+func (st *Store) DeleteDeviceToken(ctx context.Context, userID string, id int64) error {
+	result, err := st.db.ExecContext(ctx, `DELETE FROM device_tokens WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteDeviceTokenByValue removes a token the push provider reported as invalid.
+func (st *Store) DeleteDeviceTokenByValue(ctx context.Context, token string) error {
+	_, err := st.db.ExecContext(ctx, `DELETE FROM device_tokens WHERE token = $1`, token)
+	return err
+}
@@ -0,0 +1,194 @@
+// Package models provides activity data for the weekly digest email.
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// DigestRecipient is a user who has the weekly digest enabled
+type DigestRecipient struct {
+	LastSentAt *time.Time
+	UserID     string
+	Username   string
+	Email      string
+	Timezone   string
+}
+
+// DigestSnippet is one of a user's most used snippets in the digest period
+type DigestSnippet struct {
+	Label    string
+	Shortcut string
+	Uses     int
+}
+
+// DigestDevice is a device that synced during the digest period
+type DigestDevice struct {
+	LastSyncedAt time.Time
+	DeviceInfo   string
+}
+
+// ActivityDigest summarizes a user's activity over a period
+type ActivityDigest struct {
+	PeriodStart   time.Time
+	PeriodEnd     time.Time
+	TopSnippets   []DigestSnippet
+	Devices       []DigestDevice
+	SnippetsAdded int
+}
+
+// IsEmpty reports whether there was no activity worth reporting
+func (d *ActivityDigest) IsEmpty() bool {
+	return d.SnippetsAdded == 0 && len(d.TopSnippets) == 0 && len(d.Devices) == 0
+}
+
+// digestTopSnippetLimit is how many most-used snippets a digest lists
+const digestTopSnippetLimit = 5
+
+// GetDigestRecipients returns active users with the weekly digest enabled.
+// Users without stored preferences get the defaults (enabled, UTC).
+func (st *Store) GetDigestRecipients(ctx context.Context) ([]DigestRecipient, error) {
+	query := `
+		SELECT u.id, u.username, u.email, COALESCE(p.timezone, 'UTC'), p.digest_last_sent_at
+		FROM users u
+		LEFT JOIN notification_preferences p ON p.user_id = u.id
+		WHERE u.is_deleted = false AND COALESCE(p.weekly_digest, true)
+	`
+
+	rows, err := st.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing digest recipient rows: %v\n", closeErr)
+		}
+	}()
+
+	recipients := make([]DigestRecipient, 0)
+	for rows.Next() {
+		var r DigestRecipient
+		var lastSent sql.NullTime
+		if err := rows.Scan(&r.UserID, &r.Username, &r.Email, &r.Timezone, &lastSent); err != nil {
+			return nil, err
+		}
+		if lastSent.Valid {
+			r.LastSentAt = &lastSent.Time
+		}
+		recipients = append(recipients, r)
+	}
+	return recipients, rows.Err()
+}
+
+// GetActivityDigest collects a user's activity between since and until.
+func (st *Store) GetActivityDigest(ctx context.Context, userID string, since, until time.Time) (*ActivityDigest, error) {
+	digest := &ActivityDigest{PeriodStart: since, PeriodEnd: until}
+
+	err := st.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM snippets
+		WHERE user_id = $1 AND is_deleted = false AND created_at >= $2 AND created_at < $3
+	`, userID, since, until).Scan(&digest.SnippetsAdded)
+	if err != nil {
+		return nil, err
+	}
+
+	if digest.TopSnippets, err = st.topUsedSnippets(ctx, userID, since, until); err != nil {
+		return nil, err
+	}
+	if digest.Devices, err = st.syncedDevices(ctx, userID, since, until); err != nil {
+		return nil, err
+	}
+	return digest, nil
+}
+
+// topUsedSnippets returns the user's most used snippets in the period
+func (st *Store) topUsedSnippets(ctx context.Context, userID string, since, until time.Time) ([]DigestSnippet, error) {
+	rows, err := st.db.QueryContext(ctx, `
+		SELECT s.label, s.shortcut, COUNT(*) AS uses
+		FROM snippet_usage su
+		JOIN snippets s ON s.id = su.snippet_id
+		WHERE su.user_id = $1 AND s.is_deleted = false AND su.used_at >= $2 AND su.used_at < $3
+		GROUP BY s.id, s.label, s.shortcut
+		ORDER BY uses DESC, s.label
+		LIMIT $4
+	`, userID, since, until, digestTopSnippetLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing top snippet rows: %v\n", closeErr)
+		}
+	}()
+
+	snippets := make([]DigestSnippet, 0, digestTopSnippetLimit)
+	for rows.Next() {
+		var s DigestSnippet
+		if err := rows.Scan(&s.Label, &s.Shortcut, &s.Uses); err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, s)
+	}
+	return snippets, rows.Err()
+}
+
+// syncedDevices returns the user's sessions that synced in the period
+func (st *Store) syncedDevices(ctx context.Context, userID string, since, until time.Time) ([]DigestDevice, error) {
+	rows, err := st.db.QueryContext(ctx, `
+		SELECT COALESCE(NULLIF(device_info, ''), 'Unknown device'), last_synced_at
+		FROM sessions
+		WHERE user_id = $1 AND last_synced_at >= $2 AND last_synced_at < $3
+		ORDER BY last_synced_at DESC
+	`, userID, since, until)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing synced device rows: %v\n", closeErr)
+		}
+	}()
+
+	devices := make([]DigestDevice, 0)
+	for rows.Next() {
+		var d DigestDevice
+		if err := rows.Scan(&d.DeviceInfo, &d.LastSyncedAt); err != nil {
+			return nil, err
+		}
+		devices = append(devices, d)
+	}
+	return devices, rows.Err()
+}
+
+// MarkDigestSent records when a user's digest was last processed.
+func (st *Store) MarkDigestSent(ctx context.Context, userID string, sentAt time.Time) error {
+	_, err := st.db.ExecContext(ctx, `
+		INSERT INTO notification_preferences (user_id, digest_last_sent_at)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET digest_last_sent_at = $2
+	`, userID, sentAt)
+	return err
+}
+
+// RecordSnippetUse records that the owner used (expanded) a snippet.
+// Returns sql.ErrNoRows if the snippet doesn't exist or isn't owned by userID.
+func (st *Store) RecordSnippetUse(ctx context.Context, snippetID int64, userID string) error {
+	result, err := st.db.ExecContext(ctx, `
+		INSERT INTO snippet_usage (snippet_id, user_id)
+		SELECT id, user_id FROM snippets
+		WHERE id = $1 AND user_id = $2 AND is_deleted = false
+	`, snippetID, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+// Package models provides shortcut expansion lookups for text expanders and chat integrations.
+package models
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Expansion is the content a shortcut expands to
+type Expansion struct {
+	Shortcut string `json:"shortcut"`
+	Label    string `json:"label"`
+	Content  string `json:"content"`
+	ID       int64  `json:"id"`
+}
+
+// RenderSnippetRequest holds the values of a snippet's placeholders, keyed by name
+type RenderSnippetRequest struct {
+	Values map[string]interface{} `json:"values"`
+}
+
+// ExpandShortcut resolves one of userID's shortcuts to its snippet. When recordUse is set
+// the use is recorded in the same statement. Returns sql.ErrNoRows if no snippet has the
+// shortcut.
+func (st *Store) ExpandShortcut(ctx context.Context, userID, shortcut string, recordUse bool) (*Expansion, error) {
+	var e Expansion
+	err := st.db.QueryRowContext(ctx, `
+		WITH match AS (
+			SELECT id, shortcut, label, content FROM snippets
+			WHERE user_id = $1 AND shortcut = $2 AND is_deleted = false AND archived_at IS NULL
+		), used AS (
+			INSERT INTO snippet_usage (snippet_id, user_id)
+			SELECT id, $1 FROM match WHERE $3
+		)
+		SELECT id, shortcut, label, content FROM match
+	`, userID, shortcut, recordUse).Scan(&e.ID, &e.Shortcut, &e.Label, &e.Content)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// GetSnippetByShortcut returns userID's snippet with shortcut. Archived snippets aren't
+// resolved, like in expansion. Returns sql.ErrNoRows if no snippet has the shortcut.
+func (st *Store) GetSnippetByShortcut(ctx context.Context, userID, shortcut string) (*Snippet, error) {
+	return ScanSnippet(st.db.QueryRowContext(ctx, `
+		SELECT `+snippetColumns+`
+		FROM snippets
+		WHERE user_id = $1 AND shortcut = $2 AND is_deleted = false AND archived_at IS NULL
+	`, userID, shortcut))
+}
+
+// likeEscaper escapes LIKE wildcards so user input matches literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchShortcuts returns up to limit of userID's snippets whose shortcut starts with
+// prefix, in shortcut order, for autocompletion
+func (st *Store) SearchShortcuts(ctx context.Context, userID, prefix string, limit int) ([]Expansion, error) {
+	rows, err := st.db.QueryContext(ctx, `
+		SELECT id, shortcut, label, content FROM snippets
+		WHERE user_id = $1 AND shortcut LIKE $2 AND is_deleted = false AND archived_at IS NULL
+		ORDER BY shortcut, updated_at DESC
+		LIMIT $3
+	`, userID, likeEscaper.Replace(prefix)+"%", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing shortcut rows: %v\n", closeErr)
+		}
+	}()
+
+	expansions := make([]Expansion, 0, limit)
+	for rows.Next() {
+		var e Expansion
+		if err := rows.Scan(&e.ID, &e.Shortcut, &e.Label, &e.Content); err != nil {
+			return nil, err
+		}
+		expansions = append(expansions, e)
+	}
+	return expansions, rows.Err()
+}
@@ -0,0 +1,216 @@
+// Package models provides scoped browser-extension tokens.
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Scopes of extension tokens and API keys
+const (
+	// ScopeSnippetsRead allows listing, syncing, searching and reading snippets
+	ScopeSnippetsRead = "snippets:read"
+	// ScopeSnippetsWrite allows creating, updating and deleting snippets
+	ScopeSnippetsWrite = "snippets:write"
+	// ScopeUsageWrite allows recording snippet use
+	ScopeUsageWrite = "usage:write"
+)
+
+// ExtensionScopes are the scopes every extension token is issued with
+var ExtensionScopes = []string{ScopeSnippetsRead, ScopeUsageWrite}
+
+const (
+	// ExtensionTokenPrefix starts every extension token, telling it apart from access tokens
+	ExtensionTokenPrefix = "snx_"
+
+	// ExtensionTokenDuration is how long an extension token stays valid (one year)
+	ExtensionTokenDuration = 365 * 24 * time.Hour
+
+	// ExtensionTokenMaxPerUser is how many unrevoked extension tokens a user may hold
+	ExtensionTokenMaxPerUser = 10
+
+	// extensionTokenTouchInterval limits how often last_used_at is written
+	extensionTokenTouchInterval = 5 * time.Minute
+)
+
+// ErrExtensionTokenLimit is returned when a user already has ExtensionTokenMaxPerUser tokens
+var ErrExtensionTokenLimit = errors.New("extension token limit reached")
+
+// ExtensionToken is a long-lived token a browser extension uses instead of a session
+type ExtensionToken struct {
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	// Token is only returned when the token is created
+	Token  string   `json:"token,omitempty"`
+	UserID string   `json:"-"`
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	ID     int64    `json:"id"`
+}
+
+// CreateExtensionTokenRequest names a new extension token, e.g. after the browser it's used in
+type CreateExtensionTokenRequest struct {
+	Name string `json:"name" binding:"max=100"`
+}
+
+// HasScope reports whether the token grants scope
+func (t *ExtensionToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsExtensionToken reports whether a bearer token is an extension token
+func IsExtensionToken(token string) bool {
+	return strings.HasPrefix(token, ExtensionTokenPrefix)
+}
+
+// hashExtensionToken hashes an extension token for storage and lookup
+func hashExtensionToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+const extensionTokenColumns = `id, user_id, name, scopes, last_used_at, expires_at, created_at`
+
+// scanExtensionToken scans a database row into an ExtensionToken
+func scanExtensionToken(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*ExtensionToken, error) {
+	var t ExtensionToken
+	var scopes pq.StringArray
+	var lastUsedAt sql.NullTime
+	if err := scanner.Scan(&t.ID, &t.UserID, &t.Name, &scopes, &lastUsedAt, &t.ExpiresAt, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	t.Scopes = scopes
+	if lastUsedAt.Valid {
+		t.LastUsedAt = &lastUsedAt.Time
+	}
+	return &t, nil
+}
+
+// CreateExtensionToken issues an extension token for userID with ExtensionScopes. The
+// returned token carries the secret, which is only stored hashed. Returns
+// ErrExtensionTokenLimit when the user already has ExtensionTokenMaxPerUser active tokens.
+func (st *Store) CreateExtensionToken(ctx context.Context, userID, name string) (*ExtensionToken, error) {
+	secret, err := GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	token := ExtensionTokenPrefix + strings.TrimRight(secret, "=")
+
+	// Only tokens that are neither revoked nor expired count, so an expired token frees its
+	// slot without being revoked first
+	row := st.db.QueryRowContext(ctx, `
+		INSERT INTO extension_tokens (user_id, token_hash, name, scopes, expires_at)
+		SELECT $1, $2, $3, $4, $5
+		WHERE (
+			SELECT COUNT(*) FROM extension_tokens
+			WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		) < $6
+		RETURNING `+extensionTokenColumns,
+		userID, hashExtensionToken(token), name, pq.Array(ExtensionScopes),
+		time.Now().Add(ExtensionTokenDuration), ExtensionTokenMaxPerUser)
+	t, err := scanExtensionToken(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrExtensionTokenLimit
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.Token = token
+	return t, nil
+}
+
+// ValidateExtensionToken returns the active token matching token. Returns sql.ErrNoRows
+// if it doesn't exist, was revoked, has expired, or its owner was deleted or isn't in orgID.
+func (st *Store) ValidateExtensionToken(ctx context.Context, token, orgID string) (*ExtensionToken, error) {
+	t, err := scanExtensionToken(st.db.QueryRowContext(ctx, `
+		SELECT t.id, t.user_id, t.name, t.scopes, t.last_used_at, t.expires_at, t.created_at
+		FROM extension_tokens t
+		JOIN users u ON u.id = t.user_id
+		WHERE t.token_hash = $1 AND t.revoked_at IS NULL AND t.expires_at > NOW()
+			AND u.is_deleted = false AND u.org_id = $2
+	`, hashExtensionToken(token), orgID))
+	if err != nil {
+		return nil, err
+	}
+
+	if t.LastUsedAt == nil || time.Since(*t.LastUsedAt) > extensionTokenTouchInterval {
+		if _, err := st.db.ExecContext(ctx,
+			`UPDATE extension_tokens SET last_used_at = NOW() WHERE id = $1`, t.ID); err != nil {
+			fmt.Printf("failed to update extension token %d last use: %v\n", t.ID, err)
+		}
+	}
+	return t, nil
+}
+
+// GetUserExtensionTokens lists a user's active extension tokens, newest first
+func (st *Store) GetUserExtensionTokens(ctx context.Context, userID string) ([]ExtensionToken, error) {
+	rows, err := st.db.QueryContext(ctx, `
+		SELECT `+extensionTokenColumns+`
+		FROM extension_tokens
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing extension token rows: %v\n", closeErr)
+		}
+	}()
+
+	tokens := make([]ExtensionToken, 0)
+	for rows.Next() {
+		t, err := scanExtensionToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *t)
+	}
+	return tokens, rows.Err()
+}
+
+// RevokeExtensionToken revokes one of a user's extension tokens. Returns sql.ErrNoRows if
+// the user has no such active token.
+func (st *Store) RevokeExtensionToken(ctx context.Context, userID string, id int64) error {
+	result, err := st.db.ExecContext(ctx, `
+		UPDATE extension_tokens SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, id, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RevokeAllUserExtensionTokens revokes every extension token a user holds
+func (st *Store) RevokeAllUserExtensionTokens(ctx context.Context, userID string) error {
+	_, err := st.db.ExecContext(ctx, `
+		UPDATE extension_tokens SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID)
+	return err
+}
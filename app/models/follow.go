@@ -0,0 +1,175 @@
+// Package models provides user follows and the followed-users feed.
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// FollowedUser is a user in a following or followers list
+type FollowedUser struct {
+	FollowedAt time.Time `json:"followedAt"`
+	ID         string    `json:"id"`
+	Username   string    `json:"username"`
+	FullName   string    `json:"fullName"`
+	AvatarURL  string    `json:"avatarUrl"`
+}
+
+// FeedItem is a public snippet published by a followed user
+type FeedItem struct {
+	Author  PublicProfile `json:"author"`
+	Snippet Snippet       `json:"snippet"`
+}
+
+// FollowUser makes followerID follow followeeID. It reports whether a new follow was created;
+// following someone twice is not an error. Returns sql.ErrNoRows if the followee doesn't exist
+// or is in another organization, and ErrBlocked if either user has blocked the other.
+func (st *Store) FollowUser(ctx context.Context, followerID, followeeID string) (bool, error) {
+	query := `
+		WITH target AS (
+			SELECT id, ` + blockBetweenCondition + ` AS blocked
+			FROM users WHERE id = $2 AND is_deleted = false
+			  AND org_id = (SELECT org_id FROM users WHERE id = $1)
+		), inserted AS (
+			INSERT INTO follows (follower_id, followee_id)
+			SELECT $1, id FROM target WHERE NOT blocked
+			ON CONFLICT DO NOTHING
+			RETURNING 1
+		)
+		SELECT EXISTS (SELECT 1 FROM target), EXISTS (SELECT 1 FROM target WHERE blocked), EXISTS (SELECT 1 FROM inserted)
+	`
+
+	var found, blocked, created bool
+	if err := st.db.QueryRowContext(ctx, query, followerID, followeeID).Scan(&found, &blocked, &created); err != nil {
+		return false, err
+	}
+	if !found {
+		return false, sql.ErrNoRows
+	}
+	if blocked {
+		return false, ErrBlocked
+	}
+	return created, nil
+}
+
+// UnfollowUser removes a follow. Returns sql.ErrNoRows if followerID wasn't following followeeID.
+func (st *Store) UnfollowUser(ctx context.Context, followerID, followeeID string) error {
+	result, err := st.db.ExecContext(ctx, `
+		DELETE FROM follows WHERE follower_id = $1 AND followee_id = $2
+	`, followerID, followeeID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetFollowing returns a page of the users userID follows, most recently followed first,
+// and how many they follow in all.
+func (st *Store) GetFollowing(ctx context.Context, userID string, limit, offset int) ([]FollowedUser, int, error) {
+	return st.queryFollowedUsers(ctx, `
+		SELECT u.id, u.username, u.full_name, u.avatar_url, f.created_at, COUNT(*) OVER()
+		FROM follows f
+		JOIN users u ON u.id = f.followee_id
+		WHERE f.follower_id = $1 AND u.is_deleted = false
+		ORDER BY f.created_at DESC
+		LIMIT $2 OFFSET $3
+	`, userID, limit, offset)
+}
+
+// GetFollowers returns a page of the users following userID, most recent first, and how
+// many follow them in all.
+func (st *Store) GetFollowers(ctx context.Context, userID string, limit, offset int) ([]FollowedUser, int, error) {
+	return st.queryFollowedUsers(ctx, `
+		SELECT u.id, u.username, u.full_name, u.avatar_url, f.created_at, COUNT(*) OVER()
+		FROM follows f
+		JOIN users u ON u.id = f.follower_id
+		WHERE f.followee_id = $1 AND u.is_deleted = false
+		ORDER BY f.created_at DESC
+		LIMIT $2 OFFSET $3
+	`, userID, limit, offset)
+}
+
+// queryFollowedUsers runs a query returning followed user rows and their total count
+func (st *Store) queryFollowedUsers(ctx context.Context, query string, args ...interface{}) ([]FollowedUser, int, error) {
+	rows, err := st.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing follow rows: %v\n", closeErr)
+		}
+	}()
+
+	total := 0
+	users := make([]FollowedUser, 0)
+	for rows.Next() {
+		var u FollowedUser
+		if err := rows.Scan(&u.ID, &u.Username, &u.FullName, &u.AvatarURL, &u.FollowedAt, &total); err != nil {
+			return nil, 0, err
+		}
+		users = append(users, u)
+	}
+	return users, total, rows.Err()
+}
+
+// GetFeed returns public snippets from the users userID follows, newest first.
+// Pass the previous page's oldest createdAt as before to page through the feed.
+func (st *Store) GetFeed(ctx context.Context, userID string, before *time.Time, limit int) ([]FeedItem, error) {
+	query := `
+		SELECT s.id, s.label, s.shortcut, s.content, s.tags, s.user_id, s.created_at, s.updated_at, s.visibility,
+		       u.username, u.full_name, u.avatar_url, u.created_at
+		FROM follows f
+		JOIN snippets s ON s.user_id = f.followee_id
+		JOIN users u ON u.id = f.followee_id
+		WHERE f.follower_id = $1
+		  AND s.visibility = $2
+		  AND s.is_deleted = false
+		  AND u.is_deleted = false
+		  AND ($3::timestamptz IS NULL OR s.created_at < $3)
+		ORDER BY s.created_at DESC, s.id DESC
+		LIMIT $4
+	`
+
+	rows, err := st.db.QueryContext(ctx, query, userID, VisibilityPublic, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing feed rows: %v\n", closeErr)
+		}
+	}()
+
+	items := make([]FeedItem, 0)
+	for rows.Next() {
+		var item FeedItem
+		var tags pq.StringArray
+		var snippetUserID sql.NullString
+		s := &item.Snippet
+		a := &item.Author
+		if err := rows.Scan(
+			&s.ID, &s.Label, &s.Shortcut, &s.Content, &tags, &snippetUserID, &s.CreatedAt, &s.UpdatedAt, &s.Visibility,
+			&a.Username, &a.FullName, &a.AvatarURL, &a.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		s.Tags = tags
+		if snippetUserID.Valid {
+			s.UserID = &snippetUserID.String
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
@@ -0,0 +1,241 @@
+// Package models provides Git repository mirrors of users' snippets.
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Git mirror sync modes
+const (
+	// GitMirrorOnChange pushes shortly after snippets are created, updated or deleted
+	GitMirrorOnChange = "on_change"
+	// GitMirrorSchedule pushes every IntervalHours
+	GitMirrorSchedule = "schedule"
+)
+
+const (
+	// GitMirrorDefaultInterval is the schedule interval when none is given
+	GitMirrorDefaultInterval = 24
+
+	// GitMirrorDebounce is how long an on_change mirror waits after a change, so a burst
+	// of edits becomes a single commit
+	GitMirrorDebounce = 30 * time.Second
+
+	// GitMirrorRetryDelay is the wait before retrying a failed sync
+	GitMirrorRetryDelay = 30 * time.Minute
+
+	// gitMirrorStaleSync is how long a sync may run before another worker reclaims it
+	gitMirrorStaleSync = 10 * time.Minute
+)
+
+// GitMirror is a Git repository a user's snippets are mirrored to
+type GitMirror struct {
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+	NextSyncAt   *time.Time `json:"nextSyncAt,omitempty"`
+	LastSyncedAt *time.Time `json:"lastSyncedAt,omitempty"`
+	LastCommit   *string    `json:"lastCommit,omitempty"`
+	LastError    *string    `json:"lastError,omitempty"`
+	// Token is never returned; HasToken tells whether one is stored
+	Token         string `json:"-"`
+	UserID        string `json:"-"`
+	RepoURL       string `json:"repoUrl"`
+	Branch        string `json:"branch"`
+	Username      string `json:"username,omitempty"`
+	Mode          string `json:"mode"`
+	IntervalHours int    `json:"intervalHours"`
+	HasToken      bool   `json:"hasToken"`
+}
+
+// GitMirrorRequest configures a user's Git mirror. An omitted token keeps the stored one.
+type GitMirrorRequest struct {
+	Token         *string `json:"token,omitempty" binding:"omitempty,max=500"`
+	RepoURL       string  `json:"repoUrl" binding:"required,url,max=2048"`
+	Branch        string  `json:"branch" binding:"omitempty,max=255"`
+	Username      string  `json:"username" binding:"omitempty,max=255"`
+	Mode          string  `json:"mode" binding:"omitempty,oneof=on_change schedule"`
+	IntervalHours int     `json:"intervalHours" binding:"omitempty,min=1,max=168"`
+}
+
+const gitMirrorColumns = `user_id, repo_url, branch, username, token, mode, interval_hours,
+	next_sync_at, last_synced_at, last_commit, last_error, created_at, updated_at`
+
+// scanGitMirror scans a database row into a GitMirror
+func scanGitMirror(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*GitMirror, error) {
+	var m GitMirror
+	var nextSync, lastSynced sql.NullTime
+	var lastCommit, lastError sql.NullString
+	if err := scanner.Scan(&m.UserID, &m.RepoURL, &m.Branch, &m.Username, &m.Token, &m.Mode, &m.IntervalHours,
+		&nextSync, &lastSynced, &lastCommit, &lastError, &m.CreatedAt, &m.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if nextSync.Valid {
+		m.NextSyncAt = &nextSync.Time
+	}
+	if lastSynced.Valid {
+		m.LastSyncedAt = &lastSynced.Time
+	}
+	if lastCommit.Valid {
+		m.LastCommit = &lastCommit.String
+	}
+	if lastError.Valid {
+		m.LastError = &lastError.String
+	}
+	m.HasToken = m.Token != ""
+	return &m, nil
+}
+
+// SaveGitMirror creates or replaces a user's Git mirror and schedules a sync right away
+func (st *Store) SaveGitMirror(ctx context.Context, userID string, req GitMirrorRequest) (*GitMirror, error) {
+	if req.Branch == "" {
+		req.Branch = "main"
+	}
+	if req.Mode == "" {
+		req.Mode = GitMirrorOnChange
+	}
+	if req.IntervalHours == 0 {
+		req.IntervalHours = GitMirrorDefaultInterval
+	}
+
+	return scanGitMirror(st.db.QueryRowContext(ctx, `
+		INSERT INTO git_mirrors (user_id, repo_url, branch, username, token, mode, interval_hours, next_sync_at)
+		VALUES ($1, $2, $3, $4, COALESCE($5, ''), $6, $7, NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET repo_url = EXCLUDED.repo_url,
+		    branch = EXCLUDED.branch,
+		    username = EXCLUDED.username,
+		    token = CASE WHEN $5::text IS NULL THEN git_mirrors.token ELSE EXCLUDED.token END,
+		    mode = EXCLUDED.mode,
+		    interval_hours = EXCLUDED.interval_hours,
+		    next_sync_at = NOW(),
+		    last_error = NULL,
+		    updated_at = NOW()
+		RETURNING `+gitMirrorColumns,
+		userID, req.RepoURL, req.Branch, req.Username, req.Token, req.Mode, req.IntervalHours))
+}
+
+// GetGitMirror returns a user's Git mirror, or sql.ErrNoRows
+func (st *Store) GetGitMirror(ctx context.Context, userID string) (*GitMirror, error) {
+	return scanGitMirror(st.db.QueryRowContext(ctx, `
+		SELECT `+gitMirrorColumns+`
+		FROM git_mirrors
+		WHERE user_id = $1
+	`, userID))
+}
+
+// DeleteGitMirror stops mirroring a user's snippets, or returns sql.ErrNoRows. The
+// repository itself is left as it is.
+func (st *Store) DeleteGitMirror(ctx context.Context, userID string) error {
+	result, err := st.db.ExecContext(ctx, `DELETE FROM git_mirrors WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RequestGitMirrorSync makes a user's mirror due now, or returns sql.ErrNoRows
+func (st *Store) RequestGitMirrorSync(ctx context.Context, userID string) error {
+	result, err := st.db.ExecContext(ctx,
+		`UPDATE git_mirrors SET next_sync_at = NOW() WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ScheduleGitMirrorSync makes a user's on_change mirror due after GitMirrorDebounce.
+// A sync that is already due sooner is kept, so changes don't postpone it.
+func (st *Store) ScheduleGitMirrorSync(ctx context.Context, userID string) error {
+	_, err := st.db.ExecContext(ctx, `
+		UPDATE git_mirrors
+		SET next_sync_at = LEAST(COALESCE(next_sync_at, 'infinity'), NOW() + make_interval(secs => $2))
+		WHERE user_id = $1 AND mode = 'on_change'
+	`, userID, GitMirrorDebounce.Seconds())
+	return err
+}
+
+// ClaimGitMirrors marks up to limit due mirrors as syncing and returns them with their
+// tokens. Schedule mirrors are made due again after their interval; on_change mirrors
+// wait for the next change. Deleted accounts are skipped, and syncs stuck for
+// gitMirrorStaleSync (e.g. after a crash) are reclaimed.
+func (st *Store) ClaimGitMirrors(ctx context.Context, limit int) ([]GitMirror, error) {
+	rows, err := st.db.QueryContext(ctx, `
+		UPDATE git_mirrors
+		SET sync_started_at = NOW(),
+		    next_sync_at = CASE WHEN mode = 'schedule'
+		                        THEN NOW() + make_interval(hours => interval_hours)
+		                        ELSE NULL END
+		WHERE user_id IN (
+			SELECT m.user_id
+			FROM git_mirrors m
+			JOIN users u ON u.id = m.user_id
+			WHERE u.is_deleted = false
+			  AND ((m.next_sync_at <= NOW() AND m.sync_started_at IS NULL)
+			       OR m.sync_started_at < NOW() - make_interval(secs => $2))
+			ORDER BY m.next_sync_at NULLS FIRST
+			LIMIT $1
+			FOR UPDATE OF m SKIP LOCKED
+		)
+		RETURNING `+gitMirrorColumns,
+		limit, gitMirrorStaleSync.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing git mirror rows: %v\n", closeErr)
+		}
+	}()
+
+	mirrors := make([]GitMirror, 0)
+	for rows.Next() {
+		m, err := scanGitMirror(rows)
+		if err != nil {
+			return nil, err
+		}
+		mirrors = append(mirrors, *m)
+	}
+	return mirrors, rows.Err()
+}
+
+// MarkGitMirrorSynced records a successful sync that left the branch at commit
+func (st *Store) MarkGitMirrorSynced(ctx context.Context, userID, commit string) error {
+	_, err := st.db.ExecContext(ctx, `
+		UPDATE git_mirrors
+		SET sync_started_at = NULL, last_synced_at = NOW(), last_commit = NULLIF($2, ''), last_error = NULL
+		WHERE user_id = $1
+	`, userID, commit)
+	return err
+}
+
+// MarkGitMirrorFailed records a failed sync and retries it after GitMirrorRetryDelay,
+// unless the mirror is due sooner anyway
+func (st *Store) MarkGitMirrorFailed(ctx context.Context, userID string, syncErr error) error {
+	_, err := st.db.ExecContext(ctx, `
+		UPDATE git_mirrors
+		SET sync_started_at = NULL,
+		    last_error = $2,
+		    next_sync_at = LEAST(COALESCE(next_sync_at, 'infinity'), NOW() + make_interval(secs => $3))
+		WHERE user_id = $1
+	`, userID, syncErr.Error(), GitMirrorRetryDelay.Seconds())
+	return err
+}
@@ -0,0 +1,182 @@
+// Package models provides the mapping of chat (Slack, Discord) users to Snippy users.
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Chat integrations that link their users to Snippy users
+const (
+	IntegrationSlack   = "slack"
+	IntegrationDiscord = "discord"
+)
+
+// IntegrationLinkCodeDuration is how long an integration link code can be used
+const IntegrationLinkCodeDuration = 10 * time.Minute
+
+// IntegrationLink is a chat identity linked to a Snippy user
+type IntegrationLink struct {
+	CreatedAt time.Time `json:"createdAt"`
+	Provider  string    `json:"provider"`
+	// WorkspaceID is the Slack team; Discord user IDs are global, so it's empty for them
+	WorkspaceID    string `json:"workspaceId,omitempty"`
+	ExternalUserID string `json:"externalUserId"`
+	ID             int64  `json:"id"`
+}
+
+// hashIntegrationLinkCode hashes a link code for storage and lookup. Codes are case-insensitive.
+func hashIntegrationLinkCode(code string) string {
+	hash := sha256.Sum256([]byte(strings.ToUpper(strings.TrimSpace(code))))
+	return hex.EncodeToString(hash[:])
+}
+
+// CreateIntegrationLinkCode issues a one-time code that links the Slack or Discord user who
+// enters it to userID. Earlier unused codes of the user stop working.
+func (st *Store) CreateIntegrationLinkCode(ctx context.Context, userID string) (string, time.Time, error) {
+	b := make([]byte, 5)
+	if _, err := rand.Read(b); err != nil {
+		return "", time.Time{}, err
+	}
+	code := base32.StdEncoding.EncodeToString(b)
+	expiresAt := time.Now().Add(IntegrationLinkCodeDuration)
+
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			fmt.Printf("error rolling back integration link code transaction: %v\n", rbErr)
+		}
+	}()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM integration_link_codes WHERE user_id = $1`, userID); err != nil {
+		return "", time.Time{}, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO integration_link_codes (code_hash, user_id, expires_at) VALUES ($1, $2, $3)
+	`, hashIntegrationLinkCode(code), userID, expiresAt); err != nil {
+		return "", time.Time{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", time.Time{}, err
+	}
+	return code, expiresAt, nil
+}
+
+// LinkIntegrationUser uses a link code to map a chat user to the code's owner, replacing
+// any earlier link of that chat user. Returns sql.ErrNoRows if the code is unknown, used
+// or expired.
+func (st *Store) LinkIntegrationUser(ctx context.Context, provider, workspaceID, externalUserID, code string) error {
+	result, err := st.db.ExecContext(ctx, `
+		WITH used AS (
+			DELETE FROM integration_link_codes
+			WHERE code_hash = $4 AND expires_at > NOW()
+			RETURNING user_id
+		)
+		INSERT INTO integration_links (provider, workspace_id, external_user_id, user_id)
+		SELECT $1, $2, $3, user_id FROM used
+		ON CONFLICT (provider, workspace_id, external_user_id)
+		DO UPDATE SET user_id = EXCLUDED.user_id, created_at = NOW()
+	`, provider, workspaceID, externalUserID, hashIntegrationLinkCode(code))
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetIntegrationLinkedUser returns the Snippy user a chat user is linked to. Returns
+// sql.ErrNoRows if they aren't linked or the user was deleted.
+func (st *Store) GetIntegrationLinkedUser(ctx context.Context, provider, workspaceID, externalUserID string) (string, error) {
+	var userID string
+	err := st.db.QueryRowContext(ctx, `
+		SELECT l.user_id
+		FROM integration_links l
+		JOIN users u ON u.id = l.user_id
+		WHERE l.provider = $1 AND l.workspace_id = $2 AND l.external_user_id = $3
+			AND u.is_deleted = false
+	`, provider, workspaceID, externalUserID).Scan(&userID)
+	return userID, err
+}
+
+// UnlinkIntegrationUser removes a chat user's link. Returns sql.ErrNoRows if they weren't linked.
+func (st *Store) UnlinkIntegrationUser(ctx context.Context, provider, workspaceID, externalUserID string) error {
+	result, err := st.db.ExecContext(ctx, `
+		DELETE FROM integration_links
+		WHERE provider = $1 AND workspace_id = $2 AND external_user_id = $3
+	`, provider, workspaceID, externalUserID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetUserIntegrationLinks lists the chat identities linked to a user, newest first
+func (st *Store) GetUserIntegrationLinks(ctx context.Context, userID string) ([]IntegrationLink, error) {
+	rows, err := st.db.QueryContext(ctx, `
+		SELECT id, provider, workspace_id, external_user_id, created_at
+		FROM integration_links
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing integration link rows: %v\n", closeErr)
+		}
+	}()
+
+	links := make([]IntegrationLink, 0)
+	for rows.Next() {
+		var l IntegrationLink
+		if err := rows.Scan(&l.ID, &l.Provider, &l.WorkspaceID, &l.ExternalUserID, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+// DeleteIntegrationLink removes one of a user's chat links. Returns sql.ErrNoRows if the
+// user has no such link.
+func (st *Store) DeleteIntegrationLink(ctx context.Context, userID string, id int64) error {
+	result, err := st.db.ExecContext(ctx, `
+		DELETE FROM integration_links WHERE id = $1 AND user_id = $2
+	`, id, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+// Package models provides login history tracking.
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Login methods recorded in login history
+const (
+	LoginMethodPassword = "password"
+	LoginMethodRefresh  = "refresh"
+)
+
+// Login failure reasons
+const (
+	LoginFailureInvalidPassword = "invalid_password"
+)
+
+// LoginEvent is a single entry in a user's login history
+type LoginEvent struct {
+	CreatedAt     time.Time `json:"createdAt"`
+	SessionID     *string   `json:"sessionId,omitempty"`
+	DeviceInfo    *string   `json:"deviceInfo,omitempty"`
+	FailureReason *string   `json:"failureReason,omitempty"`
+	Method        string    `json:"method"`
+	IPAddressHash string    `json:"ipAddressHash"`
+	ID            int64     `json:"id"`
+	Success       bool      `json:"success"`
+}
+
+// LoginAttempt describes a login to record
+type LoginAttempt struct {
+	UserID        string
+	SessionID     string // empty when no session was created
+	Method        string
+	DeviceInfo    string
+	IPAddress     string
+	FailureReason string
+	Success       bool
+}
+
+// RecordLogin stores a login attempt and, when successful, updates the user's last_login_at.
+// IP addresses are hashed before storage, like sessions.
+func (st *Store) RecordLogin(ctx context.Context, attempt LoginAttempt) error {
+	query := `
+		WITH touched AS (
+			UPDATE users SET last_login_at = NOW()
+			WHERE id = $1 AND $5
+		)
+		INSERT INTO login_events (user_id, session_id, method, device_info, success, failure_reason, ip_address_hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	var sessionID, failureReason interface{}
+	if attempt.SessionID != "" {
+		sessionID = attempt.SessionID
+	}
+	if attempt.FailureReason != "" {
+		failureReason = attempt.FailureReason
+	}
+
+	_, err := st.db.ExecContext(ctx, query,
+		attempt.UserID,
+		sessionID,
+		attempt.Method,
+		attempt.DeviceInfo,
+		attempt.Success,
+		failureReason,
+		hashIP(attempt.IPAddress),
+	)
+	return err
+}
+
+// GetRecentLogins returns a user's most recent login attempts, newest first.
+func (st *Store) GetRecentLogins(ctx context.Context, userID string, limit int) ([]LoginEvent, error) {
+	query := `
+		SELECT id, session_id, method, success, failure_reason, device_info, ip_address_hash, created_at
+		FROM login_events
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+
+	rows, err := st.db.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing login event rows: %v\n", closeErr)
+		}
+	}()
+
+	events := make([]LoginEvent, 0)
+	for rows.Next() {
+		var e LoginEvent
+		var sessionID, failureReason, deviceInfo sql.NullString
+		if err := rows.Scan(&e.ID, &sessionID, &e.Method, &e.Success, &failureReason, &deviceInfo, &e.IPAddressHash, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if sessionID.Valid {
+			e.SessionID = &sessionID.String
+		}
+		if failureReason.Valid {
+			e.FailureReason = &failureReason.String
+		}
+		if deviceInfo.Valid {
+			e.DeviceInfo = &deviceInfo.String
+		}
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+// GetLastLoginAt returns the time of the user's last successful login, or nil if they never logged in.
+func (st *Store) GetLastLoginAt(ctx context.Context, userID string) (*time.Time, error) {
+	var lastLogin sql.NullTime
+	err := st.db.QueryRowContext(ctx, `SELECT last_login_at FROM users WHERE id = $1`, userID).Scan(&lastLogin)
+	if err != nil {
+		return nil, err
+	}
+	if !lastLogin.Valid {
+		return nil, nil
+	}
+	return &lastLogin.Time, nil
+}
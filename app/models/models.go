@@ -3,44 +3,116 @@ package models
 
 import (
 	"database/sql"
+	"encoding/json"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/jheysaaz/snippy-backend/app/placeholder"
 	"github.com/lib/pq"
 )
 
 // Snippet represents a code snippet
 type Snippet struct {
-	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
-	UpdatedAt time.Time  `json:"updatedAt" db:"updated_at"`
-	DeletedAt *time.Time `json:"-" db:"deleted_at"`
-	UserID    *string    `json:"userId,omitempty" db:"user_id"`
-	Label     string     `json:"label" db:"label"`
-	Shortcut  string     `json:"shortcut" db:"shortcut"`
-	Content   string     `json:"content" db:"content"`
-	Tags      []string   `json:"tags" db:"tags"`
-	ID        int64      `json:"id" db:"id"`
-	IsDeleted bool       `json:"-" db:"is_deleted"`
+	CreatedAt    time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updatedAt" db:"updated_at"`
+	DeletedAt    *time.Time `json:"-" db:"deleted_at"`
+	PinnedAt     *time.Time `json:"pinnedAt,omitempty" db:"pinned_at"`
+	ArchivedAt   *time.Time `json:"archivedAt,omitempty" db:"archived_at"`
+	UserID       *string    `json:"userId,omitempty" db:"user_id"`
+	CollectionID *int64     `json:"collectionId,omitempty" db:"collection_id"`
+	Language     *string    `json:"language,omitempty" db:"language"`
+	// Description documents what the snippet does and when to use it, in Markdown
+	Description *string  `json:"description,omitempty" db:"description"`
+	Label       string   `json:"label" db:"label"`
+	Shortcut    string   `json:"shortcut" db:"shortcut"`
+	Content     string   `json:"content" db:"content"`
+	Tags        []string `json:"tags" db:"tags"`
+	// Placeholders are the typed placeholders of Content, for clients to prompt for
+	Placeholders []placeholder.Placeholder `json:"placeholders" db:"placeholders"`
+	Visibility   string                    `json:"visibility" db:"visibility"`
+	Ownership    string                    `json:"ownership,omitempty" db:"-"` // owner, or the share role of a snippet shared with the user
+	ID           int64                     `json:"id" db:"id"`
+	IsFavorite   bool                      `json:"isFavorite" db:"is_favorite"`
+	// KeepHistory exempts the snippet's versions from retention while its owner is premium
+	KeepHistory bool `json:"keepHistory" db:"keep_history"`
+	IsDeleted   bool `json:"-" db:"is_deleted"`
 }
 
+// Snippet visibility levels
+const (
+	VisibilityPrivate = "private"
+	VisibilityPublic  = "public"
+)
+
 // CreateSnippetRequest for creating a new snippet
 type CreateSnippetRequest struct {
 	Label    string   `json:"label" binding:"required,max=255"`
-	Shortcut string   `json:"shortcut" binding:"required,max=50"`    // Short string without spaces
-	Content  string   `json:"content" binding:"required,max=100000"` // 100KB max
-	Tags     []string `json:"tags" binding:"max=20,dive,max=50"`     // Max 20 tags, each max 50 chars
+	Shortcut string   `json:"shortcut" binding:"required,max=50"` // Short string without spaces
+	Content  string   `json:"content" binding:"required"`         // Size limited by the user's plan
+	Tags     []string `json:"tags" binding:"max=20,dive,max=50"`  // Max 20 tags, each max 50 chars
+	// Visibility defaults to private; public snippets appear on the owner's public profile
+	Visibility string `json:"visibility" binding:"omitempty,oneof=private public"`
+	// CollectionID files the snippet in one of the user's collections
+	CollectionID *int64 `json:"collectionId,omitempty"`
+	// Language is the snippet's programming language, such as go, python or bash
+	Language *string `json:"language,omitempty" binding:"omitempty,max=32"`
+	// Description documents the snippet in Markdown
+	Description *string `json:"description,omitempty" binding:"omitempty,max=10000"`
 	// UserID is now extracted from JWT token, not from request body
 }
 
 // UpdateSnippetRequest for updating an existing snippet
 type UpdateSnippetRequest struct {
-	Label       *string  `json:"label,omitempty"`
-	Shortcut    *string  `json:"shortcut,omitempty"`
-	Content     *string  `json:"content,omitempty"`
-	UserID      *string  `json:"userId,omitempty"`      // UUID as string
-	ChangeNotes *string  `json:"changeNotes,omitempty"` // Optional description of the change
+	Label       *string `json:"label,omitempty"`
+	Shortcut    *string `json:"shortcut,omitempty"`
+	Content     *string `json:"content,omitempty"`
+	UserID      *string `json:"userId,omitempty"`      // UUID as string
+	ChangeNotes *string `json:"changeNotes,omitempty"` // Optional description of the change
+	Visibility  *string `json:"visibility,omitempty" binding:"omitempty,oneof=private public"`
+	// CollectionID moves the snippet to one of the user's collections; 0 unfiles it
+	CollectionID *int64 `json:"collectionId,omitempty"`
+	// Language sets the snippet's programming language; "" clears it
+	Language *string `json:"language,omitempty" binding:"omitempty,max=32"`
+	// Description sets the snippet's Markdown description; "" clears it
+	Description *string  `json:"description,omitempty" binding:"omitempty,max=10000"`
 	Tags        []string `json:"tags,omitempty"`
 }
 
+// FavoriteSnippetRequest stars or unstars a snippet
+type FavoriteSnippetRequest struct {
+	Favorite *bool `json:"favorite" binding:"required"`
+}
+
+// KeepHistorySnippetRequest turns keeping all of a snippet's versions on or off
+type KeepHistorySnippetRequest struct {
+	KeepHistory *bool `json:"keepHistory" binding:"required"`
+}
+
+// RenameTagRequest renames a tag on all of a user's snippets. Renaming to a tag a
+// snippet already has merges the two.
+type RenameTagRequest struct {
+	From string `json:"from" binding:"required,max=50"`
+	To   string `json:"to" binding:"required,max=50"`
+}
+
+// Change types of snippet history entries
+const (
+	ChangeCreate     = "create"
+	ChangeEdit       = "edit"
+	ChangeRestore    = "restore"
+	ChangeSoftDelete = "soft_delete"
+)
+
+// ValidChangeType reports whether changeType is a snippet history change type
+func ValidChangeType(changeType string) bool {
+	switch changeType {
+	case ChangeCreate, ChangeEdit, ChangeRestore, ChangeSoftDelete:
+		return true
+	}
+	return false
+}
+
 // SnippetHistory represents a version in snippet history
 type SnippetHistory struct {
 	ChangedAt     time.Time `json:"changedAt"`
@@ -56,6 +128,84 @@ type SnippetHistory struct {
 	VersionNumber int       `json:"versionNumber"`
 }
 
+// totalScanner scans rows ending in a COUNT(*) OVER() column, so the scan helpers for the
+// other columns can be reused; each Scan also stores the total in *total. Columns between
+// the helper's and the total are scanned into extra.
+type totalScanner struct {
+	rows  *sql.Rows
+	total *int
+	extra []interface{}
+}
+
+// Scan scans the row's columns into dest followed by extra and the total
+func (s totalScanner) Scan(dest ...interface{}) error {
+	return s.rows.Scan(append(append(dest, s.extra...), s.total)...)
+}
+
+// extraScanner scans rows of a scan helper's columns followed by more columns, scanned
+// into extra
+type extraScanner struct {
+	rows  *sql.Rows
+	extra []interface{}
+}
+
+// Scan scans the row's columns into dest followed by extra
+func (s extraScanner) Scan(dest ...interface{}) error {
+	return s.rows.Scan(append(dest, s.extra...)...)
+}
+
+// SnippetHighlight is a snippet's label and content with the search matches wrapped in
+// <mark> tags. The text is not HTML-escaped.
+type SnippetHighlight struct {
+	Label   string `json:"label"`
+	Content string `json:"content"`
+}
+
+// SnippetHit is a snippet matching a search, with how well it matches
+type SnippetHit struct {
+	Highlight SnippetHighlight `json:"highlight"`
+	Snippet
+	Rank float64 `json:"rank"`
+}
+
+// hitScanner scans rows of snippet columns followed by the rank, the label and content
+// highlights and a COUNT(*) OVER() total, into hit and *total
+type hitScanner struct {
+	rows  *sql.Rows
+	hit   *SnippetHit
+	total *int
+}
+
+// Scan scans the row's columns into dest followed by the hit's fields and the total
+func (s hitScanner) Scan(dest ...interface{}) error {
+	return s.rows.Scan(append(dest, &s.hit.Rank, &s.hit.Highlight.Label, &s.hit.Highlight.Content, s.total)...)
+}
+
+// insertBatchRows is how many rows a multi-row INSERT writes at once, keeping its
+// parameters well under Postgres' limit of 65535
+const insertBatchRows = 500
+
+// valuesList returns the placeholders of a multi-row VALUES clause for rows rows of
+// columns columns, e.g. "($1, $2), ($3, $4)"
+func valuesList(rows, columns int) string {
+	var sb strings.Builder
+	for r := range rows {
+		if r > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteByte('(')
+		for col := range columns {
+			if col > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteByte('$')
+			sb.WriteString(strconv.Itoa(r*columns + col + 1))
+		}
+		sb.WriteByte(')')
+	}
+	return sb.String()
+}
+
 // ScanSnippet scans a database row into a Snippet struct.
 func ScanSnippet(scanner interface {
 	Scan(dest ...interface{}) error
@@ -63,6 +213,10 @@ func ScanSnippet(scanner interface {
 	var s Snippet
 	var tags pq.StringArray
 	var userID sql.NullString // UUID stored as string
+	var collectionID sql.NullInt64
+	var pinnedAt, archivedAt sql.NullTime
+	var language, description sql.NullString
+	var placeholders []byte
 
 	err := scanner.Scan(
 		&s.ID,
@@ -73,6 +227,15 @@ func ScanSnippet(scanner interface {
 		&userID,
 		&s.CreatedAt,
 		&s.UpdatedAt,
+		&s.Visibility,
+		&collectionID,
+		&s.IsFavorite,
+		&pinnedAt,
+		&archivedAt,
+		&language,
+		&placeholders,
+		&description,
+		&s.KeepHistory,
 	)
 
 	if err != nil {
@@ -83,6 +246,27 @@ func ScanSnippet(scanner interface {
 	if userID.Valid {
 		s.UserID = &userID.String
 	}
+	if collectionID.Valid {
+		s.CollectionID = &collectionID.Int64
+	}
+	if pinnedAt.Valid {
+		s.PinnedAt = &pinnedAt.Time
+	}
+	if archivedAt.Valid {
+		s.ArchivedAt = &archivedAt.Time
+	}
+	if language.Valid {
+		s.Language = &language.String
+	}
+	if description.Valid {
+		s.Description = &description.String
+	}
+	s.Placeholders = []placeholder.Placeholder{}
+	if len(placeholders) > 0 {
+		if err := json.Unmarshal(placeholders, &s.Placeholders); err != nil {
+			return nil, err
+		}
+	}
 
 	return &s, nil
 }
@@ -90,6 +274,7 @@ func ScanSnippet(scanner interface {
 // User represents a user in the system
 type User struct {
 	ID           string     `json:"id"` // UUID as string
+	OrgID        string     `json:"-"`  // Organization the user belongs to
 	CreatedAt    time.Time  `json:"createdAt"`
 	UpdatedAt    time.Time  `json:"updatedAt"`
 	DeletedAt    *time.Time `json:"-"`
@@ -101,6 +286,30 @@ type User struct {
 	IsDeleted    bool       `json:"-"`
 }
 
+// AdminUser is the extended user view returned by admin endpoints
+type AdminUser struct {
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+	DeletedAt       *time.Time `json:"deletedAt,omitempty"`
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt,omitempty"`
+	LastLoginAt     *time.Time `json:"lastLoginAt,omitempty"`
+	ID              string     `json:"id"`
+	Username        string     `json:"username"`
+	Email           string     `json:"email"`
+	FullName        string     `json:"fullName"`
+	Roles           []string   `json:"roles"`
+	IsDeleted       bool       `json:"isDeleted"`
+	EmailVerified   bool       `json:"emailVerified"`
+}
+
+// PublicProfile is the subset of a user that is visible without authentication
+type PublicProfile struct {
+	CreatedAt time.Time `json:"createdAt"`
+	Username  string    `json:"username"`
+	FullName  string    `json:"fullName"`
+	AvatarURL string    `json:"avatarUrl"`
+}
+
 // CreateUserRequest for creating a new user
 type CreateUserRequest struct {
 	Username  string `json:"username" binding:"required,min=3,max=50,alphanum"`
@@ -1,10 +1,16 @@
 package models
 
 import (
+	"context"
 	"database/sql"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/jheysaaz/snippy-backend/app/cache"
 	"github.com/lib/pq"
 )
 
@@ -48,13 +54,14 @@ func TestSnippetScanFunction(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Create mock row
 			mockRow := &mockScanner{
-				id:        tt.id,
-				label:     tt.label,
-				shortcut:  tt.shortcut,
-				content:   tt.content,
-				tags:      pq.StringArray(tt.tags),
-				createdAt: tt.createdAt,
-				updatedAt: tt.updatedAt,
+				id:         tt.id,
+				label:      tt.label,
+				shortcut:   tt.shortcut,
+				content:    tt.content,
+				tags:       pq.StringArray(tt.tags),
+				createdAt:  tt.createdAt,
+				updatedAt:  tt.updatedAt,
+				visibility: VisibilityPrivate,
 			}
 
 			snippet, err := ScanSnippet(mockRow)
@@ -77,10 +84,35 @@ func TestSnippetScanFunction(t *testing.T) {
 			if len(snippet.Tags) != len(tt.tags) {
 				t.Errorf("Tags length = %d, want %d", len(snippet.Tags), len(tt.tags))
 			}
+			if snippet.Visibility != VisibilityPrivate {
+				t.Errorf("Visibility = %s, want %s", snippet.Visibility, VisibilityPrivate)
+			}
 		})
 	}
 }
 
+func TestNormalizeLanguage(t *testing.T) {
+	tests := []struct {
+		language string
+		want     string
+		wantErr  bool
+	}{
+		{language: "Go", want: "go"},
+		{language: " c++ ", want: "c++"},
+		{language: "objective-c", want: "objective-c"},
+		{language: "", want: ""},
+		{language: "   ", want: ""},
+		{language: "shell script", wantErr: true},
+		{language: "-go", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeLanguage(tt.language)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("NormalizeLanguage(%q) = %q, %v; want %q, error %v", tt.language, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
 func TestUpdateSnippetRequestValidation(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -127,18 +159,19 @@ func TestUpdateSnippetRequestValidation(t *testing.T) {
 
 // Mock scanner for testing
 type mockScanner struct {
-	createdAt time.Time
-	updatedAt time.Time
-	userID    *string
-	label     string
-	shortcut  string
-	content   string
-	tags      pq.StringArray
-	id        int64
+	createdAt  time.Time
+	updatedAt  time.Time
+	userID     *string
+	label      string
+	shortcut   string
+	content    string
+	visibility string
+	tags       pq.StringArray
+	id         int64
 }
 
 func (m *mockScanner) Scan(dest ...interface{}) error {
-	if len(dest) != 8 { // Updated to 8 fields
+	if len(dest) != 17 {
 		return nil
 	}
 
@@ -158,6 +191,15 @@ func (m *mockScanner) Scan(dest ...interface{}) error {
 
 	*dest[6].(*time.Time) = m.createdAt
 	*dest[7].(*time.Time) = m.updatedAt
+	*dest[8].(*string) = m.visibility
+	*dest[9].(*sql.NullInt64) = sql.NullInt64{}
+	*dest[10].(*bool) = false
+	*dest[11].(*sql.NullTime) = sql.NullTime{}
+	*dest[12].(*sql.NullTime) = sql.NullTime{}
+	*dest[13].(*sql.NullString) = sql.NullString{}
+	*dest[14].(*[]byte) = []byte(`[]`)
+	*dest[15].(*sql.NullString) = sql.NullString{}
+	*dest[16].(*bool) = false
 
 	return nil
 }
@@ -279,3 +321,388 @@ func (m *mockUserAuthScanner) Scan(dest ...interface{}) error {
 
 	return nil
 }
+
+func TestNewQuotaUsage(t *testing.T) {
+	quota := Quota{MaxSnippets: 200, MaxStorageBytes: 1000}
+
+	usage := NewQuotaUsage(quota, 50, 333)
+	if usage.SnippetsPercent == nil || *usage.SnippetsPercent != 25 {
+		t.Errorf("SnippetsPercent = %v, want 25", usage.SnippetsPercent)
+	}
+	if usage.StoragePercent == nil || *usage.StoragePercent != 33.3 {
+		t.Errorf("StoragePercent = %v, want 33.3", usage.StoragePercent)
+	}
+	if usage.MaxSnippets != 200 || usage.MaxStorageBytes != 1000 {
+		t.Errorf("limits not carried over: %+v", usage.Quota)
+	}
+
+	if usage.OverQuota || !usage.CanCreateSnippets {
+		t.Errorf("usage within quota should allow creation: %+v", usage)
+	}
+
+	unlimited := NewQuotaUsage(Quota{}, 50, 333)
+	if unlimited.SnippetsPercent != nil || unlimited.StoragePercent != nil {
+		t.Errorf("unlimited quota should have no percentages, got %+v", unlimited)
+	}
+	if !unlimited.CanCreateSnippets {
+		t.Error("unlimited quota should allow creation")
+	}
+
+	full := NewQuotaUsage(quota, 200, 333)
+	if full.OverQuota || full.CanCreateSnippets {
+		t.Errorf("a full quota is not over but blocks creation: %+v", full)
+	}
+
+	// After a downgrade, usage can exceed the free quota
+	downgraded := NewQuotaUsage(quota, 250, 5000)
+	if !downgraded.OverQuota || downgraded.CanCreateSnippets {
+		t.Errorf("expected over quota and creation blocked: %+v", downgraded)
+	}
+}
+
+func TestQuotaAllowsSnippet(t *testing.T) {
+	quota := Quota{MaxSnippets: 10, MaxStorageBytes: 1000}
+	tests := []struct {
+		name         string
+		snippets     int
+		contentBytes int64
+		newBytes     int64
+		want         error
+	}{
+		{name: "room left", snippets: 9, contentBytes: 900, newBytes: 100},
+		{name: "snippet limit", snippets: 10, contentBytes: 0, newBytes: 1, want: ErrSnippetQuotaExceeded},
+		{name: "storage limit", snippets: 1, contentBytes: 900, newBytes: 101, want: ErrStorageQuotaExceeded},
+		{name: "over after downgrade", snippets: 50, contentBytes: 100, newBytes: 1, want: ErrSnippetQuotaExceeded},
+	}
+	for _, tt := range tests {
+		if got := quota.AllowsSnippet(tt.snippets, tt.contentBytes, tt.newBytes); got != tt.want {
+			t.Errorf("%s: AllowsSnippet = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if err := (Quota{}).AllowsSnippet(1_000_000, 1<<40, 1<<20); err != nil {
+		t.Errorf("unlimited quota refused a snippet: %v", err)
+	}
+}
+
+func TestValuesList(t *testing.T) {
+	if got := valuesList(1, 3); got != "($1, $2, $3)" {
+		t.Errorf("valuesList(1, 3) = %q", got)
+	}
+	if got := valuesList(3, 2); got != "($1, $2), ($3, $4), ($5, $6)" {
+		t.Errorf("valuesList(3, 2) = %q", got)
+	}
+	if got := valuesList(insertBatchRows, 6); !strings.HasSuffix(got, "$"+strconv.Itoa(insertBatchRows*6)+")") {
+		t.Errorf("valuesList of a full batch ends with %q", got[len(got)-10:])
+	}
+}
+
+func TestLoadQuota(t *testing.T) {
+	t.Setenv("QUOTA_MAX_SNIPPETS", "0")
+	t.Setenv("QUOTA_MAX_STORAGE_BYTES", "not-a-number")
+
+	quota := LoadQuota()
+	if quota.MaxSnippets != 0 {
+		t.Errorf("MaxSnippets = %d, want 0", quota.MaxSnippets)
+	}
+	if quota.MaxStorageBytes != DefaultMaxStorageBytes {
+		t.Errorf("MaxStorageBytes = %d, want default %d", quota.MaxStorageBytes, DefaultMaxStorageBytes)
+	}
+
+	t.Setenv("PREMIUM_QUOTA_MAX_SNIPPETS", "5000")
+	t.Setenv("PREMIUM_QUOTA_MAX_CONTENT_BYTES", "1048576")
+	quotas := LoadQuotas()
+	if got := quotas.For(PlanPremium); got.MaxSnippets != 5000 || got.MaxStorageBytes != DefaultPremiumMaxStorageBytes || got.MaxContentBytes != 1<<20 {
+		t.Errorf("premium quota = %+v", got)
+	}
+	if got := quotas.For(PlanFree); got != quota {
+		t.Errorf("free quota = %+v, want %+v", got, quota)
+	}
+}
+
+func TestNewQuotaReport(t *testing.T) {
+	report := NewQuotaReport(PlanFree, Quota{MaxSnippets: 200}, 150, 1024)
+	if report.RemainingSnippets == nil || *report.RemainingSnippets != 50 || report.Quota.SnippetsPercent == nil || *report.Quota.SnippetsPercent != 75 {
+		t.Errorf("free report = %+v, want 50 remaining at 75%%", report)
+	}
+	if over := NewQuotaReport(PlanFree, Quota{MaxSnippets: 200}, 250, 0); *over.RemainingSnippets != 0 || !over.Quota.OverQuota {
+		t.Errorf("over-quota report = %+v, want none remaining", over)
+	}
+	if unlimited := NewQuotaReport(PlanPremium, Quota{}, 5000, 0); unlimited.RemainingSnippets != nil || !unlimited.Quota.CanCreateSnippets {
+		t.Errorf("unlimited report = %+v, want no remaining count", unlimited)
+	}
+}
+
+func TestQuotasCheckContent(t *testing.T) {
+	quotas := Quotas{Free: Quota{MaxContentBytes: 100}, Premium: Quota{MaxContentBytes: 1000}}
+	if err := quotas.CheckContent(PlanFree, 100); err != nil {
+		t.Errorf("content at the limit refused: %v", err)
+	}
+
+	var tooLarge *ContentTooLargeError
+	if err := quotas.CheckContent(PlanFree, 101); !errors.As(err, &tooLarge) {
+		t.Fatalf("CheckContent = %v, want a ContentTooLargeError", err)
+	}
+	want := ContentTooLargeError{Plan: PlanFree, ContentBytes: 101, MaxContentBytes: 100, UpgradePlan: PlanPremium, UpgradeMaxContentBytes: 1000}
+	if *tooLarge != want {
+		t.Errorf("error = %+v, want %+v", *tooLarge, want)
+	}
+
+	if err := quotas.CheckContent(PlanPremium, 1001); !errors.As(err, &tooLarge) || tooLarge.UpgradePlan != "" {
+		t.Errorf("premium CheckContent = %v, want an error without an upgrade", err)
+	}
+	quotas.Premium.MaxContentBytes = 0
+	if err := quotas.CheckContent(PlanPremium, 1<<30); err != nil {
+		t.Errorf("unlimited premium refused content: %v", err)
+	}
+	if err := quotas.CheckContent(PlanFree, 101); !errors.As(err, &tooLarge) || tooLarge.UpgradePlan != PlanPremium || tooLarge.UpgradeMaxContentBytes != 0 {
+		t.Errorf("free CheckContent = %v, want an upgrade to unlimited premium", err)
+	}
+}
+
+func TestBuildAuditLogQuery(t *testing.T) {
+	since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	query, args := buildAuditLogQuery(AuditFilter{
+		Actions:      []string{AuditRoleAssigned},
+		TargetUserID: "0b3c9a1e-6a5f-4c1b-9d2e-3f4a5b6c7d8e",
+		Since:        &since,
+		Limit:        25,
+		Offset:       50,
+	})
+
+	for _, fragment := range []string{
+		"a.action = ANY($1)",
+		"a.target_user_id = $2",
+		"a.created_at >= $3",
+		"LIMIT $4 OFFSET $5",
+	} {
+		if !strings.Contains(query, fragment) {
+			t.Errorf("query missing %q:\n%s", fragment, query)
+		}
+	}
+	if len(args) != 5 {
+		t.Errorf("expected 5 args, got %d", len(args))
+	}
+
+	query, args = buildAuditLogQuery(AuditFilter{Limit: 50})
+	if strings.Contains(query, "a.action =") || len(args) != 2 {
+		t.Errorf("unexpected default query (%d args):\n%s", len(args), query)
+	}
+}
+
+func TestBuildBroadcastRecipientQuery(t *testing.T) {
+	verified := true
+	after := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	query, args := buildBroadcastRecipientQuery(BroadcastFilter{
+		Verified:        &verified,
+		Roles:           []string{"premium"},
+		RegisteredAfter: &after,
+	}, 2)
+
+	for _, fragment := range []string{
+		"u.is_deleted = false",
+		"u.email_verified_at IS NOT NULL",
+		"r.name = ANY($2)",
+		"u.created_at >= $3",
+	} {
+		if !strings.Contains(query, fragment) {
+			t.Errorf("query missing %q:\n%s", fragment, query)
+		}
+	}
+	if len(args) != 2 {
+		t.Errorf("expected 2 args, got %d", len(args))
+	}
+
+	// An empty filter still excludes deleted users
+	query, args = buildBroadcastRecipientQuery(BroadcastFilter{}, 1)
+	if !strings.Contains(query, "u.is_deleted = false") || len(args) != 0 {
+		t.Errorf("unexpected default query (%d args):\n%s", len(args), query)
+	}
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: 30 * time.Second},
+		{attempts: 2, want: time.Minute},
+		{attempts: 4, want: 4 * time.Minute},
+		{attempts: 20, want: 6 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := WebhookRetryDelay(tt.attempts); got != tt.want {
+			t.Errorf("WebhookRetryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestIsPremiumStatus(t *testing.T) {
+	for status, want := range map[string]bool{
+		SubscriptionActive:            true,
+		SubscriptionTrialing:          true,
+		SubscriptionPastDue:           true,
+		SubscriptionIncomplete:        false,
+		SubscriptionIncompleteExpired: false,
+		SubscriptionCanceled:          false,
+		SubscriptionUnpaid:            false,
+		SubscriptionPaused:            false,
+	} {
+		if got := IsPremiumStatus(status); got != want {
+			t.Errorf("IsPremiumStatus(%q) = %v, want %v", status, got, want)
+		}
+	}
+}
+
+func TestCachedLookupsSkipDatabase(t *testing.T) {
+	// The store has no database, so any query would panic
+	st := NewStore(nil)
+	defer cache.SetStore(nil)
+	cache.SetStore(cache.NewMemory())
+	ctx := context.Background()
+
+	cache.SetJSON(ctx, rolesCacheKey("u1"), []string{RoleUser, RolePremium}, time.Minute)
+	if ok, err := st.HasRole(ctx, "u1", RolePremium); err != nil || !ok {
+		t.Errorf("HasRole(premium) = %v, %v; want true", ok, err)
+	}
+	if ok, err := st.HasAnyRole(ctx, "u1", []string{RoleAdmin, RoleTester}); err != nil || ok {
+		t.Errorf("HasAnyRole(admin, tester) = %v, %v; want false", ok, err)
+	}
+
+	owner := "u1"
+	cache.SetJSON(ctx, snippetCacheKey(7), Snippet{ID: 7, UserID: &owner, Label: "greeting"}, time.Minute)
+	if s, err := st.GetUserSnippet(ctx, 7, "u1"); err != nil || s.Label != "greeting" {
+		t.Errorf("GetUserSnippet = %+v, %v; want cached snippet", s, err)
+	}
+	if _, err := st.GetUserSnippet(ctx, 7, "u2"); err != ErrNotSnippetOwner {
+		t.Errorf("GetUserSnippet(other user) error = %v, want ErrNotSnippetOwner", err)
+	}
+
+	cache.SetJSON(ctx, userCacheKey("u1"), User{ID: "u1", Username: "ada"}, time.Minute)
+	if u, err := st.GetCachedUser(ctx, "u1"); err != nil || u.Username != "ada" {
+		t.Errorf("GetCachedUser = %+v, %v; want cached user", u, err)
+	}
+
+	InvalidateUserRoles(ctx, "u1")
+	InvalidateSnippet(ctx, 7)
+	InvalidateUser(ctx, "u1")
+	var roles []string
+	if cache.GetJSON(ctx, rolesCacheKey("u1"), &roles) {
+		t.Error("roles still cached after InvalidateUserRoles")
+	}
+	var s Snippet
+	if cache.GetJSON(ctx, snippetCacheKey(7), &s) {
+		t.Error("snippet still cached after InvalidateSnippet")
+	}
+	var u User
+	if cache.GetJSON(ctx, userCacheKey("u1"), &u) {
+		t.Error("user still cached after InvalidateUser")
+	}
+}
+
+func TestExtensionTokenScopes(t *testing.T) {
+	token := &ExtensionToken{Scopes: ExtensionScopes}
+	if !token.HasScope(ScopeSnippetsRead) || !token.HasScope(ScopeUsageWrite) {
+		t.Errorf("extension token scopes %v should include snippet reads and usage", token.Scopes)
+	}
+	if token.HasScope("snippets:write") {
+		t.Error("extension tokens must not grant snippet writes")
+	}
+
+	if !IsExtensionToken(ExtensionTokenPrefix+"abc") || IsExtensionToken("eyJhbGciOiJIUzI1NiJ9.e30.sig") {
+		t.Error("IsExtensionToken should only match the extension prefix")
+	}
+	if hashExtensionToken("snx_a") == hashExtensionToken("snx_b") || len(hashExtensionToken("snx_a")) != 64 {
+		t.Error("hashExtensionToken should return distinct hex SHA-256 digests")
+	}
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	key := &APIKey{Scopes: []string{ScopeSnippetsRead}}
+	if !key.HasScope(ScopeSnippetsRead) || key.HasScope(ScopeSnippetsWrite) {
+		t.Errorf("API key scopes %v should grant exactly snippet reads", key.Scopes)
+	}
+
+	if !IsAPIKey(APIKeyPrefix+"abc") || IsAPIKey(ExtensionTokenPrefix+"abc") {
+		t.Error("IsAPIKey should only match the API key prefix")
+	}
+	if hashAPIKey("snk_a") == hashAPIKey("snk_b") || len(hashAPIKey("snk_a")) != 64 {
+		t.Error("hashAPIKey should return distinct hex SHA-256 digests")
+	}
+}
+
+func TestNewZapierItem(t *testing.T) {
+	created := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	snippet := &Snippet{ID: 42, Label: "Greeting", Shortcut: "/hi", Content: "Hello", CreatedAt: created, UpdatedAt: created.Add(time.Minute)}
+
+	item := NewZapierItem(ZapierTriggerNewSnippet, snippet)
+	if item.ID != "42" || item.Event != EventSnippetCreated || item.SnippetID != 42 || item.Tags == nil {
+		t.Errorf("new snippet item = %+v", item)
+	}
+
+	item = NewZapierItem(ZapierTriggerUpdatedSnippet, snippet)
+	if item.ID != "42-"+strconv.FormatInt(snippet.UpdatedAt.UnixMilli(), 10) || item.Event != EventSnippetUpdated {
+		t.Errorf("updated snippet item = %+v", item)
+	}
+	snippet.UpdatedAt = snippet.UpdatedAt.Add(time.Second)
+	if NewZapierItem(ZapierTriggerUpdatedSnippet, snippet).ID == item.ID {
+		t.Error("each update should get a new item ID")
+	}
+
+	for trigger, event := range ZapierTriggerEvents {
+		if got := ZapierTriggerForEvent(event); got != trigger {
+			t.Errorf("ZapierTriggerForEvent(%q) = %q, want %q", event, got, trigger)
+		}
+	}
+	if ZapierTriggerForEvent(EventSnippetDeleted) != "" {
+		t.Error("deleted snippets have no Zapier trigger")
+	}
+}
+
+func TestSyncPushChangeValidate(t *testing.T) {
+	base := time.Now()
+	tests := []struct {
+		name    string
+		change  SyncPushChange
+		wantErr bool
+	}{
+		{"create", SyncPushChange{Op: SyncOpCreate, Create: &CreateSnippetRequest{}}, false},
+		{"create without snippet", SyncPushChange{Op: SyncOpCreate}, true},
+		{"update", SyncPushChange{Op: SyncOpUpdate, ID: 1, BaseUpdatedAt: &base, Update: &UpdateSnippetRequest{}}, false},
+		{"update without fields", SyncPushChange{Op: SyncOpUpdate, ID: 1, BaseUpdatedAt: &base}, true},
+		{"update without base", SyncPushChange{Op: SyncOpUpdate, ID: 1, Update: &UpdateSnippetRequest{}}, true},
+		{"delete", SyncPushChange{Op: SyncOpDelete, ID: 1, BaseUpdatedAt: &base}, false},
+		{"delete without id", SyncPushChange{Op: SyncOpDelete, BaseUpdatedAt: &base}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.change.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSyncCursor(t *testing.T) {
+	cursor := SyncCursor{Time: time.Date(2024, 3, 1, 12, 0, 0, 123456000, time.UTC), ID: 7}
+	parsed, err := ParseSyncCursor(cursor.String())
+	if err != nil || parsed != cursor {
+		t.Errorf("ParseSyncCursor(String()) = %+v, %v; want %+v", parsed, err, cursor)
+	}
+
+	sameTime := SyncCursor{Time: cursor.Time, ID: 8}
+	if !sameTime.After(cursor) || cursor.After(sameTime) || cursor.After(cursor) {
+		t.Error("After should order cursors at the same time by ID")
+	}
+	if since := SyncCursorSince(cursor.Time); !since.After(sameTime) || since.After(SyncCursor{Time: cursor.Time.Add(time.Microsecond)}) {
+		t.Error("SyncCursorSince should come after every change at its time and before later ones")
+	}
+
+	for _, s := range []string{"", "not-a-cursor", base64.RawURLEncoding.EncodeToString([]byte("v2:1:1")), base64.RawURLEncoding.EncodeToString([]byte("v1:1:-1"))} {
+		if _, err := ParseSyncCursor(s); !errors.Is(err, ErrInvalidSyncCursor) {
+			t.Errorf("ParseSyncCursor(%q) error = %v, want ErrInvalidSyncCursor", s, err)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Package models provides in-app notifications.
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Notification types
+const (
+	NotificationHistoryTrimmed  = "history_trimmed"
+	NotificationNewLogin        = "new_login"
+	NotificationShareInvitation = "share_invitation"
+)
+
+// Notification is an in-app message for a user
+type Notification struct {
+	CreatedAt time.Time       `json:"createdAt"`
+	ReadAt    *time.Time      `json:"readAt,omitempty"`
+	Type      string          `json:"type"`
+	Title     string          `json:"title"`
+	Body      string          `json:"body"`
+	Data      json.RawMessage `json:"data,omitempty" swaggertype:"object"`
+	ID        int64           `json:"id"`
+	Read      bool            `json:"read"`
+}
+
+const notificationColumns = `id, type, title, body, data, read_at, created_at`
+
+// scanNotification scans a database row into a Notification
+func scanNotification(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*Notification, error) {
+	var n Notification
+	var data []byte
+	var readAt sql.NullTime
+	if err := scanner.Scan(&n.ID, &n.Type, &n.Title, &n.Body, &data, &readAt, &n.CreatedAt); err != nil {
+		return nil, err
+	}
+	if len(data) > 0 {
+		n.Data = data
+	}
+	if readAt.Valid {
+		n.ReadAt = &readAt.Time
+		n.Read = true
+	}
+	return &n, nil
+}
+
+// CreateNotification stores a notification for userID. data is marshalled to JSON and may be nil.
+// Pass a *sql.Tx to create the notification atomically with the change it describes.
+func CreateNotification(ctx context.Context, exec Execer, userID, notificationType, title, body string, data interface{}) error {
+	var payload interface{}
+	if data != nil {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			return fmt.Errorf("marshal %s notification data: %w", notificationType, err)
+		}
+		payload = encoded
+	}
+
+	_, err := exec.ExecContext(ctx, `
+		INSERT INTO notifications (user_id, type, title, body, data)
+		VALUES ($1, $2, $3, $4, $5)
+	`, userID, notificationType, title, body, payload)
+	return err
+}
+
+// GetNotifications returns a page of a user's notifications, newest first, and the total
+// number of matching notifications.
+func (st *Store) GetNotifications(ctx context.Context, userID string, unreadOnly bool, limit, offset int) ([]Notification, int, error) {
+	rows, err := st.db.QueryContext(ctx, `
+		SELECT `+notificationColumns+`, COUNT(*) OVER()
+		FROM notifications
+		WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3 OFFSET $4
+	`, userID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing notification rows: %v\n", closeErr)
+		}
+	}()
+
+	total := 0
+	notifications := make([]Notification, 0)
+	for rows.Next() {
+		n, err := scanNotification(totalScanner{rows: rows, total: &total})
+		if err != nil {
+			return nil, 0, err
+		}
+		notifications = append(notifications, *n)
+	}
+	return notifications, total, rows.Err()
+}
+
+// CountUnreadNotifications returns how many unread notifications a user has.
+func (st *Store) CountUnreadNotifications(ctx context.Context, userID string) (int, error) {
+	var count int
+	err := st.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL
+	`, userID).Scan(&count)
+	return count, err
+}
+
+// MarkNotificationRead marks one of a user's notifications as read.
+// Returns sql.ErrNoRows if it doesn't exist.
+func (st *Store) MarkNotificationRead(ctx context.Context, userID string, id int64) (*Notification, error) {
+	row := st.db.QueryRowContext(ctx, `
+		UPDATE notifications
+		SET read_at = COALESCE(read_at, NOW())
+		WHERE id = $1 AND user_id = $2
+		RETURNING `+notificationColumns,
+		id, userID)
+	return scanNotification(row)
+}
+
+// MarkAllNotificationsRead marks every unread notification of a user as read and returns how many changed.
+func (st *Store) MarkAllNotificationsRead(ctx context.Context, userID string) (int64, error) {
+	result, err := st.db.ExecContext(ctx, `
+		UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL
+	`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// IsNewLoginDevice reports whether deviceInfo has never been used for a successful login
+// by a user who has logged in before. Call it before recording the current login.
+func (st *Store) IsNewLoginDevice(ctx context.Context, userID, deviceInfo string) (bool, error) {
+	var isNew bool
+	err := st.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM login_events WHERE user_id = $1 AND success = true
+		) AND NOT EXISTS (
+			SELECT 1 FROM login_events WHERE user_id = $1 AND success = true AND device_info = $2
+		)
+	`, userID, deviceInfo).Scan(&isNew)
+	return isNew, err
+}
@@ -0,0 +1,69 @@
+// Package models provides per-user notification preferences.
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// DefaultTimezone is used for users who haven't set a time zone
+const DefaultTimezone = "UTC"
+
+// NotificationPreferences controls which notifications a user receives and when
+type NotificationPreferences struct {
+	UpdatedAt    *time.Time `json:"updatedAt,omitempty"`
+	Timezone     string     `json:"timezone"`
+	WeeklyDigest bool       `json:"weeklyDigest"`
+}
+
+// UpdateNotificationPreferencesRequest changes notification preferences; omitted fields are kept
+type UpdateNotificationPreferencesRequest struct {
+	WeeklyDigest *bool   `json:"weeklyDigest,omitempty"`
+	Timezone     *string `json:"timezone,omitempty" binding:"omitempty,max=64"`
+}
+
+// DefaultNotificationPreferences returns the preferences of a user who never changed them
+func DefaultNotificationPreferences() *NotificationPreferences {
+	return &NotificationPreferences{Timezone: DefaultTimezone, WeeklyDigest: true}
+}
+
+// GetNotificationPreferences returns a user's notification preferences, or the defaults if unset.
+func (st *Store) GetNotificationPreferences(ctx context.Context, userID string) (*NotificationPreferences, error) {
+	prefs := &NotificationPreferences{}
+	var updatedAt time.Time
+	err := st.db.QueryRowContext(ctx, `
+		SELECT weekly_digest, timezone, updated_at
+		FROM notification_preferences
+		WHERE user_id = $1
+	`, userID).Scan(&prefs.WeeklyDigest, &prefs.Timezone, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DefaultNotificationPreferences(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	prefs.UpdatedAt = &updatedAt
+	return prefs, nil
+}
+
+// UpdateNotificationPreferences stores a user's notification preferences, creating them if needed.
+func (st *Store) UpdateNotificationPreferences(ctx context.Context, userID string, req UpdateNotificationPreferencesRequest) (*NotificationPreferences, error) {
+	prefs := &NotificationPreferences{}
+	var updatedAt time.Time
+	err := st.db.QueryRowContext(ctx, `
+		INSERT INTO notification_preferences (user_id, weekly_digest, timezone)
+		VALUES ($1, COALESCE($2, true), COALESCE($3, 'UTC'))
+		ON CONFLICT (user_id) DO UPDATE SET
+			weekly_digest = COALESCE($2, notification_preferences.weekly_digest),
+			timezone = COALESCE($3, notification_preferences.timezone),
+			updated_at = NOW()
+		RETURNING weekly_digest, timezone, updated_at
+	`, userID, req.WeeklyDigest, req.Timezone).Scan(&prefs.WeeklyDigest, &prefs.Timezone, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	prefs.UpdatedAt = &updatedAt
+	return prefs, nil
+}
@@ -0,0 +1,114 @@
+// Package models provides organizations, the tenants of a deployment.
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// The default organization owns every user that didn't sign up to another one,
+// including all users from before organizations were introduced
+const (
+	DefaultOrgID   = "00000000-0000-0000-0000-000000000001"
+	DefaultOrgSlug = "default"
+)
+
+// ErrOrganizationSlugTaken is returned when creating an organization whose slug is in use
+var ErrOrganizationSlugTaken = errors.New("organization slug already exists")
+
+// orgSlugPattern matches slugs usable as a DNS label: lowercase letters, digits and inner dashes
+var orgSlugPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// Organization is a tenant whose users, snippets and sessions are isolated from other tenants
+type Organization struct {
+	CreatedAt time.Time `json:"createdAt"`
+	ID        string    `json:"id"`
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+}
+
+// CreateOrganizationRequest is the body for creating an organization
+type CreateOrganizationRequest struct {
+	Slug string `json:"slug" binding:"required,max=63"`
+	Name string `json:"name" binding:"required,max=255"`
+}
+
+// ValidOrgSlug reports whether slug can name an organization (and its subdomain)
+func ValidOrgSlug(slug string) bool {
+	return orgSlugPattern.MatchString(slug)
+}
+
+const organizationColumns = `id, slug, name, created_at`
+
+func scanOrganization(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*Organization, error) {
+	var o Organization
+	if err := scanner.Scan(&o.ID, &o.Slug, &o.Name, &o.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// GetOrganizationBySlug returns the organization with slug, or sql.ErrNoRows
+func (st *Store) GetOrganizationBySlug(ctx context.Context, slug string) (*Organization, error) {
+	return scanOrganization(st.db.QueryRowContext(ctx, `
+		SELECT `+organizationColumns+`
+		FROM organizations
+		WHERE slug = $1
+	`, slug))
+}
+
+// CreateOrganization creates an organization. Returns ErrOrganizationSlugTaken if the slug is in use.
+func (st *Store) CreateOrganization(ctx context.Context, req CreateOrganizationRequest) (*Organization, error) {
+	org, err := scanOrganization(st.db.QueryRowContext(ctx, `
+		INSERT INTO organizations (slug, name)
+		VALUES ($1, $2)
+		ON CONFLICT (slug) DO NOTHING
+		RETURNING `+organizationColumns+`
+	`, req.Slug, req.Name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOrganizationSlugTaken
+	}
+	return org, err
+}
+
+// UserInOrg reports whether userID is an active user of organization orgID
+func (st *Store) UserInOrg(ctx context.Context, userID, orgID string) (bool, error) {
+	var exists bool
+	err := st.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM users WHERE id::text = $1 AND org_id = $2 AND is_deleted = false)
+	`, userID, orgID).Scan(&exists)
+	return exists, err
+}
+
+// ListOrganizations returns all organizations, oldest first
+func (st *Store) ListOrganizations(ctx context.Context) ([]Organization, error) {
+	rows, err := st.db.QueryContext(ctx, `
+		SELECT `+organizationColumns+`
+		FROM organizations
+		ORDER BY created_at, slug
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing organization rows: %v\n", closeErr)
+		}
+	}()
+
+	orgs := make([]Organization, 0)
+	for rows.Next() {
+		o, err := scanOrganization(rows)
+		if err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, *o)
+	}
+	return orgs, rows.Err()
+}
@@ -0,0 +1,239 @@
+// Package models provides the transactional outbox for domain events.
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Domain event types written to the outbox
+const (
+	EventSnippetCreated = "snippet.created"
+	EventSnippetUpdated = "snippet.updated"
+	EventSnippetDeleted = "snippet.deleted"
+	EventUserDeleted    = "user.deleted"
+	EventSessionCreated = "session.created"
+	EventSessionEnded   = "session.ended"
+)
+
+// Aggregate types for outbox events
+const (
+	AggregateSnippet = "snippet"
+	AggregateUser    = "user"
+	AggregateSession = "session"
+)
+
+// OutboxEvent represents a domain event awaiting delivery
+type OutboxEvent struct {
+	CreatedAt       time.Time       `json:"createdAt"`
+	EventType       string          `json:"type"`
+	AggregateType   string          `json:"aggregateType"`
+	AggregateID     string          `json:"aggregateId"`
+	OriginSessionID *string         `json:"originSessionId,omitempty"`
+	Payload         json.RawMessage `json:"payload" swaggertype:"object"`
+	ID              int64           `json:"id"`
+	Attempts        int             `json:"-"`
+}
+
+// Execer is implemented by *sql.DB and *sql.Tx so events can join the caller's transaction.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// EnqueueEvent writes a domain event to the outbox using the given executor.
+// Pass the same *sql.Tx used for the change so the event is committed atomically with it.
+func EnqueueEvent(ctx context.Context, exec Execer, eventType, aggregateType, aggregateID string, payload interface{}) error {
+	return EnqueueEventFromSession(ctx, exec, "", eventType, aggregateType, aggregateID, payload)
+}
+
+// EnqueueEventFromSession is EnqueueEvent for changes made by a known client session,
+// so sinks such as push notifications can skip the device that made the change.
+func EnqueueEventFromSession(ctx context.Context, exec Execer, originSessionID, eventType, aggregateType, aggregateID string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal %s payload: %w", eventType, err)
+	}
+
+	var origin interface{}
+	if originSessionID != "" {
+		origin = originSessionID
+	}
+
+	_, err = exec.ExecContext(ctx, `
+		INSERT INTO outbox_events (event_type, aggregate_type, aggregate_id, payload, origin_session_id)
+		VALUES ($1, $2, $3, $4, $5)
+	`, eventType, aggregateType, aggregateID, body, origin)
+	return err
+}
+
+// AggregateEvent is the aggregate and payload of one of a batch of events
+type AggregateEvent struct {
+	Payload     interface{}
+	AggregateID string
+}
+
+// EnqueueEventsFromSession is EnqueueEventFromSession for many events of the same type,
+// written with multi-row inserts instead of one round trip each.
+func EnqueueEventsFromSession(ctx context.Context, exec Execer, originSessionID, eventType, aggregateType string, events []AggregateEvent) error {
+	var origin interface{}
+	if originSessionID != "" {
+		origin = originSessionID
+	}
+
+	for start := 0; start < len(events); start += insertBatchRows {
+		batch := events[start:min(start+insertBatchRows, len(events))]
+		args := make([]interface{}, 0, len(batch)*5)
+		for _, evt := range batch {
+			body, err := json.Marshal(evt.Payload)
+			if err != nil {
+				return fmt.Errorf("marshal %s payload: %w", eventType, err)
+			}
+			args = append(args, eventType, aggregateType, evt.AggregateID, body, origin)
+		}
+		_, err := exec.ExecContext(ctx, `
+			INSERT INTO outbox_events (event_type, aggregate_type, aggregate_id, payload, origin_session_id)
+			VALUES `+valuesList(len(batch), 5), args...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClaimPendingEvents claims up to limit due events for lease, skipping rows other
+// dispatchers are claiming. The claim commits right away, so no locks are held while the
+// events are delivered; other dispatchers skip them until the lease runs out, and pick
+// them up again then if the claimer never recorded an outcome.
+func (st *Store) ClaimPendingEvents(ctx context.Context, limit int, lease time.Duration) ([]OutboxEvent, error) {
+	rows, err := st.db.QueryContext(ctx, `
+		UPDATE outbox_events
+		SET next_attempt_at = NOW() + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id
+			FROM outbox_events
+			WHERE delivered_at IS NULL AND failed_at IS NULL AND next_attempt_at <= NOW()
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, event_type, aggregate_type, aggregate_id, payload, origin_session_id, attempts, created_at
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing outbox rows: %v\n", closeErr)
+		}
+	}()
+
+	events := make([]OutboxEvent, 0, limit)
+	for rows.Next() {
+		evt, err := scanOutboxEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *evt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// RETURNING order isn't guaranteed
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events, nil
+}
+
+// GetOutboxEvent returns an event by ID, delivered or not. Returns sql.ErrNoRows if it
+// doesn't exist (or was cleaned up).
+func (st *Store) GetOutboxEvent(ctx context.Context, id int64) (*OutboxEvent, error) {
+	return scanOutboxEvent(st.db.QueryRowContext(ctx, `
+		SELECT id, event_type, aggregate_type, aggregate_id, payload, origin_session_id, attempts, created_at
+		FROM outbox_events
+		WHERE id = $1
+	`, id))
+}
+
+// scanOutboxEvent scans a database row into an OutboxEvent
+func scanOutboxEvent(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*OutboxEvent, error) {
+	var evt OutboxEvent
+	var payload []byte
+	var origin sql.NullString
+	if err := scanner.Scan(&evt.ID, &evt.EventType, &evt.AggregateType, &evt.AggregateID, &payload, &origin, &evt.Attempts, &evt.CreatedAt); err != nil {
+		return nil, err
+	}
+	evt.Payload = payload
+	if origin.Valid {
+		evt.OriginSessionID = &origin.String
+	}
+	return &evt, nil
+}
+
+// MarkEventDelivered records successful delivery of an event.
+func (st *Store) MarkEventDelivered(ctx context.Context, eventID int64) error {
+	_, err := st.db.ExecContext(ctx, `
+		UPDATE outbox_events
+		SET delivered_at = NOW(), attempts = attempts + 1, last_error = NULL
+		WHERE id = $1
+	`, eventID)
+	return err
+}
+
+// MarkEventFailed records a failed attempt and schedules the next one.
+// When giveUp is true the event is parked and no longer retried.
+func (st *Store) MarkEventFailed(ctx context.Context, eventID int64, deliveryErr error, retryIn time.Duration, giveUp bool) error {
+	_, err := st.db.ExecContext(ctx, `
+		UPDATE outbox_events
+		SET attempts = attempts + 1,
+		    last_error = $2,
+		    next_attempt_at = NOW() + make_interval(secs => $3),
+		    failed_at = CASE WHEN $4 THEN NOW() ELSE NULL END
+		WHERE id = $1
+	`, eventID, deliveryErr.Error(), retryIn.Seconds(), giveUp)
+	return err
+}
+
+// DeliveredSinks returns the sinks each of the events has already been delivered to
+func (st *Store) DeliveredSinks(ctx context.Context, eventIDs []int64) (map[int64]map[string]bool, error) {
+	rows, err := st.db.QueryContext(ctx, `
+		SELECT event_id, sink FROM outbox_sink_deliveries WHERE event_id = ANY($1)
+	`, pq.Array(eventIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing outbox sink rows: %v\n", closeErr)
+		}
+	}()
+
+	delivered := make(map[int64]map[string]bool)
+	for rows.Next() {
+		var eventID int64
+		var sink string
+		if err := rows.Scan(&eventID, &sink); err != nil {
+			return nil, err
+		}
+		if delivered[eventID] == nil {
+			delivered[eventID] = make(map[string]bool)
+		}
+		delivered[eventID][sink] = true
+	}
+	return delivered, rows.Err()
+}
+
+// MarkSinkDelivered records that sink has taken the event, so retries skip it
+func (st *Store) MarkSinkDelivered(ctx context.Context, eventID int64, sink string) error {
+	_, err := st.db.ExecContext(ctx, `
+		INSERT INTO outbox_sink_deliveries (event_id, sink) VALUES ($1, $2)
+		ON CONFLICT (event_id, sink) DO NOTHING
+	`, eventID, sink)
+	return err
+}
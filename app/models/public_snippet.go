@@ -0,0 +1,51 @@
+// Package models provides lookups of individual public snippets for embeds.
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/jheysaaz/snippy-backend/app/placeholder"
+	"github.com/lib/pq"
+)
+
+// GetPublicSnippet returns a public snippet of organization orgID with its author. Returns
+// sql.ErrNoRows if the snippet doesn't exist, isn't public, is in another organization, or
+// it or its author was deleted.
+func (st *Store) GetPublicSnippet(ctx context.Context, orgID string, id int64) (*FeedItem, error) {
+	var item FeedItem
+	var tags pq.StringArray
+	var snippetUserID, language sql.NullString
+	var placeholders []byte
+	s := &item.Snippet
+	a := &item.Author
+	err := st.db.QueryRowContext(ctx, `
+		SELECT s.id, s.label, s.shortcut, s.content, s.tags, s.user_id, s.created_at, s.updated_at, s.visibility,
+		       s.language, s.placeholders, u.username, u.full_name, u.avatar_url, u.created_at
+		FROM snippets s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.id = $1 AND s.visibility = $2 AND s.is_deleted = false AND u.is_deleted = false
+		  AND s.org_id = $3
+	`, id, VisibilityPublic, orgID).Scan(
+		&s.ID, &s.Label, &s.Shortcut, &s.Content, &tags, &snippetUserID, &s.CreatedAt, &s.UpdatedAt, &s.Visibility,
+		&language, &placeholders, &a.Username, &a.FullName, &a.AvatarURL, &a.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	s.Tags = tags
+	if snippetUserID.Valid {
+		s.UserID = &snippetUserID.String
+	}
+	if language.Valid {
+		s.Language = &language.String
+	}
+	s.Placeholders = []placeholder.Placeholder{}
+	if len(placeholders) > 0 {
+		if err := json.Unmarshal(placeholders, &s.Placeholders); err != nil {
+			return nil, err
+		}
+	}
+	return &item, nil
+}
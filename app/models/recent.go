@@ -0,0 +1,57 @@
+// Package models provides the recently used snippets behind quick-pickers.
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Limits of the recently used snippets a client can ask for
+const (
+	DefaultRecentSnippets = 10
+	MaxRecentSnippets     = 50
+)
+
+// RecentSnippet is a snippet with when its owner last used it
+type RecentSnippet struct {
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	Snippet
+}
+
+// ListRecentSnippets returns up to limit of the user's most recently used snippets, last
+// used first. Deleted and archived snippets are left out.
+func (st *Store) ListRecentSnippets(ctx context.Context, userID string, limit int) ([]RecentSnippet, error) {
+	rows, err := st.db.QueryContext(ctx, `
+		SELECT `+snippetColumns+`, last_used.at
+		FROM snippets
+		JOIN (
+			SELECT snippet_id, MAX(used_at) AS at FROM snippet_usage
+			WHERE user_id = $1
+			GROUP BY snippet_id
+		) last_used ON last_used.snippet_id = snippets.id
+		WHERE snippets.user_id = $1 AND is_deleted = false AND archived_at IS NULL
+		ORDER BY last_used.at DESC, snippets.id DESC
+		LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing recent snippet rows: %v\n", closeErr)
+		}
+	}()
+
+	recent := make([]RecentSnippet, 0, limit)
+	for rows.Next() {
+		var item RecentSnippet
+		s, err := ScanSnippet(extraScanner{rows: rows, extra: []interface{}{&item.LastUsedAt}})
+		if err != nil {
+			return nil, err
+		}
+		item.Snippet = *s
+		recent = append(recent, item)
+	}
+	return recent, rows.Err()
+}
@@ -7,8 +7,6 @@ import (
 	"encoding/base64"
 	"errors"
 	"time"
-
-	"github.com/jheysaaz/snippy-backend/app/database"
 )
 
 // Refresh token errors
@@ -39,10 +37,15 @@ func GenerateRefreshToken() (string, error) {
 }
 
 // StoreRefreshToken saves a refresh token to the database.
-func StoreRefreshToken(ctx context.Context, sessionID, token string) error {
+func (st *Store) StoreRefreshToken(ctx context.Context, sessionID, token string) error {
+	return storeRefreshToken(ctx, st.db, sessionID, token)
+}
+
+// storeRefreshToken saves a refresh token of sessionID using the given executor
+func storeRefreshToken(ctx context.Context, exec Execer, sessionID, token string) error {
 	expiresAt := time.Now().Add(RefreshTokenDuration)
 
-	_, err := database.DB.ExecContext(ctx, `
+	_, err := exec.ExecContext(ctx, `
 		INSERT INTO refresh_tokens (session_id, token, expires_at)
 		VALUES ($1, $2, $3)
 	`, sessionID, token, expiresAt)
@@ -51,10 +54,10 @@ func StoreRefreshToken(ctx context.Context, sessionID, token string) error {
 }
 
 // ValidateRefreshToken checks if a refresh token is valid and not expired/revoked.
-func ValidateRefreshToken(ctx context.Context, token string) (*RefreshToken, error) {
+func (st *Store) ValidateRefreshToken(ctx context.Context, token string) (*RefreshToken, error) {
 	var rt RefreshToken
 
-	err := database.DB.QueryRowContext(ctx, `
+	err := st.db.QueryRowContext(ctx, `
 		SELECT rt.id, rt.token, rt.expires_at, rt.created_at, rt.revoked,
 		       s.id AS session_id, s.user_id AS user_id
 		FROM refresh_tokens rt
@@ -87,9 +90,22 @@ func ValidateRefreshToken(ctx context.Context, token string) (*RefreshToken, err
 	return &rt, nil
 }
 
+// RefreshTokenUserID returns the user a refresh token was issued to, whether or not it's
+// still valid, or sql.ErrNoRows for an unknown token
+func (st *Store) RefreshTokenUserID(ctx context.Context, token string) (string, error) {
+	var userID string
+	err := st.db.QueryRowContext(ctx, `
+		SELECT s.user_id
+		FROM refresh_tokens rt
+		JOIN sessions s ON rt.session_id = s.id
+		WHERE rt.token = $1
+	`, token).Scan(&userID)
+	return userID, err
+}
+
 // RevokeRefreshToken marks a refresh token as revoked.
-func RevokeRefreshToken(ctx context.Context, token string) error {
-	_, err := database.DB.ExecContext(ctx, `
+func (st *Store) RevokeRefreshToken(ctx context.Context, token string) error {
+	_, err := st.db.ExecContext(ctx, `
 		UPDATE refresh_tokens
 		SET revoked = TRUE
 		WHERE token = $1
@@ -99,8 +115,8 @@ func RevokeRefreshToken(ctx context.Context, token string) error {
 }
 
 // RevokeAllUserTokens revokes all refresh tokens for a user.
-func RevokeAllUserTokens(ctx context.Context, userID string) error {
-	_, err := database.DB.ExecContext(ctx, `
+func (st *Store) RevokeAllUserTokens(ctx context.Context, userID string) error {
+	_, err := st.db.ExecContext(ctx, `
 		UPDATE refresh_tokens
 		SET revoked = TRUE
 		WHERE session_id IN (SELECT id FROM sessions WHERE user_id = $1) AND revoked = FALSE
@@ -109,8 +125,8 @@ func RevokeAllUserTokens(ctx context.Context, userID string) error {
 }
 
 // RevokeAllSessionTokens revokes all refresh tokens for a specific session.
-func RevokeAllSessionTokens(ctx context.Context, sessionID string) error {
-	_, err := database.DB.ExecContext(ctx, `
+func (st *Store) RevokeAllSessionTokens(ctx context.Context, sessionID string) error {
+	_, err := st.db.ExecContext(ctx, `
 		UPDATE refresh_tokens
 		SET revoked = TRUE
 		WHERE session_id = $1 AND revoked = FALSE
@@ -119,9 +135,9 @@ func RevokeAllSessionTokens(ctx context.Context, sessionID string) error {
 }
 
 // CleanupExpiredTokens removes expired and revoked tokens (run periodically).
-func CleanupExpiredTokens(ctx context.Context) error {
+func (st *Store) CleanupExpiredTokens(ctx context.Context) error {
 	// Delete tokens that expired more than 7 days ago
-	_, err := database.DB.ExecContext(ctx, `
+	_, err := st.db.ExecContext(ctx, `
 		DELETE FROM refresh_tokens
 		WHERE (expires_at < NOW() - INTERVAL '7 days')
 		   OR (revoked = TRUE AND created_at < NOW() - INTERVAL '7 days')
@@ -7,7 +7,7 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/jheysaaz/snippy-backend/app/database"
+	"github.com/jheysaaz/snippy-backend/app/cache"
 )
 
 // Role represents a user role for authorization
@@ -36,7 +36,7 @@ const (
 )
 
 // GetUserRoles retrieves all roles for a specific user.
-func GetUserRoles(ctx context.Context, userID string) ([]UserRole, error) {
+func (st *Store) GetUserRoles(ctx context.Context, userID string) ([]UserRole, error) {
 	query := `
 		SELECT ur.user_id, ur.role_id, r.name, ur.assigned_at, ur.assigned_by
 		FROM user_roles ur
@@ -45,7 +45,7 @@ func GetUserRoles(ctx context.Context, userID string) ([]UserRole, error) {
 		ORDER BY ur.assigned_at DESC
 	`
 
-	rows, err := database.DB.QueryContext(ctx, query, userID)
+	rows, err := st.db.QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -75,8 +75,14 @@ func GetUserRoles(ctx context.Context, userID string) ([]UserRole, error) {
 	return userRoles, rows.Err()
 }
 
-// GetUserRoleNames retrieves just the role names for a user (for JWT claims).
-func GetUserRoleNames(ctx context.Context, userID string) ([]string, error) {
+// GetUserRoleNames retrieves just the role names for a user (for JWT claims and role
+// checks), from the cache when possible.
+func (st *Store) GetUserRoleNames(ctx context.Context, userID string) ([]string, error) {
+	var roles []string
+	if cache.GetJSON(ctx, rolesCacheKey(userID), &roles) {
+		return roles, nil
+	}
+
 	query := `
 		SELECT r.name
 		FROM user_roles ur
@@ -84,7 +90,7 @@ func GetUserRoleNames(ctx context.Context, userID string) ([]string, error) {
 		WHERE ur.user_id = $1
 	`
 
-	rows, err := database.DB.QueryContext(ctx, query, userID)
+	rows, err := st.db.QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -94,7 +100,6 @@ func GetUserRoleNames(ctx context.Context, userID string) ([]string, error) {
 		}
 	}()
 
-	var roles []string
 	for rows.Next() {
 		var roleName string
 		if err := rows.Scan(&roleName); err != nil {
@@ -102,45 +107,40 @@ func GetUserRoleNames(ctx context.Context, userID string) ([]string, error) {
 		}
 		roles = append(roles, roleName)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	return roles, rows.Err()
+	cache.SetJSON(ctx, rolesCacheKey(userID), roles, rolesCacheTTL)
+	return roles, nil
 }
 
 // HasRole checks if a user has a specific role.
-func HasRole(ctx context.Context, userID, roleName string) (bool, error) {
-	query := `
-		SELECT EXISTS(
-			SELECT 1 FROM user_roles ur
-			JOIN roles r ON ur.role_id = r.id
-			WHERE ur.user_id = $1 AND r.name = $2
-		)
-	`
-
-	var exists bool
-	err := database.DB.QueryRowContext(ctx, query, userID, roleName).Scan(&exists)
-	return exists, err
+func (st *Store) HasRole(ctx context.Context, userID, roleName string) (bool, error) {
+	return st.HasAnyRole(ctx, userID, []string{roleName})
 }
 
 // HasAnyRole checks if a user has any of the specified roles.
-func HasAnyRole(ctx context.Context, userID string, roleNames []string) (bool, error) {
-	query := `
-		SELECT EXISTS(
-			SELECT 1 FROM user_roles ur
-			JOIN roles r ON ur.role_id = r.id
-			WHERE ur.user_id = $1 AND r.name = ANY($2)
-		)
-	`
-
-	var exists bool
-	err := database.DB.QueryRowContext(ctx, query, userID, roleNames).Scan(&exists)
-	return exists, err
+func (st *Store) HasAnyRole(ctx context.Context, userID string, roleNames []string) (bool, error) {
+	roles, err := st.GetUserRoleNames(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, role := range roles {
+		for _, name := range roleNames {
+			if role == name {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
 }
 
 // AssignRole assigns a role to a user.
-func AssignRole(ctx context.Context, userID, roleName string, assignedBy *string) error {
+func (st *Store) AssignRole(ctx context.Context, userID, roleName string, assignedBy *string) error {
 	// Get role ID by name
 	var roleID int
-	err := database.DB.QueryRowContext(ctx, `SELECT id FROM roles WHERE name = $1`, roleName).Scan(&roleID)
+	err := st.db.QueryRowContext(ctx, `SELECT id FROM roles WHERE name = $1`, roleName).Scan(&roleID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return fmt.Errorf("role '%s' not found", roleName)
@@ -155,18 +155,21 @@ func AssignRole(ctx context.Context, userID, roleName string, assignedBy *string
 		ON CONFLICT (user_id, role_id) DO NOTHING
 	`
 
-	_, err = database.DB.ExecContext(ctx, query, userID, roleID, assignedBy)
-	return err
+	if _, err = st.db.ExecContext(ctx, query, userID, roleID, assignedBy); err != nil {
+		return err
+	}
+	InvalidateUserRoles(ctx, userID)
+	return nil
 }
 
 // RevokeRole removes a role from a user.
-func RevokeRole(ctx context.Context, userID, roleName string) error {
+func (st *Store) RevokeRole(ctx context.Context, userID, roleName string) error {
 	query := `
 		DELETE FROM user_roles
 		WHERE user_id = $1 AND role_id = (SELECT id FROM roles WHERE name = $2)
 	`
 
-	result, err := database.DB.ExecContext(ctx, query, userID, roleName)
+	result, err := st.db.ExecContext(ctx, query, userID, roleName)
 	if err != nil {
 		return err
 	}
@@ -180,18 +183,19 @@ func RevokeRole(ctx context.Context, userID, roleName string) error {
 		return fmt.Errorf("user does not have role '%s'", roleName)
 	}
 
+	InvalidateUserRoles(ctx, userID)
 	return nil
 }
 
 // GetAllRoles retrieves all available roles.
-func GetAllRoles(ctx context.Context) ([]Role, error) {
+func (st *Store) GetAllRoles(ctx context.Context) ([]Role, error) {
 	query := `
 		SELECT id, name, description, created_at
 		FROM roles
 		ORDER BY name
 	`
 
-	rows, err := database.DB.QueryContext(ctx, query)
+	rows, err := st.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -215,7 +219,7 @@ func GetAllRoles(ctx context.Context) ([]Role, error) {
 }
 
 // GetRoleByName retrieves a role by its name.
-func GetRoleByName(ctx context.Context, name string) (*Role, error) {
+func (st *Store) GetRoleByName(ctx context.Context, name string) (*Role, error) {
 	query := `
 		SELECT id, name, description, created_at
 		FROM roles
@@ -223,7 +227,7 @@ func GetRoleByName(ctx context.Context, name string) (*Role, error) {
 	`
 
 	var role Role
-	err := database.DB.QueryRowContext(ctx, query, name).Scan(&role.ID, &role.Name, &role.Description, &role.CreatedAt)
+	err := st.db.QueryRowContext(ctx, query, name).Scan(&role.ID, &role.Name, &role.Description, &role.CreatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("role '%s' not found", name)
@@ -236,9 +240,9 @@ func GetRoleByName(ctx context.Context, name string) (*Role, error) {
 
 // HasPermission checks if a user has a specific permission based on their roles.
 // Admin role has access to all permissions.
-func HasPermission(ctx context.Context, userID, permission string) (bool, error) {
+func (st *Store) HasPermission(ctx context.Context, userID, permission string) (bool, error) {
 	// Check if user has admin role (admins have all permissions)
-	hasAdmin, err := HasRole(ctx, userID, RoleAdmin)
+	hasAdmin, err := st.HasRole(ctx, userID, RoleAdmin)
 	if err != nil {
 		return false, err
 	}
@@ -258,6 +262,6 @@ func HasPermission(ctx context.Context, userID, permission string) (bool, error)
 	`
 
 	var hasPermission bool
-	err = database.DB.QueryRowContext(ctx, query, userID, permission).Scan(&hasPermission)
+	err = st.db.QueryRowContext(ctx, query, userID, permission).Scan(&hasPermission)
 	return hasPermission, err
 }
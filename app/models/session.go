@@ -6,31 +6,65 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/jheysaaz/snippy-backend/app/database"
 )
 
-// CreateSession creates a new user session.
-func CreateSession(ctx context.Context, userID, deviceInfo, ipAddress, userAgent string) (*Session, error) {
+// CreateSession creates a new user session together with its first refresh token, in one
+// transaction, so the token belongs to the session and is revoked when it's logged out.
+func (st *Store) CreateSession(ctx context.Context, userID, deviceInfo, ipAddress, userAgent, refreshToken string) (*Session, error) {
 	// Hash the IP address for privacy
 	ipHash := hashIP(ipAddress)
 
 	expiresAt := timePtr(time.Now().Add(RefreshTokenDuration))
 
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			fmt.Printf("error rolling back session transaction: %v\n", rbErr)
+		}
+	}()
+
+	// The session.created event is written in the same statement
 	query := `
-		INSERT INTO sessions (user_id, device_info, ip_address_hash, user_agent, active, expires_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, user_id, device_info, ip_address_hash, user_agent, active, last_activity, created_at, expires_at, logged_out_at
+		WITH created AS (
+			INSERT INTO sessions (user_id, device_info, ip_address_hash, user_agent, active, expires_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id, user_id, device_info, ip_address_hash, user_agent, active, last_activity, created_at, expires_at, logged_out_at
+		), evt AS (
+			INSERT INTO outbox_events (event_type, aggregate_type, aggregate_id, payload)
+			SELECT 'session.created', 'session', id::text,
+			       jsonb_build_object('sessionId', id, 'userId', user_id, 'deviceInfo', device_info)
+			FROM created
+		)
+		SELECT id, user_id, device_info, ip_address_hash, user_agent, active, last_activity, created_at, expires_at, logged_out_at
+		FROM created
 	`
 
-	row := database.DB.QueryRowContext(ctx, query, userID, deviceInfo, ipHash, userAgent, true, expiresAt)
-	return scanSession(row)
+	row := tx.QueryRowContext(ctx, query, userID, deviceInfo, ipHash, userAgent, true, expiresAt)
+	session, err := scanSession(row)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := storeRefreshToken(ctx, tx, session.ID, refreshToken); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return session, nil
 }
 
 // GetUserSessions gets all active sessions for a user.
-func GetUserSessions(ctx context.Context, userID string) ([]Session, error) {
+func (st *Store) GetUserSessions(ctx context.Context, userID string) ([]Session, error) {
 	query := `
 		SELECT id, user_id, device_info, ip_address_hash, user_agent, active, last_activity, created_at, expires_at, logged_out_at
 		FROM sessions
@@ -38,7 +72,7 @@ func GetUserSessions(ctx context.Context, userID string) ([]Session, error) {
 		ORDER BY last_activity DESC
 	`
 
-	rows, err := database.DB.QueryContext(ctx, query, userID)
+	rows, err := st.db.QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -62,42 +96,85 @@ func GetUserSessions(ctx context.Context, userID string) ([]Session, error) {
 }
 
 // GetSessionByID gets a specific session.
-func GetSessionByID(ctx context.Context, sessionID string) (*Session, error) {
+func (st *Store) GetSessionByID(ctx context.Context, sessionID string) (*Session, error) {
 	query := `
 		SELECT id, user_id, device_info, ip_address_hash, user_agent, active, last_activity, created_at, expires_at, logged_out_at
 		FROM sessions
 		WHERE id = $1
 	`
 
-	row := database.DB.QueryRowContext(ctx, query, sessionID)
+	row := st.db.QueryRowContext(ctx, query, sessionID)
 	return scanSession(row)
 }
 
+// IsSessionActive reports whether a session of userID exists and is neither logged out
+// nor expired.
+func (st *Store) IsSessionActive(ctx context.Context, sessionID, userID string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM sessions
+			WHERE id = $1 AND user_id = $2 AND active = true
+			  AND (expires_at IS NULL OR expires_at > NOW())
+		)
+	`
+	var active bool
+	err := st.db.QueryRowContext(ctx, query, sessionID, userID).Scan(&active)
+	return active, err
+}
+
 // UpdateSessionActivity updates the last activity timestamp for a session.
-func UpdateSessionActivity(ctx context.Context, sessionID string) error {
+func (st *Store) UpdateSessionActivity(ctx context.Context, sessionID string) error {
 	query := `UPDATE sessions SET last_activity = NOW() WHERE id = $1`
-	_, err := database.DB.ExecContext(ctx, query, sessionID)
+	_, err := st.db.ExecContext(ctx, query, sessionID)
 	return err
 }
 
+// MarkSessionSynced records that a user's session just synced snippets.
+func (st *Store) MarkSessionSynced(ctx context.Context, sessionID, userID string) error {
+	query := `UPDATE sessions SET last_synced_at = NOW() WHERE id = $1 AND user_id = $2`
+	_, err := st.db.ExecContext(ctx, query, sessionID, userID)
+	return err
+}
+
+// Reasons recorded on session.ended events
+const (
+	SessionEndedLogout    = "logout"
+	SessionEndedLogoutAll = "logout_all"
+	SessionEndedIdle      = "idle"
+)
+
+// endSessionsQuery logs out the active sessions matched by condition and writes a
+// session.ended event for each; $1 is the reason
+const endSessionsQuery = `
+	WITH ended AS (
+		UPDATE sessions SET active = false, logged_out_at = NOW()
+		WHERE active = true AND %s
+		RETURNING id, user_id
+	)
+	INSERT INTO outbox_events (event_type, aggregate_type, aggregate_id, payload)
+	SELECT 'session.ended', 'session', id::text,
+	       jsonb_build_object('sessionId', id, 'userId', user_id, 'reason', $1::text)
+	FROM ended
+`
+
 // LogoutSession marks a session as inactive.
-func LogoutSession(ctx context.Context, sessionID string) error {
-	query := `UPDATE sessions SET active = false, logged_out_at = NOW() WHERE id = $1`
-	_, err := database.DB.ExecContext(ctx, query, sessionID)
+func (st *Store) LogoutSession(ctx context.Context, sessionID string) error {
+	query := fmt.Sprintf(endSessionsQuery, "id = $2")
+	_, err := st.db.ExecContext(ctx, query, SessionEndedLogout, sessionID)
 	return err
 }
 
 // LogoutAllUserSessions marks all sessions for a user as inactive.
-func LogoutAllUserSessions(ctx context.Context, userID string) error {
-	query := `UPDATE sessions SET active = false, logged_out_at = NOW() WHERE user_id = $1 AND active = true`
-	_, err := database.DB.ExecContext(ctx, query, userID)
+func (st *Store) LogoutAllUserSessions(ctx context.Context, userID string) error {
+	query := fmt.Sprintf(endSessionsQuery, "user_id = $2")
+	_, err := st.db.ExecContext(ctx, query, SessionEndedLogoutAll, userID)
 	return err
 }
 
 // DeleteExpiredSessions permanently deletes expired sessions.
-func DeleteExpiredSessions(ctx context.Context) (int64, error) {
+func (st *Store) DeleteExpiredSessions(ctx context.Context) (int64, error) {
 	query := `DELETE FROM sessions WHERE expires_at < NOW() OR (logged_out_at IS NOT NULL AND logged_out_at < NOW() - INTERVAL '30 days')`
-	result, err := database.DB.ExecContext(ctx, query)
+	result, err := st.db.ExecContext(ctx, query)
 	if err != nil {
 		return 0, err
 	}
@@ -106,16 +183,7 @@ func DeleteExpiredSessions(ctx context.Context) (int64, error) {
 
 // LogoutIdleSessions logs out sessions that have been idle for more than the specified days.
 func LogoutIdleSessions(ctx context.Context, idleDays int) (int64, error) {
-	query := `
-		UPDATE sessions 
-		SET active = false, logged_out_at = NOW()
-		WHERE active = true AND last_activity < NOW() - INTERVAL '%d days'
-	`
-	result, err := database.DB.ExecContext(ctx, fmt.Sprintf(query, idleDays))
-	if err != nil {
-		return 0, err
-	}
-	return result.RowsAffected()
+	return database.LogoutIdleSessions(ctx, idleDays)
 }
 
 // scanSession scans a database row into a Session struct
@@ -0,0 +1,291 @@
+// Package models provides snippet shares, granting other users access to a snippet.
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Share roles. Viewers can read a shared snippet; editors can also change its label,
+// content, tags and language.
+const (
+	ShareRoleViewer = "viewer"
+	ShareRoleEditor = "editor"
+)
+
+// OwnershipOwner marks snippets of the user in listings and sync; snippets shared with
+// the user are marked with their share role instead
+const OwnershipOwner = "owner"
+
+// MaxSnippetShares is how many users a snippet can be shared with
+const MaxSnippetShares = 50
+
+var (
+	// ErrShareUserNotFound is returned when sharing with a user who doesn't exist or is
+	// in another organization
+	ErrShareUserNotFound = errors.New("user not found")
+	// ErrShareNotFound is returned when revoking a share that doesn't exist
+	ErrShareNotFound = errors.New("share not found")
+	// ErrShareLimitReached is returned when sharing a snippet would exceed MaxSnippetShares
+	ErrShareLimitReached = errors.New("snippet share limit reached")
+)
+
+// SnippetShare is a user a snippet is shared with
+type SnippetShare struct {
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	UserID    string    `json:"userId"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+}
+
+// ShareSnippetRequest grants a user access to a snippet
+type ShareSnippetRequest struct {
+	Role string `json:"role" binding:"required,oneof=viewer editor"`
+}
+
+// ShareSnippet shares one of ownerID's snippets with userID, or changes the role of an
+// existing share, and reports whether the share is new. Returns sql.ErrNoRows if the
+// snippet doesn't exist, ErrNotSnippetOwner if it belongs to someone else,
+// ErrShareUserNotFound if userID isn't a user of the owner's organization, ErrBlocked if
+// either user blocked the other and ErrShareLimitReached if the snippet is shared with
+// MaxSnippetShares users already.
+func (st *Store) ShareSnippet(ctx context.Context, id int64, ownerID, userID, role string) (*SnippetShare, bool, error) {
+	if err := st.checkSnippetOwner(ctx, id, ownerID); err != nil {
+		return nil, false, err
+	}
+
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rollbackSnippetTx(tx)
+
+	// Locking the snippet serializes its grants, so concurrent ones can't pass the limit
+	var deleted bool
+	if err := tx.QueryRowContext(ctx, `SELECT is_deleted FROM snippets WHERE id = $1 FOR UPDATE`, id).Scan(&deleted); err != nil {
+		return nil, false, err
+	}
+	if deleted {
+		return nil, false, sql.ErrNoRows
+	}
+
+	share := SnippetShare{UserID: userID, Role: role}
+	var blocked bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT username, `+blockBetweenCondition+`
+		FROM users WHERE id = $2 AND is_deleted = false
+		  AND org_id = (SELECT org_id FROM users WHERE id = $1)
+	`, ownerID, userID).Scan(&share.Username, &blocked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, ErrShareUserNotFound
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if blocked {
+		return nil, false, ErrBlocked
+	}
+
+	var active bool
+	var others int
+	err = tx.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*) FILTER (WHERE user_id = $2) > 0,
+			COUNT(*) FILTER (WHERE user_id <> $2)
+		FROM snippet_shares
+		WHERE snippet_id = $1 AND revoked_at IS NULL
+	`, id, userID).Scan(&active, &others)
+	if err != nil {
+		return nil, false, err
+	}
+	if !active && others >= MaxSnippetShares {
+		return nil, false, ErrShareLimitReached
+	}
+
+	// A revoked share granted again starts over, so sync sends the snippet afresh
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO snippet_shares (snippet_id, user_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (snippet_id, user_id) DO UPDATE SET
+			role = EXCLUDED.role,
+			created_at = CASE WHEN snippet_shares.revoked_at IS NULL THEN snippet_shares.created_at ELSE NOW() END,
+			updated_at = CASE
+				WHEN snippet_shares.revoked_at IS NULL AND snippet_shares.role = EXCLUDED.role THEN snippet_shares.updated_at
+				ELSE NOW()
+			END,
+			revoked_at = NULL
+		RETURNING created_at, updated_at
+	`, id, userID, role).Scan(&share.CreatedAt, &share.UpdatedAt)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+	return &share, !active, nil
+}
+
+// UnshareSnippet revokes userID's share of a snippet. The owner can revoke any share and
+// a user their own. Returns sql.ErrNoRows if the snippet doesn't exist,
+// ErrNotSnippetOwner if actorID may not revoke the share and ErrShareNotFound if the
+// snippet isn't shared with userID.
+func (st *Store) UnshareSnippet(ctx context.Context, id int64, actorID, userID string) error {
+	if actorID != userID {
+		if err := st.checkSnippetOwner(ctx, id, actorID); err != nil {
+			return err
+		}
+	}
+
+	result, err := st.db.ExecContext(ctx, `
+		UPDATE snippet_shares SET revoked_at = NOW(), updated_at = NOW()
+		WHERE snippet_id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, id, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrShareNotFound
+	}
+	return nil
+}
+
+// ListSnippetShares returns the users one of ownerID's snippets is shared with, in the
+// order they were granted access. Returns sql.ErrNoRows if the snippet doesn't exist and
+// ErrNotSnippetOwner if it belongs to someone else.
+func (st *Store) ListSnippetShares(ctx context.Context, id int64, ownerID string) ([]SnippetShare, error) {
+	if err := st.checkSnippetOwner(ctx, id, ownerID); err != nil {
+		return nil, err
+	}
+
+	rows, err := st.db.QueryContext(ctx, `
+		SELECT sh.user_id, u.username, sh.role, sh.created_at, sh.updated_at
+		FROM snippet_shares sh
+		JOIN users u ON u.id = sh.user_id
+		WHERE sh.snippet_id = $1 AND sh.revoked_at IS NULL AND u.is_deleted = false
+		ORDER BY sh.created_at, u.username
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing snippet share rows: %v\n", closeErr)
+		}
+	}()
+
+	shares := make([]SnippetShare, 0)
+	for rows.Next() {
+		var share SnippetShare
+		if err := rows.Scan(&share.UserID, &share.Username, &share.Role, &share.CreatedAt, &share.UpdatedAt); err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+	return shares, rows.Err()
+}
+
+// sharedSnippetRoles returns a query of the IDs and roles of the snippets shared with
+// the user whose ID is the argument placeholder user: directly, and through the
+// collections they're a member of and the collections inside those. The owners of a
+// collection are editors of its snippets. A snippet can come up once for each way it's
+// shared; sharedRole picks the strongest.
+func sharedSnippetRoles(user string) string {
+	return `
+		WITH RECURSIVE member_collections AS (
+			SELECT collection_id AS id, CASE WHEN role = 'viewer' THEN 'viewer' ELSE 'editor' END AS role
+			FROM collection_members WHERE user_id = ` + user + `
+			UNION
+			SELECT c.id, mc.role FROM collections c JOIN member_collections mc ON c.parent_id = mc.id
+		)
+		SELECT snippet_id, role FROM snippet_shares WHERE user_id = ` + user + ` AND revoked_at IS NULL
+		UNION ALL
+		SELECT s.id, mc.role FROM snippets s JOIN member_collections mc ON s.collection_id = mc.id
+		WHERE s.user_id <> ` + user
+}
+
+// sharedRole aggregates the roles of a snippet from sharedSnippetRoles to the strongest
+const sharedRole = `CASE WHEN bool_or(role = 'editor') THEN 'editor' ELSE 'viewer' END`
+
+// SnippetShareRole returns userID's role on a snippet shared with them, directly or
+// through a collection, or "" if it isn't
+func (st *Store) SnippetShareRole(ctx context.Context, id int64, userID string) (string, error) {
+	var role string
+	err := st.db.QueryRowContext(ctx, `
+		SELECT `+sharedRole+` FROM (`+sharedSnippetRoles("$1")+`) shared
+		WHERE snippet_id = $2
+		HAVING COUNT(*) > 0
+	`, userID, id).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return role, err
+}
+
+// setOwnership marks each snippet with userID's ownership of it: OwnershipOwner for
+// their own, their share role for the others, which are shown as shared
+func (st *Store) setOwnership(ctx context.Context, userID string, snippets ...*Snippet) error {
+	shared := make([]int64, 0)
+	for _, s := range snippets {
+		if s.UserID != nil && *s.UserID == userID {
+			s.Ownership = OwnershipOwner
+		} else {
+			shared = append(shared, s.ID)
+		}
+	}
+	if len(shared) == 0 {
+		return nil
+	}
+
+	rows, err := st.db.QueryContext(ctx, `
+		SELECT snippet_id, `+sharedRole+` FROM (`+sharedSnippetRoles("$1")+`) shared
+		WHERE snippet_id = ANY($2)
+		GROUP BY snippet_id
+	`, userID, pq.Array(shared))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing share role rows: %v\n", closeErr)
+		}
+	}()
+
+	roles := make(map[int64]string, len(shared))
+	for rows.Next() {
+		var id int64
+		var role string
+		if err := rows.Scan(&id, &role); err != nil {
+			return err
+		}
+		roles[id] = role
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, s := range snippets {
+		if s.Ownership == "" {
+			asShared(s, roles[s.ID])
+		}
+	}
+	return nil
+}
+
+// asShared marks a snippet shared with the user as role, hiding the owner's own
+// organization of it: their star, pin and collection
+func asShared(s *Snippet, role string) {
+	s.Ownership = role
+	s.IsFavorite = false
+	s.PinnedAt = nil
+	s.CollectionID = nil
+}
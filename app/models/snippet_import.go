@@ -0,0 +1,106 @@
+// Package models provides the single-transaction writes of snippet imports.
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+// SnippetOverwrite replaces one of the user's snippets with an imported one
+type SnippetOverwrite struct {
+	Request CreateSnippetRequest
+	ID      int64
+}
+
+// ImportSnippets overwrites the label, content, tags, language and description of the
+// user's snippets in overwrites, then creates reqs as CreateSnippets does, all in one
+// transaction: either the whole import lands or none of it. Overwrites record a history entry each and
+// aren't held to the plan quota, which only stops new snippets. With dryRun the
+// transaction is rolled back instead of committed, so the result previews the import,
+// quota included, without changing anything.
+func (st *Store) ImportSnippets(ctx context.Context, userID, originSessionID string, reqs []CreateSnippetRequest, overwrites []SnippetOverwrite, dryRun bool) (created, overwritten []Snippet, err error) {
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rollbackSnippetTx(tx)
+
+	overwritten = make([]Snippet, 0, len(overwrites))
+	for _, o := range overwrites {
+		snippet, err := overwriteSnippet(ctx, tx, userID, o)
+		if err != nil {
+			return nil, nil, err
+		}
+		overwritten = append(overwritten, *snippet)
+	}
+
+	if len(overwritten) > 0 {
+		ids := make([]int64, len(overwritten))
+		events := make([]AggregateEvent, len(overwritten))
+		for i := range overwritten {
+			ids[i] = overwritten[i].ID
+			events[i] = AggregateEvent{AggregateID: strconv.FormatInt(overwritten[i].ID, 10), Payload: &overwritten[i]}
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO snippet_history (
+				snippet_id, version_number, label, shortcut, content, tags,
+				changed_by, change_type, change_notes
+			)
+			SELECT id, get_next_snippet_version(id), label, shortcut, content, tags, $2, 'edit', 'Overwritten by import'
+			FROM snippets
+			WHERE id = ANY($1)
+		`, pq.Array(ids), userID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("record import overwrite history: %w", err)
+		}
+		if err := EnqueueEventsFromSession(ctx, tx, originSessionID, EventSnippetUpdated, AggregateSnippet, events); err != nil {
+			return nil, nil, fmt.Errorf("enqueue %s events: %w", EventSnippetUpdated, err)
+		}
+	}
+
+	created, err = createSnippetsTx(ctx, tx, userID, originSessionID, reqs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if dryRun {
+		return created, overwritten, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	for _, snippet := range overwritten {
+		InvalidateSnippet(ctx, snippet.ID)
+	}
+	return created, overwritten, nil
+}
+
+// overwriteSnippet replaces the label, content, tags, language and description of one of
+// the user's snippets within tx. Invalid languages and placeholders are dropped, as on insert.
+// Returns sql.ErrNoRows if the snippet was deleted meanwhile.
+func overwriteSnippet(ctx context.Context, tx *sql.Tx, userID string, o SnippetOverwrite) (*Snippet, error) {
+	req := o.Request
+	if req.Tags == nil {
+		req.Tags = []string{}
+	}
+	var language string
+	if req.Language != nil {
+		language, _ = NormalizeLanguage(*req.Language)
+	}
+	placeholders, err := PlaceholdersJSON(req.Content, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return ScanSnippet(tx.QueryRowContext(ctx, `
+		UPDATE snippets
+		SET label = $3, content = $4, tags = $5, language = NULLIF($6, ''), placeholders = $7::jsonb,
+			description = $8
+		WHERE id = $1 AND user_id = $2 AND is_deleted = false
+		RETURNING `+snippetColumns,
+		o.ID, userID, req.Label, req.Content, pq.Array(req.Tags), language, placeholders, req.Description))
+}
@@ -0,0 +1,177 @@
+// Package models provides snippet queries used by the search subsystem.
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/jheysaaz/snippy-backend/app/snippetquery"
+	"github.com/lib/pq"
+)
+
+// SnippetSearch is a full-text search of one user's snippets
+type SnippetSearch struct {
+	UserID string
+	Text   string
+	Tag    string
+	Limit  int
+	Offset int
+	// Fuzzy falls back to a trigram similarity search when the full-text search finds
+	// nothing, so typos still match
+	Fuzzy bool
+}
+
+// TagFacet is how many matching snippets carry a tag
+type TagFacet struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// maxTagFacets caps the tag facets returned with a search
+const maxTagFacets = 20
+
+// GetSnippet returns a non-deleted snippet regardless of owner. Returns sql.ErrNoRows if
+// it doesn't exist or was deleted.
+func (st *Store) GetSnippet(ctx context.Context, id int64) (*Snippet, error) {
+	return ScanSnippet(st.db.QueryRowContext(ctx, `
+		SELECT `+snippetColumns+`
+		FROM snippets
+		WHERE id = $1 AND is_deleted = false
+	`, id))
+}
+
+// ListSnippetsAfter returns up to limit non-deleted snippets with IDs above afterID, in ID
+// order, for walking every snippet in batches
+func (st *Store) ListSnippetsAfter(ctx context.Context, afterID int64, limit int) ([]Snippet, error) {
+	rows, err := st.db.QueryContext(ctx, `
+		SELECT `+snippetColumns+`
+		FROM snippets
+		WHERE id > $1 AND is_deleted = false
+		ORDER BY id
+		LIMIT $2
+	`, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return collectSnippets(rows)
+}
+
+// GetUserSnippetsByIDs returns the user's non-deleted, unarchived snippets among ids, in
+// the order of ids. IDs of other users' or deleted or archived snippets are skipped.
+func (st *Store) GetUserSnippetsByIDs(ctx context.Context, userID string, ids []int64) ([]Snippet, error) {
+	if len(ids) == 0 {
+		return []Snippet{}, nil
+	}
+	rows, err := st.db.QueryContext(ctx, `
+		SELECT `+snippetColumns+`
+		FROM snippets
+		WHERE id = ANY($1) AND user_id = $2 AND is_deleted = false AND archived_at IS NULL
+		ORDER BY array_position($1::bigint[], id)
+	`, pq.Array(ids), userID)
+	if err != nil {
+		return nil, err
+	}
+	return collectSnippets(rows)
+}
+
+// SearchUserSnippets runs a Postgres full-text search on snippet labels, best match first.
+// It returns one page of matches, the total match count and the most common tags among
+// all matches.
+func (st *Store) SearchUserSnippets(ctx context.Context, s SnippetSearch) ([]Snippet, int, []TagFacet, error) {
+	return st.searchUserSnippets(ctx, s, false)
+}
+
+// FuzzySearchUserSnippets is SearchUserSnippets matching labels by trigram word
+// similarity instead of full text, most similar first, so misspelled words still match
+func (st *Store) FuzzySearchUserSnippets(ctx context.Context, s SnippetSearch) ([]Snippet, int, []TagFacet, error) {
+	return st.searchUserSnippets(ctx, s, true)
+}
+
+// searchUserSnippets runs a full-text or, if fuzzy, trigram search on snippet labels
+func (st *Store) searchUserSnippets(ctx context.Context, s SnippetSearch, fuzzy bool) ([]Snippet, int, []TagFacet, error) {
+	q := snippetquery.New().Where("user_id = ?", s.UserID).Filter(snippetquery.Filter{Search: s.Text, Tag: s.Tag, Fuzzy: fuzzy})
+	where, args := q.Conditions(), q.Args()
+
+	var total int
+	if err := st.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM snippets WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, nil, err
+	}
+
+	snippets, err := st.ListSnippets(ctx, q.OrderBySearchRank().Limit(s.Limit).Offset(s.Offset))
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	facets, err := st.searchTagFacets(ctx, where, args)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	return snippets, total, facets, nil
+}
+
+// ListUserTags returns each distinct tag on the user's non-deleted snippets with how many
+// snippets carry it, most used first
+func (st *Store) ListUserTags(ctx context.Context, userID string) ([]TagFacet, error) {
+	q := snippetquery.New().Where("user_id = ?", userID).Filter(snippetquery.Filter{})
+	return st.countTags(ctx, q.Conditions(), q.Args(), 0)
+}
+
+// searchTagFacets counts tags across all snippets matching where
+func (st *Store) searchTagFacets(ctx context.Context, where string, args []interface{}) ([]TagFacet, error) {
+	return st.countTags(ctx, where, args, maxTagFacets)
+}
+
+// countTags counts tags across all snippets matching where, most used first, returning
+// up to limit tags (all of them if limit is 0)
+func (st *Store) countTags(ctx context.Context, where string, args []interface{}, limit int) ([]TagFacet, error) {
+	query := `
+		SELECT tag, COUNT(*)
+		FROM snippets, unnest(tags) AS tag
+		WHERE ` + where + `
+		GROUP BY tag
+		ORDER BY COUNT(*) DESC, tag`
+	if limit > 0 {
+		query += `
+		LIMIT ` + strconv.Itoa(limit)
+	}
+	rows, err := st.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing tag facet rows: %v\n", closeErr)
+		}
+	}()
+
+	facets := make([]TagFacet, 0)
+	for rows.Next() {
+		var f TagFacet
+		if err := rows.Scan(&f.Tag, &f.Count); err != nil {
+			return nil, err
+		}
+		facets = append(facets, f)
+	}
+	return facets, rows.Err()
+}
+
+// collectSnippets scans and closes snippet rows
+func collectSnippets(rows *sql.Rows) ([]Snippet, error) {
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing snippet rows: %v\n", closeErr)
+		}
+	}()
+
+	snippets := make([]Snippet, 0, 10)
+	for rows.Next() {
+		s, err := ScanSnippet(rows)
+		if err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, *s)
+	}
+	return snippets, rows.Err()
+}
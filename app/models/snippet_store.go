@@ -0,0 +1,1127 @@
+// Package models provides snippet writes and sync queries shared by the HTTP and gRPC APIs.
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jheysaaz/snippy-backend/app/database"
+	"github.com/jheysaaz/snippy-backend/app/placeholder"
+	"github.com/jheysaaz/snippy-backend/app/snippetquery"
+	"github.com/lib/pq"
+)
+
+// ErrNotSnippetOwner is returned when a user changes a snippet they don't own
+var ErrNotSnippetOwner = errors.New("snippet belongs to another user")
+
+// ErrShortcutExists is returned when a write would give a user two live snippets with
+// the same shortcut
+var ErrShortcutExists = errors.New("shortcut already used by another snippet")
+
+// shortcutIndex is the unique index keeping a user's live shortcuts distinct
+const shortcutIndex = "idx_snippets_user_shortcut_unique"
+
+// ShortcutConflict returns ErrShortcutExists if err is a violation of the unique
+// shortcut index, and err otherwise
+func ShortcutConflict(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == shortcutIndex {
+		return ErrShortcutExists
+	}
+	return err
+}
+
+// PlaceholdersJSON returns content's placeholders as stored in the placeholders column.
+// Unless strict, content with an invalid placeholder keeps those before it instead of
+// failing, for content that was never validated, like old history versions and imports.
+// Strict errors wrap placeholder.ErrInvalid.
+func PlaceholdersJSON(content string, strict bool) (string, error) {
+	placeholders, err := placeholder.Parse(content)
+	if err != nil && strict {
+		return "", err
+	}
+	encoded, err := json.Marshal(placeholders)
+	return string(encoded), err
+}
+
+// ErrInvalidLanguage is returned for a snippet language that isn't a plain identifier
+var ErrInvalidLanguage = errors.New("language must contain only letters, digits and + # . _ -")
+
+// languagePattern matches a normalized language name, such as go, c++, c# or objective-c
+var languagePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9+#._-]*$`)
+
+// NormalizeLanguage trims and lowercases a snippet language so filters match however it
+// was typed. It returns "" for a blank language and ErrInvalidLanguage for one that isn't
+// a plain identifier.
+func NormalizeLanguage(language string) (string, error) {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if language != "" && !languagePattern.MatchString(language) {
+		return "", ErrInvalidLanguage
+	}
+	return language, nil
+}
+
+// normalizeLanguageField normalizes an optional language in place, keeping a blank one
+// as "" so updates can tell clearing it from leaving it unchanged
+func normalizeLanguageField(language *string) error {
+	if language == nil {
+		return nil
+	}
+	normalized, err := NormalizeLanguage(*language)
+	if err != nil {
+		return err
+	}
+	*language = normalized
+	return nil
+}
+
+// snippetColumns lists the columns read by ScanSnippet, in order
+const snippetColumns = `id, label, shortcut, content, tags, user_id, created_at, updated_at, visibility, collection_id, is_favorite, pinned_at, archived_at, language, placeholders, description, keep_history`
+
+// DeletedSnippet is a tombstone returned by sync
+type DeletedSnippet struct {
+	DeletedAt *time.Time `json:"deletedAt"`
+	ID        int64      `json:"id"`
+}
+
+// SnippetChanges are a user's snippet changes since a point in time
+type SnippetChanges struct {
+	// Cursor is the position of the last change, to sync from next time
+	Cursor  string           `json:"cursor" example:"djE6MTcwMDAwMDAwMDAwMDAwMDoxMg"`
+	Created []Snippet        `json:"created"`
+	Updated []Snippet        `json:"updated"`
+	Deleted []DeletedSnippet `json:"deleted"`
+}
+
+// CreateSnippet inserts a snippet owned by userID and records the domain event in the
+// same transaction. originSessionID identifies the device making the change, if known.
+// Returns ErrSnippetQuotaExceeded or ErrStorageQuotaExceeded when the user's plan is full,
+// ErrCollectionNotFound if the requested collection isn't one of the user's,
+// a *ContentTooLargeError when the content is over the plan's size limit,
+// ErrInvalidLanguage for an invalid language and ErrShortcutExists if the user already
+// has a snippet with the shortcut.
+func (st *Store) CreateSnippet(ctx context.Context, userID, originSessionID string, req CreateSnippetRequest) (*Snippet, error) {
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rollbackSnippetTx(tx)
+
+	usage, err := lockSnippetUsage(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	snippet, err := createSnippetTx(ctx, tx, usage, userID, originSessionID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return snippet, tx.Commit()
+}
+
+// createSnippetTx inserts a snippet within tx for CreateSnippet and counts it in usage,
+// the user's usage locked by lockSnippetUsage
+func createSnippetTx(ctx context.Context, tx *sql.Tx, usage *snippetUsage, userID, originSessionID string, req CreateSnippetRequest) (*Snippet, error) {
+	if req.Tags == nil {
+		req.Tags = []string{}
+	}
+	if err := normalizeLanguageField(req.Language); err != nil {
+		return nil, err
+	}
+	placeholders, err := PlaceholdersJSON(req.Content, true)
+	if err != nil {
+		return nil, err
+	}
+	// Snippets are private unless explicitly published
+	if req.Visibility == "" {
+		req.Visibility = VisibilityPrivate
+	}
+
+	newBytes := int64(len(req.Content))
+	if err := usage.checkContent(newBytes); err != nil {
+		return nil, err
+	}
+	if err := usage.quota.AllowsSnippet(usage.snippets, usage.contentBytes, newBytes); err != nil {
+		return nil, err
+	}
+	if req.CollectionID != nil {
+		if err := lockCollection(ctx, tx, userID, *req.CollectionID, "FOR KEY SHARE"); err != nil {
+			return nil, err
+		}
+	}
+
+	snippet, err := ScanSnippet(tx.QueryRowContext(ctx, `
+		INSERT INTO snippets (label, shortcut, content, tags, user_id, visibility, collection_id, language, placeholders, description)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8::text, ''), $9::jsonb, NULLIF($10::text, ''))
+		RETURNING `+snippetColumns,
+		req.Label, req.Shortcut, req.Content, pq.Array(req.Tags), userID, req.Visibility, req.CollectionID, req.Language,
+		placeholders, req.Description))
+	if err != nil {
+		return nil, ShortcutConflict(err)
+	}
+
+	if err := enqueueSnippetChange(ctx, tx, originSessionID, EventSnippetCreated, snippet); err != nil {
+		return nil, err
+	}
+
+	usage.snippets++
+	usage.contentBytes += newBytes
+	return snippet, nil
+}
+
+// CreateSnippets inserts many snippets owned by userID in one transaction, with
+// multi-row inserts for the snippets and their domain events. The insert trigger writes
+// each snippet's first history entry within the same statements. Requests are accepted
+// in order until the user's plan quota refuses one; it and the rest are not created, so
+// returning fewer snippets than requested means the quota is full. A snippet over the
+// plan's content size limit refuses the whole batch with a *ContentTooLargeError. The
+// created snippets are returned in ID order, which is the order they were requested in.
+func (st *Store) CreateSnippets(ctx context.Context, userID, originSessionID string, reqs []CreateSnippetRequest) ([]Snippet, error) {
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rollbackSnippetTx(tx)
+
+	snippets, err := createSnippetsTx(ctx, tx, userID, originSessionID, reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return snippets, nil
+}
+
+// createSnippetsTx inserts snippets within tx for CreateSnippets
+func createSnippetsTx(ctx context.Context, tx *sql.Tx, userID, originSessionID string, reqs []CreateSnippetRequest) ([]Snippet, error) {
+	usage, err := lockSnippetUsage(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, req := range reqs {
+		if err := usage.checkContent(int64(len(req.Content))); err != nil {
+			return nil, err
+		}
+	}
+	accepted := 0
+	for _, req := range reqs {
+		newBytes := int64(len(req.Content))
+		if usage.quota.AllowsSnippet(usage.snippets, usage.contentBytes, newBytes) != nil {
+			break
+		}
+		usage.snippets++
+		usage.contentBytes += newBytes
+		accepted++
+	}
+
+	snippets := make([]Snippet, 0, accepted)
+	for start := 0; start < accepted; start += insertBatchRows {
+		batch := reqs[start:min(start+insertBatchRows, accepted)]
+		created, err := insertSnippets(ctx, tx, userID, batch)
+		if err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, created...)
+	}
+
+	events := make([]AggregateEvent, len(snippets))
+	for i := range snippets {
+		events[i] = AggregateEvent{AggregateID: strconv.FormatInt(snippets[i].ID, 10), Payload: &snippets[i]}
+	}
+	if err := EnqueueEventsFromSession(ctx, tx, originSessionID, EventSnippetCreated, AggregateSnippet, events); err != nil {
+		return nil, fmt.Errorf("enqueue %s events: %w", EventSnippetCreated, err)
+	}
+	return snippets, nil
+}
+
+// insertSnippets inserts reqs with a single multi-row INSERT within tx. Invalid languages
+// and placeholders are dropped rather than failing the batch.
+func insertSnippets(ctx context.Context, tx *sql.Tx, userID string, reqs []CreateSnippetRequest) ([]Snippet, error) {
+	args := make([]interface{}, 0, len(reqs)*9)
+	for _, req := range reqs {
+		if req.Tags == nil {
+			req.Tags = []string{}
+		}
+		if req.Visibility == "" {
+			req.Visibility = VisibilityPrivate
+		}
+		var language sql.NullString
+		if req.Language != nil {
+			language.String, _ = NormalizeLanguage(*req.Language)
+			language.Valid = language.String != ""
+		}
+		placeholders, err := PlaceholdersJSON(req.Content, false)
+		if err != nil {
+			return nil, err
+		}
+		var description sql.NullString
+		if req.Description != nil {
+			description = sql.NullString{String: *req.Description, Valid: *req.Description != ""}
+		}
+		args = append(args, req.Label, req.Shortcut, req.Content, pq.Array(req.Tags), userID, req.Visibility, language, placeholders,
+			description)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		INSERT INTO snippets (label, shortcut, content, tags, user_id, visibility, language, placeholders, description)
+		VALUES `+valuesList(len(reqs), 9)+`
+		RETURNING `+snippetColumns, args...)
+	if err != nil {
+		return nil, ShortcutConflict(err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing inserted snippet rows: %v\n", closeErr)
+		}
+	}()
+
+	snippets := make([]Snippet, 0, len(reqs))
+	for rows.Next() {
+		s, err := ScanSnippet(rows)
+		if err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, *s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// IDs are drawn in VALUES order, while RETURNING order isn't guaranteed
+	sort.Slice(snippets, func(i, j int) bool { return snippets[i].ID < snippets[j].ID })
+	return snippets, nil
+}
+
+// UpdateSnippet applies the provided fields to a user's snippet and records a history
+// entry. Editors of a snippet shared with the user can change its label, content, tags,
+// language and description too. Returns sql.ErrNoRows if the snippet doesn't exist,
+// ErrNotSnippetOwner if it belongs to someone else, ErrCollectionNotFound if the
+// requested collection isn't one of the user's, a *ContentTooLargeError when new content
+// is over the size limit of the user's plan, ErrInvalidLanguage for an invalid language
+// and ErrShortcutExists if another of the user's snippets has the new shortcut.
+func (st *Store) UpdateSnippet(ctx context.Context, id int64, userID, originSessionID string, req UpdateSnippetRequest) (*Snippet, error) {
+	if err := normalizeLanguageField(req.Language); err != nil {
+		return nil, err
+	}
+	var placeholders *string
+	if req.Content != nil {
+		encoded, err := PlaceholdersJSON(*req.Content, true)
+		if err != nil {
+			return nil, err
+		}
+		placeholders = &encoded
+	}
+	if err := st.checkSnippetEditor(ctx, id, userID, req); err != nil {
+		return nil, err
+	}
+	if req.Content != nil {
+		plan, err := st.GetUserPlan(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if err := LoadQuotas().CheckContent(plan, int64(len(*req.Content))); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rollbackSnippetTx(tx)
+
+	snippet, err := updateSnippetRow(ctx, tx, id, userID, req, placeholders)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := enqueueSnippetChange(ctx, tx, originSessionID, EventSnippetUpdated, snippet); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	InvalidateSnippet(ctx, id)
+
+	// History is best effort; the update has already been committed
+	if err := recordSnippetHistory(ctx, st.db, snippet, userID, "edit", req.ChangeNotes); err != nil {
+		fmt.Printf("failed to create snippet history for %d: %v\n", snippet.ID, err)
+	}
+
+	return snippet, nil
+}
+
+// updateSnippetRow applies req to snippet id within tx for UpdateSnippet, placeholders
+// being those of req.Content when it's set
+func updateSnippetRow(ctx context.Context, tx *sql.Tx, id int64, userID string, req UpdateSnippetRequest, placeholders *string) (*Snippet, error) {
+	if req.CollectionID != nil && *req.CollectionID != 0 {
+		if err := lockCollection(ctx, tx, userID, *req.CollectionID, "FOR KEY SHARE"); err != nil {
+			return nil, err
+		}
+	}
+
+	// Static UPDATE using COALESCE to only update provided fields
+	var tags interface{}
+	if req.Tags != nil {
+		tags = pq.Array(req.Tags)
+	}
+	snippet, err := ScanSnippet(tx.QueryRowContext(ctx, `
+		UPDATE snippets
+		SET
+			label = COALESCE($1, label),
+			shortcut = COALESCE($2, shortcut),
+			content = COALESCE($3, content),
+			tags = COALESCE($4, tags),
+			visibility = COALESCE($5, visibility),
+			collection_id = CASE WHEN $7::bigint IS NULL THEN collection_id ELSE NULLIF($7, 0) END,
+			language = CASE WHEN $8::text IS NULL THEN language ELSE NULLIF($8, '') END,
+			placeholders = COALESCE($9::jsonb, placeholders),
+			description = CASE WHEN $10::text IS NULL THEN description ELSE NULLIF($10, '') END
+		WHERE id = $6 AND is_deleted = false
+		RETURNING `+snippetColumns,
+		req.Label, req.Shortcut, req.Content, tags, req.Visibility, id, req.CollectionID, req.Language, placeholders,
+		req.Description))
+	if err != nil {
+		return nil, ShortcutConflict(err)
+	}
+	return snippet, nil
+}
+
+// ErrSnippetVersionNotFound is returned when restoring a version a snippet doesn't have
+var ErrSnippetVersionNotFound = errors.New("snippet version not found")
+
+// RestoreSnippetVersion sets a user's snippet back to the label, shortcut, content and
+// tags of one of its history versions, and records a history entry in the same
+// transaction. A snippet in the trash comes back too: unpinned, and counting towards
+// the plan quota again. Returns sql.ErrNoRows if the snippet doesn't exist,
+// ErrNotSnippetOwner if it belongs to someone else, ErrSnippetVersionNotFound if it has
+// no such version, a *ContentTooLargeError when the version's content is over the size
+// limit of the user's plan, ErrSnippetQuotaExceeded or ErrStorageQuotaExceeded when
+// restoring a deleted snippet to a full plan and ErrShortcutExists if another of the user's snippets
+// has the version's shortcut.
+func (st *Store) RestoreSnippetVersion(ctx context.Context, id int64, versionNumber int, userID, originSessionID string) (*Snippet, error) {
+	if err := st.checkSnippetOwner(ctx, id, userID); err != nil {
+		return nil, err
+	}
+
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rollbackSnippetTx(tx)
+
+	usage, err := lockSnippetUsage(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var version SnippetHistory
+	var tags pq.StringArray
+	err = tx.QueryRowContext(ctx, `
+		SELECT label, shortcut, content, tags
+		FROM snippet_history
+		WHERE snippet_id = $1 AND version_number = $2
+	`, id, versionNumber).Scan(&version.Label, &version.Shortcut, &version.Content, &tags)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSnippetVersionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The version may be from a plan with a higher content limit
+	contentBytes := int64(len(version.Content))
+	if err := usage.checkContent(contentBytes); err != nil {
+		return nil, err
+	}
+
+	var deleted bool
+	err = tx.QueryRowContext(ctx, `SELECT is_deleted FROM snippets WHERE id = $1 FOR UPDATE`, id).Scan(&deleted)
+	if err != nil {
+		return nil, err
+	}
+	// A deleted snippet isn't counted in usage, so restoring it is like creating one
+	if deleted {
+		if err := usage.quota.AllowsSnippet(usage.snippets, usage.contentBytes, contentBytes); err != nil {
+			return nil, err
+		}
+	}
+
+	// Old versions may predate placeholder validation, so invalid ones don't block a restore
+	placeholders, err := PlaceholdersJSON(version.Content, false)
+	if err != nil {
+		return nil, err
+	}
+
+	snippet, err := ScanSnippet(tx.QueryRowContext(ctx, `
+		UPDATE snippets
+		SET label = $1, shortcut = $2, content = $3, tags = $4, placeholders = $5::jsonb,
+			pinned_at = CASE WHEN is_deleted THEN NULL ELSE pinned_at END,
+			is_deleted = false, deleted_at = NULL
+		WHERE id = $6
+		RETURNING `+snippetColumns,
+		version.Label, version.Shortcut, version.Content, tags, placeholders, id))
+	if err != nil {
+		return nil, ShortcutConflict(err)
+	}
+
+	if err := enqueueSnippetChange(ctx, tx, originSessionID, EventSnippetUpdated, snippet); err != nil {
+		return nil, err
+	}
+	notes := "Restored to version " + strconv.Itoa(versionNumber)
+	if err := recordSnippetHistory(ctx, tx, snippet, userID, "restore", &notes); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	InvalidateSnippet(ctx, id)
+	return snippet, nil
+}
+
+// MaxPinnedSnippets is how many snippets a user can pin
+const MaxPinnedSnippets = 10
+
+// ErrPinLimitReached is returned when pinning a snippet would exceed MaxPinnedSnippets
+var ErrPinLimitReached = errors.New("pinned snippet limit reached")
+
+// SetSnippetFavorite stars or unstars a user's snippet. Returns sql.ErrNoRows if the
+// snippet doesn't exist and ErrNotSnippetOwner if it belongs to someone else.
+func (st *Store) SetSnippetFavorite(ctx context.Context, id int64, userID, originSessionID string, favorite bool) (*Snippet, error) {
+	return st.setSnippetState(ctx, id, userID, originSessionID, nil, "is_favorite = $2", favorite)
+}
+
+// PinSnippet pins a user's snippet so it's listed first; pinning it again keeps its pin
+// time. Returns sql.ErrNoRows if the snippet doesn't exist, ErrNotSnippetOwner if it
+// belongs to someone else and ErrPinLimitReached if they already have MaxPinnedSnippets
+// other pins.
+func (st *Store) PinSnippet(ctx context.Context, id int64, userID, originSessionID string) (*Snippet, error) {
+	checkPins := func(tx *sql.Tx) error {
+		// Lock the user row so concurrent pins can't both take the last slot
+		if _, err := tx.ExecContext(ctx, `SELECT id FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+			return err
+		}
+		var pinned int
+		err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM snippets
+			WHERE user_id = $1 AND is_deleted = false AND pinned_at IS NOT NULL AND id <> $2
+		`, userID, id).Scan(&pinned)
+		if err != nil {
+			return err
+		}
+		if pinned >= MaxPinnedSnippets {
+			return ErrPinLimitReached
+		}
+		return nil
+	}
+	return st.setSnippetState(ctx, id, userID, originSessionID, checkPins, "pinned_at = COALESCE(pinned_at, NOW())")
+}
+
+// UnpinSnippet unpins a user's snippet. Returns sql.ErrNoRows if the snippet doesn't
+// exist and ErrNotSnippetOwner if it belongs to someone else.
+func (st *Store) UnpinSnippet(ctx context.Context, id int64, userID, originSessionID string) (*Snippet, error) {
+	return st.setSnippetState(ctx, id, userID, originSessionID, nil, "pinned_at = NULL")
+}
+
+// ArchiveSnippet archives a user's snippet, hiding it from listings and sync without
+// deleting it. Archiving also unpins it; archiving it again keeps its archive time.
+// Returns sql.ErrNoRows if the snippet doesn't exist and ErrNotSnippetOwner if it
+// belongs to someone else.
+func (st *Store) ArchiveSnippet(ctx context.Context, id int64, userID, originSessionID string) (*Snippet, error) {
+	return st.setSnippetState(ctx, id, userID, originSessionID, nil, "archived_at = COALESCE(archived_at, NOW()), pinned_at = NULL")
+}
+
+// UnarchiveSnippet brings an archived snippet of the user's back to listings and sync.
+// Returns sql.ErrNoRows if the snippet doesn't exist and ErrNotSnippetOwner if it
+// belongs to someone else.
+func (st *Store) UnarchiveSnippet(ctx context.Context, id int64, userID, originSessionID string) (*Snippet, error) {
+	return st.setSnippetState(ctx, id, userID, originSessionID, nil, "archived_at = NULL")
+}
+
+// ErrPremiumRequired is returned when a free user turns on a premium-only feature
+var ErrPremiumRequired = errors.New("premium plan required")
+
+// SetSnippetKeepHistory sets whether the retention job keeps all versions of a user's
+// snippet, however old or many. Only premium users can turn it on; after a downgrade it
+// stays set but is ignored until they're premium again. Returns sql.ErrNoRows if the
+// snippet doesn't exist, ErrNotSnippetOwner if it belongs to someone else and
+// ErrPremiumRequired if a free user turns it on.
+func (st *Store) SetSnippetKeepHistory(ctx context.Context, id int64, userID, originSessionID string, keep bool) (*Snippet, error) {
+	var checkPlan func(tx *sql.Tx) error
+	if keep {
+		checkPlan = func(tx *sql.Tx) error {
+			var premium bool
+			if err := tx.QueryRowContext(ctx, `SELECT `+hasPremiumQuery, userID, premiumRoles).Scan(&premium); err != nil {
+				return err
+			}
+			if !premium {
+				return ErrPremiumRequired
+			}
+			return nil
+		}
+	}
+	return st.setSnippetState(ctx, id, userID, originSessionID, checkPlan, "keep_history = $2", keep)
+}
+
+// setSnippetState applies set, with id as $1 and args from $2, to a user's non-deleted
+// snippet and records a snippet.updated event, for changes that aren't edits of the
+// snippet and so get no history entry. check, if not nil, runs first within the
+// transaction and aborts the change if it returns an error.
+func (st *Store) setSnippetState(ctx context.Context, id int64, userID, originSessionID string, check func(tx *sql.Tx) error, set string, args ...interface{}) (*Snippet, error) {
+	if err := st.checkSnippetOwner(ctx, id, userID); err != nil {
+		return nil, err
+	}
+
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rollbackSnippetTx(tx)
+
+	if check != nil {
+		if err := check(tx); err != nil {
+			return nil, err
+		}
+	}
+
+	snippet, err := ScanSnippet(tx.QueryRowContext(ctx, `
+		UPDATE snippets SET `+set+`
+		WHERE id = $1 AND is_deleted = false
+		RETURNING `+snippetColumns, append([]interface{}{id}, args...)...))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := enqueueSnippetChange(ctx, tx, originSessionID, EventSnippetUpdated, snippet); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	InvalidateSnippet(ctx, id)
+	return snippet, nil
+}
+
+// DeleteSnippet soft-deletes a user's snippet and records a history entry.
+// Returns sql.ErrNoRows if the snippet doesn't exist and ErrNotSnippetOwner if it
+// belongs to someone else.
+func (st *Store) DeleteSnippet(ctx context.Context, id int64, userID, originSessionID string) error {
+	if err := st.checkSnippetOwner(ctx, id, userID); err != nil {
+		return err
+	}
+
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer rollbackSnippetTx(tx)
+
+	snippet, err := ScanSnippet(tx.QueryRowContext(ctx, `
+		UPDATE snippets SET is_deleted = true, deleted_at = NOW()
+		WHERE id = $1 AND is_deleted = false
+		RETURNING `+snippetColumns, id))
+	if err != nil {
+		return err
+	}
+
+	if err := enqueueSnippetChange(ctx, tx, originSessionID, EventSnippetDeleted, snippet); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	InvalidateSnippet(ctx, id)
+
+	notes := "Snippet marked as deleted"
+	if err := recordSnippetHistory(ctx, st.db, snippet, userID, "soft_delete", &notes); err != nil {
+		fmt.Printf("failed to create snippet deletion history for %d: %v\n", snippet.ID, err)
+	}
+	return nil
+}
+
+// RenameTag replaces tag from with to on all of the user's non-deleted snippets in one
+// transaction, merging it into to where a snippet has both, and records a history entry
+// and domain event for each changed snippet. It returns the changed snippets.
+func (st *Store) RenameTag(ctx context.Context, userID, originSessionID, from, to string) ([]Snippet, error) {
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rollbackSnippetTx(tx)
+
+	// Tags keep their first position, so a merged tag takes the place of the earlier one
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE snippets
+		SET tags = (
+			SELECT array_agg(tag ORDER BY position)
+			FROM (
+				SELECT tag, MIN(position) AS position
+				FROM unnest(array_replace(tags, $2, $3)) WITH ORDINALITY AS t(tag, position)
+				GROUP BY tag
+			) merged
+		)
+		WHERE user_id = $1 AND is_deleted = false AND $2 = ANY(tags)
+		RETURNING `+snippetColumns,
+		userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	snippets, err := collectSnippets(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(snippets) == 0 {
+		return snippets, nil
+	}
+
+	ids := make([]int64, len(snippets))
+	events := make([]AggregateEvent, len(snippets))
+	for i := range snippets {
+		ids[i] = snippets[i].ID
+		events[i] = AggregateEvent{AggregateID: strconv.FormatInt(snippets[i].ID, 10), Payload: &snippets[i]}
+	}
+
+	notes := fmt.Sprintf("Renamed tag %q to %q", from, to)
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO snippet_history (
+			snippet_id, version_number, label, shortcut, content, tags,
+			changed_by, change_type, change_notes
+		)
+		SELECT id, get_next_snippet_version(id), label, shortcut, content, tags, $2, 'edit', $3
+		FROM snippets
+		WHERE id = ANY($1)
+	`, pq.Array(ids), userID, notes)
+	if err != nil {
+		return nil, fmt.Errorf("record tag rename history: %w", err)
+	}
+
+	if err := EnqueueEventsFromSession(ctx, tx, originSessionID, EventSnippetUpdated, AggregateSnippet, events); err != nil {
+		return nil, fmt.Errorf("enqueue %s events: %w", EventSnippetUpdated, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		InvalidateSnippet(ctx, id)
+	}
+	return snippets, nil
+}
+
+// syncDeletedColumns are the columns of a snippet reported as deleted by
+// GetSnippetChanges: its ID and timestamps, with empty content
+const syncDeletedColumns = `id, '' as label, '' as shortcut, '' as content, ARRAY[]::TEXT[] as tags,
+		       user_id, created_at, updated_at, visibility, NULL::INTEGER as collection_id,
+		       false as is_favorite, NULL::TIMESTAMP WITH TIME ZONE as pinned_at,
+		       NULL::TIMESTAMP WITH TIME ZONE as archived_at, NULL::VARCHAR as language,
+		       '[]'::JSONB as placeholders, NULL::TEXT as description, false as keep_history`
+
+// GetSnippetChanges returns a user's snippets created, updated and deleted after since,
+// in a single round-trip, along with those shared with the user, and the cursor of the
+// last change, or since if nothing changed. Archived snippets are
+// left out of created and updated; those archived after since are reported as deleted,
+// with their archive time, so devices drop them. Unarchiving a snippet updates it, so it
+// comes back as updated. Likewise a snippet shared after since is created for the user,
+// a changed share role updates it and a revoked share deletes it. Each snippet is marked
+// with the user's ownership of it.
+func (st *Store) GetSnippetChanges(ctx context.Context, userID string, since SyncCursor) (*SnippetChanges, error) {
+	query := `
+		WITH shared AS (
+			SELECT snippet_id, role AS share_role, created_at AS shared_at,
+			       updated_at AS share_updated_at, revoked_at AS unshared_at
+			FROM snippet_shares
+			WHERE user_id = $1
+		)
+		SELECT ` + snippetColumns + `,
+		       NULL::TIMESTAMP WITH TIME ZONE as deleted_at, 'created' as sync_type, '' as share_role,
+		       updated_at as sync_at
+		FROM snippets
+		WHERE user_id = $1 AND is_deleted = false AND archived_at IS NULL AND (created_at, id) > ($2, $3::bigint)
+
+		UNION ALL
+
+		SELECT ` + snippetColumns + `,
+		       NULL::TIMESTAMP WITH TIME ZONE as deleted_at, 'updated' as sync_type, '' as share_role,
+		       updated_at as sync_at
+		FROM snippets
+		WHERE user_id = $1 AND is_deleted = false AND archived_at IS NULL
+		  AND (updated_at, id) > ($2, $3::bigint) AND (created_at, id) <= ($2, $3::bigint)
+
+		UNION ALL
+
+		SELECT ` + syncDeletedColumns + `,
+		       COALESCE(deleted_at, archived_at) as deleted_at, 'deleted' as sync_type, '' as share_role,
+		       GREATEST(updated_at, deleted_at, archived_at) as sync_at
+		FROM snippets
+		WHERE user_id = $1 AND (
+			(is_deleted = true AND deleted_at IS NOT NULL AND (deleted_at, id) > ($2, $3::bigint)) OR
+			(is_deleted = false AND (archived_at, id) > ($2, $3::bigint))
+		)
+
+		UNION ALL
+
+		SELECT ` + snippetColumns + `,
+		       NULL::TIMESTAMP WITH TIME ZONE as deleted_at, 'created' as sync_type, share_role,
+		       GREATEST(updated_at, shared_at, share_updated_at) as sync_at
+		FROM snippets JOIN shared ON shared.snippet_id = snippets.id
+		WHERE unshared_at IS NULL AND is_deleted = false AND archived_at IS NULL
+		  AND ((created_at, id) > ($2, $3::bigint) OR (shared_at, id) > ($2, $3::bigint))
+
+		UNION ALL
+
+		SELECT ` + snippetColumns + `,
+		       NULL::TIMESTAMP WITH TIME ZONE as deleted_at, 'updated' as sync_type, share_role,
+		       GREATEST(updated_at, shared_at, share_updated_at) as sync_at
+		FROM snippets JOIN shared ON shared.snippet_id = snippets.id
+		WHERE unshared_at IS NULL AND is_deleted = false AND archived_at IS NULL
+		  AND (created_at, id) <= ($2, $3::bigint) AND (shared_at, id) <= ($2, $3::bigint)
+		  AND ((updated_at, id) > ($2, $3::bigint) OR (share_updated_at, id) > ($2, $3::bigint))
+
+		UNION ALL
+
+		SELECT ` + syncDeletedColumns + `,
+		       COALESCE(unshared_at, deleted_at, archived_at) as deleted_at, 'deleted' as sync_type, share_role,
+		       GREATEST(updated_at, shared_at, share_updated_at, unshared_at, deleted_at, archived_at) as sync_at
+		FROM snippets JOIN shared ON shared.snippet_id = snippets.id
+		WHERE (unshared_at, id) > ($2, $3::bigint) OR (unshared_at IS NULL AND (
+			(is_deleted = true AND deleted_at IS NOT NULL AND (deleted_at, id) > ($2, $3::bigint)) OR
+			(is_deleted = false AND (archived_at, id) > ($2, $3::bigint))
+		))
+	`
+
+	// Rows compare as (change time, snippet ID) against the cursor, so snippets changed at
+	// the cursor's time but after its snippet are still picked up
+	rows, err := st.db.QueryContext(ctx, query, userID, since.Time, since.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing sync rows: %v\n", closeErr)
+		}
+	}()
+
+	changes := &SnippetChanges{
+		Created: make([]Snippet, 0, 10),
+		Updated: make([]Snippet, 0, 10),
+		Deleted: make([]DeletedSnippet, 0, 10),
+	}
+	next := since
+	for rows.Next() {
+		var s Snippet
+		var tags pq.StringArray
+		var rowUserID sql.NullString
+		var collectionID sql.NullInt64
+		var pinnedAt, archivedAt, deletedAt sql.NullTime
+		var language, description sql.NullString
+		var placeholders []byte
+		var syncType, shareRole string
+		var syncAt time.Time
+		if err := rows.Scan(&s.ID, &s.Label, &s.Shortcut, &s.Content, &tags, &rowUserID,
+			&s.CreatedAt, &s.UpdatedAt, &s.Visibility, &collectionID, &s.IsFavorite, &pinnedAt, &archivedAt, &language,
+			&placeholders, &description, &s.KeepHistory, &deletedAt, &syncType, &shareRole, &syncAt); err != nil {
+			return nil, err
+		}
+		if position := (SyncCursor{Time: syncAt, ID: s.ID}); position.After(next) {
+			next = position
+		}
+
+		switch syncType {
+		case "created", "updated":
+			s.Tags = tags
+			if rowUserID.Valid {
+				s.UserID = &rowUserID.String
+			}
+			if collectionID.Valid {
+				s.CollectionID = &collectionID.Int64
+			}
+			if pinnedAt.Valid {
+				s.PinnedAt = &pinnedAt.Time
+			}
+			if archivedAt.Valid {
+				s.ArchivedAt = &archivedAt.Time
+			}
+			if language.Valid {
+				s.Language = &language.String
+			}
+			if description.Valid {
+				s.Description = &description.String
+			}
+			if err := json.Unmarshal(placeholders, &s.Placeholders); err != nil {
+				return nil, fmt.Errorf("decode placeholders of snippet %d: %w", s.ID, err)
+			}
+			s.Ownership = OwnershipOwner
+			if shareRole != "" {
+				asShared(&s, shareRole)
+			}
+			if syncType == "created" {
+				changes.Created = append(changes.Created, s)
+			} else {
+				changes.Updated = append(changes.Updated, s)
+			}
+		case "deleted":
+			item := DeletedSnippet{ID: s.ID}
+			if deletedAt.Valid {
+				item.DeletedAt = &deletedAt.Time
+			}
+			changes.Deleted = append(changes.Deleted, item)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	changes.Cursor = next.String()
+	return changes, nil
+}
+
+// GetUserSnippet returns one of a user's non-deleted snippets. Returns sql.ErrNoRows if
+// it doesn't exist and ErrNotSnippetOwner if it belongs to someone else.
+func (st *Store) GetUserSnippet(ctx context.Context, id int64, userID string) (*Snippet, error) {
+	snippet, err := st.GetCachedSnippet(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if snippet.UserID == nil || *snippet.UserID != userID {
+		return nil, ErrNotSnippetOwner
+	}
+	return snippet, nil
+}
+
+// GetReadableSnippet returns snippet id if userID may read it in organization orgID. Owners
+// can always read their snippets, users it's shared with can too, and anyone in the
+// owner's organization can read public ones. Any other snippet is reported as
+// sql.ErrNoRows, so other users' private snippets can't be told apart from missing ones.
+func (st *Store) GetReadableSnippet(ctx context.Context, userID, orgID string, id int64) (*Snippet, error) {
+	snippet, err := st.GetCachedSnippet(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if snippet.UserID != nil && *snippet.UserID == userID {
+		snippet.Ownership = OwnershipOwner
+		return snippet, nil
+	}
+	role, err := st.SnippetShareRole(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role != "" {
+		asShared(snippet, role)
+		return snippet, nil
+	}
+	if snippet.Visibility != VisibilityPublic {
+		return nil, sql.ErrNoRows
+	}
+
+	// Public snippets are only public in their organization, and not once the author is gone
+	item, err := st.GetPublicSnippet(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	return &item.Snippet, nil
+}
+
+// ListSnippets returns the snippets selected by q
+func (st *Store) ListSnippets(ctx context.Context, q *snippetquery.Builder) ([]Snippet, error) {
+	query, args := q.Select(snippetColumns)
+	rows, err := st.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return collectSnippets(rows)
+}
+
+// ListSnippetsPage returns the snippets selected by q and how many snippets match q's
+// conditions regardless of its limit and offset
+func (st *Store) ListSnippetsPage(ctx context.Context, q *snippetquery.Builder) ([]Snippet, int, error) {
+	query, args := q.Select(snippetColumns + ", COUNT(*) OVER()")
+	rows, err := st.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing snippet rows: %v\n", closeErr)
+		}
+	}()
+
+	total := 0
+	snippets := make([]Snippet, 0, 10)
+	for rows.Next() {
+		s, err := ScanSnippet(totalScanner{rows: rows, total: &total})
+		if err != nil {
+			return nil, 0, err
+		}
+		snippets = append(snippets, *s)
+	}
+	return snippets, total, rows.Err()
+}
+
+// ListUserSnippets returns a user's non-deleted snippets matching f, best search match or
+// newest first unless f sorts them otherwise. With f.IncludeShared each snippet is marked
+// with the user's ownership of it.
+func (st *Store) ListUserSnippets(ctx context.Context, userID string, f snippetquery.Filter) ([]Snippet, error) {
+	snippets, err := st.ListSnippets(ctx, userSnippetsQuery(userID, f))
+	if err != nil || !f.IncludeShared {
+		return snippets, err
+	}
+	return snippets, st.setOwnership(ctx, userID, snippetPointers(snippets)...)
+}
+
+// ListUserSnippetsPage returns a page of a user's non-deleted snippets matching f, best
+// search match or newest first unless f sorts them otherwise, and how many match in all.
+// With f.IncludeShared each snippet is marked with the user's ownership of it.
+func (st *Store) ListUserSnippetsPage(ctx context.Context, userID string, f snippetquery.Filter) ([]Snippet, int, error) {
+	snippets, total, err := st.ListSnippetsPage(ctx, userSnippetsQuery(userID, f))
+	if err != nil || !f.IncludeShared {
+		return snippets, total, err
+	}
+	return snippets, total, st.setOwnership(ctx, userID, snippetPointers(snippets)...)
+}
+
+// snippetPointers returns pointers to each of snippets
+func snippetPointers(snippets []Snippet) []*Snippet {
+	pointers := make([]*Snippet, len(snippets))
+	for i := range snippets {
+		pointers[i] = &snippets[i]
+	}
+	return pointers
+}
+
+// ListUserSnippetHitsPage is ListUserSnippetsPage for a search, with each snippet's rank
+// and highlights. Unless f sorts them otherwise, the best matches come first.
+func (st *Store) ListUserSnippetHitsPage(ctx context.Context, userID string, f snippetquery.Filter) ([]SnippetHit, int, error) {
+	q := userSnippetsQuery(userID, f)
+	label, content := q.SearchHeadlines()
+	query, args := q.Select(snippetColumns + ", " + q.SearchRank() + ", " + label + ", " + content + ", COUNT(*) OVER()")
+	rows, err := st.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing snippet hit rows: %v\n", closeErr)
+		}
+	}()
+
+	total := 0
+	hits := make([]SnippetHit, 0, 10)
+	for rows.Next() {
+		var hit SnippetHit
+		s, err := ScanSnippet(hitScanner{rows: rows, hit: &hit, total: &total})
+		if err != nil {
+			return nil, 0, err
+		}
+		hit.Snippet = *s
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil || !f.IncludeShared {
+		return hits, total, err
+	}
+
+	pointers := make([]*Snippet, len(hits))
+	for i := range hits {
+		pointers[i] = &hits[i].Snippet
+	}
+	return hits, total, st.setOwnership(ctx, userID, pointers...)
+}
+
+// userSnippetsQuery selects a user's non-deleted snippets matching f, and with
+// f.IncludeShared those shared with them, directly or through a collection. Stars and collections are the owner's, so
+// favorites and collection filters only keep the user's own snippets. Searches are in f's
+// sort order, otherwise best match first. Other listings have the user's pinned snippets
+// first, most recently pinned first, then the rest in f's sort order, otherwise newest
+// first.
+func userSnippetsQuery(userID string, f snippetquery.Filter) *snippetquery.Builder {
+	q := snippetquery.New()
+	owner := q.Arg(userID)
+	if f.IncludeShared && !f.Favorites && f.Collection == 0 {
+		q.Where("(user_id = " + owner + " OR id IN (SELECT snippet_id FROM (" + sharedSnippetRoles(owner) + ") shared))")
+	} else {
+		q.Where("user_id = " + owner)
+	}
+	q.Filter(f)
+	if f.Search != "" {
+		if f.Sort != "" {
+			return q.OrderBy(f.Sort)
+		}
+		return q.OrderBySearchRank()
+	}
+	order := "created_at DESC"
+	if f.Sort != "" {
+		order = f.Sort
+	}
+	return q.OrderBy("CASE WHEN user_id = " + owner + " THEN pinned_at END DESC NULLS LAST, " + order)
+}
+
+// checkSnippetEditor verifies a snippet exists and userID may apply req to it: owners
+// can change anything, editors it's shared with all but its shortcut, visibility and
+// collection, which are the owner's
+func (st *Store) checkSnippetEditor(ctx context.Context, id int64, userID string, req UpdateSnippetRequest) error {
+	err := st.checkSnippetOwner(ctx, id, userID)
+	if !errors.Is(err, ErrNotSnippetOwner) || req.Shortcut != nil || req.Visibility != nil || req.CollectionID != nil {
+		return err
+	}
+	role, roleErr := st.SnippetShareRole(ctx, id, userID)
+	if roleErr != nil {
+		return roleErr
+	}
+	if role != ShareRoleEditor {
+		return err
+	}
+	return nil
+}
+
+// checkSnippetOwner verifies a snippet exists and belongs to userID
+func (st *Store) checkSnippetOwner(ctx context.Context, id int64, userID string) error {
+	ownerID, err := database.CheckSnippetOwnership(ctx, st.db, id)
+	if err != nil {
+		return err
+	}
+	if !ownerID.Valid || ownerID.String != userID {
+		return ErrNotSnippetOwner
+	}
+	return nil
+}
+
+// enqueueSnippetChange writes a snippet domain event to the outbox within tx
+func enqueueSnippetChange(ctx context.Context, tx *sql.Tx, originSessionID, eventType string, snippet *Snippet) error {
+	err := EnqueueEventFromSession(ctx, tx, originSessionID, eventType, AggregateSnippet, strconv.FormatInt(snippet.ID, 10), snippet)
+	if err != nil {
+		return fmt.Errorf("enqueue %s event for snippet %d: %w", eventType, snippet.ID, err)
+	}
+	return nil
+}
+
+// recordSnippetHistory stores the snippet's current state as a new version using exec
+func recordSnippetHistory(ctx context.Context, exec Execer, snippet *Snippet, userID, changeType string, changeNotes *string) error {
+	_, err := exec.ExecContext(ctx, `
+		INSERT INTO snippet_history (
+			snippet_id, version_number, label, shortcut, content, tags,
+			changed_by, change_type, change_notes
+		) VALUES (
+			$1, get_next_snippet_version($1), $2, $3, $4, $5, $6, $7, $8
+		)
+	`, snippet.ID, snippet.Label, snippet.Shortcut, snippet.Content, pq.Array(snippet.Tags), userID, changeType, changeNotes)
+	return err
+}
+
+// rollbackSnippetTx rolls back a snippet transaction unless it was already committed
+func rollbackSnippetTx(tx *sql.Tx) {
+	if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		fmt.Printf("error rolling back snippet transaction: %v\n", err)
+	}
+}
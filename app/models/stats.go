@@ -0,0 +1,147 @@
+// Package models provides aggregate statistics for admin dashboards.
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// DailyCount is one point of a per-day time series
+type DailyCount struct {
+	Day   string `json:"day"` // YYYY-MM-DD
+	Count int64  `json:"count"`
+}
+
+// TableSize reports the on-disk size of a table including indexes and TOAST
+type TableSize struct {
+	Table     string `json:"table"`
+	SizeBytes int64  `json:"sizeBytes"`
+}
+
+// AdminStats aggregates counts and time series for operational dashboards
+type AdminStats struct {
+	NewUsersPerDay        []DailyCount `json:"newUsersPerDay"`
+	SnippetsCreatedPerDay []DailyCount `json:"snippetsCreatedPerDay"`
+	TableSizes            []TableSize  `json:"tableSizes"`
+	TotalUsers            int64        `json:"totalUsers"`
+	DeletedUsers          int64        `json:"deletedUsers"`
+	ActiveSessions        int64        `json:"activeSessions"`
+	TotalSnippets         int64        `json:"totalSnippets"`
+	DeletedSnippets       int64        `json:"deletedSnippets"`
+	DatabaseSizeBytes     int64        `json:"databaseSizeBytes"`
+	SnippetsPerDayAverage float64      `json:"snippetsPerDayAverage"`
+	PeriodDays            int          `json:"periodDays"`
+}
+
+// GetAdminStats collects totals, storage usage and per-day series covering the last days days.
+func (st *Store) GetAdminStats(ctx context.Context, days int) (*AdminStats, error) {
+	stats := &AdminStats{PeriodDays: days}
+
+	err := st.db.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM users WHERE is_deleted = false),
+			(SELECT COUNT(*) FROM users WHERE is_deleted = true),
+			(SELECT COUNT(*) FROM sessions WHERE active = true AND (expires_at IS NULL OR expires_at > NOW())),
+			(SELECT COUNT(*) FROM snippets WHERE is_deleted = false),
+			(SELECT COUNT(*) FROM snippets WHERE is_deleted = true),
+			pg_database_size(current_database())
+	`).Scan(
+		&stats.TotalUsers,
+		&stats.DeletedUsers,
+		&stats.ActiveSessions,
+		&stats.TotalSnippets,
+		&stats.DeletedSnippets,
+		&stats.DatabaseSizeBytes,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("totals: %w", err)
+	}
+
+	if stats.NewUsersPerDay, err = st.dailyCreatedCounts(ctx, "users", days); err != nil {
+		return nil, fmt.Errorf("new users per day: %w", err)
+	}
+	if stats.SnippetsCreatedPerDay, err = st.dailyCreatedCounts(ctx, "snippets", days); err != nil {
+		return nil, fmt.Errorf("snippets per day: %w", err)
+	}
+
+	var created int64
+	for _, d := range stats.SnippetsCreatedPerDay {
+		created += d.Count
+	}
+	if days > 0 {
+		stats.SnippetsPerDayAverage = float64(created) / float64(days)
+	}
+
+	if stats.TableSizes, err = st.getTableSizes(ctx); err != nil {
+		return nil, fmt.Errorf("table sizes: %w", err)
+	}
+
+	return stats, nil
+}
+
+// dailyCreatedCounts counts rows created per day in table, filling days without rows with zero.
+// table must be a trusted identifier; it is quoted but never taken from user input.
+func (st *Store) dailyCreatedCounts(ctx context.Context, table string, days int) ([]DailyCount, error) {
+	query := `
+		WITH days AS (
+			SELECT generate_series(CURRENT_DATE - ($1::int - 1), CURRENT_DATE, INTERVAL '1 day')::date AS day
+		)
+		SELECT to_char(days.day, 'YYYY-MM-DD'), COUNT(t.created_at)
+		FROM days
+		LEFT JOIN ` + pq.QuoteIdentifier(table) + ` t
+			ON t.created_at >= days.day AND t.created_at < days.day + 1
+		GROUP BY days.day
+		ORDER BY days.day
+	`
+
+	rows, err := st.db.QueryContext(ctx, query, days)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing daily count rows: %v\n", closeErr)
+		}
+	}()
+
+	series := make([]DailyCount, 0, days)
+	for rows.Next() {
+		var d DailyCount
+		if err := rows.Scan(&d.Day, &d.Count); err != nil {
+			return nil, err
+		}
+		series = append(series, d)
+	}
+
+	return series, rows.Err()
+}
+
+// getTableSizes lists user tables by total size, largest first
+func (st *Store) getTableSizes(ctx context.Context) ([]TableSize, error) {
+	rows, err := st.db.QueryContext(ctx, `
+		SELECT relname, pg_total_relation_size(relid)
+		FROM pg_stat_user_tables
+		ORDER BY pg_total_relation_size(relid) DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing table size rows: %v\n", closeErr)
+		}
+	}()
+
+	sizes := make([]TableSize, 0)
+	for rows.Next() {
+		var ts TableSize
+		if err := rows.Scan(&ts.Table, &ts.SizeBytes); err != nil {
+			return nil, err
+		}
+		sizes = append(sizes, ts)
+	}
+
+	return sizes, rows.Err()
+}
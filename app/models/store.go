@@ -0,0 +1,21 @@
+// Package models provides the Store the models' queries run against.
+package models
+
+import "database/sql"
+
+// Store runs the models' queries against a database. Handlers, the gRPC services and the
+// background jobs are each built with one, so tests give them a test database instead of
+// swapping a global connection.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a Store querying db
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// DB returns the database the store queries, for callers running their own statements
+func (st *Store) DB() *sql.DB {
+	return st.db
+}
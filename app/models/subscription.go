@@ -0,0 +1,194 @@
+// Package models provides paid subscriptions and the premium role they grant.
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Subscription statuses reported by Stripe
+const (
+	SubscriptionIncomplete        = "incomplete"
+	SubscriptionIncompleteExpired = "incomplete_expired"
+	SubscriptionTrialing          = "trialing"
+	SubscriptionActive            = "active"
+	SubscriptionPastDue           = "past_due"
+	SubscriptionCanceled          = "canceled"
+	SubscriptionUnpaid            = "unpaid"
+	SubscriptionPaused            = "paused"
+)
+
+// Subscription is a user's billing state
+type Subscription struct {
+	UpdatedAt         time.Time  `json:"updatedAt"`
+	CurrentPeriodEnd  *time.Time `json:"currentPeriodEnd,omitempty"`
+	SubscriptionID    *string    `json:"-"`
+	PriceID           *string    `json:"priceId,omitempty"`
+	UserID            string     `json:"userId"`
+	CustomerID        string     `json:"-"`
+	Status            string     `json:"status"`
+	CancelAtPeriodEnd bool       `json:"cancelAtPeriodEnd"`
+	Premium           bool       `json:"premium"`
+}
+
+// SubscriptionUpdate is the state of a Stripe subscription as of EventAt
+type SubscriptionUpdate struct {
+	EventAt           time.Time
+	CurrentPeriodEnd  *time.Time
+	UserID            string
+	CustomerID        string
+	SubscriptionID    string
+	Status            string
+	PriceID           string
+	CancelAtPeriodEnd bool
+}
+
+// IsPremiumStatus reports whether a subscription in status should have premium. Past-due
+// subscriptions keep it while Stripe retries the payment.
+func IsPremiumStatus(status string) bool {
+	switch status {
+	case SubscriptionActive, SubscriptionTrialing, SubscriptionPastDue:
+		return true
+	}
+	return false
+}
+
+const subscriptionColumns = `user_id, stripe_customer_id, stripe_subscription_id, status, price_id,
+	current_period_end, cancel_at_period_end, updated_at`
+
+// scanSubscription scans a database row into a Subscription
+func scanSubscription(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*Subscription, error) {
+	var s Subscription
+	var subscriptionID, priceID sql.NullString
+	var periodEnd sql.NullTime
+	if err := scanner.Scan(&s.UserID, &s.CustomerID, &subscriptionID, &s.Status, &priceID,
+		&periodEnd, &s.CancelAtPeriodEnd, &s.UpdatedAt); err != nil {
+		return nil, err
+	}
+	s.SubscriptionID = nullStringPtr(subscriptionID)
+	s.PriceID = nullStringPtr(priceID)
+	if periodEnd.Valid {
+		s.CurrentPeriodEnd = &periodEnd.Time
+	}
+	s.Premium = IsPremiumStatus(s.Status)
+	return &s, nil
+}
+
+// GetUserSubscription returns a user's subscription. Returns sql.ErrNoRows if they never checked out.
+func (st *Store) GetUserSubscription(ctx context.Context, userID string) (*Subscription, error) {
+	return scanSubscription(st.db.QueryRowContext(ctx, `
+		SELECT `+subscriptionColumns+`
+		FROM subscriptions
+		WHERE user_id = $1
+	`, userID))
+}
+
+// GetUserIDByStripeCustomer resolves a Stripe customer to its user. Returns sql.ErrNoRows if unknown.
+func (st *Store) GetUserIDByStripeCustomer(ctx context.Context, customerID string) (string, error) {
+	var userID string
+	err := st.db.QueryRowContext(ctx,
+		`SELECT user_id FROM subscriptions WHERE stripe_customer_id = $1`, customerID).Scan(&userID)
+	return userID, err
+}
+
+// LinkStripeCustomer records the Stripe customer created for a user at checkout. Later
+// checkouts reuse it. The subscription status is left to subscription events.
+func (st *Store) LinkStripeCustomer(ctx context.Context, userID, customerID string) error {
+	_, err := st.db.ExecContext(ctx, `
+		INSERT INTO subscriptions (user_id, stripe_customer_id)
+		SELECT id, $2 FROM users WHERE id = $1
+		ON CONFLICT (user_id) DO UPDATE SET stripe_customer_id = EXCLUDED.stripe_customer_id, updated_at = CURRENT_TIMESTAMP
+	`, userID, customerID)
+	return err
+}
+
+// ApplySubscriptionUpdate stores a subscription's state and grants or revokes the premium
+// role to match, in one transaction. Updates older than the stored state are ignored, since
+// Stripe does not deliver events in order. Premium granted by an admin (assigned_by set) is
+// never revoked here. Returns whether the user's premium role changed.
+func (st *Store) ApplySubscriptionUpdate(ctx context.Context, u SubscriptionUpdate) (bool, error) {
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			fmt.Printf("error rolling back subscription transaction: %v\n", rbErr)
+		}
+	}()
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO subscriptions (user_id, stripe_customer_id, stripe_subscription_id, status, price_id,
+			current_period_end, cancel_at_period_end, stripe_event_at)
+		SELECT id, $2, $3, $4, $5, $6, $7, $8 FROM users WHERE id = $1
+		ON CONFLICT (user_id) DO UPDATE SET
+			stripe_customer_id = EXCLUDED.stripe_customer_id,
+			stripe_subscription_id = EXCLUDED.stripe_subscription_id,
+			status = EXCLUDED.status,
+			price_id = EXCLUDED.price_id,
+			current_period_end = EXCLUDED.current_period_end,
+			cancel_at_period_end = EXCLUDED.cancel_at_period_end,
+			stripe_event_at = EXCLUDED.stripe_event_at,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE subscriptions.stripe_event_at IS NULL OR subscriptions.stripe_event_at <= EXCLUDED.stripe_event_at
+	`, u.UserID, u.CustomerID, u.SubscriptionID, u.Status, nullIfEmpty(u.PriceID),
+		u.CurrentPeriodEnd, u.CancelAtPeriodEnd, u.EventAt)
+	if err != nil {
+		return false, err
+	}
+	if applied, err := result.RowsAffected(); err != nil || applied == 0 {
+		return false, err
+	}
+
+	premium := IsPremiumStatus(u.Status)
+	if premium {
+		result, err = tx.ExecContext(ctx, `
+			INSERT INTO user_roles (user_id, role_id)
+			SELECT $1, id FROM roles WHERE name = $2
+			ON CONFLICT (user_id, role_id) DO NOTHING
+		`, u.UserID, RolePremium)
+	} else {
+		result, err = tx.ExecContext(ctx, `
+			DELETE FROM user_roles
+			WHERE user_id = $1 AND assigned_by IS NULL
+				AND role_id = (SELECT id FROM roles WHERE name = $2)
+		`, u.UserID, RolePremium)
+	}
+	if err != nil {
+		return false, err
+	}
+	changed, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	if changed > 0 {
+		action := AuditPremiumGranted
+		if !premium {
+			action = AuditPremiumRevoked
+		}
+		err = RecordAudit(ctx, tx, AuditAction{
+			Action:       action,
+			TargetType:   AuditTargetUser,
+			TargetID:     u.UserID,
+			TargetUserID: u.UserID,
+			Details:      map[string]interface{}{"subscriptionStatus": u.Status},
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	if changed > 0 {
+		InvalidateUserRoles(ctx, u.UserID)
+	}
+	return changed > 0, nil
+}
@@ -0,0 +1,68 @@
+// Package models provides the opaque cursors sync clients resume from.
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSyncCursor is returned for a sync cursor this server didn't issue
+var ErrInvalidSyncCursor = errors.New("invalid sync cursor")
+
+// syncCursorVersion prefixes encoded cursors so the encoding can change
+const syncCursorVersion = "v1"
+
+// SyncCursor is a position in a user's snippet changes: the time of a change and the
+// snippet changed, which orders the changes made at the same time. Changes are synced
+// after a cursor, so snippets sharing a change time are neither missed nor sent twice.
+type SyncCursor struct {
+	Time time.Time
+	ID   int64
+}
+
+// SyncCursorSince is the position after every change made up to since, for clients that
+// sync from a timestamp
+func SyncCursorSince(since time.Time) SyncCursor {
+	return SyncCursor{Time: since, ID: math.MaxInt64}
+}
+
+// After reports whether c comes after o
+func (c SyncCursor) After(o SyncCursor) bool {
+	if !c.Time.Equal(o.Time) {
+		return c.Time.After(o.Time)
+	}
+	return c.ID > o.ID
+}
+
+// String encodes the cursor as the opaque token handed to clients, to microseconds like
+// the database's timestamps
+func (c SyncCursor) String() string {
+	raw := syncCursorVersion + ":" + strconv.FormatInt(c.Time.UnixMicro(), 10) + ":" + strconv.FormatInt(c.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseSyncCursor decodes a cursor encoded by String. It returns ErrInvalidSyncCursor if
+// s isn't one.
+func ParseSyncCursor(s string) (SyncCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return SyncCursor{}, ErrInvalidSyncCursor
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 || parts[0] != syncCursorVersion {
+		return SyncCursor{}, ErrInvalidSyncCursor
+	}
+	micros, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return SyncCursor{}, ErrInvalidSyncCursor
+	}
+	id, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || id < 0 {
+		return SyncCursor{}, ErrInvalidSyncCursor
+	}
+	return SyncCursor{Time: time.UnixMicro(micros).UTC(), ID: id}, nil
+}
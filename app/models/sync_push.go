@@ -0,0 +1,264 @@
+// Package models provides the push side of snippet sync, applying a batch of client changes.
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MaxSyncPushChanges is how many changes a client can push at once
+const MaxSyncPushChanges = 100
+
+// Sync push operations
+const (
+	SyncOpCreate = "create"
+	SyncOpUpdate = "update"
+	SyncOpDelete = "delete"
+)
+
+// Sync push conflict reasons
+const (
+	// SyncConflictChanged means the snippet changed on the server since the client's base
+	SyncConflictChanged = "changed"
+	// SyncConflictDeleted means the snippet was deleted, or isn't one of the user's
+	SyncConflictDeleted = "deleted"
+	// SyncConflictShortcutExists means another of the user's snippets has the shortcut
+	SyncConflictShortcutExists = "shortcut_exists"
+)
+
+// SyncPushRequest is a batch of changes a client made, applied in order
+type SyncPushRequest struct {
+	Changes []SyncPushChange `json:"changes" binding:"required,min=1,max=100,dive"`
+}
+
+// SyncPushChange is one change a client made to its snippets. Updates and deletes name
+// the snippet by ID and carry the updatedAt the client last saw, so changes made on the
+// server since are reported as conflicts instead of being overwritten.
+type SyncPushChange struct {
+	// BaseUpdatedAt is the snippet's updatedAt the change was made on, for update and delete
+	BaseUpdatedAt *time.Time `json:"baseUpdatedAt,omitempty"`
+	// Create is the new snippet, for create
+	Create *CreateSnippetRequest `json:"create,omitempty"`
+	// Update has the fields to change, for update
+	Update *UpdateSnippetRequest `json:"update,omitempty"`
+	Op     string                `json:"op" binding:"required,oneof=create update delete"`
+	// ClientID identifies the change on the client; results carry it back
+	ClientID string `json:"clientId" binding:"required,max=100"`
+	// ID is the snippet to update or delete
+	ID int64 `json:"id,omitempty"`
+}
+
+// Validate checks that the change carries what its operation needs
+func (c *SyncPushChange) Validate() error {
+	switch c.Op {
+	case SyncOpCreate:
+		if c.Create == nil {
+			return errors.New("create needs create")
+		}
+	case SyncOpUpdate, SyncOpDelete:
+		if c.ID <= 0 || c.BaseUpdatedAt == nil {
+			return fmt.Errorf("%s needs id and baseUpdatedAt", c.Op)
+		}
+		if c.Op == SyncOpUpdate && c.Update == nil {
+			return errors.New("update needs update")
+		}
+	}
+	return nil
+}
+
+// SyncPushApplied is a change that was applied
+type SyncPushApplied struct {
+	// Snippet is the snippet as stored after the change; deletes have none
+	Snippet  *Snippet `json:"snippet,omitempty"`
+	ClientID string   `json:"clientId"`
+	Op       string   `json:"op"`
+	ID       int64    `json:"id"`
+}
+
+// SyncPushConflict is a change that wasn't applied because it conflicts with the server
+type SyncPushConflict struct {
+	// Current is the server's snippet: the one changed since the base, or the one holding
+	// the shortcut. Deleted snippets have none.
+	Current  *Snippet `json:"current,omitempty"`
+	ClientID string   `json:"clientId"`
+	Op       string   `json:"op"`
+	Reason   string   `json:"reason" example:"changed"`
+	ID       int64    `json:"id,omitempty"`
+}
+
+// SyncPushResult is the outcome of a pushed batch, each change being either applied or
+// a conflict
+type SyncPushResult struct {
+	Applied   []SyncPushApplied  `json:"applied"`
+	Conflicts []SyncPushConflict `json:"conflicts"`
+}
+
+// PushSnippetChanges applies a client's changes to the user's own snippets, in order, in
+// one transaction. Changes that conflict with the server are skipped and reported, and
+// the rest applied; any other error, such as a full quota, applies none of them. The
+// same errors as CreateSnippet and UpdateSnippet are returned, wrapped with the change.
+func (st *Store) PushSnippetChanges(ctx context.Context, userID, originSessionID string, changes []SyncPushChange) (*SyncPushResult, error) {
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rollbackSnippetTx(tx)
+
+	usage, err := lockSnippetUsage(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SyncPushResult{
+		Applied:   make([]SyncPushApplied, 0, len(changes)),
+		Conflicts: make([]SyncPushConflict, 0),
+	}
+	changed := make([]int64, 0, len(changes))
+	for i := range changes {
+		change := &changes[i]
+		// A conflicting change is undone on its own, keeping the rest of the batch
+		if _, err := tx.ExecContext(ctx, `SAVEPOINT sync_change`); err != nil {
+			return nil, err
+		}
+		snippet, conflict, err := pushSnippetChange(ctx, tx, usage, userID, originSessionID, change)
+		if errors.Is(err, ErrShortcutExists) {
+			conflict, err = &SyncPushConflict{Reason: SyncConflictShortcutExists}, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("change %s: %w", change.ClientID, err)
+		}
+
+		if conflict != nil {
+			if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT sync_change`); err != nil {
+				return nil, err
+			}
+			if conflict.Reason == SyncConflictShortcutExists {
+				conflict.Current = shortcutHolder(ctx, tx, userID, change)
+			}
+			conflict.ClientID, conflict.Op, conflict.ID = change.ClientID, change.Op, change.ID
+			result.Conflicts = append(result.Conflicts, *conflict)
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT sync_change`); err != nil {
+			return nil, err
+		}
+
+		applied := SyncPushApplied{Snippet: snippet, ClientID: change.ClientID, Op: change.Op, ID: change.ID}
+		if snippet != nil {
+			applied.ID = snippet.ID
+		}
+		if change.Op != SyncOpCreate {
+			changed = append(changed, change.ID)
+		}
+		result.Applied = append(result.Applied, applied)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	for _, id := range changed {
+		InvalidateSnippet(ctx, id)
+	}
+	return result, nil
+}
+
+// pushSnippetChange applies one change within tx for PushSnippetChanges. It returns the
+// snippet as stored, or a conflict, in which case the caller undoes anything written.
+func pushSnippetChange(ctx context.Context, tx *sql.Tx, usage *snippetUsage, userID, originSessionID string, change *SyncPushChange) (*Snippet, *SyncPushConflict, error) {
+	if change.Op == SyncOpCreate {
+		snippet, err := createSnippetTx(ctx, tx, usage, userID, originSessionID, *change.Create)
+		return snippet, nil, err
+	}
+
+	current, err := ScanSnippet(tx.QueryRowContext(ctx, `
+		SELECT `+snippetColumns+` FROM snippets
+		WHERE id = $1 AND user_id = $2 AND is_deleted = false
+		FOR UPDATE
+	`, change.ID, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		if change.Op == SyncOpDelete {
+			// Deleting a snippet that's already gone leaves it gone
+			return nil, nil, nil
+		}
+		return nil, &SyncPushConflict{Reason: SyncConflictDeleted}, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if !current.UpdatedAt.Equal(*change.BaseUpdatedAt) {
+		return nil, &SyncPushConflict{Reason: SyncConflictChanged, Current: current}, nil
+	}
+
+	if change.Op == SyncOpDelete {
+		snippet, err := ScanSnippet(tx.QueryRowContext(ctx, `
+			UPDATE snippets SET is_deleted = true, deleted_at = NOW()
+			WHERE id = $1
+			RETURNING `+snippetColumns, change.ID))
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := enqueueSnippetChange(ctx, tx, originSessionID, EventSnippetDeleted, snippet); err != nil {
+			return nil, nil, err
+		}
+		notes := "Snippet marked as deleted"
+		if err := recordSnippetHistory(ctx, tx, snippet, userID, "soft_delete", &notes); err != nil {
+			return nil, nil, err
+		}
+		usage.snippets--
+		usage.contentBytes -= int64(len(snippet.Content))
+		return nil, nil, nil
+	}
+
+	req := *change.Update
+	if err := normalizeLanguageField(req.Language); err != nil {
+		return nil, nil, err
+	}
+	var placeholders *string
+	if req.Content != nil {
+		if err := usage.checkContent(int64(len(*req.Content))); err != nil {
+			return nil, nil, err
+		}
+		encoded, err := PlaceholdersJSON(*req.Content, true)
+		if err != nil {
+			return nil, nil, err
+		}
+		placeholders = &encoded
+	}
+	snippet, err := updateSnippetRow(ctx, tx, change.ID, userID, req, placeholders)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := enqueueSnippetChange(ctx, tx, originSessionID, EventSnippetUpdated, snippet); err != nil {
+		return nil, nil, err
+	}
+	if err := recordSnippetHistory(ctx, tx, snippet, userID, "edit", req.ChangeNotes); err != nil {
+		return nil, nil, err
+	}
+	usage.contentBytes += int64(len(snippet.Content) - len(current.Content))
+	return snippet, nil, nil
+}
+
+// shortcutHolder returns the user's snippet with the shortcut change wanted, or nil if
+// it can't be loaded
+func shortcutHolder(ctx context.Context, tx *sql.Tx, userID string, change *SyncPushChange) *Snippet {
+	var shortcut string
+	switch {
+	case change.Create != nil:
+		shortcut = change.Create.Shortcut
+	case change.Update != nil && change.Update.Shortcut != nil:
+		shortcut = *change.Update.Shortcut
+	default:
+		return nil
+	}
+	snippet, err := ScanSnippet(tx.QueryRowContext(ctx, `
+		SELECT `+snippetColumns+` FROM snippets
+		WHERE user_id = $1 AND shortcut = $2 AND is_deleted = false
+	`, userID, shortcut))
+	if err != nil {
+		return nil
+	}
+	return snippet
+}
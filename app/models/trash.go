@@ -0,0 +1,159 @@
+// Package models provides the trash of soft-deleted snippets users can restore from.
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jheysaaz/snippy-backend/app/snippetquery"
+)
+
+// ErrSnippetNotDeleted is returned when purging a snippet that isn't in the trash
+var ErrSnippetNotDeleted = errors.New("snippet is not deleted")
+
+// TrashedSnippet is a soft-deleted snippet, with when retention will purge it for good
+type TrashedSnippet struct {
+	DeletedAt time.Time `json:"deletedAt"`
+	PurgeAt   time.Time `json:"purgeAt"`
+	Snippet
+}
+
+// ListTrashedSnippetsPage returns a page of the user's soft-deleted snippets, most
+// recently deleted first, and how many there are in all. PurgeAt is retentionDays after
+// each deletion.
+func (st *Store) ListTrashedSnippetsPage(ctx context.Context, userID string, retentionDays, limit, offset int) ([]TrashedSnippet, int, error) {
+	q := snippetquery.New().Where("user_id = ?", userID).Where("is_deleted = true").
+		OrderBy("deleted_at DESC NULLS LAST, id DESC").Limit(limit).Offset(offset)
+	query, args := q.Select(snippetColumns + ", COALESCE(deleted_at, updated_at), COUNT(*) OVER()")
+	rows, err := st.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing trashed snippet rows: %v\n", closeErr)
+		}
+	}()
+
+	total := 0
+	trashed := make([]TrashedSnippet, 0, 10)
+	for rows.Next() {
+		var item TrashedSnippet
+		s, err := ScanSnippet(totalScanner{rows: rows, total: &total, extra: []interface{}{&item.DeletedAt}})
+		if err != nil {
+			return nil, 0, err
+		}
+		item.Snippet = *s
+		item.PurgeAt = item.DeletedAt.AddDate(0, 0, retentionDays)
+		trashed = append(trashed, item)
+	}
+	return trashed, total, rows.Err()
+}
+
+// UndeleteSnippet restores a user's soft-deleted snippet from the trash and records a
+// history entry. The snippet comes back unpinned, and counts towards the plan quota
+// again. Returns sql.ErrNoRows if the snippet doesn't exist or isn't in the trash,
+// ErrNotSnippetOwner if it belongs to someone else, ErrSnippetQuotaExceeded or
+// ErrStorageQuotaExceeded when the user's plan is full and ErrShortcutExists if another
+// of the user's snippets has taken its shortcut since.
+func (st *Store) UndeleteSnippet(ctx context.Context, id int64, userID, originSessionID string) (*Snippet, error) {
+	if err := st.checkSnippetOwner(ctx, id, userID); err != nil {
+		return nil, err
+	}
+
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rollbackSnippetTx(tx)
+
+	var contentBytes int64
+	err = tx.QueryRowContext(ctx, `
+		SELECT octet_length(content) FROM snippets WHERE id = $1 AND is_deleted = true
+	`, id).Scan(&contentBytes)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSnippetQuota(ctx, tx, userID, contentBytes); err != nil {
+		return nil, err
+	}
+
+	snippet, err := ScanSnippet(tx.QueryRowContext(ctx, `
+		UPDATE snippets SET is_deleted = false, deleted_at = NULL, pinned_at = NULL
+		WHERE id = $1 AND is_deleted = true
+		RETURNING `+snippetColumns, id))
+	if err != nil {
+		return nil, ShortcutConflict(err)
+	}
+
+	if err := enqueueSnippetChange(ctx, tx, originSessionID, EventSnippetUpdated, snippet); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	InvalidateSnippet(ctx, id)
+
+	notes := "Restored from trash"
+	if err := recordSnippetHistory(ctx, st.db, snippet, userID, "restore", &notes); err != nil {
+		fmt.Printf("failed to create snippet restore history for %d: %v\n", snippet.ID, err)
+	}
+	return snippet, nil
+}
+
+// PurgeSnippet permanently deletes a user's soft-deleted snippet now instead of at
+// retention cleanup, along with its history, usage and the finished webhook deliveries
+// and delivered domain events carrying its content. Returns sql.ErrNoRows if the snippet
+// doesn't exist, ErrNotSnippetOwner if it belongs to someone else and
+// ErrSnippetNotDeleted if it isn't in the trash.
+func (st *Store) PurgeSnippet(ctx context.Context, id int64, userID string) error {
+	if err := st.checkSnippetOwner(ctx, id, userID); err != nil {
+		return err
+	}
+
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer rollbackSnippetTx(tx)
+
+	var deleted bool
+	err = tx.QueryRowContext(ctx, `SELECT is_deleted FROM snippets WHERE id = $1 FOR UPDATE`, id).Scan(&deleted)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrSnippetNotDeleted
+	}
+
+	aggregateID := strconv.FormatInt(id, 10)
+	for _, query := range []string{
+		`DELETE FROM webhook_deliveries
+		WHERE status IN ('succeeded', 'failed') AND event_id IN (
+			SELECT id FROM outbox_events WHERE aggregate_type = $1 AND aggregate_id = $2
+		)`,
+		`DELETE FROM outbox_events
+		WHERE aggregate_type = $1 AND aggregate_id = $2 AND delivered_at IS NOT NULL`,
+	} {
+		if _, err := tx.ExecContext(ctx, query, AggregateSnippet, aggregateID); err != nil {
+			return err
+		}
+	}
+	// Usage rows go with the snippet through their cascade
+	if _, err := tx.ExecContext(ctx, `DELETE FROM snippet_history WHERE snippet_id = $1`, id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM snippets WHERE id = $1`, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	InvalidateSnippet(ctx, id)
+	return nil
+}
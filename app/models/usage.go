@@ -0,0 +1,358 @@
+// Package models provides per-account usage reporting and quotas.
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+// Default per-account quotas
+const (
+	DefaultMaxSnippets     = 200
+	DefaultMaxStorageBytes = 50 << 20 // 50 MiB of snippet content
+	DefaultMaxContentBytes = 20 << 10 // 20 KiB per snippet
+)
+
+// Quota limits what an account may store. Zero means unlimited.
+type Quota struct {
+	MaxSnippets     int   `json:"maxSnippets"`
+	MaxStorageBytes int64 `json:"maxStorageBytes"`
+	// MaxContentBytes caps the content of each snippet
+	MaxContentBytes int64 `json:"maxContentBytes"`
+}
+
+// Default quotas for premium accounts
+const (
+	DefaultPremiumMaxSnippets     = 0         // unlimited
+	DefaultPremiumMaxStorageBytes = 1 << 30   // 1 GiB of snippet content
+	DefaultPremiumMaxContentBytes = 500 << 10 // 500 KiB per snippet
+)
+
+// Account plans. Premium subscribers and admins have the premium plan.
+const (
+	PlanFree    = "free"
+	PlanPremium = "premium"
+)
+
+// Quota errors returned when creating a snippet would exceed the account's plan
+var (
+	ErrSnippetQuotaExceeded = errors.New("snippet quota exceeded")
+	ErrStorageQuotaExceeded = errors.New("storage quota exceeded")
+)
+
+// ContentTooLargeError is returned when a snippet's content is over the size limit of the
+// writing user's plan. UpgradePlan names a plan with a higher limit, if there is one.
+type ContentTooLargeError struct {
+	Plan            string
+	UpgradePlan     string
+	ContentBytes    int64
+	MaxContentBytes int64
+	// UpgradeMaxContentBytes is UpgradePlan's limit; 0 means unlimited
+	UpgradeMaxContentBytes int64
+}
+
+func (e *ContentTooLargeError) Error() string {
+	return fmt.Sprintf("snippet content is %d bytes, over the %d bytes the %s plan allows", e.ContentBytes, e.MaxContentBytes, e.Plan)
+}
+
+// LoadQuota returns the default quota with overrides from the environment
+// (QUOTA_MAX_SNIPPETS, QUOTA_MAX_STORAGE_BYTES, QUOTA_MAX_CONTENT_BYTES; 0 disables a
+// limit).
+func LoadQuota() Quota {
+	return quotaFromEnv("QUOTA_", Quota{
+		MaxSnippets:     DefaultMaxSnippets,
+		MaxStorageBytes: DefaultMaxStorageBytes,
+		MaxContentBytes: DefaultMaxContentBytes,
+	})
+}
+
+// LoadPremiumQuota returns the premium quota with overrides from the environment
+// (PREMIUM_QUOTA_MAX_SNIPPETS, PREMIUM_QUOTA_MAX_STORAGE_BYTES,
+// PREMIUM_QUOTA_MAX_CONTENT_BYTES; 0 disables a limit).
+func LoadPremiumQuota() Quota {
+	return quotaFromEnv("PREMIUM_QUOTA_", Quota{
+		MaxSnippets:     DefaultPremiumMaxSnippets,
+		MaxStorageBytes: DefaultPremiumMaxStorageBytes,
+		MaxContentBytes: DefaultPremiumMaxContentBytes,
+	})
+}
+
+// quotaFromEnv reads <prefix>MAX_SNIPPETS, <prefix>MAX_STORAGE_BYTES and
+// <prefix>MAX_CONTENT_BYTES over the defaults
+func quotaFromEnv(prefix string, quota Quota) Quota {
+	if raw := os.Getenv(prefix + "MAX_SNIPPETS"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			log.Printf("Ignoring invalid %sMAX_SNIPPETS %q, using %d", prefix, raw, quota.MaxSnippets)
+		} else {
+			quota.MaxSnippets = n
+		}
+	}
+	if raw := os.Getenv(prefix + "MAX_STORAGE_BYTES"); raw != "" {
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || n < 0 {
+			log.Printf("Ignoring invalid %sMAX_STORAGE_BYTES %q, using %d", prefix, raw, quota.MaxStorageBytes)
+		} else {
+			quota.MaxStorageBytes = n
+		}
+	}
+	if raw := os.Getenv(prefix + "MAX_CONTENT_BYTES"); raw != "" {
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || n < 0 {
+			log.Printf("Ignoring invalid %sMAX_CONTENT_BYTES %q, using %d", prefix, raw, quota.MaxContentBytes)
+		} else {
+			quota.MaxContentBytes = n
+		}
+	}
+
+	return quota
+}
+
+// Quotas are the limits of each plan
+type Quotas struct {
+	Free    Quota
+	Premium Quota
+}
+
+// LoadQuotas returns the free and premium quotas
+func LoadQuotas() Quotas {
+	return Quotas{Free: LoadQuota(), Premium: LoadPremiumQuota()}
+}
+
+// For returns the quota of the given plan
+func (q Quotas) For(plan string) Quota {
+	if plan == PlanPremium {
+		return q.Premium
+	}
+	return q.Free
+}
+
+// CheckContent refuses snippet content of contentBytes over plan's size limit with a
+// *ContentTooLargeError, which suggests premium to free accounts when its limit is higher
+func (q Quotas) CheckContent(plan string, contentBytes int64) error {
+	limit := q.For(plan).MaxContentBytes
+	if limit == 0 || contentBytes <= limit {
+		return nil
+	}
+	err := &ContentTooLargeError{Plan: plan, ContentBytes: contentBytes, MaxContentBytes: limit}
+	if premium := q.Premium.MaxContentBytes; plan != PlanPremium && (premium == 0 || premium > limit) {
+		err.UpgradePlan, err.UpgradeMaxContentBytes = PlanPremium, premium
+	}
+	return err
+}
+
+// AllowsSnippet reports whether an account holding snippets and contentBytes may create
+// another snippet of newBytes. Existing snippets over the quota (after a downgrade) are
+// kept; only new ones are refused.
+func (q Quota) AllowsSnippet(snippets int, contentBytes, newBytes int64) error {
+	if q.MaxSnippets > 0 && snippets >= q.MaxSnippets {
+		return ErrSnippetQuotaExceeded
+	}
+	if q.MaxStorageBytes > 0 && contentBytes+newBytes > q.MaxStorageBytes {
+		return ErrStorageQuotaExceeded
+	}
+	return nil
+}
+
+// QuotaUsage reports how much of each quota limit is used.
+// Percentages are nil for unlimited quotas.
+type QuotaUsage struct {
+	SnippetsPercent *float64 `json:"snippetsPercent,omitempty"`
+	StoragePercent  *float64 `json:"storagePercent,omitempty"`
+	Quota
+	// OverQuota is set when usage exceeds the limits, e.g. after premium lapsed
+	OverQuota         bool `json:"overQuota"`
+	CanCreateSnippets bool `json:"canCreateSnippets"`
+}
+
+// UsageReport summarizes what an account stores
+type UsageReport struct {
+	SubscriptionStatus *string    `json:"subscriptionStatus,omitempty"`
+	Plan               string     `json:"plan"`
+	Quota              QuotaUsage `json:"quota"`
+	ContentBytes       int64      `json:"contentBytes"`
+	Snippets           int        `json:"snippets"`
+	HistoryEntries     int        `json:"historyEntries"`
+	ActiveSessions     int        `json:"activeSessions"`
+}
+
+// NewQuotaUsage computes quota consumption for the given totals
+func NewQuotaUsage(quota Quota, snippets int, contentBytes int64) QuotaUsage {
+	usage := QuotaUsage{Quota: quota}
+	if quota.MaxSnippets > 0 {
+		pct := percentOf(int64(snippets), int64(quota.MaxSnippets))
+		usage.SnippetsPercent = &pct
+	}
+	if quota.MaxStorageBytes > 0 {
+		pct := percentOf(contentBytes, quota.MaxStorageBytes)
+		usage.StoragePercent = &pct
+	}
+	usage.OverQuota = (quota.MaxSnippets > 0 && snippets > quota.MaxSnippets) ||
+		(quota.MaxStorageBytes > 0 && contentBytes > quota.MaxStorageBytes)
+	usage.CanCreateSnippets = quota.AllowsSnippet(snippets, contentBytes, 0) == nil
+	return usage
+}
+
+// percentOf returns used/limit as a percentage rounded to one decimal
+func percentOf(used, limit int64) float64 {
+	return float64(used*1000/limit) / 10
+}
+
+// QuotaReport is a user's snippets and content against their plan's quota
+type QuotaReport struct {
+	// RemainingSnippets is how many more snippets the plan allows; nil when unlimited
+	RemainingSnippets *int       `json:"remainingSnippets"`
+	Plan              string     `json:"plan"`
+	Quota             QuotaUsage `json:"quota"`
+	ContentBytes      int64      `json:"contentBytes"`
+	Snippets          int        `json:"snippets"`
+}
+
+// NewQuotaReport computes a plan's quota report for the given totals
+func NewQuotaReport(plan string, quota Quota, snippets int, contentBytes int64) *QuotaReport {
+	report := &QuotaReport{
+		Plan:         plan,
+		Snippets:     snippets,
+		ContentBytes: contentBytes,
+		Quota:        NewQuotaUsage(quota, snippets, contentBytes),
+	}
+	if quota.MaxSnippets > 0 {
+		remaining := max(quota.MaxSnippets-snippets, 0)
+		report.RemainingSnippets = &remaining
+	}
+	return report
+}
+
+// GetQuotaReport returns a user's snippet count and content size against their plan's
+// quota, reading less than GetUsageReport
+func (st *Store) GetQuotaReport(ctx context.Context, userID string, quotas Quotas) (*QuotaReport, error) {
+	var snippets int
+	var contentBytes int64
+	var premium bool
+	err := st.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(octet_length(content)), 0), `+hasPremiumQuery+`
+		FROM snippets
+		WHERE user_id = $1 AND is_deleted = false
+	`, userID, premiumRoles).Scan(&snippets, &contentBytes, &premium)
+	if err != nil {
+		return nil, err
+	}
+	plan := planFor(premium)
+	return NewQuotaReport(plan, quotas.For(plan), snippets, contentBytes), nil
+}
+
+// GetUsageReport returns a user's storage and session usage against their plan's quota.
+func (st *Store) GetUsageReport(ctx context.Context, userID string, quotas Quotas) (*UsageReport, error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM snippets WHERE user_id = $1 AND is_deleted = false),
+			(SELECT COALESCE(SUM(octet_length(content)), 0) FROM snippets WHERE user_id = $1 AND is_deleted = false),
+			(SELECT COUNT(*) FROM snippet_history h JOIN snippets s ON s.id = h.snippet_id WHERE s.user_id = $1),
+			(SELECT COUNT(*) FROM sessions WHERE user_id = $1 AND active = true),
+			` + hasPremiumQuery + `,
+			(SELECT status FROM subscriptions WHERE user_id = $1)
+	`
+
+	report := &UsageReport{}
+	var premium bool
+	var subscriptionStatus sql.NullString
+	err := st.db.QueryRowContext(ctx, query, userID, premiumRoles).Scan(
+		&report.Snippets,
+		&report.ContentBytes,
+		&report.HistoryEntries,
+		&report.ActiveSessions,
+		&premium,
+		&subscriptionStatus,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	report.Plan = planFor(premium)
+	report.SubscriptionStatus = nullStringPtr(subscriptionStatus)
+	report.Quota = NewQuotaUsage(quotas.For(report.Plan), report.Snippets, report.ContentBytes)
+	return report, nil
+}
+
+// hasPremiumQuery selects whether user $1 holds one of the roles in $2, premiumRoles
+const hasPremiumQuery = `EXISTS(
+				SELECT 1 FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+				WHERE ur.user_id = $1 AND r.name = ANY($2)
+			)`
+
+// premiumRoles are the roles that give the premium plan
+var premiumRoles = pq.Array([]string{RolePremium, RoleAdmin})
+
+// GetUserPlan returns the plan of a user, premium if they hold the premium or admin role
+func (st *Store) GetUserPlan(ctx context.Context, userID string) (string, error) {
+	var premium bool
+	err := st.db.QueryRowContext(ctx, `SELECT `+hasPremiumQuery, userID, premiumRoles).Scan(&premium)
+	if err != nil {
+		return "", err
+	}
+	return planFor(premium), nil
+}
+
+func planFor(premium bool) string {
+	if premium {
+		return PlanPremium
+	}
+	return PlanFree
+}
+
+// snippetUsage is what an account holds against its plan's quota
+type snippetUsage struct {
+	plan         string
+	quotas       Quotas
+	quota        Quota
+	snippets     int
+	contentBytes int64
+}
+
+// checkContent refuses snippet content of contentBytes over the plan's size limit
+func (u *snippetUsage) checkContent(contentBytes int64) error {
+	return u.quotas.CheckContent(u.plan, contentBytes)
+}
+
+// checkSnippetQuota refuses a new snippet of newBytes that would take the user over their
+// plan's quota. It locks the user row so concurrent creates can't both pass the check.
+func checkSnippetQuota(ctx context.Context, tx *sql.Tx, userID string, newBytes int64) error {
+	usage, err := lockSnippetUsage(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	return usage.quota.AllowsSnippet(usage.snippets, usage.contentBytes, newBytes)
+}
+
+// lockSnippetUsage locks the user row and returns their snippet usage and plan quota,
+// which stay accurate for the rest of tx
+func lockSnippetUsage(ctx context.Context, tx *sql.Tx, userID string) (*snippetUsage, error) {
+	var premium bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT `+hasPremiumQuery+`
+		FROM users WHERE id = $1
+		FOR UPDATE
+	`, userID, premiumRoles).Scan(&premium)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &snippetUsage{plan: planFor(premium), quotas: LoadQuotas()}
+	usage.quota = usage.quotas.For(usage.plan)
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(octet_length(content)), 0)
+		FROM snippets
+		WHERE user_id = $1 AND is_deleted = false
+	`, userID).Scan(&usage.snippets, &usage.contentBytes)
+	if err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
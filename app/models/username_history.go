@@ -0,0 +1,65 @@
+// Package models provides username change history.
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// UsernameReuseGracePeriod is how long a former username stays reserved for its
+// previous owner and redirects to their new username.
+const UsernameReuseGracePeriod = 30 * 24 * time.Hour
+
+// RowQueryer is implemented by *sql.DB and *sql.Tx
+type RowQueryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// IsUsernameReserved reports whether username was given up by another account of organization
+// orgID within the grace period. Pass an empty exceptUserID for new registrations; a user may
+// always take back their own former name.
+func IsUsernameReserved(ctx context.Context, q RowQueryer, orgID, username, exceptUserID string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM username_history h
+			JOIN users u ON u.id = h.user_id
+			WHERE h.old_username = $1
+			  AND ($2 = '' OR h.user_id::text <> $2)
+			  AND h.changed_at > NOW() - make_interval(secs => $3)
+			  AND u.org_id = $4
+		)
+	`
+
+	var reserved bool
+	err := q.QueryRowContext(ctx, query, username, exceptUserID, UsernameReuseGracePeriod.Seconds(), orgID).Scan(&reserved)
+	return reserved, err
+}
+
+// RecordUsernameChange stores a user's previous username. Pass the transaction that renames the user.
+func RecordUsernameChange(ctx context.Context, exec Execer, userID, oldUsername string) error {
+	_, err := exec.ExecContext(ctx, `
+		INSERT INTO username_history (user_id, old_username)
+		VALUES ($1, $2)
+	`, userID, oldUsername)
+	return err
+}
+
+// ResolveFormerUsername returns the current username of the account of organization orgID
+// that used username within the grace period. Returns sql.ErrNoRows if there is none.
+func (st *Store) ResolveFormerUsername(ctx context.Context, orgID, username string) (string, error) {
+	query := `
+		SELECT u.username
+		FROM username_history h
+		JOIN users u ON u.id = h.user_id
+		WHERE h.old_username = $1
+		  AND h.changed_at > NOW() - make_interval(secs => $2)
+		  AND u.is_deleted = false AND u.org_id = $3
+		ORDER BY h.changed_at DESC
+		LIMIT 1
+	`
+
+	var current string
+	err := st.db.QueryRowContext(ctx, query, username, UsernameReuseGracePeriod.Seconds(), orgID).Scan(&current)
+	return current, err
+}
@@ -0,0 +1,359 @@
+// Package models provides webhook subscriptions and their delivery log.
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Webhook delivery states
+const (
+	WebhookPending   = "pending"
+	WebhookSending   = "sending"
+	WebhookSucceeded = "succeeded"
+	WebhookFailed    = "failed"
+)
+
+const (
+	// WebhookMaxPerUser is how many webhooks a user may register
+	WebhookMaxPerUser = 10
+
+	// WebhookMaxAttempts is how many times a delivery is tried before it is marked failed
+	WebhookMaxAttempts = 8
+
+	webhookBaseRetryDelay = 30 * time.Second
+	webhookMaxRetryDelay  = 6 * time.Hour
+
+	// webhookStaleSending is how long a delivery may stay "sending" before another
+	// worker reclaims it
+	webhookStaleSending = 5 * time.Minute
+)
+
+// Webhook payload formats. Snippy webhooks receive the signed event envelope;
+// Zapier REST hooks receive the trigger's item, as returned by polling.
+const (
+	WebhookFormatSnippy = "snippy"
+	WebhookFormatZapier = "zapier"
+)
+
+// WebhookEvents are the event types a webhook can subscribe to
+var WebhookEvents = []string{EventSnippetCreated, EventSnippetUpdated, EventSnippetDeleted, EventUserDeleted}
+
+// ErrWebhookLimit is returned when a user already has WebhookMaxPerUser webhooks
+var ErrWebhookLimit = errors.New("webhook limit reached")
+
+// Webhook is a user's subscription to their own domain events
+type Webhook struct {
+	CreatedAt   time.Time `json:"createdAt"`
+	Description *string   `json:"description,omitempty"`
+	// Secret signs deliveries; it is only returned when the webhook is created
+	Secret string   `json:"secret,omitempty"`
+	UserID string   `json:"-"`
+	URL    string   `json:"url"`
+	Format string   `json:"format"`
+	Events []string `json:"events"`
+	ID     int64    `json:"id"`
+	Active bool     `json:"active"`
+}
+
+// CreateWebhookRequest registers a webhook. An empty event list subscribes to every event.
+type CreateWebhookRequest struct {
+	Description *string  `json:"description,omitempty" binding:"omitempty,max=200"`
+	URL         string   `json:"url" binding:"required,url,max=2048"`
+	Events      []string `json:"events" binding:"max=10,dive,oneof=snippet.created snippet.updated snippet.deleted user.deleted"`
+}
+
+// WebhookDelivery is one event sent (or to be sent) to a webhook
+type WebhookDelivery struct {
+	CreatedAt      time.Time       `json:"createdAt"`
+	LastAttemptAt  *time.Time      `json:"lastAttemptAt,omitempty"`
+	NextAttemptAt  *time.Time      `json:"nextAttemptAt,omitempty"`
+	DeliveredAt    *time.Time      `json:"deliveredAt,omitempty"`
+	ResponseStatus *int            `json:"responseStatus,omitempty"`
+	Error          *string         `json:"error,omitempty"`
+	EventType      string          `json:"eventType"`
+	Status         string          `json:"status"`
+	Payload        json.RawMessage `json:"payload" swaggertype:"object"`
+	ID             int64           `json:"id"`
+	WebhookID      int64           `json:"webhookId"`
+	EventID        int64           `json:"eventId"`
+	Attempts       int             `json:"attempts"`
+}
+
+// WebhookJob is a claimed delivery with what is needed to send it
+type WebhookJob struct {
+	URL       string
+	Secret    string
+	EventType string
+	Format    string
+	Payload   []byte
+	ID        int64
+	WebhookID int64
+	Attempts  int
+}
+
+// WebhookRetryDelay is the wait after a failed attempt: 30s doubled per attempt, capped at 6h
+func WebhookRetryDelay(attempts int) time.Duration {
+	delay := webhookBaseRetryDelay
+	for i := 1; i < attempts && delay < webhookMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > webhookMaxRetryDelay {
+		delay = webhookMaxRetryDelay
+	}
+	return delay
+}
+
+const webhookColumns = `id, user_id, url, events, description, format, is_active, created_at`
+
+// scanWebhook scans a database row into a Webhook
+func scanWebhook(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*Webhook, error) {
+	var w Webhook
+	var events pq.StringArray
+	var description sql.NullString
+	if err := scanner.Scan(&w.ID, &w.UserID, &w.URL, &events, &description, &w.Format, &w.Active, &w.CreatedAt); err != nil {
+		return nil, err
+	}
+	w.Events = events
+	if description.Valid {
+		w.Description = &description.String
+	}
+	return &w, nil
+}
+
+// CreateWebhook registers a webhook for userID with the given signing secret.
+// Returns ErrWebhookLimit when the user already has WebhookMaxPerUser webhooks.
+func (st *Store) CreateWebhook(ctx context.Context, userID, secret string, req CreateWebhookRequest) (*Webhook, error) {
+	return st.createWebhook(ctx, userID, secret, WebhookFormatSnippy, req)
+}
+
+// createWebhook registers a webhook receiving payloads in format
+func (st *Store) createWebhook(ctx context.Context, userID, secret, format string, req CreateWebhookRequest) (*Webhook, error) {
+	if req.Events == nil {
+		req.Events = []string{}
+	}
+
+	// Inactive webhooks count toward the limit too, until they're deleted
+	row := st.db.QueryRowContext(ctx, `
+		INSERT INTO webhooks (user_id, url, secret, events, description, format)
+		SELECT $1, $2, $3, $4, $5, $7
+		WHERE (SELECT COUNT(*) FROM webhooks WHERE user_id = $1) < $6
+		RETURNING `+webhookColumns,
+		userID, req.URL, secret, pq.Array(req.Events), req.Description, WebhookMaxPerUser, format)
+	webhook, err := scanWebhook(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWebhookLimit
+	}
+	if err != nil {
+		return nil, err
+	}
+	webhook.Secret = secret
+	return webhook, nil
+}
+
+// GetUserWebhooks returns a user's webhooks, newest first
+func (st *Store) GetUserWebhooks(ctx context.Context, userID string) ([]Webhook, error) {
+	rows, err := st.db.QueryContext(ctx, `
+		SELECT `+webhookColumns+`
+		FROM webhooks
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing webhook rows: %v\n", closeErr)
+		}
+	}()
+
+	webhooks := make([]Webhook, 0)
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, *w)
+	}
+	return webhooks, rows.Err()
+}
+
+// GetUserWebhook returns one of a user's webhooks, or sql.ErrNoRows
+func (st *Store) GetUserWebhook(ctx context.Context, userID string, id int64) (*Webhook, error) {
+	return scanWebhook(st.db.QueryRowContext(ctx, `
+		SELECT `+webhookColumns+`
+		FROM webhooks
+		WHERE id = $1 AND user_id = $2
+	`, id, userID))
+}
+
+// DeleteWebhook removes one of a user's webhooks and its delivery log, or returns sql.ErrNoRows
+func (st *Store) DeleteWebhook(ctx context.Context, userID string, id int64) error {
+	result, err := st.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RemoveWebhook removes a webhook regardless of its owner, e.g. when its endpoint is gone
+func (st *Store) RemoveWebhook(ctx context.Context, id int64) error {
+	_, err := st.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1`, id)
+	return err
+}
+
+// GetWebhookDeliveries returns a page of a webhook's deliveries, newest first, and the
+// total number of matching deliveries
+func (st *Store) GetWebhookDeliveries(ctx context.Context, webhookID int64, status string, limit, offset int) ([]WebhookDelivery, int, error) {
+	rows, err := st.db.QueryContext(ctx, `
+		SELECT id, webhook_id, event_id, event_type, payload, status, attempts,
+		       response_status, error, created_at, last_attempt_at, next_attempt_at, delivered_at,
+		       COUNT(*) OVER()
+		FROM webhook_deliveries
+		WHERE webhook_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3 OFFSET $4
+	`, webhookID, status, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing webhook delivery rows: %v\n", closeErr)
+		}
+	}()
+
+	total := 0
+	deliveries := make([]WebhookDelivery, 0)
+	for rows.Next() {
+		var d WebhookDelivery
+		var responseStatus sql.NullInt64
+		var deliveryErr sql.NullString
+		var lastAttempt, nextAttempt, deliveredAt sql.NullTime
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts,
+			&responseStatus, &deliveryErr, &d.CreatedAt, &lastAttempt, &nextAttempt, &deliveredAt, &total); err != nil {
+			return nil, 0, err
+		}
+		if responseStatus.Valid {
+			code := int(responseStatus.Int64)
+			d.ResponseStatus = &code
+		}
+		if deliveryErr.Valid {
+			d.Error = &deliveryErr.String
+		}
+		if lastAttempt.Valid {
+			d.LastAttemptAt = &lastAttempt.Time
+		}
+		// Only pending deliveries have a next attempt
+		if nextAttempt.Valid && d.Status == WebhookPending {
+			d.NextAttemptAt = &nextAttempt.Time
+		}
+		if deliveredAt.Valid {
+			d.DeliveredAt = &deliveredAt.Time
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, total, rows.Err()
+}
+
+// EnqueueWebhookDeliveries queues payload for every active webhook of userID subscribed to
+// the event that takes payloads in format. Redelivered outbox events are ignored, so each
+// webhook gets an event once.
+func (st *Store) EnqueueWebhookDeliveries(ctx context.Context, userID string, evt OutboxEvent, format string, payload []byte) (int64, error) {
+	result, err := st.db.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload)
+		SELECT id, $2, $3, $4
+		FROM webhooks
+		WHERE user_id = $1 AND is_active = true AND format = $5
+		  AND (cardinality(events) = 0 OR $3 = ANY(events))
+		ON CONFLICT (webhook_id, event_id) DO NOTHING
+	`, userID, evt.ID, evt.EventType, payload, format)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ClaimWebhookDeliveries marks up to limit due deliveries as sending and returns them.
+// Deliveries stuck in sending (e.g. after a crash) are reclaimed.
+func (st *Store) ClaimWebhookDeliveries(ctx context.Context, limit int) ([]WebhookJob, error) {
+	rows, err := st.db.QueryContext(ctx, `
+		UPDATE webhook_deliveries d
+		SET status = 'sending', attempts = d.attempts + 1, last_attempt_at = NOW()
+		FROM webhooks w
+		WHERE w.id = d.webhook_id
+		  AND d.id IN (
+			SELECT id
+			FROM webhook_deliveries
+			WHERE (status = 'pending' AND next_attempt_at <= NOW())
+			   OR (status = 'sending' AND last_attempt_at < NOW() - make_interval(secs => $2))
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		  )
+		RETURNING d.id, d.webhook_id, d.event_type, d.payload, d.attempts, w.url, w.secret, w.format
+	`, limit, webhookStaleSending.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			fmt.Printf("error closing webhook job rows: %v\n", closeErr)
+		}
+	}()
+
+	jobs := make([]WebhookJob, 0)
+	for rows.Next() {
+		var j WebhookJob
+		if err := rows.Scan(&j.ID, &j.WebhookID, &j.EventType, &j.Payload, &j.Attempts, &j.URL, &j.Secret, &j.Format); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+// MarkWebhookDelivered records a successful attempt
+func (st *Store) MarkWebhookDelivered(ctx context.Context, id int64, responseStatus int) error {
+	_, err := st.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = 'succeeded', delivered_at = NOW(), response_status = $2, error = NULL
+		WHERE id = $1
+	`, id, responseStatus)
+	return err
+}
+
+// MarkWebhookFailed records a failed attempt. The delivery is retried after
+// WebhookRetryDelay until WebhookMaxAttempts is reached, then marked failed.
+// responseStatus is 0 when no response was received.
+func (st *Store) MarkWebhookFailed(ctx context.Context, job WebhookJob, responseStatus int, sendErr error) error {
+	var status interface{}
+	if responseStatus > 0 {
+		status = responseStatus
+	}
+	_, err := st.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = CASE WHEN attempts >= $2 THEN 'failed' ELSE 'pending' END,
+		    next_attempt_at = NOW() + make_interval(secs => $3),
+		    response_status = $4,
+		    error = $5
+		WHERE id = $1
+	`, job.ID, WebhookMaxAttempts, WebhookRetryDelay(job.Attempts).Seconds(), status, sendErr.Error())
+	return err
+}
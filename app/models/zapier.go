@@ -0,0 +1,111 @@
+// Package models provides Zapier triggers over a user's snippets.
+package models
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/jheysaaz/snippy-backend/app/snippetquery"
+)
+
+// Zapier triggers
+const (
+	ZapierTriggerNewSnippet     = "new_snippet"
+	ZapierTriggerUpdatedSnippet = "updated_snippet"
+)
+
+// ZapierPollLimit is how many items a polling trigger returns; Zapier only
+// looks at the newest ones to find what it hasn't seen
+const ZapierPollLimit = 100
+
+// ZapierTriggerEvents maps each Zapier trigger to the event its REST hooks receive
+var ZapierTriggerEvents = map[string]string{
+	ZapierTriggerNewSnippet:     EventSnippetCreated,
+	ZapierTriggerUpdatedSnippet: EventSnippetUpdated,
+}
+
+// ZapierItem is a snippet as a Zapier trigger item. Zapier deduplicates items by ID, so
+// updated snippet items get a new ID for every update.
+type ZapierItem struct {
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	ID        string    `json:"id"`
+	Event     string    `json:"event"`
+	Label     string    `json:"label"`
+	Shortcut  string    `json:"shortcut"`
+	Content   string    `json:"content"`
+	Tags      []string  `json:"tags"`
+	SnippetID int64     `json:"snippetId"`
+}
+
+// ZapierSubscribeRequest subscribes a Zapier REST hook to a trigger
+type ZapierSubscribeRequest struct {
+	HookURL string `json:"hookUrl" binding:"required,url,max=2048"`
+	Trigger string `json:"trigger" binding:"required,oneof=new_snippet updated_snippet"`
+}
+
+// NewZapierItem returns snippet as an item of trigger
+func NewZapierItem(trigger string, snippet *Snippet) ZapierItem {
+	id := strconv.FormatInt(snippet.ID, 10)
+	if trigger == ZapierTriggerUpdatedSnippet {
+		id += "-" + strconv.FormatInt(snippet.UpdatedAt.UnixMilli(), 10)
+	}
+	tags := snippet.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return ZapierItem{
+		CreatedAt: snippet.CreatedAt,
+		UpdatedAt: snippet.UpdatedAt,
+		ID:        id,
+		Event:     ZapierTriggerEvents[trigger],
+		Label:     snippet.Label,
+		Shortcut:  snippet.Shortcut,
+		Content:   snippet.Content,
+		Tags:      tags,
+		SnippetID: snippet.ID,
+	}
+}
+
+// ListZapierItems returns the newest items of trigger for userID, newest first
+func (st *Store) ListZapierItems(ctx context.Context, userID, trigger string) ([]ZapierItem, error) {
+	q := snippetquery.New().Where("user_id = ?", userID).Filter(snippetquery.Filter{Limit: ZapierPollLimit})
+	if trigger == ZapierTriggerUpdatedSnippet {
+		q.Where("updated_at > created_at").OrderBy("updated_at DESC, id DESC")
+	} else {
+		q.OrderBy("created_at DESC, id DESC")
+	}
+
+	snippets, err := st.ListSnippets(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]ZapierItem, 0, len(snippets))
+	for i := range snippets {
+		items = append(items, NewZapierItem(trigger, &snippets[i]))
+	}
+	return items, nil
+}
+
+// CreateZapierHook subscribes a Zapier REST hook to trigger for userID. It counts towards
+// WebhookMaxPerUser like any other webhook.
+func (st *Store) CreateZapierHook(ctx context.Context, userID, secret string, req ZapierSubscribeRequest) (*Webhook, error) {
+	description := "Zapier: " + req.Trigger
+	return st.createWebhook(ctx, userID, secret, WebhookFormatZapier, CreateWebhookRequest{
+		Description: &description,
+		URL:         req.HookURL,
+		Events:      []string{ZapierTriggerEvents[req.Trigger]},
+	})
+}
+
+// ZapierTriggerForEvent returns the Zapier trigger receiving eventType, or "" if none does
+func ZapierTriggerForEvent(eventType string) string {
+	for trigger, event := range ZapierTriggerEvents {
+		if event == eventType {
+			return trigger
+		}
+	}
+	return ""
+}
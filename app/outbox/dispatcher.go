@@ -0,0 +1,180 @@
+// Package outbox delivers domain events recorded in the outbox table to registered sinks.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+const (
+	defaultBatchSize = 50
+	defaultInterval  = 2 * time.Second
+
+	// maxAttempts is the number of delivery attempts before an event is parked
+	maxAttempts = 10
+
+	baseRetryDelay = 5 * time.Second
+	maxRetryDelay  = time.Hour
+
+	// claimLease is how long a claimed batch is kept from other dispatchers while its
+	// events are delivered; events whose outcome isn't recorded by then are retried
+	claimLease = 5 * time.Minute
+)
+
+// Sink receives domain events from the dispatcher (webhooks, brokers, etc.)
+type Sink interface {
+	Name() string
+	Deliver(ctx context.Context, evt models.OutboxEvent) error
+}
+
+// Dispatcher polls the outbox and delivers pending events to every sink.
+// Delivery is at-least-once: each sink that takes an event is recorded, and a failure
+// retries the event only for the sinks that haven't. A crash mid-batch redelivers once
+// the batch's lease runs out.
+type Dispatcher struct {
+	store     *models.Store
+	sinks     []Sink
+	mu        sync.RWMutex
+	batchSize int
+	interval  time.Duration
+}
+
+// NewDispatcher creates a dispatcher delivering the events in store's outbox to the given sinks
+func NewDispatcher(store *models.Store, sinks ...Sink) *Dispatcher {
+	return &Dispatcher{
+		store:     store,
+		sinks:     sinks,
+		batchSize: defaultBatchSize,
+		interval:  defaultInterval,
+	}
+}
+
+// Register adds a sink to the dispatcher
+func (d *Dispatcher) Register(sink Sink) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sinks = append(d.sinks, sink)
+}
+
+// Run polls the outbox until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Drain full batches before waiting for the next tick
+			for {
+				n, err := d.DispatchPending(ctx)
+				if err != nil {
+					log.Printf("Outbox dispatch failed: %v", err)
+					break
+				}
+				if n < d.batchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// DispatchPending delivers one batch of due events and returns how many were processed.
+// The batch is claimed for claimLease and delivered outside any transaction, so a slow
+// sink doesn't hold row locks; each event's outcome is recorded once its sinks are done.
+func (d *Dispatcher) DispatchPending(ctx context.Context) (int, error) {
+	events, err := d.store.ClaimPendingEvents(ctx, d.batchSize, claimLease)
+	if err != nil {
+		return 0, err
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	d.mu.RLock()
+	sinks := append([]Sink(nil), d.sinks...)
+	d.mu.RUnlock()
+
+	ids := make([]int64, len(events))
+	for i, evt := range events {
+		ids[i] = evt.ID
+	}
+	delivered, err := d.store.DeliveredSinks(ctx, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, evt := range events {
+		took, deliverErr := deliver(ctx, sinks, evt, delivered[evt.ID])
+		for _, name := range took {
+			if err := d.store.MarkSinkDelivered(ctx, evt.ID, name); err != nil {
+				return 0, err
+			}
+		}
+		if deliverErr != nil {
+			attempt := evt.Attempts + 1
+			giveUp := attempt >= maxAttempts
+			if giveUp {
+				log.Printf("Outbox event %d (%s) failed permanently after %d attempts: %v", evt.ID, evt.EventType, attempt, deliverErr)
+			}
+			if err := d.store.MarkEventFailed(ctx, evt.ID, deliverErr, retryDelay(attempt), giveUp); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		if err := d.store.MarkEventDelivered(ctx, evt.ID); err != nil {
+			return 0, err
+		}
+	}
+	return len(events), nil
+}
+
+// deliver sends the event to every sink not in done, returning the names of the sinks
+// that took it and the failures of the rest
+func deliver(ctx context.Context, sinks []Sink, evt models.OutboxEvent, done map[string]bool) ([]string, error) {
+	var took []string
+	var errs []error
+	for _, sink := range sinks {
+		if done[sink.Name()] {
+			continue
+		}
+		if err := sink.Deliver(ctx, evt); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sink.Name(), err))
+			continue
+		}
+		took = append(took, sink.Name())
+	}
+	return took, errors.Join(errs...)
+}
+
+// retryDelay returns an exponential backoff for the given attempt number, capped at maxRetryDelay
+func retryDelay(attempt int) time.Duration {
+	delay := baseRetryDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= maxRetryDelay {
+			return maxRetryDelay
+		}
+	}
+	return delay
+}
+
+// LogSink logs every event; useful in development and as a delivery audit trail
+type LogSink struct{}
+
+// Name returns the sink name
+func (LogSink) Name() string { return "log" }
+
+// Deliver logs the event
+func (LogSink) Deliver(_ context.Context, evt models.OutboxEvent) error {
+	log.Printf("Outbox event %d: %s %s/%s", evt.ID, evt.EventType, evt.AggregateType, evt.AggregateID)
+	return nil
+}
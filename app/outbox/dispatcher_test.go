@@ -0,0 +1,81 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+type fakeSink struct {
+	err       error
+	name      string
+	delivered []int64
+}
+
+func (f *fakeSink) Name() string { return f.name }
+
+func (f *fakeSink) Deliver(_ context.Context, evt models.OutboxEvent) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.delivered = append(f.delivered, evt.ID)
+	return nil
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempt  int
+		expected time.Duration
+	}{
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{3, 20 * time.Second},
+		{8, 640 * time.Second},
+		{10, 2560 * time.Second},
+		{11, maxRetryDelay},
+		{50, maxRetryDelay},
+	}
+
+	for _, tt := range tests {
+		if got := retryDelay(tt.attempt); got != tt.expected {
+			t.Errorf("retryDelay(%d) = %v, want %v", tt.attempt, got, tt.expected)
+		}
+	}
+}
+
+func TestDeliverRetriesOnlyFailedSinks(t *testing.T) {
+	ok := &fakeSink{name: "ok"}
+	failing := &fakeSink{name: "failing", err: errors.New("boom")}
+	after := &fakeSink{name: "after"}
+	sinks := []Sink{ok, failing, after}
+
+	took, err := deliver(context.Background(), sinks, models.OutboxEvent{ID: 7}, nil)
+	if err == nil || err.Error() != "failing: boom" {
+		t.Fatalf("error = %v, want the failing sink's error with its name", err)
+	}
+	if len(took) != 2 || took[0] != "ok" || took[1] != "after" {
+		t.Errorf("took = %v, want the sinks that didn't fail", took)
+	}
+
+	// The retry skips the sinks that took the event
+	failing.err = nil
+	took, err = deliver(context.Background(), sinks, models.OutboxEvent{ID: 7}, map[string]bool{"ok": true, "after": true})
+	if err != nil || len(took) != 1 || took[0] != "failing" {
+		t.Errorf("retry took %v with %v, want only the failed sink", took, err)
+	}
+	if len(ok.delivered) != 1 || len(failing.delivered) != 1 || len(after.delivered) != 1 {
+		t.Errorf("deliveries: ok=%v failing=%v after=%v, want one each", ok.delivered, failing.delivered, after.delivered)
+	}
+}
+
+func TestRegisterSink(t *testing.T) {
+	d := NewDispatcher(nil, LogSink{})
+	d.Register(&fakeSink{name: "extra"})
+
+	if len(d.sinks) != 2 {
+		t.Errorf("expected 2 sinks, got %d", len(d.sinks))
+	}
+}
@@ -0,0 +1,202 @@
+// Package placeholder finds the typed placeholders of snippet templates, such as
+// {{name}}, {{count:number}} or {{date}}, so clients can prompt for their values before
+// expanding a snippet.
+package placeholder
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxPlaceholders caps how many distinct placeholders a snippet can have
+const MaxPlaceholders = 20
+
+// Placeholder types
+const (
+	TypeString   = "string"
+	TypeNumber   = "number"
+	TypeBoolean  = "boolean"
+	TypeDate     = "date"
+	TypeTime     = "time"
+	TypeDateTime = "datetime"
+)
+
+// types lists the placeholder types, in the order error messages name them
+var types = []string{TypeString, TypeNumber, TypeBoolean, TypeDate, TypeTime, TypeDateTime}
+
+// ErrInvalid is wrapped by the errors of Parse
+var ErrInvalid = errors.New("invalid placeholder")
+
+// ErrValue is wrapped by the errors of Render
+var ErrValue = errors.New("invalid placeholder values")
+
+// builtins are placeholders clients fill in themselves when expanding, with the current
+// date or time or the clipboard's content, so Render leaves them in place
+var builtins = []string{TypeDate, TypeTime, TypeDateTime, "clipboard"}
+
+// Layouts of the date and time values Render accepts
+const (
+	DateLayout = "2006-01-02"
+	TimeLayout = "15:04"
+)
+
+// pattern matches {{name}} and {{name:type}}, allowing spaces inside the braces. Other
+// uses of double braces, like {{#each items}} in a Handlebars snippet, aren't
+// placeholders and are left alone.
+var pattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*(?::\s*([A-Za-z]+)\s*)?\}\}`)
+
+// Placeholder is a value a client prompts for before expanding a snippet
+type Placeholder struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Parse returns the placeholders of content in order of first appearance, each name
+// once. A placeholder without a type is a string, unless it's named after a date or time
+// type: {{date}} is a date. Parse fails with an error wrapping ErrInvalid on an unknown
+// type, a name used with two types or more than MaxPlaceholders names; the placeholders
+// found up to the error are returned along with it.
+func Parse(content string) ([]Placeholder, error) {
+	placeholders := make([]Placeholder, 0)
+	seen := make(map[string]string)
+	for _, match := range pattern.FindAllStringSubmatch(content, -1) {
+		name, typ := match[1], strings.ToLower(match[2])
+		if typ == "" {
+			typ = defaultType(name)
+		}
+		if !slices.Contains(types, typ) {
+			return placeholders, fmt.Errorf("%w %s: type must be one of %s", ErrInvalid, match[0], strings.Join(types, ", "))
+		}
+		if previous, ok := seen[name]; ok {
+			if previous != typ {
+				return placeholders, fmt.Errorf("%w %s: %s is already a %s", ErrInvalid, match[0], name, previous)
+			}
+			continue
+		}
+		if len(placeholders) == MaxPlaceholders {
+			return placeholders, fmt.Errorf("%w: a snippet can have at most %d placeholders", ErrInvalid, MaxPlaceholders)
+		}
+		seen[name] = typ
+		placeholders = append(placeholders, Placeholder{Name: name, Type: typ})
+	}
+	return placeholders, nil
+}
+
+// defaultType is the type of an untyped placeholder
+func defaultType(name string) string {
+	switch lower := strings.ToLower(name); lower {
+	case TypeDate, TypeTime, TypeDateTime:
+		return lower
+	}
+	return TypeString
+}
+
+// IsBuiltin reports whether name is a built-in placeholder, which clients fill in
+// themselves: {{date}}, {{time}}, {{datetime}} or {{clipboard}}, in any case
+func IsBuiltin(name string) bool {
+	return slices.Contains(builtins, strings.ToLower(name))
+}
+
+// ReplaceFunc replaces each placeholder of content with fn's result for it, such as a
+// tab stop in another editor's snippet syntax. Matches of an unknown type are left as
+// they are.
+func ReplaceFunc(content string, fn func(p Placeholder) string) string {
+	return pattern.ReplaceAllStringFunc(content, func(match string) string {
+		groups := pattern.FindStringSubmatch(match)
+		name, typ := groups[1], strings.ToLower(groups[2])
+		if typ == "" {
+			typ = defaultType(name)
+		}
+		if !slices.Contains(types, typ) {
+			return match
+		}
+		return fn(Placeholder{Name: name, Type: typ})
+	})
+}
+
+// Render replaces the placeholders of content with values, keyed by placeholder name.
+// Values may be strings, numbers or booleans, and must suit the placeholder's type:
+// numbers, true or false, dates as 2006-01-02, times as 15:04 and date-times as RFC 3339.
+// Built-in placeholders ({{date}}, {{time}}, {{datetime}} and {{clipboard}}) and ones of
+// an unknown type are left as they are, and values no placeholder uses are ignored.
+// Render fails with an error wrapping ErrValue naming every placeholder whose value is
+// missing or invalid.
+func Render(content string, values map[string]interface{}) (string, error) {
+	var missing, invalid []string
+	failed := make(map[string]bool)
+	rendered := pattern.ReplaceAllStringFunc(content, func(match string) string {
+		groups := pattern.FindStringSubmatch(match)
+		name, typ := groups[1], strings.ToLower(groups[2])
+		if typ == "" {
+			typ = defaultType(name)
+		}
+		if IsBuiltin(name) || !slices.Contains(types, typ) {
+			return match
+		}
+		raw, ok := values[name]
+		if !ok {
+			if !failed[name] {
+				failed[name] = true
+				missing = append(missing, name)
+			}
+			return match
+		}
+		value, ok := formatValue(raw, typ)
+		if !ok {
+			if !failed[name] {
+				failed[name] = true
+				invalid = append(invalid, name+" (a "+typ+")")
+			}
+			return match
+		}
+		return value
+	})
+
+	var problems []string
+	if len(missing) > 0 {
+		problems = append(problems, "missing "+strings.Join(missing, ", "))
+	}
+	if len(invalid) > 0 {
+		problems = append(problems, "invalid "+strings.Join(invalid, ", "))
+	}
+	if len(problems) > 0 {
+		return "", fmt.Errorf("%w: %s", ErrValue, strings.Join(problems, "; "))
+	}
+	return rendered, nil
+}
+
+// formatValue returns raw as the text of a placeholder of type typ, reporting whether
+// it's a valid value of the type
+func formatValue(raw interface{}, typ string) (string, bool) {
+	var value string
+	switch v := raw.(type) {
+	case string:
+		value = v
+	case float64:
+		value = strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		value = strconv.FormatBool(v)
+	default:
+		return "", false
+	}
+
+	var err error
+	switch typ {
+	case TypeNumber:
+		_, err = strconv.ParseFloat(value, 64)
+	case TypeBoolean:
+		_, err = strconv.ParseBool(value)
+	case TypeDate:
+		_, err = time.Parse(DateLayout, value)
+	case TypeTime:
+		_, err = time.Parse(TimeLayout, value)
+	case TypeDateTime:
+		_, err = time.Parse(time.RFC3339, value)
+	}
+	return value, err == nil
+}
@@ -0,0 +1,110 @@
+package placeholder
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Placeholder
+		wantErr bool
+	}{
+		{name: "No placeholders", content: "plain text", want: []Placeholder{}},
+		{
+			name:    "Typed and untyped",
+			content: "Hi {{name}}, you owe {{ amount : Number }} by {{date}}. Thanks, {{name}}",
+			want: []Placeholder{
+				{Name: "name", Type: TypeString},
+				{Name: "amount", Type: TypeNumber},
+				{Name: "date", Type: TypeDate},
+			},
+		},
+		{name: "Template syntax is left alone", content: "{{#each items}}{{/each}} {{ .Name }}", want: []Placeholder{}},
+		{name: "Unknown type", content: "{{due:dat}}", wantErr: true},
+		{name: "Conflicting types", content: "{{n:number}} {{n}}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.content)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalid) {
+					t.Errorf("Parse(%q) error = %v, want ErrInvalid", tt.content, err)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, %v; want %+v", tt.content, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; i <= MaxPlaceholders; i++ {
+		sb.WriteString("{{p" + strings.Repeat("x", i) + "}}")
+	}
+	got, err := Parse(sb.String())
+	if !errors.Is(err, ErrInvalid) || len(got) != MaxPlaceholders {
+		t.Errorf("Parse with %d placeholders = %d, %v; want %d and ErrInvalid", MaxPlaceholders+1, len(got), err, MaxPlaceholders)
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		values  map[string]interface{}
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "Typed values",
+			content: "Hi {{name}}, you owe {{ amount:number }} by {{due:date}}. Paid: {{paid:boolean}}",
+			values:  map[string]interface{}{"name": "Ada", "amount": 12.5, "due": "2026-01-31", "paid": false, "unused": "x"},
+			want:    "Hi Ada, you owe 12.5 by 2026-01-31. Paid: false",
+		},
+		{
+			name:    "Built-ins are left to the client",
+			content: "{{date}} {{ clipboard }} {{time}} {{who}}",
+			values:  map[string]interface{}{"who": "me", "date": "2026-01-31"},
+			want:    "{{date}} {{ clipboard }} {{time}} me",
+		},
+		{name: "Template syntax is left alone", content: "{{#each items}}{{/each}}", want: "{{#each items}}{{/each}}"},
+		{name: "Missing value", content: "{{name}}", wantErr: true},
+		{name: "Invalid number", content: "{{n:number}}", values: map[string]interface{}{"n": "many"}, wantErr: true},
+		{name: "Invalid time", content: "{{at:time}}", values: map[string]interface{}{"at": "noon"}, wantErr: true},
+		{name: "Object value", content: "{{name}}", values: map[string]interface{}{"name": map[string]interface{}{}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.content, tt.values)
+			if tt.wantErr {
+				if !errors.Is(err, ErrValue) {
+					t.Errorf("Render(%q) error = %v, want ErrValue", tt.content, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Render(%q) = %q, %v; want %q", tt.content, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplaceFunc(t *testing.T) {
+	got := ReplaceFunc("{{name}} {{ due:date }} {{x:color}} {{#each}}", func(p Placeholder) string {
+		return "<" + p.Name + ":" + p.Type + ">"
+	})
+	if want := "<name:string> <due:date> {{x:color}} {{#each}}"; got != want {
+		t.Errorf("ReplaceFunc = %q, want %q", got, want)
+	}
+	if !IsBuiltin("Clipboard") || IsBuiltin("name") {
+		t.Error("IsBuiltin should match the built-in names in any case, and only them")
+	}
+}
@@ -0,0 +1,151 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+const (
+	apnsProductionEndpoint = "https://api.push.apple.com"
+	apnsSandboxEndpoint    = "https://api.sandbox.push.apple.com"
+
+	// APNs provider tokens must be refreshed at most once every 20 minutes and at least hourly
+	apnsTokenLifetime = 50 * time.Minute
+)
+
+// APNs sends background pushes through Apple's token-based HTTP/2 API
+type APNs struct {
+	key      *ecdsa.PrivateKey
+	keyID    string
+	teamID   string
+	topic    string
+	endpoint string
+
+	mu          sync.Mutex
+	bearer      string
+	bearerIssue time.Time
+}
+
+// NewAPNsFromEnv reads APNS_KEY_FILE (.p8 auth key), APNS_KEY_ID, APNS_TEAM_ID and
+// APNS_TOPIC (the app bundle ID). APNS_ENVIRONMENT=sandbox targets the development
+// gateway. Returns nil if APNs isn't configured.
+func NewAPNsFromEnv() (*APNs, error) {
+	path := os.Getenv("APNS_KEY_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read APNs key: %w", err)
+	}
+	return NewAPNs(data, os.Getenv("APNS_KEY_ID"), os.Getenv("APNS_TEAM_ID"), os.Getenv("APNS_TOPIC"),
+		os.Getenv("APNS_ENVIRONMENT") == "sandbox")
+}
+
+// NewAPNs creates an APNs provider from a PKCS#8 auth key
+func NewAPNs(keyPEM []byte, keyID, teamID, topic string, sandbox bool) (*APNs, error) {
+	if keyID == "" || teamID == "" || topic == "" {
+		return nil, fmt.Errorf("APNs requires a key ID, team ID and topic")
+	}
+	key, err := jwt.ParseECPrivateKeyFromPEM(keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("parse APNs key: %w", err)
+	}
+	endpoint := apnsProductionEndpoint
+	if sandbox {
+		endpoint = apnsSandboxEndpoint
+	}
+	return &APNs{key: key, keyID: keyID, teamID: teamID, topic: topic, endpoint: endpoint}, nil
+}
+
+// Platform returns the platform name
+func (a *APNs) Platform() string { return models.PlatformAPNs }
+
+// Push sends a content-available background notification
+func (a *APNs) Push(ctx context.Context, token string, n Notification) error {
+	bearer, err := a.providerToken()
+	if err != nil {
+		return err
+	}
+
+	payload := map[string]interface{}{
+		"aps": map[string]interface{}{"content-available": 1},
+	}
+	for k, v := range n.Data {
+		payload[k] = v
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint+"/3/device/"+url.PathEscape(token), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "bearer "+bearer)
+	req.Header.Set("apns-topic", a.topic)
+	req.Header.Set("apns-push-type", "background")
+	// Background pushes must use priority 5
+	req.Header.Set("apns-priority", "5")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var reason struct {
+		Reason string `json:"reason"`
+	}
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	_ = json.Unmarshal(respBody, &reason)
+
+	if resp.StatusCode == http.StatusGone || reason.Reason == "BadDeviceToken" || reason.Reason == "DeviceTokenNotForTopic" {
+		return ErrInvalidToken
+	}
+	return fmt.Errorf("apns: status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+}
+
+// providerToken returns a cached ES256 provider token, signing a new one when it gets old
+func (a *APNs) providerToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.bearer != "" && time.Since(a.bearerIssue) < apnsTokenLifetime {
+		return a.bearer, nil
+	}
+
+	now := time.Now()
+	t := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": a.teamID,
+		"iat": now.Unix(),
+	})
+	t.Header["kid"] = a.keyID
+	signed, err := t.SignedString(a.key)
+	if err != nil {
+		return "", err
+	}
+
+	a.bearer = signed
+	a.bearerIssue = now
+	return a.bearer, nil
+}
@@ -0,0 +1,179 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+const (
+	fcmEndpoint = "https://fcm.googleapis.com"
+	fcmScope    = "https://www.googleapis.com/auth/firebase.messaging"
+)
+
+// fcmCredentials is the subset of a Google service account key file used for FCM
+type fcmCredentials struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// FCM sends data messages through the Firebase Cloud Messaging HTTP v1 API
+type FCM struct {
+	creds    fcmCredentials
+	endpoint string
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewFCMFromEnv reads the service account key from FCM_CREDENTIALS_FILE.
+// FCM_PROJECT_ID overrides the key's project. Returns nil if FCM isn't configured.
+func NewFCMFromEnv() (*FCM, error) {
+	path := os.Getenv("FCM_CREDENTIALS_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read FCM credentials: %w", err)
+	}
+	return NewFCM(data, os.Getenv("FCM_PROJECT_ID"))
+}
+
+// NewFCM creates an FCM provider from a service account key
+func NewFCM(credentialsJSON []byte, projectID string) (*FCM, error) {
+	var creds fcmCredentials
+	if err := json.Unmarshal(credentialsJSON, &creds); err != nil {
+		return nil, fmt.Errorf("parse FCM credentials: %w", err)
+	}
+	if projectID != "" {
+		creds.ProjectID = projectID
+	}
+	if creds.ProjectID == "" || creds.ClientEmail == "" || creds.PrivateKey == "" || creds.TokenURI == "" {
+		return nil, fmt.Errorf("FCM credentials must include project_id, client_email, private_key and token_uri")
+	}
+	if _, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(creds.PrivateKey)); err != nil {
+		return nil, fmt.Errorf("parse FCM private key: %w", err)
+	}
+	return &FCM{creds: creds, endpoint: fcmEndpoint}, nil
+}
+
+// Platform returns the platform name
+func (f *FCM) Platform() string { return models.PlatformFCM }
+
+// Push sends a data-only message so the app syncs in the background
+func (f *FCM) Push(ctx context.Context, token string, n Notification) error {
+	accessToken, err := f.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"message": map[string]interface{}{
+			"token":   token,
+			"data":    n.Data,
+			"android": map[string]string{"priority": "normal"},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/projects/%s/messages:send", f.endpoint, url.PathEscape(f.creds.ProjectID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	// UNREGISTERED (404) and INVALID_ARGUMENT (400) mean the token should be dropped
+	if resp.StatusCode == http.StatusNotFound ||
+		(resp.StatusCode == http.StatusBadRequest && strings.Contains(string(respBody), "INVALID_ARGUMENT")) {
+		return ErrInvalidToken
+	}
+	return fmt.Errorf("fcm: status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+}
+
+// token returns a cached OAuth access token, exchanging a signed JWT for a new one when needed
+func (f *FCM) token(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.accessToken != "" && time.Now().Before(f.expiresAt.Add(-time.Minute)) {
+		return f.accessToken, nil
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(f.creds.PrivateKey))
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   f.creds.ClientEmail,
+		"scope": fcmScope,
+		"aud":   f.creds.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(key)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.creds.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("fcm oauth: status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+
+	f.accessToken = result.AccessToken
+	f.expiresAt = now.Add(time.Duration(result.ExpiresIn) * time.Second)
+	return f.accessToken, nil
+}
@@ -0,0 +1,149 @@
+// Package push sends mobile push notifications through FCM and APNs when a
+// user's snippets change, so other devices sync without polling.
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+// ErrInvalidToken is returned by providers when the device token is no longer valid
+var ErrInvalidToken = errors.New("push: invalid device token")
+
+// requestTimeout bounds each call to a push provider
+const requestTimeout = 10 * time.Second
+
+// Notification is a silent push telling a device something changed
+type Notification struct {
+	Data  map[string]string
+	Event string
+}
+
+// Provider delivers notifications for one platform
+type Provider interface {
+	Platform() string
+	Push(ctx context.Context, token string, n Notification) error
+}
+
+// Sink is an outbox sink that pushes snippet changes to the owner's other devices
+type Sink struct {
+	store     *models.Store
+	providers map[string]Provider
+}
+
+// NewSink creates a sink delivering to the devices registered in store through the given
+// providers
+func NewSink(store *models.Store, providers ...Provider) *Sink {
+	s := &Sink{store: store, providers: make(map[string]Provider, len(providers))}
+	for _, p := range providers {
+		s.providers[p.Platform()] = p
+	}
+	return s
+}
+
+// NewSinkFromEnv configures FCM and APNs from the environment. It returns nil when
+// neither provider is configured.
+func NewSinkFromEnv(store *models.Store) (*Sink, error) {
+	providers := make([]Provider, 0, 2)
+
+	fcm, err := NewFCMFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if fcm != nil {
+		providers = append(providers, fcm)
+	}
+
+	apns, err := NewAPNsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if apns != nil {
+		providers = append(providers, apns)
+	}
+
+	if len(providers) == 0 {
+		return nil, nil
+	}
+	return NewSink(store, providers...), nil
+}
+
+// Name returns the sink name
+func (s *Sink) Name() string { return "push" }
+
+// snippetPayload is the part of a snippet event payload the sink needs
+type snippetPayload struct {
+	UserID *string `json:"userId"`
+	ID     int64   `json:"id"`
+}
+
+// Deliver pushes snippet events to the owner's devices other than the one that made the change.
+// Invalid tokens are removed; a provider error fails the event so the outbox retries it.
+func (s *Sink) Deliver(ctx context.Context, evt models.OutboxEvent) error {
+	if evt.AggregateType != models.AggregateSnippet {
+		return nil
+	}
+
+	var payload snippetPayload
+	if err := json.Unmarshal(evt.Payload, &payload); err != nil {
+		return fmt.Errorf("decode %s payload: %w", evt.EventType, err)
+	}
+	if payload.UserID == nil {
+		return nil
+	}
+
+	origin := ""
+	if evt.OriginSessionID != nil {
+		origin = *evt.OriginSessionID
+	}
+
+	targets, err := s.store.GetPushTargets(ctx, *payload.UserID, origin)
+	if err != nil {
+		return err
+	}
+
+	n := Notification{
+		Event: evt.EventType,
+		Data: map[string]string{
+			"event":     evt.EventType,
+			"snippetId": strconv.FormatInt(payload.ID, 10),
+		},
+	}
+
+	var firstErr error
+	for _, target := range targets {
+		provider, ok := s.providers[target.Platform]
+		if !ok {
+			continue
+		}
+
+		pushCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+		err := provider.Push(pushCtx, target.Token, n)
+		cancel()
+
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrInvalidToken):
+			if delErr := s.store.DeleteDeviceTokenByValue(ctx, target.Token); delErr != nil {
+				log.Printf("Failed to remove invalid %s device token %d: %v", target.Platform, target.ID, delErr)
+			}
+		default:
+			log.Printf("Push to %s device %d failed: %v", target.Platform, target.ID, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// httpClient is shared by providers; APNs requires HTTP/2, which net/http negotiates over TLS
+var httpClient = &http.Client{Timeout: requestTimeout}
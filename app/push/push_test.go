@@ -0,0 +1,188 @@
+package push
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+func rsaKeyPEM(t *testing.T) string {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal RSA key: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func ecKeyPEM(t *testing.T) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate EC key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal EC key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func TestFCMPush(t *testing.T) {
+	tokenRequests := 0
+	var sent map[string]interface{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			tokenRequests++
+			if r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || r.FormValue("assertion") == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "access-123", "expires_in": 3600})
+		case "/v1/projects/demo/messages:send":
+			if r.Header.Get("Authorization") != "Bearer access-123" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_ = json.NewDecoder(r.Body).Decode(&sent)
+			if strings.Contains(sent["message"].(map[string]interface{})["token"].(string), "stale") {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"error":{"status":"NOT_FOUND","details":[{"errorCode":"UNREGISTERED"}]}}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"name":"projects/demo/messages/1"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	creds, _ := json.Marshal(map[string]string{
+		"project_id":   "demo",
+		"client_email": "push@demo.iam.gserviceaccount.com",
+		"private_key":  rsaKeyPEM(t),
+		"token_uri":    srv.URL + "/token",
+	})
+	fcm, err := NewFCM(creds, "")
+	if err != nil {
+		t.Fatalf("NewFCM failed: %v", err)
+	}
+	fcm.endpoint = srv.URL
+
+	n := Notification{Event: models.EventSnippetUpdated, Data: map[string]string{"snippetId": "7"}}
+	if err := fcm.Push(context.Background(), "device-1", n); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	data := sent["message"].(map[string]interface{})["data"].(map[string]interface{})
+	if data["snippetId"] != "7" {
+		t.Errorf("Expected snippetId data, got %v", data)
+	}
+
+	if err := fcm.Push(context.Background(), "stale-device", n); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken for unregistered token, got %v", err)
+	}
+	if tokenRequests != 1 {
+		t.Errorf("Expected access token to be cached, got %d token requests", tokenRequests)
+	}
+}
+
+func TestNewFCMRejectsIncompleteCredentials(t *testing.T) {
+	if _, err := NewFCM([]byte(`{"project_id":"demo"}`), ""); err == nil {
+		t.Error("Expected error for incomplete credentials")
+	}
+}
+
+func TestAPNsPush(t *testing.T) {
+	var gotHeaders http.Header
+	var gotBody map[string]interface{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeaders = r.Header.Clone()
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		switch r.URL.Path {
+		case "/3/device/good":
+			w.WriteHeader(http.StatusOK)
+		case "/3/device/gone":
+			w.WriteHeader(http.StatusGone)
+			_, _ = w.Write([]byte(`{"reason":"Unregistered"}`))
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"reason":"ServiceUnavailable"}`))
+		}
+	}))
+	defer srv.Close()
+
+	apns, err := NewAPNs(ecKeyPEM(t), "KEY123", "TEAM456", "app.snippy.ios", true)
+	if err != nil {
+		t.Fatalf("NewAPNs failed: %v", err)
+	}
+	if apns.endpoint != apnsSandboxEndpoint {
+		t.Errorf("Expected sandbox endpoint, got %s", apns.endpoint)
+	}
+	apns.endpoint = srv.URL
+
+	n := Notification{Event: models.EventSnippetCreated, Data: map[string]string{"snippetId": "9"}}
+	if err := apns.Push(context.Background(), "good", n); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	if gotHeaders.Get("apns-topic") != "app.snippy.ios" || gotHeaders.Get("apns-push-type") != "background" {
+		t.Errorf("Unexpected APNs headers: %v", gotHeaders)
+	}
+	if !strings.HasPrefix(gotHeaders.Get("Authorization"), "bearer ") {
+		t.Errorf("Expected bearer provider token, got %q", gotHeaders.Get("Authorization"))
+	}
+	if gotBody["snippetId"] != "9" || gotBody["aps"] == nil {
+		t.Errorf("Unexpected APNs payload: %v", gotBody)
+	}
+
+	if err := apns.Push(context.Background(), "gone", n); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken for 410, got %v", err)
+	}
+	if err := apns.Push(context.Background(), "busy", n); err == nil || errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected transient error for 503, got %v", err)
+	}
+}
+
+func TestNewAPNsRequiresIdentifiers(t *testing.T) {
+	if _, err := NewAPNs(ecKeyPEM(t), "", "TEAM", "topic", false); err == nil {
+		t.Error("Expected error without key ID")
+	}
+}
+
+func TestSinkIgnoresNonSnippetEvents(t *testing.T) {
+	sink := NewSink(nil)
+	evt := models.OutboxEvent{EventType: models.EventUserDeleted, AggregateType: models.AggregateUser, Payload: []byte(`{}`)}
+	if err := sink.Deliver(context.Background(), evt); err != nil {
+		t.Errorf("Expected non-snippet events to be skipped, got %v", err)
+	}
+	if sink.Name() != "push" {
+		t.Errorf("Expected sink name push, got %s", sink.Name())
+	}
+}
+
+func TestNewSinkFromEnvUnconfigured(t *testing.T) {
+	t.Setenv("FCM_CREDENTIALS_FILE", "")
+	t.Setenv("APNS_KEY_FILE", "")
+	sink, err := NewSinkFromEnv(nil)
+	if err != nil || sink != nil {
+		t.Errorf("Expected no sink without configuration, got %v, %v", sink, err)
+	}
+}
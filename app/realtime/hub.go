@@ -0,0 +1,153 @@
+// Package realtime fans snippet changes out to the clients connected to this instance:
+// gRPC Watch streams and the HTTP snippet event stream.
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+// subscriberBuffer is how many changes may queue for a slow subscriber before its
+// stream is ended (the client reconnects and replays from its cursor)
+const subscriberBuffer = 64
+
+// Change is a snippet change fanned out to subscribers
+type Change struct {
+	ChangedAt       time.Time
+	Snippet         models.Snippet
+	EventType       string
+	OriginSessionID string
+}
+
+type subscriber struct {
+	ch        chan Change
+	sessionID string
+}
+
+// Hub fans snippet events out to the owner's clients connected to this instance. A
+// Listener feeds it the events of every instance. Delivery is best effort: streams are
+// ended when changes may have been missed, so clients should sync after reconnecting.
+type Hub struct {
+	subscribers map[string]map[*subscriber]struct{}
+	mu          sync.Mutex
+	closed      bool
+}
+
+// NewHub creates an empty hub
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[string]map[*subscriber]struct{})}
+}
+
+// Deliver forwards snippet events to the owner's subscribers, except the session that
+// made the change. It never fails: watchers are best effort.
+func (h *Hub) Deliver(_ context.Context, evt models.OutboxEvent) error {
+	if evt.AggregateType != models.AggregateSnippet {
+		return nil
+	}
+
+	var snippet models.Snippet
+	if err := json.Unmarshal(evt.Payload, &snippet); err != nil {
+		log.Printf("realtime: decode %s payload: %v", evt.EventType, err)
+		return nil
+	}
+	if snippet.UserID == nil {
+		return nil
+	}
+
+	change := Change{Snippet: snippet, EventType: evt.EventType, ChangedAt: evt.CreatedAt}
+	if evt.OriginSessionID != nil {
+		change.OriginSessionID = *evt.OriginSessionID
+	}
+	h.publish(*snippet.UserID, change)
+	return nil
+}
+
+// publish sends a change to a user's subscribers without blocking. A subscriber whose
+// buffer is full would miss the change, so its stream is ended instead.
+func (h *Hub) publish(userID string, change Change) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subs := h.subscribers[userID]
+	for sub := range subs {
+		if change.OriginSessionID != "" && change.OriginSessionID == sub.sessionID {
+			continue
+		}
+		select {
+		case sub.ch <- change:
+		default:
+			log.Printf("realtime: ending stream of slow subscriber of user %s at %s", userID, change.EventType)
+			close(sub.ch)
+			delete(subs, sub)
+		}
+	}
+	if len(subs) == 0 {
+		delete(h.subscribers, userID)
+	}
+}
+
+// Subscribe registers a client of userID connected as sessionID. The channel is closed
+// when the hub is, or when the client falls too far behind; call the returned function
+// to unsubscribe.
+func (h *Hub) Subscribe(userID, sessionID string) (<-chan Change, func()) {
+	sub := &subscriber{ch: make(chan Change, subscriberBuffer), sessionID: sessionID}
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		close(sub.ch)
+		return sub.ch, func() {}
+	}
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[*subscriber]struct{})
+	}
+	h.subscribers[userID][sub] = struct{}{}
+	h.mu.Unlock()
+
+	return sub.ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		// The hub may already have removed a slow or disconnected subscriber
+		subs, ok := h.subscribers[userID]
+		if !ok {
+			return
+		}
+		delete(subs, sub)
+		if len(subs) == 0 {
+			delete(h.subscribers, userID)
+		}
+	}
+}
+
+// Disconnect closes every subscriber's channel, ending their streams so the clients
+// reconnect and replay the changes they may have missed. Later subscriptions work as usual.
+func (h *Hub) Disconnect() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.disconnect()
+}
+
+// Close closes every subscriber's channel, ending their streams so the server can shut
+// down, and makes later subscriptions closed from the start
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	h.disconnect()
+}
+
+// disconnect closes and forgets every subscriber; h.mu must be held
+func (h *Hub) disconnect() {
+	for userID, subs := range h.subscribers {
+		for sub := range subs {
+			close(sub.ch)
+		}
+		delete(h.subscribers, userID)
+	}
+}
@@ -0,0 +1,142 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+func snippetEvent(t *testing.T, eventType, userID, originSessionID string) models.OutboxEvent {
+	t.Helper()
+	payload, err := json.Marshal(models.Snippet{ID: 7, Label: "Greeting", UserID: &userID})
+	if err != nil {
+		t.Fatalf("marshal payload: %v", err)
+	}
+	evt := models.OutboxEvent{EventType: eventType, AggregateType: models.AggregateSnippet, Payload: payload, CreatedAt: time.Now()}
+	if originSessionID != "" {
+		evt.OriginSessionID = &originSessionID
+	}
+	return evt
+}
+
+func TestHubDeliver(t *testing.T) {
+	hub := NewHub()
+	laptop, unsubscribeLaptop := hub.Subscribe("user-1", "laptop")
+	defer unsubscribeLaptop()
+	phone, unsubscribePhone := hub.Subscribe("user-1", "phone")
+	defer unsubscribePhone()
+	other, unsubscribeOther := hub.Subscribe("user-2", "")
+	defer unsubscribeOther()
+
+	if err := hub.Deliver(context.Background(), snippetEvent(t, models.EventSnippetUpdated, "user-1", "laptop")); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+
+	select {
+	case change := <-phone:
+		if change.Snippet.ID != 7 || change.EventType != models.EventSnippetUpdated {
+			t.Errorf("unexpected change %+v", change)
+		}
+	default:
+		t.Fatal("phone did not receive the change")
+	}
+	if len(laptop) != 0 {
+		t.Error("originating session should be skipped")
+	}
+	if len(other) != 0 {
+		t.Error("other users should not receive the change")
+	}
+
+	// Unsubscribed watchers are no longer tracked
+	unsubscribePhone()
+	unsubscribeLaptop()
+	if _, ok := hub.subscribers["user-1"]; ok {
+		t.Error("expected user-1 to be removed after unsubscribing")
+	}
+}
+
+func TestHubDeliverEndsSlowSubscriber(t *testing.T) {
+	hub := NewHub()
+	slow, unsubscribeSlow := hub.Subscribe("user-1", "laptop")
+	defer unsubscribeSlow()
+	fast, unsubscribeFast := hub.Subscribe("user-1", "phone")
+	defer unsubscribeFast()
+
+	received := 0
+	for i := 0; i < subscriberBuffer+5; i++ {
+		if err := hub.Deliver(context.Background(), snippetEvent(t, models.EventSnippetCreated, "user-1", "")); err != nil {
+			t.Fatalf("Deliver: %v", err)
+		}
+		select {
+		case <-fast:
+			received++
+		default:
+			t.Fatalf("fast subscriber missed change %d", i)
+		}
+	}
+	if received != subscriberBuffer+5 {
+		t.Errorf("fast subscriber got %d changes, want %d", received, subscriberBuffer+5)
+	}
+
+	// The slow subscriber keeps what was buffered, then its channel is closed
+	buffered := 0
+	for range slow {
+		buffered++
+	}
+	if buffered != subscriberBuffer {
+		t.Errorf("slow subscriber drained %d changes, want %d", buffered, subscriberBuffer)
+	}
+	if len(hub.subscribers["user-1"]) != 1 {
+		t.Errorf("expected only the fast subscriber to remain, got %d", len(hub.subscribers["user-1"]))
+	}
+
+	// Unsubscribing an already removed subscriber is harmless
+	unsubscribeSlow()
+	unsubscribeFast()
+	if _, ok := hub.subscribers["user-1"]; ok {
+		t.Error("expected user-1 to be removed after unsubscribing")
+	}
+}
+
+func TestHubClose(t *testing.T) {
+	hub := NewHub()
+	changes, unsubscribe := hub.Subscribe("user-1", "")
+	defer unsubscribe()
+
+	hub.Close()
+	if _, ok := <-changes; ok {
+		t.Error("expected the channel to be closed")
+	}
+	late, unsubscribeLate := hub.Subscribe("user-1", "")
+	defer unsubscribeLate()
+	if _, ok := <-late; ok {
+		t.Error("expected subscriptions after Close to be closed")
+	}
+	if err := hub.Deliver(context.Background(), snippetEvent(t, models.EventSnippetUpdated, "user-1", "")); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+}
+
+func TestHubDisconnect(t *testing.T) {
+	hub := NewHub()
+	changes, unsubscribe := hub.Subscribe("user-1", "")
+	defer unsubscribe()
+
+	hub.Disconnect()
+	if _, ok := <-changes; ok {
+		t.Error("expected the channel to be closed")
+	}
+
+	// Clients reconnecting afterwards are streamed to as usual
+	again, unsubscribeAgain := hub.Subscribe("user-1", "")
+	defer unsubscribeAgain()
+	if err := hub.Deliver(context.Background(), snippetEvent(t, models.EventSnippetUpdated, "user-1", "")); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+	if len(again) != 1 {
+		t.Errorf("reconnected subscriber got %d changes, want 1", len(again))
+	}
+}
@@ -0,0 +1,117 @@
+// Package realtime carries snippet events between instances with Postgres LISTEN/NOTIFY.
+package realtime
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"github.com/lib/pq"
+)
+
+const (
+	// notifyChannel is the Postgres channel snippet events are announced on
+	notifyChannel = "snippet_events"
+
+	// listenerMinReconnect and listenerMaxReconnect bound how long a listener waits
+	// before reconnecting after losing its connection
+	listenerMinReconnect = time.Second
+	listenerMaxReconnect = time.Minute
+
+	// listenerPing is how often an idle listener checks its connection is alive
+	listenerPing = 90 * time.Second
+)
+
+// Notifier is an outbox sink announcing snippet events to every instance with Postgres
+// NOTIFY, whichever instance's dispatcher claimed them. Only the event ID is sent, as
+// notification payloads are limited to 8000 bytes; each instance's Listener loads it.
+type Notifier struct {
+	store *models.Store
+}
+
+// NewNotifier creates a notifier announcing on the database of store
+func NewNotifier(store *models.Store) *Notifier {
+	return &Notifier{store: store}
+}
+
+// Name returns the sink name
+func (n *Notifier) Name() string { return "realtime" }
+
+// Deliver announces snippet events; other events aren't streamed
+func (n *Notifier) Deliver(ctx context.Context, evt models.OutboxEvent) error {
+	if evt.AggregateType != models.AggregateSnippet {
+		return nil
+	}
+	_, err := n.store.DB().ExecContext(ctx, `SELECT pg_notify($1, $2)`, notifyChannel, strconv.FormatInt(evt.ID, 10))
+	return err
+}
+
+// Listener feeds a hub with the snippet events every instance's Notifier announces
+type Listener struct {
+	hub  *Hub
+	load func(ctx context.Context, id int64) (*models.OutboxEvent, error)
+}
+
+// NewListener creates a listener delivering to hub the events it loads from store
+func NewListener(store *models.Store, hub *Hub) *Listener {
+	return &Listener{hub: hub, load: store.GetOutboxEvent}
+}
+
+// Run listens for announcements on the database at dbURL until ctx is cancelled.
+// Announcements made while the connection is down are lost, so when it's re-established
+// the hub's streams are ended for clients to reconnect and replay what they missed.
+func (l *Listener) Run(ctx context.Context, dbURL string) {
+	listener := pq.NewListener(dbURL, listenerMinReconnect, listenerMaxReconnect, func(_ pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("realtime: listener connection: %v", err)
+		}
+	})
+	defer func() {
+		if err := listener.Close(); err != nil {
+			log.Printf("realtime: error closing listener: %v", err)
+		}
+	}()
+	if err := listener.Listen(notifyChannel); err != nil {
+		log.Printf("realtime: failed to listen for snippet events: %v", err)
+		return
+	}
+
+	ticker := time.NewTicker(listenerPing)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-listener.Notify:
+			l.handle(ctx, n)
+		case <-ticker.C:
+			// A dead connection is noticed and re-established by the listener
+			go func() { _ = listener.Ping() }()
+		}
+	}
+}
+
+// handle delivers an announced event to the hub. A nil notification means the connection
+// was re-established, so announcements may have been missed; so may an event that can't
+// be loaded. Either way the hub's streams are ended.
+func (l *Listener) handle(ctx context.Context, n *pq.Notification) {
+	if n == nil {
+		log.Printf("realtime: listener reconnected, ending streams so clients catch up")
+		l.hub.Disconnect()
+		return
+	}
+	id, err := strconv.ParseInt(n.Extra, 10, 64)
+	if err != nil {
+		log.Printf("realtime: ignoring invalid announcement %q", n.Extra)
+		return
+	}
+	evt, err := l.load(ctx, id)
+	if err != nil {
+		log.Printf("realtime: failed to load event %d, ending streams so clients catch up: %v", id, err)
+		l.hub.Disconnect()
+		return
+	}
+	_ = l.hub.Deliver(ctx, *evt)
+}
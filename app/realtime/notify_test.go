@@ -0,0 +1,58 @@
+package realtime
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"github.com/lib/pq"
+)
+
+func TestListenerHandle(t *testing.T) {
+	hub := NewHub()
+	events := map[int64]models.OutboxEvent{42: snippetEvent(t, models.EventSnippetUpdated, "user-1", "")}
+	l := &Listener{hub: hub, load: func(_ context.Context, id int64) (*models.OutboxEvent, error) {
+		evt, ok := events[id]
+		if !ok {
+			return nil, sql.ErrNoRows
+		}
+		return &evt, nil
+	}}
+	ctx := context.Background()
+
+	changes, unsubscribe := hub.Subscribe("user-1", "")
+	defer unsubscribe()
+
+	// Announced events are loaded and streamed; invalid announcements are ignored
+	l.handle(ctx, &pq.Notification{Channel: notifyChannel, Extra: "42"})
+	l.handle(ctx, &pq.Notification{Channel: notifyChannel, Extra: "not-an-id"})
+	if len(changes) != 1 {
+		t.Fatalf("got %d changes, want 1", len(changes))
+	}
+	if change := <-changes; change.Snippet.ID != 7 || change.EventType != models.EventSnippetUpdated {
+		t.Errorf("unexpected change %+v", change)
+	}
+
+	// An event that can't be loaded was missed, so the stream ends
+	l.handle(ctx, &pq.Notification{Channel: notifyChannel, Extra: "43"})
+	if _, ok := <-changes; ok {
+		t.Error("expected the stream to end after a failed load")
+	}
+
+	// So does a reconnect, after which announcements may have been missed
+	again, unsubscribeAgain := hub.Subscribe("user-1", "")
+	defer unsubscribeAgain()
+	l.handle(ctx, nil)
+	if _, ok := <-again; ok {
+		t.Error("expected the stream to end after a reconnect")
+	}
+}
+
+func TestNotifierIgnoresOtherAggregates(t *testing.T) {
+	// Non-snippet events are never announced, so no database is needed
+	err := NewNotifier(nil).Deliver(context.Background(), models.OutboxEvent{AggregateType: models.AggregateUser, EventType: models.EventUserDeleted})
+	if err != nil {
+		t.Errorf("Deliver(user event) = %v, want nil", err)
+	}
+}
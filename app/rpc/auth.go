@@ -0,0 +1,131 @@
+package rpc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/jheysaaz/snippy-backend/app/auth"
+	"github.com/jheysaaz/snippy-backend/app/middleware"
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type contextKey int
+
+const (
+	userIDKey contextKey = iota
+	sessionIDKey
+)
+
+// authenticator checks calls the way auth.Middleware and auth.RequireActiveSession
+// check HTTP requests
+type authenticator struct {
+	// tenants resolves the organization a call is made to
+	tenants *middleware.TenantResolver
+	// sessionActive reports whether a login session is still active
+	sessionActive func(ctx context.Context, sessionID, userID string) (bool, error)
+}
+
+// newAuthenticator creates an authenticator resolving organizations with tenants and
+// looking sessions up in store
+func newAuthenticator(store *models.Store, tenants *middleware.TenantResolver) *authenticator {
+	return &authenticator{tenants: tenants, sessionActive: store.IsSessionActive}
+}
+
+// authenticate validates the bearer token in the call metadata and stores the user and
+// the token's session in the returned context. The token must belong to the organization
+// named by the x-organization metadata or the authority, and its session must be active.
+func (a *authenticator) authenticate(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "authorization metadata required")
+	}
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok || token == "" {
+		return nil, status.Error(codes.Unauthenticated, "invalid authorization metadata format")
+	}
+
+	orgID, err := a.tenants.Resolve(ctx, firstValue(md, "x-organization"), firstValue(md, ":authority"))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Error(codes.NotFound, "organization not found")
+	}
+	if err != nil {
+		log.Printf("grpc: failed to resolve organization: %v", err)
+		return nil, status.Error(codes.Internal, "failed to resolve organization")
+	}
+
+	claims, err := auth.ValidateOrgToken(token, orgID)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired token")
+	}
+	if claims.SessionID == "" {
+		return nil, status.Error(codes.Unauthenticated, "session required, please login again")
+	}
+	active, err := a.sessionActive(ctx, claims.SessionID, claims.UserID)
+	if err != nil {
+		log.Printf("grpc: failed to check session %s: %v", claims.SessionID, err)
+		return nil, status.Error(codes.Internal, "failed to check session")
+	}
+	if !active {
+		return nil, status.Error(codes.Unauthenticated, "session has ended, please login again")
+	}
+	auth.RecordSessionActivity(claims.SessionID)
+
+	ctx = context.WithValue(ctx, userIDKey, claims.UserID)
+	ctx = context.WithValue(ctx, sessionIDKey, claims.SessionID)
+	return ctx, nil
+}
+
+// firstValue returns the first value of the metadata key, or ""
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// userIDFromContext returns the authenticated user ID
+func userIDFromContext(ctx context.Context) string {
+	userID, _ := ctx.Value(userIDKey).(string)
+	return userID
+}
+
+// sessionIDFromContext returns the login session of the caller's token
+func sessionIDFromContext(ctx context.Context) string {
+	sessionID, _ := ctx.Value(sessionIDKey).(string)
+	return sessionID
+}
+
+// unary authenticates unary calls
+func (a *authenticator) unary(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// authenticatedStream carries the authenticated context into stream handlers
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context { return s.ctx }
+
+// stream authenticates streaming calls
+func (a *authenticator) stream(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+}
@@ -0,0 +1,105 @@
+package rpc
+
+import (
+	"time"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"github.com/jheysaaz/snippy-backend/app/realtime"
+	snippyv1 "github.com/jheysaaz/snippy-backend/app/rpc/snippyv1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// toProtoSnippet converts a snippet model to its protobuf message
+func toProtoSnippet(s *models.Snippet) *snippyv1.Snippet {
+	msg := &snippyv1.Snippet{
+		Id:         s.ID,
+		Label:      s.Label,
+		Shortcut:   s.Shortcut,
+		Content:    s.Content,
+		Tags:       s.Tags,
+		Visibility: s.Visibility,
+		CreatedAt:  timestamppb.New(s.CreatedAt),
+		UpdatedAt:  timestamppb.New(s.UpdatedAt),
+	}
+	if s.UserID != nil {
+		msg.UserId = *s.UserID
+	}
+	return msg
+}
+
+// toProtoSnippets converts a slice of snippet models
+func toProtoSnippets(snippets []models.Snippet) []*snippyv1.Snippet {
+	out := make([]*snippyv1.Snippet, 0, len(snippets))
+	for i := range snippets {
+		out = append(out, toProtoSnippet(&snippets[i]))
+	}
+	return out
+}
+
+// toProtoSyncResponse converts sync changes to a SyncResponse stamped with syncedAt
+func toProtoSyncResponse(changes *models.SnippetChanges, syncedAt time.Time) *snippyv1.SyncResponse {
+	resp := &snippyv1.SyncResponse{
+		Created:  toProtoSnippets(changes.Created),
+		Updated:  toProtoSnippets(changes.Updated),
+		Deleted:  make([]*snippyv1.DeletedSnippet, 0, len(changes.Deleted)),
+		SyncedAt: timestamppb.New(syncedAt),
+		Cursor:   changes.Cursor,
+	}
+	for _, d := range changes.Deleted {
+		item := &snippyv1.DeletedSnippet{Id: d.ID}
+		if d.DeletedAt != nil {
+			item.DeletedAt = timestamppb.New(*d.DeletedAt)
+		}
+		resp.Deleted = append(resp.Deleted, item)
+	}
+	return resp
+}
+
+// toWatchResponse converts a hub change to a Watch message. Deletions only carry the ID.
+func toWatchResponse(change realtime.Change) *snippyv1.WatchResponse {
+	resp := &snippyv1.WatchResponse{
+		SnippetId: change.Snippet.ID,
+		ChangedAt: timestamppb.New(change.ChangedAt),
+	}
+	switch change.EventType {
+	case models.EventSnippetCreated:
+		resp.Type = snippyv1.WatchResponse_CHANGE_TYPE_CREATED
+		resp.Snippet = toProtoSnippet(&change.Snippet)
+	case models.EventSnippetUpdated:
+		resp.Type = snippyv1.WatchResponse_CHANGE_TYPE_UPDATED
+		resp.Snippet = toProtoSnippet(&change.Snippet)
+	case models.EventSnippetDeleted:
+		resp.Type = snippyv1.WatchResponse_CHANGE_TYPE_DELETED
+	}
+	return resp
+}
+
+// fromProtoCreate converts a CreateSnippetRequest to the model request
+func fromProtoCreate(req *snippyv1.CreateSnippetRequest) models.CreateSnippetRequest {
+	return models.CreateSnippetRequest{
+		Label:      req.GetLabel(),
+		Shortcut:   req.GetShortcut(),
+		Content:    req.GetContent(),
+		Tags:       req.GetTags(),
+		Visibility: req.GetVisibility(),
+	}
+}
+
+// fromProtoUpdate converts an UpdateSnippetRequest to the model request. Tags are only
+// changed when replace_tags is set, so an empty list can clear them.
+func fromProtoUpdate(req *snippyv1.UpdateSnippetRequest) models.UpdateSnippetRequest {
+	update := models.UpdateSnippetRequest{
+		Label:       req.Label,
+		Shortcut:    req.Shortcut,
+		Content:     req.Content,
+		Visibility:  req.Visibility,
+		ChangeNotes: req.ChangeNotes,
+	}
+	if req.GetReplaceTags() {
+		update.Tags = req.GetTags()
+		if update.Tags == nil {
+			update.Tags = []string{}
+		}
+	}
+	return update
+}
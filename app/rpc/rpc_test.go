@@ -0,0 +1,94 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jheysaaz/snippy-backend/app/auth"
+	"github.com/jheysaaz/snippy-backend/app/middleware"
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"github.com/jheysaaz/snippy-backend/app/realtime"
+	snippyv1 "github.com/jheysaaz/snippy-backend/app/rpc/snippyv1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthenticate(t *testing.T) {
+	user := &models.User{ID: "user-1", Username: "alice"}
+	token := func(user *models.User, sessionID string) string {
+		t.Helper()
+		token, err := auth.GenerateSessionAccessToken(user, nil, sessionID)
+		if err != nil {
+			t.Fatalf("generate token: %v", err)
+		}
+		return token
+	}
+	a := &authenticator{
+		tenants: middleware.NewTenantResolver(nil, ""),
+		sessionActive: func(_ context.Context, sessionID, userID string) (bool, error) {
+			return sessionID == "session-1" && userID == "user-1", nil
+		},
+	}
+
+	// The session comes from the token, not from client-supplied metadata
+	md := metadata.Pairs("authorization", "Bearer "+token(user, "session-1"), "x-session-id", "session-2")
+	ctx, err := a.authenticate(metadata.NewIncomingContext(context.Background(), md))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := userIDFromContext(ctx); got != "user-1" {
+		t.Errorf("user ID = %q, want user-1", got)
+	}
+	if got := sessionIDFromContext(ctx); got != "session-1" {
+		t.Errorf("session ID = %q, want session-1", got)
+	}
+
+	otherOrg := &models.User{ID: "user-1", Username: "alice", OrgID: "7d1f2c3b-4a5e-4f60-8b7a-9c8d7e6f5a4b"}
+	for name, md := range map[string]metadata.MD{
+		"Missing":            {},
+		"Wrong scheme":       metadata.Pairs("authorization", "Basic "+token(user, "session-1")),
+		"Invalid token":      metadata.Pairs("authorization", "Bearer not-a-token"),
+		"Without session":    metadata.Pairs("authorization", "Bearer "+token(user, "")),
+		"Ended session":      metadata.Pairs("authorization", "Bearer "+token(user, "session-2")),
+		"Other organization": metadata.Pairs("authorization", "Bearer "+token(otherOrg, "session-1")),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := a.authenticate(metadata.NewIncomingContext(context.Background(), md))
+			if status.Code(err) != codes.Unauthenticated {
+				t.Errorf("code = %v, want Unauthenticated", status.Code(err))
+			}
+		})
+	}
+}
+
+func TestToWatchResponse(t *testing.T) {
+	userID := "user-1"
+	snippet := models.Snippet{ID: 3, Label: "Sig", Tags: []string{"email"}, UserID: &userID}
+
+	resp := toWatchResponse(realtime.Change{Snippet: snippet, EventType: models.EventSnippetCreated})
+	if resp.GetType() != snippyv1.WatchResponse_CHANGE_TYPE_CREATED || resp.GetSnippet().GetUserId() != userID {
+		t.Errorf("unexpected created response %v", resp)
+	}
+
+	resp = toWatchResponse(realtime.Change{Snippet: snippet, EventType: models.EventSnippetDeleted})
+	if resp.GetType() != snippyv1.WatchResponse_CHANGE_TYPE_DELETED || resp.GetSnippet() != nil || resp.GetSnippetId() != 3 {
+		t.Errorf("unexpected deleted response %v", resp)
+	}
+}
+
+func TestFromProtoUpdate(t *testing.T) {
+	label := "New label"
+	update := fromProtoUpdate(&snippyv1.UpdateSnippetRequest{Id: 1, Label: &label, Tags: []string{"ignored"}})
+	if update.Label == nil || *update.Label != label {
+		t.Errorf("Label = %v, want %q", update.Label, label)
+	}
+	if update.Tags != nil {
+		t.Errorf("Tags = %v, want unchanged without replace_tags", update.Tags)
+	}
+
+	update = fromProtoUpdate(&snippyv1.UpdateSnippetRequest{Id: 1, ReplaceTags: true})
+	if update.Tags == nil || len(update.Tags) != 0 {
+		t.Errorf("Tags = %v, want cleared", update.Tags)
+	}
+}
@@ -0,0 +1,241 @@
+// Package rpc serves the snippet and sync APIs over gRPC for desktop clients and
+// internal services. Calls authenticate with the same access tokens as HTTP, sent as
+// "authorization: Bearer <token>" metadata, and may name an organization with
+// "x-organization" like the X-Organization header. The token's login session must still
+// be active, and identifies the device making changes.
+package rpc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/jheysaaz/snippy-backend/app/middleware"
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"github.com/jheysaaz/snippy-backend/app/placeholder"
+	"github.com/jheysaaz/snippy-backend/app/realtime"
+	snippyv1 "github.com/jheysaaz/snippy-backend/app/rpc/snippyv1"
+	"github.com/jheysaaz/snippy-backend/app/snippetquery"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// defaultListLimit is how many snippets ListSnippets returns without a limit
+const defaultListLimit = 50
+
+// NewServer creates a gRPC server with the snippet and sync services registered, querying
+// store. Watch streams come from hub, which a realtime.Listener must feed, and calls
+// are made to the organizations tenants resolves.
+func NewServer(store *models.Store, hub *realtime.Hub, tenants *middleware.TenantResolver) *grpc.Server {
+	a := newAuthenticator(store, tenants)
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(a.unary),
+		grpc.StreamInterceptor(a.stream),
+	)
+	snippyv1.RegisterSnippetServiceServer(srv, &snippetServer{store: store})
+	snippyv1.RegisterSyncServiceServer(srv, &syncServer{store: store, hub: hub})
+	return srv
+}
+
+// snippetServer implements SnippetService
+type snippetServer struct {
+	snippyv1.UnimplementedSnippetServiceServer
+	store *models.Store
+}
+
+func (s *snippetServer) ListSnippets(ctx context.Context, req *snippyv1.ListSnippetsRequest) (*snippyv1.ListSnippetsResponse, error) {
+	limit := int(req.GetLimit())
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	limit = min(limit, snippetquery.MaxLimit)
+
+	snippets, err := s.store.ListUserSnippets(ctx, userIDFromContext(ctx), snippetquery.Filter{
+		Tag:    req.GetTag(),
+		Search: req.GetSearch(),
+		Limit:  limit,
+	})
+	if err != nil {
+		return nil, snippetError(err, "failed to fetch snippets")
+	}
+	return &snippyv1.ListSnippetsResponse{Snippets: toProtoSnippets(snippets)}, nil
+}
+
+func (s *snippetServer) GetSnippet(ctx context.Context, req *snippyv1.GetSnippetRequest) (*snippyv1.GetSnippetResponse, error) {
+	snippet, err := s.store.GetUserSnippet(ctx, req.GetId(), userIDFromContext(ctx))
+	if err != nil {
+		return nil, snippetError(err, "failed to fetch snippet")
+	}
+	return &snippyv1.GetSnippetResponse{Snippet: toProtoSnippet(snippet)}, nil
+}
+
+func (s *snippetServer) CreateSnippet(ctx context.Context, req *snippyv1.CreateSnippetRequest) (*snippyv1.CreateSnippetResponse, error) {
+	create := fromProtoCreate(req)
+	if err := binding.Validator.ValidateStruct(&create); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	snippet, err := s.store.CreateSnippet(ctx, userIDFromContext(ctx), sessionIDFromContext(ctx), create)
+	if err != nil {
+		return nil, snippetError(err, "failed to create snippet")
+	}
+	return &snippyv1.CreateSnippetResponse{Snippet: toProtoSnippet(snippet)}, nil
+}
+
+func (s *snippetServer) UpdateSnippet(ctx context.Context, req *snippyv1.UpdateSnippetRequest) (*snippyv1.UpdateSnippetResponse, error) {
+	update := fromProtoUpdate(req)
+	if err := binding.Validator.ValidateStruct(&update); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	snippet, err := s.store.UpdateSnippet(ctx, req.GetId(), userIDFromContext(ctx), sessionIDFromContext(ctx), update)
+	if err != nil {
+		return nil, snippetError(err, "failed to update snippet")
+	}
+	return &snippyv1.UpdateSnippetResponse{Snippet: toProtoSnippet(snippet)}, nil
+}
+
+func (s *snippetServer) DeleteSnippet(ctx context.Context, req *snippyv1.DeleteSnippetRequest) (*snippyv1.DeleteSnippetResponse, error) {
+	if err := s.store.DeleteSnippet(ctx, req.GetId(), userIDFromContext(ctx), sessionIDFromContext(ctx)); err != nil {
+		return nil, snippetError(err, "failed to delete snippet")
+	}
+	return &snippyv1.DeleteSnippetResponse{}, nil
+}
+
+// syncServer implements SyncService
+type syncServer struct {
+	snippyv1.UnimplementedSyncServiceServer
+	store *models.Store
+	hub   *realtime.Hub
+}
+
+func (s *syncServer) Sync(ctx context.Context, req *snippyv1.SyncRequest) (*snippyv1.SyncResponse, error) {
+	if req.GetCursor() == "" && req.GetUpdatedSince() == nil {
+		return nil, status.Error(codes.InvalidArgument, "cursor or updated_since is required")
+	}
+	since, err := syncCursor(req.GetCursor(), req.GetUpdatedSince())
+	if err != nil {
+		return nil, err
+	}
+	userID := userIDFromContext(ctx)
+
+	// Taken before the query so changes made while it runs are picked up next time
+	syncedAt := time.Now()
+	changes, err := s.store.GetSnippetChanges(ctx, userID, since)
+	if err != nil {
+		return nil, snippetError(err, "failed to fetch sync data")
+	}
+
+	// Remember which device synced for the weekly digest
+	if sessionID := sessionIDFromContext(ctx); sessionID != "" {
+		if err := s.store.MarkSessionSynced(ctx, sessionID, userID); err != nil {
+			log.Printf("Failed to record sync for session %s: %v", sessionID, err)
+		}
+	}
+
+	return toProtoSyncResponse(changes, syncedAt), nil
+}
+
+func (s *syncServer) Watch(req *snippyv1.WatchRequest, stream snippyv1.SyncService_WatchServer) error {
+	ctx := stream.Context()
+	userID := userIDFromContext(ctx)
+
+	// Subscribe before replaying so nothing committed in between is missed; a change
+	// may then be sent twice, which clients apply idempotently by snippet ID
+	changes, unsubscribe := s.hub.Subscribe(userID, sessionIDFromContext(ctx))
+	defer unsubscribe()
+
+	if req.GetCursor() != "" || req.GetUpdatedSince() != nil {
+		since, err := syncCursor(req.GetCursor(), req.GetUpdatedSince())
+		if err != nil {
+			return err
+		}
+		if err := s.replay(ctx, stream, userID, since); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case change, ok := <-changes:
+			if !ok {
+				// The hub ended the stream: the server is shutting down, or changes may
+				// have been missed and the client should watch again from its cursor
+				return status.Error(codes.Unavailable, "stream interrupted, watch again from your last cursor")
+			}
+			if err := stream.Send(toWatchResponse(change)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// syncCursor returns the position to sync from: cursor if set, else updatedSince
+func syncCursor(cursor string, updatedSince *timestamppb.Timestamp) (models.SyncCursor, error) {
+	if cursor == "" {
+		return models.SyncCursorSince(updatedSince.AsTime()), nil
+	}
+	since, err := models.ParseSyncCursor(cursor)
+	if err != nil {
+		return models.SyncCursor{}, status.Error(codes.InvalidArgument, "cursor is invalid; sync again with updated_since")
+	}
+	return since, nil
+}
+
+// replay streams the changes a watcher missed since its last sync
+func (s *syncServer) replay(ctx context.Context, stream snippyv1.SyncService_WatchServer, userID string, since models.SyncCursor) error {
+	missed, err := s.store.GetSnippetChanges(ctx, userID, since)
+	if err != nil {
+		return snippetError(err, "failed to fetch sync data")
+	}
+
+	for _, snippet := range missed.Created {
+		if err := stream.Send(toWatchResponse(realtime.Change{Snippet: snippet, EventType: models.EventSnippetCreated, ChangedAt: snippet.CreatedAt})); err != nil {
+			return err
+		}
+	}
+	for _, snippet := range missed.Updated {
+		if err := stream.Send(toWatchResponse(realtime.Change{Snippet: snippet, EventType: models.EventSnippetUpdated, ChangedAt: snippet.UpdatedAt})); err != nil {
+			return err
+		}
+	}
+	for _, d := range missed.Deleted {
+		change := realtime.Change{Snippet: models.Snippet{ID: d.ID}, EventType: models.EventSnippetDeleted}
+		if d.DeletedAt != nil {
+			change.ChangedAt = *d.DeletedAt
+		}
+		if err := stream.Send(toWatchResponse(change)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snippetError maps model errors to gRPC status errors
+func snippetError(err error, failureMsg string) error {
+	var tooLarge *models.ContentTooLargeError
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return status.Error(codes.NotFound, "snippet not found")
+	case errors.Is(err, models.ErrNotSnippetOwner):
+		return status.Error(codes.PermissionDenied, "you don't have permission to access this snippet")
+	case errors.Is(err, placeholder.ErrInvalid):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, models.ErrShortcutExists):
+		return status.Error(codes.AlreadyExists, "you already have a snippet with this shortcut")
+	case errors.Is(err, models.ErrSnippetQuotaExceeded), errors.Is(err, models.ErrStorageQuotaExceeded),
+		errors.As(err, &tooLarge):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "request canceled")
+	}
+	log.Printf("grpc: %s: %v", failureMsg, err)
+	return status.Error(codes.Internal, failureMsg)
+}
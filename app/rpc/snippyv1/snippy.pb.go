@@ -0,0 +1,1235 @@
+// Snippy gRPC API: snippet CRUD and sync for desktop clients and internal services.
+// Regenerate Go code with `buf generate` from the repository root.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: snippy/v1/snippy.proto
+
+package snippyv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type WatchResponse_ChangeType int32
+
+const (
+	WatchResponse_CHANGE_TYPE_UNSPECIFIED WatchResponse_ChangeType = 0
+	WatchResponse_CHANGE_TYPE_CREATED     WatchResponse_ChangeType = 1
+	WatchResponse_CHANGE_TYPE_UPDATED     WatchResponse_ChangeType = 2
+	WatchResponse_CHANGE_TYPE_DELETED     WatchResponse_ChangeType = 3
+)
+
+// Enum value maps for WatchResponse_ChangeType.
+var (
+	WatchResponse_ChangeType_name = map[int32]string{
+		0: "CHANGE_TYPE_UNSPECIFIED",
+		1: "CHANGE_TYPE_CREATED",
+		2: "CHANGE_TYPE_UPDATED",
+		3: "CHANGE_TYPE_DELETED",
+	}
+	WatchResponse_ChangeType_value = map[string]int32{
+		"CHANGE_TYPE_UNSPECIFIED": 0,
+		"CHANGE_TYPE_CREATED":     1,
+		"CHANGE_TYPE_UPDATED":     2,
+		"CHANGE_TYPE_DELETED":     3,
+	}
+)
+
+func (x WatchResponse_ChangeType) Enum() *WatchResponse_ChangeType {
+	p := new(WatchResponse_ChangeType)
+	*p = x
+	return p
+}
+
+func (x WatchResponse_ChangeType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (WatchResponse_ChangeType) Descriptor() protoreflect.EnumDescriptor {
+	return file_snippy_v1_snippy_proto_enumTypes[0].Descriptor()
+}
+
+func (WatchResponse_ChangeType) Type() protoreflect.EnumType {
+	return &file_snippy_v1_snippy_proto_enumTypes[0]
+}
+
+func (x WatchResponse_ChangeType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use WatchResponse_ChangeType.Descriptor instead.
+func (WatchResponse_ChangeType) EnumDescriptor() ([]byte, []int) {
+	return file_snippy_v1_snippy_proto_rawDescGZIP(), []int{15, 0}
+}
+
+// Snippet is a text snippet owned by a user
+type Snippet struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Label         string                 `protobuf:"bytes,2,opt,name=label,proto3" json:"label,omitempty"`
+	Shortcut      string                 `protobuf:"bytes,3,opt,name=shortcut,proto3" json:"shortcut,omitempty"`
+	Content       string                 `protobuf:"bytes,4,opt,name=content,proto3" json:"content,omitempty"`
+	Tags          []string               `protobuf:"bytes,5,rep,name=tags,proto3" json:"tags,omitempty"`
+	UserId        string                 `protobuf:"bytes,6,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Visibility    string                 `protobuf:"bytes,7,opt,name=visibility,proto3" json:"visibility,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Snippet) Reset() {
+	*x = Snippet{}
+	mi := &file_snippy_v1_snippy_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Snippet) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Snippet) ProtoMessage() {}
+
+func (x *Snippet) ProtoReflect() protoreflect.Message {
+	mi := &file_snippy_v1_snippy_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Snippet.ProtoReflect.Descriptor instead.
+func (*Snippet) Descriptor() ([]byte, []int) {
+	return file_snippy_v1_snippy_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Snippet) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Snippet) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *Snippet) GetShortcut() string {
+	if x != nil {
+		return x.Shortcut
+	}
+	return ""
+}
+
+func (x *Snippet) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *Snippet) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Snippet) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *Snippet) GetVisibility() string {
+	if x != nil {
+		return x.Visibility
+	}
+	return ""
+}
+
+func (x *Snippet) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Snippet) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type ListSnippetsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Tag   string                 `protobuf:"bytes,1,opt,name=tag,proto3" json:"tag,omitempty"`
+	// Full-text search on the label
+	Search string `protobuf:"bytes,2,opt,name=search,proto3" json:"search,omitempty"`
+	// Defaults to 50, max 100
+	Limit         int32 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSnippetsRequest) Reset() {
+	*x = ListSnippetsRequest{}
+	mi := &file_snippy_v1_snippy_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSnippetsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSnippetsRequest) ProtoMessage() {}
+
+func (x *ListSnippetsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_snippy_v1_snippy_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSnippetsRequest.ProtoReflect.Descriptor instead.
+func (*ListSnippetsRequest) Descriptor() ([]byte, []int) {
+	return file_snippy_v1_snippy_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListSnippetsRequest) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+func (x *ListSnippetsRequest) GetSearch() string {
+	if x != nil {
+		return x.Search
+	}
+	return ""
+}
+
+func (x *ListSnippetsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type ListSnippetsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Snippets      []*Snippet             `protobuf:"bytes,1,rep,name=snippets,proto3" json:"snippets,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSnippetsResponse) Reset() {
+	*x = ListSnippetsResponse{}
+	mi := &file_snippy_v1_snippy_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSnippetsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSnippetsResponse) ProtoMessage() {}
+
+func (x *ListSnippetsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_snippy_v1_snippy_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSnippetsResponse.ProtoReflect.Descriptor instead.
+func (*ListSnippetsResponse) Descriptor() ([]byte, []int) {
+	return file_snippy_v1_snippy_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListSnippetsResponse) GetSnippets() []*Snippet {
+	if x != nil {
+		return x.Snippets
+	}
+	return nil
+}
+
+type GetSnippetRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSnippetRequest) Reset() {
+	*x = GetSnippetRequest{}
+	mi := &file_snippy_v1_snippy_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSnippetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSnippetRequest) ProtoMessage() {}
+
+func (x *GetSnippetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_snippy_v1_snippy_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSnippetRequest.ProtoReflect.Descriptor instead.
+func (*GetSnippetRequest) Descriptor() ([]byte, []int) {
+	return file_snippy_v1_snippy_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetSnippetRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type GetSnippetResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Snippet       *Snippet               `protobuf:"bytes,1,opt,name=snippet,proto3" json:"snippet,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSnippetResponse) Reset() {
+	*x = GetSnippetResponse{}
+	mi := &file_snippy_v1_snippy_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSnippetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSnippetResponse) ProtoMessage() {}
+
+func (x *GetSnippetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_snippy_v1_snippy_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSnippetResponse.ProtoReflect.Descriptor instead.
+func (*GetSnippetResponse) Descriptor() ([]byte, []int) {
+	return file_snippy_v1_snippy_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetSnippetResponse) GetSnippet() *Snippet {
+	if x != nil {
+		return x.Snippet
+	}
+	return nil
+}
+
+type CreateSnippetRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Label    string                 `protobuf:"bytes,1,opt,name=label,proto3" json:"label,omitempty"`
+	Shortcut string                 `protobuf:"bytes,2,opt,name=shortcut,proto3" json:"shortcut,omitempty"`
+	Content  string                 `protobuf:"bytes,3,opt,name=content,proto3" json:"content,omitempty"`
+	Tags     []string               `protobuf:"bytes,4,rep,name=tags,proto3" json:"tags,omitempty"`
+	// "private" (default) or "public"
+	Visibility    string `protobuf:"bytes,5,opt,name=visibility,proto3" json:"visibility,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateSnippetRequest) Reset() {
+	*x = CreateSnippetRequest{}
+	mi := &file_snippy_v1_snippy_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateSnippetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateSnippetRequest) ProtoMessage() {}
+
+func (x *CreateSnippetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_snippy_v1_snippy_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateSnippetRequest.ProtoReflect.Descriptor instead.
+func (*CreateSnippetRequest) Descriptor() ([]byte, []int) {
+	return file_snippy_v1_snippy_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CreateSnippetRequest) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *CreateSnippetRequest) GetShortcut() string {
+	if x != nil {
+		return x.Shortcut
+	}
+	return ""
+}
+
+func (x *CreateSnippetRequest) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *CreateSnippetRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *CreateSnippetRequest) GetVisibility() string {
+	if x != nil {
+		return x.Visibility
+	}
+	return ""
+}
+
+type CreateSnippetResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Snippet       *Snippet               `protobuf:"bytes,1,opt,name=snippet,proto3" json:"snippet,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateSnippetResponse) Reset() {
+	*x = CreateSnippetResponse{}
+	mi := &file_snippy_v1_snippy_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateSnippetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateSnippetResponse) ProtoMessage() {}
+
+func (x *CreateSnippetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_snippy_v1_snippy_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateSnippetResponse.ProtoReflect.Descriptor instead.
+func (*CreateSnippetResponse) Descriptor() ([]byte, []int) {
+	return file_snippy_v1_snippy_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CreateSnippetResponse) GetSnippet() *Snippet {
+	if x != nil {
+		return x.Snippet
+	}
+	return nil
+}
+
+// UpdateSnippetRequest changes only the fields that are set
+type UpdateSnippetRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Id       int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Label    *string                `protobuf:"bytes,2,opt,name=label,proto3,oneof" json:"label,omitempty"`
+	Shortcut *string                `protobuf:"bytes,3,opt,name=shortcut,proto3,oneof" json:"shortcut,omitempty"`
+	Content  *string                `protobuf:"bytes,4,opt,name=content,proto3,oneof" json:"content,omitempty"`
+	// Replaces the tags when replace_tags is true
+	Tags          []string `protobuf:"bytes,5,rep,name=tags,proto3" json:"tags,omitempty"`
+	ReplaceTags   bool     `protobuf:"varint,6,opt,name=replace_tags,json=replaceTags,proto3" json:"replace_tags,omitempty"`
+	Visibility    *string  `protobuf:"bytes,7,opt,name=visibility,proto3,oneof" json:"visibility,omitempty"`
+	ChangeNotes   *string  `protobuf:"bytes,8,opt,name=change_notes,json=changeNotes,proto3,oneof" json:"change_notes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateSnippetRequest) Reset() {
+	*x = UpdateSnippetRequest{}
+	mi := &file_snippy_v1_snippy_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateSnippetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateSnippetRequest) ProtoMessage() {}
+
+func (x *UpdateSnippetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_snippy_v1_snippy_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateSnippetRequest.ProtoReflect.Descriptor instead.
+func (*UpdateSnippetRequest) Descriptor() ([]byte, []int) {
+	return file_snippy_v1_snippy_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *UpdateSnippetRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *UpdateSnippetRequest) GetLabel() string {
+	if x != nil && x.Label != nil {
+		return *x.Label
+	}
+	return ""
+}
+
+func (x *UpdateSnippetRequest) GetShortcut() string {
+	if x != nil && x.Shortcut != nil {
+		return *x.Shortcut
+	}
+	return ""
+}
+
+func (x *UpdateSnippetRequest) GetContent() string {
+	if x != nil && x.Content != nil {
+		return *x.Content
+	}
+	return ""
+}
+
+func (x *UpdateSnippetRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *UpdateSnippetRequest) GetReplaceTags() bool {
+	if x != nil {
+		return x.ReplaceTags
+	}
+	return false
+}
+
+func (x *UpdateSnippetRequest) GetVisibility() string {
+	if x != nil && x.Visibility != nil {
+		return *x.Visibility
+	}
+	return ""
+}
+
+func (x *UpdateSnippetRequest) GetChangeNotes() string {
+	if x != nil && x.ChangeNotes != nil {
+		return *x.ChangeNotes
+	}
+	return ""
+}
+
+type UpdateSnippetResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Snippet       *Snippet               `protobuf:"bytes,1,opt,name=snippet,proto3" json:"snippet,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateSnippetResponse) Reset() {
+	*x = UpdateSnippetResponse{}
+	mi := &file_snippy_v1_snippy_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateSnippetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateSnippetResponse) ProtoMessage() {}
+
+func (x *UpdateSnippetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_snippy_v1_snippy_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateSnippetResponse.ProtoReflect.Descriptor instead.
+func (*UpdateSnippetResponse) Descriptor() ([]byte, []int) {
+	return file_snippy_v1_snippy_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *UpdateSnippetResponse) GetSnippet() *Snippet {
+	if x != nil {
+		return x.Snippet
+	}
+	return nil
+}
+
+type DeleteSnippetRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteSnippetRequest) Reset() {
+	*x = DeleteSnippetRequest{}
+	mi := &file_snippy_v1_snippy_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteSnippetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteSnippetRequest) ProtoMessage() {}
+
+func (x *DeleteSnippetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_snippy_v1_snippy_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteSnippetRequest.ProtoReflect.Descriptor instead.
+func (*DeleteSnippetRequest) Descriptor() ([]byte, []int) {
+	return file_snippy_v1_snippy_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *DeleteSnippetRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type DeleteSnippetResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteSnippetResponse) Reset() {
+	*x = DeleteSnippetResponse{}
+	mi := &file_snippy_v1_snippy_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteSnippetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteSnippetResponse) ProtoMessage() {}
+
+func (x *DeleteSnippetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_snippy_v1_snippy_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteSnippetResponse.ProtoReflect.Descriptor instead.
+func (*DeleteSnippetResponse) Descriptor() ([]byte, []int) {
+	return file_snippy_v1_snippy_proto_rawDescGZIP(), []int{10}
+}
+
+type SyncRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// For the first sync; cursor takes precedence
+	UpdatedSince *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=updated_since,json=updatedSince,proto3" json:"updated_since,omitempty"`
+	// The cursor of the previous sync
+	Cursor        string `protobuf:"bytes,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SyncRequest) Reset() {
+	*x = SyncRequest{}
+	mi := &file_snippy_v1_snippy_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SyncRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SyncRequest) ProtoMessage() {}
+
+func (x *SyncRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_snippy_v1_snippy_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SyncRequest.ProtoReflect.Descriptor instead.
+func (*SyncRequest) Descriptor() ([]byte, []int) {
+	return file_snippy_v1_snippy_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SyncRequest) GetUpdatedSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedSince
+	}
+	return nil
+}
+
+func (x *SyncRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+type DeletedSnippet struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	DeletedAt     *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=deleted_at,json=deletedAt,proto3" json:"deleted_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeletedSnippet) Reset() {
+	*x = DeletedSnippet{}
+	mi := &file_snippy_v1_snippy_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeletedSnippet) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletedSnippet) ProtoMessage() {}
+
+func (x *DeletedSnippet) ProtoReflect() protoreflect.Message {
+	mi := &file_snippy_v1_snippy_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletedSnippet.ProtoReflect.Descriptor instead.
+func (*DeletedSnippet) Descriptor() ([]byte, []int) {
+	return file_snippy_v1_snippy_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *DeletedSnippet) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *DeletedSnippet) GetDeletedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DeletedAt
+	}
+	return nil
+}
+
+type SyncResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Created []*Snippet             `protobuf:"bytes,1,rep,name=created,proto3" json:"created,omitempty"`
+	Updated []*Snippet             `protobuf:"bytes,2,rep,name=updated,proto3" json:"updated,omitempty"`
+	Deleted []*DeletedSnippet      `protobuf:"bytes,3,rep,name=deleted,proto3" json:"deleted,omitempty"`
+	// Pass as updated_since on the next sync; prefer cursor
+	SyncedAt *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=synced_at,json=syncedAt,proto3" json:"synced_at,omitempty"`
+	// The position of the last change; pass as cursor on the next sync
+	Cursor        string `protobuf:"bytes,5,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SyncResponse) Reset() {
+	*x = SyncResponse{}
+	mi := &file_snippy_v1_snippy_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SyncResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SyncResponse) ProtoMessage() {}
+
+func (x *SyncResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_snippy_v1_snippy_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SyncResponse.ProtoReflect.Descriptor instead.
+func (*SyncResponse) Descriptor() ([]byte, []int) {
+	return file_snippy_v1_snippy_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *SyncResponse) GetCreated() []*Snippet {
+	if x != nil {
+		return x.Created
+	}
+	return nil
+}
+
+func (x *SyncResponse) GetUpdated() []*Snippet {
+	if x != nil {
+		return x.Updated
+	}
+	return nil
+}
+
+func (x *SyncResponse) GetDeleted() []*DeletedSnippet {
+	if x != nil {
+		return x.Deleted
+	}
+	return nil
+}
+
+func (x *SyncResponse) GetSyncedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.SyncedAt
+	}
+	return nil
+}
+
+func (x *SyncResponse) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+type WatchRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Changes after this time are replayed before live changes; unset streams only live changes
+	UpdatedSince *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=updated_since,json=updatedSince,proto3" json:"updated_since,omitempty"`
+	// Changes after this sync cursor are replayed instead, if set
+	Cursor        string `protobuf:"bytes,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	mi := &file_snippy_v1_snippy_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_snippy_v1_snippy_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_snippy_v1_snippy_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *WatchRequest) GetUpdatedSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedSince
+	}
+	return nil
+}
+
+func (x *WatchRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+// WatchResponse is one snippet change
+type WatchResponse struct {
+	state protoimpl.MessageState   `protogen:"open.v1"`
+	Type  WatchResponse_ChangeType `protobuf:"varint,1,opt,name=type,proto3,enum=snippy.v1.WatchResponse_ChangeType" json:"type,omitempty"`
+	// Set for created and updated changes
+	Snippet       *Snippet               `protobuf:"bytes,2,opt,name=snippet,proto3" json:"snippet,omitempty"`
+	SnippetId     int64                  `protobuf:"varint,3,opt,name=snippet_id,json=snippetId,proto3" json:"snippet_id,omitempty"`
+	ChangedAt     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=changed_at,json=changedAt,proto3" json:"changed_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchResponse) Reset() {
+	*x = WatchResponse{}
+	mi := &file_snippy_v1_snippy_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchResponse) ProtoMessage() {}
+
+func (x *WatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_snippy_v1_snippy_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchResponse.ProtoReflect.Descriptor instead.
+func (*WatchResponse) Descriptor() ([]byte, []int) {
+	return file_snippy_v1_snippy_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *WatchResponse) GetType() WatchResponse_ChangeType {
+	if x != nil {
+		return x.Type
+	}
+	return WatchResponse_CHANGE_TYPE_UNSPECIFIED
+}
+
+func (x *WatchResponse) GetSnippet() *Snippet {
+	if x != nil {
+		return x.Snippet
+	}
+	return nil
+}
+
+func (x *WatchResponse) GetSnippetId() int64 {
+	if x != nil {
+		return x.SnippetId
+	}
+	return 0
+}
+
+func (x *WatchResponse) GetChangedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ChangedAt
+	}
+	return nil
+}
+
+var File_snippy_v1_snippy_proto protoreflect.FileDescriptor
+
+const file_snippy_v1_snippy_proto_rawDesc = "" +
+	"\n" +
+	"\x16snippy/v1/snippy.proto\x12\tsnippy.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xa8\x02\n" +
+	"\aSnippet\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x14\n" +
+	"\x05label\x18\x02 \x01(\tR\x05label\x12\x1a\n" +
+	"\bshortcut\x18\x03 \x01(\tR\bshortcut\x12\x18\n" +
+	"\acontent\x18\x04 \x01(\tR\acontent\x12\x12\n" +
+	"\x04tags\x18\x05 \x03(\tR\x04tags\x12\x17\n" +
+	"\auser_id\x18\x06 \x01(\tR\x06userId\x12\x1e\n" +
+	"\n" +
+	"visibility\x18\a \x01(\tR\n" +
+	"visibility\x129\n" +
+	"\n" +
+	"created_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"U\n" +
+	"\x13ListSnippetsRequest\x12\x10\n" +
+	"\x03tag\x18\x01 \x01(\tR\x03tag\x12\x16\n" +
+	"\x06search\x18\x02 \x01(\tR\x06search\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\"F\n" +
+	"\x14ListSnippetsResponse\x12.\n" +
+	"\bsnippets\x18\x01 \x03(\v2\x12.snippy.v1.SnippetR\bsnippets\"#\n" +
+	"\x11GetSnippetRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"B\n" +
+	"\x12GetSnippetResponse\x12,\n" +
+	"\asnippet\x18\x01 \x01(\v2\x12.snippy.v1.SnippetR\asnippet\"\x96\x01\n" +
+	"\x14CreateSnippetRequest\x12\x14\n" +
+	"\x05label\x18\x01 \x01(\tR\x05label\x12\x1a\n" +
+	"\bshortcut\x18\x02 \x01(\tR\bshortcut\x12\x18\n" +
+	"\acontent\x18\x03 \x01(\tR\acontent\x12\x12\n" +
+	"\x04tags\x18\x04 \x03(\tR\x04tags\x12\x1e\n" +
+	"\n" +
+	"visibility\x18\x05 \x01(\tR\n" +
+	"visibility\"E\n" +
+	"\x15CreateSnippetResponse\x12,\n" +
+	"\asnippet\x18\x01 \x01(\v2\x12.snippy.v1.SnippetR\asnippet\"\xc8\x02\n" +
+	"\x14UpdateSnippetRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x19\n" +
+	"\x05label\x18\x02 \x01(\tH\x00R\x05label\x88\x01\x01\x12\x1f\n" +
+	"\bshortcut\x18\x03 \x01(\tH\x01R\bshortcut\x88\x01\x01\x12\x1d\n" +
+	"\acontent\x18\x04 \x01(\tH\x02R\acontent\x88\x01\x01\x12\x12\n" +
+	"\x04tags\x18\x05 \x03(\tR\x04tags\x12!\n" +
+	"\freplace_tags\x18\x06 \x01(\bR\vreplaceTags\x12#\n" +
+	"\n" +
+	"visibility\x18\a \x01(\tH\x03R\n" +
+	"visibility\x88\x01\x01\x12&\n" +
+	"\fchange_notes\x18\b \x01(\tH\x04R\vchangeNotes\x88\x01\x01B\b\n" +
+	"\x06_labelB\v\n" +
+	"\t_shortcutB\n" +
+	"\n" +
+	"\b_contentB\r\n" +
+	"\v_visibilityB\x0f\n" +
+	"\r_change_notes\"E\n" +
+	"\x15UpdateSnippetResponse\x12,\n" +
+	"\asnippet\x18\x01 \x01(\v2\x12.snippy.v1.SnippetR\asnippet\"&\n" +
+	"\x14DeleteSnippetRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"\x17\n" +
+	"\x15DeleteSnippetResponse\"f\n" +
+	"\vSyncRequest\x12?\n" +
+	"\rupdated_since\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\fupdatedSince\x12\x16\n" +
+	"\x06cursor\x18\x02 \x01(\tR\x06cursor\"[\n" +
+	"\x0eDeletedSnippet\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x129\n" +
+	"\n" +
+	"deleted_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\tdeletedAt\"\xf0\x01\n" +
+	"\fSyncResponse\x12,\n" +
+	"\acreated\x18\x01 \x03(\v2\x12.snippy.v1.SnippetR\acreated\x12,\n" +
+	"\aupdated\x18\x02 \x03(\v2\x12.snippy.v1.SnippetR\aupdated\x123\n" +
+	"\adeleted\x18\x03 \x03(\v2\x19.snippy.v1.DeletedSnippetR\adeleted\x127\n" +
+	"\tsynced_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\bsyncedAt\x12\x16\n" +
+	"\x06cursor\x18\x05 \x01(\tR\x06cursor\"g\n" +
+	"\fWatchRequest\x12?\n" +
+	"\rupdated_since\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\fupdatedSince\x12\x16\n" +
+	"\x06cursor\x18\x02 \x01(\tR\x06cursor\"\xc6\x02\n" +
+	"\rWatchResponse\x127\n" +
+	"\x04type\x18\x01 \x01(\x0e2#.snippy.v1.WatchResponse.ChangeTypeR\x04type\x12,\n" +
+	"\asnippet\x18\x02 \x01(\v2\x12.snippy.v1.SnippetR\asnippet\x12\x1d\n" +
+	"\n" +
+	"snippet_id\x18\x03 \x01(\x03R\tsnippetId\x129\n" +
+	"\n" +
+	"changed_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tchangedAt\"t\n" +
+	"\n" +
+	"ChangeType\x12\x1b\n" +
+	"\x17CHANGE_TYPE_UNSPECIFIED\x10\x00\x12\x17\n" +
+	"\x13CHANGE_TYPE_CREATED\x10\x01\x12\x17\n" +
+	"\x13CHANGE_TYPE_UPDATED\x10\x02\x12\x17\n" +
+	"\x13CHANGE_TYPE_DELETED\x10\x032\xa8\x03\n" +
+	"\x0eSnippetService\x12O\n" +
+	"\fListSnippets\x12\x1e.snippy.v1.ListSnippetsRequest\x1a\x1f.snippy.v1.ListSnippetsResponse\x12I\n" +
+	"\n" +
+	"GetSnippet\x12\x1c.snippy.v1.GetSnippetRequest\x1a\x1d.snippy.v1.GetSnippetResponse\x12R\n" +
+	"\rCreateSnippet\x12\x1f.snippy.v1.CreateSnippetRequest\x1a .snippy.v1.CreateSnippetResponse\x12R\n" +
+	"\rUpdateSnippet\x12\x1f.snippy.v1.UpdateSnippetRequest\x1a .snippy.v1.UpdateSnippetResponse\x12R\n" +
+	"\rDeleteSnippet\x12\x1f.snippy.v1.DeleteSnippetRequest\x1a .snippy.v1.DeleteSnippetResponse2\x84\x01\n" +
+	"\vSyncService\x127\n" +
+	"\x04Sync\x12\x16.snippy.v1.SyncRequest\x1a\x17.snippy.v1.SyncResponse\x12<\n" +
+	"\x05Watch\x12\x17.snippy.v1.WatchRequest\x1a\x18.snippy.v1.WatchResponse0\x01B>Z<github.com/jheysaaz/snippy-backend/app/rpc/snippyv1;snippyv1b\x06proto3"
+
+var (
+	file_snippy_v1_snippy_proto_rawDescOnce sync.Once
+	file_snippy_v1_snippy_proto_rawDescData []byte
+)
+
+func file_snippy_v1_snippy_proto_rawDescGZIP() []byte {
+	file_snippy_v1_snippy_proto_rawDescOnce.Do(func() {
+		file_snippy_v1_snippy_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_snippy_v1_snippy_proto_rawDesc), len(file_snippy_v1_snippy_proto_rawDesc)))
+	})
+	return file_snippy_v1_snippy_proto_rawDescData
+}
+
+var file_snippy_v1_snippy_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_snippy_v1_snippy_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
+var file_snippy_v1_snippy_proto_goTypes = []any{
+	(WatchResponse_ChangeType)(0), // 0: snippy.v1.WatchResponse.ChangeType
+	(*Snippet)(nil),               // 1: snippy.v1.Snippet
+	(*ListSnippetsRequest)(nil),   // 2: snippy.v1.ListSnippetsRequest
+	(*ListSnippetsResponse)(nil),  // 3: snippy.v1.ListSnippetsResponse
+	(*GetSnippetRequest)(nil),     // 4: snippy.v1.GetSnippetRequest
+	(*GetSnippetResponse)(nil),    // 5: snippy.v1.GetSnippetResponse
+	(*CreateSnippetRequest)(nil),  // 6: snippy.v1.CreateSnippetRequest
+	(*CreateSnippetResponse)(nil), // 7: snippy.v1.CreateSnippetResponse
+	(*UpdateSnippetRequest)(nil),  // 8: snippy.v1.UpdateSnippetRequest
+	(*UpdateSnippetResponse)(nil), // 9: snippy.v1.UpdateSnippetResponse
+	(*DeleteSnippetRequest)(nil),  // 10: snippy.v1.DeleteSnippetRequest
+	(*DeleteSnippetResponse)(nil), // 11: snippy.v1.DeleteSnippetResponse
+	(*SyncRequest)(nil),           // 12: snippy.v1.SyncRequest
+	(*DeletedSnippet)(nil),        // 13: snippy.v1.DeletedSnippet
+	(*SyncResponse)(nil),          // 14: snippy.v1.SyncResponse
+	(*WatchRequest)(nil),          // 15: snippy.v1.WatchRequest
+	(*WatchResponse)(nil),         // 16: snippy.v1.WatchResponse
+	(*timestamppb.Timestamp)(nil), // 17: google.protobuf.Timestamp
+}
+var file_snippy_v1_snippy_proto_depIdxs = []int32{
+	17, // 0: snippy.v1.Snippet.created_at:type_name -> google.protobuf.Timestamp
+	17, // 1: snippy.v1.Snippet.updated_at:type_name -> google.protobuf.Timestamp
+	1,  // 2: snippy.v1.ListSnippetsResponse.snippets:type_name -> snippy.v1.Snippet
+	1,  // 3: snippy.v1.GetSnippetResponse.snippet:type_name -> snippy.v1.Snippet
+	1,  // 4: snippy.v1.CreateSnippetResponse.snippet:type_name -> snippy.v1.Snippet
+	1,  // 5: snippy.v1.UpdateSnippetResponse.snippet:type_name -> snippy.v1.Snippet
+	17, // 6: snippy.v1.SyncRequest.updated_since:type_name -> google.protobuf.Timestamp
+	17, // 7: snippy.v1.DeletedSnippet.deleted_at:type_name -> google.protobuf.Timestamp
+	1,  // 8: snippy.v1.SyncResponse.created:type_name -> snippy.v1.Snippet
+	1,  // 9: snippy.v1.SyncResponse.updated:type_name -> snippy.v1.Snippet
+	13, // 10: snippy.v1.SyncResponse.deleted:type_name -> snippy.v1.DeletedSnippet
+	17, // 11: snippy.v1.SyncResponse.synced_at:type_name -> google.protobuf.Timestamp
+	17, // 12: snippy.v1.WatchRequest.updated_since:type_name -> google.protobuf.Timestamp
+	0,  // 13: snippy.v1.WatchResponse.type:type_name -> snippy.v1.WatchResponse.ChangeType
+	1,  // 14: snippy.v1.WatchResponse.snippet:type_name -> snippy.v1.Snippet
+	17, // 15: snippy.v1.WatchResponse.changed_at:type_name -> google.protobuf.Timestamp
+	2,  // 16: snippy.v1.SnippetService.ListSnippets:input_type -> snippy.v1.ListSnippetsRequest
+	4,  // 17: snippy.v1.SnippetService.GetSnippet:input_type -> snippy.v1.GetSnippetRequest
+	6,  // 18: snippy.v1.SnippetService.CreateSnippet:input_type -> snippy.v1.CreateSnippetRequest
+	8,  // 19: snippy.v1.SnippetService.UpdateSnippet:input_type -> snippy.v1.UpdateSnippetRequest
+	10, // 20: snippy.v1.SnippetService.DeleteSnippet:input_type -> snippy.v1.DeleteSnippetRequest
+	12, // 21: snippy.v1.SyncService.Sync:input_type -> snippy.v1.SyncRequest
+	15, // 22: snippy.v1.SyncService.Watch:input_type -> snippy.v1.WatchRequest
+	3,  // 23: snippy.v1.SnippetService.ListSnippets:output_type -> snippy.v1.ListSnippetsResponse
+	5,  // 24: snippy.v1.SnippetService.GetSnippet:output_type -> snippy.v1.GetSnippetResponse
+	7,  // 25: snippy.v1.SnippetService.CreateSnippet:output_type -> snippy.v1.CreateSnippetResponse
+	9,  // 26: snippy.v1.SnippetService.UpdateSnippet:output_type -> snippy.v1.UpdateSnippetResponse
+	11, // 27: snippy.v1.SnippetService.DeleteSnippet:output_type -> snippy.v1.DeleteSnippetResponse
+	14, // 28: snippy.v1.SyncService.Sync:output_type -> snippy.v1.SyncResponse
+	16, // 29: snippy.v1.SyncService.Watch:output_type -> snippy.v1.WatchResponse
+	23, // [23:30] is the sub-list for method output_type
+	16, // [16:23] is the sub-list for method input_type
+	16, // [16:16] is the sub-list for extension type_name
+	16, // [16:16] is the sub-list for extension extendee
+	0,  // [0:16] is the sub-list for field type_name
+}
+
+func init() { file_snippy_v1_snippy_proto_init() }
+func file_snippy_v1_snippy_proto_init() {
+	if File_snippy_v1_snippy_proto != nil {
+		return
+	}
+	file_snippy_v1_snippy_proto_msgTypes[7].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_snippy_v1_snippy_proto_rawDesc), len(file_snippy_v1_snippy_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   16,
+			NumExtensions: 0,
+			NumServices:   2,
+		},
+		GoTypes:           file_snippy_v1_snippy_proto_goTypes,
+		DependencyIndexes: file_snippy_v1_snippy_proto_depIdxs,
+		EnumInfos:         file_snippy_v1_snippy_proto_enumTypes,
+		MessageInfos:      file_snippy_v1_snippy_proto_msgTypes,
+	}.Build()
+	File_snippy_v1_snippy_proto = out.File
+	file_snippy_v1_snippy_proto_goTypes = nil
+	file_snippy_v1_snippy_proto_depIdxs = nil
+}
@@ -0,0 +1,243 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+// Elasticsearch indexes snippets in an Elasticsearch (or OpenSearch) index
+type Elasticsearch struct {
+	store  *models.Store
+	url    string
+	apiKey string
+	index  string
+}
+
+// NewElasticsearch creates an Elasticsearch backend loading hits from store. apiKey is the base64 "id:key" API
+// key, sent as "Authorization: ApiKey"; leave it empty for unsecured clusters.
+func NewElasticsearch(store *models.Store, baseURL, apiKey, index string) *Elasticsearch {
+	return &Elasticsearch{store: store, url: baseURL, apiKey: apiKey, index: index}
+}
+
+// Name returns the backend name
+func (e *Elasticsearch) Name() string { return BackendElasticsearch }
+
+// EnsureIndex creates the index with keyword fields for filtering and faceting
+func (e *Elasticsearch) EnsureIndex(ctx context.Context) error {
+	body, _ := json.Marshal(map[string]interface{}{
+		"mappings": map[string]interface{}{
+			"properties": map[string]interface{}{
+				"id":         map[string]string{"type": "long"},
+				"userId":     map[string]string{"type": "keyword"},
+				"label":      map[string]string{"type": "text"},
+				"shortcut":   map[string]string{"type": "text"},
+				"content":    map[string]string{"type": "text"},
+				"tags":       map[string]string{"type": "keyword"},
+				"visibility": map[string]string{"type": "keyword"},
+				"updatedAt":  map[string]string{"type": "date"},
+			},
+		},
+	})
+	status, respBody, err := e.send(ctx, http.MethodPut, "/"+url.PathEscape(e.index), "application/json", body)
+	if err != nil {
+		return err
+	}
+	if status >= 300 && !strings.Contains(string(respBody), "resource_already_exists_exception") {
+		return fmt.Errorf("elasticsearch: create index: status %d: %s", status, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+// Search runs a fuzzy multi-field query restricted to the user's documents
+func (e *Elasticsearch) Search(ctx context.Context, q models.SnippetSearch) (*Result, error) {
+	filter := []interface{}{map[string]interface{}{"term": map[string]string{"userId": q.UserID}}}
+	if q.Tag != "" {
+		filter = append(filter, map[string]interface{}{"term": map[string]string{"tags": q.Tag}})
+	}
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must": map[string]interface{}{
+					"multi_match": map[string]interface{}{
+						"query":     q.Text,
+						"fields":    []string{"label^3", "shortcut^2", "tags^2", "content"},
+						"fuzziness": "AUTO",
+					},
+				},
+				"filter": filter,
+			},
+		},
+		"from":             q.Offset,
+		"size":             q.Limit,
+		"_source":          false,
+		"track_total_hits": true,
+		"aggs": map[string]interface{}{
+			"tags": map[string]interface{}{"terms": map[string]interface{}{"field": "tags", "size": maxFacets}},
+		},
+	}
+
+	var resp struct {
+		Hits struct {
+			Total struct {
+				Value int `json:"value"`
+			} `json:"total"`
+			Hits []struct {
+				ID string `json:"_id"`
+			} `json:"hits"`
+		} `json:"hits"`
+		Aggregations struct {
+			Tags struct {
+				Buckets []struct {
+					Key      string `json:"key"`
+					DocCount int    `json:"doc_count"`
+				} `json:"buckets"`
+			} `json:"tags"`
+		} `json:"aggregations"`
+	}
+	if err := e.doJSON(ctx, http.MethodPost, "/"+url.PathEscape(e.index)+"/_search", query, &resp); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(resp.Hits.Hits))
+	for _, hit := range resp.Hits.Hits {
+		if id, err := strconv.ParseInt(hit.ID, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	snippets, err := e.store.GetUserSnippetsByIDs(ctx, q.UserID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	facets := make([]models.TagFacet, 0, len(resp.Aggregations.Tags.Buckets))
+	for _, b := range resp.Aggregations.Tags.Buckets {
+		facets = append(facets, models.TagFacet{Tag: b.Key, Count: b.DocCount})
+	}
+	return &Result{Snippets: snippets, Facets: facets, Total: resp.Hits.Total.Value}, nil
+}
+
+// Index adds or replaces documents with one bulk request
+func (e *Elasticsearch) Index(ctx context.Context, docs []Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, doc := range docs {
+		if err := enc.Encode(e.bulkAction("index", doc.ID)); err != nil {
+			return err
+		}
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+	}
+	return e.bulk(ctx, buf.Bytes())
+}
+
+// Delete removes documents by ID with one bulk request
+func (e *Elasticsearch) Delete(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, id := range ids {
+		if err := enc.Encode(e.bulkAction("delete", id)); err != nil {
+			return err
+		}
+	}
+	return e.bulk(ctx, buf.Bytes())
+}
+
+// DeleteUser removes all of a user's documents
+func (e *Elasticsearch) DeleteUser(ctx context.Context, userID string) error {
+	return e.doJSON(ctx, http.MethodPost, "/"+url.PathEscape(e.index)+"/_delete_by_query?conflicts=proceed",
+		map[string]interface{}{"query": map[string]interface{}{"term": map[string]string{"userId": userID}}}, nil)
+}
+
+func (e *Elasticsearch) bulkAction(action string, id int64) map[string]interface{} {
+	return map[string]interface{}{action: map[string]string{"_index": e.index, "_id": strconv.FormatInt(id, 10)}}
+}
+
+// bulk sends an NDJSON bulk request. The bulk API reports per-item failures in a 200
+// response; deleting a missing document is not a failure.
+func (e *Elasticsearch) bulk(ctx context.Context, body []byte) error {
+	status, respBody, err := e.send(ctx, http.MethodPost, "/_bulk", "application/x-ndjson", body)
+	if err != nil {
+		return err
+	}
+	if status >= 300 {
+		return fmt.Errorf("elasticsearch: bulk: status %d: %s", status, strings.TrimSpace(string(respBody)))
+	}
+
+	var resp struct {
+		Items []map[string]struct {
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+		Errors bool `json:"errors"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return fmt.Errorf("elasticsearch: bulk: decode response: %w", err)
+	}
+	if !resp.Errors {
+		return nil
+	}
+	for _, item := range resp.Items {
+		for action, result := range item {
+			if len(result.Error) > 0 && !(action == "delete" && result.Status == http.StatusNotFound) {
+				return fmt.Errorf("elasticsearch: bulk %s: status %d: %s", action, result.Status, result.Error)
+			}
+		}
+	}
+	return nil
+}
+
+// doJSON sends a JSON request and decodes the response into out, if given
+func (e *Elasticsearch) doJSON(ctx context.Context, method, path string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	status, respBody, err := e.send(ctx, method, path, "application/json", payload)
+	if err != nil {
+		return err
+	}
+	if status >= 300 {
+		return fmt.Errorf("elasticsearch: %s %s: status %d: %s", method, path, status, strings.TrimSpace(string(respBody)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+// send performs a request and returns the status and body
+func (e *Elasticsearch) send(ctx context.Context, method, path, contentType string, body []byte) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, e.url+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+e.apiKey)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	return resp.StatusCode, respBody, err
+}
@@ -0,0 +1,148 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+// Meilisearch indexes snippets in a Meilisearch index. Writes are asynchronous tasks in
+// Meilisearch, so a change becomes searchable shortly after it is accepted.
+type Meilisearch struct {
+	store  *models.Store
+	url    string
+	apiKey string
+	index  string
+}
+
+// NewMeilisearch creates a Meilisearch backend loading hits from store
+func NewMeilisearch(store *models.Store, baseURL, apiKey, index string) *Meilisearch {
+	return &Meilisearch{store: store, url: baseURL, apiKey: apiKey, index: index}
+}
+
+// Name returns the backend name
+func (m *Meilisearch) Name() string { return BackendMeilisearch }
+
+// EnsureIndex creates the index and configures searchable and filterable attributes
+func (m *Meilisearch) EnsureIndex(ctx context.Context) error {
+	err := m.do(ctx, http.MethodPost, "/indexes", map[string]string{"uid": m.index, "primaryKey": "id"}, nil)
+	if err != nil {
+		return err
+	}
+	// Attribute order sets ranking priority: a label match beats a content match
+	return m.do(ctx, http.MethodPatch, m.indexPath("/settings"), map[string]interface{}{
+		"searchableAttributes": []string{"label", "shortcut", "tags", "content"},
+		"filterableAttributes": []string{"userId", "tags", "visibility"},
+	}, nil)
+}
+
+// Search queries the index, restricted to the user's documents
+func (m *Meilisearch) Search(ctx context.Context, q models.SnippetSearch) (*Result, error) {
+	filter := []string{"userId = " + meiliQuote(q.UserID)}
+	if q.Tag != "" {
+		filter = append(filter, "tags = "+meiliQuote(q.Tag))
+	}
+
+	var resp struct {
+		Hits []struct {
+			ID int64 `json:"id"`
+		} `json:"hits"`
+		EstimatedTotalHits int                       `json:"estimatedTotalHits"`
+		FacetDistribution  map[string]map[string]int `json:"facetDistribution"`
+	}
+	err := m.do(ctx, http.MethodPost, m.indexPath("/search"), map[string]interface{}{
+		"q":                    q.Text,
+		"filter":               filter,
+		"limit":                q.Limit,
+		"offset":               q.Offset,
+		"facets":               []string{"tags"},
+		"attributesToRetrieve": []string{"id"},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, len(resp.Hits))
+	for i, hit := range resp.Hits {
+		ids[i] = hit.ID
+	}
+	snippets, err := m.store.GetUserSnippetsByIDs(ctx, q.UserID, ids)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		Snippets: snippets,
+		Facets:   topFacets(resp.FacetDistribution["tags"]),
+		Total:    resp.EstimatedTotalHits,
+	}, nil
+}
+
+// Index adds or replaces documents
+func (m *Meilisearch) Index(ctx context.Context, docs []Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	return m.do(ctx, http.MethodPost, m.indexPath("/documents"), docs, nil)
+}
+
+// Delete removes documents by ID
+func (m *Meilisearch) Delete(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return m.do(ctx, http.MethodPost, m.indexPath("/documents/delete-batch"), ids, nil)
+}
+
+// DeleteUser removes all of a user's documents
+func (m *Meilisearch) DeleteUser(ctx context.Context, userID string) error {
+	return m.do(ctx, http.MethodPost, m.indexPath("/documents/delete"),
+		map[string]string{"filter": "userId = " + meiliQuote(userID)}, nil)
+}
+
+func (m *Meilisearch) indexPath(suffix string) string {
+	return "/indexes/" + url.PathEscape(m.index) + suffix
+}
+
+// do sends a JSON request and decodes the response into out, if given. Creating an index
+// that already exists is reported by Meilisearch as a failed task, not an HTTP error.
+func (m *Meilisearch) do(ctx context.Context, method, path string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, m.url+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if m.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+m.apiKey)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("meilisearch: %s %s: status %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// meiliQuote quotes a value for a Meilisearch filter expression
+func meiliQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,342 @@
+// Package search provides snippet search through a pluggable backend. Postgres full-text
+// search is the default; Meilisearch or Elasticsearch can be configured for typo
+// tolerance, better ranking and facets, kept in sync by an outbox sink.
+package search
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+// Backend names accepted by SEARCH_BACKEND
+const (
+	BackendPostgres      = "postgres"
+	BackendMeilisearch   = "meilisearch"
+	BackendElasticsearch = "elasticsearch"
+)
+
+const (
+	// reindexBatchSize is how many snippets are sent to the engine per request when reindexing
+	reindexBatchSize = 500
+
+	// maxFacets caps the tag facets returned with a search
+	maxFacets = 20
+)
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Document is the searchable form of a snippet
+type Document struct {
+	UpdatedAt  time.Time `json:"updatedAt"`
+	UserID     string    `json:"userId"`
+	Label      string    `json:"label"`
+	Shortcut   string    `json:"shortcut"`
+	Content    string    `json:"content"`
+	Visibility string    `json:"visibility"`
+	Tags       []string  `json:"tags"`
+	ID         int64     `json:"id"`
+}
+
+// NewDocument converts a snippet. Snippets without an owner can't be searched and return false.
+func NewDocument(s *models.Snippet) (Document, bool) {
+	if s.UserID == nil {
+		return Document{}, false
+	}
+	tags := s.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return Document{
+		UpdatedAt:  s.UpdatedAt,
+		UserID:     *s.UserID,
+		Label:      s.Label,
+		Shortcut:   s.Shortcut,
+		Content:    s.Content,
+		Visibility: s.Visibility,
+		Tags:       tags,
+		ID:         s.ID,
+	}, true
+}
+
+// Result is one page of search results
+type Result struct {
+	Snippets []models.Snippet  `json:"items"`
+	Facets   []models.TagFacet `json:"facets"`
+	Total    int               `json:"total"`
+	// Fuzzy is set when the results come from the trigram fallback
+	Fuzzy bool `json:"fuzzy"`
+}
+
+// Backend searches snippets. External engines also hold a copy of every snippet;
+// Postgres searches the snippets table directly and ignores the index calls.
+type Backend interface {
+	Name() string
+	// EnsureIndex creates the index and its settings if needed
+	EnsureIndex(ctx context.Context) error
+	// Search returns a user's matching snippets, best match first
+	Search(ctx context.Context, q models.SnippetSearch) (*Result, error)
+	// Index adds or replaces documents
+	Index(ctx context.Context, docs []Document) error
+	// Delete removes documents by snippet ID
+	Delete(ctx context.Context, ids []int64) error
+	// DeleteUser removes all of a user's documents
+	DeleteUser(ctx context.Context, userID string) error
+}
+
+// Config selects and configures the search backend
+type Config struct {
+	Backend string
+	URL     string
+	APIKey  string
+	Index   string
+}
+
+// LoadConfig reads SEARCH_BACKEND, SEARCH_URL, SEARCH_API_KEY and SEARCH_INDEX from the environment
+func LoadConfig() Config {
+	cfg := Config{
+		Backend: strings.ToLower(strings.TrimSpace(os.Getenv("SEARCH_BACKEND"))),
+		URL:     strings.TrimRight(os.Getenv("SEARCH_URL"), "/"),
+		APIKey:  os.Getenv("SEARCH_API_KEY"),
+		Index:   os.Getenv("SEARCH_INDEX"),
+	}
+	if cfg.Backend == "" {
+		cfg.Backend = BackendPostgres
+	}
+	if cfg.Index == "" {
+		cfg.Index = "snippets"
+	}
+	return cfg
+}
+
+// External reports whether an external engine is configured
+func (cfg Config) External() bool {
+	return cfg.Backend != BackendPostgres
+}
+
+// New creates the configured backend. Every backend loads the snippets it finds from store.
+func New(cfg Config, store *models.Store) (Backend, error) {
+	switch cfg.Backend {
+	case BackendPostgres:
+		return NewPostgres(store), nil
+	case BackendMeilisearch, BackendElasticsearch:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("search: SEARCH_URL is required for %s", cfg.Backend)
+		}
+		if cfg.Backend == BackendMeilisearch {
+			return NewMeilisearch(store, cfg.URL, cfg.APIKey, cfg.Index), nil
+		}
+		return NewElasticsearch(store, cfg.URL, cfg.APIKey, cfg.Index), nil
+	}
+	return nil, fmt.Errorf("search: unknown SEARCH_BACKEND %q (want postgres, meilisearch or elasticsearch)", cfg.Backend)
+}
+
+// FromEnv creates the backend configured in the environment
+func FromEnv(store *models.Store) (Backend, error) {
+	return New(LoadConfig(), store)
+}
+
+// Run searches with the configured backend, falling back to Postgres when the
+// configuration is invalid or the external engine fails. It returns the name of the
+// backend that produced the result.
+func Run(ctx context.Context, store *models.Store, q models.SnippetSearch) (*Result, string, error) {
+	backend, err := FromEnv(store)
+	if err != nil {
+		log.Printf("Invalid search configuration, using Postgres: %v", err)
+		backend = NewPostgres(store)
+	}
+	result, err := backend.Search(ctx, q)
+	if err != nil && backend.Name() != BackendPostgres {
+		// Keep search working while the external engine is down
+		log.Printf("Search with %s failed, falling back to Postgres: %v", backend.Name(), err)
+		backend = NewPostgres(store)
+		result, err = backend.Search(ctx, q)
+	}
+	return result, backend.Name(), err
+}
+
+// Postgres searches snippet labels with the existing full-text index
+type Postgres struct {
+	store *models.Store
+}
+
+// NewPostgres creates a Postgres backend searching store
+func NewPostgres(store *models.Store) Postgres {
+	return Postgres{store: store}
+}
+
+// Name returns the backend name
+func (Postgres) Name() string { return BackendPostgres }
+
+// Search runs a Postgres full-text search. A fuzzy search that finds nothing (e.g.
+// because of a typo) is retried by trigram similarity.
+func (p Postgres) Search(ctx context.Context, q models.SnippetSearch) (*Result, error) {
+	snippets, total, facets, err := p.store.SearchUserSnippets(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	if total > 0 || !q.Fuzzy {
+		return &Result{Snippets: snippets, Facets: facets, Total: total}, nil
+	}
+
+	snippets, total, facets, err = p.store.FuzzySearchUserSnippets(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Snippets: snippets, Facets: facets, Total: total, Fuzzy: true}, nil
+}
+
+// EnsureIndex is a no-op; the full-text index is part of the schema
+func (Postgres) EnsureIndex(context.Context) error { return nil }
+
+// Index is a no-op; Postgres searches the snippets table
+func (Postgres) Index(context.Context, []Document) error { return nil }
+
+// Delete is a no-op
+func (Postgres) Delete(context.Context, []int64) error { return nil }
+
+// DeleteUser is a no-op
+func (Postgres) DeleteUser(context.Context, string) error { return nil }
+
+// Sink is an outbox sink that keeps an external engine in sync with snippet changes
+type Sink struct {
+	store   *models.Store
+	backend Backend
+}
+
+// NewSink creates a sink indexing snippets loaded from store into backend
+func NewSink(store *models.Store, backend Backend) *Sink {
+	return &Sink{store: store, backend: backend}
+}
+
+// NewSinkFromEnv returns a sink for the configured external engine, or nil when
+// searching Postgres only
+func NewSinkFromEnv(store *models.Store) (*Sink, error) {
+	cfg := LoadConfig()
+	if !cfg.External() {
+		return nil, nil
+	}
+	backend, err := New(cfg, store)
+	if err != nil {
+		return nil, err
+	}
+	return NewSink(store, backend), nil
+}
+
+// Name returns the sink name
+func (s *Sink) Name() string { return "search" }
+
+// Backend returns the engine the sink indexes into
+func (s *Sink) Backend() Backend { return s.backend }
+
+// Deliver indexes the current state of changed snippets. It reloads the snippet rather
+// than indexing the event payload, so a redelivered older event can't overwrite a newer
+// version.
+func (s *Sink) Deliver(ctx context.Context, evt models.OutboxEvent) error {
+	switch evt.AggregateType {
+	case models.AggregateSnippet:
+		id, err := strconv.ParseInt(evt.AggregateID, 10, 64)
+		if err != nil {
+			return nil
+		}
+		snippet, err := s.store.GetSnippet(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return s.backend.Delete(ctx, []int64{id})
+		}
+		if err != nil {
+			return err
+		}
+		doc, ok := NewDocument(snippet)
+		if !ok {
+			return s.backend.Delete(ctx, []int64{id})
+		}
+		return s.backend.Index(ctx, []Document{doc})
+
+	case models.AggregateUser:
+		if evt.EventType != models.EventUserDeleted {
+			return nil
+		}
+		var payload struct {
+			UserID string `json:"userId"`
+		}
+		if err := json.Unmarshal(evt.Payload, &payload); err != nil || payload.UserID == "" {
+			payload.UserID = evt.AggregateID
+		}
+		return s.backend.DeleteUser(ctx, payload.UserID)
+	}
+	return nil
+}
+
+// ErrReindexRunning is returned when a reindex is already in progress on this instance
+var ErrReindexRunning = errors.New("search: reindex already running")
+
+var reindexing atomic.Bool
+
+// Reindexing reports whether a reindex is in progress on this instance
+func Reindexing() bool {
+	return reindexing.Load()
+}
+
+// Reindex sends every non-deleted snippet in store to backend, for populating a new
+// engine or index. Deletions that happened while the engine was out of sync are not removed.
+func Reindex(ctx context.Context, store *models.Store, backend Backend) (int, error) {
+	if !reindexing.CompareAndSwap(false, true) {
+		return 0, ErrReindexRunning
+	}
+	defer reindexing.Store(false)
+
+	var afterID int64
+	total := 0
+	for {
+		snippets, err := store.ListSnippetsAfter(ctx, afterID, reindexBatchSize)
+		if err != nil {
+			return total, err
+		}
+		if len(snippets) == 0 {
+			return total, nil
+		}
+
+		docs := make([]Document, 0, len(snippets))
+		for i := range snippets {
+			if doc, ok := NewDocument(&snippets[i]); ok {
+				docs = append(docs, doc)
+			}
+		}
+		if err := backend.Index(ctx, docs); err != nil {
+			return total, err
+		}
+		total += len(docs)
+		afterID = snippets[len(snippets)-1].ID
+		log.Printf("Search reindex: %d snippets indexed", total)
+	}
+}
+
+// topFacets returns the most common tags from an engine's tag counts
+func topFacets(counts map[string]int) []models.TagFacet {
+	facets := make([]models.TagFacet, 0, len(counts))
+	for tag, count := range counts {
+		facets = append(facets, models.TagFacet{Tag: tag, Count: count})
+	}
+	sort.Slice(facets, func(i, j int) bool {
+		if facets[i].Count != facets[j].Count {
+			return facets[i].Count > facets[j].Count
+		}
+		return facets[i].Tag < facets[j].Tag
+	})
+	if len(facets) > maxFacets {
+		facets = facets[:maxFacets]
+	}
+	return facets
+}
@@ -0,0 +1,218 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+type recordedRequest struct {
+	Method string
+	Path   string
+	Auth   string
+	Body   string
+}
+
+// recordingServer answers every request with status and body and records what it received
+func recordingServer(t *testing.T, status int, body string) (*httptest.Server, *[]recordedRequest) {
+	t.Helper()
+	var requests []recordedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		requests = append(requests, recordedRequest{
+			Method: r.Method,
+			Path:   r.URL.RequestURI(),
+			Auth:   r.Header.Get("Authorization"),
+			Body:   string(data),
+		})
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestLoadConfigAndNew(t *testing.T) {
+	t.Setenv("SEARCH_BACKEND", "")
+	t.Setenv("SEARCH_INDEX", "")
+	cfg := LoadConfig()
+	if cfg.Backend != BackendPostgres || cfg.External() || cfg.Index != "snippets" {
+		t.Errorf("unexpected defaults %+v", cfg)
+	}
+
+	t.Setenv("SEARCH_BACKEND", " Meilisearch ")
+	t.Setenv("SEARCH_URL", "")
+	if _, err := New(LoadConfig(), nil); err == nil {
+		t.Error("expected an error without SEARCH_URL")
+	}
+
+	t.Setenv("SEARCH_URL", "http://meili:7700/")
+	backend, err := New(LoadConfig(), nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if m, ok := backend.(*Meilisearch); !ok || m.url != "http://meili:7700" {
+		t.Errorf("expected Meilisearch at http://meili:7700, got %#v", backend)
+	}
+
+	t.Setenv("SEARCH_BACKEND", "solr")
+	if _, err := New(LoadConfig(), nil); err == nil {
+		t.Error("expected an error for an unknown backend")
+	}
+}
+
+func TestNewDocument(t *testing.T) {
+	owner := "0b3c9a1e-6a5f-4c1b-9d2e-3f4a5b6c7d8e"
+	doc, ok := NewDocument(&models.Snippet{ID: 7, UserID: &owner, Label: "Sig", Visibility: models.VisibilityPrivate})
+	if !ok || doc.ID != 7 || doc.UserID != owner || doc.Tags == nil {
+		t.Errorf("unexpected document %+v", doc)
+	}
+	if _, ok := NewDocument(&models.Snippet{ID: 8}); ok {
+		t.Error("snippets without an owner should not be indexed")
+	}
+}
+
+func TestMeilisearchWrites(t *testing.T) {
+	server, requests := recordingServer(t, http.StatusAccepted, `{"taskUid":1}`)
+	m := NewMeilisearch(nil, server.URL, "master-key", "snippets")
+	ctx := context.Background()
+
+	docs := []Document{{ID: 1, UserID: "u1", Label: "Greeting", Tags: []string{"email"}, UpdatedAt: time.Unix(0, 0).UTC()}}
+	if err := m.Index(ctx, docs); err != nil {
+		t.Fatalf("Index: %v", err)
+	}
+	if err := m.Delete(ctx, []int64{1, 2}); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := m.DeleteUser(ctx, `u"1`); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if err := m.Index(ctx, nil); err != nil || len(*requests) != 3 {
+		t.Errorf("empty batches should not be sent (err %v, %d requests)", err, len(*requests))
+	}
+
+	want := []recordedRequest{
+		{Method: "POST", Path: "/indexes/snippets/documents"},
+		{Method: "POST", Path: "/indexes/snippets/documents/delete-batch", Body: "[1,2]"},
+		{Method: "POST", Path: "/indexes/snippets/documents/delete", Body: `{"filter":"userId = \"u\\\"1\""}`},
+	}
+	for i, w := range want {
+		got := (*requests)[i]
+		if got.Method != w.Method || got.Path != w.Path || (w.Body != "" && got.Body != w.Body) {
+			t.Errorf("request %d = %s %s %s, want %s %s %s", i, got.Method, got.Path, got.Body, w.Method, w.Path, w.Body)
+		}
+		if got.Auth != "Bearer master-key" {
+			t.Errorf("request %d: Authorization = %q", i, got.Auth)
+		}
+	}
+
+	var indexed []Document
+	if err := json.Unmarshal([]byte((*requests)[0].Body), &indexed); err != nil || len(indexed) != 1 || indexed[0].Label != "Greeting" {
+		t.Errorf("unexpected indexed body %s", (*requests)[0].Body)
+	}
+}
+
+func TestMeilisearchError(t *testing.T) {
+	server, _ := recordingServer(t, http.StatusUnauthorized, `{"message":"The provided API key is invalid."}`)
+	err := NewMeilisearch(nil, server.URL, "wrong", "snippets").Delete(context.Background(), []int64{1})
+	if err == nil || !strings.Contains(err.Error(), "status 401") {
+		t.Errorf("expected a 401 error, got %v", err)
+	}
+}
+
+func TestElasticsearchBulk(t *testing.T) {
+	server, requests := recordingServer(t, http.StatusOK,
+		`{"errors":true,"items":[{"delete":{"status":404,"error":{"type":"not_found"}}}]}`)
+	e := NewElasticsearch(nil, server.URL, "a2V5", "snippets")
+	ctx := context.Background()
+
+	// The canned response only reports a missing document on delete, which is not a failure
+	if err := e.Index(ctx, []Document{{ID: 3, UserID: "u1", Label: "Sig"}}); err != nil {
+		t.Errorf("Index: %v", err)
+	}
+	if err := e.Delete(ctx, []int64{3}); err != nil {
+		t.Errorf("deleting a missing document should succeed, got %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace((*requests)[0].Body), "\n")
+	if (*requests)[0].Path != "/_bulk" || len(lines) != 2 {
+		t.Fatalf("unexpected bulk request %s %q", (*requests)[0].Path, (*requests)[0].Body)
+	}
+	if lines[0] != `{"index":{"_id":"3","_index":"snippets"}}` {
+		t.Errorf("unexpected action line %s", lines[0])
+	}
+	if (*requests)[0].Auth != "ApiKey a2V5" {
+		t.Errorf("Authorization = %q", (*requests)[0].Auth)
+	}
+}
+
+func TestElasticsearchBulkItemFailure(t *testing.T) {
+	server, _ := recordingServer(t, http.StatusOK,
+		`{"errors":true,"items":[{"index":{"status":400,"error":{"type":"mapper_parsing_exception"}}}]}`)
+	err := NewElasticsearch(nil, server.URL, "", "snippets").Index(context.Background(), []Document{{ID: 1, UserID: "u1"}})
+	if err == nil || !strings.Contains(err.Error(), "mapper_parsing_exception") {
+		t.Errorf("expected the item error, got %v", err)
+	}
+}
+
+func TestElasticsearchEnsureIndexExisting(t *testing.T) {
+	server, _ := recordingServer(t, http.StatusBadRequest,
+		`{"error":{"type":"resource_already_exists_exception"},"status":400}`)
+	if err := NewElasticsearch(nil, server.URL, "", "snippets").EnsureIndex(context.Background()); err != nil {
+		t.Errorf("an existing index should not be an error, got %v", err)
+	}
+}
+
+type fakeBackend struct {
+	Postgres
+	deletedUsers []string
+}
+
+func (f *fakeBackend) DeleteUser(_ context.Context, userID string) error {
+	f.deletedUsers = append(f.deletedUsers, userID)
+	return nil
+}
+
+func TestSinkDeletesUserDocuments(t *testing.T) {
+	backend := &fakeBackend{}
+	sink := NewSink(nil, backend)
+	ctx := context.Background()
+
+	err := sink.Deliver(ctx, models.OutboxEvent{
+		EventType:     models.EventUserDeleted,
+		AggregateType: models.AggregateUser,
+		AggregateID:   "u1",
+		Payload:       json.RawMessage(`{"userId":"u1"}`),
+	})
+	if err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+	if err := sink.Deliver(ctx, models.OutboxEvent{EventType: models.EventSessionCreated, AggregateType: models.AggregateSession}); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+	if len(backend.deletedUsers) != 1 || backend.deletedUsers[0] != "u1" {
+		t.Errorf("deleted users = %v, want [u1]", backend.deletedUsers)
+	}
+}
+
+func TestTopFacets(t *testing.T) {
+	counts := map[string]int{"b": 2, "a": 2, "c": 5}
+	for i := 0; i < 30; i++ {
+		counts["t"+strings.Repeat("x", i)] = 1
+	}
+
+	facets := topFacets(counts)
+	if len(facets) != maxFacets {
+		t.Fatalf("expected %d facets, got %d", maxFacets, len(facets))
+	}
+	if facets[0].Tag != "c" || facets[1].Tag != "a" || facets[2].Tag != "b" {
+		t.Errorf("facets not ordered by count then tag: %v", facets[:3])
+	}
+}
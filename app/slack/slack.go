@@ -0,0 +1,176 @@
+// Package slack implements the /snippy Slack slash command: `/snippy <shortcut>` posts
+// the linked user's snippet into the channel.
+package slack
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+// signatureTolerance is how old a signed request may be before it is rejected as a replay
+const signatureTolerance = 5 * time.Minute
+
+// ErrInvalidSignature is returned for requests not signed with the app's signing secret
+var ErrInvalidSignature = errors.New("slack: invalid request signature")
+
+// Config holds the Slack app settings
+type Config struct {
+	SigningSecret string
+}
+
+// LoadConfig reads SLACK_SIGNING_SECRET from the environment
+func LoadConfig() Config {
+	return Config{SigningSecret: os.Getenv("SLACK_SIGNING_SECRET")}
+}
+
+// Enabled reports whether the slash command is configured
+func (cfg Config) Enabled() bool {
+	return cfg.SigningSecret != ""
+}
+
+// Command is a slash command invocation
+type Command struct {
+	TeamID string
+	UserID string
+	Name   string
+	Text   string
+}
+
+// Response is a slash command reply. Ephemeral replies are only shown to the caller;
+// in_channel replies are posted to the channel.
+type Response struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+// Response types
+const (
+	ResponseEphemeral = "ephemeral"
+	ResponseInChannel = "in_channel"
+)
+
+// ParseCommand verifies the X-Slack-Signature of a slash command payload against the
+// signing secret and decodes it
+func ParseCommand(payload []byte, timestamp, signature, secret string, now time.Time) (*Command, error) {
+	if err := verifySignature(payload, timestamp, signature, secret, now); err != nil {
+		return nil, err
+	}
+	form, err := url.ParseQuery(string(payload))
+	if err != nil {
+		return nil, fmt.Errorf("slack: decode command: %w", err)
+	}
+	return &Command{
+		TeamID: form.Get("team_id"),
+		UserID: form.Get("user_id"),
+		Name:   form.Get("command"),
+		Text:   strings.TrimSpace(form.Get("text")),
+	}, nil
+}
+
+// verifySignature checks a "v0=<hex>" signature, the HMAC-SHA256 of "v0:<timestamp>:<payload>"
+func verifySignature(payload []byte, timestamp, signature, secret string, now time.Time) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > signatureTolerance || age < -signatureTolerance {
+		return ErrInvalidSignature
+	}
+
+	hexSig, ok := strings.CutPrefix(signature, "v0=")
+	if !ok {
+		return ErrInvalidSignature
+	}
+	decoded, err := hex.DecodeString(hexSig)
+	if err != nil || !hmac.Equal(decoded, sign(payload, timestamp, secret)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// sign computes the v0 request signature
+func sign(payload []byte, timestamp, secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func ephemeral(text string) Response {
+	return Response{ResponseType: ResponseEphemeral, Text: text}
+}
+
+// HandleCommand runs a slash command against the accounts and snippets in store. Failures
+// are reported to the caller as ephemeral replies, since Slack shows anything else as a
+// generic error.
+func HandleCommand(ctx context.Context, store *models.Store, cmd *Command) Response {
+	name := cmd.Name
+	if name == "" {
+		name = "/snippy"
+	}
+	help := fmt.Sprintf("Usage: `%[1]s <shortcut>` posts your snippet here. `%[1]s link <code>` links your Snippy account (create a link code in Snippy), `%[1]s unlink` removes the link.", name)
+
+	action, arg, _ := strings.Cut(cmd.Text, " ")
+	arg = strings.TrimSpace(arg)
+	switch action {
+	case "", "help":
+		return ephemeral(help)
+
+	case "link":
+		if arg == "" {
+			return ephemeral(fmt.Sprintf("Usage: `%s link <code>`", name))
+		}
+		err := store.LinkIntegrationUser(ctx, models.IntegrationSlack, cmd.TeamID, cmd.UserID, arg)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ephemeral("That link code is invalid or has expired. Create a new one in Snippy.")
+		}
+		if err != nil {
+			log.Printf("Failed to link Slack user %s/%s: %v", cmd.TeamID, cmd.UserID, err)
+			return ephemeral("Something went wrong linking your account. Please try again.")
+		}
+		return ephemeral(fmt.Sprintf("Your Snippy account is linked. Try `%s <shortcut>`.", name))
+
+	case "unlink":
+		err := store.UnlinkIntegrationUser(ctx, models.IntegrationSlack, cmd.TeamID, cmd.UserID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ephemeral("Your Slack account isn't linked to Snippy.")
+		}
+		if err != nil {
+			log.Printf("Failed to unlink Slack user %s/%s: %v", cmd.TeamID, cmd.UserID, err)
+			return ephemeral("Something went wrong unlinking your account. Please try again.")
+		}
+		return ephemeral("Your Snippy account is unlinked.")
+	}
+
+	userID, err := store.GetIntegrationLinkedUser(ctx, models.IntegrationSlack, cmd.TeamID, cmd.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ephemeral(fmt.Sprintf("Link your Snippy account first: create a link code in Snippy, then run `%s link <code>`.", name))
+	}
+	if err != nil {
+		log.Printf("Failed to look up Slack user %s/%s: %v", cmd.TeamID, cmd.UserID, err)
+		return ephemeral("Something went wrong. Please try again.")
+	}
+
+	expansion, err := store.ExpandShortcut(ctx, userID, cmd.Text, true)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ephemeral(fmt.Sprintf("You have no snippet with the shortcut `%s`.", cmd.Text))
+	}
+	if err != nil {
+		log.Printf("Failed to expand shortcut for Slack user %s/%s: %v", cmd.TeamID, cmd.UserID, err)
+		return ephemeral("Something went wrong. Please try again.")
+	}
+	return Response{ResponseType: ResponseInChannel, Text: expansion.Content}
+}
@@ -0,0 +1,60 @@
+package slack
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func signature(payload []byte, secret string, at time.Time) (string, string) {
+	ts := fmt.Sprint(at.Unix())
+	return ts, "v0=" + hex.EncodeToString(sign(payload, ts, secret))
+}
+
+func TestParseCommand(t *testing.T) {
+	payload := []byte("team_id=T123&user_id=U456&command=%2Fsnippy&text=+sig+&response_url=https%3A%2F%2Fhooks.slack.com%2Fx")
+	now := time.Unix(1700000100, 0)
+	ts, sig := signature(payload, "slack_secret", now)
+
+	cmd, err := ParseCommand(payload, ts, sig, "slack_secret", now)
+	if err != nil {
+		t.Fatalf("ParseCommand: %v", err)
+	}
+	if cmd.TeamID != "T123" || cmd.UserID != "U456" || cmd.Name != "/snippy" || cmd.Text != "sig" {
+		t.Errorf("unexpected command %+v", cmd)
+	}
+
+	_, otherSig := signature(payload, "other_secret", now)
+	rejected := map[string]struct {
+		payload   []byte
+		timestamp string
+		signature string
+		now       time.Time
+	}{
+		"wrong secret":      {payload, ts, otherSig, now},
+		"modified payload":  {append([]byte("x"), payload...), ts, sig, now},
+		"replayed":          {payload, ts, sig, now.Add(10 * time.Minute)},
+		"missing timestamp": {payload, "", sig, now},
+		"missing version":   {payload, ts, strings.TrimPrefix(sig, "v0="), now},
+	}
+	for name, tc := range rejected {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseCommand(tc.payload, tc.timestamp, tc.signature, "slack_secret", tc.now); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("expected ErrInvalidSignature, got %v", err)
+			}
+		})
+	}
+}
+
+func TestHandleCommandHelp(t *testing.T) {
+	for _, text := range []string{"", "help", "link"} {
+		resp := HandleCommand(context.Background(), nil, &Command{TeamID: "T1", UserID: "U1", Name: "/snippy", Text: text})
+		if resp.ResponseType != ResponseEphemeral || !strings.Contains(resp.Text, "/snippy link") {
+			t.Errorf("HandleCommand(%q) = %+v, want ephemeral usage", text, resp)
+		}
+	}
+}
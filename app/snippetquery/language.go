@@ -0,0 +1,49 @@
+// Package snippetquery provides the text search configuration of the full-text search.
+package snippetquery
+
+import (
+	"os"
+	"strings"
+)
+
+// DefaultLanguage is the search language used when SEARCH_LANGUAGE is unset or invalid
+const DefaultLanguage = "english"
+
+// languages are the Postgres text search configurations SEARCH_LANGUAGE can select
+var languages = map[string]bool{
+	"simple": true, "arabic": true, "danish": true, "dutch": true, "english": true,
+	"finnish": true, "french": true, "german": true, "greek": true, "hungarian": true,
+	"indonesian": true, "irish": true, "italian": true, "lithuanian": true, "nepali": true,
+	"norwegian": true, "portuguese": true, "romanian": true, "russian": true, "serbian": true,
+	"spanish": true, "swedish": true, "tamil": true, "turkish": true,
+}
+
+// ValidLanguage reports whether language is the lowercase name of a supported search
+// language
+func ValidLanguage(language string) bool {
+	return languages[language]
+}
+
+// Language returns the search language from SEARCH_LANGUAGE (case-insensitive), or
+// DefaultLanguage if it is unset or unsupported
+func Language() string {
+	language := strings.ToLower(strings.TrimSpace(os.Getenv("SEARCH_LANGUAGE")))
+	if !ValidLanguage(language) {
+		return DefaultLanguage
+	}
+	return language
+}
+
+// SearchConfig returns the name of the text search configuration for language: a copy
+// of Postgres' own that strips accents before stemming, so "cancion" finds "canción"
+func SearchConfig(language string) string {
+	return "snippy_" + language
+}
+
+// Dictionary returns the dictionary language's words are reduced with after unaccent
+func Dictionary(language string) string {
+	if language == "simple" {
+		return "simple"
+	}
+	return language + "_stem"
+}
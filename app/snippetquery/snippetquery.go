@@ -0,0 +1,335 @@
+// Package snippetquery builds the SQL behind the snippet list, search and sync endpoints,
+// so their tag, search, shortcut and limit filters behave the same everywhere.
+package snippetquery
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MaxLimit caps the limit a client can ask for
+const MaxLimit = 100
+
+// ErrInvalidSort is returned by ParseSort for a sort or order outside the whitelist
+var ErrInvalidSort = errors.New("sort must be one of createdAt, updatedAt, label, shortcut and order one of asc, desc")
+
+// sortColumns maps each sort query value to the expression it orders by. Labels sort
+// case-insensitively, like they read in alphabetical lists.
+var sortColumns = map[string]string{
+	"createdAt": "created_at",
+	"updatedAt": "updated_at",
+	"label":     "lower(label)",
+	"shortcut":  "shortcut",
+}
+
+// fuzzyCondition matches labels containing a word similar to the search (pg_trgm's word
+// similarity over its threshold, 0.6 by default), served by the label trigram index
+const fuzzyCondition = `? <% label`
+
+// Options of the ts_headline highlights: matches are wrapped in <mark> tags, labels are
+// highlighted whole and content is cut down to a few fragments around the matches
+const (
+	labelHeadlineOptions   = "StartSel=<mark>, StopSel=</mark>, HighlightAll=true"
+	contentHeadlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxFragments=3, MaxWords=20, MinWords=5"
+)
+
+// Filter is the client-facing filtering of a snippet list. Empty fields don't filter.
+type Filter struct {
+	// Tag keeps snippets carrying the tag
+	Tag string
+	// Search is a full-text search on the label
+	Search string
+	// Shortcut keeps the snippet with exactly this shortcut
+	Shortcut string
+	// Sort is an ORDER BY clause from ParseSort; empty keeps the caller's default order
+	Sort string
+	// Limit caps the number of snippets; 0 returns them all
+	Limit int
+	// Offset skips the first matching snippets, for paging
+	Offset int
+	// Collection keeps snippets filed in the collection with this ID
+	Collection int64
+	// Fuzzy matches Search by trigram similarity instead of full text, so typos match
+	Fuzzy bool
+	// Favorites keeps only starred snippets
+	Favorites bool
+	// Language keeps snippets in this programming language
+	Language string
+	// IncludeArchived keeps archived snippets, which are otherwise left out
+	IncludeArchived bool
+	// IncludeShared also keeps snippets shared with the user whose snippets are listed.
+	// Filter leaves it to the caller, which knows the user.
+	IncludeShared bool
+}
+
+// FromQuery reads a Filter from the tag, search, shortcut, collection, favorites,
+// language, include, limit and cursor (or offset) query parameters. include is a comma-separated
+// list; include=archived keeps archived snippets. Invalid limits, collections and
+// favorites are ignored and larger limits capped at MaxLimit.
+func FromQuery(query func(key string) string) Filter {
+	offset := query("cursor")
+	if offset == "" {
+		offset = query("offset")
+	}
+	return Filter{
+		Tag:             query("tag"),
+		Search:          query("search"),
+		Shortcut:        query("shortcut"),
+		Limit:           ParseLimit(query("limit")),
+		Offset:          ParseOffset(offset),
+		Collection:      ParseID(query("collection")),
+		Favorites:       parseBool(query("favorites")),
+		Language:        strings.ToLower(strings.TrimSpace(query("language"))),
+		IncludeArchived: slices.Contains(strings.Split(query("include"), ","), "archived"),
+	}
+}
+
+// parseBool parses a boolean query parameter, returning false if it isn't one
+func parseBool(raw string) bool {
+	b, err := strconv.ParseBool(raw)
+	return err == nil && b
+}
+
+// ParseID parses an ID query parameter, returning 0 (no filter) if it isn't a positive
+// number
+func ParseID(raw string) int64 {
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id <= 0 {
+		return 0
+	}
+	return id
+}
+
+// ParseLimit parses a limit query parameter, returning 0 (no limit) if it isn't a
+// positive number and MaxLimit if it's larger
+func ParseLimit(raw string) int {
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit <= 0 {
+		return 0
+	}
+	return min(limit, MaxLimit)
+}
+
+// ParseOffset parses an offset or cursor query parameter, returning 0 if it isn't a
+// non-negative number
+func ParseOffset(raw string) int {
+	offset, err := strconv.Atoi(raw)
+	if err != nil || offset < 0 {
+		return 0
+	}
+	return offset
+}
+
+// ParseSort maps the sort and order query parameters to an ORDER BY clause, or "" if
+// both are empty so the caller's default order applies. An order without a sort orders
+// by createdAt. Dates sort newest first and text A to Z unless order says otherwise;
+// ties are broken by ID so pages don't overlap.
+func ParseSort(sort, order string) (string, error) {
+	if sort == "" && order == "" {
+		return "", nil
+	}
+	if sort == "" {
+		sort = "createdAt"
+	}
+	column, ok := sortColumns[sort]
+	if !ok {
+		return "", ErrInvalidSort
+	}
+	if order == "" {
+		order = "asc"
+		if sort == "createdAt" || sort == "updatedAt" {
+			order = "desc"
+		}
+	}
+	switch order {
+	case "asc":
+		return column + " ASC, id ASC", nil
+	case "desc":
+		return column + " DESC, id DESC", nil
+	}
+	return "", ErrInvalidSort
+}
+
+// Builder builds a query over the snippets table from conditions with positional ($n)
+// arguments. The zero value is ready to use.
+type Builder struct {
+	conds  []string
+	args   []interface{}
+	search string
+	config string
+	fuzzy  bool
+	order  string
+	limit  int
+	offset int
+}
+
+// New returns an empty Builder
+func New() *Builder {
+	return &Builder{}
+}
+
+// Arg adds an argument and returns its placeholder, for conditions that reuse it
+func (b *Builder) Arg(v interface{}) string {
+	b.args = append(b.args, v)
+	return "$" + strconv.Itoa(len(b.args))
+}
+
+// Where adds a condition. Each ? in cond is replaced by the placeholder of the next of args.
+func (b *Builder) Where(cond string, args ...interface{}) *Builder {
+	var sb strings.Builder
+	for _, arg := range args {
+		before, after, ok := strings.Cut(cond, "?")
+		if !ok {
+			break
+		}
+		sb.WriteString(before)
+		sb.WriteString(b.Arg(arg))
+		cond = after
+	}
+	sb.WriteString(cond)
+	b.conds = append(b.conds, sb.String())
+	return b
+}
+
+// Filter limits the query to non-deleted snippets matching f, leaving out archived ones
+// unless f.IncludeArchived is set
+func (b *Builder) Filter(f Filter) *Builder {
+	b.Where("is_deleted = false")
+	if !f.IncludeArchived {
+		b.Where("archived_at IS NULL")
+	}
+	if f.Tag != "" {
+		b.Where("? = ANY(tags)", f.Tag)
+	}
+	if f.Search != "" {
+		b.search, b.fuzzy = b.Arg(f.Search), f.Fuzzy
+		b.config = SearchConfig(Language())
+		if f.Fuzzy {
+			b.conds = append(b.conds, strings.Replace(fuzzyCondition, "?", b.search, 1))
+		} else {
+			// The full-text match on the label and description, served by the search index
+			b.conds = append(b.conds, b.searchVector()+" @@ "+b.tsquery())
+		}
+	}
+	if f.Shortcut != "" {
+		b.Where("shortcut = ?", f.Shortcut)
+	}
+	if f.Collection > 0 {
+		b.Where("collection_id = ?", f.Collection)
+	}
+	if f.Favorites {
+		b.Where("is_favorite")
+	}
+	if f.Language != "" {
+		b.Where("language = ?", f.Language)
+	}
+	if f.Limit > 0 {
+		b.limit = f.Limit
+	}
+	if f.Offset > 0 {
+		b.offset = f.Offset
+	}
+	return b
+}
+
+// OrderBy sets the ORDER BY clause
+func (b *Builder) OrderBy(order string) *Builder {
+	b.order = order
+	return b
+}
+
+// OrderBySearchRank orders by how well a snippet matches the filter's search, best first,
+// then newest first. Without a search it orders newest first.
+func (b *Builder) OrderBySearchRank() *Builder {
+	if b.search == "" {
+		return b.OrderBy("created_at DESC")
+	}
+	return b.OrderBy(b.SearchRank() + " DESC, created_at DESC")
+}
+
+// SearchRank returns the expression scoring how well a row matches the filter's search:
+// ts_rank over the label and description for full text, word similarity to the label for
+// fuzzy searches, 0 without a search
+func (b *Builder) SearchRank() string {
+	if b.search == "" {
+		return "0"
+	}
+	if b.fuzzy {
+		return "word_similarity(" + b.search + ", label)"
+	}
+	return "ts_rank(" + b.searchVector() + ", " + b.tsquery() + ")"
+}
+
+// SearchHeadlines returns the expressions of a row's label and content with the words of
+// the filter's full-text search marked, the content shortened to fragments around them.
+// Without a full-text search the label is returned as is and the content empty.
+func (b *Builder) SearchHeadlines() (label, content string) {
+	if b.search == "" || b.fuzzy {
+		return "label", "''"
+	}
+	return "ts_headline('" + b.config + "', label, " + b.tsquery() + ", '" + labelHeadlineOptions + "')",
+		"ts_headline('" + b.config + "', content, " + b.tsquery() + ", '" + contentHeadlineOptions + "')"
+}
+
+// searchVector is the tsvector of the label and description, matching the expression of
+// the search index
+func (b *Builder) searchVector() string {
+	return SearchVector(b.config)
+}
+
+// SearchVector returns the tsvector searches in config match: the label's words weighted
+// A and the description's B, so ts_rank favors matches in the label
+func SearchVector(config string) string {
+	return "(setweight(to_tsvector('" + config + "', coalesce(label, '')), 'A') || " +
+		"setweight(to_tsvector('" + config + "', coalesce(description, '')), 'B'))"
+}
+
+// tsquery is the filter's search as a tsquery
+func (b *Builder) tsquery() string {
+	return "plainto_tsquery('" + b.config + "', " + b.search + ")"
+}
+
+// Limit caps the number of rows; 0 removes the cap
+func (b *Builder) Limit(n int) *Builder {
+	b.limit = n
+	return b
+}
+
+// Offset skips the first n rows
+func (b *Builder) Offset(n int) *Builder {
+	b.offset = n
+	return b
+}
+
+// Conditions returns the conditions joined into a WHERE clause, without the keyword
+func (b *Builder) Conditions() string {
+	if len(b.conds) == 0 {
+		return "true"
+	}
+	return strings.Join(b.conds, " AND ")
+}
+
+// Args returns the arguments of the conditions added so far
+func (b *Builder) Args() []interface{} {
+	return b.args
+}
+
+// Select returns the query selecting columns from the matching snippets, with its arguments
+func (b *Builder) Select(columns string) (string, []interface{}) {
+	args := append([]interface{}{}, b.args...)
+	query := "SELECT " + columns + " FROM snippets WHERE " + b.Conditions()
+	if b.order != "" {
+		query += " ORDER BY " + b.order
+	}
+	if b.limit > 0 {
+		args = append(args, b.limit)
+		query += " LIMIT $" + strconv.Itoa(len(args))
+	}
+	if b.offset > 0 {
+		args = append(args, b.offset)
+		query += " OFFSET $" + strconv.Itoa(len(args))
+	}
+	return query, args
+}
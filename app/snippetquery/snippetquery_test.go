@@ -0,0 +1,191 @@
+package snippetquery
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLimit(t *testing.T) {
+	for raw, want := range map[string]int{"": 0, "abc": 0, "-5": 0, "0": 0, "20": 20, "100": 100, "500": MaxLimit} {
+		if got := ParseLimit(raw); got != want {
+			t.Errorf("ParseLimit(%q) = %d, want %d", raw, got, want)
+		}
+	}
+}
+
+func TestParseOffset(t *testing.T) {
+	for raw, want := range map[string]int{"": 0, "abc": 0, "-5": 0, "0": 0, "40": 40} {
+		if got := ParseOffset(raw); got != want {
+			t.Errorf("ParseOffset(%q) = %d, want %d", raw, got, want)
+		}
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		sort, order string
+		want        string
+		wantErr     bool
+	}{
+		{sort: "", order: "", want: ""},
+		{sort: "createdAt", order: "", want: "created_at DESC, id DESC"},
+		{sort: "updatedAt", order: "asc", want: "updated_at ASC, id ASC"},
+		{sort: "label", order: "", want: "lower(label) ASC, id ASC"},
+		{sort: "shortcut", order: "desc", want: "shortcut DESC, id DESC"},
+		{sort: "", order: "asc", want: "created_at ASC, id ASC"},
+		{sort: "content", order: "", wantErr: true},
+		{sort: "label", order: "random", wantErr: true},
+		{sort: "created_at; DROP TABLE snippets", order: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSort(tt.sort, tt.order)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseSort(%q, %q) = %q, %v; want %q, error %v", tt.sort, tt.order, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestFromQuery(t *testing.T) {
+	params := map[string]string{"tag": "go", "search": "http client", "shortcut": "/hc", "limit": "250", "offset": "20", "collection": "7", "favorites": "true", "language": " Go ", "include": "history,archived"}
+	got := FromQuery(func(key string) string { return params[key] })
+	want := Filter{Tag: "go", Search: "http client", Shortcut: "/hc", Limit: MaxLimit, Offset: 20, Collection: 7, Favorites: true, Language: "go", IncludeArchived: true}
+	if got != want {
+		t.Errorf("FromQuery = %+v, want %+v", got, want)
+	}
+
+	// A cursor from a previous page takes precedence over offset
+	params["cursor"] = "100"
+	if got := FromQuery(func(key string) string { return params[key] }); got.Offset != 100 {
+		t.Errorf("FromQuery with cursor: Offset = %d, want 100", got.Offset)
+	}
+
+	params["collection"] = "-1"
+	if got := FromQuery(func(key string) string { return params[key] }); got.Collection != 0 {
+		t.Errorf("FromQuery with invalid collection: Collection = %d, want 0", got.Collection)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		name      string
+		builder   *Builder
+		wantQuery string
+		wantArgs  []interface{}
+	}{
+		{
+			name:      "No filters",
+			builder:   New().Filter(Filter{}).OrderBy("created_at DESC"),
+			wantQuery: "SELECT id FROM snippets WHERE is_deleted = false AND archived_at IS NULL ORDER BY created_at DESC",
+			wantArgs:  []interface{}{},
+		},
+		{
+			name:      "User with every filter",
+			builder:   New().Where("user_id = ?", "u1").Filter(Filter{Tag: "go", Search: "client", Shortcut: "/hc", Collection: 7, Favorites: true, Language: "go", Limit: 10}).OrderBy("created_at DESC"),
+			wantQuery: "SELECT id FROM snippets WHERE user_id = $1 AND is_deleted = false AND archived_at IS NULL AND $2 = ANY(tags) AND (setweight(to_tsvector('snippy_english', coalesce(label, '')), 'A') || setweight(to_tsvector('snippy_english', coalesce(description, '')), 'B')) @@ plainto_tsquery('snippy_english', $3) AND shortcut = $4 AND collection_id = $5 AND is_favorite AND language = $6 ORDER BY created_at DESC LIMIT $7",
+			wantArgs:  []interface{}{"u1", "go", "client", "/hc", int64(7), "go", 10},
+		},
+		{
+			name:      "Ranked search page",
+			builder:   New().Where("user_id = ?", "u1").Filter(Filter{Search: "client"}).OrderBySearchRank().Limit(20).Offset(40),
+			wantQuery: "SELECT id FROM snippets WHERE user_id = $1 AND is_deleted = false AND archived_at IS NULL AND (setweight(to_tsvector('snippy_english', coalesce(label, '')), 'A') || setweight(to_tsvector('snippy_english', coalesce(description, '')), 'B')) @@ plainto_tsquery('snippy_english', $2) ORDER BY ts_rank((setweight(to_tsvector('snippy_english', coalesce(label, '')), 'A') || setweight(to_tsvector('snippy_english', coalesce(description, '')), 'B')), plainto_tsquery('snippy_english', $2)) DESC, created_at DESC LIMIT $3 OFFSET $4",
+			wantArgs:  []interface{}{"u1", "client", 20, 40},
+		},
+		{
+			name:      "Fuzzy search page",
+			builder:   New().Where("user_id = ?", "u1").Filter(Filter{Search: "consoel", Fuzzy: true}).OrderBySearchRank().Limit(20),
+			wantQuery: "SELECT id FROM snippets WHERE user_id = $1 AND is_deleted = false AND archived_at IS NULL AND $2 <% label ORDER BY word_similarity($2, label) DESC, created_at DESC LIMIT $3",
+			wantArgs:  []interface{}{"u1", "consoel", 20},
+		},
+		{
+			name:      "Including archived",
+			builder:   New().Where("user_id = ?", "u1").Filter(Filter{IncludeArchived: true}),
+			wantQuery: "SELECT id FROM snippets WHERE user_id = $1 AND is_deleted = false",
+			wantArgs:  []interface{}{"u1"},
+		},
+		{
+			name:      "Multiple placeholders",
+			builder:   New().Where("created_at BETWEEN ? AND ?", 1, 2),
+			wantQuery: "SELECT id FROM snippets WHERE created_at BETWEEN $1 AND $2",
+			wantArgs:  []interface{}{1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args := tt.builder.Select("id")
+			if query != tt.wantQuery {
+				t.Errorf("query =\n%s\nwant\n%s", query, tt.wantQuery)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestSearchColumns(t *testing.T) {
+	b := New().Filter(Filter{})
+	if rank := b.SearchRank(); rank != "0" {
+		t.Errorf("SearchRank without a search = %q, want 0", rank)
+	}
+	if label, content := b.SearchHeadlines(); label != "label" || content != "''" {
+		t.Errorf("SearchHeadlines without a search = %q, %q", label, content)
+	}
+
+	b = New().Where("user_id = ?", "u1").Filter(Filter{Search: "client"})
+	if rank := b.SearchRank(); !strings.HasPrefix(rank, "ts_rank(") || !strings.Contains(rank, "$2") {
+		t.Errorf("SearchRank = %q, want ts_rank of $2", rank)
+	}
+	label, content := b.SearchHeadlines()
+	if !strings.HasPrefix(label, "ts_headline('snippy_english', label, plainto_tsquery('snippy_english', $2)") || !strings.Contains(label, "HighlightAll=true") {
+		t.Errorf("label headline = %q", label)
+	}
+	if !strings.HasPrefix(content, "ts_headline('snippy_english', content, plainto_tsquery('snippy_english', $2)") || !strings.Contains(content, "MaxFragments=3") {
+		t.Errorf("content headline = %q", content)
+	}
+
+	b = New().Filter(Filter{Search: "clinet", Fuzzy: true})
+	if rank := b.SearchRank(); rank != "word_similarity($1, label)" {
+		t.Errorf("fuzzy SearchRank = %q", rank)
+	}
+	if label, _ := b.SearchHeadlines(); label != "label" {
+		t.Errorf("fuzzy label headline = %q, want the plain label", label)
+	}
+}
+
+func TestSelectKeepsConditionArgs(t *testing.T) {
+	b := New().Where("user_id = ?", "u1").Filter(Filter{Tag: "go"}).Limit(5)
+	b.Select("id")
+	if got := b.Args(); len(got) != 2 {
+		t.Errorf("Args() = %v; the limit should only be added to Select's arguments", got)
+	}
+	if got := New().Conditions(); got != "true" {
+		t.Errorf("Conditions() without conditions = %q, want true", got)
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	t.Setenv("SEARCH_LANGUAGE", "")
+	if got := Language(); got != DefaultLanguage {
+		t.Errorf("Language() unset = %q, want %q", got, DefaultLanguage)
+	}
+	t.Setenv("SEARCH_LANGUAGE", " Spanish ")
+	if got := Language(); got != "spanish" {
+		t.Errorf("Language() = %q, want spanish", got)
+	}
+	t.Setenv("SEARCH_LANGUAGE", "klingon'; DROP TABLE snippets")
+	if got := Language(); got != DefaultLanguage {
+		t.Errorf("Language() with an unsupported language = %q, want %q", got, DefaultLanguage)
+	}
+
+	if got := SearchConfig("spanish"); got != "snippy_spanish" {
+		t.Errorf("SearchConfig(spanish) = %q", got)
+	}
+	if got := Dictionary("spanish"); got != "spanish_stem" {
+		t.Errorf("Dictionary(spanish) = %q", got)
+	}
+	if got := Dictionary("simple"); got != "simple" {
+		t.Errorf("Dictionary(simple) = %q", got)
+	}
+}
@@ -0,0 +1,142 @@
+// Package systemd implements the parts of the systemd service protocol the server uses:
+// readiness and watchdog notifications (sd_notify) and socket activation.
+package systemd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Notification states
+const (
+	Ready    = "READY=1"
+	Stopping = "STOPPING=1"
+	Watchdog = "WATCHDOG=1"
+)
+
+// listenFDsStart is the first file descriptor passed by socket activation
+const listenFDsStart = 3
+
+// Notify sends state to the service manager. It reports false without error when the
+// process wasn't started by systemd with NOTIFY_SOCKET (Type=notify).
+func Notify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+	// A leading @ names a socket in the abstract namespace
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("systemd: connect to notify socket: %w", err)
+	}
+	defer func() {
+		if closeErr := conn.Close(); closeErr != nil {
+			log.Printf("error closing systemd notify socket: %v", closeErr)
+		}
+	}()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, fmt.Errorf("systemd: notify: %w", err)
+	}
+	return true, nil
+}
+
+// Listeners returns the sockets passed by systemd socket activation (LISTEN_FDS), in the
+// order of the .socket unit's Listen= lines; none when the process wasn't socket activated.
+// The LISTEN_* variables are cleared so child processes don't inherit them.
+func Listeners() ([]net.Listener, error) {
+	defer func() {
+		for _, name := range []string{"LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+			if err := os.Unsetenv(name); err != nil {
+				log.Printf("error clearing %s: %v", name, err)
+			}
+		}
+	}()
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, nil
+	}
+
+	listeners := make([]net.Listener, 0, count)
+	for fd := listenFDsStart; fd < listenFDsStart+count; fd++ {
+		syscall.CloseOnExec(fd)
+		file := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+		lis, err := net.FileListener(file)
+		// FileListener dups the descriptor, so the original is closed either way
+		if closeErr := file.Close(); closeErr != nil {
+			log.Printf("error closing activated socket %d: %v", fd, closeErr)
+		}
+		if err != nil {
+			for _, l := range listeners {
+				if closeErr := l.Close(); closeErr != nil {
+					log.Printf("error closing activated listener: %v", closeErr)
+				}
+			}
+			return nil, fmt.Errorf("systemd: activated socket %d: %w", fd, err)
+		}
+		listeners = append(listeners, lis)
+	}
+	return listeners, nil
+}
+
+// WatchdogInterval returns how often the watchdog must be notified (half of
+// WATCHDOG_USEC), or 0 if the service has no WatchdogSec=
+func WatchdogInterval() time.Duration {
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond / 2
+}
+
+// RunWatchdog notifies the watchdog until ctx is done, but only while healthy succeeds,
+// so systemd restarts the service once it stays unhealthy for WatchdogSec. It returns
+// at once when the service has no watchdog.
+func RunWatchdog(ctx context.Context, healthy func(ctx context.Context) error) {
+	interval := WatchdogInterval()
+	if interval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			checkCtx, cancel := context.WithTimeout(ctx, interval)
+			err := healthy(checkCtx)
+			cancel()
+			if err != nil {
+				if !errors.Is(err, context.Canceled) {
+					log.Printf("Health check failed, not notifying the systemd watchdog: %v", err)
+				}
+				continue
+			}
+			if _, err := Notify(Watchdog); err != nil {
+				log.Printf("Failed to notify the systemd watchdog: %v", err)
+			}
+		}
+	}
+}
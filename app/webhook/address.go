@@ -0,0 +1,97 @@
+// Package webhook keeps webhook requests away from the server's own network.
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// lookupTimeout bounds resolving a webhook host at registration
+const lookupTimeout = 5 * time.Second
+
+var (
+	// ErrInvalidURL is returned for webhook URLs that aren't absolute http(s) URLs
+	ErrInvalidURL = errors.New("must be an http or https URL")
+
+	// ErrForbiddenAddress is returned for webhook URLs pointing at the server's own or
+	// internal network, which users could otherwise probe through the delivery log
+	ErrForbiddenAddress = errors.New("must not point to a loopback, link-local, private or unspecified address")
+)
+
+// forbiddenIP reports whether ip is loopback, link-local, private or unspecified
+func forbiddenIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsPrivate() || ip.IsUnspecified()
+}
+
+// ValidateURL checks that raw is an absolute http(s) URL whose host isn't, and doesn't
+// resolve to, a forbidden address. A host that can't be resolved now is accepted, as
+// deliveries check the address they connect to again.
+func ValidateURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ErrInvalidURL
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrForbiddenAddress
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if forbiddenIP(ip) {
+			return ErrForbiddenAddress
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, lookupTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if forbiddenIP(addr.IP) {
+			return ErrForbiddenAddress
+		}
+	}
+	return nil
+}
+
+// checkDialAddress refuses connections to forbidden addresses. It runs once the host
+// is resolved, so a name rebound to an internal address after registration is caught.
+func checkDialAddress(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || forbiddenIP(ip) {
+		return fmt.Errorf("connecting to %s: %w", host, ErrForbiddenAddress)
+	}
+	return nil
+}
+
+// newClient returns an HTTP client for deliveries that only connects to public
+// addresses and doesn't follow redirects, which could lead anywhere. Proxies from the
+// environment are ignored, as the address checked would be the proxy's.
+func newClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: checkDialAddress}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
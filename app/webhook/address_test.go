@@ -0,0 +1,79 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+func TestValidateURL(t *testing.T) {
+	tests := []struct {
+		want error
+		url  string
+	}{
+		{url: "https://93.184.216.34/hooks/snippy"},
+		{url: "http://[2606:4700:4700::1111]:8080/hook"},
+		{url: "ftp://example.com/hook", want: ErrInvalidURL},
+		{url: "javascript:alert(1)", want: ErrInvalidURL},
+		{url: "/relative/path", want: ErrInvalidURL},
+		{url: "http://localhost:9000/hook", want: ErrForbiddenAddress},
+		{url: "http://api.localhost./hook", want: ErrForbiddenAddress},
+		{url: "http://127.0.0.1:5432/", want: ErrForbiddenAddress},
+		{url: "http://[::1]/hook", want: ErrForbiddenAddress},
+		{url: "http://169.254.169.254/latest/meta-data/", want: ErrForbiddenAddress},
+		{url: "http://10.0.0.7/hook", want: ErrForbiddenAddress},
+		{url: "http://172.16.3.4/hook", want: ErrForbiddenAddress},
+		{url: "http://192.168.1.1/hook", want: ErrForbiddenAddress},
+		{url: "http://[fd00::1]/hook", want: ErrForbiddenAddress},
+		{url: "http://0.0.0.0:8080/hook", want: ErrForbiddenAddress},
+		{url: "http://[::ffff:127.0.0.1]/hook", want: ErrForbiddenAddress},
+	}
+	for _, tt := range tests {
+		if err := ValidateURL(context.Background(), tt.url); !errors.Is(err, tt.want) {
+			t.Errorf("ValidateURL(%q) = %v, want %v", tt.url, err, tt.want)
+		}
+	}
+}
+
+func TestSendRefusesForbiddenAddresses(t *testing.T) {
+	hit := false
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hit = true
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer internal.Close()
+
+	job := NewJob(nil)
+	status, err := job.send(context.Background(), models.WebhookJob{ID: 1, URL: internal.URL, Secret: "s3cret", Payload: []byte(`{}`)})
+	if !errors.Is(err, ErrForbiddenAddress) || status != 0 || hit {
+		t.Errorf("send = %d, %v; want the loopback connection refused", status, err)
+	}
+}
+
+func TestClientDoesNotFollowRedirects(t *testing.T) {
+	followed := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/internal" {
+			followed = true
+			return
+		}
+		http.Redirect(w, r, "/internal", http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	// Use the test server's transport so loopback is reachable
+	client := newClient(requestTimeout)
+	client.Transport = server.Client().Transport
+	resp, err := client.Get(server.URL + "/hook")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusTemporaryRedirect || followed {
+		t.Errorf("status = %d, followed = %v; want the redirect returned, not followed", resp.StatusCode, followed)
+	}
+}
@@ -0,0 +1,224 @@
+// Package webhook delivers users' domain events to their registered webhooks, signing
+// each request and retrying failures with exponential backoff.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+const (
+	// pollInterval is how often the job looks for due deliveries
+	pollInterval = 5 * time.Second
+
+	// batchSize is how many deliveries are claimed at a time
+	batchSize = 20
+
+	// requestTimeout bounds each delivery request
+	requestTimeout = 10 * time.Second
+
+	// maxErrorLength caps the response body kept in the delivery log
+	maxErrorLength = 500
+)
+
+// Delivery headers
+const (
+	HeaderEvent     = "X-Snippy-Event"
+	HeaderDelivery  = "X-Snippy-Delivery"
+	HeaderSignature = "X-Snippy-Signature"
+)
+
+// Payload is the JSON body POSTed to webhooks
+type Payload struct {
+	CreatedAt time.Time       `json:"createdAt"`
+	Event     string          `json:"event"`
+	Data      json.RawMessage `json:"data"`
+	EventID   int64           `json:"eventId"`
+}
+
+// Sign returns the signature header value for body: "sha256=" and the hex HMAC-SHA256
+// of the body keyed with the webhook secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// eventOwner returns the user whose webhooks receive evt, or "" if none do
+func eventOwner(evt models.OutboxEvent) (string, error) {
+	switch evt.AggregateType {
+	case models.AggregateUser:
+		return evt.AggregateID, nil
+	case models.AggregateSnippet:
+		var payload struct {
+			UserID *string `json:"userId"`
+		}
+		if err := json.Unmarshal(evt.Payload, &payload); err != nil {
+			return "", fmt.Errorf("decode %s payload: %w", evt.EventType, err)
+		}
+		if payload.UserID == nil {
+			return "", nil
+		}
+		return *payload.UserID, nil
+	}
+	return "", nil
+}
+
+// Sink is an outbox sink that queues a delivery for each matching webhook. Sending
+// happens in Job so a slow endpoint doesn't hold up other sinks.
+type Sink struct {
+	store *models.Store
+}
+
+// NewSink creates a sink queueing deliveries in store
+func NewSink(store *models.Store) *Sink {
+	return &Sink{store: store}
+}
+
+// Name returns the sink name
+func (s *Sink) Name() string { return "webhooks" }
+
+// Deliver queues evt for the owner's subscribed webhooks
+func (s *Sink) Deliver(ctx context.Context, evt models.OutboxEvent) error {
+	userID, err := eventOwner(evt)
+	if err != nil || userID == "" {
+		return err
+	}
+
+	body, err := json.Marshal(Payload{
+		CreatedAt: evt.CreatedAt,
+		Event:     evt.EventType,
+		Data:      evt.Payload,
+		EventID:   evt.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.store.EnqueueWebhookDeliveries(ctx, userID, evt, models.WebhookFormatSnippy, body); err != nil {
+		return err
+	}
+
+	// Zapier REST hooks receive the same item their trigger returns when polled
+	trigger := models.ZapierTriggerForEvent(evt.EventType)
+	if trigger == "" {
+		return nil
+	}
+	var snippet models.Snippet
+	if err := json.Unmarshal(evt.Payload, &snippet); err != nil {
+		return fmt.Errorf("decode %s payload: %w", evt.EventType, err)
+	}
+	item, err := json.Marshal(models.NewZapierItem(trigger, &snippet))
+	if err != nil {
+		return err
+	}
+	_, err = s.store.EnqueueWebhookDeliveries(ctx, userID, evt, models.WebhookFormatZapier, item)
+	return err
+}
+
+// Job sends queued webhook deliveries
+type Job struct {
+	store  *models.Store
+	client *http.Client
+}
+
+// NewJob creates a job sending the deliveries queued in store
+func NewJob(store *models.Store) *Job {
+	return &Job{store: store, client: newClient(requestTimeout)}
+}
+
+// Run sends due deliveries until ctx is cancelled
+func (j *Job) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Keep draining while full batches come back
+			for {
+				claimed, err := j.RunOnce(ctx)
+				if err != nil {
+					log.Printf("Webhook delivery run failed: %v", err)
+					break
+				}
+				if claimed < batchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// RunOnce claims a batch of due deliveries and sends each, returning how many were claimed
+func (j *Job) RunOnce(ctx context.Context) (int, error) {
+	jobs, err := j.store.ClaimWebhookDeliveries(ctx, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, job := range jobs {
+		status, sendErr := j.send(ctx, job)
+		// Zapier answers 410 Gone once a Zap is turned off, which unsubscribes its hook
+		if job.Format == models.WebhookFormatZapier && status == http.StatusGone {
+			log.Printf("Zapier hook %d is gone, removing it", job.WebhookID)
+			if err := j.store.RemoveWebhook(ctx, job.WebhookID); err != nil {
+				log.Printf("Failed to remove Zapier hook %d: %v", job.WebhookID, err)
+			}
+			continue
+		}
+		if sendErr != nil {
+			log.Printf("Webhook delivery %d failed (attempt %d): %v", job.ID, job.Attempts, sendErr)
+			if err := j.store.MarkWebhookFailed(ctx, job, status, sendErr); err != nil {
+				log.Printf("Failed to record webhook failure for delivery %d: %v", job.ID, err)
+			}
+			continue
+		}
+		if err := j.store.MarkWebhookDelivered(ctx, job.ID, status); err != nil {
+			log.Printf("Failed to record webhook delivery %d: %v", job.ID, err)
+		}
+	}
+	return len(jobs), nil
+}
+
+// send POSTs one delivery. Any non-2xx response is a failure; the returned status is 0
+// when no response was received.
+func (j *Job) send(ctx context.Context, job models.WebhookJob) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.URL, bytes.NewReader(job.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Snippy-Webhook/1.0")
+	req.Header.Set(HeaderEvent, job.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(job.ID, 10))
+	req.Header.Set(HeaderSignature, Sign(job.Secret, job.Payload))
+
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLength))
+		return resp.StatusCode, fmt.Errorf("endpoint returned %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	// Drain so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	return resp.StatusCode, nil
+}
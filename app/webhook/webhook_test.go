@@ -0,0 +1,97 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"snippet.created"}`)
+	sig := Sign("secret", body)
+	if !strings.HasPrefix(sig, "sha256=") || len(sig) != len("sha256=")+64 {
+		t.Fatalf("unexpected signature %q", sig)
+	}
+	if !hmac.Equal([]byte(sig), []byte(Sign("secret", body))) {
+		t.Error("signature is not deterministic")
+	}
+	if sig == Sign("other", body) {
+		t.Error("signature should depend on the secret")
+	}
+}
+
+func TestEventOwner(t *testing.T) {
+	tests := []struct {
+		name string
+		evt  models.OutboxEvent
+		want string
+	}{
+		{
+			name: "Snippet event",
+			evt:  models.OutboxEvent{AggregateType: models.AggregateSnippet, Payload: []byte(`{"id":1,"userId":"user-1"}`)},
+			want: "user-1",
+		},
+		{
+			name: "User event",
+			evt:  models.OutboxEvent{AggregateType: models.AggregateUser, AggregateID: "user-2", Payload: []byte(`{}`)},
+			want: "user-2",
+		},
+		{
+			name: "Snippet without owner",
+			evt:  models.OutboxEvent{AggregateType: models.AggregateSnippet, Payload: []byte(`{"id":1}`)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := eventOwner(tt.evt)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("owner = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := eventOwner(models.OutboxEvent{AggregateType: models.AggregateSnippet, Payload: []byte(`not json`)}); err == nil {
+		t.Error("expected an error for an invalid payload")
+	}
+}
+
+func TestSend(t *testing.T) {
+	payload := []byte(`{"event":"snippet.updated"}`)
+
+	var gotSignature, gotEvent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSignature = r.Header.Get(HeaderSignature)
+		gotEvent = r.Header.Get(HeaderEvent)
+		if r.URL.Path == "/fail" {
+			http.Error(w, "boom", http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	// The test server listens on loopback, which deliveries otherwise refuse
+	job := NewJob(nil)
+	job.client = server.Client()
+	status, err := job.send(context.Background(), models.WebhookJob{ID: 9, URL: server.URL + "/ok", Secret: "s3cret", EventType: models.EventSnippetUpdated, Payload: payload})
+	if err != nil || status != http.StatusNoContent {
+		t.Fatalf("send = %d, %v; want 204, nil", status, err)
+	}
+	if gotSignature != Sign("s3cret", payload) || gotEvent != models.EventSnippetUpdated {
+		t.Errorf("unexpected headers: signature %q, event %q", gotSignature, gotEvent)
+	}
+
+	status, err = job.send(context.Background(), models.WebhookJob{ID: 10, URL: server.URL + "/fail", Secret: "s3cret", Payload: payload})
+	if err == nil || status != http.StatusBadGateway || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("send = %d, %v; want 502 with the response body", status, err)
+	}
+}
@@ -0,0 +1,98 @@
+// Package webui serves the web frontend embedded in the binary, so a self-hosted
+// deployment is a single process.
+package webui
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jheysaaz/snippy-backend/app/apierror"
+)
+
+// dist holds the frontend's build output, copied in by `make build-selfhosted`. Without
+// it only the .gitignore placeholder is embedded.
+//
+//go:embed all:dist
+var dist embed.FS
+
+// ErrNoAssets is returned when the frontend is enabled but the binary was built without it
+var ErrNoAssets = errors.New("webui: no frontend assets embedded, build with `make build-selfhosted`")
+
+// indexFile is the SPA entry point, served for every client-side route
+const indexFile = "index.html"
+
+// backendPrefixes are paths owned by the API; unknown ones get a JSON 404, never the SPA
+var backendPrefixes = []string{"/api/", "/swagger/", "/embed/", "/openapi.json"}
+
+// NewHandlerFromEnv returns the handler serving the embedded frontend, or nil if
+// SERVE_FRONTEND isn't "true"
+func NewHandlerFromEnv() (gin.HandlerFunc, error) {
+	if os.Getenv("SERVE_FRONTEND") != "true" {
+		return nil, nil
+	}
+	assets, err := fs.Sub(dist, "dist")
+	if err != nil {
+		return nil, err
+	}
+	return NewHandler(assets)
+}
+
+// NewHandler returns a handler, meant for unmatched routes, serving files from assets.
+// Paths that aren't files get index.html so the frontend's router can handle them, except
+// for paths that look like a missing asset (have an extension) and backend paths.
+func NewHandler(assets fs.FS) (gin.HandlerFunc, error) {
+	index, err := fs.ReadFile(assets, indexFile)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNoAssets
+		}
+		return nil, err
+	}
+	files := http.FileServer(http.FS(assets))
+
+	return func(c *gin.Context) {
+		urlPath := c.Request.URL.Path
+		if (c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead) || isBackendPath(urlPath) {
+			apierror.Respond(c, http.StatusNotFound, "Not found")
+			return
+		}
+
+		name := strings.TrimPrefix(path.Clean(urlPath), "/")
+		if name != "" && name != indexFile {
+			if info, statErr := fs.Stat(assets, name); statErr == nil && !info.IsDir() {
+				// Bundlers put content-hashed files under assets/, so they never change
+				if strings.HasPrefix(name, "assets/") {
+					c.Header("Cache-Control", "public, max-age=31536000, immutable")
+				} else {
+					c.Header("Cache-Control", "public, max-age=3600")
+				}
+				files.ServeHTTP(c.Writer, c.Request)
+				return
+			}
+			if path.Ext(name) != "" {
+				c.Status(http.StatusNotFound)
+				return
+			}
+		}
+
+		// index.html references the current asset hashes, so it's always revalidated
+		c.Header("Cache-Control", "no-cache")
+		c.Data(http.StatusOK, "text/html; charset=utf-8", index)
+	}, nil
+}
+
+// isBackendPath reports whether urlPath belongs to the API rather than the frontend
+func isBackendPath(urlPath string) bool {
+	for _, prefix := range backendPrefixes {
+		if strings.HasPrefix(urlPath, prefix) || urlPath == strings.TrimSuffix(prefix, "/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package webui
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler, err := NewHandler(fstest.MapFS{
+		"index.html":           {Data: []byte("<html>app</html>")},
+		"favicon.ico":          {Data: []byte("icon")},
+		"assets/index-abc.js":  {Data: []byte("console.log(1)")},
+		"assets/index-abc.css": {Data: []byte("body{}")},
+	})
+	if err != nil {
+		t.Fatalf("NewHandler: %v", err)
+	}
+
+	router := gin.New()
+	router.GET("/api/v1/health", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	router.NoRoute(handler)
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		wantCode  int
+		wantBody  string
+		wantCache string
+	}{
+		{name: "Root", path: "/", wantCode: http.StatusOK, wantBody: "<html>app</html>", wantCache: "no-cache"},
+		{name: "Client route", path: "/snippets/42/edit", wantCode: http.StatusOK, wantBody: "<html>app</html>", wantCache: "no-cache"},
+		{name: "Hashed asset", path: "/assets/index-abc.js", wantCode: http.StatusOK, wantBody: "console.log(1)", wantCache: "public, max-age=31536000, immutable"},
+		{name: "Other file", path: "/favicon.ico", wantCode: http.StatusOK, wantBody: "icon", wantCache: "public, max-age=3600"},
+		{name: "Missing asset", path: "/assets/index-old.js", wantCode: http.StatusNotFound},
+		{name: "Unknown API route", path: "/api/v1/nope", wantCode: http.StatusNotFound},
+		{name: "API route", path: "/api/v1/health", wantCode: http.StatusOK, wantBody: "ok"},
+		{name: "POST", method: http.MethodPost, path: "/snippets", wantCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(method, tt.path, nil))
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+			if got := w.Header().Get("Cache-Control"); got != tt.wantCache {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.wantCache)
+			}
+		})
+	}
+}
+
+func TestNewHandlerWithoutAssets(t *testing.T) {
+	if _, err := NewHandler(fstest.MapFS{".gitignore": {Data: []byte("*")}}); !errors.Is(err, ErrNoAssets) {
+		t.Errorf("NewHandler(no index.html) = %v, want ErrNoAssets", err)
+	}
+}
@@ -0,0 +1,52 @@
+// Command snippy-backup decrypts a backup archive produced by the scheduled backup job.
+//
+//	BACKUP_ENCRYPTION_KEY=... snippy-backup -in snippy-20260101T030000Z.zip.enc -out backup.zip
+//
+// The result is a zip with one tables/<name>.ndjson file per table, one JSON row per line.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jheysaaz/snippy-backend/app/backup"
+)
+
+func main() {
+	in := flag.String("in", "", "encrypted backup file")
+	out := flag.String("out", "", "decrypted zip to write")
+	flag.Parse()
+
+	if err := run(*in, *out); err != nil {
+		fmt.Fprintln(os.Stderr, "snippy-backup:", err)
+		os.Exit(1)
+	}
+}
+
+func run(in, out string) error {
+	if in == "" || out == "" {
+		return fmt.Errorf("both -in and -out are required")
+	}
+	key, err := backup.ParseKey(os.Getenv("BACKUP_ENCRYPTION_KEY"))
+	if err != nil {
+		return err
+	}
+
+	src, err := os.Open(in) // #nosec G304 -- path supplied by the operator
+	if err != nil {
+		return err
+	}
+	defer func() { _ = src.Close() }()
+
+	dst, err := os.OpenFile(out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600) // #nosec G304 -- path supplied by the operator
+	if err != nil {
+		return err
+	}
+	if err := backup.Decrypt(dst, src, key); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(out)
+		return err
+	}
+	return dst.Close()
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+)
+
+// errNotLoggedIn is returned by authenticated calls before login
+var errNotLoggedIn = errors.New("not logged in, run `snippy login` first")
+
+// apiError is an error response from the API
+type apiError struct {
+	Message string `json:"error"`
+	Status  int    `json:"-"`
+}
+
+func (e *apiError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("server returned %d %s", e.Status, http.StatusText(e.Status))
+	}
+	return fmt.Sprintf("%s (%d)", e.Message, e.Status)
+}
+
+// client calls the Snippy API, refreshing the access token when it expires
+type client struct {
+	http *http.Client
+	cfg  *config
+	// saveConfig persists rotated tokens
+	saveConfig func(*config) error
+}
+
+func newClient(cfg *config, saveConfig func(*config) error) *client {
+	return &client{
+		http:       &http.Client{Timeout: 30 * time.Second},
+		cfg:        cfg,
+		saveConfig: saveConfig,
+	}
+}
+
+// login exchanges credentials for tokens and stores them in the config
+func (c *client) login(ctx context.Context, login, password string) (*models.User, error) {
+	var resp models.LoginResponse
+	httpResp, err := c.do(ctx, http.MethodPost, "/auth/login", models.LoginRequest{Login: login, Password: password}, &resp, false)
+	if err != nil {
+		return nil, err
+	}
+
+	c.cfg.AccessToken = resp.AccessToken
+	c.cfg.ExpiresAt = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	c.cfg.RefreshToken = refreshTokenCookie(httpResp)
+	if resp.User != nil {
+		c.cfg.Username = resp.User.Username
+	}
+	return resp.User, c.saveConfig(c.cfg)
+}
+
+// logout revokes the refresh token and forgets the stored tokens
+func (c *client) logout(ctx context.Context) error {
+	if c.cfg.RefreshToken != "" {
+		if _, err := c.do(ctx, http.MethodPost, "/auth/logout", models.RefreshTokenRequest{RefreshToken: c.cfg.RefreshToken}, nil, false); err != nil {
+			return err
+		}
+	}
+
+	c.cfg.AccessToken = ""
+	c.cfg.RefreshToken = ""
+	c.cfg.ExpiresAt = time.Time{}
+	return c.saveConfig(c.cfg)
+}
+
+// listSnippets returns the user's snippets matching the optional filters
+func (c *client) listSnippets(ctx context.Context, tag, search, shortcut string, limit int) ([]models.Snippet, error) {
+	query := url.Values{}
+	if tag != "" {
+		query.Set("tag", tag)
+	}
+	if search != "" {
+		query.Set("search", search)
+	}
+	if shortcut != "" {
+		query.Set("shortcut", shortcut)
+	}
+	if limit > 0 {
+		query.Set("limit", fmt.Sprint(limit))
+	}
+
+	path := "/snippets/"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var resp struct {
+		Items []models.Snippet `json:"items"`
+	}
+	if _, err := c.authorized(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Items, nil
+}
+
+// createSnippet pushes a new snippet
+func (c *client) createSnippet(ctx context.Context, req models.CreateSnippetRequest) (*models.Snippet, error) {
+	var snippet models.Snippet
+	if _, err := c.authorized(ctx, http.MethodPost, "/snippets/", req, &snippet); err != nil {
+		return nil, err
+	}
+	return &snippet, nil
+}
+
+// authorized sends an authenticated request, refreshing the token once if it was rejected
+func (c *client) authorized(ctx context.Context, method, path string, body, out interface{}) (*http.Response, error) {
+	if c.cfg.AccessToken == "" {
+		return nil, errNotLoggedIn
+	}
+	if time.Now().After(c.cfg.ExpiresAt) {
+		if err := c.refresh(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := c.do(ctx, method, path, body, out, true)
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusUnauthorized && c.cfg.RefreshToken != "" {
+		if err := c.refresh(ctx); err != nil {
+			return nil, err
+		}
+		return c.do(ctx, method, path, body, out, true)
+	}
+	return resp, err
+}
+
+// refresh obtains a new access token; the server rotates the refresh token too
+func (c *client) refresh(ctx context.Context) error {
+	if c.cfg.RefreshToken == "" {
+		return errNotLoggedIn
+	}
+
+	var resp models.RefreshTokenResponse
+	httpResp, err := c.do(ctx, http.MethodPost, "/auth/refresh", models.RefreshTokenRequest{RefreshToken: c.cfg.RefreshToken}, &resp, false)
+	if err != nil {
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && apiErr.Status == http.StatusUnauthorized {
+			return fmt.Errorf("session expired, run `snippy login` again: %w", err)
+		}
+		return err
+	}
+
+	c.cfg.AccessToken = resp.AccessToken
+	c.cfg.ExpiresAt = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	if token := refreshTokenCookie(httpResp); token != "" {
+		c.cfg.RefreshToken = token
+	}
+	return c.saveConfig(c.cfg)
+}
+
+// do sends a JSON request and decodes a JSON response into out
+func (c *client) do(ctx context.Context, method, path string, body, out interface{}, withToken bool) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.cfg.Server, "/")+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "snippy-cli")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if withToken {
+		req.Header.Set("Authorization", "Bearer "+c.cfg.AccessToken)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := &apiError{Status: resp.StatusCode}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(apiErr)
+		return resp, apiErr
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp, fmt.Errorf("decode response: %w", err)
+		}
+	}
+	return resp, nil
+}
+
+// refreshTokenCookie returns the refresh token the server set as a cookie, if any
+func refreshTokenCookie(resp *http.Response) string {
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "refresh_token" {
+			return cookie.Value
+		}
+	}
+	return ""
+}
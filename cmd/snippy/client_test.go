@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConfigRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snippy", "config.json")
+
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("load missing config: %v", err)
+	}
+	if cfg.Server != defaultServer {
+		t.Errorf("Server = %q, want %q", cfg.Server, defaultServer)
+	}
+
+	cfg.AccessToken = "access"
+	cfg.RefreshToken = "refresh"
+	if err := cfg.save(path); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	loaded, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if loaded.AccessToken != "access" || loaded.RefreshToken != "refresh" {
+		t.Errorf("unexpected config %+v", loaded)
+	}
+}
+
+func TestClientRefreshesExpiredToken(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/auth/refresh", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			RefreshToken string `json:"refreshToken"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.RefreshToken != "old-refresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "refresh_token", Value: "new-refresh"})
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"accessToken": "fresh", "expiresIn": 900})
+	})
+	mux.HandleFunc("/snippets/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"Invalid or expired token"}`))
+			return
+		}
+		if r.URL.Query().Get("shortcut") != "sig" {
+			t.Errorf("shortcut = %q, want sig", r.URL.Query().Get("shortcut"))
+		}
+		_, _ = w.Write([]byte(`{"items":[{"id":1,"shortcut":"sig","content":"Best,\nAda"}],"count":1}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	saved := 0
+	cfg := &config{Server: server.URL, AccessToken: "stale", RefreshToken: "old-refresh", ExpiresAt: time.Now().Add(time.Hour)}
+	api := newClient(cfg, func(*config) error { saved++; return nil })
+
+	snippets, err := api.listSnippets(t.Context(), "", "", "sig", 1)
+	if err != nil {
+		t.Fatalf("listSnippets: %v", err)
+	}
+	if len(snippets) != 1 || snippets[0].Content != "Best,\nAda" {
+		t.Errorf("unexpected snippets %+v", snippets)
+	}
+	if cfg.AccessToken != "fresh" || cfg.RefreshToken != "new-refresh" || saved != 1 {
+		t.Errorf("tokens not rotated: %+v (saved %d)", cfg, saved)
+	}
+}
+
+func TestClientNotLoggedIn(t *testing.T) {
+	api := newClient(&config{Server: "http://127.0.0.1:0"}, func(*config) error { return nil })
+	if _, err := api.listSnippets(t.Context(), "", "", "", 0); err != errNotLoggedIn {
+		t.Errorf("err = %v, want errNotLoggedIn", err)
+	}
+}
+
+func TestPrintSnippets(t *testing.T) {
+	var out bytes.Buffer
+	if err := printSnippets(&out, nil); err != nil {
+		t.Fatalf("printSnippets: %v", err)
+	}
+	if out.String() != "ID  SHORTCUT  LABEL  TAGS\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+}
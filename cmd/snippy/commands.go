@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// newRootCmd builds the snippy command tree. The client is created once flags are parsed.
+func newRootCmd() *cobra.Command {
+	var server string
+	var api *client
+
+	root := &cobra.Command{
+		Use:           "snippy",
+		Short:         "Snippy command-line client",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			path, err := configPath()
+			if err != nil {
+				return err
+			}
+			cfg, err := loadConfig(path)
+			if err != nil {
+				return err
+			}
+			if server != "" {
+				cfg.Server = server
+			}
+			api = newClient(cfg, func(cfg *config) error { return cfg.save(path) })
+			return nil
+		},
+	}
+	root.PersistentFlags().StringVar(&server, "server", os.Getenv("SNIPPY_SERVER"), "API base URL (default "+defaultServer+", env SNIPPY_SERVER)")
+
+	clientFn := func() *client { return api }
+	root.AddCommand(
+		newLoginCmd(clientFn),
+		newLogoutCmd(clientFn),
+		newListCmd(clientFn),
+		newSearchCmd(clientFn),
+		newCopyCmd(clientFn),
+		newPushCmd(clientFn),
+	)
+	return root
+}
+
+func newLoginCmd(api func() *client) *cobra.Command {
+	var passwordStdin bool
+
+	cmd := &cobra.Command{
+		Use:   "login [username or email]",
+		Short: "Log in and store tokens for later commands",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			in := bufio.NewReader(cmd.InOrStdin())
+
+			login := ""
+			if len(args) == 1 {
+				login = args[0]
+			} else {
+				fmt.Fprint(cmd.ErrOrStderr(), "Username or email: ")
+				line, err := in.ReadString('\n')
+				if err != nil && !errors.Is(err, io.EOF) {
+					return err
+				}
+				login = strings.TrimSpace(line)
+			}
+
+			password, err := readPassword(cmd, in, passwordStdin)
+			if err != nil {
+				return err
+			}
+			if login == "" || password == "" {
+				return errors.New("username and password are required")
+			}
+
+			user, err := api().login(cmd.Context(), login, password)
+			if err != nil {
+				return err
+			}
+			if user != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Logged in as %s\n", user.Username)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&passwordStdin, "password-stdin", false, "Read the password from stdin")
+	return cmd
+}
+
+// readPassword prompts without echo on a terminal, otherwise reads a line from stdin
+func readPassword(cmd *cobra.Command, in *bufio.Reader, fromStdin bool) (string, error) {
+	if f, ok := cmd.InOrStdin().(*os.File); ok && !fromStdin && term.IsTerminal(int(f.Fd())) {
+		fmt.Fprint(cmd.ErrOrStderr(), "Password: ")
+		password, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(cmd.ErrOrStderr())
+		return string(password), err
+	}
+
+	line, err := in.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func newLogoutCmd(api func() *client) *cobra.Command {
+	return &cobra.Command{
+		Use:   "logout",
+		Short: "Revoke the stored session and forget its tokens",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return api().logout(cmd.Context())
+		},
+	}
+}
+
+func newListCmd(api func() *client) *cobra.Command {
+	var tag string
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List your snippets, newest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			snippets, err := api().listSnippets(cmd.Context(), tag, "", "", limit)
+			if err != nil {
+				return err
+			}
+			return printSnippets(cmd.OutOrStdout(), snippets)
+		},
+	}
+	cmd.Flags().StringVar(&tag, "tag", "", "Only show snippets with this tag")
+	cmd.Flags().IntVar(&limit, "limit", 50, "Maximum number of snippets (max 100)")
+	return cmd
+}
+
+func newSearchCmd(api func() *client) *cobra.Command {
+	var tag string
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "search <query>",
+		Short: "Search your snippets by label",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			snippets, err := api().listSnippets(cmd.Context(), tag, strings.Join(args, " "), "", limit)
+			if err != nil {
+				return err
+			}
+			return printSnippets(cmd.OutOrStdout(), snippets)
+		},
+	}
+	cmd.Flags().StringVar(&tag, "tag", "", "Only show snippets with this tag")
+	cmd.Flags().IntVar(&limit, "limit", 50, "Maximum number of snippets (max 100)")
+	return cmd
+}
+
+func newCopyCmd(api func() *client) *cobra.Command {
+	return &cobra.Command{
+		Use:   "copy <shortcut>",
+		Short: "Print a snippet's content to stdout, e.g. snippy copy sig | pbcopy",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			snippets, err := api().listSnippets(cmd.Context(), "", "", args[0], 1)
+			if err != nil {
+				return err
+			}
+			if len(snippets) == 0 {
+				return fmt.Errorf("no snippet with shortcut %q", args[0])
+			}
+			_, err = io.WriteString(cmd.OutOrStdout(), snippets[0].Content)
+			return err
+		},
+	}
+}
+
+func newPushCmd(api func() *client) *cobra.Command {
+	var req models.CreateSnippetRequest
+	var file string
+	var public bool
+
+	cmd := &cobra.Command{
+		Use:   "push --label <label> --shortcut <shortcut> [--file path]",
+		Short: "Create a snippet from a file or stdin",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			var content []byte
+			var err error
+			if file == "" || file == "-" {
+				content, err = io.ReadAll(cmd.InOrStdin())
+			} else {
+				content, err = os.ReadFile(file)
+			}
+			if err != nil {
+				return fmt.Errorf("read content: %w", err)
+			}
+			if len(content) == 0 {
+				return errors.New("snippet content is empty")
+			}
+
+			req.Content = string(content)
+			if public {
+				req.Visibility = models.VisibilityPublic
+			}
+			snippet, err := api().createSnippet(cmd.Context(), req)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "Created snippet %d (%s)\n", snippet.ID, snippet.Shortcut)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&req.Label, "label", "", "Snippet label")
+	cmd.Flags().StringVar(&req.Shortcut, "shortcut", "", "Snippet shortcut")
+	cmd.Flags().StringSliceVar(&req.Tags, "tag", nil, "Tag (repeatable or comma-separated)")
+	cmd.Flags().StringVarP(&file, "file", "f", "", "Read content from this file instead of stdin")
+	cmd.Flags().BoolVar(&public, "public", false, "Show the snippet on your public profile")
+	_ = cmd.MarkFlagRequired("label")
+	_ = cmd.MarkFlagRequired("shortcut")
+	return cmd
+}
+
+// printSnippets writes snippets as an aligned table
+func printSnippets(out io.Writer, snippets []models.Snippet) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSHORTCUT\tLABEL\tTAGS")
+	for _, s := range snippets {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.ID, s.Shortcut, s.Label, strings.Join(s.Tags, ","))
+	}
+	return w.Flush()
+}
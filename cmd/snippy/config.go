@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// defaultServer is used until login saves another server URL
+const defaultServer = "http://localhost:8080/api/v1"
+
+// config is persisted between runs, readable only by the current user
+type config struct {
+	ExpiresAt    time.Time `json:"expiresAt"`
+	Server       string    `json:"server"`
+	Username     string    `json:"username,omitempty"`
+	AccessToken  string    `json:"accessToken,omitempty"`
+	RefreshToken string    `json:"refreshToken,omitempty"`
+}
+
+// configPath returns the config file location, overridable with SNIPPY_CONFIG
+func configPath() (string, error) {
+	if path := os.Getenv("SNIPPY_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("locate config directory: %w", err)
+	}
+	return filepath.Join(dir, "snippy", "config.json"), nil
+}
+
+// loadConfig reads the config file; a missing file yields defaults
+func loadConfig(path string) (*config, error) {
+	cfg := &config{Server: defaultServer}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	if cfg.Server == "" {
+		cfg.Server = defaultServer
+	}
+	return cfg, nil
+}
+
+// save writes the config file, creating its directory if needed
+func (cfg *config) save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create config directory: %w", err)
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+// Command snippy is a terminal client for the Snippy API. It logs in, lists and
+// searches snippets, prints a snippet's content by shortcut, and pushes new snippets.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	if err := newRootCmd().Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
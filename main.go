@@ -2,14 +2,40 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/jheysaaz/snippy-backend/app/auth"
+	"github.com/jheysaaz/snippy-backend/app/autotls"
+	"github.com/jheysaaz/snippy-backend/app/backup"
+	"github.com/jheysaaz/snippy-backend/app/broadcast"
+	"github.com/jheysaaz/snippy-backend/app/cache"
+	"github.com/jheysaaz/snippy-backend/app/config"
 	"github.com/jheysaaz/snippy-backend/app/database"
+	"github.com/jheysaaz/snippy-backend/app/digest"
+	"github.com/jheysaaz/snippy-backend/app/events"
+	"github.com/jheysaaz/snippy-backend/app/export"
+	"github.com/jheysaaz/snippy-backend/app/gitsync"
 	"github.com/jheysaaz/snippy-backend/app/handlers"
+	"github.com/jheysaaz/snippy-backend/app/health"
+	"github.com/jheysaaz/snippy-backend/app/httpserver"
+	"github.com/jheysaaz/snippy-backend/app/mailer"
 	"github.com/jheysaaz/snippy-backend/app/middleware"
+	"github.com/jheysaaz/snippy-backend/app/models"
+	"github.com/jheysaaz/snippy-backend/app/outbox"
+	"github.com/jheysaaz/snippy-backend/app/push"
+	"github.com/jheysaaz/snippy-backend/app/realtime"
+	"github.com/jheysaaz/snippy-backend/app/rpc"
+	"github.com/jheysaaz/snippy-backend/app/search"
+	"github.com/jheysaaz/snippy-backend/app/systemd"
+	"github.com/jheysaaz/snippy-backend/app/webhook"
+	"github.com/jheysaaz/snippy-backend/app/webui"
 	_ "github.com/jheysaaz/snippy-backend/docs"
 
 	"github.com/gin-gonic/gin"
@@ -27,6 +53,16 @@ import (
 // @name Authorization
 // @description Enter "Bearer {token}"
 func main() {
+	// snippy-api healthcheck probes a running server, for container health checks
+	if len(os.Args) > 1 && os.Args[1] == "healthcheck" {
+		os.Exit(healthcheck(os.Args[2:]))
+	}
+
+	// Settings from CONFIG_FILE override the environment; reloadable ones are re-read on SIGHUP
+	if err := config.LoadFile(); err != nil {
+		log.Fatal("Failed to load configuration file:", err)
+	}
+
 	// Initialize database
 	if err := database.Init(); err != nil {
 		log.Fatal("Failed to initialize database:", err)
@@ -38,8 +74,127 @@ func main() {
 		// Continue without prepared statements (fallback to regular queries)
 	}
 
-	// Start data retention cleanup job (runs every 24 hours)
-	go startDataRetentionCleanup()
+	// Handlers, the gRPC services and the background jobs all query through one store
+	store := models.NewStore(database.DB)
+
+	// Dependencies reported by the health endpoint
+	healthChecks := []health.Check{{Name: "database", Check: database.DB.PingContext, Required: true}}
+
+	// Cache user, role and snippet lookups in Redis when REDIS_URL is set
+	cacheCtx, cancelCache := context.WithTimeout(context.Background(), 5*time.Second)
+	if redisCache, err := cache.NewRedisFromEnv(cacheCtx); err != nil {
+		log.Printf("Warning: Redis cache disabled: %v", err)
+	} else if redisCache != nil {
+		cache.SetStore(redisCache)
+		healthChecks = append(healthChecks, health.Check{Name: "redis", Check: redisCache.Ping})
+		defer func() {
+			if err := cache.Close(); err != nil {
+				log.Printf("error closing cache: %v", err)
+			}
+		}()
+	}
+	cancelCache()
+
+	// Stop gracefully on SIGINT and SIGTERM: background jobs are cancelled and the HTTP
+	// and gRPC servers finish in-flight requests
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Record session activity on a bounded pool of workers
+	go auth.RunActivityWorkers(ctx, store)
+
+	// Start index maintenance job (runs ANALYZE on hot tables every 6 hours)
+	go startIndexMaintenance(ctx)
+
+	// Start outbox dispatcher for reliable domain event delivery
+	dispatcher := outbox.NewDispatcher(store, outbox.LogSink{}, webhook.NewSink(store))
+
+	// Send queued webhook deliveries, retrying failures with exponential backoff
+	go webhook.NewJob(store).Run(ctx)
+
+	// Mirror snippets to users' Git repositories after changes and on their schedules
+	dispatcher.Register(gitsync.NewSink(store))
+	go gitsync.NewJob(store).Run(ctx)
+
+	// Push snippet changes to users' other mobile devices when FCM or APNs is configured
+	if pushSink, err := push.NewSinkFromEnv(store); err != nil {
+		log.Printf("Warning: push notifications disabled: %v", err)
+	} else if pushSink != nil {
+		dispatcher.Register(pushSink)
+	}
+
+	// Publish domain events to NATS or Kafka when EVENTS_BROKER is set
+	if eventSink, err := events.NewSinkFromEnv(); err != nil {
+		log.Printf("Warning: event publishing disabled: %v", err)
+	} else if eventSink != nil {
+		dispatcher.Register(eventSink)
+		defer func() {
+			if err := eventSink.Close(); err != nil {
+				log.Printf("error closing event broker connection: %v", err)
+			}
+		}()
+	}
+
+	// Keep Meilisearch or Elasticsearch in sync with snippet changes when SEARCH_BACKEND is set
+	if searchSink, err := search.NewSinkFromEnv(store); err != nil {
+		log.Printf("Warning: external search disabled: %v", err)
+	} else if searchSink != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := searchSink.Backend().EnsureIndex(ctx); err != nil {
+			log.Printf("Warning: failed to set up %s index: %v", searchSink.Backend().Name(), err)
+		}
+		cancel()
+		dispatcher.Register(searchSink)
+	}
+
+	// Push snippet changes to the owner's connected clients (the event stream and gRPC Watch)
+	// on every instance: the dispatcher announces them with Postgres NOTIFY and each
+	// instance's listener feeds its hub. Streams end on shutdown.
+	hub := realtime.NewHub()
+	dispatcher.Register(realtime.NewNotifier(store))
+	go realtime.NewListener(store, hub).Run(ctx, database.URL())
+	go func() {
+		<-ctx.Done()
+		hub.Close()
+	}()
+
+	// Organizations are named by X-Organization (x-organization over gRPC) or the subdomain
+	tenants := middleware.NewTenantResolver(store, os.Getenv("TENANT_BASE_DOMAIN"))
+
+	// Serve the gRPC API alongside HTTP when GRPC_PORT is set, stopping it gracefully on
+	// shutdown
+	grpcStopped := make(chan struct{})
+	if grpcPort := os.Getenv("GRPC_PORT"); grpcPort != "" {
+		go func() {
+			defer close(grpcStopped)
+			startGRPCServer(ctx, grpcPort, store, hub, tenants)
+		}()
+	} else {
+		close(grpcStopped)
+	}
+	go dispatcher.Run(ctx)
+
+	// Configure outgoing email (SMTP_HOST unset or MAIL_MODE=log only logs messages)
+	mail := mailer.New(mailer.LoadConfig())
+	if smtpMailer, ok := mail.(*mailer.SMTPMailer); ok {
+		healthChecks = append(healthChecks, health.Check{Name: "mailer", Check: smtpMailer.Ping})
+	}
+
+	// Start data retention cleanup job (runs every 24 hours), archiving deleted accounts before purging them
+	go startDataRetentionCleanup(ctx, export.NewExporter(export.LoadConfig(), store, mail))
+
+	// Start weekly digest job (checks hourly for users whose digest is due in their time zone)
+	go digest.NewJob(store, mail).Run(ctx)
+
+	// Start broadcast email delivery (throttled to BROADCAST_RATE_PER_MINUTE)
+	go broadcast.NewJob(store, mail, broadcast.RatePerMinute()).Run(ctx)
+
+	// Start encrypted backups to object storage when BACKUP_BUCKET is set
+	if backupJob, err := backup.NewJobFromEnv(store); err != nil {
+		log.Printf("Warning: scheduled backups disabled: %v", err)
+	} else if backupJob != nil {
+		go backupJob.Run(ctx)
+	}
 
 	// Start token cleanup job (optional background task)
 	// go models.StartTokenCleanupJob()
@@ -52,6 +207,8 @@ func main() {
 			}
 		}
 	}()
+	// Let in-flight gRPC calls finish before the database is closed
+	defer func() { <-grpcStopped }()
 
 	log.Println("Starting Snippy API server...")
 
@@ -64,106 +221,119 @@ func main() {
 	// Initialize Gin router
 	r := gin.New()
 
-	// Add middleware
-	r.Use(gin.Logger())
+	// Add middleware, logging requests at LOG_LEVEL (debug, info, warn, error)
+	requestLogger := middleware.NewRequestLogger(os.Getenv("LOG_LEVEL"))
+	r.Use(middleware.RequestLogMiddleware(requestLogger))
 	r.Use(gin.Recovery())
 
 	// CORS middleware with environment-specific origins
-	corsOrigins := os.Getenv("CORS_ALLOWED_ORIGINS")
-	if corsOrigins == "" {
-		corsOrigins = "http://localhost:3000" // Default for development
-	}
-	r.Use(func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", corsOrigins)
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization")
-		c.Header("Access-Control-Allow-Credentials", "true")
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
-		c.Next()
-	})
+	cors := middleware.NewCORSPolicy(corsOrigins())
+	r.Use(middleware.CORSMiddleware(cors))
 
-	// Rate limiting middleware
+	// Rate limiting middleware (RATE_LIMIT_RPS/BURST, AUTH_RATE_LIMIT_RPS/BURST for auth routes)
 	generalLimiter := middleware.NewRateLimiter(100, 100)
 	r.Use(middleware.RateLimitMiddleware(generalLimiter))
 	strictLimiter := middleware.NewRateLimiter(5, 5)
+	applyRateLimits := func() error {
+		generalRate, generalBurst, err := middleware.RateLimitFromEnv("RATE_LIMIT", 100)
+		if err != nil {
+			return err
+		}
+		strictRate, strictBurst, err := middleware.RateLimitFromEnv("AUTH_RATE_LIMIT", 5)
+		if err != nil {
+			return err
+		}
+		generalLimiter.SetLimit(generalRate, generalBurst)
+		strictLimiter.SetLimit(strictRate, strictBurst)
+		return nil
+	}
+	if err := applyRateLimits(); err != nil {
+		log.Printf("Warning: using default rate limits: %v", err)
+	}
 
-	// Health endpoint
-	r.GET("/api/v1/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok"})
+	// Settings re-read on SIGHUP and POST /admin/config/reload
+	config.Register("rate limits", applyRateLimits)
+	config.Register("cors origins", func() error {
+		cors.SetOrigins(corsOrigins())
+		return nil
 	})
+	config.Register("log level", func() error {
+		return requestLogger.SetLevel(os.Getenv("LOG_LEVEL"))
+	})
+	config.Register("retention windows", func() error {
+		// The cleanup job reads the policy for each run, so this only reports it
+		policy := database.LoadRetentionPolicy()
+		log.Printf("Retention windows: versions %dd (at most %d per snippet), deleted snippets %dd, deleted users %dd, idle sessions %dd",
+			policy.SnippetVersionDays, policy.MaxSnippetVersions, policy.SoftDeletedSnippetDays, policy.SoftDeletedUserDays, policy.IdleSessionDays)
+		return nil
+	})
+
+	// Organization (tenant) of each request, from X-Organization or the subdomain
+	r.Use(middleware.TenantMiddleware(tenants))
+
+	// Health endpoint with build info and dependency status (503 when the database is down)
+	r.GET("/api/v1/health", health.New(healthChecks...).Handler)
 
 	// Swagger docs
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	// API routes
-	api := r.Group("/api/v1")
-	{
-		// Authentication routes (with strict rate limiting)
-		authRoutes := api.Group("/auth")
-		authRoutes.Use(middleware.StrictRateLimitMiddleware(strictLimiter))
-		{
-			authRoutes.POST("/register", handlers.CreateUser)
-			authRoutes.POST("/login", handlers.Login)
-			authRoutes.GET("/availability", handlers.CheckAvailability)
-			authRoutes.POST("/refresh", handlers.RefreshAccessToken)
-			authRoutes.POST("/logout", handlers.Logout)
-			authRoutes.POST("/logout-all", handlers.LogoutAll)
-		}
+	// API routes, the OpenAPI description and snippet embeds
+	handlers.NewServer(database.DB, time.Now).WithHub(hub).Register(r, handlers.RateLimits{
+		Strict:  strictLimiter,
+		Refresh: middleware.NewRefreshRateLimiter(store),
+		APIKey:  middleware.NewAPIKeyRateLimiter(),
+	})
 
-		// Protected auth routes (require authentication)
-		protectedAuth := api.Group("/auth")
-		protectedAuth.Use(auth.Middleware())
-		{
-			// Sessions endpoints restricted to tester/premium/admin users
-			protectedAuth.GET("/sessions", middleware.SessionsAccess, handlers.GetSessions)
-			protectedAuth.POST("/sessions/:sessionId", middleware.SessionsAccess, handlers.LogoutSession)
-		}
+	// Serve the embedded web frontend on every other route when SERVE_FRONTEND=true
+	if frontend, err := webui.NewHandlerFromEnv(); err != nil {
+		log.Printf("Warning: embedded frontend disabled: %v", err)
+	} else if frontend != nil {
+		r.NoRoute(frontend)
+	}
 
-		// Public role routes
-		api.GET("/roles", handlers.GetAllRoles)
-
-		// Protected routes (require authentication)
-		protected := api.Group("")
-		protected.Use(auth.Middleware())
-		{
-			// User routes
-			users := protected.Group("/users")
-			{
-				users.GET("/", handlers.GetUsers)
-				users.GET("/profile", handlers.GetCurrentUser)
-				users.PUT("/profile", handlers.UpdateCurrentUser)
-				users.GET("/me/roles", handlers.GetMyRoles)
-				users.GET("/:id", handlers.GetUser)
-				users.PUT("/:id", handlers.UpdateUser)
-				users.DELETE("/:id", handlers.DeleteUser)
-			}
+	// Tell browsers to use HTTPS only when HSTS_MAX_AGE is set
+	var handler http.Handler = r
+	if hsts, err := httpserver.LoadHSTSConfig(); err != nil {
+		log.Printf("Warning: HSTS disabled: %v", err)
+	} else {
+		handler = httpserver.HSTS(hsts, r)
+	}
 
-			// Snippet routes
-			snippets := protected.Group("/snippets")
-			{
-				snippets.GET("/", handlers.GetCurrentUserSnippets)
-				snippets.GET("/sync", handlers.SyncSnippets)
-				snippets.POST("/", handlers.CreateSnippet)
-				snippets.GET("/:id", handlers.GetSnippet)
-				snippets.PUT("/:id", handlers.UpdateSnippet)
-				snippets.DELETE("/:id", handlers.DeleteSnippet)
-				snippets.GET("/:id/history", handlers.GetSnippetHistory)
-				snippets.POST("/:id/restore/:versionNumber", handlers.RestoreSnippetVersion)
-			}
+	go func() {
+		<-ctx.Done()
+		notifySystemd(systemd.Stopping)
+	}()
+	go config.ReloadOnSIGHUP(ctx)
 
-			// Admin-only routes
-			admin := protected.Group("/admin")
-			admin.Use(middleware.AdminOnly)
-			{
-				// Role management
-				admin.GET("/users/:userId/roles", handlers.GetUserRoles)
-				admin.POST("/users/:userId/roles", handlers.AssignUserRole)
-				admin.DELETE("/users/:userId/roles/:roleName", handlers.RevokeUserRole)
+	// Under systemd socket activation the listening sockets are inherited, so they stay
+	// open (queueing connections) while the service restarts
+	activated, err := systemd.Listeners()
+	if err != nil {
+		log.Printf("Warning: socket activation disabled: %v", err)
+	}
+
+	// Notify the systemd watchdog (WatchdogSec=) while the database is reachable
+	go systemd.RunWatchdog(ctx, database.DB.PingContext)
+
+	// Terminate TLS with Let's Encrypt certificates when TLS_DOMAINS is set
+	if tlsConfig := autotls.LoadConfig(); tlsConfig.Enabled() {
+		httpsLis, err := httpserver.Listen(activated, 0, tlsConfig.HTTPSPort)
+		if err != nil {
+			log.Printf("Failed to start TLS server: %v", err)
+			return
+		}
+		var httpLis net.Listener
+		if tlsConfig.HTTPPort != "off" {
+			if httpLis, err = httpserver.Listen(activated, 1, tlsConfig.HTTPPort); err != nil {
+				log.Printf("Warning: HTTP redirect server disabled: %v", err)
+				httpLis = nil
 			}
 		}
+		notifySystemd(systemd.Ready)
+		if err := autotls.Serve(ctx, tlsConfig, handler, httpsLis, httpLis); err != nil {
+			log.Printf("TLS server stopped: %v", err)
+		}
+		return
 	}
 
 	// Start server
@@ -171,18 +341,101 @@ func main() {
 	if port == "" {
 		port = "8080"
 	}
-
-	log.Printf("Server running on port %s", port)
-	if err := r.Run(":" + port); err != nil {
+	lis, err := httpserver.Listen(activated, 0, port)
+	if err != nil {
 		log.Printf("Failed to start server: %v", err)
+		return
+	}
+
+	// Redirect plain HTTP on REDIRECT_PORT to HTTPS, for proxies that only terminate TLS
+	if redirect := httpserver.LoadRedirectConfig(); redirect.Port != "" {
+		if redirectLis, err := httpserver.Listen(activated, 1, redirect.Port); err != nil {
+			log.Printf("Warning: HTTP redirect server disabled: %v", err)
+		} else {
+			redirectServer := httpserver.New(redirectLis.Addr().String(), httpserver.Redirect(redirect, handler), false)
+			go func() {
+				log.Printf("HTTP redirect server running on %s", redirectLis.Addr())
+				if err := httpserver.Serve(ctx, redirectServer, redirectLis); err != nil {
+					log.Printf("HTTP redirect server stopped: %v", err)
+				}
+			}()
+		}
+	}
+
+	// HTTP/2 without TLS (h2c) when H2C=true, for proxies that forward HTTP/2
+	h2c := httpserver.H2CFromEnv()
+	log.Printf("Server running on %s (h2c %t)", lis.Addr(), h2c)
+	notifySystemd(systemd.Ready)
+	if err := httpserver.Serve(ctx, httpserver.New(lis.Addr().String(), handler, h2c), lis); err != nil {
+		log.Printf("Server stopped: %v", err)
 	}
 }
 
-// startDataRetentionCleanup runs the data retention cleanup job every 24 hours
-func startDataRetentionCleanup() {
+// healthcheck requests the health endpoint of the server running on this host, or the
+// URL given as the only argument, and returns the exit status: 0 when it's ok or
+// degraded, 1 otherwise
+func healthcheck(args []string) int {
+	target, serverName := "", ""
+	tlsConfig := autotls.LoadConfig()
+	switch {
+	case len(args) > 0:
+		target = args[0]
+	case tlsConfig.Enabled():
+		target = "https://127.0.0.1:" + tlsConfig.HTTPSPort + "/api/v1/health"
+		serverName = tlsConfig.Domains[0]
+	default:
+		port := os.Getenv("PORT")
+		if port == "" {
+			port = "8080"
+		}
+		target = "http://127.0.0.1:" + port + "/api/v1/health"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := health.Probe(ctx, target, serverName); err != nil {
+		log.Printf("Health check failed: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// notifySystemd tells systemd about a state change when running as a Type=notify service
+func notifySystemd(state string) {
+	if _, err := systemd.Notify(state); err != nil {
+		log.Printf("Failed to notify systemd: %v", err)
+	}
+}
+
+// corsOrigins returns the comma-separated origins in CORS_ALLOWED_ORIGINS
+func corsOrigins() string {
+	if origins := os.Getenv("CORS_ALLOWED_ORIGINS"); origins != "" {
+		return origins
+	}
+	return "http://localhost:3000" // Default for development
+}
+
+const (
+	// retentionRunTimeout bounds each data retention cleanup run
+	retentionRunTimeout = time.Hour
+
+	// indexMaintenanceTimeout bounds each index maintenance run
+	indexMaintenanceTimeout = 10 * time.Minute
+)
+
+// startDataRetentionCleanup runs the data retention cleanup job every 24 hours until ctx is cancelled
+func startDataRetentionCleanup(ctx context.Context, exporter *export.Exporter) {
+	run := func() error {
+		// Re-read the retention windows, so reloaded ones apply from the next run
+		policy := database.LoadRetentionPolicy()
+		policy.BeforeUserPurge = exporter.BeforePurge
+		runCtx, cancel := context.WithTimeout(ctx, retentionRunTimeout)
+		defer cancel()
+		return database.CleanupOldData(runCtx, policy)
+	}
+
 	// Run cleanup immediately on startup
-	policy := database.DefaultRetentionPolicy()
-	if err := database.CleanupOldData(policy); err != nil {
+	if err := run(); err != nil {
 		log.Printf("Initial data cleanup failed: %v", err)
 	}
 
@@ -190,10 +443,86 @@ func startDataRetentionCleanup() {
 	ticker := time.NewTicker(24 * time.Hour)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		log.Println("Running scheduled data retention cleanup...")
-		if err := database.CleanupOldData(policy); err != nil {
-			log.Printf("Scheduled data cleanup failed: %v", err)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			log.Println("Running scheduled data retention cleanup...")
+			if err := run(); err != nil {
+				log.Printf("Scheduled data cleanup failed: %v", err)
+			}
+		}
+	}
+}
+
+// startGRPCServer serves the gRPC snippet and sync API on port until ctx is cancelled.
+// It then stops accepting calls and waits for in-flight ones as long as the HTTP server
+// does (Watch streams end as the hub closes), cancelling those still running after that.
+func startGRPCServer(ctx context.Context, port string, store *models.Store, hub *realtime.Hub, tenants *middleware.TenantResolver) {
+	lis, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		log.Printf("Failed to listen for gRPC on port %s: %v", port, err)
+		return
+	}
+
+	srv := rpc.NewServer(store, hub, tenants)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.Serve(lis)
+	}()
+	log.Printf("gRPC server running on port %s", port)
+
+	select {
+	case err := <-errc:
+		log.Printf("gRPC server stopped: %v", err)
+		return
+	case <-ctx.Done():
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+	timer := time.NewTimer(httpserver.ShutdownTimeout)
+	defer timer.Stop()
+	select {
+	case <-stopped:
+	case <-timer.C:
+		log.Printf("gRPC calls still running after %s, cancelling them", httpserver.ShutdownTimeout)
+		srv.Stop()
+		<-stopped
+	}
+	if err := <-errc; err != nil {
+		log.Printf("gRPC server stopped: %v", err)
+	}
+}
+
+// startIndexMaintenance refreshes planner statistics on hot tables every 6 hours until ctx is cancelled
+func startIndexMaintenance(ctx context.Context) {
+	run := func() error {
+		runCtx, cancel := context.WithTimeout(ctx, indexMaintenanceTimeout)
+		defer cancel()
+		return database.RunIndexMaintenance(runCtx)
+	}
+
+	if err := run(); err != nil {
+		log.Printf("Initial index maintenance failed: %v", err)
+	}
+
+	ticker := time.NewTicker(6 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			log.Println("Running scheduled index maintenance...")
+			if err := run(); err != nil {
+				log.Printf("Scheduled index maintenance failed: %v", err)
+			}
 		}
 	}
 }
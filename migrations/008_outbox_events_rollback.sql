@@ -0,0 +1,3 @@
+-- Rollback Migration 008: Remove outbox table
+DROP INDEX IF EXISTS idx_outbox_events_pending;
+DROP TABLE IF EXISTS outbox_events;
@@ -0,0 +1,11 @@
+-- Migration 009: Store processed avatar variants
+-- Uploads are validated and re-encoded server-side (metadata stripped) into fixed square sizes.
+
+CREATE TABLE IF NOT EXISTS user_avatars (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    size INTEGER NOT NULL,
+    content_type VARCHAR(50) NOT NULL,
+    data BYTEA NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, size)
+);
@@ -0,0 +1,2 @@
+-- Rollback Migration 009: Remove stored avatar variants
+DROP TABLE IF EXISTS user_avatars;
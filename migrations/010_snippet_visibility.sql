@@ -0,0 +1,11 @@
+-- Migration 010: Snippet visibility
+-- Snippets stay private by default; public ones are listed on the owner's public profile.
+
+ALTER TABLE snippets ADD COLUMN IF NOT EXISTS visibility VARCHAR(20) NOT NULL DEFAULT 'private';
+
+ALTER TABLE snippets DROP CONSTRAINT IF EXISTS snippets_visibility_check;
+ALTER TABLE snippets ADD CONSTRAINT snippets_visibility_check CHECK (visibility IN ('private', 'public'));
+
+-- Partial index for public profile listings
+CREATE INDEX IF NOT EXISTS idx_snippets_public ON snippets(user_id, created_at DESC)
+    WHERE visibility = 'public' AND is_deleted = false;
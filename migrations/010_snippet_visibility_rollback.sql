@@ -0,0 +1,4 @@
+-- Rollback Migration 010: Remove snippet visibility
+DROP INDEX IF EXISTS idx_snippets_public;
+ALTER TABLE snippets DROP CONSTRAINT IF EXISTS snippets_visibility_check;
+ALTER TABLE snippets DROP COLUMN IF EXISTS visibility;
@@ -0,0 +1,4 @@
+-- Migration 011: Track email verification state on users
+-- NULL means the address has not been verified yet.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP WITH TIME ZONE;
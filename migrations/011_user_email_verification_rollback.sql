@@ -0,0 +1,2 @@
+-- Rollback Migration 011: Remove email verification state
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at;
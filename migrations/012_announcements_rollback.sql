@@ -0,0 +1,4 @@
+-- Rollback Migration 012: Remove announcements
+DROP TRIGGER IF EXISTS update_announcements_updated_at ON announcements;
+DROP INDEX IF EXISTS idx_announcements_window;
+DROP TABLE IF EXISTS announcements;
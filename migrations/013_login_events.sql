@@ -0,0 +1,18 @@
+-- Migration 013: Last-login tracking and login history
+-- Records password logins (including failed attempts) and refresh-token logins per user.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS last_login_at TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE IF NOT EXISTS login_events (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    session_id UUID REFERENCES sessions(id) ON DELETE SET NULL,
+    method VARCHAR(20) NOT NULL CHECK (method IN ('password', 'refresh')),
+    success BOOLEAN NOT NULL,
+    failure_reason VARCHAR(50),
+    device_info TEXT,
+    ip_address_hash VARCHAR(64),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_events_user_created ON login_events(user_id, created_at DESC);
@@ -0,0 +1,4 @@
+-- Rollback Migration 013: Remove login history
+DROP INDEX IF EXISTS idx_login_events_user_created;
+DROP TABLE IF EXISTS login_events;
+ALTER TABLE users DROP COLUMN IF EXISTS last_login_at;
@@ -0,0 +1,13 @@
+-- Migration 014: Username change history
+-- Former usernames are reserved for their previous owner and redirect to the
+-- new username for a grace period (30 days, see models.UsernameReuseGracePeriod).
+
+CREATE TABLE IF NOT EXISTS username_history (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    old_username VARCHAR(255) NOT NULL,
+    changed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_username_history_old_username ON username_history(old_username, changed_at DESC);
+CREATE INDEX IF NOT EXISTS idx_username_history_user_id ON username_history(user_id);
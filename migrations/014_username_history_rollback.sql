@@ -0,0 +1,4 @@
+-- Rollback Migration 014: Remove username change history
+DROP INDEX IF EXISTS idx_username_history_user_id;
+DROP INDEX IF EXISTS idx_username_history_old_username;
+DROP TABLE IF EXISTS username_history;
@@ -0,0 +1,3 @@
+-- Rollback Migration 015: Remove follows
+DROP INDEX IF EXISTS idx_follows_followee_id;
+DROP TABLE IF EXISTS follows;
@@ -0,0 +1,3 @@
+-- Rollback Migration 016: Remove user blocks
+DROP INDEX IF EXISTS idx_user_blocks_blocked_id;
+DROP TABLE IF EXISTS user_blocks;
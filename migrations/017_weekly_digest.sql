@@ -0,0 +1,21 @@
+-- Migration 017: Weekly digest email
+-- Adds snippet usage tracking, per-session sync times and notification preferences.
+
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS last_synced_at TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE IF NOT EXISTS snippet_usage (
+    id BIGSERIAL PRIMARY KEY,
+    snippet_id BIGINT NOT NULL REFERENCES snippets(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    used_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_snippet_usage_user_used_at ON snippet_usage(user_id, used_at DESC);
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    weekly_digest BOOLEAN NOT NULL DEFAULT true,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    digest_last_sent_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,5 @@
+-- Rollback Migration 017: Remove weekly digest tables
+DROP TABLE IF EXISTS notification_preferences;
+DROP INDEX IF EXISTS idx_snippet_usage_user_used_at;
+DROP TABLE IF EXISTS snippet_usage;
+ALTER TABLE sessions DROP COLUMN IF EXISTS last_synced_at;
@@ -0,0 +1,18 @@
+-- Migration 018: Mobile push notifications
+-- Devices register FCM/APNs tokens; snippet changes are pushed to every device
+-- except the session that made the change (recorded on the outbox event).
+
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS origin_session_id TEXT;
+
+CREATE TABLE IF NOT EXISTS device_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    session_id TEXT,
+    platform VARCHAR(10) NOT NULL,
+    token TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT device_tokens_platform_check CHECK (platform IN ('fcm', 'apns'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_device_tokens_user_id ON device_tokens(user_id);
@@ -0,0 +1,4 @@
+-- Rollback Migration 018: Remove push notification support
+DROP INDEX IF EXISTS idx_device_tokens_user_id;
+DROP TABLE IF EXISTS device_tokens;
+ALTER TABLE outbox_events DROP COLUMN IF EXISTS origin_session_id;
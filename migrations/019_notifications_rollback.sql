@@ -0,0 +1,4 @@
+-- Rollback Migration 019: Remove in-app notifications
+DROP INDEX IF EXISTS idx_notifications_unread;
+DROP INDEX IF EXISTS idx_notifications_user_created;
+DROP TABLE IF EXISTS notifications;
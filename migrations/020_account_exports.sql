@@ -0,0 +1,17 @@
+-- Migration 020: Pre-purge account exports
+-- The retention job archives a soft-deleted user's data before purging it. Rows have no
+-- foreign key to users because they must outlive the purged account.
+
+CREATE TABLE IF NOT EXISTS account_exports (
+    id BIGSERIAL PRIMARY KEY,
+    token_hash VARCHAR(64) NOT NULL,
+    user_id UUID NOT NULL,
+    username VARCHAR(50) NOT NULL,
+    archive BYTEA NOT NULL,
+    downloaded_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT account_exports_token_hash_key UNIQUE (token_hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_exports_expires ON account_exports(expires_at);
@@ -0,0 +1,3 @@
+-- Rollback Migration 020: Remove pre-purge account exports
+DROP INDEX IF EXISTS idx_account_exports_expires;
+DROP TABLE IF EXISTS account_exports;
@@ -0,0 +1,21 @@
+-- Migration 021: Audit log
+-- Records role changes, announcement changes, account deletions and purges, and forced
+-- logouts for compliance reviews. Actor and target IDs have no foreign keys so entries
+-- survive account purges.
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    actor_id UUID,
+    action VARCHAR(100) NOT NULL,
+    target_type VARCHAR(50) NOT NULL,
+    target_id TEXT,
+    target_user_id UUID,
+    details JSONB,
+    ip_address_hash VARCHAR(64),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_created ON audit_log(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_action_created ON audit_log(action, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor_id, created_at DESC) WHERE actor_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_audit_log_target_user ON audit_log(target_user_id, created_at DESC) WHERE target_user_id IS NOT NULL;
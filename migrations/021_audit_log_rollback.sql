@@ -0,0 +1,6 @@
+-- Rollback Migration 021: Remove audit log
+DROP INDEX IF EXISTS idx_audit_log_target_user;
+DROP INDEX IF EXISTS idx_audit_log_actor;
+DROP INDEX IF EXISTS idx_audit_log_action_created;
+DROP INDEX IF EXISTS idx_audit_log_created;
+DROP TABLE IF EXISTS audit_log;
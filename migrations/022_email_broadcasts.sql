@@ -0,0 +1,32 @@
+-- Migration 022: Admin broadcast emails
+-- Recipients are snapshotted when a broadcast is queued; a background job sends to them
+-- at a throttled rate and records delivery status per recipient.
+
+CREATE TABLE IF NOT EXISTS email_broadcasts (
+    id BIGSERIAL PRIMARY KEY,
+    subject VARCHAR(200) NOT NULL,
+    message TEXT NOT NULL,
+    filter JSONB NOT NULL DEFAULT '{}',
+    created_by UUID,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT email_broadcasts_created_by_fkey FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS email_broadcast_recipients (
+    broadcast_id BIGINT NOT NULL,
+    user_id UUID NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    username VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    error TEXT,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_attempt_at TIMESTAMP WITH TIME ZONE,
+    sent_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT email_broadcast_recipients_pkey PRIMARY KEY (broadcast_id, user_id),
+    CONSTRAINT email_broadcast_recipients_broadcast_fkey FOREIGN KEY (broadcast_id) REFERENCES email_broadcasts(id) ON DELETE CASCADE,
+    CONSTRAINT email_broadcast_recipients_user_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT email_broadcast_recipients_status_check CHECK (status IN ('pending', 'sending', 'sent', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_broadcast_recipients_due ON email_broadcast_recipients(next_attempt_at) WHERE status IN ('pending', 'sending');
@@ -0,0 +1,4 @@
+-- Rollback Migration 022: Remove admin broadcast emails
+DROP INDEX IF EXISTS idx_broadcast_recipients_due;
+DROP TABLE IF EXISTS email_broadcast_recipients;
+DROP TABLE IF EXISTS email_broadcasts;
@@ -0,0 +1,18 @@
+-- Migration 024: Scheduled backup history
+-- Each run of the backup job records where its encrypted archive was uploaded, or why it failed.
+
+CREATE TABLE IF NOT EXISTS backup_runs (
+    id BIGSERIAL PRIMARY KEY,
+    status VARCHAR(20) NOT NULL,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    object_key TEXT,
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    tables INT NOT NULL DEFAULT 0,
+    rows BIGINT NOT NULL DEFAULT 0,
+    pruned INT NOT NULL DEFAULT 0,
+    error TEXT,
+    CONSTRAINT backup_runs_status_check CHECK (status IN ('succeeded', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_backup_runs_started_at ON backup_runs(status, started_at DESC);
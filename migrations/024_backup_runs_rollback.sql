@@ -0,0 +1,3 @@
+-- Rollback Migration 024: Remove scheduled backup history
+DROP INDEX IF EXISTS idx_backup_runs_started_at;
+DROP TABLE IF EXISTS backup_runs;
@@ -0,0 +1,18 @@
+-- Migration 025: Stripe subscriptions
+-- Stripe webhooks keep one row per paying user; active, trialing and past-due
+-- subscriptions grant the premium role.
+
+CREATE TABLE IF NOT EXISTS subscriptions (
+    user_id UUID PRIMARY KEY,
+    stripe_customer_id VARCHAR(100) NOT NULL,
+    stripe_subscription_id VARCHAR(100),
+    status VARCHAR(30) NOT NULL DEFAULT 'incomplete',
+    price_id VARCHAR(100),
+    current_period_end TIMESTAMP WITH TIME ZONE,
+    cancel_at_period_end BOOLEAN NOT NULL DEFAULT false,
+    stripe_event_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT subscriptions_user_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT subscriptions_stripe_customer_key UNIQUE (stripe_customer_id)
+);
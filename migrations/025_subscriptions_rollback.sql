@@ -0,0 +1,2 @@
+-- Rollback Migration 025: Remove Stripe subscriptions
+DROP TABLE IF EXISTS subscriptions;
@@ -0,0 +1,19 @@
+-- Migration 026: Browser-extension tokens
+-- Long-lived tokens limited to reading snippets and reporting usage. Only a hash of
+-- each token is stored.
+
+CREATE TABLE IF NOT EXISTS extension_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    token_hash VARCHAR(64) NOT NULL,
+    name VARCHAR(100) NOT NULL DEFAULT '',
+    scopes TEXT[] NOT NULL,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT extension_tokens_user_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT extension_tokens_token_hash_key UNIQUE (token_hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_extension_tokens_user_id ON extension_tokens(user_id);
@@ -0,0 +1,2 @@
+-- Rollback Migration 026: Remove browser-extension tokens
+DROP TABLE IF EXISTS extension_tokens;
@@ -0,0 +1,5 @@
+-- Migration 027: Shortcut expansion lookup
+-- Text expanders resolve shortcuts on keystroke, so look up a user's live snippets by
+-- shortcut through an index.
+
+CREATE INDEX IF NOT EXISTS idx_snippets_user_shortcut ON snippets(user_id, shortcut) WHERE is_deleted = false;
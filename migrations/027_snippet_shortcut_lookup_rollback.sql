@@ -0,0 +1,2 @@
+-- Rollback Migration 027: Remove the shortcut expansion index
+DROP INDEX IF EXISTS idx_snippets_user_shortcut;
@@ -0,0 +1,21 @@
+-- Migration 028: API keys
+-- Keys for third-party integrations. Each key has its own scopes, a rate limit in
+-- requests per minute and a request counter. Only a hash of each key is stored.
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    key_hash VARCHAR(64) NOT NULL,
+    key_prefix VARCHAR(16) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    scopes TEXT[] NOT NULL,
+    rate_limit INTEGER NOT NULL,
+    request_count BIGINT NOT NULL DEFAULT 0,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT api_keys_user_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT api_keys_key_hash_key UNIQUE (key_hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
@@ -0,0 +1,2 @@
+-- Rollback Migration 028: Remove API keys
+DROP TABLE IF EXISTS api_keys;
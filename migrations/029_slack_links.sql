@@ -0,0 +1,24 @@
+-- Migration 029: Slack slash command
+-- Maps Slack users, per workspace, to Snippy users, and stores the one-time codes used
+-- to link them. Only a hash of each code is stored.
+
+CREATE TABLE IF NOT EXISTS slack_links (
+    id BIGSERIAL PRIMARY KEY,
+    team_id VARCHAR(32) NOT NULL,
+    slack_user_id VARCHAR(32) NOT NULL,
+    user_id UUID NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT slack_links_user_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT slack_links_team_user_key UNIQUE (team_id, slack_user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_slack_links_user_id ON slack_links(user_id);
+
+CREATE TABLE IF NOT EXISTS slack_link_codes (
+    code_hash VARCHAR(64) PRIMARY KEY,
+    user_id UUID NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    CONSTRAINT slack_link_codes_user_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_slack_link_codes_user_id ON slack_link_codes(user_id);
@@ -0,0 +1,3 @@
+-- Rollback Migration 029: Remove Slack links
+DROP TABLE IF EXISTS slack_link_codes;
+DROP TABLE IF EXISTS slack_links;
@@ -0,0 +1,21 @@
+-- Migration 030: Integration links
+-- Generalizes the Slack links to any chat integration so Discord shares them. Slack
+-- links keep their workspace (team) ID; Discord user IDs are global, so their
+-- workspace_id is empty. Link codes work for either integration.
+
+ALTER TABLE slack_links RENAME TO integration_links;
+ALTER TABLE integration_links RENAME COLUMN team_id TO workspace_id;
+ALTER TABLE integration_links RENAME COLUMN slack_user_id TO external_user_id;
+ALTER TABLE integration_links ALTER COLUMN workspace_id SET DEFAULT '';
+ALTER TABLE integration_links ADD COLUMN IF NOT EXISTS provider VARCHAR(20) NOT NULL DEFAULT 'slack';
+ALTER TABLE integration_links ALTER COLUMN provider DROP DEFAULT;
+
+ALTER TABLE integration_links DROP CONSTRAINT IF EXISTS slack_links_team_user_key;
+ALTER TABLE integration_links ADD CONSTRAINT integration_links_provider_user_key
+    UNIQUE (provider, workspace_id, external_user_id);
+ALTER TABLE integration_links RENAME CONSTRAINT slack_links_user_fkey TO integration_links_user_fkey;
+ALTER INDEX IF EXISTS idx_slack_links_user_id RENAME TO idx_integration_links_user_id;
+
+ALTER TABLE slack_link_codes RENAME TO integration_link_codes;
+ALTER TABLE integration_link_codes RENAME CONSTRAINT slack_link_codes_user_fkey TO integration_link_codes_user_fkey;
+ALTER INDEX IF EXISTS idx_slack_link_codes_user_id RENAME TO idx_integration_link_codes_user_id;
@@ -0,0 +1,16 @@
+-- Rollback Migration 030: Restore Slack-only links (Discord links are dropped)
+DELETE FROM integration_links WHERE provider <> 'slack';
+
+ALTER INDEX IF EXISTS idx_integration_link_codes_user_id RENAME TO idx_slack_link_codes_user_id;
+ALTER TABLE integration_link_codes RENAME CONSTRAINT integration_link_codes_user_fkey TO slack_link_codes_user_fkey;
+ALTER TABLE integration_link_codes RENAME TO slack_link_codes;
+
+ALTER INDEX IF EXISTS idx_integration_links_user_id RENAME TO idx_slack_links_user_id;
+ALTER TABLE integration_links RENAME CONSTRAINT integration_links_user_fkey TO slack_links_user_fkey;
+ALTER TABLE integration_links DROP CONSTRAINT IF EXISTS integration_links_provider_user_key;
+ALTER TABLE integration_links DROP COLUMN IF EXISTS provider;
+ALTER TABLE integration_links ALTER COLUMN workspace_id DROP DEFAULT;
+ALTER TABLE integration_links RENAME COLUMN external_user_id TO slack_user_id;
+ALTER TABLE integration_links RENAME COLUMN workspace_id TO team_id;
+ALTER TABLE integration_links ADD CONSTRAINT slack_links_team_user_key UNIQUE (team_id, slack_user_id);
+ALTER TABLE integration_links RENAME TO slack_links;
@@ -0,0 +1,26 @@
+-- Migration 031: Git mirrors
+-- Lets a user mirror their snippets to a Git repository, one Markdown file per snippet,
+-- either shortly after every change (on_change) or every interval_hours (schedule).
+-- next_sync_at is when the mirror is next due; it is NULL while nothing is pending.
+
+CREATE TABLE IF NOT EXISTS git_mirrors (
+    user_id UUID PRIMARY KEY,
+    repo_url TEXT NOT NULL,
+    branch VARCHAR(255) NOT NULL DEFAULT 'main',
+    username VARCHAR(255) NOT NULL DEFAULT '',
+    token TEXT NOT NULL DEFAULT '',
+    mode VARCHAR(20) NOT NULL DEFAULT 'on_change',
+    interval_hours INTEGER NOT NULL DEFAULT 24,
+    next_sync_at TIMESTAMP WITH TIME ZONE,
+    sync_started_at TIMESTAMP WITH TIME ZONE,
+    last_synced_at TIMESTAMP WITH TIME ZONE,
+    last_commit VARCHAR(40),
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT git_mirrors_user_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT git_mirrors_mode_check CHECK (mode IN ('on_change', 'schedule')),
+    CONSTRAINT git_mirrors_interval_check CHECK (interval_hours BETWEEN 1 AND 168)
+);
+
+CREATE INDEX IF NOT EXISTS idx_git_mirrors_next_sync ON git_mirrors(next_sync_at) WHERE next_sync_at IS NOT NULL;
@@ -0,0 +1,3 @@
+-- Rollback Migration 031: Remove Git mirrors
+DROP INDEX IF EXISTS idx_git_mirrors_next_sync;
+DROP TABLE IF EXISTS git_mirrors;
@@ -0,0 +1,76 @@
+-- Migration 032: Organizations (multi-tenancy)
+-- One deployment can host several isolated organizations. Existing users and their
+-- sessions, snippets and history belong to the default organization. Usernames and
+-- emails become unique per organization instead of across the deployment.
+
+CREATE TABLE IF NOT EXISTS organizations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    slug VARCHAR(63) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO organizations (id, slug, name)
+VALUES ('00000000-0000-0000-0000-000000000001', 'default', 'Default')
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001';
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001';
+ALTER TABLE snippets ADD COLUMN IF NOT EXISTS org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001';
+ALTER TABLE snippet_history ADD COLUMN IF NOT EXISTS org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001';
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_org_fkey;
+ALTER TABLE users ADD CONSTRAINT users_org_fkey FOREIGN KEY (org_id) REFERENCES organizations(id);
+ALTER TABLE sessions DROP CONSTRAINT IF EXISTS sessions_org_fkey;
+ALTER TABLE sessions ADD CONSTRAINT sessions_org_fkey FOREIGN KEY (org_id) REFERENCES organizations(id);
+ALTER TABLE snippets DROP CONSTRAINT IF EXISTS snippets_org_fkey;
+ALTER TABLE snippets ADD CONSTRAINT snippets_org_fkey FOREIGN KEY (org_id) REFERENCES organizations(id);
+ALTER TABLE snippet_history DROP CONSTRAINT IF EXISTS snippet_history_org_fkey;
+ALTER TABLE snippet_history ADD CONSTRAINT snippet_history_org_fkey FOREIGN KEY (org_id) REFERENCES organizations(id);
+
+-- Usernames and emails are unique per organization
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_username_key;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_org_username_key;
+ALTER TABLE users ADD CONSTRAINT users_org_username_key UNIQUE (org_id, username);
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_org_email_key;
+ALTER TABLE users ADD CONSTRAINT users_org_email_key UNIQUE (org_id, email);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_org_id ON sessions(org_id);
+CREATE INDEX IF NOT EXISTS idx_snippets_org_id ON snippets(org_id);
+CREATE INDEX IF NOT EXISTS idx_snippet_history_org_id ON snippet_history(org_id);
+
+-- Rows owned by a user or a snippet belong to its organization
+CREATE OR REPLACE FUNCTION set_org_id_from_user()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.org_id := COALESCE((SELECT org_id FROM users WHERE id = NEW.user_id), NEW.org_id);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION set_org_id_from_snippet()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.org_id := COALESCE((SELECT org_id FROM snippets WHERE id = NEW.snippet_id), NEW.org_id);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_sessions_org_id ON sessions;
+CREATE TRIGGER trigger_sessions_org_id
+    BEFORE INSERT ON sessions
+    FOR EACH ROW
+    EXECUTE FUNCTION set_org_id_from_user();
+
+DROP TRIGGER IF EXISTS trigger_snippets_org_id ON snippets;
+CREATE TRIGGER trigger_snippets_org_id
+    BEFORE INSERT ON snippets
+    FOR EACH ROW
+    EXECUTE FUNCTION set_org_id_from_user();
+
+DROP TRIGGER IF EXISTS trigger_snippet_history_org_id ON snippet_history;
+CREATE TRIGGER trigger_snippet_history_org_id
+    BEFORE INSERT ON snippet_history
+    FOR EACH ROW
+    EXECUTE FUNCTION set_org_id_from_snippet();
@@ -0,0 +1,23 @@
+-- Rollback Migration 032: Remove organizations
+-- Fails if two organizations have users with the same username or email.
+DROP TRIGGER IF EXISTS trigger_snippet_history_org_id ON snippet_history;
+DROP TRIGGER IF EXISTS trigger_snippets_org_id ON snippets;
+DROP TRIGGER IF EXISTS trigger_sessions_org_id ON sessions;
+DROP FUNCTION IF EXISTS set_org_id_from_snippet();
+DROP FUNCTION IF EXISTS set_org_id_from_user();
+
+DROP INDEX IF EXISTS idx_snippet_history_org_id;
+DROP INDEX IF EXISTS idx_snippets_org_id;
+DROP INDEX IF EXISTS idx_sessions_org_id;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_org_email_key;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_org_username_key;
+ALTER TABLE users ADD CONSTRAINT users_username_key UNIQUE (username);
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+
+ALTER TABLE snippet_history DROP COLUMN IF EXISTS org_id;
+ALTER TABLE snippets DROP COLUMN IF EXISTS org_id;
+ALTER TABLE sessions DROP COLUMN IF EXISTS org_id;
+ALTER TABLE users DROP COLUMN IF EXISTS org_id;
+
+DROP TABLE IF EXISTS organizations;
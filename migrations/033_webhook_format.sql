@@ -0,0 +1,6 @@
+-- Migration 033: Webhook payload formats
+-- Zapier REST hook subscriptions are stored as webhooks with format 'zapier': they get
+-- the trigger item (the snippet itself) instead of the signed Snippy event envelope.
+
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS format VARCHAR(20) NOT NULL DEFAULT 'snippy'
+    CHECK (format IN ('snippy', 'zapier'));
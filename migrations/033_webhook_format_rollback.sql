@@ -0,0 +1,3 @@
+-- Rollback Migration 033: Remove webhook payload formats
+DELETE FROM webhooks WHERE format = 'zapier';
+ALTER TABLE webhooks DROP COLUMN IF EXISTS format;
@@ -0,0 +1,7 @@
+-- Migration 034: Trigram search
+-- The fuzzy snippet search falls back to pg_trgm word similarity on labels when the
+-- full-text search finds nothing, so typos still match.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_snippets_label_trgm ON snippets USING GIN(label gin_trgm_ops);
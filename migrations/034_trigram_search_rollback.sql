@@ -0,0 +1,3 @@
+-- Rollback Migration 034: Remove trigram search
+DROP INDEX IF EXISTS idx_snippets_label_trgm;
+DROP EXTENSION IF EXISTS pg_trgm;
@@ -0,0 +1,22 @@
+-- Migration 035: Search language and unaccent
+-- Full-text search now uses an unaccenting copy of the SEARCH_LANGUAGE configuration
+-- (snippy_<language>) with an index per language. The server creates them for the
+-- configured language on startup; this sets up the default, english.
+
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'snippy_english') THEN
+        CREATE TEXT SEARCH CONFIGURATION snippy_english (COPY = pg_catalog.english);
+        ALTER TEXT SEARCH CONFIGURATION snippy_english
+            ALTER MAPPING FOR hword, hword_part, word WITH unaccent, english_stem;
+    END IF;
+END
+$$;
+
+CREATE INDEX IF NOT EXISTS idx_snippets_search_english ON snippets USING GIN(
+    to_tsvector('snippy_english', coalesce(label, ''))
+);
+
+DROP INDEX IF EXISTS idx_snippets_search;
@@ -0,0 +1,8 @@
+-- Rollback Migration 035: Restore the english full-text search index
+CREATE INDEX IF NOT EXISTS idx_snippets_search ON snippets USING GIN(
+    to_tsvector('english', coalesce(label, ''))
+);
+
+DROP INDEX IF EXISTS idx_snippets_search_english;
+DROP TEXT SEARCH CONFIGURATION IF EXISTS snippy_english;
+DROP EXTENSION IF EXISTS unaccent;
@@ -0,0 +1,16 @@
+-- Migration 036: Collections
+-- Users can file each of their snippets in one named collection. Deleting a collection
+-- keeps its snippets, unfiled.
+
+CREATE TABLE IF NOT EXISTS collections (
+    id SERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, name)
+);
+
+ALTER TABLE snippets ADD COLUMN IF NOT EXISTS collection_id INTEGER REFERENCES collections(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_snippets_collection_id ON snippets(collection_id) WHERE collection_id IS NOT NULL;
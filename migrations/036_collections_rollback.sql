@@ -0,0 +1,4 @@
+-- Rollback Migration 036: Remove collections
+DROP INDEX IF EXISTS idx_snippets_collection_id;
+ALTER TABLE snippets DROP COLUMN IF EXISTS collection_id;
+DROP TABLE IF EXISTS collections;
@@ -0,0 +1,9 @@
+-- Migration 037: Nested collections
+-- Collections can sit inside a parent collection; deleting one deletes its
+-- sub-collections. Names become unique among siblings instead of per user.
+
+ALTER TABLE collections ADD COLUMN IF NOT EXISTS parent_id INTEGER REFERENCES collections(id) ON DELETE CASCADE;
+
+ALTER TABLE collections DROP CONSTRAINT IF EXISTS collections_user_id_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_collections_user_parent_name ON collections(user_id, COALESCE(parent_id, 0), name);
+CREATE INDEX IF NOT EXISTS idx_collections_parent_id ON collections(parent_id) WHERE parent_id IS NOT NULL;
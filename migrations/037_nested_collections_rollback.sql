@@ -0,0 +1,9 @@
+-- Rollback Migration 037: Flatten collections
+-- Sub-collections move to the top level; this fails if that leaves two collections of a
+-- user with the same name.
+UPDATE collections SET parent_id = NULL WHERE parent_id IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_collections_parent_id;
+DROP INDEX IF EXISTS idx_collections_user_parent_name;
+ALTER TABLE collections DROP COLUMN IF EXISTS parent_id;
+ALTER TABLE collections ADD CONSTRAINT collections_user_id_name_key UNIQUE (user_id, name);
@@ -0,0 +1,6 @@
+-- Migration 038: Favorite snippets
+-- Users can star snippets and list only their favorites.
+
+ALTER TABLE snippets ADD COLUMN IF NOT EXISTS is_favorite BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_snippets_user_favorites ON snippets(user_id) WHERE is_favorite;
@@ -0,0 +1,3 @@
+-- Rollback Migration 038: Remove favorite snippets
+DROP INDEX IF EXISTS idx_snippets_user_favorites;
+ALTER TABLE snippets DROP COLUMN IF EXISTS is_favorite;
@@ -0,0 +1,6 @@
+-- Migration 039: Pinned snippets
+-- Users can pin a few snippets to the top of their listings.
+
+ALTER TABLE snippets ADD COLUMN IF NOT EXISTS pinned_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_snippets_user_pinned ON snippets(user_id, pinned_at DESC) WHERE pinned_at IS NOT NULL;
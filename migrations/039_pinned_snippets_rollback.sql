@@ -0,0 +1,3 @@
+-- Rollback Migration 039: Remove pinned snippets
+DROP INDEX IF EXISTS idx_snippets_user_pinned;
+ALTER TABLE snippets DROP COLUMN IF EXISTS pinned_at;
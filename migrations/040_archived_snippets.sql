@@ -0,0 +1,5 @@
+-- Migration 040: Archived snippets
+-- Archived snippets are hidden from listings and sync but, unlike soft-deleted ones, stay
+-- out of the deletion and retention pipeline.
+
+ALTER TABLE snippets ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP WITH TIME ZONE;
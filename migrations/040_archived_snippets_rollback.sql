@@ -0,0 +1,2 @@
+-- Rollback Migration 040: Remove archived snippets
+ALTER TABLE snippets DROP COLUMN IF EXISTS archived_at;
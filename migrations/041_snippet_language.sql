@@ -0,0 +1,7 @@
+-- Migration 041: Snippet language
+-- The programming language of a snippet (go, python, bash, ...), for syntax highlighting
+-- and the language filter on listings.
+
+ALTER TABLE snippets ADD COLUMN IF NOT EXISTS language VARCHAR(32);
+
+CREATE INDEX IF NOT EXISTS idx_snippets_user_language ON snippets(user_id, language) WHERE language IS NOT NULL;
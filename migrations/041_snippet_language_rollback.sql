@@ -0,0 +1,3 @@
+-- Rollback Migration 041: Remove snippet language
+DROP INDEX IF EXISTS idx_snippets_user_language;
+ALTER TABLE snippets DROP COLUMN IF EXISTS language;
@@ -0,0 +1,17 @@
+-- Migration 042: Unique shortcuts per user
+-- A user's live snippets must have distinct shortcuts, or expansion picks one of them at
+-- random. Existing duplicates get the snippet ID appended, keeping the oldest snippet's
+-- shortcut as is. Deleted snippets don't count, so a shortcut can be reused after
+-- deleting its snippet.
+
+UPDATE snippets s
+SET shortcut = left(s.shortcut, 49 - length(s.id::text)) || '-' || s.id
+FROM (
+	SELECT id, ROW_NUMBER() OVER (PARTITION BY user_id, shortcut ORDER BY created_at, id) AS n
+	FROM snippets
+	WHERE is_deleted = false
+) dup
+WHERE s.id = dup.id AND dup.n > 1;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_snippets_user_shortcut_unique ON snippets(user_id, shortcut) WHERE is_deleted = false;
+DROP INDEX IF EXISTS idx_snippets_user_shortcut;
@@ -0,0 +1,4 @@
+-- Rollback Migration 042: Allow duplicate shortcuts again
+-- Renamed duplicate shortcuts are not restored.
+CREATE INDEX IF NOT EXISTS idx_snippets_user_shortcut ON snippets(user_id, shortcut) WHERE is_deleted = false;
+DROP INDEX IF EXISTS idx_snippets_user_shortcut_unique;
@@ -0,0 +1,20 @@
+-- Migration 043: Snippet placeholders
+-- The typed placeholders of a snippet's content ({{name}}, {{count:number}}, {{date}}),
+-- stored when the snippet is written so clients can prompt for them. Existing snippets
+-- are parsed here the way the API parses them: names in order of first appearance, the
+-- first type of each name, untyped ones strings unless named date, time or datetime.
+
+ALTER TABLE snippets ADD COLUMN IF NOT EXISTS placeholders JSONB NOT NULL DEFAULT '[]';
+
+UPDATE snippets s
+SET placeholders = COALESCE((
+	SELECT jsonb_agg(jsonb_build_object('name', p.name, 'type', p.type) ORDER BY p.pos)
+	FROM (
+		SELECT DISTINCT ON (m[1]) m[1] AS name, pos,
+			COALESCE(lower(m[2]), CASE WHEN lower(m[1]) IN ('date', 'time', 'datetime') THEN lower(m[1]) ELSE 'string' END) AS type
+		FROM regexp_matches(s.content, '\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*(?::\s*([A-Za-z]+)\s*)?\}\}', 'g') WITH ORDINALITY AS r(m, pos)
+		ORDER BY m[1], pos
+	) p
+	WHERE p.type IN ('string', 'number', 'boolean', 'date', 'time', 'datetime')
+), '[]')
+WHERE s.content LIKE '%{{%';
@@ -0,0 +1,2 @@
+-- Rollback Migration 043: Remove snippet placeholders
+ALTER TABLE snippets DROP COLUMN IF EXISTS placeholders;
@@ -0,0 +1,16 @@
+-- Migration 044: Snippet shares
+-- Owners can grant other users of their organization viewer or editor access to a
+-- snippet. Revoked grants keep their row, with revoked_at set, so sync can tell the
+-- user's devices to drop the snippet.
+
+CREATE TABLE IF NOT EXISTS snippet_shares (
+    snippet_id BIGINT NOT NULL REFERENCES snippets(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(10) NOT NULL CHECK (role IN ('viewer', 'editor')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (snippet_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_snippet_shares_user_id ON snippet_shares(user_id);
@@ -0,0 +1,2 @@
+-- Rollback Migration 044: Remove snippet shares
+DROP TABLE IF EXISTS snippet_shares;
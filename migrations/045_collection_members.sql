@@ -0,0 +1,15 @@
+-- Migration 045: Collection members
+-- Collections can be shared with other users of the creator's organization as owners,
+-- editors or viewers. Membership covers the collections inside too; owners can manage
+-- the members and edit the snippets like editors, viewers only read them.
+
+CREATE TABLE IF NOT EXISTS collection_members (
+    collection_id INTEGER NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(10) NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (collection_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_collection_members_user_id ON collection_members(user_id);
@@ -0,0 +1,2 @@
+-- Rollback Migration 045: Remove collection members
+DROP TABLE IF EXISTS collection_members;
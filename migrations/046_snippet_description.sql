@@ -0,0 +1,14 @@
+-- Migration 046: Snippet description
+-- An optional Markdown description documenting what a snippet does and when to use it.
+-- Full-text search now matches descriptions too, with label matches ranked higher; this
+-- replaces the default language's label index, and the server does the same for the
+-- configured SEARCH_LANGUAGE on startup.
+
+ALTER TABLE snippets ADD COLUMN IF NOT EXISTS description TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_snippets_fts_english ON snippets USING GIN(
+    (setweight(to_tsvector('snippy_english', coalesce(label, '')), 'A') ||
+     setweight(to_tsvector('snippy_english', coalesce(description, '')), 'B'))
+);
+
+DROP INDEX IF EXISTS idx_snippets_search_english;
@@ -0,0 +1,7 @@
+-- Rollback Migration 046: Remove snippet description
+CREATE INDEX IF NOT EXISTS idx_snippets_search_english ON snippets USING GIN(
+    to_tsvector('snippy_english', coalesce(label, ''))
+);
+
+DROP INDEX IF EXISTS idx_snippets_fts_english;
+ALTER TABLE snippets DROP COLUMN IF EXISTS description;
@@ -0,0 +1,6 @@
+-- Migration 047: Keep snippet history forever
+-- Premium users can exempt a snippet's version history from the retention job, which
+-- otherwise deletes versions past SNIPPET_VERSION_RETENTION_DAYS and compacts each
+-- snippet down to its latest SNIPPET_MAX_VERSIONS.
+
+ALTER TABLE snippets ADD COLUMN IF NOT EXISTS keep_history BOOLEAN NOT NULL DEFAULT FALSE;
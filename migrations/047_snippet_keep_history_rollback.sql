@@ -0,0 +1,2 @@
+-- Rollback Migration 047: Remove keep snippet history forever
+ALTER TABLE snippets DROP COLUMN IF EXISTS keep_history;
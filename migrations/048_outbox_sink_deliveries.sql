@@ -0,0 +1,11 @@
+-- Migration 048: Record outbox deliveries per sink
+-- The dispatcher records each sink that has taken an event, so a retry after one sink
+-- fails only goes to the sinks that haven't (a flaky search backend no longer resends
+-- push notifications).
+
+CREATE TABLE IF NOT EXISTS outbox_sink_deliveries (
+	event_id BIGINT NOT NULL REFERENCES outbox_events(id) ON DELETE CASCADE,
+	sink VARCHAR(100) NOT NULL,
+	delivered_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (event_id, sink)
+);
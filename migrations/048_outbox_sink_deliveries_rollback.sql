@@ -0,0 +1,2 @@
+-- Rollback Migration 048: Remove per-sink outbox deliveries
+DROP TABLE IF EXISTS outbox_sink_deliveries;